@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// cliFlags holds the command-line settings. A flag that is given overrides
+// the environment, which overrides the config file.
+type cliFlags struct {
+	configFile string
+	host       string
+	port       int
+	storeDir   string
+	keysFile   string
+	tlsCert    string
+	tlsKey     string
+	tlsCA      string
+	insecure   bool
+	// set records which flags were given explicitly.
+	set map[string]bool
+}
+
+func parseFlags(args []string) *cliFlags {
+	f := &cliFlags{set: map[string]bool{}}
+	fs := flag.NewFlagSet("wacli-api", flag.ExitOnError)
+	fs.StringVar(&f.configFile, "config", "", "config file with WACLI_* settings in .env format (default: ./.env if present)")
+	fs.StringVar(&f.host, "host", "0.0.0.0", "host to bind to (WACLI_API_HOST)")
+	fs.IntVar(&f.port, "port", 8080, "port to listen on (WACLI_API_PORT)")
+	fs.StringVar(&f.storeDir, "store", "", "store directory (WACLI_STORE_DIR, default: ~/.wacli)")
+	fs.StringVar(&f.keysFile, "keys-file", "", "file with one API key per line (WACLI_API_KEYS_FILE)")
+	fs.StringVar(&f.tlsCert, "tls-cert", "", "TLS certificate file; serves HTTPS together with --tls-key (WACLI_API_TLS_CERT)")
+	fs.StringVar(&f.tlsKey, "tls-key", "", "TLS private key file (WACLI_API_TLS_KEY)")
+	fs.StringVar(&f.tlsCA, "tls-client-ca", "", "CA certificates (PEM) that client certificates must be signed by (WACLI_API_TLS_CLIENT_CA)")
+	fs.BoolVar(&f.insecure, "insecure", false, "start even if the preflight finds an unsafe setup (WACLI_API_INSECURE)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: wacli-api [flags]\n       wacli-api upgrade [flags]\n\nFlags override environment variables, which override the config file.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	fs.Visit(func(fl *flag.Flag) { f.set[fl.Name] = true })
+	return f
+}
+
+// stringOr returns the flag value if the flag was given, else the
+// environment variable, else def.
+func (f *cliFlags) stringOr(name, value, env, def string) string {
+	if f.set[name] {
+		return value
+	}
+	return getEnvOrDefault(env, def)
+}
+
+// readKeysFile reads API keys from path: one or more comma-separated keys
+// per line, ignoring blank lines and # comments.
+func readKeysFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys = append(keys, parseAPIKeys(line)...)
+	}
+	return keys, nil
+}
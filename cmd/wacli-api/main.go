@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -14,30 +16,104 @@ import (
 	"github.com/steipete/wacli/internal/api"
 	"github.com/steipete/wacli/internal/app"
 	"github.com/steipete/wacli/internal/config"
+	"github.com/steipete/wacli/internal/lock"
+	"github.com/steipete/wacli/internal/mediastore"
+	"github.com/steipete/wacli/internal/privacy"
+	"google.golang.org/grpc"
 )
 
 var version = "dev"
 
+// leaderPoll is how often a standby checks whether the leader is gone.
+var leaderPoll = 2 * time.Second
+
 func main() {
-	// Load .env file if it exists (ignore error if file doesn't exist)
-	_ = godotenv.Load()
+	if len(os.Args) > 1 && os.Args[1] == "upgrade" {
+		if err := runUpgradeCommand(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGUSR2)
+
+	// Captures the environment before .env is loaded, so an upgrade re-reads it.
+	upg, err := newUpgrader()
+	if err != nil {
+		log.Fatalf("Failed to take over from the previous process: %v", err)
+	}
+	flags := parseFlags(os.Args[1:])
+
+	// Load the config file; variables already in the environment win. The
+	// default .env is optional, an explicit --config is not.
+	if flags.configFile != "" {
+		if err := godotenv.Load(flags.configFile); err != nil {
+			log.Fatalf("Failed to load config file: %v", err)
+		}
+	} else {
+		_ = godotenv.Load()
+	}
+
+	cfg := loadConfig(flags)
+	if errs := cfg.Validate(); len(errs) > 0 {
+		for _, err := range errs {
+			log.Printf("config: %v", err)
+		}
+		log.Fatalf("Invalid configuration (%d problems)", len(errs))
+	}
+	warnings := cfg.PreflightWarnings()
+	for _, w := range warnings {
+		log.Printf("WARN: preflight: %s", w)
+	}
+	if cfg.RefuseUnsafe(warnings) {
+		log.Fatalf("Refusing to start with an unsafe setup (%d problems); fix them or pass --insecure", len(warnings))
+	}
 
-	cfg := loadConfig()
+	if cfg.PrimaryAddr != "" {
+		runProxy(cfg, upg)
+		return
+	}
 
 	storeDir := cfg.StoreDir
 	if storeDir == "" {
 		storeDir = config.DefaultStoreDir()
 	}
+	if cfg.PIDFile == "" {
+		cfg.PIDFile = defaultPIDFile(storeDir)
+	}
+
+	if cfg.LeaderElection {
+		leader := waitForLeadership(storeDir)
+		defer leader.Release()
+	}
+
+	mediaStore, err := openMediaStore(cfg)
+	if err != nil {
+		log.Fatalf("Failed to open media store: %v", err)
+	}
 
 	// Initialize the app
 	appInstance, err := app.New(app.Options{
-		StoreDir: storeDir,
-		Version:  version,
-		JSON:     true,
+		StoreDir:   storeDir,
+		Version:    version,
+		JSON:       true,
+		SandboxTo:  cfg.SandboxTo,
+		AdminTo:    cfg.AdminTo,
+		MediaStore: mediaStore,
 	})
 	if err != nil {
 		log.Fatalf("Failed to initialize app: %v", err)
 	}
+	if to, ok := appInstance.Sandbox(); ok {
+		log.Printf("Sandbox mode: all outgoing messages are sent to %s", to)
+	}
+	if errs, err := appInstance.ValidateAutomation(); err != nil {
+		log.Printf("WARN: could not check webhooks and rules: %v", err)
+	} else {
+		for _, err := range errs {
+			log.Printf("WARN: %v", err)
+		}
+	}
 
 	// Setup Gin router
 	if cfg.ReleaseMode {
@@ -45,6 +121,9 @@ func main() {
 	}
 
 	router := gin.Default()
+	if upg.takeover {
+		router.Use(afterHandoff(upg.previousGone))
+	}
 
 	// Setup routes (API key middleware applied selectively)
 	api.SetupRoutes(router, appInstance, cfg)
@@ -56,48 +135,419 @@ func main() {
 		Config: cfg,
 	}
 
+	// Optionally accept forwarded requests from proxy frontends
+	var grpcServer *grpc.Server
+	if cfg.GRPCAddr != "" {
+		lis, err := upg.listen("grpc", cfg.GRPCAddr)
+		if err != nil {
+			log.Fatalf("Failed to listen on %s: %v", cfg.GRPCAddr, err)
+		}
+		grpcServer, err = api.NewPrimaryServer(router, cfg)
+		if err != nil {
+			log.Fatalf("Failed to set up gRPC listener: %v", err)
+		}
+		go func() {
+			log.Printf("Accepting proxy frontends on %s", cfg.GRPCAddr)
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Printf("gRPC server stopped: %v", err)
+			}
+		}()
+	}
+
+	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+	ln, err := upg.listen("http", addr)
+	if err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+	httpServer := newHTTPServer(router, ln)
 	go func() {
-		addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
 		log.Printf("Starting wacli API server on %s", addr)
-		if err := router.Run(addr); err != nil {
+		if err := httpServer.serve(cfg); err != nil {
 			log.Fatalf("Failed to start server: %v", err)
 		}
 	}()
+	upg.serving()
+	if err := writePIDFile(cfg.PIDFile); err != nil {
+		log.Printf("WARN: could not write pid file: %v", err)
+	}
+	defer removePIDFile(cfg.PIDFile)
+
+	// Background workers: outbox, webhook deliveries and optional follow
+	// mode. After an upgrade they wait for the old process to let go of the
+	// session.
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go func() {
+		if upg.takeover {
+			log.Println("Upgrade: waiting for the previous process to drain")
+			select {
+			case <-upg.previousGone:
+			case <-workerCtx.Done():
+				return
+			}
+			log.Println("Upgrade: previous process exited; taking over")
+		}
+		startWorkers(workerCtx, appInstance, cfg)
+		go srv.RunWebhookInbox(workerCtx)
+	}()
 
-	// Wait for interrupt signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	for waitForSignal() == syscall.SIGUSR2 {
+		if cfg.LeaderElection {
+			log.Println("Upgrade not supported with leader election; restart instead and let a standby take over")
+			continue
+		}
+		if err := upg.upgrade(); err != nil {
+			log.Printf("Upgrade failed, still serving: %v", err)
+			continue
+		}
+		log.Println("Upgrade: new process is serving; draining")
+		break
+	}
 
 	log.Println("Shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
-
+	if err := httpServer.shutdown(ctx); err != nil {
+		log.Printf("HTTP shutdown: %v", err)
+	}
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+	stopWorkers()
 	srv.Shutdown(ctx)
 	log.Println("Server stopped")
 }
 
-func loadConfig() *api.Config {
-	apiKeys := os.Getenv("WACLI_API_KEYS")
-	if apiKeys == "" {
-		log.Fatal("WACLI_API_KEYS environment variable is required (comma-separated list of valid API keys)")
+func startWorkers(ctx context.Context, a *app.App, cfg *api.Config) {
+	go a.RunTrashPurge(ctx, cfg.TrashRetention)
+	go a.RunMessagePrune(ctx, cfg.MessageRetention)
+	go a.RunFTSRebuild(ctx)
+	go a.RunMediaGC(ctx, cfg.MediaRetention)
+	go a.RunAdminAlerts(ctx, cfg.AdminAlerts)
+	go a.RunMaintenanceSummaries(ctx)
+	if err := a.OpenWA(); err != nil {
+		log.Printf("WARN: outbox worker disabled: %v", err)
+		return
+	}
+	// One connection for the server's lifetime; requests wait for it
+	// instead of connecting themselves.
+	go func() {
+		if err := a.RunConnection(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("WARN: connection supervisor stopped: %v", err)
+		}
+	}()
+	go a.RunOutbox(ctx, 30*time.Second)
+	go a.RunSnoozeReminders(ctx)
+	go a.RunWebhooks(ctx)
+	go a.RunGroupSnapshots(ctx, cfg.GroupSnapshots)
+
+	// Optionally stay connected and follow incoming messages/events
+	if cfg.Follow {
+		go runFollow(ctx, a, cfg)
+	}
+	if cfg.PresenceWatch {
+		go a.RunPresenceWatch(ctx)
+	}
+	vm := cfg.Voicemail
+	vm.GroqAPIKey, vm.Privacy = cfg.AI.GroqAPIKey, cfg.AI.Privacy
+	go a.RunVoicemail(ctx, vm)
+	startEventSinks(ctx, a, cfg)
+}
+
+// openMediaStore returns the configured media store, or nil for the default
+// filesystem store.
+func openMediaStore(cfg *api.Config) (mediastore.Store, error) {
+	if cfg.MediaStore != "s3" {
+		return nil, nil
+	}
+	s, err := mediastore.NewS3(cfg.S3)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.Check(ctx); err != nil {
+		return nil, err
+	}
+	log.Printf("Storing media in s3://%s/%s", cfg.S3.Bucket, cfg.S3.Prefix)
+	return s, nil
+}
+
+// runProxy serves the API as a stateless frontend of the primary at
+// cfg.PrimaryAddr. It opens no store and no WhatsApp session.
+func runProxy(cfg *api.Config, upg *upgrader) {
+	primary, err := api.DialPrimary(cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up proxy: %v", err)
+	}
+	defer primary.Close()
+
+	if cfg.ReleaseMode {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	router := gin.Default()
+	api.SetupProxyRoutes(router, primary, cfg)
+
+	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+	ln, err := upg.listen("http", addr)
+	if err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+	httpServer := newHTTPServer(router, ln)
+	go func() {
+		log.Printf("Starting wacli API proxy on %s (primary %s)", addr, cfg.PrimaryAddr)
+		if err := httpServer.serve(cfg); err != nil {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+	upg.serving()
+	if err := writePIDFile(cfg.PIDFile); err != nil {
+		log.Printf("WARN: could not write pid file: %v", err)
+	}
+	defer removePIDFile(cfg.PIDFile)
+
+	for waitForSignal() == syscall.SIGUSR2 {
+		if err := upg.upgrade(); err != nil {
+			log.Printf("Upgrade failed, still serving: %v", err)
+			continue
+		}
+		log.Println("Upgrade: new process is serving; draining")
+		break
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_ = httpServer.shutdown(ctx)
+	log.Println("Proxy stopped")
+}
+
+// waitForLeadership blocks while another instance leads the store, exiting
+// if the process is stopped before it takes over.
+func waitForLeadership(storeDir string) *lock.Lock {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	leader, err := lock.WaitLeader(ctx, storeDir, leaderPoll, func(holder string) {
+		log.Printf("Standby: another instance leads %s (%s); waiting to take over", storeDir, holder)
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			log.Println("Standby stopped")
+			os.Exit(0)
+		}
+		log.Fatalf("Leader election failed: %v", err)
+	}
+	log.Printf("Acquired leadership of %s", storeDir)
+	return leader
+}
+
+// signals receives stop (SIGINT, SIGTERM) and upgrade (SIGUSR2) signals
+// from startup on, so an early SIGUSR2 does not kill the process.
+var signals = make(chan os.Signal, 1)
+
+func waitForSignal() os.Signal {
+	return <-signals
+}
+
+func runFollow(ctx context.Context, a *app.App, cfg *api.Config) {
+	if err := a.EnsureAuthed(); err != nil {
+		log.Printf("WARN: follow mode disabled: %v", err)
+		return
+	}
+	log.Println("Follow mode: keeping WhatsApp connection open")
+	for ctx.Err() == nil {
+		_, err := a.Sync(ctx, app.SyncOptions{
+			Mode:   app.SyncModeFollow,
+			Config: cfg.AppConfig(),
+		})
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		log.Printf("Follow sync stopped: %v; retrying in 30s", err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(30 * time.Second):
+		}
+	}
+}
+
+// startEventSinks starts a publisher for every configured event sink.
+func startEventSinks(ctx context.Context, a *app.App, cfg *api.Config) {
+	type sinkConfig struct {
+		name    string
+		enabled bool
+		events  []string
+		create  func() (app.EventSink, error)
+	}
+	for _, sc := range []sinkConfig{
+		{"mqtt", cfg.MQTT.Broker != "", cfg.MQTT.Events, func() (app.EventSink, error) { return app.NewMQTTSink(cfg.MQTT) }},
+		{"nats", cfg.NATS.URL != "", cfg.NATS.Events, func() (app.EventSink, error) { return app.NewNATSSink(cfg.NATS) }},
+		{"kafka", len(cfg.Kafka.Brokers) > 0, cfg.Kafka.Events, func() (app.EventSink, error) { return app.NewKafkaSink(cfg.Kafka) }},
+	} {
+		if !sc.enabled {
+			continue
+		}
+		s, err := sc.create()
+		if err != nil {
+			log.Printf("WARN: %s event sink disabled: %v", sc.name, err)
+			continue
+		}
+		log.Printf("Publishing events to %s", sc.name)
+		go a.RunSink(ctx, sc.name, s, sc.events)
+	}
+}
+
+func loadConfig(flags *cliFlags) *api.Config {
+	clientCA := flags.stringOr("tls-client-ca", flags.tlsCA, "WACLI_API_TLS_CLIENT_CA", "")
+	clientAuth := os.Getenv("WACLI_API_TLS_CLIENT_AUTH")
+	apiKeys, err := loadAPIKeys(flags)
+	// Client certificates may replace the API keys, and proxy frontends
+	// leave checking them to the primary.
+	keysOptional := (clientCA != "" && clientAuth == api.ClientCertOnly) || os.Getenv("WACLI_API_PRIMARY") != ""
+	if err != nil && !(errors.Is(err, errNoAPIKeys) && keysOptional) {
+		log.Fatal(err)
+	}
+
+	port := getEnvIntOrDefault("WACLI_API_PORT", 8080)
+	if flags.set["port"] {
+		port = flags.port
+	}
+	insecure := getEnvBool("WACLI_API_INSECURE")
+	if flags.set["insecure"] {
+		insecure = flags.insecure
 	}
 
 	cfg := &api.Config{
-		Host:        getEnvOrDefault("WACLI_API_HOST", "0.0.0.0"),
-		Port:        getEnvIntOrDefault("WACLI_API_PORT", 8080),
-		StoreDir:    os.Getenv("WACLI_STORE_DIR"),
-		APIKeys:     parseAPIKeys(apiKeys),
-		ReleaseMode: getEnvOrDefault("GIN_MODE", "debug") == "release",
+		Host:                flags.stringOr("host", flags.host, "WACLI_API_HOST", "0.0.0.0"),
+		Port:                port,
+		StoreDir:            flags.stringOr("store", flags.storeDir, "WACLI_STORE_DIR", ""),
+		APIKeys:             apiKeys,
+		TLSCert:             flags.stringOr("tls-cert", flags.tlsCert, "WACLI_API_TLS_CERT", ""),
+		TLSKey:              flags.stringOr("tls-key", flags.tlsKey, "WACLI_API_TLS_KEY", ""),
+		TLSClientCA:         clientCA,
+		TLSClientAuth:       clientAuth,
+		PreflightMode:       getEnvOrDefault("WACLI_API_PREFLIGHT", api.PreflightStrict),
+		Insecure:            insecure,
+		MinKeyBits:          getEnvIntOrDefault("WACLI_API_MIN_KEY_BITS", api.DefaultMinKeyBits),
+		PIDFile:             os.Getenv("WACLI_API_PIDFILE"),
+		ReleaseMode:         getEnvOrDefault("GIN_MODE", "debug") == "release",
+		Follow:              getEnvBool("WACLI_API_FOLLOW"),
+		LeaderElection:      getEnvBool("WACLI_API_LEADER_ELECTION"),
+		GRPCAddr:            os.Getenv("WACLI_API_GRPC_ADDR"),
+		PrimaryAddr:         os.Getenv("WACLI_API_PRIMARY"),
+		GRPCTLSCert:         os.Getenv("WACLI_API_GRPC_TLS_CERT"),
+		GRPCTLSKey:          os.Getenv("WACLI_API_GRPC_TLS_KEY"),
+		GRPCTLSCA:           os.Getenv("WACLI_API_GRPC_TLS_CA"),
+		PresenceWatch:       getEnvBool("WACLI_API_PRESENCE_WATCH"),
+		SlackSigningSecret:  os.Getenv("WACLI_SLACK_SIGNING_SECRET"),
+		Footer:              os.Getenv("WACLI_API_FOOTER"),
+		KeyFooters:          parseKeyFooters(os.Getenv("WACLI_API_KEY_FOOTERS")),
+		RouteLimits:         parseRouteLimits(os.Getenv("WACLI_API_ROUTE_LIMITS")),
+		WebhookMaxBodyBytes: int64(getEnvIntOrDefault("WACLI_WEBHOOK_MAX_BODY_KB", 1024)) << 10,
+		WebhookQueue:        getEnvBool("WACLI_WEBHOOK_QUEUE"),
+		BackupDir:           os.Getenv("WACLI_API_BACKUP_DIR"),
+		SandboxTo:           os.Getenv("WACLI_SANDBOX_TO"),
+		AdminTo:             os.Getenv("WACLI_ADMIN_JID"),
+		AdminAlerts: app.AdminAlerts{
+			MinFreeDiskMB: int64(getEnvIntOrDefault("WACLI_ADMIN_MIN_FREE_DISK_MB", 1024)),
+			MaxBacklog:    int64(getEnvIntOrDefault("WACLI_ADMIN_MAX_BACKLOG", 500)),
+		},
+		Voicemail: app.Voicemail{
+			Chats:     splitAndTrim(os.Getenv("WACLI_VOICEMAIL_CHATS"), ","),
+			ForwardTo: os.Getenv("WACLI_VOICEMAIL_FORWARD_TO"),
+			Email:     splitAndTrim(os.Getenv("WACLI_VOICEMAIL_EMAIL"), ","),
+			SMTP: app.SMTPOptions{
+				Addr:     os.Getenv("WACLI_SMTP_ADDR"),
+				Username: os.Getenv("WACLI_SMTP_USERNAME"),
+				Password: os.Getenv("WACLI_SMTP_PASSWORD"),
+				From:     os.Getenv("WACLI_SMTP_FROM"),
+			},
+		},
+		TrashRetention:    time.Duration(getEnvIntOrDefault("WACLI_TRASH_RETENTION_DAYS", 30)) * 24 * time.Hour,
+		MessageRetention:  time.Duration(getEnvIntOrDefault("WACLI_MESSAGE_RETENTION_DAYS", 0)) * 24 * time.Hour,
+		NumberCheckMaxAge: time.Duration(getEnvIntOrDefault("WACLI_NUMBER_CHECK_CACHE_HOURS", 168)) * time.Hour,
+		AvatarMaxAge:      time.Duration(getEnvIntOrDefault("WACLI_AVATAR_CACHE_HOURS", 24)) * time.Hour,
+		GroupSnapshots: app.GroupSnapshots{
+			Interval:  time.Duration(getEnvIntOrDefault("WACLI_GROUP_SNAPSHOT_HOURS", 24)) * time.Hour,
+			MassLeave: getEnvIntOrDefault("WACLI_GROUP_MASS_LEAVE", 10),
+		},
+		MediaStore: getEnvOrDefault("WACLI_MEDIA_STORE", "fs"),
+		S3: mediastore.S3Options{
+			Endpoint:  os.Getenv("WACLI_S3_ENDPOINT"),
+			Bucket:    os.Getenv("WACLI_S3_BUCKET"),
+			Prefix:    os.Getenv("WACLI_S3_PREFIX"),
+			Region:    os.Getenv("WACLI_S3_REGION"),
+			AccessKey: os.Getenv("WACLI_S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("WACLI_S3_SECRET_KEY"),
+			Insecure:  getEnvBool("WACLI_S3_INSECURE"),
+		},
+		MediaRetention: app.MediaRetention{
+			MaxAge:   time.Duration(getEnvIntOrDefault("WACLI_MEDIA_MAX_AGE_DAYS", 0)) * 24 * time.Hour,
+			MaxBytes: int64(getEnvIntOrDefault("WACLI_MEDIA_MAX_SIZE_MB", 0)) << 20,
+		},
+		MediaURLSecret: os.Getenv("WACLI_MEDIA_URL_SECRET"),
+		MediaURLMaxTTL: time.Duration(getEnvIntOrDefault("WACLI_MEDIA_URL_MAX_TTL_HOURS", 168)) * time.Hour,
+		MQTT: app.MQTTOptions{
+			Broker:      os.Getenv("WACLI_MQTT_BROKER"),
+			ClientID:    os.Getenv("WACLI_MQTT_CLIENT_ID"),
+			Username:    os.Getenv("WACLI_MQTT_USERNAME"),
+			Password:    os.Getenv("WACLI_MQTT_PASSWORD"),
+			TopicPrefix: getEnvOrDefault("WACLI_MQTT_TOPIC_PREFIX", "wacli"),
+			Events:      splitAndTrim(os.Getenv("WACLI_MQTT_EVENTS"), ","),
+			QoS:         byte(getEnvIntOrDefault("WACLI_MQTT_QOS", 0)),
+		},
+		NATS: app.NATSOptions{
+			URL:           os.Getenv("WACLI_NATS_URL"),
+			CredsFile:     os.Getenv("WACLI_NATS_CREDS"),
+			SubjectPrefix: getEnvOrDefault("WACLI_NATS_SUBJECT_PREFIX", "wacli"),
+			Events:        splitAndTrim(os.Getenv("WACLI_NATS_EVENTS"), ","),
+		},
+		Kafka: app.KafkaOptions{
+			Brokers: splitAndTrim(os.Getenv("WACLI_KAFKA_BROKERS"), ","),
+			Topic:   getEnvOrDefault("WACLI_KAFKA_TOPIC", "wacli.events"),
+			Events:  splitAndTrim(os.Getenv("WACLI_KAFKA_EVENTS"), ","),
+		},
 		AI: api.AIConfig{
-			Enabled:    getEnvBool("WACLI_AI_ENABLED"),
-			GroqAPIKey: os.Getenv("GROQ_API_KEY"),
+			Enabled:           getEnvBool("WACLI_AI_ENABLED"),
+			GroqAPIKey:        os.Getenv("GROQ_API_KEY"),
+			ChatModel:         os.Getenv("WACLI_AI_CHAT_MODEL"),
+			SummaryMinSeconds: getEnvIntOrDefault("WACLI_AI_SUMMARY_MIN_SECONDS", 60),
+			TranscribeVideo:   getEnvBool("WACLI_AI_TRANSCRIBE_VIDEO"),
+			FFmpegPath:        getEnvOrDefault("WACLI_FFMPEG_PATH", "ffmpeg"),
+			Sentiment:         getEnvBool("WACLI_AI_SENTIMENT"),
+			Privacy: privacy.Guard{
+				DenyChats:     splitAndTrim(os.Getenv("WACLI_AI_DENY_CHATS"), ","),
+				AllowChats:    splitAndTrim(os.Getenv("WACLI_AI_ALLOW_CHATS"), ","),
+				AllowlistOnly: getEnvBool("WACLI_AI_ALLOWLIST_ONLY"),
+				RedactPhones:  getEnvBool("WACLI_AI_REDACT_PHONES"),
+				RedactNames:   getEnvBool("WACLI_AI_REDACT_NAMES"),
+			},
 		},
 	}
 
 	return cfg
 }
 
+var errNoAPIKeys = errors.New("API keys are required: set WACLI_API_KEYS (comma-separated), WACLI_API_KEYS_FILE or --keys-file")
+
+// loadAPIKeys reads the keys from --keys-file, WACLI_API_KEYS or
+// WACLI_API_KEYS_FILE, in that order.
+func loadAPIKeys(flags *cliFlags) ([]string, error) {
+	path := flags.keysFile
+	if !flags.set["keys-file"] {
+		if raw := os.Getenv("WACLI_API_KEYS"); raw != "" {
+			return parseAPIKeys(raw), nil
+		}
+		path = os.Getenv("WACLI_API_KEYS_FILE")
+	}
+	if path == "" {
+		return nil, errNoAPIKeys
+	}
+	keys, err := readKeysFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read API keys: %w", err)
+	}
+	return keys, nil
+}
+
 func getEnvOrDefault(key, defaultValue string) string {
 	if val := os.Getenv(key); val != "" {
 		return val
@@ -130,6 +580,31 @@ func parseAPIKeys(raw string) []string {
 	return keys
 }
 
+// parseKeyFooters reads a JSON object mapping API keys to their footer.
+func parseKeyFooters(raw string) map[string]string {
+	if raw == "" {
+		return nil
+	}
+	var footers map[string]string
+	if err := json.Unmarshal([]byte(raw), &footers); err != nil {
+		log.Fatalf("WACLI_API_KEY_FOOTERS must be a JSON object of API key to footer: %v", err)
+	}
+	return footers
+}
+
+// parseRouteLimits reads a JSON object mapping route patterns to their
+// limits.
+func parseRouteLimits(raw string) map[string]api.RouteLimit {
+	if raw == "" {
+		return nil
+	}
+	var limits map[string]api.RouteLimit
+	if err := json.Unmarshal([]byte(raw), &limits); err != nil {
+		log.Fatalf("WACLI_API_ROUTE_LIMITS must be a JSON object of route pattern to limits: %v", err)
+	}
+	return limits
+}
+
 func splitAndTrim(s, sep string) []string {
 	parts := []string{}
 	for _, p := range split(s, sep) {
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/steipete/wacli/internal/api"
+)
+
+// freshConnGrace bounds how long shutdown waits for accepted connections to
+// send their first request. net/http treats older ones as idle too.
+const freshConnGrace = 5 * time.Second
+
+// httpServer serves the API on a listener and shuts down without dropping
+// requests: http.Server.Shutdown alone closes a connection accepted just
+// before it without answering, which loses requests racing an upgrade.
+type httpServer struct {
+	srv *http.Server
+	ln  net.Listener
+
+	mu    sync.Mutex
+	fresh map[net.Conn]struct{}
+}
+
+func newHTTPServer(handler http.Handler, ln net.Listener) *httpServer {
+	s := &httpServer{ln: ln, fresh: map[net.Conn]struct{}{}}
+	s.srv = &http.Server{Handler: handler, ConnState: s.trackConn}
+	return s
+}
+
+// trackConn records connections that have not sent a request yet.
+func (s *httpServer) trackConn(c net.Conn, state http.ConnState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state == http.StateNew {
+		s.fresh[c] = struct{}{}
+	} else {
+		delete(s.fresh, c)
+	}
+}
+
+func (s *httpServer) freshConns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.fresh)
+}
+
+// serve serves over TLS when a certificate is set, verifying client
+// certificates when a client CA is set, until shut down.
+func (s *httpServer) serve(cfg *api.Config) error {
+	var err error
+	if cfg.TLSCert != "" {
+		if s.srv.TLSConfig, err = cfg.ServerTLSConfig(); err != nil {
+			return err
+		}
+		err = s.srv.ServeTLS(s.ln, cfg.TLSCert, cfg.TLSKey)
+	} else {
+		err = s.srv.Serve(s.ln)
+	}
+	if errors.Is(err, http.ErrServerClosed) || errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+// shutdown stops accepting, gives fresh connections time to send their
+// request and waits for in-flight requests to finish.
+func (s *httpServer) shutdown(ctx context.Context) error {
+	_ = s.ln.Close()
+	deadline := time.Now().Add(freshConnGrace)
+	for s.freshConns() > 0 && time.Now().Before(deadline) && ctx.Err() == nil {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := s.srv.Shutdown(ctx); err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
+	"github.com/steipete/wacli/internal/config"
+)
+
+// Zero-downtime upgrades: on SIGUSR2 the server starts its binary again and
+// hands over its listening sockets. The new process accepts connections
+// right away, so none are refused, but holds API requests and background
+// work until the old process has drained its in-flight requests, released
+// the WhatsApp session and exited.
+
+// envUpgrade names the listeners a new process inherits, in file descriptor
+// order from 3. The handoff and ready pipes follow them.
+const envUpgrade = "WACLI_API_UPGRADE"
+
+// upgradeTimeout bounds how long the old process waits for the new one to
+// start serving.
+var upgradeTimeout = time.Minute
+
+type upgrader struct {
+	// exe and env start the new process: the binary path and environment
+	// as this process saw them at startup, so a replaced binary and edited
+	// .env file are picked up.
+	exe string
+	env []string
+
+	// takeover is set when this process replaced a running one.
+	takeover  bool
+	inherited map[string]net.Listener
+	names     []string
+	listeners []net.Listener
+
+	// previousGone is closed once the process this one replaced has exited,
+	// or right away after a normal start.
+	previousGone chan struct{}
+	ready        *os.File
+	// handoff stays open until this process exits; its replacement waits
+	// for it to close.
+	handoff *os.File
+}
+
+// newUpgrader picks up the listeners handed over by a previous process. It
+// must run before the environment is modified.
+func newUpgrader() (*upgrader, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	u := &upgrader{exe: exe, env: os.Environ(), inherited: map[string]net.Listener{}, previousGone: make(chan struct{})}
+	spec := os.Getenv(envUpgrade)
+	if spec == "" {
+		close(u.previousGone)
+		return u, nil
+	}
+	u.takeover = true
+	os.Unsetenv(envUpgrade)
+	u.env = slices.DeleteFunc(u.env, func(kv string) bool { return strings.HasPrefix(kv, envUpgrade+"=") })
+
+	names := strings.Split(spec, ",")
+	for i, name := range names {
+		f := os.NewFile(uintptr(3+i), name)
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("inherit %s listener: %w", name, err)
+		}
+		u.inherited[name] = ln
+	}
+	handoff := os.NewFile(uintptr(3+len(names)), "handoff")
+	u.ready = os.NewFile(uintptr(4+len(names)), "ready")
+	go func() {
+		_, _ = io.Copy(io.Discard, handoff)
+		handoff.Close()
+		close(u.previousGone)
+	}()
+	return u, nil
+}
+
+// listen returns the listener handed over under name, or a new one on addr.
+// An inherited listener keeps its address; changing it needs a restart.
+func (u *upgrader) listen(name, addr string) (net.Listener, error) {
+	ln, ok := u.inherited[name]
+	if ok {
+		delete(u.inherited, name)
+	} else {
+		var err error
+		if ln, err = net.Listen("tcp", addr); err != nil {
+			return nil, err
+		}
+	}
+	u.names = append(u.names, name)
+	u.listeners = append(u.listeners, ln)
+	return ln, nil
+}
+
+// serving tells the previous process that this one accepts connections and
+// closes inherited listeners that are no longer configured. Under systemd
+// (Type=notify) it also reports this process as the service's main PID.
+func (u *upgrader) serving() {
+	for name, ln := range u.inherited {
+		ln.Close()
+		delete(u.inherited, name)
+	}
+	if u.ready != nil {
+		sdNotify(fmt.Sprintf("MAINPID=%d\nREADY=1", os.Getpid()))
+		_, _ = u.ready.Write([]byte{1})
+		u.ready.Close()
+		u.ready = nil
+		return
+	}
+	sdNotify("READY=1")
+}
+
+// upgrade starts a new process with this one's listeners and waits until it
+// is serving. On success the caller stops accepting, drains and exits.
+func (u *upgrader) upgrade() error {
+	var files []*os.File
+	closeFiles := func() {
+		for _, f := range files {
+			f.Close()
+		}
+		files = nil
+	}
+	defer closeFiles()
+	for i, ln := range u.listeners {
+		fl, ok := ln.(interface{ File() (*os.File, error) })
+		if !ok {
+			return fmt.Errorf("%s listener cannot be handed over", u.names[i])
+		}
+		f, err := fl.File()
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+	}
+	handoffR, handoffW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		handoffR.Close()
+		handoffW.Close()
+		return err
+	}
+	defer readyR.Close()
+	files = append(files, handoffR, readyW)
+
+	cmd := exec.Command(u.exe, os.Args[1:]...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.Env = append(slices.Clone(u.env), envUpgrade+"="+strings.Join(u.names, ","))
+	cmd.ExtraFiles = files
+	err = cmd.Start()
+	// Passing the duplicates puts them in blocking mode, and with them the
+	// listeners they share it with; a blocking accept would hang Close.
+	for _, ln := range u.listeners {
+		setNonblock(ln)
+	}
+	if err != nil {
+		handoffW.Close()
+		return err
+	}
+	// Drop our copies so a crashing child closes the ready pipe.
+	closeFiles()
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+
+	result := make(chan error, 1)
+	go func() {
+		if _, err := readyR.Read(make([]byte, 1)); err != nil {
+			result <- errors.New("new process exited before serving")
+			return
+		}
+		result <- nil
+	}()
+	select {
+	case err = <-result:
+	case <-time.After(upgradeTimeout):
+		err = fmt.Errorf("new process not serving after %s", upgradeTimeout)
+	}
+	if err != nil {
+		_ = cmd.Process.Kill()
+		<-exited
+		handoffW.Close()
+		return err
+	}
+	u.handoff = handoffW
+	return nil
+}
+
+func setNonblock(ln net.Listener) {
+	sc, ok := ln.(syscall.Conn)
+	if !ok {
+		return
+	}
+	if rc, err := sc.SyscallConn(); err == nil {
+		_ = rc.Control(func(fd uintptr) { _ = syscall.SetNonblock(int(fd), true) })
+	}
+}
+
+// afterHandoff holds requests in a process that replaced another until the
+// old one has exited. The health check answers right away.
+func afterHandoff(previousGone <-chan struct{}) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.FullPath() == "/health" {
+			c.Next()
+			return
+		}
+		select {
+		case <-previousGone:
+			c.Next()
+		case <-c.Request.Context().Done():
+			c.Abort()
+		}
+	}
+}
+
+// sdNotify sends a state update to systemd when running as a Type=notify
+// service.
+func sdNotify(state string) {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	_, _ = conn.Write([]byte(state))
+}
+
+func writePIDFile(path string) error {
+	if path == "" {
+		return nil
+	}
+	return os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644)
+}
+
+// removePIDFile removes the pid file unless a replacement has taken it
+// over.
+func removePIDFile(path string) {
+	if pid, err := readPIDFile(path); err == nil && pid == os.Getpid() {
+		_ = os.Remove(path)
+	}
+}
+
+func readPIDFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// defaultPIDFile is where a server with the given store records its pid.
+func defaultPIDFile(storeDir string) string {
+	if storeDir == "" {
+		storeDir = config.DefaultStoreDir()
+	}
+	return filepath.Join(storeDir, "wacli-api.pid")
+}
+
+// runUpgradeCommand implements `wacli-api upgrade`: it signals the running
+// server to replace itself with the binary now on disk and waits until the
+// new process has taken over.
+func runUpgradeCommand(args []string) error {
+	fs := flag.NewFlagSet("wacli-api upgrade", flag.ExitOnError)
+	pidFile := fs.String("pidfile", "", "pid file of the running server (default: WACLI_API_PIDFILE or <store>/wacli-api.pid)")
+	storeDir := fs.String("store", "", "store directory of the running server (WACLI_STORE_DIR)")
+	timeout := fs.Duration("timeout", 2*time.Minute, "how long to wait for the new process to take over")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: wacli-api upgrade [flags]\n\nReplaces the running server with the wacli-api binary now on disk without dropping connections.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	_ = godotenv.Load()
+
+	path := *pidFile
+	if path == "" {
+		path = os.Getenv("WACLI_API_PIDFILE")
+	}
+	if path == "" {
+		dir := *storeDir
+		if dir == "" {
+			dir = os.Getenv("WACLI_STORE_DIR")
+		}
+		path = defaultPIDFile(dir)
+	}
+	old, err := readPIDFile(path)
+	if err != nil {
+		return fmt.Errorf("read pid file: %w", err)
+	}
+	if err := syscall.Kill(old, syscall.SIGUSR2); err != nil {
+		return fmt.Errorf("signal server (pid %d): %w", old, err)
+	}
+
+	deadline := time.Now().Add(*timeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(200 * time.Millisecond)
+		pid, _ := readPIDFile(path)
+		if processAlive(old) {
+			continue
+		}
+		if pid == old || pid == 0 || !processAlive(pid) {
+			return fmt.Errorf("server (pid %d) exited without a replacement; check its logs", old)
+		}
+		fmt.Printf("Upgraded: pid %d replaced by %d\n", old, pid)
+		return nil
+	}
+	return fmt.Errorf("upgrade did not finish within %s; pid %d is still serving, check its logs", *timeout, old)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/config"
+	"github.com/steipete/wacli/internal/out"
+	"github.com/steipete/wacli/internal/store"
+)
+
+func newConfigCmd(flags *rootFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Export and import automation config",
+	}
+	cmd.AddCommand(newConfigExportBundleCmd(flags))
+	cmd.AddCommand(newConfigImportBundleCmd(flags))
+	cmd.AddCommand(newConfigValidateCmd(flags))
+	return cmd
+}
+
+func newConfigExportBundleCmd(flags *rootFlags) *cobra.Command {
+	var outPath string
+	var includeSecrets bool
+	cmd := &cobra.Command{
+		Use:   "export-bundle",
+		Short: "Write rules and webhook subscriptions to a versioned JSON bundle",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := withTimeout(context.Background(), flags)
+			defer cancel()
+
+			a, lk, err := newApp(ctx, flags, false, true)
+			if err != nil {
+				return err
+			}
+			defer closeApp(a, lk)
+
+			b, err := a.DB().ExportBundle(includeSecrets)
+			if err != nil {
+				return err
+			}
+			data, err := json.MarshalIndent(b, "", "  ")
+			if err != nil {
+				return err
+			}
+			data = append(data, '\n')
+
+			if outPath == "" || outPath == "-" {
+				_, err = os.Stdout.Write(data)
+				return err
+			}
+			// Bundles with secrets should not be world-readable.
+			if err := os.WriteFile(outPath, data, 0o600); err != nil {
+				return err
+			}
+			if flags.asJSON {
+				return out.WriteJSON(os.Stdout, map[string]any{"path": outPath, "webhooks": len(b.Webhooks), "rules": len(b.Rules)})
+			}
+			fmt.Fprintf(os.Stdout, "Wrote %d webhooks and %d rules to %s\n", len(b.Webhooks), len(b.Rules), outPath)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&outPath, "out", "", "output file (default: stdout)")
+	cmd.Flags().BoolVar(&includeSecrets, "include-secrets", false, "include webhook signing secrets")
+	return cmd
+}
+
+func newConfigImportBundleCmd(flags *rootFlags) *cobra.Command {
+	var replace bool
+	cmd := &cobra.Command{
+		Use:   "import-bundle <file>",
+		Short: "Apply a bundle written by export-bundle (use - for stdin)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := readBundle(args[0])
+			if err != nil {
+				return err
+			}
+
+			ctx, cancel := withTimeout(context.Background(), flags)
+			defer cancel()
+
+			a, lk, err := newApp(ctx, flags, false, true)
+			if err != nil {
+				return err
+			}
+			defer closeApp(a, lk)
+
+			res, err := a.DB().ImportBundle(b, replace)
+			if err != nil {
+				return err
+			}
+			if flags.asJSON {
+				return out.WriteJSON(os.Stdout, res)
+			}
+			fmt.Fprintf(os.Stdout, "Imported %d webhooks and %d rules (%d unchanged)\n", res.Webhooks, res.Rules, res.Skipped)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&replace, "replace", false, "delete existing rules and webhooks first")
+	return cmd
+}
+
+func newConfigValidateCmd(flags *rootFlags) *cobra.Command {
+	var bundlePath string
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Check environment config, stored rules and webhooks (and optionally a bundle) without running anything",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var problems []string
+			add := func(errs []error) {
+				for _, err := range errs {
+					problems = append(problems, err.Error())
+				}
+			}
+			add(config.Load().Validate())
+
+			if bundlePath != "" {
+				b, err := readBundle(bundlePath)
+				if err != nil {
+					return err
+				}
+				add(app.ValidateBundle(b))
+			}
+
+			ctx, cancel := withTimeout(context.Background(), flags)
+			defer cancel()
+			a, lk, err := newApp(ctx, flags, false, true)
+			if err != nil {
+				return err
+			}
+			defer closeApp(a, lk)
+			errs, err := a.ValidateAutomation()
+			if err != nil {
+				return err
+			}
+			add(errs)
+
+			if flags.asJSON {
+				if err := out.WriteJSON(os.Stdout, map[string]any{"valid": len(problems) == 0, "problems": nonNilStrings(problems)}); err != nil {
+					return err
+				}
+			} else {
+				for _, p := range problems {
+					fmt.Fprintln(os.Stdout, "- "+p)
+				}
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("%d problems found", len(problems))
+			}
+			if !flags.asJSON {
+				fmt.Fprintln(os.Stdout, "OK")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&bundlePath, "bundle", "", "also check a bundle file written by export-bundle")
+	return cmd
+}
+
+// readBundle reads a bundle from path, or from stdin for "-".
+func readBundle(path string) (store.Bundle, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return store.Bundle{}, err
+	}
+	var b store.Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return store.Bundle{}, fmt.Errorf("parse bundle: %w", err)
+	}
+	return b, nil
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
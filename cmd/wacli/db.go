@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steipete/wacli/internal/out"
+)
+
+func newDBCmd(flags *rootFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Local database maintenance",
+	}
+	cmd.AddCommand(newDBRebuildFTSCmd(flags))
+	return cmd
+}
+
+func newDBRebuildFTSCmd(flags *rootFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rebuild-fts",
+		Short: "Rebuild the full-text search index",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			a, lk, err := newApp(ctx, flags, true, true)
+			if err != nil {
+				return err
+			}
+			defer closeApp(a, lk)
+
+			started := time.Now()
+			if err := a.RebuildFTS(ctx); err != nil {
+				return err
+			}
+			st := a.DB().FTSStatus()
+
+			if flags.asJSON {
+				return out.WriteJSON(os.Stdout, map[string]any{
+					"state":       st.State,
+					"messages":    st.Total,
+					"duration_ms": time.Since(started).Milliseconds(),
+				})
+			}
+			fmt.Fprintf(os.Stdout, "Indexed %d messages.\n", st.Total)
+			return nil
+		},
+	}
+}
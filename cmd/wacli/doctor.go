@@ -65,6 +65,8 @@ func newDoctorCmd(flags *rootFlags) *cobra.Command {
 				Authed     bool   `json:"authenticated"`
 				Connected  bool   `json:"connected"`
 				FTSEnabled bool   `json:"fts_enabled"`
+				FTSState   string `json:"fts_state"`
+				FTSError   string `json:"fts_error,omitempty"`
 			}
 
 			rep := report{
@@ -74,6 +76,8 @@ func newDoctorCmd(flags *rootFlags) *cobra.Command {
 				Authed:     authed,
 				Connected:  connected,
 				FTSEnabled: a.DB().HasFTS(),
+				FTSState:   a.DB().FTSStatus().State,
+				FTSError:   a.DB().FTSStatus().Error,
 			}
 
 			if flags.asJSON {
@@ -88,12 +92,18 @@ func newDoctorCmd(flags *rootFlags) *cobra.Command {
 			}
 			fmt.Fprintf(w, "AUTHENTICATED\t%v\n", rep.Authed)
 			fmt.Fprintf(w, "CONNECTED\t%v\n", rep.Connected)
-			fmt.Fprintf(w, "FTS5\t%v\n", rep.FTSEnabled)
+			fmt.Fprintf(w, "FTS5\t%v (%s)\n", rep.FTSEnabled, rep.FTSState)
+			if rep.FTSError != "" {
+				fmt.Fprintf(w, "FTS_ERROR\t%s\n", rep.FTSError)
+			}
 			_ = w.Flush()
 
 			if rep.LockHeld {
 				fmt.Fprintln(os.Stdout, "\nTip: stop the running `wacli sync` before running write operations.")
 			}
+			if a.DB().FTSNeedsRebuild() {
+				fmt.Fprintln(os.Stdout, "\nTip: run `wacli db rebuild-fts` to restore fast search.")
+			}
 			return nil
 		},
 	}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/mdp/qrterminal/v3"
+	"github.com/spf13/cobra"
+	"github.com/steipete/wacli/internal/out"
+)
+
+func newFailoverCmd(flags *rootFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "failover",
+		Short: "Pre-pair a standby session and promote it",
+	}
+	cmd.AddCommand(newFailoverPairCmd(flags))
+	cmd.AddCommand(newFailoverStatusCmd(flags))
+	cmd.AddCommand(newFailoverPromoteCmd(flags))
+	return cmd
+}
+
+func newFailoverPairCmd(flags *rootFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "pair",
+		Short: "Link a second device into the standby slot (QR)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			// The serving session keeps running, so no store lock.
+			a, lk, err := newApp(ctx, flags, false, true)
+			if err != nil {
+				return err
+			}
+			defer closeApp(a, lk)
+
+			fmt.Fprintln(os.Stderr, "Pairing standby session…")
+			jid, err := a.PairStandby(ctx, func(code string) {
+				fmt.Fprintln(os.Stderr, "\nScan this QR code with WhatsApp (Linked Devices):")
+				qrterminal.GenerateHalfBlock(code, qrterminal.M, os.Stderr)
+				fmt.Fprintln(os.Stderr)
+			})
+			if err != nil {
+				return err
+			}
+
+			if flags.asJSON {
+				return out.WriteJSON(os.Stdout, map[string]any{"paired": true, "jid": jid.String()})
+			}
+			fmt.Fprintf(os.Stdout, "Standby paired as %s. Run `wacli failover promote` to switch to it.\n", jid)
+			return nil
+		},
+	}
+}
+
+func newFailoverStatusCmd(flags *rootFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the serving and standby sessions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := withTimeout(context.Background(), flags)
+			defer cancel()
+
+			a, lk, err := newApp(ctx, flags, false, true)
+			if err != nil {
+				return err
+			}
+			defer closeApp(a, lk)
+
+			active, standby, err := a.SessionSlots()
+			if err != nil {
+				return err
+			}
+
+			if flags.asJSON {
+				return out.WriteJSON(os.Stdout, map[string]any{"active": active, "standby": standby})
+			}
+			w := tabwriter.NewWriter(os.Stdout, 2, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "SLOT\tPAIRED\tJID\tPATH")
+			for _, s := range []struct {
+				name string
+				jid  string
+				path string
+			}{{"active", active.JID, active.Path}, {"standby", standby.JID, standby.Path}} {
+				fmt.Fprintf(w, "%s\t%v\t%s\t%s\n", s.name, s.jid != "", s.jid, s.path)
+			}
+			_ = w.Flush()
+			return nil
+		},
+	}
+}
+
+func newFailoverPromoteCmd(flags *rootFlags) *cobra.Command {
+	var logoutOld bool
+	cmd := &cobra.Command{
+		Use:   "promote",
+		Short: "Make the standby session the serving one (stop wacli-api first)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := withTimeout(context.Background(), flags)
+			defer cancel()
+
+			a, lk, err := newApp(ctx, flags, true, true)
+			if err != nil {
+				return err
+			}
+			defer closeApp(a, lk)
+
+			res, err := a.PromoteStandby(ctx, logoutOld)
+			if err != nil {
+				return err
+			}
+
+			if flags.asJSON {
+				return out.WriteJSON(os.Stdout, res)
+			}
+			fmt.Fprintf(os.Stdout, "Promoted standby %s. Start wacli-api again to serve from it.\n", res.JID)
+			switch {
+			case res.LoggedOut:
+				fmt.Fprintf(os.Stdout, "Previous session %s logged out.\n", res.RetiredJID)
+			case res.RetiredPath != "":
+				fmt.Fprintf(os.Stdout, "Previous session kept at %s (still linked; unlink it in WhatsApp when done).\n", res.RetiredPath)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&logoutOld, "logout-old", false, "unlink the previous session instead of keeping it for rollback")
+	return cmd
+}
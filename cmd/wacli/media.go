@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"time"
@@ -67,7 +68,7 @@ func newMediaDownloadCmd(flags *rootFlags) *cobra.Command {
 				return err
 			}
 			now := time.Now().UTC()
-			_ = a.DB().MarkMediaDownloaded(info.ChatJID, info.MsgID, target, now)
+			_ = a.DB().MarkMediaDownloaded(info.ChatJID, info.MsgID, target, hex.EncodeToString(info.FileSHA256), now)
 
 			resp := map[string]any{
 				"chat":          info.ChatJID,
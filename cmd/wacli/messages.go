@@ -30,6 +30,7 @@ func newMessagesListCmd(flags *rootFlags) *cobra.Command {
 	var limit int
 	var afterStr string
 	var beforeStr string
+	var system string
 
 	cmd := &cobra.Command{
 		Use:   "list",
@@ -66,6 +67,7 @@ func newMessagesListCmd(flags *rootFlags) *cobra.Command {
 				Limit:   limit,
 				After:   after,
 				Before:  before,
+				System:  system,
 			})
 			if err != nil {
 				return err
@@ -113,6 +115,7 @@ func newMessagesListCmd(flags *rootFlags) *cobra.Command {
 	cmd.Flags().IntVar(&limit, "limit", 50, "limit results")
 	cmd.Flags().StringVar(&afterStr, "after", "", "only messages after time (RFC3339 or YYYY-MM-DD)")
 	cmd.Flags().StringVar(&beforeStr, "before", "", "only messages before time (RFC3339 or YYYY-MM-DD)")
+	cmd.Flags().StringVar(&system, "system", store.SystemInclude, "system notices (security code, disappearing timer, group created): include|exclude|only")
 	return cmd
 }
 
@@ -202,7 +205,9 @@ func newMessagesSearchCmd(flags *rootFlags) *cobra.Command {
 				)
 			}
 			_ = w.Flush()
-			if !a.DB().HasFTS() {
+			if a.DB().FTSNeedsRebuild() {
+				fmt.Fprintln(os.Stderr, "Note: search index needs a rebuild; using LIKE (slow). Run `wacli db rebuild-fts`.")
+			} else if !a.DB().HasFTS() {
 				fmt.Fprintln(os.Stderr, "Note: FTS5 not enabled; search is using LIKE (slow).")
 			}
 			return nil
@@ -21,6 +21,8 @@ type rootFlags struct {
 	storeDir string
 	asJSON   bool
 	timeout  time.Duration
+	// sandboxTo redirects every outgoing message to this test number.
+	sandboxTo string
 }
 
 func execute(args []string) error {
@@ -37,6 +39,7 @@ func execute(args []string) error {
 	rootCmd.PersistentFlags().StringVar(&flags.storeDir, "store", "", "store directory (default: ~/.wacli)")
 	rootCmd.PersistentFlags().BoolVar(&flags.asJSON, "json", false, "output JSON instead of human-readable text")
 	rootCmd.PersistentFlags().DurationVar(&flags.timeout, "timeout", 5*time.Minute, "command timeout (non-sync commands)")
+	rootCmd.PersistentFlags().StringVar(&flags.sandboxTo, "sandbox-to", "", "redirect every outgoing message to this test number")
 
 	rootCmd.AddCommand(newVersionCmd())
 	rootCmd.AddCommand(newDoctorCmd(&flags))
@@ -49,6 +52,11 @@ func execute(args []string) error {
 	rootCmd.AddCommand(newChatsCmd(&flags))
 	rootCmd.AddCommand(newGroupsCmd(&flags))
 	rootCmd.AddCommand(newHistoryCmd(&flags))
+	rootCmd.AddCommand(newConfigCmd(&flags))
+	rootCmd.AddCommand(newFailoverCmd(&flags))
+	rootCmd.AddCommand(newSessionCmd(&flags))
+	rootCmd.AddCommand(newDBCmd(&flags))
+	rootCmd.AddCommand(newVerifyArchiveCmd(&flags))
 
 	rootCmd.SetArgs(args)
 	if err := rootCmd.Execute(); err != nil {
@@ -79,6 +87,7 @@ func newApp(ctx context.Context, flags *rootFlags, needLock bool, allowUnauthed
 		Version:       version,
 		JSON:          flags.asJSON,
 		AllowUnauthed: allowUnauthed,
+		SandboxTo:     flags.sandboxTo,
 	})
 	if err != nil {
 		if lk != nil {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"mime"
 	"net/http"
 	"os"
@@ -20,7 +21,7 @@ import (
 func sendFile(ctx context.Context, a interface {
 	WA() app.WAClient
 	DB() *store.DB
-}, to types.JID, filePath, filename, caption, mimeOverride string) (string, map[string]string, error) {
+}, to types.JID, filePath, filename, caption, mimeOverride string, resize app.ImageLimits, gifFFmpeg string) (string, map[string]string, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return "", nil, err
@@ -43,6 +44,15 @@ func sendFile(ctx context.Context, a interface {
 		mimeType = http.DetectContentType(sniff)
 	}
 
+	gifVideo := false
+	if gifFFmpeg != "" && mimeType == "image/gif" {
+		video, err := app.GIFToMP4(ctx, gifFFmpeg, data)
+		if err != nil {
+			return "", nil, fmt.Errorf("convert gif: %w", err)
+		}
+		data, mimeType, gifVideo = video, "video/mp4", true
+	}
+
 	mediaType := "document"
 	uploadType, _ := wa.MediaTypeFromString("document")
 	switch {
@@ -57,6 +67,17 @@ func sendFile(ctx context.Context, a interface {
 		uploadType, _ = wa.MediaTypeFromString("audio")
 	}
 
+	resized := false
+	if mediaType == "image" {
+		small, ok, err := app.ShrinkImage(data, resize)
+		if err != nil {
+			return "", nil, fmt.Errorf("resize image: %w", err)
+		}
+		if ok {
+			data, mimeType, resized = small, "image/jpeg", true
+		}
+	}
+
 	up, err := a.WA().Upload(ctx, data, uploadType)
 	if err != nil {
 		return "", nil, err
@@ -87,6 +108,7 @@ func sendFile(ctx context.Context, a interface {
 			FileLength:    proto.Uint64(up.FileLength),
 			Mimetype:      proto.String(mimeType),
 			Caption:       proto.String(caption),
+			GifPlayback:   proto.Bool(gifVideo),
 		}
 	case "audio":
 		msg.AudioMessage = &waProto.AudioMessage{
@@ -142,11 +164,18 @@ func sendFile(ctx context.Context, a interface {
 		FileLength:    up.FileLength,
 	})
 
-	return id, map[string]string{
+	meta := map[string]string{
 		"name":      name,
 		"mime_type": mimeType,
 		"media":     mediaType,
-	}, nil
+	}
+	if resized {
+		meta["resized"] = "true"
+	}
+	if gifVideo {
+		meta["gif"] = "true"
+	}
+	return id, meta, nil
 }
 
 func chatKindFromJID(j types.JID) string {
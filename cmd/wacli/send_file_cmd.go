@@ -6,6 +6,8 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/config"
 	"github.com/steipete/wacli/internal/out"
 	"github.com/steipete/wacli/internal/wa"
 )
@@ -16,6 +18,9 @@ func newSendFileCmd(flags *rootFlags) *cobra.Command {
 	var filename string
 	var caption string
 	var mimeOverride string
+	var resize bool
+	var maxDimension int
+	var gif bool
 
 	cmd := &cobra.Command{
 		Use:   "file",
@@ -46,7 +51,20 @@ func newSendFileCmd(flags *rootFlags) *cobra.Command {
 				return err
 			}
 
-			msgID, meta, err := sendFile(ctx, a, toJID, filePath, filename, caption, mimeOverride)
+			var limits app.ImageLimits
+			if resize {
+				limits = app.DefaultImageLimits
+				if maxDimension > 0 {
+					limits.MaxDimension = maxDimension
+				}
+			}
+
+			gifFFmpeg := ""
+			if gif {
+				gifFFmpeg = config.Load().AI.FFmpegPath
+			}
+
+			msgID, meta, err := sendFile(ctx, a, toJID, filePath, filename, caption, mimeOverride, limits, gifFFmpeg)
 			if err != nil {
 				return err
 			}
@@ -69,5 +87,8 @@ func newSendFileCmd(flags *rootFlags) *cobra.Command {
 	cmd.Flags().StringVar(&filename, "filename", "", "display name for the file (defaults to basename of --file)")
 	cmd.Flags().StringVar(&caption, "caption", "", "caption (images/videos/documents)")
 	cmd.Flags().StringVar(&mimeOverride, "mime", "", "override detected mime type")
+	cmd.Flags().BoolVar(&resize, "resize", false, "downscale images over 4096px or 5MB to JPEG before upload")
+	cmd.Flags().IntVar(&maxDimension, "max-dimension", 0, "longest side in pixels when resizing (default 4096)")
+	cmd.Flags().BoolVar(&gif, "gif", false, "send a .gif as a looping video (converted to MP4 with ffmpeg, see WACLI_FFMPEG_PATH)")
 	return cmd
 }
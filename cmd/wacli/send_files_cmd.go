@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/out"
+	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// captionData is what --caption-template can use.
+type captionData struct {
+	Name    string // file name, e.g. "sales-2024-05-01.pdf"
+	Stem    string // file name without extension
+	Path    string
+	Index   int // 1-based position in the batch
+	Total   int
+	ModTime time.Time
+}
+
+type sentFile struct {
+	File  string `json:"file"`
+	To    string `json:"to"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+func newSendFilesCmd(flags *rootFlags) *cobra.Command {
+	var to []string
+	var dir string
+	var glob string
+	var captionTemplate string
+	var delay time.Duration
+	var stopOnError bool
+	var dryRun bool
+	var resize bool
+
+	cmd := &cobra.Command{
+		Use:   "files",
+		Short: "Send every file in a directory matching a pattern, one after another",
+		Long: "Send every file in --dir matching --glob to each --to recipient, in name order, waiting --delay\n" +
+			"between sends. Prints a summary and exits non-zero if any send failed. --timeout applies\n" +
+			"to each file, not the whole batch.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(to) == 0 || dir == "" {
+				return fmt.Errorf("--to and --dir are required")
+			}
+			if delay < 0 {
+				return fmt.Errorf("--delay must not be negative")
+			}
+			var recipients []types.JID
+			for _, r := range to {
+				jid, err := wa.ParseUserOrJID(r)
+				if err != nil {
+					return fmt.Errorf("invalid recipient %q: %w", r, err)
+				}
+				recipients = append(recipients, jid)
+			}
+			tmpl, err := template.New("caption").Option("missingkey=error").Parse(captionTemplate)
+			if err != nil {
+				return fmt.Errorf("invalid --caption-template: %w", err)
+			}
+			files, err := matchFiles(dir, glob)
+			if err != nil {
+				return err
+			}
+			if len(files) == 0 {
+				return fmt.Errorf("no files in %s match %q", dir, glob)
+			}
+
+			captions := make([]string, len(files))
+			for i, f := range files {
+				data := captionData{Name: filepath.Base(f), Path: f, Index: i + 1, Total: len(files)}
+				data.Stem = strings.TrimSuffix(data.Name, filepath.Ext(data.Name))
+				if st, err := os.Stat(f); err == nil {
+					data.ModTime = st.ModTime()
+				}
+				var sb strings.Builder
+				if err := tmpl.Execute(&sb, data); err != nil {
+					return fmt.Errorf("caption for %s: %w", data.Name, err)
+				}
+				captions[i] = sb.String()
+			}
+
+			if dryRun {
+				var plan []map[string]string
+				for i, f := range files {
+					plan = append(plan, map[string]string{"file": f, "caption": captions[i]})
+				}
+				if flags.asJSON {
+					return out.WriteJSON(os.Stdout, map[string]any{"dry_run": true, "files": plan})
+				}
+				for _, p := range plan {
+					fmt.Fprintf(os.Stdout, "%s\t%s\n", p["file"], p["caption"])
+				}
+				fmt.Fprintf(os.Stdout, "%d files to %d recipients (dry run, nothing sent)\n", len(files), len(recipients))
+				return nil
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			connectCtx, connectCancel := withTimeout(ctx, flags)
+			defer connectCancel()
+
+			a, lk, err := newApp(connectCtx, flags, true, false)
+			if err != nil {
+				return err
+			}
+			defer closeApp(a, lk)
+
+			if err := a.EnsureAuthed(); err != nil {
+				return err
+			}
+			if err := a.Connect(connectCtx, false, nil); err != nil {
+				return err
+			}
+
+			var limits app.ImageLimits
+			if resize {
+				limits = app.DefaultImageLimits
+			}
+
+			started := time.Now()
+			var results []sentFile
+			failed := 0
+		batch:
+			for i, f := range files {
+				for _, rcpt := range recipients {
+					if len(results) > 0 && delay > 0 {
+						time.Sleep(delay)
+					}
+					fileCtx, fileCancel := withTimeout(ctx, flags)
+					id, _, err := sendFile(fileCtx, a, rcpt, f, "", captions[i], "", limits, "")
+					fileCancel()
+					r := sentFile{File: f, To: rcpt.String(), ID: id}
+					if err != nil {
+						r.Error = err.Error()
+						failed++
+					}
+					results = append(results, r)
+					if !flags.asJSON {
+						if err != nil {
+							fmt.Fprintf(os.Stderr, "FAIL %s -> %s: %v\n", filepath.Base(f), r.To, err)
+						} else {
+							fmt.Fprintf(os.Stdout, "sent %s -> %s (id %s)\n", filepath.Base(f), r.To, id)
+						}
+					}
+					if err != nil && stopOnError {
+						break batch
+					}
+				}
+			}
+
+			elapsed := time.Since(started).Round(time.Second)
+			if flags.asJSON {
+				if err := out.WriteJSON(os.Stdout, map[string]any{
+					"files":      len(files),
+					"recipients": len(recipients),
+					"sent":       len(results) - failed,
+					"failed":     failed,
+					"skipped":    len(files)*len(recipients) - len(results),
+					"elapsed":    elapsed.String(),
+					"results":    results,
+				}); err != nil {
+					return err
+				}
+			} else {
+				fmt.Fprintf(os.Stdout, "%d sent, %d failed, %d skipped in %s\n", len(results)-failed, failed, len(files)*len(recipients)-len(results), elapsed)
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d sends failed", failed, len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&to, "to", nil, "recipient phone number or JID (repeat or comma-separate for several)")
+	cmd.Flags().StringVar(&dir, "dir", "", "directory with the files to send")
+	cmd.Flags().StringVar(&glob, "glob", "*", "file name pattern, e.g. \"*.pdf\"")
+	cmd.Flags().StringVar(&captionTemplate, "caption-template", "", "caption as a Go template with {{.Name}}, {{.Stem}}, {{.Index}}, {{.Total}} and {{.ModTime}}")
+	cmd.Flags().DurationVar(&delay, "delay", 3*time.Second, "pause between sends, to stay under WhatsApp's rate limits")
+	cmd.Flags().BoolVar(&stopOnError, "stop-on-error", false, "stop at the first failed send instead of continuing")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "list the files and captions without connecting or sending")
+	cmd.Flags().BoolVar(&resize, "resize", false, "downscale images over 4096px or 5MB to JPEG before upload")
+	return cmd
+}
+
+// matchFiles returns the regular files directly in dir whose name matches
+// pattern, sorted by name. Hidden files are skipped.
+func matchFiles(dir, pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid --glob: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") || !e.Type().IsRegular() {
+			continue
+		}
+		if ok, _ := filepath.Match(pattern, name); ok {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
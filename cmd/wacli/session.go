@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/out"
+	"golang.org/x/term"
+)
+
+const passphraseEnv = "WACLI_SESSION_PASSPHRASE"
+
+func newSessionCmd(flags *rootFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "session",
+		Short: "Move the paired session to another host",
+	}
+	cmd.AddCommand(newSessionExportCmd(flags))
+	cmd.AddCommand(newSessionImportCmd(flags))
+	return cmd
+}
+
+func newSessionExportCmd(flags *rootFlags) *cobra.Command {
+	var includeStore bool
+	cmd := &cobra.Command{
+		Use:   "export <file>",
+		Short: "Write the session (and optionally messages) to an encrypted archive",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			passphrase, err := readPassphrase(true)
+			if err != nil {
+				return err
+			}
+
+			// Both databases are snapshotted, so the server may keep running.
+			a, lk, err := newApp(ctx, flags, false, true)
+			if err != nil {
+				return err
+			}
+			defer closeApp(a, lk)
+
+			f, err := os.OpenFile(args[0], os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+			if err != nil {
+				return err
+			}
+			m, err := a.ExportSession(ctx, f, passphrase, includeStore)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				_ = os.Remove(args[0])
+				return err
+			}
+
+			if flags.asJSON {
+				return out.WriteJSON(os.Stdout, map[string]any{"path": args[0], "manifest": m})
+			}
+			fmt.Fprintf(os.Stdout, "Exported %s to %s.\n", m.JID, args[0])
+			fmt.Fprintln(os.Stdout, "Stop wacli on this host before the session goes live elsewhere; two hosts on one session will fight over it.")
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&includeStore, "include-store", false, "also export the message store")
+	return cmd
+}
+
+func newSessionImportCmd(flags *rootFlags) *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Restore a session archive written by `session export` (stop wacli-api first)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			passphrase, err := readPassphrase(false)
+			if err != nil {
+				return err
+			}
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			a, lk, err := newApp(ctx, flags, true, true)
+			if err != nil {
+				return err
+			}
+			defer closeApp(nil, lk)
+
+			m, err := a.ImportSession(ctx, f, passphrase, force)
+			a.Close()
+			if err != nil {
+				return err
+			}
+			if _, err := app.ApplyStagedImport(a.StoreDir()); err != nil {
+				return err
+			}
+
+			if flags.asJSON {
+				return out.WriteJSON(os.Stdout, map[string]any{"imported": true, "manifest": m})
+			}
+			fmt.Fprintf(os.Stdout, "Imported session %s", m.JID)
+			if m.IncludesStore {
+				fmt.Fprint(os.Stdout, " with its message store")
+			}
+			fmt.Fprintln(os.Stdout, ". Replaced databases were kept as *.retired-<timestamp>.")
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "replace a session that is already paired")
+	return cmd
+}
+
+// readPassphrase takes the archive passphrase from WACLI_SESSION_PASSPHRASE,
+// or asks for it on the terminal, twice when confirm is set.
+func readPassphrase(confirm bool) (string, error) {
+	if p := os.Getenv(passphraseEnv); p != "" {
+		return p, nil
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("set %s or run in a terminal to enter the passphrase", passphraseEnv)
+	}
+	fmt.Fprint(os.Stderr, "Passphrase: ")
+	p, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+		again, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		if string(again) != string(p) {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+	return string(p), nil
+}
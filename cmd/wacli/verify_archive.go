@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/steipete/wacli/internal/out"
+	"github.com/steipete/wacli/internal/store"
+)
+
+// maxListedIssues bounds the modified/missing messages printed as text.
+const maxListedIssues = 20
+
+func newVerifyArchiveCmd(flags *rootFlags) *cobra.Command {
+	var seal bool
+	var anchor string
+
+	cmd := &cobra.Command{
+		Use:   "verify-archive",
+		Short: "Check that stored messages were not modified since ingestion",
+		Long: `Checks the archive's hash chain and compares every stored message with its
+last chained version. Exits with an error if the chain is broken, a message
+was modified or removed outside wacli, or a message is not chained.
+
+Record the printed head (entry number and hash) somewhere safe; passing it
+back with --anchor later proves the chain was not rebuilt in between.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var anchorSeq int64
+			var anchorHash string
+			if anchor != "" {
+				seqStr, hash, ok := strings.Cut(anchor, ":")
+				seq, err := strconv.ParseInt(seqStr, 10, 64)
+				if !ok || err != nil || seq <= 0 || hash == "" {
+					return fmt.Errorf("--anchor must be SEQ:HASH as printed by verify-archive")
+				}
+				anchorSeq, anchorHash = seq, hash
+			}
+
+			ctx, cancel := withTimeout(context.Background(), flags)
+			defer cancel()
+
+			a, lk, err := newApp(ctx, flags, seal, true)
+			if err != nil {
+				return err
+			}
+			defer closeApp(a, lk)
+
+			var sealed int64
+			if seal {
+				if sealed, err = a.DB().SealArchive(); err != nil {
+					return err
+				}
+			}
+			r, err := a.DB().VerifyArchive()
+			if err != nil {
+				return err
+			}
+
+			anchorOK := true
+			if anchorSeq > 0 {
+				hash, err := a.DB().ArchiveEntryHash(anchorSeq)
+				if err != nil && !errors.Is(err, sql.ErrNoRows) {
+					return err
+				}
+				anchorOK = hash == anchorHash
+			}
+			ok := r.OK() && anchorOK
+
+			if flags.asJSON {
+				res := map[string]any{"ok": ok, "report": r}
+				if seal {
+					res["sealed"] = sealed
+				}
+				if anchorSeq > 0 {
+					res["anchor_ok"] = anchorOK
+				}
+				if err := out.WriteJSON(os.Stdout, res); err != nil {
+					return err
+				}
+			} else {
+				printArchiveReport(r, sealed, seal)
+				if anchorSeq > 0 {
+					if anchorOK {
+						fmt.Fprintf(os.Stdout, "Anchor #%d: matches\n", anchorSeq)
+					} else {
+						fmt.Fprintf(os.Stdout, "Anchor #%d: DOES NOT MATCH, the chain was rebuilt\n", anchorSeq)
+					}
+				}
+			}
+			if !ok {
+				return fmt.Errorf("archive verification failed")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&seal, "seal", false, "first chain messages that have no entry yet (e.g. stored before the chain existed)")
+	cmd.Flags().StringVar(&anchor, "anchor", "", "check a previously recorded head, as SEQ:HASH")
+	return cmd
+}
+
+func printArchiveReport(r store.ArchiveReport, sealed int64, seal bool) {
+	if seal {
+		fmt.Fprintf(os.Stdout, "Sealed %d messages.\n", sealed)
+	}
+	if r.BrokenAt > 0 {
+		fmt.Fprintf(os.Stdout, "Chain: %d entries, BROKEN at entry #%d\n", r.Entries, r.BrokenAt)
+	} else {
+		fmt.Fprintf(os.Stdout, "Chain: %d entries, intact\n", r.Entries)
+	}
+	if r.Entries > 0 {
+		fmt.Fprintf(os.Stdout, "Head: %d:%s\n", r.HeadSeq, r.Head)
+	}
+	fmt.Fprintf(os.Stdout, "Messages: %d checked, %d verified\n", r.Messages, r.Verified)
+	for _, list := range []struct {
+		name   string
+		issues []store.ArchiveIssue
+	}{{"Modified", r.Modified}, {"Missing", r.Missing}} {
+		if len(list.issues) == 0 {
+			continue
+		}
+		fmt.Fprintf(os.Stdout, "%s: %d\n", list.name, len(list.issues))
+		for i, issue := range list.issues {
+			if i == maxListedIssues {
+				fmt.Fprintf(os.Stdout, "  ... and %d more (use --json for all)\n", len(list.issues)-i)
+				break
+			}
+			fmt.Fprintf(os.Stdout, "  %s %s (entry #%d)\n", issue.ChatJID, issue.MsgID, issue.Seq)
+		}
+	}
+	if r.Unchained > 0 {
+		fmt.Fprintf(os.Stdout, "Unchained: %d (not covered by the chain; --seal adds them once checked)\n", r.Unchained)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/steipete/wacli/internal/config"
@@ -88,42 +89,89 @@ func TranscribeAudio(audioData []byte, apiKey string) (string, error) {
 func HandleMessages(ctx context.Context, client *whatsmeow.Client, evt interface{}, cfg *config.Config) {
 	switch v := evt.(type) {
 	case *events.Message:
-		if v.Message.GetAudioMessage() != nil {
+		if !cfg.AI.Privacy.Allowed(v.Info.Chat.String()) {
+			return
+		}
+		if audio := v.Message.GetAudioMessage(); audio != nil {
 			fmt.Println("🎙️ Received voice note from", v.Info.Sender.String())
 			// Download audio
-			audioData, err := client.Download(ctx, v.Message.GetAudioMessage())
+			audioData, err := client.Download(ctx, audio)
 			if err != nil {
 				fmt.Println("Error downloading audio:", err)
 				return
 			}
 
-			// Call Groq transcription
+			transcribeAndReply(ctx, client, v, audioData, audio.GetSeconds(), "🎙️ *Transcrição do áudio:*", cfg)
+			return
+		}
 
-			transcript, err := TranscribeAudio(audioData, cfg.AI.GroqAPIKey)
+		if video := v.Message.GetVideoMessage(); video != nil && cfg.AI.TranscribeVideo {
+			fmt.Println("🎬 Received video from", v.Info.Sender.String())
+			videoData, err := client.Download(ctx, video)
 			if err != nil {
-				fmt.Println("❌ Transcription error:", err)
+				fmt.Println("Error downloading video:", err)
 				return
 			}
 
-			// Build reply
-
-			messageText := fmt.Sprintf("🎙️ *Transcrição do áudio:*\n\n\"%s\"\n\n_Powered by Cris AI 🤖_", transcript)
-			quotedInfo := &waProto.ContextInfo{
-				QuotedMessage: v.Message,
-				Participant:   proto.String(v.Info.Sender.String()),
-				StanzaID:      proto.String(v.Info.ID),
+			// Extract the audio track with ffmpeg
+			audioData, err := ExtractAudio(ctx, cfg.AI.FFmpegPath, videoData)
+			if err != nil {
+				fmt.Println("❌ Audio extraction error:", err)
+				return
 			}
 
-			_, err = client.SendMessage(ctx, v.Info.Sender, &waProto.Message{
-				ExtendedTextMessage: &waProto.ExtendedTextMessage{
-					Text:        proto.String(messageText),
-					ContextInfo: quotedInfo,
-				},
-			})
+			transcribeAndReply(ctx, client, v, audioData, video.GetSeconds(), "🎬 *Transcrição do vídeo:*", cfg)
+		}
+	}
+}
+
+// transcribeAndReply transcribes audio and replies to the sender, quoting the
+// original message. The reply is picked up by sync like any other message.
+func transcribeAndReply(ctx context.Context, client *whatsmeow.Client, v *events.Message, audioData []byte, seconds uint32, header string, cfg *config.Config) {
+	// Call Groq transcription
 
-			if err != nil {
-				fmt.Println("Error sending message:", err)
-			}
+	transcript, err := TranscribeAudio(audioData, cfg.AI.GroqAPIKey)
+	if err != nil {
+		fmt.Println("❌ Transcription error:", err)
+		return
+	}
+	if strings.TrimSpace(transcript) == "" {
+		fmt.Println("Empty transcript, skipping reply")
+		return
+	}
+
+	// Long recordings also get a TL;DR on top of the transcript
+
+	summary := ""
+	minSeconds := cfg.AI.SummaryMinSeconds
+	if minSeconds > 0 && int(seconds) >= minSeconds {
+		summary, err = Summarize(ctx, cfg.AI.Privacy.Redact(transcript, v.Info.PushName), cfg.AI.GroqAPIKey, cfg.AI.ChatModel)
+		if err != nil {
+			fmt.Println("❌ Summary error:", err)
+			summary = ""
 		}
 	}
+
+	// Build reply
+
+	messageText := fmt.Sprintf("%s\n\n\"%s\"\n\n_Powered by Cris AI 🤖_", header, transcript)
+	if summary != "" {
+		messageText = fmt.Sprintf("📝 *Resumo (TL;DR):*\n%s\n\n%s", summary, messageText)
+	}
+	quotedInfo := &waProto.ContextInfo{
+		QuotedMessage: v.Message,
+		Participant:   proto.String(v.Info.Sender.String()),
+		StanzaID:      proto.String(v.Info.ID),
+	}
+
+	_, err = client.SendMessage(ctx, v.Info.Sender, &waProto.Message{
+		ExtendedTextMessage: &waProto.ExtendedTextMessage{
+			Text:        proto.String(messageText),
+			ContextInfo: quotedInfo,
+		},
+	})
+
+	if err != nil {
+		fmt.Println("Error sending message:", err)
+	}
 }
@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	groqChatURL      = "https://api.groq.com/openai/v1/chat/completions"
+	DefaultChatModel = "llama-3.1-8b-instant"
+)
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// Complete runs a single-turn chat completion against Groq's OpenAI-compatible API.
+func Complete(ctx context.Context, apiKey, model, system, user string) (string, error) {
+	if strings.TrimSpace(apiKey) == "" {
+		return "", fmt.Errorf("groq api key is required")
+	}
+	if strings.TrimSpace(model) == "" {
+		model = DefaultChatModel
+	}
+
+	payload, err := json.Marshal(chatRequest{
+		Model: model,
+		Messages: []chatMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: user},
+		},
+		Temperature: 0.2,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", groqChatURL, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errMsg, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("API error %d: %s", resp.StatusCode, string(errMsg))
+	}
+
+	var out chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(out.Choices) == 0 {
+		return "", fmt.Errorf("empty completion response")
+	}
+	return strings.TrimSpace(out.Choices[0].Message.Content), nil
+}
+
+// Summarize returns a short TL;DR for a voice note transcript.
+func Summarize(ctx context.Context, transcript, apiKey, model string) (string, error) {
+	system := "You summarize WhatsApp voice note transcripts. Reply with a TL;DR of at most three short sentences, in the same language as the transcript. Do not add any preamble."
+	return Complete(ctx, apiKey, model, system, transcript)
+}
@@ -0,0 +1,47 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Sentiment is the classification of a single inbound message.
+type Sentiment struct {
+	Label   string  `json:"sentiment"` // positive|neutral|negative
+	Score   float64 `json:"score"`     // -1 .. 1
+	Urgency float64 `json:"urgency"`   // 0 .. 1
+}
+
+const sentimentPrompt = `You classify customer messages received on WhatsApp for a support inbox.
+Reply with a single JSON object and nothing else:
+{"sentiment": "positive" | "neutral" | "negative", "score": <number from -1 (angry) to 1 (happy)>, "urgency": <number from 0 (can wait) to 1 (needs an answer now)>}`
+
+// ScoreSentiment asks the chat model to rate the sentiment and urgency of text.
+func ScoreSentiment(ctx context.Context, text, apiKey, model string) (Sentiment, error) {
+	raw, err := Complete(ctx, apiKey, model, sentimentPrompt, text)
+	if err != nil {
+		return Sentiment{}, err
+	}
+	return parseSentiment(raw)
+}
+
+func parseSentiment(raw string) (Sentiment, error) {
+	start := strings.Index(raw, "{")
+	end := strings.LastIndex(raw, "}")
+	if start < 0 || end <= start {
+		return Sentiment{}, fmt.Errorf("no JSON object in sentiment response: %q", raw)
+	}
+	var s Sentiment
+	if err := json.Unmarshal([]byte(raw[start:end+1]), &s); err != nil {
+		return Sentiment{}, fmt.Errorf("failed to decode sentiment: %w", err)
+	}
+	s.Label = strings.ToLower(strings.TrimSpace(s.Label))
+	switch s.Label {
+	case "positive", "neutral", "negative":
+	default:
+		return Sentiment{}, fmt.Errorf("unknown sentiment label %q", s.Label)
+	}
+	return s, nil
+}
@@ -0,0 +1,61 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ExtractAudio pulls the audio track out of a video and re-encodes it as a
+// mono Opus/Ogg stream suitable for transcription. ffmpeg needs a seekable
+// input for MP4 files, so the video is written to a temp file first.
+func ExtractAudio(ctx context.Context, ffmpegPath string, video []byte) ([]byte, error) {
+	if strings.TrimSpace(ffmpegPath) == "" {
+		ffmpegPath = "ffmpeg"
+	}
+	if _, err := exec.LookPath(ffmpegPath); err != nil {
+		return nil, fmt.Errorf("ffmpeg not found: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "wacli-video-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	inPath := filepath.Join(dir, "input.mp4")
+	outPath := filepath.Join(dir, "audio.ogg")
+	if err := os.WriteFile(inPath, video, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write video: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, ffmpegPath,
+		"-hide_banner", "-loglevel", "error", "-y",
+		"-i", inPath,
+		"-vn", "-ac", "1", "-ar", "16000",
+		"-c:a", "libopus", "-b:a", "32k",
+		outPath,
+	)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	audio, err := os.ReadFile(outPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read extracted audio: %w", err)
+	}
+	if len(audio) == 0 {
+		return nil, fmt.Errorf("video has no audio track")
+	}
+	return audio, nil
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Client certificate modes, set by WACLI_API_TLS_CLIENT_AUTH when
+// WACLI_API_TLS_CLIENT_CA is set.
+const (
+	// ClientCertRequire requires a client certificate on every connection,
+	// in addition to an API key on every request.
+	ClientCertRequire = "require"
+	// ClientCertOnly requires a client certificate, which replaces the API
+	// key.
+	ClientCertOnly = "cert-only"
+	// ClientCertOptional verifies a certificate if the client presents one
+	// and lets it replace the API key; clients without one use API keys.
+	ClientCertOptional = "optional"
+)
+
+// clientCertContextKey holds the subject common name of a request's
+// verified client certificate.
+const clientCertContextKey = "wacli.client_cert"
+
+// clientCertHashPrefix marks the key hash of requests authenticated by
+// certificate, so queued webhooks from them can be replayed.
+const clientCertHashPrefix = "cert:"
+
+func (c *Config) clientCertMode() string {
+	if c.TLSClientAuth == "" {
+		return ClientCertRequire
+	}
+	return c.TLSClientAuth
+}
+
+// certAuthenticates reports whether a verified client certificate
+// authenticates requests without an API key.
+func (c *Config) certAuthenticates() bool {
+	return c.TLSClientCA != "" && c.clientCertMode() != ClientCertRequire
+}
+
+// ServerTLSConfig returns the TLS settings of the API listener: nil without
+// a client CA, else one that verifies client certificates against it.
+func (c *Config) ServerTLSConfig() (*tls.Config, error) {
+	if c.TLSClientCA == "" {
+		return nil, nil
+	}
+	pool, err := loadClientCAs(c.TLSClientCA)
+	if err != nil {
+		return nil, err
+	}
+	auth := tls.RequireAndVerifyClientCert
+	if c.clientCertMode() == ClientCertOptional {
+		auth = tls.VerifyClientCertIfGiven
+	}
+	return &tls.Config{ClientCAs: pool, ClientAuth: auth, MinVersion: tls.VersionTLS12}, nil
+}
+
+func loadClientCAs(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%s contains no PEM certificates", path)
+	}
+	return pool, nil
+}
+
+// clientCertAuth checks the request's client certificate before next, the
+// API key check. In ClientCertOnly and ClientCertOptional mode a verified
+// certificate authenticates a request without an API key on its own.
+// Requests forwarded by proxy frontends carry the client's certificate, which
+// the primary has verified against its own CAs like a direct one.
+func clientCertAuth(cfg *Config, next gin.HandlerFunc) gin.HandlerFunc {
+	if cfg.TLSClientCA == "" {
+		return next
+	}
+	return func(c *gin.Context) {
+		if hash, ok := c.Request.Context().Value(inboxReplayKeyHash{}).(string); ok {
+			if cfg.certAuthenticates() && strings.HasPrefix(hash, clientCertHashPrefix) {
+				c.Set(apiKeyHashContextKey, hash)
+				c.Next()
+				return
+			}
+			next(c)
+			return
+		}
+		cert := verifiedClientCert(c.Request)
+		if cert == nil {
+			if cfg.clientCertMode() != ClientCertOptional {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "a client certificate signed by the configured CA is required"})
+				return
+			}
+			next(c)
+			return
+		}
+		c.Set(clientCertContextKey, cert.Subject.CommonName)
+		// A key sent along is still checked, so its footer and rate limit
+		// apply.
+		if !cfg.certAuthenticates() || requestAPIKey(c) != "" {
+			next(c)
+			return
+		}
+		c.Set(apiKeyHashContextKey, clientCertHash(cert))
+		c.Next()
+	}
+}
+
+// verifiedClientCert returns the leaf of the request's verified client
+// certificate chain, or nil.
+func verifiedClientCert(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+func clientCertHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return clientCertHashPrefix + hex.EncodeToString(sum[:])
+}
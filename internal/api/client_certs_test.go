@@ -0,0 +1,234 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// testCA issues certificates for tests.
+type testCA struct {
+	path string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{path: path, cert: cert, key: key}
+}
+
+func writeTestCA(t *testing.T) string {
+	return newTestCA(t).path
+}
+
+// issue returns a certificate for cn, valid for localhost as server and
+// client, and the paths of it and its key in PEM.
+func (ca *testCA) issue(t *testing.T, cn string) (*x509.Certificate, string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, cn+".pem"), filepath.Join(dir, cn+"-key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return cert, certFile, keyFile
+}
+
+// frontendContext is the context of a gRPC call from a frontend with a
+// verified certificate.
+func frontendContext(cert *x509.Certificate) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{
+		State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}},
+	}})
+}
+
+func TestClientCAPrimaryChecksForwardedCertificates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	a, err := app.New(app.Options{StoreDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+	clients := newTestCA(t)
+	bot, _, _ := clients.issue(t, "bot")
+	proxy := newTestCA(t)
+	frontend, certFile, keyFile := proxy.issue(t, "frontend")
+	// Signed by the proxy CA, which the primary does not trust for clients.
+	stranger, _, _ := proxy.issue(t, "stranger")
+
+	for _, tc := range []struct {
+		mode                               string
+		keyOnly, certOnly, keyAndCert, bad int
+	}{
+		{ClientCertRequire, http.StatusUnauthorized, http.StatusUnauthorized, http.StatusOK, http.StatusUnauthorized},
+		{ClientCertOnly, http.StatusUnauthorized, http.StatusOK, http.StatusOK, http.StatusUnauthorized},
+		{ClientCertOptional, http.StatusOK, http.StatusOK, http.StatusOK, http.StatusOK},
+	} {
+		cfg := &Config{
+			Port:          8080,
+			APIKeys:       []string{"primary-key"},
+			TLSCert:       "server.pem",
+			TLSKey:        "server-key.pem",
+			TLSClientCA:   clients.path,
+			TLSClientAuth: tc.mode,
+			GRPCAddr:      "127.0.0.1:9090",
+			GRPCTLSCert:   certFile,
+			GRPCTLSKey:    keyFile,
+			GRPCTLSCA:     proxy.path,
+		}
+		if errs := cfg.Validate(); len(errs) > 0 {
+			t.Fatalf("%s: Validate: %v", tc.mode, errs)
+		}
+		router := gin.New()
+		SetupRoutes(router, a, cfg)
+		pool, err := loadClientCAs(clients.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fwd := routerForwarder{handler: router, clientCAs: pool}
+		key := map[string][]string{"X-Api-Key": {"primary-key"}}
+
+		// Calls from a peer without a verified certificate are refused
+		// before they reach the router.
+		if _, err := fwd.Forward(context.Background(), &forwardRequest{Method: http.MethodGet, Path: "/api/v1/outbox", Header: key}); status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("%s: Forward without a frontend certificate = %v, want Unauthenticated", tc.mode, err)
+		}
+
+		ctx := frontendContext(frontend)
+		for _, r := range []struct {
+			name   string
+			header map[string][]string
+			certs  [][]byte
+			want   int
+		}{
+			{"key only", key, nil, tc.keyOnly},
+			{"certificate only", nil, [][]byte{bot.Raw}, tc.certOnly},
+			{"key and certificate", key, [][]byte{bot.Raw}, tc.keyAndCert},
+			{"key and foreign certificate", key, [][]byte{stranger.Raw}, tc.bad},
+		} {
+			resp, err := fwd.Forward(ctx, &forwardRequest{Method: http.MethodGet, Path: "/api/v1/outbox", Header: r.header, ClientCerts: r.certs})
+			if err != nil {
+				t.Fatalf("%s, %s: Forward: %v", tc.mode, r.name, err)
+			}
+			if resp.Status != r.want {
+				t.Fatalf("%s, %s: forwarded request = %d %s, want %d", tc.mode, r.name, resp.Status, resp.Body, r.want)
+			}
+		}
+
+		// Direct requests without a certificate are treated the same.
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/outbox", nil)
+		req.Header.Set("X-API-Key", "primary-key")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tc.keyOnly {
+			t.Fatalf("%s: direct request without certificate = %d, want %d", tc.mode, rec.Code, tc.keyOnly)
+		}
+	}
+}
+
+func TestValidateClientCA(t *testing.T) {
+	ca := writeTestCA(t)
+	base := func() *Config {
+		return &Config{Port: 8080, APIKeys: []string{"k"}, TLSCert: "c.pem", TLSKey: "k.pem", TLSClientCA: ca}
+	}
+
+	cfg := base()
+	cfg.TLSClientAuth = "sometimes"
+	if len(cfg.Validate()) == 0 {
+		t.Fatalf("expected error for unknown client auth mode")
+	}
+	cfg = base()
+	cfg.TLSCert, cfg.TLSKey = "", ""
+	if len(cfg.Validate()) == 0 {
+		t.Fatalf("expected error for a client CA without TLS")
+	}
+	cfg = base()
+	cfg.TLSClientCA = filepath.Join(t.TempDir(), "missing.pem")
+	if len(cfg.Validate()) == 0 {
+		t.Fatalf("expected error for a missing CA file")
+	}
+	cfg = base()
+	cfg.APIKeys, cfg.TLSClientAuth = nil, ClientCertOnly
+	if errs := cfg.Validate(); len(errs) > 0 {
+		t.Fatalf("cert-only without keys: %v", errs)
+	}
+	cfg = base()
+	cfg.PrimaryAddr, cfg.TLSClientAuth = "primary:9090", ClientCertOptional
+	if len(cfg.Validate()) == 0 {
+		t.Fatalf("expected error for a frontend accepting certificates instead of keys")
+	}
+}
@@ -1,15 +1,347 @@
 package api
 
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/config"
+	"github.com/steipete/wacli/internal/mediastore"
+	"github.com/steipete/wacli/internal/privacy"
+)
+
 type Config struct {
 	Host        string
 	Port        int
 	StoreDir    string
 	APIKeys     []string
 	ReleaseMode bool
-	AI          AIConfig
+	// TLSCert and TLSKey serve HTTPS instead of plain HTTP when both are set.
+	TLSCert string
+	TLSKey  string
+	// TLSClientCA verifies client certificates against the CAs in this PEM
+	// file. TLSClientAuth is ClientCertRequire (default), ClientCertOnly or
+	// ClientCertOptional.
+	TLSClientCA   string
+	TLSClientAuth string
+	// PreflightMode is PreflightStrict (default), PreflightWarn or
+	// PreflightOff; see PreflightWarnings. Insecure starts a strict server
+	// despite its warnings. MinKeyBits is the estimated entropy API keys
+	// need (default: DefaultMinKeyBits).
+	PreflightMode string
+	Insecure      bool
+	MinKeyBits    int
+	// PIDFile records the server's pid for `wacli-api upgrade`. A primary
+	// defaults to <store>/wacli-api.pid; a proxy writes none unless set.
+	PIDFile string
+	// Follow keeps a live WhatsApp connection in the background, storing
+	// incoming messages and feeding the event stream.
+	Follow bool
+	// PresenceWatch records online/offline intervals of watched contacts.
+	PresenceWatch bool
+	// LeaderElection makes instances sharing the store elect one leader that
+	// owns the session; the others wait as standbys and take over when it dies.
+	LeaderElection bool
+	// GRPCAddr is where a primary accepts requests from proxy frontends.
+	GRPCAddr string
+	// PrimaryAddr runs this instance as a stateless proxy frontend that
+	// forwards every API call to the primary's gRPC listener.
+	PrimaryAddr string
+	// GRPCTLSCert, GRPCTLSKey and GRPCTLSCA secure the channel between a
+	// primary and its frontends with mutual TLS: each side presents its
+	// certificate and verifies the other's against GRPCTLSCA. Required with
+	// GRPCAddr and PrimaryAddr.
+	GRPCTLSCert string
+	GRPCTLSKey  string
+	GRPCTLSCA   string
+	// SlackSigningSecret verifies Slack callbacks to /away/slack (optional).
+	SlackSigningSecret string
+	// Footer is appended to messages sent through the API so recipients can
+	// tell them from personal ones. KeyFooters overrides it per API key; an
+	// empty override sends that key's messages without a footer.
+	Footer     string
+	KeyFooters map[string]string
+	// SandboxTo redirects every outgoing message to this test number, for
+	// staging setups that must not message real contacts.
+	SandboxTo string
+	// AdminTo receives alerts about wacli itself (logout, low disk space,
+	// failed backups, growing queues), checked as configured by AdminAlerts.
+	AdminTo     string
+	AdminAlerts app.AdminAlerts
+	// Voicemail transcribes voice notes arriving in direct chats (or the
+	// chats it lists) and forwards them to a chat or by email. It needs
+	// Follow; transcripts use the AI settings' Groq key and privacy guard.
+	Voicemail app.Voicemail
+	// TrashRetention is how long deleted chats stay restorable before they
+	// are purged (default: 30 days).
+	TrashRetention time.Duration
+	// MessageRetention prunes messages older than this from chats without
+	// their own retention (zero keeps them). Chats on legal hold or marked
+	// never-prune are always kept; see /retention.
+	MessageRetention time.Duration
+	// NumberCheckMaxAge is how long GET /contacts/check reuses WhatsApp's
+	// answer for a number (default: 7 days).
+	NumberCheckMaxAge time.Duration
+	// AvatarMaxAge is how long a cached profile picture is served before
+	// WhatsApp is asked whether it changed (default: 24 hours).
+	AvatarMaxAge time.Duration
+	// GroupSnapshots records the participants of every joined group on a
+	// schedule for GET /groups/:jid/membership/history and flags mass
+	// leaves between snapshots.
+	GroupSnapshots app.GroupSnapshots
+	// MediaStore selects where downloaded media is kept: "fs" (default, the
+	// store's media directory) or "s3", configured by S3.
+	MediaStore string
+	S3         mediastore.S3Options
+	// MediaRetention limits downloaded media by age and total size; the
+	// zero value keeps everything.
+	MediaRetention app.MediaRetention
+	// MediaURLSecret signs expiring media links minted by
+	// POST /media/:id/sign; they are disabled while it is empty. Links are
+	// valid for at most MediaURLMaxTTL (default: 7 days).
+	MediaURLSecret string
+	MediaURLMaxTTL time.Duration
+	// BackupDir is the only place POST /admin/backup/online writes backups
+	// on the server (default: <store>/backups).
+	BackupDir string
+	// WebhookQueue acknowledges inbound webhooks with 202 once they are
+	// stored and processes them in the background with retries.
+	WebhookQueue bool
+	// WebhookMaxBodyBytes caps the body of inbound webhooks (default: 1 MiB).
+	WebhookMaxBodyBytes int64
+	// RouteLimits maps route patterns such as "POST /api/v1/send/*" to their
+	// concurrency limits and timeouts; nil uses DefaultRouteLimits.
+	RouteLimits map[string]RouteLimit
+	// Event sinks, each enabled when its address is set.
+	MQTT  app.MQTTOptions
+	NATS  app.NATSOptions
+	Kafka app.KafkaOptions
+	AI    AIConfig
 }
 
 type AIConfig struct {
-	Enabled    bool
-	GroqAPIKey string
+	Enabled           bool
+	GroqAPIKey        string
+	ChatModel         string
+	SummaryMinSeconds int
+	TranscribeVideo   bool
+	FFmpegPath        string
+	Sentiment         bool
+	Privacy           privacy.Guard
+}
+
+// AppConfig converts the server config into the shape app.Sync expects.
+func (c *Config) AppConfig() *config.Config {
+	return &config.Config{
+		StoreDir: c.StoreDir,
+		AI: config.AIConfig{
+			Enabled:           c.AI.Enabled,
+			GroqAPIKey:        c.AI.GroqAPIKey,
+			ChatModel:         c.AI.ChatModel,
+			SummaryMinSeconds: c.AI.SummaryMinSeconds,
+			TranscribeVideo:   c.AI.TranscribeVideo,
+			FFmpegPath:        c.AI.FFmpegPath,
+			Sentiment:         c.AI.Sentiment,
+			Privacy:           c.AI.Privacy,
+		},
+	}
+}
+
+func (c *Config) trashRetention() time.Duration {
+	if c.TrashRetention <= 0 {
+		return app.DefaultTrashRetention
+	}
+	return c.TrashRetention
+}
+
+// messageRetentionDays is the default message retention in days; zero
+// keeps messages indefinitely.
+func (c *Config) messageRetentionDays() int {
+	return int(c.MessageRetention / (24 * time.Hour))
+}
+
+func (c *Config) mediaURLMaxTTL() time.Duration {
+	if c.MediaURLMaxTTL <= 0 {
+		return 7 * 24 * time.Hour
+	}
+	return c.MediaURLMaxTTL
+}
+
+// Validate checks the server settings and returns every problem found.
+func (c *Config) Validate() []error {
+	var errs []error
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("WACLI_API_PORT must be between 1 and 65535, got %d", c.Port))
+	}
+	// Frontends leave the key check to the primary.
+	if len(c.APIKeys) == 0 && c.PrimaryAddr == "" && !(c.TLSClientCA != "" && c.clientCertMode() == ClientCertOnly) {
+		errs = append(errs, fmt.Errorf("WACLI_API_KEYS contains no keys"))
+	}
+	keys := make(map[string]bool, len(c.APIKeys))
+	for _, k := range c.APIKeys {
+		keys[k] = true
+	}
+	for k := range c.KeyFooters {
+		if !keys[k] {
+			errs = append(errs, fmt.Errorf("WACLI_API_KEY_FOOTERS has a footer for a key not in WACLI_API_KEYS"))
+			break
+		}
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		errs = append(errs, fmt.Errorf("WACLI_API_TLS_CERT and WACLI_API_TLS_KEY must be set together"))
+	}
+	switch c.TLSClientAuth {
+	case "", ClientCertRequire, ClientCertOnly, ClientCertOptional:
+	default:
+		errs = append(errs, fmt.Errorf("WACLI_API_TLS_CLIENT_AUTH must be require, cert-only or optional, got %q", c.TLSClientAuth))
+	}
+	if c.TLSClientCA != "" {
+		if c.TLSCert == "" {
+			errs = append(errs, fmt.Errorf("WACLI_API_TLS_CLIENT_CA requires WACLI_API_TLS_CERT and WACLI_API_TLS_KEY"))
+		}
+		if _, err := loadClientCAs(c.TLSClientCA); err != nil {
+			errs = append(errs, fmt.Errorf("WACLI_API_TLS_CLIENT_CA: %w", err))
+		}
+	} else if c.TLSClientAuth != "" {
+		errs = append(errs, fmt.Errorf("WACLI_API_TLS_CLIENT_AUTH requires WACLI_API_TLS_CLIENT_CA"))
+	}
+	switch c.PreflightMode {
+	case "", PreflightStrict, PreflightWarn, PreflightOff:
+	default:
+		errs = append(errs, fmt.Errorf("WACLI_API_PREFLIGHT must be strict, warn or off, got %q", c.PreflightMode))
+	}
+	if c.MinKeyBits < 0 {
+		errs = append(errs, fmt.Errorf("WACLI_API_MIN_KEY_BITS must not be negative"))
+	}
+	errs = append(errs, validateRouteLimits(c.RouteLimits)...)
+	if c.TrashRetention < 0 {
+		errs = append(errs, fmt.Errorf("WACLI_TRASH_RETENTION_DAYS must not be negative"))
+	}
+	if c.NumberCheckMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("WACLI_NUMBER_CHECK_CACHE_HOURS must not be negative"))
+	}
+	if c.AvatarMaxAge < 0 {
+		errs = append(errs, fmt.Errorf("WACLI_AVATAR_CACHE_HOURS must not be negative"))
+	}
+	if c.GroupSnapshots.Interval < 0 {
+		errs = append(errs, fmt.Errorf("WACLI_GROUP_SNAPSHOT_HOURS must not be negative"))
+	}
+	if c.GroupSnapshots.MassLeave < 0 {
+		errs = append(errs, fmt.Errorf("WACLI_GROUP_MASS_LEAVE must not be negative"))
+	}
+	if c.MessageRetention < 0 {
+		errs = append(errs, fmt.Errorf("WACLI_MESSAGE_RETENTION_DAYS must not be negative"))
+	}
+	switch c.MediaStore {
+	case "", "fs":
+	case "s3":
+		if c.S3.Endpoint == "" || c.S3.Bucket == "" {
+			errs = append(errs, fmt.Errorf("WACLI_MEDIA_STORE=s3 requires WACLI_S3_ENDPOINT and WACLI_S3_BUCKET"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("WACLI_MEDIA_STORE must be fs or s3, got %q", c.MediaStore))
+	}
+	if c.MediaRetention.MaxAge < 0 {
+		errs = append(errs, fmt.Errorf("WACLI_MEDIA_MAX_AGE_DAYS must not be negative"))
+	}
+	if c.MediaRetention.MaxBytes < 0 {
+		errs = append(errs, fmt.Errorf("WACLI_MEDIA_MAX_SIZE_MB must not be negative"))
+	}
+	if c.MediaURLMaxTTL < 0 {
+		errs = append(errs, fmt.Errorf("WACLI_MEDIA_URL_MAX_TTL_HOURS must not be negative"))
+	}
+	if c.WebhookMaxBodyBytes < 0 {
+		errs = append(errs, fmt.Errorf("WACLI_WEBHOOK_MAX_BODY_KB must not be negative"))
+	}
+	if c.AdminAlerts.MinFreeDiskMB < 0 {
+		errs = append(errs, fmt.Errorf("WACLI_ADMIN_MIN_FREE_DISK_MB must not be negative"))
+	}
+	if c.AdminAlerts.MaxBacklog < 0 {
+		errs = append(errs, fmt.Errorf("WACLI_ADMIN_MAX_BACKLOG must not be negative"))
+	}
+	if c.Voicemail.Enabled() {
+		if !c.Follow {
+			errs = append(errs, fmt.Errorf("WACLI_VOICEMAIL_FORWARD_TO and WACLI_VOICEMAIL_EMAIL require WACLI_API_FOLLOW"))
+		}
+		errs = append(errs, c.Voicemail.Validate()...)
+	}
+	if c.PresenceWatch && !c.Follow {
+		errs = append(errs, fmt.Errorf("WACLI_API_PRESENCE_WATCH requires WACLI_API_FOLLOW"))
+	}
+	if c.PrimaryAddr != "" {
+		if _, _, err := net.SplitHostPort(c.PrimaryAddr); err != nil {
+			errs = append(errs, fmt.Errorf("WACLI_API_PRIMARY: %w", err))
+		}
+		if c.GRPCAddr != "" {
+			errs = append(errs, fmt.Errorf("WACLI_API_PRIMARY and WACLI_API_GRPC_ADDR are mutually exclusive"))
+		}
+		if c.LeaderElection {
+			errs = append(errs, fmt.Errorf("WACLI_API_PRIMARY frontends do not take part in leader election"))
+		}
+		if c.certAuthenticates() {
+			errs = append(errs, fmt.Errorf("WACLI_API_PRIMARY frontends forward the API key to the primary; use WACLI_API_TLS_CLIENT_AUTH=require"))
+		}
+		if c.Follow || c.MQTT.Broker != "" || c.NATS.URL != "" || len(c.Kafka.Brokers) > 0 {
+			errs = append(errs, fmt.Errorf("WACLI_API_PRIMARY frontends have no session; configure follow mode and event sinks on the primary"))
+		}
+	}
+	if c.GRPCAddr != "" {
+		if _, _, err := net.SplitHostPort(c.GRPCAddr); err != nil {
+			errs = append(errs, fmt.Errorf("WACLI_API_GRPC_ADDR: %w", err))
+		}
+	}
+	if c.GRPCAddr != "" || c.PrimaryAddr != "" {
+		if c.GRPCTLSCert == "" || c.GRPCTLSKey == "" || c.GRPCTLSCA == "" {
+			errs = append(errs, fmt.Errorf("WACLI_API_GRPC_ADDR and WACLI_API_PRIMARY require WACLI_API_GRPC_TLS_CERT, WACLI_API_GRPC_TLS_KEY and WACLI_API_GRPC_TLS_CA"))
+		} else if _, err := loadClientCAs(c.GRPCTLSCA); err != nil {
+			errs = append(errs, fmt.Errorf("WACLI_API_GRPC_TLS_CA: %w", err))
+		}
+	}
+	if c.MQTT.Broker != "" {
+		if err := checkURLScheme(c.MQTT.Broker, "tcp", "ssl", "tls", "mqtt", "mqtts", "ws", "wss"); err != nil {
+			errs = append(errs, fmt.Errorf("WACLI_MQTT_BROKER: %w", err))
+		}
+		if c.MQTT.QoS > 2 {
+			errs = append(errs, fmt.Errorf("WACLI_MQTT_QOS must be 0, 1 or 2"))
+		}
+	}
+	if c.NATS.URL != "" {
+		for _, u := range strings.Split(c.NATS.URL, ",") {
+			if err := checkURLScheme(strings.TrimSpace(u), "nats", "tls", "ws", "wss"); err != nil {
+				errs = append(errs, fmt.Errorf("WACLI_NATS_URL: %w", err))
+			}
+		}
+	}
+	for _, b := range c.Kafka.Brokers {
+		if _, _, err := net.SplitHostPort(b); err != nil {
+			errs = append(errs, fmt.Errorf("WACLI_KAFKA_BROKERS: %q must be host:port", b))
+		}
+	}
+	for _, list := range []struct {
+		name   string
+		events []string
+	}{{"WACLI_MQTT_EVENTS", c.MQTT.Events}, {"WACLI_NATS_EVENTS", c.NATS.Events}, {"WACLI_KAFKA_EVENTS", c.Kafka.Events}} {
+		for _, e := range list.events {
+			if !app.IsEventType(e) {
+				errs = append(errs, fmt.Errorf("%s: unknown event type %q", list.name, e))
+			}
+		}
+	}
+	return append(errs, c.AppConfig().Validate()...)
+}
+
+func checkURLScheme(raw string, schemes ...string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	for _, s := range schemes {
+		if u.Scheme == s && u.Host != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q must be a URL with scheme %s", raw, strings.Join(schemes, ", "))
 }
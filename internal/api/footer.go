@@ -0,0 +1,39 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const footerContextKey = "wacli.footer"
+
+// messageFooter resolves the footer for the request's API key. A key listed
+// in KeyFooters uses its own footer, even an empty one; other keys use Footer.
+func messageFooter(cfg *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		footer := cfg.Footer
+		if f, ok := cfg.KeyFooters[c.GetString(apiKeyContextKey)]; ok {
+			footer = f
+		}
+		if footer != "" {
+			c.Set(footerContextKey, footer)
+		}
+		c.Next()
+	}
+}
+
+// withFooter appends the request's footer to text sent on its behalf.
+func withFooter(c *gin.Context, text string) string {
+	return appendFooter(text, c.GetString(footerContextKey))
+}
+
+func appendFooter(text, footer string) string {
+	if footer == "" {
+		return text
+	}
+	if strings.TrimSpace(text) == "" {
+		return footer
+	}
+	return text + "\n\n" + footer
+}
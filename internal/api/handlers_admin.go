@@ -0,0 +1,346 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/store"
+)
+
+type onlineBackupRequest struct {
+	// Path writes the backup on the server instead of streaming it. It is
+	// relative to the backup directory, or absolute inside it.
+	Path string `json:"path"`
+}
+
+func (c *Config) backupDir() string {
+	if c.BackupDir != "" {
+		return c.BackupDir
+	}
+	return filepath.Join(c.StoreDir, "backups")
+}
+
+// backupDest resolves a requested backup path inside dir, creating the
+// directories it needs. Paths with ".." elements or outside dir, including
+// through symlinks, are rejected.
+func backupDest(dir, p string) (string, error) {
+	errOutside := fmt.Errorf("path must be inside the backup directory %s", dir)
+	for _, elem := range strings.Split(filepath.ToSlash(p), "/") {
+		if elem == ".." {
+			return "", errOutside
+		}
+	}
+	rel := p
+	if filepath.IsAbs(p) {
+		var err error
+		if rel, err = filepath.Rel(dir, p); err != nil {
+			return "", errOutside
+		}
+	}
+	if !filepath.IsLocal(rel) {
+		return "", errOutside
+	}
+	dest := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(dest), 0o700); err != nil {
+		return "", err
+	}
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", err
+	}
+	parent, err := filepath.EvalSymlinks(filepath.Dir(dest))
+	if err != nil {
+		return "", err
+	}
+	if parent != root && !strings.HasPrefix(parent, root+string(filepath.Separator)) {
+		return "", errOutside
+	}
+	return dest, nil
+}
+
+// writeBackup snapshots the store to dest without ever replacing an existing
+// file: the snapshot is written next to it and then hard-linked into place.
+func writeBackup(ctx context.Context, a *app.App, dest string) error {
+	tmpDir, err := os.MkdirTemp(filepath.Dir(dest), ".backup-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	tmpPath := filepath.Join(tmpDir, "wacli.db")
+	if err := a.DB().Backup(ctx, tmpPath); err != nil {
+		return err
+	}
+	return os.Link(tmpPath, dest)
+}
+
+// onlineBackupHandler snapshots the message store while the server runs.
+// Without a path the snapshot is streamed as a download.
+func onlineBackupHandler(a *app.App, cfg *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req onlineBackupRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Minute)
+		defer cancel()
+
+		started := time.Now()
+		if req.Path != "" {
+			dest, err := backupDest(cfg.backupDir(), req.Path)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if err := writeBackup(ctx, a, dest); err != nil {
+				if errors.Is(err, fs.ErrExist) {
+					c.JSON(http.StatusConflict, gin.H{"error": "path already exists"})
+					return
+				}
+				notifyBackupFailed(ctx, a, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "backup failed: " + err.Error()})
+				return
+			}
+			st, err := os.Stat(dest)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"path":        dest,
+				"size_bytes":  st.Size(),
+				"duration_ms": time.Since(started).Milliseconds(),
+			})
+			return
+		}
+
+		// Snapshot next to the store so a large backup does not fill /tmp.
+		dir, err := os.MkdirTemp(a.StoreDir(), "backup-")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "backup failed: " + err.Error()})
+			return
+		}
+		defer os.RemoveAll(dir)
+		tmpPath := filepath.Join(dir, "wacli.db")
+		if err := a.DB().Backup(ctx, tmpPath); err != nil {
+			notifyBackupFailed(ctx, a, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "backup failed: " + err.Error()})
+			return
+		}
+		c.FileAttachment(tmpPath, fmt.Sprintf("wacli-%s.db", started.UTC().Format("20060102-150405")))
+	}
+}
+
+type sessionExportRequest struct {
+	Passphrase   string `json:"passphrase" binding:"required"`
+	IncludeStore bool   `json:"include_store"`
+}
+
+// sessionExportHandler downloads the paired session, and optionally the
+// message store, as an encrypted archive for `wacli session import` or
+// POST /admin/session/import on another host.
+func sessionExportHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req sessionExportRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(req.Passphrase) < app.MinPassphraseLen {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("passphrase must be at least %d characters", app.MinPassphraseLen)})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Minute)
+		defer cancel()
+
+		// Write next to the store so a large archive does not fill /tmp.
+		dir, err := os.MkdirTemp(a.StoreDir(), "backup-")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "export failed: " + err.Error()})
+			return
+		}
+		defer os.RemoveAll(dir)
+		tmpPath := filepath.Join(dir, "session.wacli")
+		f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "export failed: " + err.Error()})
+			return
+		}
+		m, err := a.ExportSession(ctx, f, req.Passphrase, req.IncludeStore)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "export failed: " + err.Error()})
+			return
+		}
+		c.FileAttachment(tmpPath, fmt.Sprintf("wacli-session-%s.wacli", m.CreatedAt.Format("20060102-150405")))
+	}
+}
+
+// sessionImportHandler stages an archive from sessionExportHandler. It
+// takes effect when the server restarts, as the open session cannot be
+// swapped underneath it.
+func sessionImportHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		passphrase := c.PostForm("passphrase")
+		if passphrase == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "passphrase is required"})
+			return
+		}
+		file, _, err := c.Request.FormFile("archive")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "archive is required"})
+			return
+		}
+		defer file.Close()
+		force := c.PostForm("force") == "true"
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Minute)
+		defer cancel()
+
+		m, err := a.ImportSession(ctx, file, passphrase, force)
+		switch {
+		case errors.Is(err, app.ErrSessionPaired):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, app.ErrBadPassphrase):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "import failed: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"staged":           true,
+			"restart_required": true,
+			"manifest":         m,
+		})
+	}
+}
+
+// notifyBackupFailed alerts the admin chat unless the client went away.
+func notifyBackupFailed(ctx context.Context, a *app.App, err error) {
+	if ctx.Err() == nil {
+		a.NotifyAdmin(ctx, app.AlertBackupFailed, "online backup failed: "+err.Error())
+	}
+}
+
+func ftsStatusJSON(st store.FTSStatus) gin.H {
+	out := gin.H{
+		"state": st.State,
+		"done":  st.Done,
+		"total": st.Total,
+	}
+	if st.Error != "" {
+		out["error"] = st.Error
+	}
+	return out
+}
+
+// ftsStatusHandler reports the state of the full-text search index.
+func ftsStatusHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, ftsStatusJSON(app.DB().FTSStatus()))
+	}
+}
+
+// rebuildFTSHandler starts a background rebuild of the search index; poll
+// GET /admin/fts for progress.
+func rebuildFTSHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch st := app.DB().FTSStatus(); st.State {
+		case store.FTSRebuilding:
+			c.JSON(http.StatusConflict, gin.H{"error": "fts rebuild already running"})
+			return
+		case store.FTSUnavailable:
+			c.JSON(http.StatusConflict, gin.H{"error": "fts5 is not available in this build"})
+			return
+		}
+		go func() {
+			if err := app.RebuildFTS(context.Background()); err != nil && !errors.Is(err, store.ErrFTSRebuildRunning) {
+				fmt.Fprintf(os.Stderr, "fts: rebuild: %v\n", err)
+			}
+		}()
+		c.JSON(http.StatusAccepted, ftsStatusJSON(app.DB().FTSStatus()))
+	}
+}
+
+type mediaGCRequest struct {
+	// MaxAgeDays and MaxSizeMB override the configured retention for this
+	// run; 0 removes that limit.
+	MaxAgeDays *int `json:"max_age_days"`
+	MaxSizeMB  *int `json:"max_size_mb"`
+	DryRun     bool `json:"dry_run"`
+}
+
+// mediaGCHandler applies the media retention policy now and reports what
+// was reclaimed.
+func mediaGCHandler(a *app.App, cfg *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req mediaGCRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		r := cfg.MediaRetention
+		if req.MaxAgeDays != nil {
+			if *req.MaxAgeDays < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "max_age_days must not be negative"})
+				return
+			}
+			r.MaxAge = time.Duration(*req.MaxAgeDays) * 24 * time.Hour
+		}
+		if req.MaxSizeMB != nil {
+			if *req.MaxSizeMB < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "max_size_mb must not be negative"})
+				return
+			}
+			r.MaxBytes = int64(*req.MaxSizeMB) << 20
+		}
+
+		res, err := a.CollectMediaGarbage(c.Request.Context(), r, req.DryRun)
+		if errors.Is(err, app.ErrMediaGCRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// notifyTestHandler sends a test alert to the admin chat, so the channel can
+// be checked without waiting for something to go wrong.
+func notifyTestHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		to, ok := a.AdminChat()
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "no admin chat configured (WACLI_ADMIN_JID)"})
+			return
+		}
+		queued, err := a.SendAdminAlert(c.Request.Context(), "test alert: this chat receives wacli's operational alerts.")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"to": to, "queued": queued})
+	}
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/store"
+)
+
+func TestBackupDest(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dir, "escape")); err != nil {
+		t.Fatalf("Symlink: %v", err)
+	}
+
+	for p, want := range map[string]string{
+		"wacli.db":                               filepath.Join(dir, "wacli.db"),
+		"daily/wacli.db":                         filepath.Join(dir, "daily", "wacli.db"),
+		filepath.Join(dir, "abs.db"):             filepath.Join(dir, "abs.db"),
+		"../wacli.db":                            "",
+		"daily/../../wacli.db":                   "",
+		"daily/../wacli.db":                      "",
+		filepath.Join(outside, "wacli.db"):       "",
+		"/etc/cron.d/wacli":                      "",
+		"escape/wacli.db":                        "",
+		filepath.Join(dir, "escape", "wacli.db"): "",
+	} {
+		got, err := backupDest(dir, p)
+		if want == "" {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %s", p, got)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("%s: got %q, %v; want %q", p, got, err, want)
+		}
+	}
+}
+
+func TestAdminEndpointsRejectManagedKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	a, err := app.New(app.Options{StoreDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+	managed := "wacli_managed"
+	if _, err := a.DB().AddAPIKey(store.APIKey{Name: "bot", Prefix: managed, KeyHash: store.HashAPIKey(managed)}); err != nil {
+		t.Fatalf("AddAPIKey: %v", err)
+	}
+	router := gin.New()
+	SetupRoutes(router, a, &Config{Port: 8080, APIKeys: []string{"primary-key"}})
+
+	do := func(method, path, key string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	for _, r := range []struct{ method, path string }{
+		{http.MethodPost, "/api/v1/admin/session/export"},
+		{http.MethodPost, "/api/v1/admin/backup/online"},
+		{http.MethodPost, "/api/v1/admin/media/gc"},
+		{http.MethodGet, "/api/v1/admin/preflight"},
+		{http.MethodGet, "/api/v1/admin/keys"},
+	} {
+		if got := do(r.method, r.path, managed); got != http.StatusForbidden {
+			t.Errorf("%s %s with a managed key = %d, want 403", r.method, r.path, got)
+		}
+	}
+	if got := do(http.MethodGet, "/api/v1/admin/preflight", "primary-key"); got != http.StatusOK {
+		t.Errorf("preflight with a configured key = %d, want 200", got)
+	}
+	// Managed keys still work outside /admin.
+	if got := do(http.MethodGet, "/api/v1/outbox", managed); got != http.StatusOK {
+		t.Errorf("outbox with a managed key = %d, want 200", got)
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+)
+
+type askRequest struct {
+	Question string `json:"question" binding:"required"`
+	Chat     string `json:"chat"`
+	Limit    int    `json:"limit"`
+}
+
+// askHandler answers a question over the local archive with citations.
+func askHandler(a *app.App, cfg *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req askRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if strings.TrimSpace(cfg.AI.GroqAPIKey) == "" {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "AI is not configured (set GROQ_API_KEY)"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+		defer cancel()
+
+		res, err := a.Ask(ctx, req.Question, app.AskOptions{
+			ChatJID: req.Chat,
+			Limit:   req.Limit,
+			APIKey:  cfg.AI.GroqAPIKey,
+			Model:   cfg.AI.ChatModel,
+			Privacy: cfg.AI.Privacy,
+		})
+		if errors.Is(err, app.ErrPrivacyExcluded) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		citations := make([]gin.H, 0, len(res.Citations))
+		for _, ct := range res.Citations {
+			m := ct.Message
+			text := m.Text
+			if strings.TrimSpace(text) == "" {
+				text = m.DisplayText
+			}
+			citations = append(citations, gin.H{
+				"ref":        ct.Ref,
+				"chat_jid":   m.ChatJID,
+				"chat_name":  m.ChatName,
+				"msg_id":     m.MsgID,
+				"sender_jid": m.SenderJID,
+				"from_me":    m.FromMe,
+				"timestamp":  m.Timestamp,
+				"text":       text,
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"answer":    res.Answer,
+			"citations": citations,
+			"sources":   res.Sources,
+		})
+	}
+}
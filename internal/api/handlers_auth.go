@@ -52,8 +52,7 @@ func getQRCodeHandler(a *app.App) gin.HandlerFunc {
 		// Wait for QR code or error
 		select {
 		case code := <-qrCodeChan:
-			// Generate QR code image
-			png, err := qrcode.Encode(code, qrcode.Medium, 256)
+			png, err := qrPNGDataURL(code)
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{
 					"error": "failed to generate QR code image: " + err.Error(),
@@ -61,11 +60,9 @@ func getQRCodeHandler(a *app.App) gin.HandlerFunc {
 				return
 			}
 
-			// Return as base64-encoded PNG
-			encoded := base64.StdEncoding.EncodeToString(png)
 			c.JSON(http.StatusOK, gin.H{
 				"qr_code":      code,
-				"qr_code_png":  "data:image/png;base64," + encoded,
+				"qr_code_png":  png,
 				"expires_in":   60, // QR codes typically expire in 60 seconds
 				"instructions": "Scan this QR code with WhatsApp: Settings → Linked Devices → Link a Device",
 			})
@@ -83,6 +80,98 @@ func getQRCodeHandler(a *app.App) gin.HandlerFunc {
 	}
 }
 
+// qrPNGDataURL renders a QR code as a base64-encoded PNG data URL.
+func qrPNGDataURL(code string) (string, error) {
+	png, err := qrcode.Encode(code, qrcode.Medium, 256)
+	if err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(png), nil
+}
+
+// qrStreamMaxDuration caps how long GET /auth/qr/stream keeps pairing.
+const qrStreamMaxDuration = 10 * time.Minute
+
+// qrStreamHandler streams pairing QR codes as server-sent events: a "qr"
+// event for each new code, as WhatsApp refreshes them and new pairing
+// sessions start, then "paired" once the phone scanned one, or "error".
+// Pairing stops when the client disconnects.
+func qrStreamHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := a.OpenWA(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to initialize WhatsApp client: " + err.Error(),
+			})
+			return
+		}
+		if a.WA().IsAuthed() {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":         "already authenticated",
+				"authenticated": true,
+			})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), qrStreamMaxDuration)
+		defer cancel()
+
+		codes := make(chan string, 4)
+		done := make(chan error, 1)
+		go func() {
+			done <- a.PairQR(ctx, func(code string) {
+				select {
+				case codes <- code:
+				case <-ctx.Done():
+				}
+			})
+		}()
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		ping := time.NewTicker(15 * time.Second)
+		defer ping.Stop()
+
+		for {
+			select {
+			case code := <-codes:
+				png, err := qrPNGDataURL(code)
+				if err != nil {
+					c.SSEvent("error", gin.H{"error": "failed to generate QR code image: " + err.Error()})
+					return
+				}
+				c.SSEvent("qr", gin.H{
+					"qr_code":     code,
+					"qr_code_png": png,
+				})
+			case err := <-done:
+				if err != nil {
+					if ctx.Err() != nil {
+						err = fmt.Errorf("timeout waiting for pairing")
+					}
+					c.SSEvent("error", gin.H{"error": err.Error()})
+				} else {
+					c.SSEvent("paired", gin.H{
+						"authenticated": true,
+						"jid":           a.WA().OwnJID().ToNonAD().String(),
+					})
+				}
+				c.Writer.Flush()
+				return
+			case <-ping.C:
+				// A comment keeps proxies from closing an idle stream.
+				if _, err := c.Writer.WriteString(": ping\n\n"); err != nil {
+					return
+				}
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
 // pairWithCodeRequest is the request body for pairing with a code
 type pairWithCodeRequest struct {
 	PhoneNumber string `json:"phone_number" binding:"required"`
@@ -210,6 +299,26 @@ func waitForPairingHandler(a *app.App) gin.HandlerFunc {
 	}
 }
 
+// sessionHealthHandler reports whether the WhatsApp session works, when it
+// last connected and received a message, and whether it was logged out
+// remotely and needs pairing again.
+func sessionHealthHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := a.OpenWA(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "failed to check auth status: " + err.Error(),
+			})
+			return
+		}
+		h, err := a.SessionHealth()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, h)
+	}
+}
+
 // logoutHandler logs out and invalidates the session
 func logoutHandler(a *app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
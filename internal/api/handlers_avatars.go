@@ -0,0 +1,59 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/wa"
+)
+
+// avatarHandler serves the profile picture of a contact, or of a group for
+// group routes. Pictures are cached in the store; the picture ID is the
+// ETag, so dashboards revalidate with If-None-Match and get 304 until the
+// picture changes.
+func avatarHandler(a *app.App, cfg *Config, group bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil || wa.IsGroupJID(jid) != group {
+			if group {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group JID"})
+			} else {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid contact JID"})
+			}
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 1*time.Minute)
+		defer cancel()
+
+		if err := a.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+		if err := a.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+
+		av, err := a.Avatar(ctx, jid, cfg.AvatarMaxAge, c.Query("refresh") == "true")
+		if errors.Is(err, app.ErrNoAvatar) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "fetch failed: " + err.Error()})
+			return
+		}
+
+		c.Header("Content-Type", "image/jpeg")
+		c.Header("Cache-Control", "private, no-cache")
+		c.Header("ETag", `"`+av.PictureID+`"`)
+		c.Header("X-Picture-Id", av.PictureID)
+		http.ServeContent(c.Writer, c.Request, "", time.Time{}, bytes.NewReader(av.Data))
+	}
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+)
+
+type setAwayRequest struct {
+	Active   *bool      `json:"active" binding:"required"`
+	Message  string     `json:"message"`
+	Source   string     `json:"source"`
+	Until    *time.Time `json:"until"`
+	Minutes  int        `json:"minutes"`
+	SetAbout bool       `json:"set_about"`
+}
+
+func awayJSON(s app.AwayState) gin.H {
+	resp := gin.H{
+		"active":    s.Active,
+		"effective": s.Effective(time.Now()),
+		"message":   s.Message,
+		"source":    s.Source,
+		"set_about": s.SetAbout,
+	}
+	if !s.Until.IsZero() {
+		resp["until"] = s.Until
+	}
+	if !s.UpdatedAt.IsZero() {
+		resp["updated_at"] = s.UpdatedAt
+	}
+	return resp
+}
+
+func getAwayHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s, err := a.Away()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, awayJSON(s))
+	}
+}
+
+func setAwayHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req setAwayRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		next := app.AwayState{
+			Active:   *req.Active,
+			Message:  req.Message,
+			Source:   req.Source,
+			SetAbout: req.SetAbout,
+		}
+		if req.Until != nil {
+			next.Until = req.Until.UTC()
+		} else if req.Minutes > 0 {
+			next.Until = time.Now().UTC().Add(time.Duration(req.Minutes) * time.Minute)
+		}
+		applyAway(c, a, next)
+	}
+}
+
+// applyAway connects when the profile about text has to change and stores the
+// new away state.
+func applyAway(c *gin.Context, a *app.App, next app.AwayState) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	if a.AwayNeedsConnection(next) {
+		if err := a.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+		if err := a.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+	}
+
+	s, err := a.SetAway(ctx, next)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, awayJSON(s))
+}
+
+type slackEnvelope struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Event     struct {
+		Type      string `json:"type"`
+		Presence  string `json:"presence"`
+		DNDStatus struct {
+			Enabled bool `json:"dnd_enabled"`
+		} `json:"dnd_status"`
+	} `json:"event"`
+}
+
+// slackAwayHandler receives Slack Events API callbacks and mirrors the user's
+// presence ("away") or Do Not Disturb status into away mode. The configured
+// away message and about behaviour are kept.
+func slackAwayHandler(a *app.App, cfg *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if cfg.SlackSigningSecret != "" && !validSlackSignature(cfg.SlackSigningSecret, c.GetHeader("X-Slack-Request-Timestamp"), c.GetHeader("X-Slack-Signature"), body) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid slack signature"})
+			return
+		}
+
+		var env slackEnvelope
+		if err := json.Unmarshal(body, &env); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON: " + err.Error()})
+			return
+		}
+		if env.Type == "url_verification" {
+			c.JSON(http.StatusOK, gin.H{"challenge": env.Challenge})
+			return
+		}
+
+		var active bool
+		switch env.Event.Type {
+		case "presence_change":
+			active = env.Event.Presence == "away"
+		case "dnd_updated_user", "dnd_updated":
+			active = env.Event.DNDStatus.Enabled
+		default:
+			c.JSON(http.StatusOK, gin.H{"ignored": true, "event": env.Event.Type})
+			return
+		}
+
+		next, err := a.Away()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		next.Active = active
+		next.Source = "slack"
+		next.Until = time.Time{}
+		applyAway(c, a, next)
+	}
+}
+
+// validSlackSignature checks Slack's v0 request signature and rejects
+// requests older than five minutes.
+func validSlackSignature(secret, timestamp, signature string, body []byte) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || math.Abs(float64(time.Now().Unix()-ts)) > 300 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	return hmac.Equal([]byte("v0="+hex.EncodeToString(mac.Sum(nil))), []byte(signature))
+}
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow/types"
+)
+
+const (
+	batchMaxOperations = 50
+	// batchMaxFileSize bounds files fetched for send_file operations.
+	batchMaxFileSize = 100 << 20
+	// batchMaxRedirects caps the redirects followed when fetching a file.
+	batchMaxRedirects = 5
+)
+
+// batchHTTPClient fetches send_file URLs. It only connects to public
+// addresses: the check runs on the resolved address of every connection,
+// redirects included, so DNS rebinding cannot reach internal services.
+var batchHTTPClient = &http.Client{
+	Timeout: 2 * time.Minute,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 30 * time.Second,
+			Control: publicAddressOnly,
+		}).DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: time.Minute,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= batchMaxRedirects {
+			return fmt.Errorf("stopped after %d redirects", batchMaxRedirects)
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+		}
+		return nil
+	},
+}
+
+var errNonPublicAddress = errors.New("address is not public")
+
+// publicAddressOnly is a net.Dialer Control function that refuses loopback,
+// private, link-local (such as the 169.254.169.254 metadata service),
+// multicast and unspecified addresses.
+func publicAddressOnly(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	ip := ap.Addr().Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip) {
+		return fmt.Errorf("%s: %w", ip, errNonPublicAddress)
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598).
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// batchOperation is one item of a batch request. Which fields apply depends
+// on Type:
+//
+//	send_text: to, message
+//	send_file: to, url, caption, filename
+//	react:     chat, id, emoji (empty emoji removes the reaction)
+//	mark_read: chat, ids
+type batchOperation struct {
+	Type     string   `json:"type"`
+	To       string   `json:"to"`
+	Message  string   `json:"message"`
+	URL      string   `json:"url"`
+	Caption  string   `json:"caption"`
+	Filename string   `json:"filename"`
+	Chat     string   `json:"chat"`
+	ID       string   `json:"id"`
+	Emoji    string   `json:"emoji"`
+	IDs      []string `json:"ids"`
+}
+
+type batchRequest struct {
+	Operations  []batchOperation `json:"operations" binding:"required"`
+	StopOnError bool             `json:"stop_on_error"`
+}
+
+func batchHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req batchRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(req.Operations) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "operations must not be empty"})
+			return
+		}
+		if len(req.Operations) > batchMaxOperations {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d operations per batch", batchMaxOperations)})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Minute)
+		defer cancel()
+
+		if err := a.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+
+		if err := a.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+
+		results := make([]gin.H, 0, len(req.Operations))
+		succeeded, failed := 0, 0
+		for i, op := range req.Operations {
+			res, err := runBatchOperation(ctx, a, op, c.GetString(footerContextKey))
+			if res == nil {
+				res = gin.H{}
+			}
+			res["index"] = i
+			res["type"] = op.Type
+			res["ok"] = err == nil
+			if err != nil {
+				res["error"] = err.Error()
+				failed++
+			} else {
+				succeeded++
+			}
+			results = append(results, res)
+			if err != nil && req.StopOnError {
+				break
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"succeeded": succeeded,
+			"failed":    failed,
+			"skipped":   len(req.Operations) - len(results),
+			"results":   results,
+		})
+	}
+}
+
+// runBatchOperation runs one operation; footer is appended to sent text and
+// captions.
+func runBatchOperation(ctx context.Context, a *app.App, op batchOperation, footer string) (gin.H, error) {
+	switch op.Type {
+	case "send_text":
+		to, err := batchJID(op.To, "to")
+		if err != nil {
+			return nil, err
+		}
+		if op.Message == "" {
+			return nil, fmt.Errorf("message is required")
+		}
+		id, err := sendText(ctx, a, to, appendFooter(op.Message, footer), 0)
+		if err != nil {
+			return nil, fmt.Errorf("send failed: %w", err)
+		}
+		return gin.H{"to": to.String(), "id": id}, nil
+
+	case "send_file":
+		to, err := batchJID(op.To, "to")
+		if err != nil {
+			return nil, err
+		}
+		tmpPath, name, mimeType, err := fetchFileURL(ctx, op.URL, op.Filename)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(tmpPath)
+		id, info, err := sendFile(ctx, a, to, tmpPath, name, appendFooter(op.Caption, footer), mimeType, 0, app.ImageLimits{}, "")
+		if err != nil {
+			return nil, fmt.Errorf("send failed: %w", err)
+		}
+		return gin.H{"to": to.String(), "id": id, "filename": info["name"], "media": info["media"]}, nil
+
+	case "react":
+		chat, err := batchJID(op.Chat, "chat")
+		if err != nil {
+			return nil, err
+		}
+		if op.ID == "" {
+			return nil, fmt.Errorf("id is required")
+		}
+		id, err := a.SendReaction(ctx, chat, op.ID, op.Emoji)
+		if err != nil {
+			return nil, fmt.Errorf("react failed: %w", err)
+		}
+		return gin.H{"chat": chat.String(), "id": id}, nil
+
+	case "mark_read":
+		chat, err := batchJID(op.Chat, "chat")
+		if err != nil {
+			return nil, err
+		}
+		if len(op.IDs) == 0 {
+			return nil, fmt.Errorf("ids is required")
+		}
+		n, err := a.MarkRead(ctx, chat, op.IDs)
+		if err != nil {
+			return nil, fmt.Errorf("mark read failed: %w", err)
+		}
+		return gin.H{"chat": chat.String(), "marked": n}, nil
+
+	case "":
+		return nil, fmt.Errorf("type is required")
+	default:
+		return nil, fmt.Errorf("unknown operation type %q (use send_text, send_file, react or mark_read)", op.Type)
+	}
+}
+
+func batchJID(s, field string) (types.JID, error) {
+	if strings.TrimSpace(s) == "" {
+		return types.JID{}, fmt.Errorf("%s is required", field)
+	}
+	jid, err := wa.ParseUserOrJID(s)
+	if err != nil {
+		return types.JID{}, fmt.Errorf("invalid %s: %w", field, err)
+	}
+	return jid, nil
+}
+
+// fetchFileURL downloads rawURL to a temp file. The filename defaults to the
+// last path segment and the MIME type to the response Content-Type.
+func fetchFileURL(ctx context.Context, rawURL, filename string) (tmpPath, name, mimeType string, err error) {
+	rawURL, err = store.ParseHTTPURL(rawURL)
+	if err != nil {
+		return "", "", "", fmt.Errorf("url: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", "", "", err
+	}
+	u := req.URL
+	resp, err := batchHTTPClient.Do(req)
+	if err != nil {
+		return "", "", "", fmt.Errorf("fetch %s: %w", u.Redacted(), err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", "", "", fmt.Errorf("fetch %s: HTTP %d", u.Redacted(), resp.StatusCode)
+	}
+
+	name = strings.TrimSpace(filename)
+	if name == "" {
+		name = path.Base(u.Path)
+	}
+	if name == "" || name == "." || name == "/" {
+		name = "file"
+	}
+	if ct, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil && ct != "application/octet-stream" {
+		mimeType = ct
+	}
+
+	f, err := os.CreateTemp("", "wacli-batch-*")
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to save file: %w", err)
+	}
+	n, err := io.Copy(f, io.LimitReader(resp.Body, batchMaxFileSize+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > batchMaxFileSize {
+		err = fmt.Errorf("file larger than %d MB", batchMaxFileSize>>20)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", "", "", fmt.Errorf("fetch %s: %w", u.Redacted(), err)
+	}
+	return f.Name(), name, mimeType, nil
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPublicAddressOnly(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"93.184.216.34:443":      true,
+		"[2606:4700::1111]:443":  true,
+		"127.0.0.1:80":           false,
+		"10.1.2.3:80":            false,
+		"192.168.0.10:8080":      false,
+		"169.254.169.254:80":     false,
+		"100.100.100.200:80":     false,
+		"0.0.0.0:80":             false,
+		"[::1]:80":               false,
+		"[fe80::1]:80":           false,
+		"[fd00::1]:80":           false,
+		"[::ffff:10.0.0.1]:443":  false,
+		"[::ffff:169.254.1.1]:0": false,
+	} {
+		err := publicAddressOnly("tcp", addr, nil)
+		if public && err != nil {
+			t.Errorf("%s: unexpected error %v", addr, err)
+		}
+		if !public && !errors.Is(err, errNonPublicAddress) {
+			t.Errorf("%s: expected errNonPublicAddress, got %v", addr, err)
+		}
+	}
+}
+
+func TestFetchFileURLRefusesLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("secret"))
+	}))
+	defer srv.Close()
+
+	if _, _, _, err := fetchFileURL(context.Background(), srv.URL+"/file.txt", ""); !errors.Is(err, errNonPublicAddress) {
+		t.Fatalf("expected errNonPublicAddress, got %v", err)
+	}
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+)
+
+func listCallsHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		if err != nil {
+			limit = 50
+		}
+		calls, err := a.DB().ListCalls(c.Query("chat"), c.Query("status"), limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		out := make([]gin.H, 0, len(calls))
+		for _, call := range calls {
+			entry := gin.H{
+				"call_id":    call.ID,
+				"chat_jid":   call.ChatJID,
+				"caller_jid": call.CallerJID,
+				"media":      call.Media,
+				"group":      call.Group,
+				"status":     call.Status,
+				"started_at": call.StartedAt,
+			}
+			if !call.EndedAt.IsZero() {
+				entry["ended_at"] = call.EndedAt
+			}
+			out = append(out, entry)
+		}
+		c.JSON(http.StatusOK, gin.H{"calls": out})
+	}
+}
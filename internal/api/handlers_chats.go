@@ -1,29 +1,38 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
-	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow/types"
 )
 
 func listChatsHandler(app *app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		query := c.DefaultQuery("query", "")
-		limitStr := c.DefaultQuery("limit", "100")
-		limit, err := strconv.Atoi(limitStr)
+		params, err := pageQuery(c, store.SortByLastActivity)
 		if err != nil {
-			limit = 100
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
+		params.Snoozed = c.Query("snoozed") == "true"
 
-		chats, err := app.DB().ListChats(query, limit)
+		chats, next, total, err := app.DB().ListChatsPage(params)
+		if errors.Is(err, store.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"chats": chats})
+		c.JSON(http.StatusOK, gin.H{"chats": chats, "next_cursor": next, "total": total})
 	}
 }
 
@@ -40,3 +49,436 @@ func getChatHandler(app *app.App) gin.HandlerFunc {
 		c.JSON(http.StatusOK, chat)
 	}
 }
+
+type setChatEphemeralRequest struct {
+	Seconds *int `json:"seconds" binding:"required"`
+}
+
+// setChatEphemeralHandler changes the default disappearing-message timer of a chat.
+func setChatEphemeralHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req setChatEphemeralRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := wa.ValidateEphemeralSeconds(*req.Seconds); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		chat, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid chat: " + err.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+
+		if err := app.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+
+		if err := app.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+
+		if err := app.WA().SetDisappearingTimer(ctx, chat, time.Duration(*req.Seconds)*time.Second); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set disappearing timer: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"updated":           true,
+			"chat":              chat.String(),
+			"ephemeral_seconds": *req.Seconds,
+		})
+	}
+}
+
+type chatTypingRequest struct {
+	State string `json:"state" binding:"required"`
+}
+
+func chatTypingHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req chatTypingRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		state, media, err := wa.ParseChatState(req.State)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		chat, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid chat: " + err.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+
+		if err := app.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+
+		if err := app.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+
+		if err := app.WA().SendChatPresence(ctx, chat, state, media); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send chat state: " + err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"sent":  true,
+			"chat":  chat.String(),
+			"state": req.State,
+		})
+	}
+}
+
+type markChatReadRequest struct {
+	// UpTo defaults to the latest message of the chat.
+	UpTo *time.Time `json:"up_to"`
+	// SendReceipts defaults to true; false only updates the local watermark.
+	SendReceipts *bool `json:"send_receipts"`
+}
+
+// markChatReadHandler moves the read watermark of a chat and sends read
+// receipts for the messages it passes.
+func markChatReadHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req markChatReadRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		sendReceipts := req.SendReceipts == nil || *req.SendReceipts
+		var upTo time.Time
+		if req.UpTo != nil {
+			upTo = req.UpTo.UTC()
+		}
+
+		chat, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid chat: " + err.Error()})
+			return
+		}
+		if _, err := app.DB().GetChat(chat.String()); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "chat not found"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+
+		if sendReceipts {
+			if err := app.EnsureAuthed(); err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+				return
+			}
+
+			if err := app.Connect(ctx, false, nil); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+				return
+			}
+		}
+
+		readUntil, marked, err := app.MarkChatRead(ctx, chat, upTo, sendReceipts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "mark read failed: " + err.Error()})
+			return
+		}
+		updated, err := app.DB().GetChat(chat.String())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"chat":          chat.String(),
+			"read_until":    readUntil,
+			"marked":        marked,
+			"receipts_sent": sendReceipts && marked > 0,
+			"unread_count":  updated.UnreadCount,
+		})
+	}
+}
+
+// chatStateHandler archives, pins or mutes a chat on WhatsApp through set
+// and returns the updated chat.
+func chatStateHandler(a *app.App, set func(ctx context.Context, chat types.JID) error) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chat, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid chat: " + err.Error()})
+			return
+		}
+		if _, err := a.DB().GetChat(chat.String()); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "chat not found"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+
+		if err := a.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+
+		if err := a.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+
+		if err := set(ctx, chat); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update chat: " + err.Error()})
+			return
+		}
+		updated, err := a.DB().GetChat(chat.String())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, updated)
+	}
+}
+
+// archiveChatHandler archives (POST) or unarchives (DELETE) a chat.
+func archiveChatHandler(a *app.App, archived bool) gin.HandlerFunc {
+	return chatStateHandler(a, func(ctx context.Context, chat types.JID) error {
+		return a.ArchiveChat(ctx, chat, archived)
+	})
+}
+
+// pinChatHandler pins (POST) or unpins (DELETE) a chat.
+func pinChatHandler(a *app.App, pinned bool) gin.HandlerFunc {
+	return chatStateHandler(a, func(ctx context.Context, chat types.JID) error {
+		return a.PinChat(ctx, chat, pinned)
+	})
+}
+
+// muteChatHandler mutes a chat until a time (RFC3339), for a duration, or
+// forever without until.
+func muteChatHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var d time.Duration
+		if raw := c.Query("until"); raw != "" {
+			if until, err := time.Parse(time.RFC3339, raw); err == nil {
+				d = time.Until(until)
+			} else if d, err = time.ParseDuration(raw); err != nil {
+				d = 0
+			}
+			if d <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "until must be a future RFC3339 time or a positive duration such as 8h"})
+				return
+			}
+		}
+		chatStateHandler(a, func(ctx context.Context, chat types.JID) error {
+			return a.MuteChat(ctx, chat, true, d)
+		})(c)
+	}
+}
+
+// unmuteChatHandler unmutes a chat.
+func unmuteChatHandler(a *app.App) gin.HandlerFunc {
+	return chatStateHandler(a, func(ctx context.Context, chat types.JID) error {
+		return a.MuteChat(ctx, chat, false, 0)
+	})
+}
+
+// deleteChatHandler moves a chat's local history to the trash. Nothing is
+// deleted on WhatsApp.
+func deleteChatHandler(app *app.App, cfg *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid := c.Param("jid")
+		now := time.Now().UTC()
+		if err := app.DB().TrashChat(jid, now); err != nil {
+			if store.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "chat not found"})
+				return
+			}
+			if errors.Is(err, store.ErrLegalHold) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"deleted":  true,
+			"chat":     jid,
+			"purge_at": now.Add(cfg.trashRetention()),
+		})
+	}
+}
+
+func listTrashHandler(app *app.App, cfg *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chats, err := app.DB().ListTrashedChats()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		out := make([]gin.H, 0, len(chats))
+		for _, ch := range chats {
+			out = append(out, gin.H{
+				"jid":           ch.JID,
+				"kind":          ch.Kind,
+				"name":          ch.Name,
+				"message_count": ch.MessageCount,
+				"deleted_at":    ch.DeletedAt,
+				"purge_at":      ch.DeletedAt.Add(cfg.trashRetention()),
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{"chats": out})
+	}
+}
+
+func restoreChatHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid := c.Param("jid")
+		if err := app.DB().RestoreChat(jid); err != nil {
+			if store.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "chat not in trash"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"restored": true, "chat": jid})
+	}
+}
+
+func purgeChatHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid := c.Param("jid")
+		if err := app.PurgeChat(jid); err != nil {
+			if store.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "chat not in trash"})
+				return
+			}
+			if errors.Is(err, store.ErrLegalHold) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"purged": true, "chat": jid})
+	}
+}
+
+func chatSnoozeJSON(s store.ChatSnooze) gin.H {
+	return gin.H{
+		"chat":       s.ChatJID,
+		"until":      s.Until,
+		"remind_to":  s.RemindTo,
+		"note":       s.Note,
+		"quote_last": s.QuoteLast,
+		"created_at": s.CreatedAt,
+	}
+}
+
+// snoozeChatHandler hides a chat from GET /chats until a time (RFC3339) or
+// for a duration, then sends a reminder to follow up on it.
+func snoozeChatHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid chat JID"})
+			return
+		}
+		if _, err := a.DB().GetChat(jid.String()); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "chat not found"})
+			return
+		}
+		raw := c.Query("until")
+		if raw == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "until query parameter is required"})
+			return
+		}
+		until, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			d, derr := time.ParseDuration(raw)
+			if derr != nil || d <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "until must be an RFC3339 time or a positive duration such as 20h"})
+				return
+			}
+			until = time.Now().Add(d)
+		}
+		var remindTo types.JID
+		if s := c.Query("remind_to"); s != "" {
+			if remindTo, err = wa.ParseUserOrJID(s); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid remind_to: " + err.Error()})
+				return
+			}
+		}
+
+		s, err := a.SnoozeChat(jid, until, remindTo, c.Query("note"), c.Query("quote_last") == "true")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, chatSnoozeJSON(s))
+	}
+}
+
+func getChatSnoozeHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid chat JID"})
+			return
+		}
+		s, err := a.DB().GetChatSnooze(jid.String())
+		if store.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "chat is not snoozed"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, chatSnoozeJSON(s))
+	}
+}
+
+// unsnoozeChatHandler returns a chat to GET /chats without a reminder.
+func unsnoozeChatHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid chat JID"})
+			return
+		}
+		ok, err := a.DB().DeleteChatSnooze(jid.String())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "chat is not snoozed"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"chat": jid.String(), "snoozed": false})
+	}
+}
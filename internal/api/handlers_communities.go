@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// listCommunitiesHandler lists communities with the linked groups this
+// account has joined.
+func listCommunitiesHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+		defer cancel()
+
+		if err := app.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+
+		if err := app.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+
+		communities, err := app.Communities(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"communities": communities})
+	}
+}
+
+type createCommunityRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+func createCommunityHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req createCommunityRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 1*time.Minute)
+		defer cancel()
+
+		if err := app.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+
+		if err := app.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+
+		info, err := app.WA().CreateCommunity(ctx, req.Name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		resp := gin.H{"created": true, "jid": info.JID.String(), "name": info.Name}
+		if strings.TrimSpace(req.Description) != "" {
+			// The community exists either way, so a failure here is reported
+			// alongside it rather than as an error.
+			if err := app.WA().SetGroupDescription(ctx, info.JID, req.Description); err != nil {
+				resp["description_error"] = err.Error()
+			} else {
+				resp["description"] = req.Description
+			}
+		}
+
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// listCommunityGroupsHandler lists every group linked to a community,
+// including ones this account has not joined.
+func listCommunityGroupsHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 1*time.Minute)
+		defer cancel()
+
+		if err := app.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+
+		if err := app.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+
+		community, err := types.ParseJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid community JID"})
+			return
+		}
+
+		subs, err := app.WA().GetSubGroups(ctx, community)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		out := make([]gin.H, 0, len(subs))
+		for _, g := range subs {
+			out = append(out, gin.H{
+				"jid":          g.JID.String(),
+				"name":         g.Name,
+				"announcement": g.IsDefaultSubGroup,
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{"community": community.String(), "groups": out})
+	}
+}
+
+type linkCommunityGroupRequest struct {
+	Group string `json:"group" binding:"required"`
+}
+
+// linkCommunityGroupHandler moves an existing group into a community.
+func linkCommunityGroupHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req linkCommunityGroupRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updateCommunityLink(c, app, c.Param("jid"), req.Group, true)
+	}
+}
+
+func unlinkCommunityGroupHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		updateCommunityLink(c, app, c.Param("jid"), c.Param("group"), false)
+	}
+}
+
+func updateCommunityLink(c *gin.Context, app *app.App, communityStr, groupStr string, link bool) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 1*time.Minute)
+	defer cancel()
+
+	if err := app.EnsureAuthed(); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+		return
+	}
+
+	if err := app.Connect(ctx, false, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+		return
+	}
+
+	community, err := types.ParseJID(communityStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid community JID"})
+		return
+	}
+	group, err := types.ParseJID(groupStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group JID"})
+		return
+	}
+
+	if link {
+		err = app.WA().LinkGroup(ctx, community, group)
+	} else {
+		err = app.WA().UnlinkGroup(ctx, community, group)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"linked": link, "community": community.String(), "group": group.String()})
+}
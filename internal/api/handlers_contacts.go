@@ -2,29 +2,41 @@ package api
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
 )
 
 func listContactsHandler(app *app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		limitStr := c.DefaultQuery("limit", "100")
-		limit, err := strconv.Atoi(limitStr)
+		params, err := pageQuery(c, store.SortByName)
+		if err == nil && params.Sort == store.SortByUnread {
+			err = errors.New("sort must be name or last_activity")
+		}
 		if err != nil {
-			limit = 100
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
 
-		contacts, err := app.DB().SearchContacts("", limit)
+		contacts, next, total, err := app.DB().ListContactsPage(params)
+		if errors.Is(err, store.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"contacts": contacts})
+		c.JSON(http.StatusOK, gin.H{"contacts": contacts, "next_cursor": next, "total": total})
 	}
 }
 
@@ -66,6 +78,69 @@ func getContactHandler(app *app.App) gin.HandlerFunc {
 	}
 }
 
+// getBusinessProfileHandler returns the business profile of a WhatsApp
+// Business account: what it does, where it is and when it is open.
+func getBusinessProfileHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil || wa.IsGroupJID(jid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid contact JID"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+
+		if err := a.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+		if err := a.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+
+		p, err := a.WA().GetBusinessProfile(ctx, jid)
+		if errors.Is(err, wa.ErrNotBusiness) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not a business account"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "lookup failed: " + err.Error()})
+			return
+		}
+		hours := make([]gin.H, 0, len(p.Hours))
+		for _, h := range p.Hours {
+			day := gin.H{"day": h.DayOfWeek, "mode": h.Mode}
+			if h.Mode == "specific_hours" {
+				day["open"] = businessTime(h.OpenTime)
+				day["close"] = businessTime(h.CloseTime)
+			}
+			hours = append(hours, day)
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"jid":         p.JID.String(),
+			"description": p.Description,
+			"categories":  p.Categories,
+			"address":     p.Address,
+			"email":       p.Email,
+			"websites":    p.Websites,
+			"timezone":    p.TimeZone,
+			"hours":       hours,
+		})
+	}
+}
+
+// businessTime formats opening hours, which WhatsApp gives in minutes
+// after midnight, as HH:MM.
+func businessTime(minutes string) string {
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 {
+		return minutes
+	}
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
+
 type setAliasRequest struct {
 	Alias string `json:"alias" binding:"required"`
 }
@@ -88,6 +163,63 @@ func setContactAliasHandler(app *app.App) gin.HandlerFunc {
 	}
 }
 
+// addContactTagHandler tags a contact or chat, e.g. "customer", so it can
+// be picked from the contact and chat listings with ?tag=.
+func addContactTagHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contactTagHandler(c, app, app.DB().AddTag)
+	}
+}
+
+func removeContactTagHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contactTagHandler(c, app, app.DB().RemoveTag)
+	}
+}
+
+// contactTagHandler applies a tag change and returns the resulting tags.
+func contactTagHandler(c *gin.Context, app *app.App, change func(jid, tag string) error) {
+	jid, err := wa.ParseUserOrJID(c.Param("jid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JID"})
+		return
+	}
+	tag := strings.TrimSpace(c.Param("tag"))
+	if tag == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tag is required"})
+		return
+	}
+	if err := change(jid.String(), tag); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	tags, err := app.DB().ListTags(jid.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{"jid": jid.String(), "tags": tags})
+}
+
+// listTagsHandler lists the tags in use with how many contacts and chats
+// carry each.
+func listTagsHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tags, err := app.DB().ListAllTags()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if tags == nil {
+			tags = []store.TagCount{}
+		}
+		c.JSON(http.StatusOK, gin.H{"tags": tags})
+	}
+}
+
 func refreshContactsHandler(app *app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
@@ -118,3 +250,122 @@ func refreshContactsHandler(app *app.App) gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{"refreshed": count})
 	}
 }
+
+// maxContactBulkUpdates caps one bulk request.
+const maxContactBulkUpdates = 10000
+
+type contactBulkUpdate struct {
+	JID   string  `json:"jid"`
+	Alias *string `json:"alias"`
+	// Tags replaces the contact's tags when present.
+	Tags   *[]string          `json:"tags"`
+	Fields map[string]*string `json:"fields"`
+}
+
+// bulkUpdateContactsHandler applies alias, tag and field updates for many
+// contacts in one transaction, e.g. a nightly CRM sync.
+func bulkUpdateContactsHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req []contactBulkUpdate
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(req) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "at least one update is required"})
+			return
+		}
+		if len(req) > maxContactBulkUpdates {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d updates per request", maxContactBulkUpdates)})
+			return
+		}
+
+		updates := make([]store.ContactUpdate, 0, len(req))
+		for i, u := range req {
+			jid, err := wa.ParseUserOrJID(u.JID)
+			if err != nil || strings.TrimSpace(u.JID) == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("update %d: invalid jid %q", i, u.JID)})
+				return
+			}
+			up := store.ContactUpdate{JID: jid.String(), Alias: u.Alias, Fields: u.Fields}
+			if u.Tags != nil {
+				up.Tags = *u.Tags
+				up.ReplaceTags = true
+			}
+			updates = append(updates, up)
+		}
+
+		if err := app.DB().UpdateContacts(updates); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, store.ErrInvalidContactUpdate) {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"updated": len(updates)})
+	}
+}
+
+// checkNumbersMax bounds the numbers one check request looks up.
+const checkNumbersMax = 500
+
+// checkNumbersHandler reports which phone numbers have a WhatsApp account,
+// e.g. to validate a CRM import before sending. Answers are cached.
+func checkNumbersHandler(a *app.App, cfg *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var numbers []string
+		for _, v := range c.QueryArray("numbers") {
+			for _, n := range strings.Split(v, ",") {
+				// An unescaped + arrives as a space.
+				if n = strings.TrimSpace(n); n != "" {
+					numbers = append(numbers, n)
+				}
+			}
+		}
+		if len(numbers) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "numbers query parameter is required"})
+			return
+		}
+		if len(numbers) > checkNumbersMax {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d numbers per request, got %d", checkNumbersMax, len(numbers))})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+		defer cancel()
+
+		if err := a.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+		if err := a.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+
+		results, err := a.CheckNumbers(ctx, numbers, cfg.NumberCheckMaxAge, c.Query("refresh") == "true")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "lookup failed: " + err.Error()})
+			return
+		}
+		reachable, unreachable, invalid := 0, 0, 0
+		for _, r := range results {
+			switch {
+			case r.OnWhatsApp == nil:
+				invalid++
+			case *r.OnWhatsApp:
+				reachable++
+			default:
+				unreachable++
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"results":     results,
+			"reachable":   reachable,
+			"unreachable": unreachable,
+			"invalid":     invalid,
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/entity"
+	"github.com/steipete/wacli/internal/store"
+)
+
+func listEntitiesHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		typ := c.Query("type")
+		if typ != "" && !entity.ValidType(typ) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "type must be one of " + strings.Join(entity.Types, ", ")})
+			return
+		}
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		if err != nil {
+			limit = 100
+		}
+
+		var after, before *time.Time
+		if s := c.Query("after"); s != "" {
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				after = &t
+			}
+		}
+		if s := c.Query("before"); s != "" {
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				before = &t
+			}
+		}
+
+		ents, err := app.DB().ListEntities(store.ListEntitiesParams{
+			Type:    typ,
+			ChatJID: c.Query("chat"),
+			Query:   c.Query("q"),
+			After:   after,
+			Before:  before,
+			Limit:   limit,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"entities": ents,
+			"count":    len(ents),
+		})
+	}
+}
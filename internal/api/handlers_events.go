@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+)
+
+// eventsWebSocketHandler upgrades to a WebSocket and streams WhatsApp events
+// as JSON. Optional filters: ?type=message,receipt and ?chat=<jid>,<jid>.
+func eventsWebSocketHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := app.ParseEventFilter(c.Query("type"), c.Query("chat"))
+
+		conn, err := websocket.Accept(c.Writer, c.Request, &websocket.AcceptOptions{
+			// Requests are already authenticated by API key, so any origin may connect.
+			InsecureSkipVerify: true,
+		})
+		if err != nil {
+			// Accept has already written an error response.
+			return
+		}
+		defer conn.CloseNow()
+
+		events, unsubscribe := a.Events().Subscribe(256)
+		defer unsubscribe()
+
+		// Clients only receive; CloseRead handles control frames and cancels
+		// ctx once the client goes away.
+		ctx := conn.CloseRead(c.Request.Context())
+
+		ping := time.NewTicker(30 * time.Second)
+		defer ping.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ping.C:
+				pingCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+				err := conn.Ping(pingCtx)
+				cancel()
+				if err != nil {
+					return
+				}
+			case evt, ok := <-events:
+				if !ok {
+					return
+				}
+				if !filter.Match(evt) {
+					continue
+				}
+				writeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+				err := wsjson.Write(writeCtx, conn, evt)
+				cancel()
+				if err != nil {
+					return
+				}
+			}
+		}
+	}
+}
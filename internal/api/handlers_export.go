@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/store"
+)
+
+// listExportViewsHandler lists the read-model views that can be exported.
+func listExportViewsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		views := make([]gin.H, 0)
+		for _, v := range store.ReadModelViews() {
+			views = append(views, gin.H{
+				"name":        v.Name,
+				"description": v.Description,
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{"views": views})
+	}
+}
+
+// exportViewHandler streams a read-model view as CSV or NDJSON for BI tools
+// that cannot open the SQLite file themselves.
+func exportViewHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		view := c.Param("view")
+		format := c.DefaultQuery("format", "csv")
+		known := false
+		for _, v := range store.ReadModelViews() {
+			known = known || v.Name == view
+		}
+		if !known {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown view"})
+			return
+		}
+
+		contentType := map[string]string{
+			"csv":    "text/csv; charset=utf-8",
+			"ndjson": "application/x-ndjson",
+		}[format]
+		if contentType == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or ndjson"})
+			return
+		}
+
+		var cols []string
+		w := csv.NewWriter(c.Writer)
+		enc := json.NewEncoder(c.Writer)
+		header := func(columns []string) error {
+			cols = columns
+			c.Header("Content-Type", contentType)
+			c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, view, format))
+			if format == "csv" {
+				return w.Write(cols)
+			}
+			return nil
+		}
+		row := func(vals []interface{}) error {
+			if format == "csv" {
+				rec := make([]string, len(vals))
+				for i, v := range vals {
+					rec[i] = exportCell(v)
+				}
+				return w.Write(rec)
+			}
+			obj := make(map[string]interface{}, len(cols))
+			for i, col := range cols {
+				obj[col] = vals[i]
+			}
+			return enc.Encode(obj)
+		}
+		flush := func() error {
+			w.Flush()
+			return w.Error()
+		}
+
+		err := a.DB().ExportReadModel(view, header, row)
+		if err == nil {
+			err = flush()
+		}
+		if err != nil {
+			if !c.Writer.Written() {
+				c.Writer.Header().Del("Content-Type")
+				c.Writer.Header().Del("Content-Disposition")
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			// Too late for an error status; the body simply ends early.
+			_ = c.Error(err)
+		}
+	}
+}
+
+func exportCell(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
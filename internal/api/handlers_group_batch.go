@@ -0,0 +1,241 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// groupBatchMaxGroups bounds the groups one group batch touches.
+const groupBatchMaxGroups = 100
+
+// groupBatchOperation is the change a group batch applies to every group.
+// Which fields apply depends on Type:
+//
+//	participants: action (add, remove, promote, demote), participants
+//	settings:     announce, locked, ephemeral_seconds (any subset)
+//	description:  description (empty removes it)
+type groupBatchOperation struct {
+	Type             string   `json:"type"`
+	Action           string   `json:"action"`
+	Participants     []string `json:"participants"`
+	Announce         *bool    `json:"announce"`
+	Locked           *bool    `json:"locked"`
+	EphemeralSeconds *int     `json:"ephemeral_seconds"`
+	Description      *string  `json:"description"`
+}
+
+type groupBatchRequest struct {
+	Groups []string `json:"groups"`
+	// Community adds the community's linked groups to Groups.
+	Community   string              `json:"community"`
+	Operation   groupBatchOperation `json:"operation"`
+	StopOnError bool                `json:"stop_on_error"`
+}
+
+// groupBatchHandler applies one operation to many groups in order and
+// reports a result per group.
+func groupBatchHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req groupBatchRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		apply, err := compileGroupBatchOperation(req.Operation)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "operation: " + err.Error()})
+			return
+		}
+		var groups []types.JID
+		seen := map[types.JID]bool{}
+		addGroup := func(jid types.JID) {
+			if !seen[jid] {
+				seen[jid] = true
+				groups = append(groups, jid)
+			}
+		}
+		for _, g := range req.Groups {
+			jid, err := types.ParseJID(strings.TrimSpace(g))
+			if err != nil || jid.Server != types.GroupServer {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group JID: " + g})
+				return
+			}
+			addGroup(jid)
+		}
+		var community types.JID
+		if req.Community != "" {
+			community, err = types.ParseJID(strings.TrimSpace(req.Community))
+			if err != nil || community.Server != types.GroupServer {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid community JID"})
+				return
+			}
+		} else if len(groups) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "groups or community is required"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Minute)
+		defer cancel()
+
+		if err := a.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+		if err := a.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+
+		if !community.IsEmpty() {
+			subs, err := a.WA().GetSubGroups(ctx, community)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list community groups: " + err.Error()})
+				return
+			}
+			for _, s := range subs {
+				addGroup(s.JID)
+			}
+		}
+		if len(groups) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "the community has no linked groups"})
+			return
+		}
+		if len(groups) > groupBatchMaxGroups {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d groups per batch, got %d", groupBatchMaxGroups, len(groups))})
+			return
+		}
+
+		results := make([]gin.H, 0, len(groups))
+		succeeded, failed := 0, 0
+		for _, g := range groups {
+			res, err := apply(ctx, a, g)
+			if res == nil {
+				res = gin.H{}
+			}
+			res["group"] = g.String()
+			res["ok"] = err == nil
+			if err != nil {
+				res["error"] = err.Error()
+				failed++
+			} else {
+				succeeded++
+			}
+			results = append(results, res)
+			if err != nil && (req.StopOnError || ctx.Err() != nil) {
+				break
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"type":      req.Operation.Type,
+			"succeeded": succeeded,
+			"failed":    failed,
+			"skipped":   len(groups) - len(results),
+			"results":   results,
+		})
+	}
+}
+
+type groupBatchFunc func(ctx context.Context, a *app.App, group types.JID) (gin.H, error)
+
+// compileGroupBatchOperation validates op once, so a bad request fails
+// before any group is changed.
+func compileGroupBatchOperation(op groupBatchOperation) (groupBatchFunc, error) {
+	switch op.Type {
+	case "participants":
+		action := wa.GroupParticipantAction(op.Action)
+		switch action {
+		case wa.GroupParticipantAdd, wa.GroupParticipantRemove, wa.GroupParticipantPromote, wa.GroupParticipantDemote:
+		default:
+			return nil, fmt.Errorf("action must be add, remove, promote or demote")
+		}
+		if len(op.Participants) == 0 {
+			return nil, fmt.Errorf("participants is required")
+		}
+		users := make([]types.JID, 0, len(op.Participants))
+		for _, p := range op.Participants {
+			jid, err := wa.ParseUserOrJID(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid participant: %s", p)
+			}
+			users = append(users, jid)
+		}
+		return func(ctx context.Context, a *app.App, group types.JID) (gin.H, error) {
+			updated, err := a.WA().UpdateGroupParticipants(ctx, group, users, action)
+			if err != nil {
+				return nil, err
+			}
+			out := make([]gin.H, 0, len(updated))
+			rejected := 0
+			for _, p := range updated {
+				item := gin.H{"jid": p.JID.String()}
+				if p.Error != 0 {
+					item["error_code"] = p.Error
+					rejected++
+				}
+				out = append(out, item)
+			}
+			res := gin.H{"participants": out}
+			if rejected > 0 {
+				return res, fmt.Errorf("%d of %d participants were rejected", rejected, len(out))
+			}
+			return res, nil
+		}, nil
+
+	case "settings":
+		if op.Announce == nil && op.Locked == nil && op.EphemeralSeconds == nil {
+			return nil, fmt.Errorf("set announce, locked or ephemeral_seconds")
+		}
+		if op.EphemeralSeconds != nil {
+			if err := wa.ValidateEphemeralSeconds(*op.EphemeralSeconds); err != nil {
+				return nil, err
+			}
+		}
+		return func(ctx context.Context, a *app.App, group types.JID) (gin.H, error) {
+			res := gin.H{}
+			if op.Announce != nil {
+				if err := a.WA().SetGroupAnnounce(ctx, group, *op.Announce); err != nil {
+					return res, fmt.Errorf("failed to set announce: %w", err)
+				}
+				res["announce"] = *op.Announce
+			}
+			if op.Locked != nil {
+				if err := a.WA().SetGroupLocked(ctx, group, *op.Locked); err != nil {
+					return res, fmt.Errorf("failed to set locked: %w", err)
+				}
+				res["locked"] = *op.Locked
+			}
+			if op.EphemeralSeconds != nil {
+				if err := a.WA().SetDisappearingTimer(ctx, group, time.Duration(*op.EphemeralSeconds)*time.Second); err != nil {
+					return res, fmt.Errorf("failed to set disappearing timer: %w", err)
+				}
+				res["ephemeral_seconds"] = *op.EphemeralSeconds
+			}
+			return res, nil
+		}, nil
+
+	case "description":
+		if op.Description == nil {
+			return nil, fmt.Errorf("description is required")
+		}
+		return func(ctx context.Context, a *app.App, group types.JID) (gin.H, error) {
+			if err := a.WA().SetGroupDescription(ctx, group, *op.Description); err != nil {
+				return nil, err
+			}
+			return gin.H{"description": *op.Description}, nil
+		}, nil
+
+	case "":
+		return nil, fmt.Errorf("type is required")
+	default:
+		return nil, fmt.Errorf("unknown type %q (use participants, settings or description)", op.Type)
+	}
+}
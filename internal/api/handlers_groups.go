@@ -2,12 +2,20 @@ package api
 
 import (
 	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/store"
 	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow"
 	"go.mau.fi/whatsmeow/types"
 )
 
@@ -178,6 +186,180 @@ func updateGroupNameHandler(app *app.App) gin.HandlerFunc {
 	}
 }
 
+type updateGroupDescriptionRequest struct {
+	Description string `json:"description"`
+}
+
+// updateGroupDescriptionHandler replaces the group description; an empty
+// one removes it.
+func updateGroupDescriptionHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jidStr := c.Param("jid")
+		var req updateGroupDescriptionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 1*time.Minute)
+		defer cancel()
+
+		if err := app.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+
+		if err := app.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+
+		groupJID, err := types.ParseJID(jidStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group JID"})
+			return
+		}
+
+		if err := app.WA().SetGroupDescription(ctx, groupJID, req.Description); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"updated": true, "description": req.Description})
+	}
+}
+
+type updateGroupSettingsRequest struct {
+	Announce         *bool `json:"announce"`
+	Locked           *bool `json:"locked"`
+	EphemeralSeconds *int  `json:"ephemeral_seconds"`
+}
+
+// updateGroupSettingsHandler applies whichever of the announce, locked and
+// disappearing-timer settings the request names, in that order.
+func updateGroupSettingsHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jidStr := c.Param("jid")
+		var req updateGroupSettingsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Announce == nil && req.Locked == nil && req.EphemeralSeconds == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update: set announce, locked or ephemeral_seconds"})
+			return
+		}
+		if req.EphemeralSeconds != nil {
+			if err := wa.ValidateEphemeralSeconds(*req.EphemeralSeconds); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 1*time.Minute)
+		defer cancel()
+
+		if err := app.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+
+		if err := app.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+
+		groupJID, err := types.ParseJID(jidStr)
+		if err != nil || groupJID.Server != types.GroupServer {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group JID"})
+			return
+		}
+
+		resp := gin.H{"updated": true, "jid": groupJID.String()}
+		if req.Announce != nil {
+			if err := app.WA().SetGroupAnnounce(ctx, groupJID, *req.Announce); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set announce: " + err.Error()})
+				return
+			}
+			resp["announce"] = *req.Announce
+		}
+		if req.Locked != nil {
+			if err := app.WA().SetGroupLocked(ctx, groupJID, *req.Locked); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set locked: " + err.Error()})
+				return
+			}
+			resp["locked"] = *req.Locked
+		}
+		if req.EphemeralSeconds != nil {
+			if err := app.WA().SetDisappearingTimer(ctx, groupJID, time.Duration(*req.EphemeralSeconds)*time.Second); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set disappearing timer: " + err.Error()})
+				return
+			}
+			resp["ephemeral_seconds"] = *req.EphemeralSeconds
+		}
+
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// maxGroupPhotoBytes bounds uploaded group photos before they are decoded.
+const maxGroupPhotoBytes = 20 << 20
+
+// updateGroupPhotoHandler sets the group photo from a multipart "file",
+// cropped and scaled to the square JPEG WhatsApp expects.
+func updateGroupPhotoHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jidStr := c.Param("jid")
+		file, _, err := c.Request.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(io.LimitReader(file, maxGroupPhotoBytes+1))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(data) > maxGroupPhotoBytes {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "photo must be at most 20 MB"})
+			return
+		}
+		photo, err := app.SquareJPEG(data, app.GroupPhotoSize)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported image: " + err.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+		defer cancel()
+
+		if err := a.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+
+		if err := a.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+
+		groupJID, err := types.ParseJID(jidStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group JID"})
+			return
+		}
+
+		pictureID, err := a.WA().SetGroupPhoto(ctx, groupJID, photo)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"updated": true, "picture_id": pictureID, "size_bytes": len(photo)})
+	}
+}
+
 func getGroupInviteHandler(app *app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		jidStr := c.Param("jid")
@@ -212,6 +394,78 @@ func getGroupInviteHandler(app *app.App) gin.HandlerFunc {
 	}
 }
 
+// revokeGroupInviteHandler invalidates the group's current invite link and
+// returns its replacement.
+func revokeGroupInviteHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jidStr := c.Param("jid")
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 1*time.Minute)
+		defer cancel()
+
+		if err := app.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+
+		if err := app.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+
+		groupJID, err := types.ParseJID(jidStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group JID"})
+			return
+		}
+
+		link, err := app.WA().GetGroupInviteLink(ctx, groupJID, true)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"revoked": true, "jid": groupJID.String(), "link": link})
+	}
+}
+
+// groupInviteInfoHandler describes the group behind an invite code without
+// joining it.
+func groupInviteInfoHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		code := strings.TrimSpace(c.Param("code"))
+		if code == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invite code is required"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 1*time.Minute)
+		defer cancel()
+
+		if err := app.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+
+		if err := app.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+
+		group, err := app.WA().GetGroupInfoFromLink(ctx, code)
+		if err != nil {
+			if errors.Is(err, whatsmeow.ErrInviteLinkInvalid) || errors.Is(err, whatsmeow.ErrInviteLinkRevoked) {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, group)
+	}
+}
+
 type joinGroupRequest struct {
 	InviteCode string `json:"invite_code" binding:"required"`
 }
@@ -278,3 +532,169 @@ func leaveGroupHandler(app *app.App) gin.HandlerFunc {
 		c.JSON(http.StatusOK, gin.H{"left": true})
 	}
 }
+
+// groupHistoryHandler lists recorded membership changes of a group, newest
+// first. It reads the local store only.
+func groupHistoryHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid, err := types.ParseJID(c.Param("jid"))
+		if err != nil || jid.Server != types.GroupServer {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group JID"})
+			return
+		}
+		p := store.ListGroupEventsParams{GroupJID: jid.String()}
+		if s := strings.TrimSpace(c.Query("participant")); s != "" {
+			pj, err := wa.ParseUserOrJID(strings.TrimPrefix(s, "+"))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid participant: " + err.Error()})
+				return
+			}
+			p.ParticipantJID = pj.String()
+		}
+		switch p.Action = c.Query("action"); p.Action {
+		case "", wa.GroupEventAdd, wa.GroupEventJoin, wa.GroupEventRemove, wa.GroupEventLeave, wa.GroupEventPromote, wa.GroupEventDemote:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "action must be one of add, join, remove, leave, promote, demote"})
+			return
+		}
+		if p.After, err = timeQuery(c, "after"); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if p.Before, err = timeQuery(c, "before"); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		p.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+		evs, err := app.DB().ListGroupEvents(p)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		out := make([]gin.H, 0, len(evs))
+		for _, e := range evs {
+			item := gin.H{
+				"id":          e.ID,
+				"action":      e.Action,
+				"participant": e.ParticipantJID,
+				"timestamp":   e.Timestamp,
+			}
+			if e.ParticipantName != "" {
+				item["participant_name"] = e.ParticipantName
+			}
+			if e.ActorJID != "" {
+				item["actor"] = e.ActorJID
+			}
+			if e.ActorName != "" {
+				item["actor_name"] = e.ActorName
+			}
+			out = append(out, item)
+		}
+		c.JSON(http.StatusOK, gin.H{"group": jid.String(), "events": out})
+	}
+}
+
+// groupParticipantsHandler exports a group's participants with names,
+// aliases and tags from the contact store, as JSON or CSV.
+func groupParticipantsHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format := c.DefaultQuery("format", "json")
+		if format != "json" && format != "csv" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 1*time.Minute)
+		defer cancel()
+
+		if err := a.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+		if err := a.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+
+		jid, err := types.ParseJID(c.Param("jid"))
+		if err != nil || jid.Server != types.GroupServer {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group JID"})
+			return
+		}
+
+		members, err := a.GroupMembers(ctx, jid)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if format == "json" {
+			c.JSON(http.StatusOK, gin.H{"group": jid.String(), "count": len(members), "participants": members})
+			return
+		}
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-participants.csv"`, jid.User))
+		w := csv.NewWriter(c.Writer)
+		_ = w.Write([]string{"jid", "phone", "lid", "role", "name", "alias", "tags"})
+		for _, m := range members {
+			_ = w.Write([]string{m.JID, m.Phone, m.LID, m.Role, m.Name, m.Alias, strings.Join(m.Tags, ";")})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			_ = c.Error(err)
+		}
+	}
+}
+
+// groupMembershipHistoryHandler diffs the group's scheduled membership
+// snapshots, newest first, with totals over the returned range.
+func groupMembershipHistoryHandler(a *app.App, cfg *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid, err := types.ParseJID(c.Param("jid"))
+		if err != nil || jid.Server != types.GroupServer {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group JID"})
+			return
+		}
+		after, err := timeQuery(c, "after")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		changedOnly := c.Query("changed") == "true"
+
+		changes, err := a.GroupMembershipHistory(jid.String(), after, limit, cfg.GroupSnapshots.MassLeave)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		out := make([]app.MembershipChange, 0, len(changes))
+		joined, left, massLeaves, growth := 0, 0, 0, 0
+		for _, ch := range changes {
+			joined += len(ch.Joined)
+			left += len(ch.Left)
+			growth += ch.Delta
+			if ch.MassLeave {
+				massLeaves++
+			}
+			if changedOnly && !ch.Baseline && !ch.Changed() {
+				continue
+			}
+			out = append(out, ch)
+		}
+		resp := gin.H{
+			"group":       jid.String(),
+			"snapshots":   len(changes),
+			"joined":      joined,
+			"left":        left,
+			"growth":      growth,
+			"mass_leaves": massLeaves,
+			"changes":     out,
+		}
+		if len(changes) > 0 {
+			resp["member_count"] = changes[0].MemberCount
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+)
+
+func listIdentityChangesHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		if err != nil {
+			limit = 50
+		}
+		since, err := timeQuery(c, "since")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var after time.Time
+		if since != nil {
+			after = *since
+		}
+		changes, err := a.DB().ListIdentityChanges(c.Query("jid"), after, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		out := make([]gin.H, 0, len(changes))
+		for _, ic := range changes {
+			out = append(out, gin.H{
+				"id":         ic.ID,
+				"jid":        ic.JID,
+				"implicit":   ic.Implicit,
+				"changed_at": ic.ChangedAt,
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{"identity_changes": out})
+	}
+}
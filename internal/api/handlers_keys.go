@@ -0,0 +1,159 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/store"
+)
+
+// Managed keys look like wacli_<64 hex chars>; the first apiKeyPrefixLen
+// characters are kept in clear to tell them apart.
+const (
+	apiKeyScheme    = "wacli_"
+	apiKeyPrefixLen = len(apiKeyScheme) + 8
+)
+
+type createAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	// ExpiresIn is a Go duration such as 720h, as an alternative to
+	// ExpiresAt.
+	ExpiresIn string `json:"expires_in"`
+	// RateLimit is the number of requests per minute; 0 means no limit.
+	RateLimit int `json:"rate_limit"`
+}
+
+func apiKeyJSON(k store.APIKey, now time.Time) gin.H {
+	state := "active"
+	switch {
+	case !k.RevokedAt.IsZero():
+		state = "revoked"
+	case !k.Active(now):
+		state = "expired"
+	}
+	out := gin.H{
+		"id":         k.ID,
+		"name":       k.Name,
+		"prefix":     k.Prefix,
+		"key_hash":   k.KeyHash,
+		"rate_limit": k.RateLimit,
+		"state":      state,
+		"created_at": k.CreatedAt,
+	}
+	if !k.ExpiresAt.IsZero() {
+		out["expires_at"] = k.ExpiresAt
+	}
+	if !k.RevokedAt.IsZero() {
+		out["revoked_at"] = k.RevokedAt
+	}
+	if !k.LastUsedAt.IsZero() {
+		out["last_used_at"] = k.LastUsedAt
+	}
+	return out
+}
+
+// createAPIKeyHandler issues a key. The key itself is only in this response;
+// the store keeps its hash.
+func createAPIKeyHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req createAPIKeyRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.RateLimit < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rate_limit must not be negative"})
+			return
+		}
+		now := time.Now().UTC()
+		var expires time.Time
+		switch {
+		case req.ExpiresAt != nil && req.ExpiresIn != "":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "set either expires_at or expires_in"})
+			return
+		case req.ExpiresAt != nil:
+			expires = req.ExpiresAt.UTC()
+		case req.ExpiresIn != "":
+			d, err := time.ParseDuration(req.ExpiresIn)
+			if err != nil || d <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in must be a positive Go duration such as 720h"})
+				return
+			}
+			expires = now.Add(d)
+		}
+		if !expires.IsZero() && !expires.After(now) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at is in the past"})
+			return
+		}
+
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		key := apiKeyScheme + hex.EncodeToString(secret)
+		k, err := a.DB().AddAPIKey(store.APIKey{
+			Name:      strings.TrimSpace(req.Name),
+			Prefix:    key[:apiKeyPrefixLen],
+			KeyHash:   store.HashAPIKey(key),
+			RateLimit: req.RateLimit,
+			ExpiresAt: expires,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		out := apiKeyJSON(k, now)
+		out["key"] = key
+		c.JSON(http.StatusCreated, out)
+	}
+}
+
+// listAPIKeysHandler lists the managed keys; ?revoked=true includes revoked
+// ones.
+func listAPIKeysHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys, err := a.DB().ListAPIKeys(c.Query("revoked") == "true")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		now := time.Now()
+		out := make([]gin.H, 0, len(keys))
+		for _, k := range keys {
+			out = append(out, apiKeyJSON(k, now))
+		}
+		c.JSON(http.StatusOK, gin.H{"keys": out})
+	}
+}
+
+// revokeAPIKeyHandler revokes a managed key; it is rejected from the next
+// request on.
+func revokeAPIKeyHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		k, err := a.DB().RevokeAPIKey(id, time.Now().UTC())
+		if store.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"revoked": true, "id": id, "revoked_at": k.RevokedAt})
+	}
+}
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/store"
+)
+
+// silencedAlert checks an alert from the webhook receiver named webhook
+// against the active maintenance windows. When one matches it records the
+// alert, answers 200 so the sender does not retry, and reports true.
+func silencedAlert(c *gin.Context, a *app.App, webhook, to, message string, labels ...map[string]string) bool {
+	w, ok, err := a.MaintenanceFor(app.AlertLabels(webhook, to, labels...), time.Now().UTC())
+	if err != nil || !ok {
+		return false // never lose an alert to a failed lookup
+	}
+	_ = a.SuppressAlert(w, to, message)
+	respondSuppressed(c, w, 1)
+	return true
+}
+
+// silenceGrafanaAlerts drops the alerts of a Grafana notification that an
+// active maintenance window matches, like Alertmanager silences do per
+// alert. It answers and reports true when none are left.
+func silenceGrafanaAlerts(c *gin.Context, a *app.App, alert *GrafanaAlert, to string) bool {
+	now := time.Now().UTC()
+	all := alert.Alerts
+	alert.Alerts = alert.Alerts[:0:0]
+	var last store.MaintenanceWindow
+	for i := range all {
+		labels := app.AlertLabels("grafana", to, alert.CommonLabels, all[i].Labels)
+		w, ok, err := a.MaintenanceFor(labels, now)
+		if err != nil || !ok {
+			alert.Alerts = append(alert.Alerts, all[i])
+			continue
+		}
+		one := *alert
+		one.Alerts = all[i : i+1]
+		_ = a.SuppressAlert(w, to, formatGrafanaMessage(one))
+		last = w
+	}
+	if len(all) == 0 || len(alert.Alerts) > 0 {
+		return false
+	}
+	respondSuppressed(c, last, len(all))
+	return true
+}
+
+func respondSuppressed(c *gin.Context, w store.MaintenanceWindow, n int) {
+	c.JSON(http.StatusOK, gin.H{
+		"sent":           false,
+		"suppressed":     n,
+		"maintenance_id": w.ID,
+		"mode":           w.Mode,
+		"ends_at":        w.EndsAt,
+	})
+}
+
+// scalarLabels turns the top-level strings, numbers and booleans of a JSON
+// object into labels.
+func scalarLabels(obj map[string]interface{}) map[string]string {
+	out := map[string]string{}
+	for k, v := range obj {
+		switch t := v.(type) {
+		case string:
+			out[k] = t
+		case float64:
+			out[k] = strconv.FormatFloat(t, 'f', -1, 64)
+		case bool:
+			out[k] = strconv.FormatBool(t)
+		}
+	}
+	return out
+}
+
+func maintenanceJSON(w store.MaintenanceWindow, now time.Time) gin.H {
+	state := "expired"
+	switch {
+	case now.Before(w.StartsAt):
+		state = "pending"
+	case w.Active(now):
+		state = "active"
+	}
+	out := gin.H{
+		"id":         w.ID,
+		"matchers":   json.RawMessage(w.Matchers),
+		"mode":       w.Mode,
+		"state":      state,
+		"starts_at":  w.StartsAt,
+		"ends_at":    w.EndsAt,
+		"suppressed": w.Suppressed,
+		"created_at": w.CreatedAt,
+	}
+	if w.Comment != "" {
+		out["comment"] = w.Comment
+	}
+	if w.CreatedBy != "" {
+		out["created_by"] = w.CreatedBy
+	}
+	if !w.SummarizedAt.IsZero() {
+		out["summarized_at"] = w.SummarizedAt
+	}
+	return out
+}
+
+type createMaintenanceRequest struct {
+	Matchers  json.RawMessage `json:"matchers" binding:"required"`
+	Mode      string          `json:"mode"`
+	StartsAt  *time.Time      `json:"starts_at"`
+	EndsAt    *time.Time      `json:"ends_at"`
+	Duration  string          `json:"duration"`
+	Comment   string          `json:"comment"`
+	CreatedBy string          `json:"created_by"`
+}
+
+// createMaintenanceHandler declares a maintenance window. It starts now
+// unless starts_at says otherwise and ends at ends_at or after duration.
+func createMaintenanceHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req createMaintenanceRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if _, err := app.ParseMaintenanceMatchers(string(req.Matchers)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "matchers: " + err.Error()})
+			return
+		}
+
+		now := time.Now().UTC()
+		w := store.MaintenanceWindow{
+			Matchers:  string(req.Matchers),
+			Mode:      req.Mode,
+			Comment:   strings.TrimSpace(req.Comment),
+			CreatedBy: strings.TrimSpace(req.CreatedBy),
+			StartsAt:  now,
+		}
+		if req.StartsAt != nil {
+			w.StartsAt = req.StartsAt.UTC()
+		}
+		switch {
+		case req.EndsAt != nil && req.Duration != "":
+			c.JSON(http.StatusBadRequest, gin.H{"error": "set either ends_at or duration"})
+			return
+		case req.EndsAt != nil:
+			w.EndsAt = req.EndsAt.UTC()
+		case req.Duration != "":
+			d, err := time.ParseDuration(req.Duration)
+			if err != nil || d <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "duration must be a positive Go duration such as 2h or 90m"})
+				return
+			}
+			w.EndsAt = w.StartsAt.Add(d)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at or duration is required"})
+			return
+		}
+		if !w.EndsAt.After(now) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "the window is already over"})
+			return
+		}
+
+		created, err := a.DB().AddMaintenanceWindow(w)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, maintenanceJSON(created, now))
+	}
+}
+
+func listMaintenanceHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := time.Now().UTC()
+		windows, err := a.DB().ListMaintenanceWindows(now, c.Query("expired") == "true")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		out := make([]gin.H, 0, len(windows))
+		for _, w := range windows {
+			out = append(out, maintenanceJSON(w, now))
+		}
+		c.JSON(http.StatusOK, gin.H{"windows": out})
+	}
+}
+
+// getMaintenanceHandler returns a window and, for batching windows, the
+// alerts it held back.
+func getMaintenanceHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		w, err := a.DB().GetMaintenanceWindow(id)
+		if err != nil {
+			if store.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "maintenance window not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		out := maintenanceJSON(w, time.Now().UTC())
+		if w.Mode == store.MaintenanceBatch {
+			alerts, err := a.DB().ListSuppressedAlerts(id)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			held := make([]gin.H, 0, len(alerts))
+			for _, al := range alerts {
+				held = append(held, gin.H{"to": al.Recipient, "message": al.Message, "received_at": al.CreatedAt})
+			}
+			out["alerts"] = held
+		}
+		c.JSON(http.StatusOK, out)
+	}
+}
+
+// expireMaintenanceHandler ends a window now; a batching window's summary
+// goes out within a minute. Windows that have not started are deleted.
+func expireMaintenanceHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		ok, err := a.DB().ExpireMaintenanceWindow(id, time.Now().UTC())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "maintenance window not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"expired": true, "id": id})
+	}
+}
@@ -1,8 +1,11 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -36,20 +39,66 @@ func listMessagesHandler(app *app.App) gin.HandlerFunc {
 			}
 		}
 
-		msgs, err := app.DB().ListMessages(store.ListMessagesParams{
+		msgs, next, err := app.DB().ListMessagesPage(store.ListMessagesParams{
 			ChatJID: chatJID,
 			Limit:   limit,
 			After:   after,
 			Before:  before,
+			Cursor:  c.Query("cursor"),
+			System:  c.Query("system"),
 		})
+		if errors.Is(err, store.ErrInvalidCursor) || errors.Is(err, store.ErrInvalidSystemFilter) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"messages": msgs,
-			"fts":      app.DB().HasFTS(),
+			"messages":    msgs,
+			"next_cursor": next,
+			"fts":         app.DB().HasFTS(),
+		})
+	}
+}
+
+// pollMaxTimeout caps how long a poll request may block.
+const pollMaxTimeout = 2 * time.Minute
+
+func pollMessagesHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := 30 * time.Second
+		if s := c.Query("timeout"); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil || d < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "timeout must be a duration such as 30s"})
+				return
+			}
+			timeout = min(d, pollMaxTimeout)
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		if err != nil {
+			limit = 100
+		}
+
+		msgs, next, err := app.PollMessages(c.Request.Context(), c.Query("chat"), c.Query("since_cursor"), limit, timeout)
+		if errors.Is(err, store.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if msgs == nil {
+			msgs = []store.Message{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"messages":    msgs,
+			"next_cursor": next,
 		})
 	}
 }
@@ -70,23 +119,132 @@ func searchMessagesHandler(app *app.App) gin.HandlerFunc {
 			limit = 100
 		}
 
-		msgs, err := app.DB().SearchMessages(store.SearchMessagesParams{
-			Query:   query,
-			ChatJID: chatJID,
-			Limit:   limit,
-		})
+		params := store.SearchMessagesParams{
+			Query:          query,
+			ChatJID:        chatJID,
+			From:           c.Query("sender"),
+			Type:           c.Query("type"),
+			Limit:          limit,
+			HighlightStart: c.Query("highlight_start"),
+			HighlightEnd:   c.Query("highlight_end"),
+		}
+		if params.FromMe, err = boolQuery(c, "from_me"); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if params.HasLink, err = boolQuery(c, "has_link"); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if params.After, err = timeQuery(c, "after"); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if params.Before, err = timeQuery(c, "before"); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		switch kind := c.Query("chat_kind"); kind {
+		case "", "dm", "group", "broadcast":
+			params.ChatKind = kind
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "chat_kind must be dm, group or broadcast"})
+			return
+		}
+
+		msgs, err := app.DB().SearchMessages(params)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"messages": msgs,
-			"query":    query,
+			"messages":  msgs,
+			"query":     query,
+			"fts":       app.DB().HasFTS(),
+			"fts_state": app.DB().FTSStatus().State,
 		})
 	}
 }
 
+// boolQuery parses an optional boolean query parameter; nil means unset.
+func boolQuery(c *gin.Context, name string) (*bool, error) {
+	s := c.Query(name)
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be true or false", name)
+	}
+	return &v, nil
+}
+
+// timeQuery parses an optional RFC3339 query parameter; nil means unset.
+func timeQuery(c *gin.Context, name string) (*time.Time, error) {
+	s := c.Query(name)
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC3339 timestamp", name)
+	}
+	return &t, nil
+}
+
+// dateQuery parses an optional query parameter given as an RFC3339
+// timestamp or a YYYY-MM-DD date (midnight UTC); nil means unset.
+func dateQuery(c *gin.Context, name string) (*time.Time, error) {
+	s := c.Query(name)
+	if s == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a YYYY-MM-DD date or an RFC3339 timestamp", name)
+	}
+	return &t, nil
+}
+
+// pageQuery parses the query, sort, order, limit and cursor parameters of a
+// paginated listing. Each sort has a natural direction (names A-Z, activity
+// newest first); order=asc|desc overrides it.
+func pageQuery(c *gin.Context, defaultSort string) (store.ListPageParams, error) {
+	p := store.ListPageParams{Query: c.Query("query"), Cursor: c.Query("cursor"), Sort: c.DefaultQuery("sort", defaultSort)}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil {
+		limit = 100
+	}
+	p.Limit = limit
+	for _, t := range c.QueryArray("tag") {
+		p.Tags = append(p.Tags, strings.Split(t, ",")...)
+	}
+	var natural string
+	switch p.Sort {
+	case store.SortByName:
+		natural = "asc"
+	case store.SortByLastActivity, "recent":
+		p.Sort = store.SortByLastActivity
+		natural = "desc"
+	case store.SortByUnread:
+		natural = "desc"
+	default:
+		return p, fmt.Errorf("sort must be name, last_activity (or recent) or unread")
+	}
+	switch order := c.Query("order"); order {
+	case "":
+	case "asc", "desc":
+		p.Reverse = order != natural
+	default:
+		return p, fmt.Errorf("order must be asc or desc")
+	}
+	return p, nil
+}
+
 func getMessageHandler(app *app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		msgID := c.Param("id")
@@ -106,3 +264,153 @@ func getMessageHandler(app *app.App) gin.HandlerFunc {
 		c.JSON(http.StatusOK, msg)
 	}
 }
+
+func messageRevisionsHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		msgID := c.Param("id")
+		chatJID := c.Query("chat")
+		if chatJID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "chat query parameter is required"})
+			return
+		}
+
+		msg, err := app.DB().GetMessage(chatJID, msgID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
+			return
+		}
+		revisions, err := app.DB().ListMessageRevisions(chatJID, msgID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		out := make([]gin.H, 0, len(revisions))
+		for _, r := range revisions {
+			out = append(out, gin.H{
+				"kind":          r.Kind,
+				"text":          r.Text,
+				"display_text":  r.DisplayText,
+				"media_caption": r.MediaCaption,
+				"replaced_at":   r.ReplacedAt,
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"chat_jid":  chatJID,
+			"msg_id":    msgID,
+			"edited":    msg.Edited,
+			"revoked":   msg.Revoked,
+			"revisions": out,
+		})
+	}
+}
+
+func messageThreadHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		msgID := c.Param("id")
+		chatJID := c.Query("chat")
+		if chatJID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "chat query parameter is required"})
+			return
+		}
+
+		if _, err := app.DB().GetMessage(chatJID, msgID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
+			return
+		}
+		msgs, err := app.DB().MessageThread(chatJID, msgID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		// The root is the one message that does not quote another message of
+		// the thread. If it quotes a message we never stored, the start of
+		// the thread is missing.
+		inThread := make(map[string]bool, len(msgs))
+		for _, m := range msgs {
+			inThread[m.MsgID] = true
+		}
+		resp := gin.H{
+			"chat_jid": chatJID,
+			"msg_id":   msgID,
+			"messages": msgs,
+		}
+		for _, m := range msgs {
+			if m.Quoted == nil || !inThread[m.Quoted.ID] {
+				resp["root_id"] = m.MsgID
+				resp["root_missing"] = m.Quoted != nil
+				break
+			}
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// deleteMessageHandler removes a message and its downloaded media from the
+// local store only; the message stays on WhatsApp.
+func deleteMessageHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		msgID := c.Param("id")
+		chatJID := c.Query("chat")
+		if chatJID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "chat query parameter is required"})
+			return
+		}
+
+		removed, err := app.DeleteMessage(chatJID, msgID)
+		if store.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
+			return
+		}
+		if errors.Is(err, store.ErrLegalHold) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"deleted":       true,
+			"chat":          chatJID,
+			"id":            msgID,
+			"media_removed": removed,
+		})
+	}
+}
+
+// deleteChatMessagesHandler removes a chat's messages older than before from
+// the local store, like deleteMessageHandler.
+func deleteChatMessagesHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid := c.Param("jid")
+		before, err := dateQuery(c, "before")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if before == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before query parameter is required"})
+			return
+		}
+
+		deleted, removed, err := app.DeleteMessagesBefore(jid, *before)
+		if errors.Is(err, store.ErrLegalHold) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"chat":          jid,
+			"before":        before.UTC(),
+			"deleted":       deleted,
+			"media_removed": removed,
+		})
+	}
+}
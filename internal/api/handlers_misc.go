@@ -1,8 +1,12 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"mime"
 	"net/http"
 	"time"
 
@@ -43,10 +47,14 @@ func authStatusHandler(app *app.App) gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{
+		resp := gin.H{
 			"authenticated": authed,
 			"connected":     connected,
-		})
+		}
+		if st, ok := app.ConnectionStatus(); ok {
+			resp["connection"] = st
+		}
+		c.JSON(http.StatusOK, resp)
 	}
 }
 
@@ -57,7 +65,7 @@ type syncRequest struct {
 	RefreshGroups   bool `json:"refresh_groups"`
 }
 
-func syncHandler(a *app.App) gin.HandlerFunc {
+func syncHandler(a *app.App, cfg *Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req syncRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -81,6 +89,7 @@ func syncHandler(a *app.App) gin.HandlerFunc {
 			DownloadMedia:   req.DownloadMedia,
 			RefreshContacts: req.RefreshContacts,
 			RefreshGroups:   req.RefreshGroups,
+			Config:          cfg.AppConfig(),
 		})
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -94,7 +103,11 @@ func syncHandler(a *app.App) gin.HandlerFunc {
 	}
 }
 
-func downloadMediaHandler(app *app.App) gin.HandlerFunc {
+// downloadMediaHandler streams a message's media. The first request
+// downloads it from WhatsApp into the store's media directory; later ones
+// are served from there. Range, If-Range and conditional requests are
+// honoured so players can seek without fetching the whole file.
+func downloadMediaHandler(a *app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		mediaID := c.Param("id")
 		chatJID := c.Query("chat")
@@ -104,18 +117,109 @@ func downloadMediaHandler(app *app.App) gin.HandlerFunc {
 			return
 		}
 
-		msg, err := app.DB().GetMessage(chatJID, mediaID)
+		info, err := a.DB().GetMediaDownloadInfo(chatJID, mediaID)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
 			return
 		}
 
-		if msg.MediaType == "" {
+		if info.MediaType == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "message has no media"})
 			return
 		}
 
-		c.JSON(http.StatusNotImplemented, gin.H{"error": "media download not yet implemented in API"})
+		f, obj, err := a.OpenMedia(c.Request.Context(), info)
+		if errors.Is(err, app.ErrNotDownloaded) {
+			if !app.HasDownloadableMedia(info) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "no download metadata stored for this media (run a sync first)"})
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+			defer cancel()
+
+			if err := a.EnsureAuthed(); err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+				return
+			}
+
+			if err := a.Connect(ctx, false, nil); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+				return
+			}
+
+			info.LocalPath, err = a.DownloadMedia(ctx, info)
+			if errors.Is(err, app.ErrMediaExpired) {
+				c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusBadGateway, gin.H{"error": "download failed: " + err.Error()})
+				return
+			}
+			f, obj, err = a.OpenMedia(c.Request.Context(), info)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer f.Close()
+
+		disposition := "attachment"
+		if c.Query("disposition") == "inline" {
+			disposition = "inline"
+		}
+		contentType := info.MimeType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": app.MediaFilename(info)}))
+		c.Header("Cache-Control", "private, max-age=86400")
+		if len(info.FileSHA256) > 0 {
+			// The plaintext hash identifies the content, so it makes a strong
+			// validator for If-Range.
+			c.Header("ETag", `"`+hex.EncodeToString(info.FileSHA256)+`"`)
+		}
+		http.ServeContent(c.Writer, c.Request, "", obj.ModTime, f)
+	}
+}
+
+// mediaThumbnailHandler serves a JPEG thumbnail of an image or video
+// message. It never downloads media, so galleries can call it for every
+// item.
+func mediaThumbnailHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chatJID := c.Query("chat")
+		if chatJID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "chat query parameter is required"})
+			return
+		}
+
+		info, err := a.DB().GetMediaDownloadInfo(chatJID, c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
+			return
+		}
+		if info.MediaType == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "message has no media"})
+			return
+		}
+
+		t, err := a.Thumbnail(c.Request.Context(), info)
+		if errors.Is(err, app.ErrNoThumbnail) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Header("Content-Type", "image/jpeg")
+		c.Header("Cache-Control", "private, max-age=86400")
+		c.Header("X-Thumbnail-Source", t.Source)
+		http.ServeContent(c.Writer, c.Request, "", t.CreatedAt, bytes.NewReader(t.Data))
 	}
 }
 
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+)
+
+// queueText stores a text message in the outbox after a failed connection
+// attempt so it is delivered once WhatsApp is reachable again.
+func queueText(c *gin.Context, app *app.App, to, text string, callback messageCallback, connErr error) {
+	toJID, err := wa.ParseUserOrJID(to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid recipient: " + err.Error()})
+		return
+	}
+	item, err := app.EnqueueText(toJID, text)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + connErr.Error() + "; queueing failed: " + err.Error()})
+		return
+	}
+	resp := gin.H{
+		"sent":      false,
+		"queued":    true,
+		"outbox_id": item.ID,
+		"to":        toJID.String(),
+		"reason":    connErr.Error(),
+	}
+	callback.register(app, resp, toJID.String(), "", item.ID)
+	c.JSON(http.StatusAccepted, resp)
+}
+
+func listOutboxHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := c.Query("status")
+		switch status {
+		case "", store.OutboxPending, store.OutboxSent, store.OutboxFailed:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be one of pending, sent, failed"})
+			return
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+		items, err := app.DB().ListOutbox(status, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		result := make([]gin.H, 0, len(items))
+		for _, it := range items {
+			entry := gin.H{
+				"id":         it.ID,
+				"to":         it.ToJID,
+				"text":       it.Text,
+				"status":     it.Status,
+				"attempts":   it.Attempts,
+				"last_error": it.LastError,
+				"msg_id":     it.MsgID,
+				"created_at": it.CreatedAt,
+				"updated_at": it.UpdatedAt,
+			}
+			if !it.SentAt.IsZero() {
+				entry["sent_at"] = it.SentAt
+			}
+			result = append(result, entry)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"items": result,
+			"count": len(result),
+		})
+	}
+}
+
+func flushOutboxHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+		defer cancel()
+
+		if err := app.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+
+		if err := app.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+
+		res, err := app.FlushOutbox(ctx)
+		body := gin.H{
+			"sent":    res.Sent,
+			"failed":  res.Failed,
+			"pending": res.Left,
+		}
+		if err != nil {
+			body["error"] = err.Error()
+			c.JSON(http.StatusServiceUnavailable, body)
+			return
+		}
+		c.JSON(http.StatusOK, body)
+	}
+}
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+)
+
+type presenceWatchRequest struct {
+	JID string `json:"jid" binding:"required"`
+}
+
+func listPresenceWatchHandler(a *app.App, cfg *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		watches, err := a.DB().ListPresenceWatches()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		out := make([]gin.H, 0, len(watches))
+		for _, w := range watches {
+			out = append(out, gin.H{"jid": w.JID, "name": w.Name, "created_at": w.CreatedAt})
+		}
+		c.JSON(http.StatusOK, gin.H{"enabled": cfg.PresenceWatch, "contacts": out})
+	}
+}
+
+func addPresenceWatchHandler(a *app.App, cfg *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req presenceWatchRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		jid, err := wa.ParseUserOrJID(req.JID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid jid: " + err.Error()})
+			return
+		}
+		if jid.Server != "s.whatsapp.net" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "presence can only be watched for individual contacts"})
+			return
+		}
+		if err := a.DB().AddPresenceWatch(jid.String()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"watching": true, "jid": jid.String(), "enabled": cfg.PresenceWatch})
+	}
+}
+
+func removePresenceWatchHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid jid: " + err.Error()})
+			return
+		}
+		ok, err := a.DB().RemovePresenceWatch(jid.String())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "contact is not watched"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"watching": false, "jid": jid.String()})
+	}
+}
+
+// presenceStatsHandler returns online time per watched contact.
+func presenceStatsHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+		if err != nil || days <= 0 {
+			days = 7
+		}
+		since := time.Now().UTC().AddDate(0, 0, -days)
+		stats, err := a.DB().PresenceStats(since)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		contacts := make([]gin.H, 0, len(stats))
+		for _, s := range stats {
+			entry := gin.H{
+				"jid":            s.JID,
+				"name":           s.Name,
+				"online":         s.Online,
+				"sessions":       s.Sessions,
+				"online_seconds": s.OnlineSeconds,
+			}
+			if !s.LastOnline.IsZero() {
+				entry["first_online_at"] = s.FirstOnline
+				entry["last_online_at"] = s.LastOnline
+			}
+			contacts = append(contacts, entry)
+		}
+		c.JSON(http.StatusOK, gin.H{"since": since, "contacts": contacts})
+	}
+}
+
+// presenceIntervalsHandler returns the recorded online intervals of one contact.
+func presenceIntervalsHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid jid: " + err.Error()})
+			return
+		}
+		days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+		if err != nil || days <= 0 {
+			days = 7
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+		if err != nil {
+			limit = 100
+		}
+
+		since := time.Now().UTC().AddDate(0, 0, -days)
+		ivs, err := a.DB().ListPresenceIntervals(jid.String(), since, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		out := make([]gin.H, 0, len(ivs))
+		for _, iv := range ivs {
+			entry := gin.H{"online_at": iv.OnlineAt}
+			if !iv.OfflineAt.IsZero() {
+				entry["offline_at"] = iv.OfflineAt
+				entry["seconds"] = int64(iv.OfflineAt.Sub(iv.OnlineAt).Seconds())
+			}
+			out = append(out, entry)
+		}
+		c.JSON(http.StatusOK, gin.H{"jid": jid.String(), "since": since, "intervals": out})
+	}
+}
+
+// subscribeContactPresenceHandler asks WhatsApp for a contact's presence
+// updates for the current connection.
+func subscribeContactPresenceHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid jid: " + err.Error()})
+			return
+		}
+		if jid.Server != "s.whatsapp.net" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "presence is only available for individual contacts"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+
+		if err := a.EnsureAuthed(); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+			return
+		}
+
+		if err := a.Connect(ctx, false, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			return
+		}
+
+		if err := a.SubscribeContactPresence(ctx, jid); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to subscribe: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"subscribed": true, "jid": jid.String()})
+	}
+}
+
+// contactPresenceHandler returns the latest presence received for a contact.
+func contactPresenceHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid jid: " + err.Error()})
+			return
+		}
+		p, err := a.DB().GetPresenceState(jid.String())
+		if err != nil {
+			if store.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "no presence received for this contact; subscribe first"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		watched, _ := a.DB().IsPresenceWatched(p.JID)
+		resp := gin.H{
+			"jid":        p.JID,
+			"online":     p.Available,
+			"updated_at": p.UpdatedAt,
+			"watched":    watched,
+		}
+		if !p.LastSeen.IsZero() {
+			resp["last_seen"] = p.LastSeen
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
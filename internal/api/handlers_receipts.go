@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+)
+
+func messageReceiptsHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		msgID := c.Param("id")
+		chatJID := c.Query("chat")
+		if chatJID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "chat query parameter is required"})
+			return
+		}
+
+		receipts, err := a.DB().ListReceipts(chatJID, msgID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		out := make([]gin.H, 0, len(receipts))
+		delivered, read := 0, 0
+		for _, r := range receipts {
+			entry := gin.H{"participant": r.Participant}
+			if !r.DeliveredAt.IsZero() {
+				entry["delivered_at"] = r.DeliveredAt
+				delivered++
+			}
+			if !r.ReadAt.IsZero() {
+				entry["read_at"] = r.ReadAt
+				read++
+			}
+			if !r.PlayedAt.IsZero() {
+				entry["played_at"] = r.PlayedAt
+			}
+			out = append(out, entry)
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"chat_jid":  chatJID,
+			"msg_id":    msgID,
+			"delivered": delivered,
+			"read":      read,
+			"receipts":  out,
+		})
+	}
+}
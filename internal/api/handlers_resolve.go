@@ -0,0 +1,32 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+)
+
+// resolveRecipientHandler shows how a `to` value would be resolved without
+// sending anything. Checking it against WhatsApp is skipped, not an error,
+// when the session is not available.
+func resolveRecipientHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		input, ok := c.GetQuery("input")
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "input is required"})
+			return
+		}
+		check := c.DefaultQuery("check", "true") != "false"
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+		defer cancel()
+		if check && a.EnsureAuthed() == nil {
+			_ = a.Connect(ctx, false, nil)
+		}
+
+		c.JSON(http.StatusOK, a.ResolveRecipient(ctx, input, check))
+	}
+}
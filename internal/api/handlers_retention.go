@@ -0,0 +1,157 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+)
+
+func chatRetentionJSON(r store.ChatRetention, cfg *Config) gin.H {
+	out := gin.H{
+		"chat":           r.ChatJID,
+		"legal_hold":     r.LegalHold,
+		"never_prune":    r.NeverPrune,
+		"retention_days": r.RetentionDays,
+	}
+	// Null means the chat's messages are kept indefinitely.
+	out["effective_retention_days"] = nil
+	switch {
+	case r.Exempt():
+	case r.RetentionDays > 0:
+		out["effective_retention_days"] = r.RetentionDays
+	case cfg.messageRetentionDays() > 0:
+		out["effective_retention_days"] = cfg.messageRetentionDays()
+	}
+	if r.Reason != "" {
+		out["reason"] = r.Reason
+	}
+	if r.UpdatedBy != "" {
+		out["updated_by"] = r.UpdatedBy
+	}
+	if !r.UpdatedAt.IsZero() {
+		out["updated_at"] = r.UpdatedAt
+	}
+	return out
+}
+
+// listChatRetentionHandler lists the chats with their own retention
+// settings next to the store-wide defaults.
+func listChatRetentionHandler(a *app.App, cfg *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := a.DB().ListChatRetention()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		out := make([]gin.H, 0, len(list))
+		holds := 0
+		for _, r := range list {
+			if r.LegalHold {
+				holds++
+			}
+			out = append(out, chatRetentionJSON(r, cfg))
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"default_retention_days": cfg.messageRetentionDays(),
+			"legal_holds":            holds,
+			"chats":                  out,
+		})
+	}
+}
+
+// getChatRetentionHandler returns a chat's retention settings, or the
+// defaults it follows.
+func getChatRetentionHandler(a *app.App, cfg *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid chat JID"})
+			return
+		}
+		r, err := a.DB().GetChatRetention(jid.String())
+		if store.IsNotFound(err) {
+			out := chatRetentionJSON(store.ChatRetention{ChatJID: jid.String()}, cfg)
+			out["default"] = true
+			c.JSON(http.StatusOK, out)
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, chatRetentionJSON(r, cfg))
+	}
+}
+
+type setChatRetentionRequest struct {
+	LegalHold     bool   `json:"legal_hold"`
+	NeverPrune    bool   `json:"never_prune"`
+	RetentionDays int    `json:"retention_days"`
+	Reason        string `json:"reason"`
+	UpdatedBy     string `json:"updated_by"`
+}
+
+// setChatRetentionHandler replaces a chat's retention settings. A legal
+// hold needs a reason, so it can be accounted for when it is released.
+func setChatRetentionHandler(a *app.App, cfg *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid chat JID"})
+			return
+		}
+		var req setChatRetentionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		reason := strings.TrimSpace(req.Reason)
+		if req.LegalHold && reason == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required for a legal hold"})
+			return
+		}
+		if !req.LegalHold && !req.NeverPrune && req.RetentionDays == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "set legal_hold, never_prune or retention_days (DELETE returns the chat to the defaults)"})
+			return
+		}
+		r, err := a.DB().SetChatRetention(store.ChatRetention{
+			ChatJID:       jid.String(),
+			LegalHold:     req.LegalHold,
+			NeverPrune:    req.NeverPrune,
+			RetentionDays: req.RetentionDays,
+			Reason:        reason,
+			UpdatedBy:     strings.TrimSpace(req.UpdatedBy),
+		})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, chatRetentionJSON(r, cfg))
+	}
+}
+
+// deleteChatRetentionHandler returns a chat to the defaults, releasing any
+// legal hold.
+func deleteChatRetentionHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid chat JID"})
+			return
+		}
+		ok, err := a.DB().DeleteChatRetention(jid.String())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "chat follows the defaults"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"chat": jid.String(), "released": true})
+	}
+}
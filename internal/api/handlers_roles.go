@@ -0,0 +1,83 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+)
+
+func senderRoleJSON(r store.SenderRole) gin.H {
+	out := gin.H{"jid": r.JID, "role": r.Role, "updated_at": r.UpdatedAt}
+	if r.Note != "" {
+		out["note"] = r.Note
+	}
+	return out
+}
+
+// listRolesHandler lists the senders with an assigned role.
+func listRolesHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := a.DB().ListSenderRoles()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		out := make([]gin.H, 0, len(list))
+		for _, r := range list {
+			out = append(out, senderRoleJSON(r))
+		}
+		c.JSON(http.StatusOK, gin.H{"roles": out})
+	}
+}
+
+type setRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+	Note string `json:"note"`
+}
+
+// setRoleHandler assigns a role to a sender. Roles are stored per phone
+// number, so every device of the sender shares it.
+func setRoleHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sender JID"})
+			return
+		}
+		var req setRoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		r, err := a.DB().SetSenderRole(jid.ToNonAD().String(), req.Role, req.Note)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, senderRoleJSON(r))
+	}
+}
+
+// deleteRoleHandler makes a sender a plain user again.
+func deleteRoleHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sender JID"})
+			return
+		}
+		ok, err := a.DB().DeleteSenderRole(jid.ToNonAD().String())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "sender has no role"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"jid": jid.ToNonAD().String(), "role": store.RoleUser})
+	}
+}
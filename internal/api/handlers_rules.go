@@ -0,0 +1,186 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/store"
+)
+
+type createRuleRequest struct {
+	Name           string   `json:"name"`
+	Priority       int      `json:"priority"`
+	Chats          []string `json:"chats"`
+	Senders        []string `json:"senders"`
+	Keywords       []string `json:"keywords"`
+	MediaTypes     []string `json:"media_types"`
+	Action         string   `json:"action" binding:"required"`
+	Arg            string   `json:"arg"`
+	MinRole        string   `json:"min_role"`
+	DuplicateMedia bool     `json:"duplicate_media"`
+	Enabled        *bool    `json:"enabled"`
+}
+
+// updateRuleRequest is a partial update: omitted fields are kept.
+type updateRuleRequest struct {
+	Name           *string   `json:"name"`
+	Priority       *int      `json:"priority"`
+	Enabled        *bool     `json:"enabled"`
+	Chats          *[]string `json:"chats"`
+	Senders        *[]string `json:"senders"`
+	Keywords       *[]string `json:"keywords"`
+	MediaTypes     *[]string `json:"media_types"`
+	Action         *string   `json:"action"`
+	Arg            *string   `json:"arg"`
+	MinRole        *string   `json:"min_role"`
+	DuplicateMedia *bool     `json:"duplicate_media"`
+}
+
+func createRuleHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req createRuleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		r, err := a.DB().CreateRule(store.CreateRuleParams{
+			Name:           req.Name,
+			Priority:       req.Priority,
+			Chats:          req.Chats,
+			Senders:        req.Senders,
+			Keywords:       req.Keywords,
+			MediaTypes:     req.MediaTypes,
+			Action:         req.Action,
+			Arg:            req.Arg,
+			MinRole:        req.MinRole,
+			DuplicateMedia: req.DuplicateMedia,
+			Disabled:       req.Enabled != nil && !*req.Enabled,
+		})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, ruleJSON(r))
+	}
+}
+
+func listRulesHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rules, err := a.DB().ListRules(false)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		out := make([]gin.H, 0, len(rules))
+		for _, r := range rules {
+			out = append(out, ruleJSON(r))
+		}
+		c.JSON(http.StatusOK, gin.H{"rules": out})
+	}
+}
+
+func getRuleHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rule id"})
+			return
+		}
+		r, err := a.DB().GetRule(id)
+		if err != nil {
+			if store.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "rule not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, ruleJSON(r))
+	}
+}
+
+func updateRuleHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rule id"})
+			return
+		}
+		var req updateRuleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		r, err := a.DB().UpdateRule(id, store.UpdateRuleParams{
+			Name:           req.Name,
+			Priority:       req.Priority,
+			Enabled:        req.Enabled,
+			Chats:          req.Chats,
+			Senders:        req.Senders,
+			Keywords:       req.Keywords,
+			MediaTypes:     req.MediaTypes,
+			Action:         req.Action,
+			Arg:            req.Arg,
+			MinRole:        req.MinRole,
+			DuplicateMedia: req.DuplicateMedia,
+		})
+		if err != nil {
+			if store.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "rule not found"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, ruleJSON(r))
+	}
+}
+
+func deleteRuleHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rule id"})
+			return
+		}
+		ok, err := a.DB().DeleteRule(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "rule not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"deleted": true, "id": id})
+	}
+}
+
+func ruleJSON(r store.Rule) gin.H {
+	return gin.H{
+		"id":              r.ID,
+		"name":            r.Name,
+		"priority":        r.Priority,
+		"enabled":         r.Enabled,
+		"chats":           nonNil(r.Chats),
+		"senders":         nonNil(r.Senders),
+		"keywords":        nonNil(r.Keywords),
+		"media_types":     nonNil(r.MediaTypes),
+		"action":          r.Action,
+		"arg":             r.Arg,
+		"min_role":        r.MinRole,
+		"duplicate_media": r.DuplicateMedia,
+		"created_at":      r.CreatedAt,
+		"updated_at":      r.UpdatedAt,
+	}
+}
+
+// nonNil makes empty lists encode as [] instead of null.
+func nonNil(items []string) []string {
+	if items == nil {
+		return []string{}
+	}
+	return items
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+)
+
+func payloadSchemaJSON(s store.PayloadSchema) gin.H {
+	return gin.H{
+		"chat":       s.Chat,
+		"schema":     json.RawMessage(s.Schema),
+		"created_at": s.CreatedAt,
+		"updated_at": s.UpdatedAt,
+	}
+}
+
+// checkPayloadSchema validates a generic webhook payload against the schema
+// attached to its recipient chat. It writes a 422 response and returns false
+// if the payload does not match.
+func checkPayloadSchema(c *gin.Context, a *app.App, to string, raw []byte) bool {
+	chat, err := wa.ParseUserOrJID(to)
+	if err != nil {
+		// Reported as an invalid recipient later on.
+		return true
+	}
+	ps, err := a.DB().GetPayloadSchema(chat.String())
+	if err != nil {
+		if store.IsNotFound(err) {
+			return true
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	schema, err := app.CompilePayloadSchema(ps.Schema)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "stored schema for " + ps.Chat + " is invalid: " + err.Error()})
+		return false
+	}
+
+	var payload any
+	if c.ContentType() == gin.MIMEJSON {
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid JSON: " + err.Error()})
+			return false
+		}
+	} else {
+		// Form fields are validated as an object of strings.
+		form := map[string]any{}
+		for k, vs := range c.Request.PostForm {
+			if len(vs) > 0 {
+				form[k] = vs[0]
+			}
+		}
+		payload = form
+	}
+
+	if violations := app.ValidatePayload(schema, payload); len(violations) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "payload does not match the schema for " + ps.Chat,
+			"errors": violations,
+			"schema": json.RawMessage(ps.Schema),
+		})
+		return false
+	}
+	return true
+}
+
+func listPayloadSchemasHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		schemas, err := a.DB().ListPayloadSchemas()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		out := make([]gin.H, 0, len(schemas))
+		for _, s := range schemas {
+			out = append(out, payloadSchemaJSON(s))
+		}
+		c.JSON(http.StatusOK, gin.H{"schemas": out})
+	}
+}
+
+func getPayloadSchemaHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chat, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid chat: " + err.Error()})
+			return
+		}
+		s, err := a.DB().GetPayloadSchema(chat.String())
+		if err != nil {
+			if store.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "no schema for this chat"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, payloadSchemaJSON(s))
+	}
+}
+
+// setPayloadSchemaHandler takes the JSON Schema itself as the request body.
+func setPayloadSchemaHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chat, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid chat: " + err.Error()})
+			return
+		}
+		raw, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body: " + err.Error()})
+			return
+		}
+		if !json.Valid(raw) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "schema must be a JSON document"})
+			return
+		}
+		if _, err := app.CompilePayloadSchema(string(raw)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid schema: " + err.Error()})
+			return
+		}
+		s, err := a.DB().SetPayloadSchema(chat.String(), string(raw))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, payloadSchemaJSON(s))
+	}
+}
+
+func deletePayloadSchemaHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chat, err := wa.ParseUserOrJID(c.Param("jid"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid chat: " + err.Error()})
+			return
+		}
+		ok, err := a.DB().DeletePayloadSchema(chat.String())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no schema for this chat"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"deleted": true, "chat": chat.String()})
+	}
+}
@@ -21,8 +21,42 @@ import (
 )
 
 type sendTextRequest struct {
-	To      string `json:"to" binding:"required"`
-	Message string `json:"message" binding:"required"`
+	To               string `json:"to" binding:"required"`
+	Message          string `json:"message" binding:"required"`
+	EphemeralSeconds int    `json:"ephemeral_seconds"`
+	CallbackURL      string `json:"callback_url"`
+	CallbackSecret   string `json:"callback_secret"`
+}
+
+// messageCallback is the optional callback_url (and signing secret) of a send
+// request, notified when delivery/read receipts for the message arrive.
+type messageCallback struct {
+	URL    string
+	Secret string
+}
+
+func (m messageCallback) validate() error {
+	if m.URL == "" {
+		return nil
+	}
+	_, err := store.ParseHTTPURL(m.URL)
+	return err
+}
+
+// register stores the callback for a sent (msgID) or queued (outboxID)
+// message and adds the outcome to resp. A failure does not undo the send.
+func (m messageCallback) register(a *app.App, resp gin.H, chat, msgID string, outboxID int64) {
+	if m.URL == "" {
+		return
+	}
+	cb, err := a.DB().CreateMessageCallback(store.CreateMessageCallbackParams{
+		ChatJID: chat, MsgID: msgID, OutboxID: outboxID, URL: m.URL, Secret: m.Secret,
+	})
+	if err != nil {
+		resp["callback_error"] = err.Error()
+		return
+	}
+	resp["callback_id"] = cb.ID
 }
 
 func sendTextHandler(app *app.App) gin.HandlerFunc {
@@ -32,6 +66,16 @@ func sendTextHandler(app *app.App) gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if err := wa.ValidateEphemeralSeconds(req.EphemeralSeconds); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		callback := messageCallback{URL: req.CallbackURL, Secret: req.CallbackSecret}
+		if err := callback.validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "callback_url: " + err.Error()})
+			return
+		}
+		req.Message = withFooter(c, req.Message)
 
 		ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
 		defer cancel()
@@ -42,7 +86,12 @@ func sendTextHandler(app *app.App) gin.HandlerFunc {
 		}
 
 		if err := app.Connect(ctx, false, nil); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			if req.EphemeralSeconds > 0 {
+				// Disappearing messages are not queued; their timer would start late.
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+				return
+			}
+			queueText(c, app, req.To, req.Message, callback, err)
 			return
 		}
 
@@ -52,48 +101,76 @@ func sendTextHandler(app *app.App) gin.HandlerFunc {
 			return
 		}
 
-		msgID, err := app.WA().SendText(ctx, toJID, req.Message)
+		msgID, err := sendText(ctx, app, toJID, req.Message, uint32(req.EphemeralSeconds))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "send failed: " + err.Error()})
 			return
 		}
 
-		now := time.Now().UTC()
 		chat := toJID
-		chatName := app.WA().ResolveChatName(ctx, chat, "")
-		kind := chatKindFromJID(chat)
-		_ = app.DB().UpsertChat(chat.String(), kind, chatName, now)
-		_ = app.DB().UpsertMessage(store.UpsertMessageParams{
-			ChatJID:    chat.String(),
-			ChatName:   chatName,
-			MsgID:      string(msgID),
-			SenderJID:  "",
-			SenderName: "me",
-			Timestamp:  now,
-			FromMe:     true,
-			Text:       req.Message,
-		})
-
-		c.JSON(http.StatusOK, gin.H{
+		resp := gin.H{
 			"sent": true,
 			"to":   chat.String(),
 			"id":   msgID,
-		})
+		}
+		if req.EphemeralSeconds > 0 {
+			resp["ephemeral_seconds"] = req.EphemeralSeconds
+		}
+		callback.register(app, resp, chat.String(), msgID, 0)
+		c.JSON(http.StatusOK, resp)
 	}
 }
 
 type sendFileRequest struct {
-	To      string `form:"to" binding:"required"`
-	Caption string `form:"caption"`
+	To               string `form:"to" binding:"required"`
+	Caption          string `form:"caption"`
+	EphemeralSeconds int    `form:"ephemeral_seconds"`
+	CallbackURL      string `form:"callback_url"`
+	CallbackSecret   string `form:"callback_secret"`
+	// Resize downscales images over MaxDimension pixels or 5 MB before
+	// upload.
+	Resize       bool `form:"resize"`
+	MaxDimension int  `form:"max_dimension"`
+	// GIF sends .gif files as looping videos instead of still images.
+	GIF bool `form:"gif"`
 }
 
-func sendFileHandler(app *app.App) gin.HandlerFunc {
+func sendFileHandler(a *app.App, cfg *Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req sendFileRequest
 		if err := c.ShouldBind(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if err := wa.ValidateEphemeralSeconds(req.EphemeralSeconds); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		callback := messageCallback{URL: req.CallbackURL, Secret: req.CallbackSecret}
+		if err := callback.validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "callback_url: " + err.Error()})
+			return
+		}
+		if req.MaxDimension < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "max_dimension must not be negative"})
+			return
+		}
+		var resize app.ImageLimits
+		if req.Resize {
+			resize = app.DefaultImageLimits
+			if req.MaxDimension > 0 {
+				resize.MaxDimension = req.MaxDimension
+			}
+		}
+		gifFFmpeg := ""
+		if req.GIF {
+			gifFFmpeg = cfg.AI.FFmpegPath
+			if gifFFmpeg == "" {
+				gifFFmpeg = "ffmpeg"
+			}
+		}
+		req.Caption = withFooter(c, req.Caption)
 
 		file, header, err := c.Request.FormFile("file")
 		if err != nil {
@@ -105,12 +182,12 @@ func sendFileHandler(app *app.App) gin.HandlerFunc {
 		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Minute)
 		defer cancel()
 
-		if err := app.EnsureAuthed(); err != nil {
+		if err := a.EnsureAuthed(); err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
 			return
 		}
 
-		if err := app.Connect(ctx, false, nil); err != nil {
+		if err := a.Connect(ctx, false, nil); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
 			return
 		}
@@ -140,18 +217,26 @@ func sendFileHandler(app *app.App) gin.HandlerFunc {
 		defer os.Remove(tmpPath)
 
 		// Use the sendFile function from CLI
-		msgID, _, err := sendFile(ctx, app, toJID, tmpPath, header.Filename, req.Caption, "")
+		msgID, info, err := sendFile(ctx, a, toJID, tmpPath, header.Filename, req.Caption, "", uint32(req.EphemeralSeconds), resize, gifFFmpeg)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "send failed: " + err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{
+		resp := gin.H{
 			"sent":     true,
 			"to":       toJID.String(),
 			"id":       msgID,
 			"filename": header.Filename,
-		})
+		}
+		if info["resized"] != "" {
+			resp["resized"] = true
+		}
+		if info["gif"] != "" {
+			resp["gif"] = true
+		}
+		callback.register(a, resp, toJID.String(), msgID, 0)
+		c.JSON(http.StatusOK, resp)
 	}
 }
 
@@ -163,8 +248,41 @@ func chatKindFromJID(jid interface{}) string {
 	return "dm"
 }
 
-// sendFile sends a file message (adapted from cmd/wacli/send_file.go)
-func sendFile(ctx context.Context, a *app.App, to types.JID, filePath, filename, caption, mimeOverride string) (string, map[string]string, error) {
+// sendText sends a text message and stores it as sent by us.
+func sendText(ctx context.Context, a *app.App, to types.JID, text string, ephemeralSeconds uint32) (string, error) {
+	var msgID types.MessageID
+	var err error
+	if ephemeralSeconds > 0 {
+		msg := wa.WithEphemeral(&waProto.Message{Conversation: proto.String(text)}, ephemeralSeconds)
+		msgID, err = a.WA().SendProtoMessage(ctx, to, msg)
+	} else {
+		msgID, err = a.WA().SendText(ctx, to, text)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	chatName := a.WA().ResolveChatName(ctx, to, "")
+	kind := chatKindFromJID(to)
+	_ = a.DB().UpsertChat(to.String(), kind, chatName, now)
+	_ = a.DB().UpsertMessage(store.UpsertMessageParams{
+		ChatJID:    to.String(),
+		ChatName:   chatName,
+		MsgID:      string(msgID),
+		SenderJID:  "",
+		SenderName: "me",
+		Timestamp:  now,
+		FromMe:     true,
+		Text:       text,
+	})
+	return string(msgID), nil
+}
+
+// sendFile sends a file message (adapted from cmd/wacli/send_file.go).
+// Images exceeding resize are shrunk first. With gifFFmpeg set, GIFs are
+// converted to MP4 with that ffmpeg binary and sent as looping videos.
+func sendFile(ctx context.Context, a *app.App, to types.JID, filePath, filename, caption, mimeOverride string, ephemeralSeconds uint32, resize app.ImageLimits, gifFFmpeg string) (string, map[string]string, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return "", nil, err
@@ -186,6 +304,15 @@ func sendFile(ctx context.Context, a *app.App, to types.JID, filePath, filename,
 		mimeType = http.DetectContentType(sniff)
 	}
 
+	gifVideo := false
+	if gifFFmpeg != "" && mimeType == "image/gif" {
+		video, err := app.GIFToMP4(ctx, gifFFmpeg, data)
+		if err != nil {
+			return "", nil, fmt.Errorf("convert gif: %w", err)
+		}
+		data, mimeType, gifVideo = video, "video/mp4", true
+	}
+
 	mediaType := "document"
 	uploadType, _ := wa.MediaTypeFromString("document")
 	switch {
@@ -200,6 +327,17 @@ func sendFile(ctx context.Context, a *app.App, to types.JID, filePath, filename,
 		uploadType, _ = wa.MediaTypeFromString("audio")
 	}
 
+	resized := false
+	if mediaType == "image" {
+		small, ok, err := app.ShrinkImage(data, resize)
+		if err != nil {
+			return "", nil, fmt.Errorf("resize image: %w", err)
+		}
+		if ok {
+			data, mimeType, resized = small, "image/jpeg", true
+		}
+	}
+
 	up, err := a.WA().Upload(ctx, data, uploadType)
 	if err != nil {
 		return "", nil, err
@@ -230,6 +368,7 @@ func sendFile(ctx context.Context, a *app.App, to types.JID, filePath, filename,
 			FileLength:    proto.Uint64(up.FileLength),
 			Mimetype:      proto.String(mimeType),
 			Caption:       proto.String(caption),
+			GifPlayback:   proto.Bool(gifVideo),
 		}
 	case "audio":
 		msg.AudioMessage = &waProto.AudioMessage{
@@ -257,6 +396,8 @@ func sendFile(ctx context.Context, a *app.App, to types.JID, filePath, filename,
 		}
 	}
 
+	msg = wa.WithEphemeral(msg, ephemeralSeconds)
+
 	id, err := a.WA().SendProtoMessage(ctx, to, msg)
 	if err != nil {
 		return "", nil, err
@@ -276,9 +417,16 @@ func sendFile(ctx context.Context, a *app.App, to types.JID, filePath, filename,
 		Text:       caption,
 	})
 
-	return id, map[string]string{
+	meta := map[string]string{
 		"name":      name,
 		"mime_type": mimeType,
 		"media":     mediaType,
-	}, nil
+	}
+	if resized {
+		meta["resized"] = "true"
+	}
+	if gifVideo {
+		meta["gif"] = "true"
+	}
+	return id, meta, nil
 }
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// sentimentStatsHandler returns per-chat sentiment rollups, angriest and most
+// urgent chats first.
+func sentimentStatsHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+		if err != nil || days <= 0 {
+			days = 7
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		if err != nil {
+			limit = 50
+		}
+
+		since := time.Now().UTC().AddDate(0, 0, -days)
+		stats, err := app.DB().ChatSentimentStats(since, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		chats := make([]gin.H, 0, len(stats))
+		for _, s := range stats {
+			entry := gin.H{
+				"chat_jid":        s.ChatJID,
+				"chat_name":       s.ChatName,
+				"scored":          s.Scored,
+				"negative":        s.Negative,
+				"avg_score":       s.AvgScore,
+				"avg_urgency":     s.AvgUrgency,
+				"max_urgency":     s.MaxUrgency,
+				"last_message_at": s.LastMessageTS,
+			}
+			if !s.LastNegative.IsZero() {
+				entry["last_negative_at"] = s.LastNegative
+			}
+			chats = append(chats, entry)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"since": since,
+			"chats": chats,
+		})
+	}
+}
+
+// messageStatsHandler returns message counts per day, chat and sender.
+func messageStatsHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		after, err := timeQuery(c, "after")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		before, err := timeQuery(c, "before")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if err != nil {
+			limit = 20
+		}
+
+		p := store.MessageStatsParams{ChatJID: c.Query("chat"), Limit: limit}
+		if before != nil {
+			p.Before = *before
+		}
+		if after != nil {
+			p.After = *after
+		} else {
+			days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+			if err != nil || days <= 0 {
+				days = 30
+			}
+			end := time.Now().UTC()
+			if before != nil {
+				end = *before
+			}
+			p.After = end.AddDate(0, 0, -days)
+		}
+
+		st, err := app.DB().MessageStats(p)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		days := make([]gin.H, 0, len(st.Days))
+		for _, d := range st.Days {
+			days = append(days, gin.H{"day": d.Day, "total": d.Total, "incoming": d.Incoming, "outgoing": d.Outgoing})
+		}
+		chats := make([]gin.H, 0, len(st.Chats))
+		for _, ch := range st.Chats {
+			chats = append(chats, gin.H{
+				"chat_jid":        ch.ChatJID,
+				"chat_name":       ch.ChatName,
+				"kind":            ch.Kind,
+				"total":           ch.Total,
+				"incoming":        ch.Incoming,
+				"outgoing":        ch.Outgoing,
+				"last_message_at": ch.LastMessageTS,
+			})
+		}
+		senders := make([]gin.H, 0, len(st.Senders))
+		for _, s := range st.Senders {
+			senders = append(senders, gin.H{"sender_jid": s.SenderJID, "sender_name": s.SenderName, "from_me": s.FromMe, "total": s.Total})
+		}
+
+		resp := gin.H{
+			"after":    p.After,
+			"total":    st.Total,
+			"incoming": st.Incoming,
+			"outgoing": st.Outgoing,
+			"days":     days,
+			"chats":    chats,
+			"senders":  senders,
+		}
+		if before != nil {
+			resp["before"] = p.Before
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+// groupHeatmapHandler returns a group's message activity by weekday and hour,
+// overall and per member, from the local archive.
+func groupHeatmapHandler(app *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		jid, err := types.ParseJID(c.Param("jid"))
+		if err != nil || jid.Server != types.GroupServer {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid group JID"})
+			return
+		}
+		after, err := timeQuery(c, "after")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		before, err := timeQuery(c, "before")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		loc, err := time.LoadLocation(c.DefaultQuery("tz", "UTC"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tz must be an IANA time zone such as Europe/Berlin"})
+			return
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		if err != nil || limit <= 0 {
+			limit = 50
+		}
+
+		p := store.ActivityHeatmapParams{ChatJID: jid.String(), Location: loc}
+		if before != nil {
+			p.Before = *before
+		}
+		if after != nil {
+			p.After = *after
+		} else {
+			days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+			if err != nil || days <= 0 {
+				days = 30
+			}
+			end := time.Now().UTC()
+			if before != nil {
+				end = *before
+			}
+			p.After = end.AddDate(0, 0, -days)
+		}
+
+		hm, err := app.DB().ActivityHeatmap(p)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		members := make([]gin.H, 0, len(hm.Members))
+		for i, m := range hm.Members {
+			if i == limit {
+				break
+			}
+			members = append(members, gin.H{
+				"sender_jid":  m.SenderJID,
+				"sender_name": m.SenderName,
+				"from_me":     m.FromMe,
+				"total":       m.Total,
+				"matrix":      m.Counts,
+			})
+		}
+
+		resp := gin.H{
+			"group_jid":     p.ChatJID,
+			"after":         p.After,
+			"tz":            loc.String(),
+			"total":         hm.Total,
+			"matrix":        hm.Counts,
+			"members":       members,
+			"members_total": len(hm.Members),
+		}
+		if before != nil {
+			resp["before"] = p.Before
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
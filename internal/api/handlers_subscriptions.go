@@ -0,0 +1,378 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/store"
+)
+
+// Outgoing webhook subscriptions (wacli -> subscriber). Incoming webhooks
+// that trigger sends live in handlers_webhook.go.
+
+type createWebhookRequest struct {
+	URL       string   `json:"url" binding:"required"`
+	Events    []string `json:"events"`
+	Chats     []string `json:"chats"`
+	Secret    string   `json:"secret"`
+	Enabled   *bool    `json:"enabled"`
+	SkipMuted bool     `json:"skip_muted"`
+}
+
+// updateWebhookRequest is a partial update: omitted fields are kept.
+type updateWebhookRequest struct {
+	URL       *string   `json:"url"`
+	Events    *[]string `json:"events"`
+	Chats     *[]string `json:"chats"`
+	Enabled   *bool     `json:"enabled"`
+	Secret    *string   `json:"secret"`
+	SkipMuted *bool     `json:"skip_muted"`
+	// RotateSecret generates a new secret, returned in the response.
+	RotateSecret bool `json:"rotate_secret"`
+}
+
+func checkEventTypes(events []string) error {
+	for _, e := range events {
+		if !app.IsEventType(e) {
+			return fmt.Errorf("unknown event type: %s", e)
+		}
+	}
+	return nil
+}
+
+func createWebhookHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req createWebhookRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if len(req.Events) == 0 {
+			req.Events = []string{app.EventMessage}
+		}
+		if err := checkEventTypes(req.Events); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		w, err := a.DB().CreateWebhook(store.CreateWebhookParams{
+			URL:       req.URL,
+			Events:    req.Events,
+			Chats:     req.Chats,
+			Secret:    req.Secret,
+			Disabled:  req.Enabled != nil && !*req.Enabled,
+			SkipMuted: req.SkipMuted,
+		})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// The secret is only returned once, on creation.
+		resp := webhookJSON(w)
+		resp["secret"] = w.Secret
+		c.JSON(http.StatusCreated, resp)
+	}
+}
+
+func listWebhooksHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		hooks, err := a.DB().ListWebhooks(false)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		out := make([]gin.H, 0, len(hooks))
+		for _, w := range hooks {
+			out = append(out, webhookJSON(w))
+		}
+		c.JSON(http.StatusOK, gin.H{"webhooks": out})
+	}
+}
+
+func getWebhookHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook id"})
+			return
+		}
+		w, err := a.DB().GetWebhook(id)
+		if err != nil {
+			if store.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, webhookJSON(w))
+	}
+}
+
+func updateWebhookHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook id"})
+			return
+		}
+		var req updateWebhookRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Events != nil {
+			if len(*req.Events) == 0 {
+				*req.Events = []string{app.EventMessage}
+			}
+			if err := checkEventTypes(*req.Events); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.RotateSecret {
+			empty := ""
+			req.Secret = &empty
+		}
+
+		w, err := a.DB().UpdateWebhook(id, store.UpdateWebhookParams{
+			URL:       req.URL,
+			Events:    req.Events,
+			Chats:     req.Chats,
+			Enabled:   req.Enabled,
+			Secret:    req.Secret,
+			SkipMuted: req.SkipMuted,
+		})
+		if err != nil {
+			if store.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		resp := webhookJSON(w)
+		if req.RotateSecret {
+			resp["secret"] = w.Secret
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
+
+func deleteWebhookHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook id"})
+			return
+		}
+		ok, err := a.DB().DeleteWebhook(id)
+		if errors.Is(err, store.ErrWebhookInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"deleted": true, "id": id})
+	}
+}
+
+func webhookJSON(w store.Webhook) gin.H {
+	return gin.H{
+		"id":         w.ID,
+		"url":        w.URL,
+		"events":     nonNil(w.Events),
+		"chats":      nonNil(w.Chats),
+		"enabled":    w.Enabled,
+		"skip_muted": w.SkipMuted,
+		"created_at": w.CreatedAt,
+		"updated_at": w.UpdatedAt,
+	}
+}
+
+// listWebhookFailuresHandler returns dead-lettered deliveries of a subscription.
+func listWebhookFailuresHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook id"})
+			return
+		}
+		if _, err := a.DB().GetWebhook(id); err != nil {
+			if store.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		if err != nil {
+			limit = 50
+		}
+
+		failures, err := a.DB().ListWebhookFailures(id, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		queued, err := a.DB().CountWebhookQueue(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		out := make([]gin.H, 0, len(failures))
+		for _, f := range failures {
+			out = append(out, gin.H{
+				"id":         f.ID,
+				"event":      f.EventType,
+				"payload":    json.RawMessage(f.Payload),
+				"attempts":   f.Attempts,
+				"last_error": f.LastError,
+				"created_at": f.CreatedAt,
+				"failed_at":  f.FailedAt,
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{"webhook_id": id, "queued": queued, "failures": out})
+	}
+}
+
+// redeliverWebhookFailureHandler moves a dead letter back into the queue.
+func redeliverWebhookFailureHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook id"})
+			return
+		}
+		failureID, err := strconv.ParseInt(c.Param("failure_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid failure id"})
+			return
+		}
+
+		deliveryID, err := a.DB().RedeliverWebhookFailure(id, failureID)
+		if err != nil {
+			if store.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "failure not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"queued": true, "webhook_id": id, "delivery_id": deliveryID})
+	}
+}
+
+// listWebhookAttemptsHandler returns the delivery log of a subscription:
+// every attempt with its outcome, newest first, plus a health summary.
+func listWebhookAttemptsHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook id"})
+			return
+		}
+		if _, err := a.DB().GetWebhook(id); err != nil {
+			if store.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "webhook not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		params := store.ListWebhookAttemptsParams{WebhookID: id, ChatJID: c.Query("chat"), MsgID: c.Query("msg_id")}
+		switch c.Query("status") {
+		case "":
+		case "success":
+			ok := true
+			params.Success = &ok
+		case "failed":
+			ok := false
+			params.Success = &ok
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be success or failed"})
+			return
+		}
+		if params.Limit, err = strconv.Atoi(c.DefaultQuery("limit", "50")); err != nil {
+			params.Limit = 50
+		}
+
+		attempts, err := a.DB().ListWebhookAttempts(params)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		health, err := a.DB().WebhookHealth(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		out := make([]gin.H, 0, len(attempts))
+		for _, at := range attempts {
+			out = append(out, gin.H{
+				"id":               at.ID,
+				"delivery_id":      at.DeliveryID,
+				"event":            at.EventType,
+				"chat":             at.ChatJID,
+				"msg_id":           at.MsgID,
+				"attempt":          at.Attempt,
+				"success":          at.Success,
+				"status_code":      at.StatusCode,
+				"error":            at.Error,
+				"latency_ms":       at.Latency.Milliseconds(),
+				"response_snippet": at.ResponseSnippet,
+				"attempted_at":     at.AttemptedAt,
+			})
+		}
+		h := gin.H{"consecutive_failures": health.ConsecutiveFailures, "last_error": health.LastError}
+		if !health.LastSuccessAt.IsZero() {
+			h["last_success_at"] = health.LastSuccessAt
+		}
+		if !health.LastFailureAt.IsZero() {
+			h["last_failure_at"] = health.LastFailureAt
+		}
+		c.JSON(http.StatusOK, gin.H{"webhook_id": id, "health": h, "attempts": out})
+	}
+}
+
+// retryWebhookAttemptHandler queues the payload of a logged attempt again,
+// e.g. to resend an event the receiver lost after acknowledging it.
+func retryWebhookAttemptHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid webhook id"})
+			return
+		}
+		attemptID, err := strconv.ParseInt(c.Param("attempt_id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid attempt id"})
+			return
+		}
+
+		deliveryID, err := a.DB().RetryWebhookAttempt(id, attemptID)
+		if err != nil {
+			if store.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "attempt not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"queued": true, "webhook_id": id, "delivery_id": deliveryID})
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -60,7 +61,7 @@ func webhookGrafanaHandler(app *app.App, cfg *Config) gin.HandlerFunc {
 		// Read raw body for debugging
 		bodyBytes, _ := c.GetRawData()
 		rawPayload := string(bodyBytes)
-		fmt.Printf("DEBUG: Received webhook payload (%d bytes):\n%s\n", len(bodyBytes), rawPayload)
+		fmt.Printf("DEBUG: Received webhook payload (%d bytes):\n%s\n", len(bodyBytes), payloadPreview(bodyBytes))
 
 		// Try to parse as Grafana JSON; if it fails, continue with raw body as message
 		var alert GrafanaAlert
@@ -97,13 +98,19 @@ func webhookGrafanaHandler(app *app.App, cfg *Config) gin.HandlerFunc {
 		}
 		if recipient == "" {
 			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "recipient required: add ?to=PHONE to URL, set X-WhatsApp-To header, or add whatsapp_to annotation in Grafana alert rule",
-				"payload": rawPayload,
-				"help":    "Example URL: /api/v1/webhook/grafana?to=5511999999999",
+				"error":         "recipient required: add ?to=PHONE to URL, set X-WhatsApp-To header, or add whatsapp_to annotation in Grafana alert rule",
+				"payload_bytes": len(bodyBytes),
+				"help":          "Example URL: /api/v1/webhook/grafana?to=5511999999999",
 			})
 			return
 		}
 
+		callback := messageCallback{URL: c.Query("callback_url"), Secret: c.Query("callback_secret")}
+		if err := callback.validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "callback_url: " + err.Error()})
+			return
+		}
+
 		// If JSON parsing failed, use the raw body as the message (fallback for custom templates)
 		if parseErr != nil {
 			trimmed := strings.TrimSpace(rawPayload)
@@ -113,6 +120,10 @@ func webhookGrafanaHandler(app *app.App, cfg *Config) gin.HandlerFunc {
 				fmt.Printf("WARN: Empty body received from Grafana. The webhook Message field in Grafana may need to be cleared. Sending default message.\n")
 				trimmed = "⚠️ Grafana alert received (empty payload — clear the Message field in Grafana Webhook Contact Point to get full alert details)"
 			}
+			if silencedAlert(c, app, "grafana", recipient, trimmed) {
+				return
+			}
+			trimmed = withFooter(c, trimmed)
 			fmt.Printf("DEBUG: Using raw body as message (JSON parse failed), sending to %s\n", recipient)
 
 			ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
@@ -123,7 +134,7 @@ func webhookGrafanaHandler(app *app.App, cfg *Config) gin.HandlerFunc {
 				return
 			}
 			if err := app.Connect(ctx, false, nil); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+				queueText(c, app, recipient, trimmed, callback, err)
 				return
 			}
 
@@ -139,12 +150,18 @@ func webhookGrafanaHandler(app *app.App, cfg *Config) gin.HandlerFunc {
 				return
 			}
 
-			c.JSON(http.StatusOK, gin.H{
+			resp := gin.H{
 				"sent":     true,
 				"to":       toJID.String(),
 				"id":       msgID,
 				"fallback": true,
-			})
+			}
+			callback.register(app, resp, toJID.String(), string(msgID), 0)
+			c.JSON(http.StatusOK, resp)
+			return
+		}
+
+		if silenceGrafanaAlerts(c, app, &alert, recipient) {
 			return
 		}
 
@@ -156,8 +173,11 @@ func webhookGrafanaHandler(app *app.App, cfg *Config) gin.HandlerFunc {
 			return
 		}
 
+		// Format the message
+		message := withFooter(c, formatGrafanaMessage(alert))
+
 		if err := app.Connect(ctx, false, nil); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
+			queueText(c, app, recipient, message, callback, err)
 			return
 		}
 
@@ -167,21 +187,20 @@ func webhookGrafanaHandler(app *app.App, cfg *Config) gin.HandlerFunc {
 			return
 		}
 
-		// Format the message
-		message := formatGrafanaMessage(alert)
-
 		msgID, err := app.WA().SendText(ctx, toJID, message)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "send failed: " + err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{
+		resp := gin.H{
 			"sent":  true,
 			"to":    toJID.String(),
 			"id":    msgID,
 			"alert": alert.Title,
-		})
+		}
+		callback.register(app, resp, toJID.String(), string(msgID), 0)
+		c.JSON(http.StatusOK, resp)
 	}
 }
 
@@ -230,11 +249,21 @@ type GenericWebhookRequest struct {
 	To      string                 `json:"to" form:"to"`
 	Message string                 `json:"message" form:"message"`
 	Data    map[string]interface{} `json:"data"`
+	// CallbackURL receives delivery/read status updates for the message.
+	CallbackURL    string `json:"callback_url" form:"callback_url"`
+	CallbackSecret string `json:"callback_secret" form:"callback_secret"`
 }
 
 // webhookGenericHandler is a flexible webhook handler
 func webhookGenericHandler(app *app.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		raw, err := c.GetRawData()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body: " + err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(raw))
+
 		var req GenericWebhookRequest
 		if err := c.ShouldBind(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -246,40 +275,64 @@ func webhookGenericHandler(app *app.App) gin.HandlerFunc {
 			req.To = c.Query("to")
 		}
 
-		if req.To == "" || req.Message == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "'to' and 'message' are required"})
+		if req.To != "" && !checkPayloadSchema(c, app, req.To, raw) {
 			return
 		}
 
-		ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
-		defer cancel()
-
-		if err := app.EnsureAuthed(); err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+		if req.To == "" || req.Message == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "'to' and 'message' are required"})
 			return
 		}
-
-		if err := app.Connect(ctx, false, nil); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "connection failed: " + err.Error()})
-			return
+		if req.CallbackURL == "" {
+			req.CallbackURL = c.Query("callback_url")
 		}
-
-		toJID, err := wa.ParseUserOrJID(req.To)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid recipient: " + err.Error()})
+		callback := messageCallback{URL: req.CallbackURL, Secret: req.CallbackSecret}
+		if err := callback.validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "callback_url: " + err.Error()})
 			return
 		}
-
-		msgID, err := app.WA().SendText(ctx, toJID, req.Message)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "send failed: " + err.Error()})
+		if silencedAlert(c, app, "generic", req.To, req.Message, scalarLabels(req.Data)) {
 			return
 		}
+		sendWebhookText(c, app, req.To, req.Message, callback)
+	}
+}
+
+// sendWebhookText sends a generic webhook message with the API footer,
+// queueing it in the outbox while WhatsApp is unreachable.
+func sendWebhookText(c *gin.Context, app *app.App, to, message string, callback messageCallback) {
+	message = withFooter(c, message)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+	defer cancel()
+
+	if err := app.EnsureAuthed(); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "not authenticated: " + err.Error()})
+		return
+	}
+
+	if err := app.Connect(ctx, false, nil); err != nil {
+		queueText(c, app, to, message, callback, err)
+		return
+	}
+
+	toJID, err := wa.ParseUserOrJID(to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid recipient: " + err.Error()})
+		return
+	}
+
+	msgID, err := app.WA().SendText(ctx, toJID, message)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "send failed: " + err.Error()})
+		return
+	}
 
-		c.JSON(http.StatusOK, gin.H{
-			"sent": true,
-			"to":   toJID.String(),
-			"id":   msgID,
-		})
+	resp := gin.H{
+		"sent": true,
+		"to":   toJID.String(),
+		"id":   msgID,
 	}
+	callback.register(app, resp, toJID.String(), string(msgID), 0)
+	c.JSON(http.StatusOK, resp)
 }
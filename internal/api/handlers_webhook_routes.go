@@ -0,0 +1,171 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/store"
+)
+
+func webhookRouteJSON(r store.WebhookRoute) gin.H {
+	return gin.H{
+		"name":       r.Name,
+		"mapping":    json.RawMessage(r.Mapping),
+		"url":        "/api/v1/webhook/generic/" + r.Name,
+		"created_at": r.CreatedAt,
+		"updated_at": r.UpdatedAt,
+	}
+}
+
+// webhookRouteHandler receives a payload on a named route, adapts it with
+// the route's field mapping and sends the result like the generic webhook.
+func webhookRouteHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		r, err := a.DB().GetWebhookRoute(c.Param("name"))
+		if err != nil {
+			if store.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "no webhook route named " + c.Param("name")})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		m, err := app.ParseWebhookMapping(r.Mapping)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "stored mapping for " + r.Name + " is invalid: " + err.Error()})
+			return
+		}
+
+		raw, err := c.GetRawData()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body: " + err.Error()})
+			return
+		}
+		mapped, err := m.Apply(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if mapped.To == "" {
+			mapped.To = c.Query("to")
+		}
+		if mapped.To != "" && !checkPayloadSchema(c, a, mapped.To, raw) {
+			return
+		}
+		if mapped.To == "" || mapped.Message == "" {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":   "mapping produced no recipient or message; check the paths against the payload or pass ?to=",
+				"to":      mapped.To,
+				"mapping": json.RawMessage(r.Mapping),
+			})
+			return
+		}
+		if mapped.CallbackURL == "" {
+			mapped.CallbackURL = c.Query("callback_url")
+		}
+		callback := messageCallback{URL: mapped.CallbackURL, Secret: c.Query("callback_secret")}
+		if err := callback.validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "callback_url: " + err.Error()})
+			return
+		}
+
+		var top map[string]interface{}
+		_ = json.Unmarshal(raw, &top) // labels are best-effort; Apply already parsed it
+		if silencedAlert(c, a, "generic", mapped.To, mapped.Message, scalarLabels(top), map[string]string{"route": r.Name}) {
+			return
+		}
+		sendWebhookText(c, a, mapped.To, mapped.Message, callback)
+	}
+}
+
+func listWebhookRoutesHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		routes, err := a.DB().ListWebhookRoutes()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		out := make([]gin.H, 0, len(routes))
+		for _, r := range routes {
+			out = append(out, webhookRouteJSON(r))
+		}
+		c.JSON(http.StatusOK, gin.H{"routes": out})
+	}
+}
+
+func getWebhookRouteHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		r, err := a.DB().GetWebhookRoute(c.Param("name"))
+		if err != nil {
+			if store.IsNotFound(err) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "no webhook route named " + c.Param("name")})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, webhookRouteJSON(r))
+	}
+}
+
+// setWebhookRouteHandler takes the mapping itself as the request body.
+func setWebhookRouteHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+		if !validWebhookRouteName(name) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "route names may only contain letters, digits, '-' and '_'"})
+			return
+		}
+		raw, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body: " + err.Error()})
+			return
+		}
+		if !json.Valid(raw) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mapping must be a JSON object"})
+			return
+		}
+		if _, err := app.ParseWebhookMapping(string(raw)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid mapping: " + err.Error()})
+			return
+		}
+		r, err := a.DB().SetWebhookRoute(name, string(raw))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, webhookRouteJSON(r))
+	}
+}
+
+func deleteWebhookRouteHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, err := a.DB().DeleteWebhookRoute(c.Param("name"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no webhook route named " + c.Param("name")})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"deleted": true, "name": c.Param("name")})
+	}
+}
+
+func validWebhookRouteName(name string) bool {
+	if name == "" || len(name) > 64 {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RouteLimit caps the requests running on the routes matching a pattern.
+type RouteLimit struct {
+	// Concurrency is how many matching requests run at once; 0 means no
+	// limit.
+	Concurrency int `json:"concurrency"`
+	// Queue is how many more requests may wait for a slot. Requests beyond
+	// it are rejected with 429.
+	Queue int `json:"queue"`
+	// MaxWaitSeconds bounds the time a request waits in the queue before it
+	// is rejected with 503; 0 waits until the client gives up.
+	MaxWaitSeconds int `json:"max_wait_seconds"`
+	// TimeoutSeconds sets a deadline on the request once it runs; 0 means
+	// none.
+	TimeoutSeconds int `json:"timeout_seconds"`
+}
+
+// DefaultRouteLimits keeps bursts of syncs, sends and webhook retries from
+// piling onto the single WhatsApp connection.
+var DefaultRouteLimits = map[string]RouteLimit{
+	"POST /api/v1/sync":      {Concurrency: 2},
+	"POST /api/v1/send/*":    {Concurrency: 10, Queue: 100, MaxWaitSeconds: 60},
+	"POST /api/v1/webhook/*": {Concurrency: 10, Queue: 100, MaxWaitSeconds: 60},
+}
+
+func (c *Config) routeLimits() map[string]RouteLimit {
+	if c.RouteLimits == nil {
+		return DefaultRouteLimits
+	}
+	return c.RouteLimits
+}
+
+// routePattern matches requests by method and route path. An empty method
+// matches any; a path ending in * matches every route below it.
+type routePattern struct {
+	method string
+	path   string
+	prefix bool
+}
+
+func parseRoutePattern(s string) (routePattern, error) {
+	var p routePattern
+	s = strings.TrimSpace(s)
+	if method, path, ok := strings.Cut(s, " "); ok {
+		p.method = strings.ToUpper(method)
+		s = strings.TrimSpace(path)
+	}
+	if !strings.HasPrefix(s, "/") {
+		return p, fmt.Errorf("route %q must be a path, optionally preceded by a method", s)
+	}
+	if strings.HasSuffix(s, "*") {
+		p.prefix = true
+		s = strings.TrimSuffix(s, "*")
+	}
+	p.path = s
+	return p, nil
+}
+
+func (p routePattern) matches(method, path string) bool {
+	if p.method != "" && p.method != method {
+		return false
+	}
+	if p.prefix {
+		return strings.HasPrefix(path, p.path)
+	}
+	return path == p.path
+}
+
+func validateRouteLimits(limits map[string]RouteLimit) []error {
+	var errs []error
+	for pattern, l := range limits {
+		if _, err := parseRoutePattern(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("WACLI_API_ROUTE_LIMITS: %w", err))
+		}
+		if l.Concurrency < 0 || l.Queue < 0 || l.MaxWaitSeconds < 0 || l.TimeoutSeconds < 0 {
+			errs = append(errs, fmt.Errorf("WACLI_API_ROUTE_LIMITS: %q has a negative limit", pattern))
+		}
+	}
+	return errs
+}
+
+type routeLimiter struct {
+	pattern routePattern
+	limit   RouteLimit
+	slots   chan struct{}
+	waiting atomic.Int64
+}
+
+// acquire takes a slot, waiting in the queue if allowed. It returns the
+// HTTP status to reject the request with, or 0 once the slot is held.
+func (l *routeLimiter) acquire(ctx context.Context) int {
+	select {
+	case l.slots <- struct{}{}:
+		return 0
+	default:
+	}
+	if l.waiting.Add(1) > int64(l.limit.Queue) {
+		l.waiting.Add(-1)
+		return http.StatusTooManyRequests
+	}
+	defer l.waiting.Add(-1)
+
+	var expired <-chan time.Time
+	if l.limit.MaxWaitSeconds > 0 {
+		t := time.NewTimer(time.Duration(l.limit.MaxWaitSeconds) * time.Second)
+		defer t.Stop()
+		expired = t.C
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return 0
+	case <-expired:
+		return http.StatusServiceUnavailable
+	case <-ctx.Done():
+		return http.StatusServiceUnavailable
+	}
+}
+
+// routeLimits enforces the configured concurrency limits and timeouts. A
+// request matches the exact pattern for its route if there is one, else the
+// longest matching prefix; all routes matching one pattern share its slots.
+func routeLimits(cfg *Config) gin.HandlerFunc {
+	var limiters []*routeLimiter
+	for s, l := range cfg.routeLimits() {
+		p, err := parseRoutePattern(s)
+		if err != nil {
+			continue // reported by Config.Validate
+		}
+		rl := &routeLimiter{pattern: p, limit: l}
+		if l.Concurrency > 0 {
+			rl.slots = make(chan struct{}, l.Concurrency)
+		}
+		limiters = append(limiters, rl)
+	}
+	// Exact patterns first, then longer paths, then method-specific ones.
+	sort.Slice(limiters, func(i, j int) bool {
+		a, b := limiters[i].pattern, limiters[j].pattern
+		if a.prefix != b.prefix {
+			return !a.prefix
+		}
+		if len(a.path) != len(b.path) {
+			return len(a.path) > len(b.path)
+		}
+		return a.method > b.method
+	})
+
+	return func(c *gin.Context) {
+		var l *routeLimiter
+		for _, rl := range limiters {
+			if rl.pattern.matches(c.Request.Method, c.FullPath()) {
+				l = rl
+				break
+			}
+		}
+		if l == nil {
+			c.Next()
+			return
+		}
+
+		if l.slots != nil {
+			if status := l.acquire(c.Request.Context()); status != 0 {
+				c.Header("Retry-After", "1")
+				c.AbortWithStatusJSON(status, gin.H{"error": "too many concurrent requests for this route, retry later"})
+				return
+			}
+			defer func() { <-l.slots }()
+		}
+		if l.limit.TimeoutSeconds > 0 {
+			ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(l.limit.TimeoutSeconds)*time.Second)
+			defer cancel()
+			c.Request = c.Request.WithContext(ctx)
+		}
+		c.Next()
+	}
+}
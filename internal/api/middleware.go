@@ -1,58 +1,189 @@
 package api
 
 import (
-	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/store"
 )
 
-// APIKeyAuth validates the API key from either header or query parameter
-func APIKeyAuth(validKeys []string) gin.HandlerFunc {
-	keyMap := make(map[string]bool)
+const (
+	// apiKeyContextKey holds the API key a request authenticated with, if
+	// known; replayed requests only carry its hash.
+	apiKeyContextKey = "wacli.api_key"
+	// apiKeyHashContextKey holds store.HashAPIKey of that key.
+	apiKeyHashContextKey = "wacli.api_key_hash"
+	// apiKeyIDContextKey holds the store ID of a managed key; it is unset
+	// for keys from WACLI_API_KEYS.
+	apiKeyIDContextKey = "wacli.api_key_id"
+)
+
+// keyTouchInterval limits how often a managed key's last use is written.
+const keyTouchInterval = time.Minute
+
+// APIKeyAuth validates the API key from either header or query parameter.
+// Keys not in validKeys are looked up among the managed keys in db.
+func APIKeyAuth(validKeys []string, db *store.DB) gin.HandlerFunc {
+	keyMap := make(map[string]string, len(validKeys))
 	for _, key := range validKeys {
-		keyMap[key] = true
+		keyMap[store.HashAPIKey(key)] = key
 	}
+	managed := &managedKeys{db: db, windows: map[int64]*rateWindow{}}
 
 	return func(c *gin.Context) {
-		// Try to get key from header first
-		apiKey := c.GetHeader("X-API-Key")
-
-		// Fall back to query parameter
-		if apiKey == "" {
-			apiKey = c.Query("api_key")
+		// Requests replayed from the webhook inbox authenticate with the
+		// hash of the key they were queued with.
+		hash, replayed := c.Request.Context().Value(inboxReplayKeyHash{}).(string)
+		apiKey := ""
+		if !replayed {
+			apiKey = requestAPIKey(c)
+			if apiKey == "" {
+				abortMissingAPIKey(c)
+				return
+			}
+			hash = store.HashAPIKey(apiKey)
 		}
 
-		// Fall back to Authorization header with Bearer scheme
-		if apiKey == "" {
-			auth := c.GetHeader("Authorization")
-			if strings.HasPrefix(auth, "Bearer ") {
-				apiKey = strings.TrimPrefix(auth, "Bearer ")
+		if key, ok := keyMap[hash]; ok {
+			c.Set(apiKeyContextKey, key)
+			c.Set(apiKeyHashContextKey, hash)
+			c.Next()
+			return
+		}
+		now := time.Now().UTC()
+		k, err := db.GetAPIKeyByHash(hash)
+		switch {
+		case store.IsNotFound(err) || (err == nil && !k.RevokedAt.IsZero()):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+			return
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		case !k.Active(now):
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API key expired"})
+			return
+		}
+		// Replays were counted when they were queued.
+		if !replayed && k.RateLimit > 0 {
+			if wait, ok := managed.allow(k.ID, k.RateLimit, now); !ok {
+				c.Header("Retry-After", strconv.Itoa(int(wait.Seconds()+0.999)))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit of this API key exceeded, retry later"})
+				return
 			}
 		}
+		managed.touch(k, now)
 
-		if apiKey == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "API key is required (use X-API-Key header, api_key query param, or Bearer token)",
-			})
-			c.Abort()
-			return
+		if apiKey != "" {
+			c.Set(apiKeyContextKey, apiKey)
 		}
+		c.Set(apiKeyHashContextKey, hash)
+		c.Set(apiKeyIDContextKey, k.ID)
+		c.Next()
+	}
+}
 
-		if !keyMap[apiKey] {
-			fmt.Printf("DEBUG AUTH: received key=%q (len=%d), valid keys: ", apiKey, len(apiKey))
-			for k := range keyMap {
-				fmt.Printf("%q (len=%d) ", k, len(k))
-			}
-			fmt.Println()
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid API key",
-			})
-			c.Abort()
+// forwardedAPIKeyAuth only requires an API key. Proxy frontends use it and
+// leave checking the key to the primary, which also knows the managed keys.
+func forwardedAPIKeyAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if requestAPIKey(c) == "" {
+			abortMissingAPIKey(c)
 			return
 		}
+		c.Next()
+	}
+}
 
+// adminOnly limits the /admin endpoints to the keys in WACLI_API_KEYS, so
+// neither a managed key nor a client certificate alone can export the
+// session, mint keys or otherwise administer the server.
+func adminOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, managed := c.Get(apiKeyIDContextKey)
+		if managed || c.GetString(apiKeyContextKey) == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin endpoints require a key from WACLI_API_KEYS"})
+			return
+		}
 		c.Next()
 	}
 }
+
+func abortMissingAPIKey(c *gin.Context) {
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"error": "API key is required (use X-API-Key header, api_key query param, or Bearer token)",
+	})
+	c.Abort()
+}
+
+func requestAPIKey(c *gin.Context) string {
+	// Try to get key from header first
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	// Fall back to query parameter
+	if key := c.Query("api_key"); key != "" {
+		return key
+	}
+	// Fall back to Authorization header with Bearer scheme
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	return ""
+}
+
+// managedKeys tracks the per-minute request counts and last use of the
+// managed keys.
+type managedKeys struct {
+	db      *store.DB
+	mu      sync.Mutex
+	windows map[int64]*rateWindow
+}
+
+type rateWindow struct {
+	start   time.Time
+	count   int
+	touched time.Time
+}
+
+func (m *managedKeys) window(id int64) *rateWindow {
+	w := m.windows[id]
+	if w == nil {
+		w = &rateWindow{}
+		m.windows[id] = w
+	}
+	return w
+}
+
+// allow counts a request of key id against perMinute requests per minute.
+// When the limit is reached it returns how long until the window resets.
+func (m *managedKeys) allow(id int64, perMinute int, now time.Time) (time.Duration, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w := m.window(id)
+	if now.Sub(w.start) >= time.Minute {
+		w.start, w.count = now, 0
+	}
+	if w.count >= perMinute {
+		return w.start.Add(time.Minute).Sub(now), false
+	}
+	w.count++
+	return 0, true
+}
+
+// touch records the use of k, at most once per keyTouchInterval.
+func (m *managedKeys) touch(k store.APIKey, now time.Time) {
+	m.mu.Lock()
+	w := m.window(k.ID)
+	due := now.Sub(w.touched) >= keyTouchInterval && now.Sub(k.LastUsedAt) >= keyTouchInterval
+	if due {
+		w.touched = now
+	}
+	m.mu.Unlock()
+	if due {
+		_ = m.db.TouchAPIKey(k.ID, now)
+	}
+}
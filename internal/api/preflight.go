@@ -0,0 +1,120 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Preflight modes, set by WACLI_API_PREFLIGHT.
+const (
+	// PreflightStrict (the default) refuses to start with an unsafe setup
+	// unless Insecure is set.
+	PreflightStrict = "strict"
+	// PreflightWarn only logs unsafe setups.
+	PreflightWarn = "warn"
+	// PreflightOff skips the checks.
+	PreflightOff = "off"
+)
+
+// DefaultMinKeyBits is the estimated entropy an API key needs to pass the
+// preflight, e.g. 16 random bytes in hex.
+const DefaultMinKeyBits = 64
+
+// PreflightWarnings checks for setups that expose the API by accident:
+// plain HTTP on all interfaces or API keys that are easy to guess. It
+// returns one warning per problem, none with PreflightOff.
+func (c *Config) PreflightWarnings() []string {
+	if c.PreflightMode == PreflightOff {
+		return nil
+	}
+	var warnings []string
+	if wildcardHost(c.Host) && (c.TLSCert == "" || c.TLSKey == "") {
+		warnings = append(warnings, fmt.Sprintf("serving plain HTTP on all interfaces (%s:%d); set WACLI_API_TLS_CERT and WACLI_API_TLS_KEY, or bind WACLI_API_HOST to 127.0.0.1 behind a TLS proxy", c.Host, c.Port))
+	}
+	weak := 0
+	for _, k := range c.APIKeys {
+		if keyEntropyBits(k) < float64(c.minKeyBits()) {
+			weak++
+		}
+	}
+	if weak > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d of %d API keys are easy to guess (under %d bits of entropy); use random keys, e.g. `openssl rand -hex 32`", weak, len(c.APIKeys), c.minKeyBits()))
+	}
+	return warnings
+}
+
+// RefuseUnsafe reports whether the server must not start with these
+// preflight warnings.
+func (c *Config) RefuseUnsafe(warnings []string) bool {
+	return len(warnings) > 0 && c.preflightMode() == PreflightStrict && !c.Insecure
+}
+
+func (c *Config) preflightMode() string {
+	if c.PreflightMode == "" {
+		return PreflightStrict
+	}
+	return c.PreflightMode
+}
+
+func (c *Config) minKeyBits() int {
+	if c.MinKeyBits <= 0 {
+		return DefaultMinKeyBits
+	}
+	return c.MinKeyBits
+}
+
+// wildcardHost reports whether a listen host accepts connections on every
+// interface.
+func wildcardHost(host string) bool {
+	if host == "" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsUnspecified()
+}
+
+// keyEntropyBits estimates the entropy of a key as its length times the
+// Shannon entropy of its characters. It underrates short random keys a
+// little but catches words, repeats and small alphabets.
+func keyEntropyBits(k string) float64 {
+	counts := map[rune]int{}
+	n := 0
+	for _, r := range k {
+		counts[r]++
+		n++
+	}
+	var h float64
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		h -= p * math.Log2(p)
+	}
+	return float64(n) * h
+}
+
+// readyzHandler reports that the server is up. It needs no API key, so the
+// preflight warnings are left to the log and preflightHandler.
+func readyzHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ready"})
+	}
+}
+
+// preflightHandler reports the preflight mode and warnings of the server's
+// setup.
+func preflightHandler(cfg *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		warnings := cfg.PreflightWarnings()
+		if warnings == nil {
+			warnings = []string{}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"preflight": cfg.preflightMode(),
+			"insecure":  cfg.Insecure,
+			"warnings":  warnings,
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Proxy mode lets extra wacli-api instances run without a WhatsApp session.
+// They require an API key and forward requests over gRPC to the primary,
+// which checks the key and replays each one through its own router. The wire format is
+// a single generic Forward call encoded as JSON, so there is no generated code
+// to keep in sync with the HTTP routes. Both sides authenticate each other
+// with certificates signed by WACLI_API_GRPC_TLS_CA.
+
+const proxyServiceName = "wacli.v1.Primary"
+
+// proxyMaxMessage bounds forwarded request and response bodies (media).
+var proxyMaxMessage = 64 << 20
+
+type forwardRequest struct {
+	Method string              `json:"method"`
+	Path   string              `json:"path"`
+	Header map[string][]string `json:"header,omitempty"`
+	Body   []byte              `json:"body,omitempty"`
+	// ClientCerts is the certificate chain the client presented to the
+	// frontend, leaf first; the primary verifies it again.
+	ClientCerts [][]byte `json:"client_certs,omitempty"`
+}
+
+type forwardResponse struct {
+	Status int                 `json:"status"`
+	Header map[string][]string `json:"header,omitempty"`
+	Body   []byte              `json:"body,omitempty"`
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                       { return "json" }
+
+type primaryServer interface {
+	Forward(ctx context.Context, req *forwardRequest) (*forwardResponse, error)
+}
+
+var proxyServiceDesc = grpc.ServiceDesc{
+	ServiceName: proxyServiceName,
+	HandlerType: (*primaryServer)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Forward",
+		Handler: func(srv any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+			var req forwardRequest
+			if err := dec(&req); err != nil {
+				return nil, err
+			}
+			return srv.(primaryServer).Forward(ctx, &req)
+		},
+	}},
+	Streams: []grpc.StreamDesc{},
+}
+
+// routerForwarder serves forwarded requests with the primary's HTTP router.
+type routerForwarder struct {
+	handler http.Handler
+	// clientCAs verifies forwarded client certificates; nil without
+	// WACLI_API_TLS_CLIENT_CA.
+	clientCAs *x509.CertPool
+}
+
+func (f routerForwarder) Forward(ctx context.Context, req *forwardRequest) (*forwardResponse, error) {
+	if !verifiedFrontend(ctx) {
+		return nil, status.Error(codes.Unauthenticated, "frontend presented no verified certificate")
+	}
+	if !strings.HasPrefix(req.Path, "/api/v1/") {
+		return &forwardResponse{Status: http.StatusNotFound}, nil
+	}
+	r, err := http.NewRequestWithContext(ctx, req.Method, req.Path, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, err
+	}
+	r.Header = http.Header(req.Header)
+	// The frontend is the peer; the client address travels in X-Forwarded-For.
+	r.RemoteAddr = "127.0.0.1:0"
+	r.TLS = f.clientCert(req.ClientCerts)
+	rec := httptest.NewRecorder()
+	f.handler.ServeHTTP(rec, r)
+	return &forwardResponse{
+		Status: rec.Code,
+		Header: rec.Header(),
+		Body:   rec.Body.Bytes(),
+	}, nil
+}
+
+// verifiedFrontend reports whether the gRPC peer presented a certificate
+// signed by WACLI_API_GRPC_TLS_CA.
+func verifiedFrontend(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	return ok && len(info.State.VerifiedChains) > 0
+}
+
+// clientCert verifies a forwarded client certificate chain against the
+// primary's own client CAs, as its TLS listener would, and returns the
+// connection state clientCertAuth reads. It returns nil when there is no
+// chain or it does not verify.
+func (f routerForwarder) clientCert(chain [][]byte) *tls.ConnectionState {
+	if f.clientCAs == nil || len(chain) == 0 {
+		return nil
+	}
+	certs := make([]*x509.Certificate, 0, len(chain))
+	intermediates := x509.NewCertPool()
+	for i, der := range chain {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil
+		}
+		if i > 0 {
+			intermediates.AddCert(cert)
+		}
+		certs = append(certs, cert)
+	}
+	verified, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         f.clientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return nil
+	}
+	return &tls.ConnectionState{PeerCertificates: certs, VerifiedChains: verified}
+}
+
+// proxyTLSConfig returns the mutual TLS settings of the gRPC channel, for
+// the primary's listener when server is set and for frontends otherwise.
+func (c *Config) proxyTLSConfig(server bool) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.GRPCTLSCert, c.GRPCTLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("load gRPC certificate: %w", err)
+	}
+	pool, err := loadClientCAs(c.GRPCTLSCA)
+	if err != nil {
+		return nil, fmt.Errorf("load gRPC CA: %w", err)
+	}
+	if server {
+		return &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			ClientCAs:    pool,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			MinVersion:   tls.VersionTLS12,
+		}, nil
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, RootCAs: pool, MinVersion: tls.VersionTLS12}, nil
+}
+
+// NewPrimaryServer returns a gRPC server that answers proxy frontends by
+// replaying their requests through handler (the primary's router). Only
+// frontends with a certificate signed by cfg.GRPCTLSCA can connect.
+func NewPrimaryServer(handler http.Handler, cfg *Config) (*grpc.Server, error) {
+	tlsCfg, err := cfg.proxyTLSConfig(true)
+	if err != nil {
+		return nil, err
+	}
+	fwd := routerForwarder{handler: handler}
+	if cfg.TLSClientCA != "" {
+		if fwd.clientCAs, err = loadClientCAs(cfg.TLSClientCA); err != nil {
+			return nil, err
+		}
+	}
+	s := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(tlsCfg)),
+		grpc.ForceServerCodec(jsonCodec{}),
+		grpc.MaxRecvMsgSize(proxyMaxMessage),
+		grpc.MaxSendMsgSize(proxyMaxMessage),
+	)
+	s.RegisterService(&proxyServiceDesc, fwd)
+	return s, nil
+}
+
+// PrimaryClient forwards requests from a proxy frontend to the primary.
+type PrimaryClient struct {
+	conn *grpc.ClientConn
+}
+
+// DialPrimary connects to the primary's gRPC listener at cfg.PrimaryAddr,
+// presenting cfg.GRPCTLSCert. The connection is established lazily and
+// re-established by gRPC when the primary restarts.
+func DialPrimary(cfg *Config) (*PrimaryClient, error) {
+	addr := cfg.PrimaryAddr
+	tlsCfg, err := cfg.proxyTLSConfig(false)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(credentials.NewTLS(tlsCfg)),
+		grpc.WithDefaultCallOptions(
+			grpc.ForceCodec(jsonCodec{}),
+			grpc.MaxCallRecvMsgSize(proxyMaxMessage),
+			grpc.MaxCallSendMsgSize(proxyMaxMessage),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("dial primary %s: %w", addr, err)
+	}
+	return &PrimaryClient{conn: conn}, nil
+}
+
+func (p *PrimaryClient) Close() error {
+	return p.conn.Close()
+}
+
+func (p *PrimaryClient) forward(ctx context.Context, req *forwardRequest) (*forwardResponse, error) {
+	var resp forwardResponse
+	if err := p.conn.Invoke(ctx, "/"+proxyServiceName+"/Forward", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SetupProxyRoutes registers the routes of a proxy frontend: health,
+// readiness and the web UI are served locally, everything under /api/v1 goes
+// to the primary.
+func SetupProxyRoutes(router *gin.Engine, primary *PrimaryClient, cfg *Config) {
+	router.GET("/health", healthHandler)
+	router.GET("/readyz", readyzHandler())
+	router.StaticFile("/", "./web/index.html")
+	router.Static("/static", "./web/static")
+
+	v1 := router.Group("/api/v1")
+	v1.Use(mediaURLAuth(cfg, clientCertAuth(cfg, forwardedAPIKeyAuth())))
+	v1.Any("/*path", forwardHandler(primary))
+}
+
+func forwardHandler(primary *PrimaryClient) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Streams need the socket owner; clients connect to the primary.
+		switch c.Param("path") {
+		case "/events/ws":
+			c.JSON(http.StatusNotImplemented, gin.H{"error": "event stream is only served by the primary"})
+			return
+		case "/auth/qr/stream":
+			c.JSON(http.StatusNotImplemented, gin.H{"error": "QR stream is only served by the primary"})
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, int64(proxyMaxMessage)+1))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read body: " + err.Error()})
+			return
+		}
+		if len(body) > proxyMaxMessage {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+			return
+		}
+		header := c.Request.Header.Clone()
+		header.Set("X-Forwarded-For", c.ClientIP())
+		// The primary checks the client certificate against its own CA.
+		var chain [][]byte
+		if state := c.Request.TLS; state != nil && len(state.VerifiedChains) > 0 {
+			for _, cert := range state.VerifiedChains[0] {
+				chain = append(chain, cert.Raw)
+			}
+		}
+
+		resp, err := primary.forward(c.Request.Context(), &forwardRequest{
+			Method:      c.Request.Method,
+			Path:        c.Request.URL.RequestURI(),
+			Header:      header,
+			Body:        body,
+			ClientCerts: chain,
+		})
+		if err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "primary unavailable: " + err.Error()})
+			return
+		}
+		for k, vs := range resp.Header {
+			for _, v := range vs {
+				c.Writer.Header().Add(k, v)
+			}
+		}
+		c.Status(resp.Status)
+		_, _ = c.Writer.Write(resp.Body)
+	}
+}
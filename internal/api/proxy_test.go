@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestProxyChannelRequiresMutualTLS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	proxy := newTestCA(t)
+	_, primaryCert, primaryKey := proxy.issue(t, "primary")
+	_, frontendCert, frontendKey := proxy.issue(t, "frontend")
+	_, strangerCert, strangerKey := newTestCA(t).issue(t, "stranger")
+
+	for _, cfg := range []*Config{
+		{Port: 8080, APIKeys: []string{"k"}, GRPCAddr: ":9090"},
+		{Port: 8080, PrimaryAddr: "primary:9090", GRPCTLSCert: frontendCert, GRPCTLSKey: frontendKey},
+	} {
+		found := false
+		for _, err := range cfg.Validate() {
+			found = found || strings.Contains(err.Error(), "WACLI_API_GRPC_TLS_CA")
+		}
+		if !found {
+			t.Fatalf("expected Validate to require the gRPC TLS settings for %+v", cfg)
+		}
+	}
+
+	router := gin.New()
+	router.GET("/api/v1/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	primary := &Config{GRPCAddr: lis.Addr().String(), GRPCTLSCert: primaryCert, GRPCTLSKey: primaryKey, GRPCTLSCA: proxy.path}
+	srv, err := NewPrimaryServer(router, primary)
+	if err != nil {
+		t.Fatalf("NewPrimaryServer: %v", err)
+	}
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	forward := func(cert, key string) (*forwardResponse, error) {
+		client, err := DialPrimary(&Config{PrimaryAddr: lis.Addr().String(), GRPCTLSCert: cert, GRPCTLSKey: key, GRPCTLSCA: proxy.path})
+		if err != nil {
+			t.Fatalf("DialPrimary: %v", err)
+		}
+		defer client.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return client.forward(ctx, &forwardRequest{Method: http.MethodGet, Path: "/api/v1/ping"})
+	}
+
+	resp, err := forward(frontendCert, frontendKey)
+	if err != nil || resp.Status != http.StatusOK || string(resp.Body) != "pong" {
+		t.Fatalf("forward = %+v, %v", resp, err)
+	}
+	if _, err := forward(strangerCert, strangerKey); err == nil {
+		t.Fatalf("expected a frontend with a foreign certificate to be refused")
+	}
+}
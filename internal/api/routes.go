@@ -8,59 +8,216 @@ import (
 func SetupRoutes(router *gin.Engine, app *app.App, cfg *Config) {
 	// Public routes (no auth required)
 	router.GET("/health", healthHandler)
+	router.GET("/readyz", readyzHandler())
 	router.StaticFile("/", "./web/index.html")
 	router.Static("/static", "./web/static")
 
 	// API v1 group (with authentication)
 	v1 := router.Group("/api/v1")
-	v1.Use(APIKeyAuth(cfg.APIKeys))
+	v1.Use(mediaURLAuth(cfg, clientCertAuth(cfg, APIKeyAuth(cfg.APIKeys, app.DB()))), routeLimits(cfg), messageFooter(cfg))
 	{
 		// Messages
 		v1.GET("/messages", listMessagesHandler(app))
 		v1.GET("/messages/search", searchMessagesHandler(app))
+		v1.GET("/messages/poll", pollMessagesHandler(app))
 		v1.GET("/messages/:id", getMessageHandler(app))
+		v1.GET("/messages/:id/receipts", messageReceiptsHandler(app))
+		v1.GET("/messages/:id/revisions", messageRevisionsHandler(app))
+		v1.GET("/messages/:id/thread", messageThreadHandler(app))
+		v1.DELETE("/messages/:id", deleteMessageHandler(app))
+
+		// Calls
+		v1.GET("/calls", listCallsHandler(app))
+
+		// Contact identity (security code) changes
+		v1.GET("/identity-changes", listIdentityChangesHandler(app))
+
+		// Entities extracted from messages
+		v1.GET("/entities", listEntitiesHandler(app))
+
+		// Questions over the archive
+		v1.POST("/ask", askHandler(app, cfg))
 
 		// Send messages
 		v1.POST("/send/text", sendTextHandler(app))
-		v1.POST("/send/file", sendFileHandler(app))
+		v1.POST("/send/file", sendFileHandler(app, cfg))
+
+		// Batch of mixed operations
+		v1.POST("/batch", batchHandler(app))
+
+		// Outbox
+		v1.GET("/outbox", listOutboxHandler(app))
+		v1.POST("/outbox/flush", flushOutboxHandler(app))
 
 		// Webhooks
-		v1.POST("/webhook/grafana", webhookGrafanaHandler(app, cfg))
-		v1.POST("/webhook/generic", webhookGenericHandler(app))
+		inbox := webhookInbox(app, cfg)
+		v1.POST("/webhook/grafana", webhookBody(cfg.webhookMaxBody(), gin.MIMEJSON, gin.MIMEPlain), inbox, webhookGrafanaHandler(app, cfg))
+		v1.POST("/webhook/generic", webhookBody(cfg.webhookMaxBody(), gin.MIMEJSON, gin.MIMEPOSTForm, gin.MIMEMultipartPOSTForm), inbox, webhookGenericHandler(app))
+		v1.GET("/webhook/generic/schemas", listPayloadSchemasHandler(app))
+		v1.GET("/webhook/generic/schemas/:jid", getPayloadSchemaHandler(app))
+		v1.PUT("/webhook/generic/schemas/:jid", setPayloadSchemaHandler(app))
+		v1.DELETE("/webhook/generic/schemas/:jid", deletePayloadSchemaHandler(app))
+		v1.POST("/webhook/generic/:name", webhookBody(cfg.webhookMaxBody(), gin.MIMEJSON), inbox, webhookRouteHandler(app))
+		v1.GET("/webhook/inbox", listWebhookInboxHandler(app))
+		v1.POST("/webhook/inbox/:id/retry", retryWebhookInboxHandler(app))
+		v1.GET("/webhook/routes", listWebhookRoutesHandler(app))
+		v1.GET("/webhook/routes/:name", getWebhookRouteHandler(app))
+		v1.PUT("/webhook/routes/:name", setWebhookRouteHandler(app))
+		v1.DELETE("/webhook/routes/:name", deleteWebhookRouteHandler(app))
+		v1.POST("/maintenance", createMaintenanceHandler(app))
+		v1.GET("/maintenance", listMaintenanceHandler(app))
+		v1.GET("/maintenance/:id", getMaintenanceHandler(app))
+		v1.DELETE("/maintenance/:id", expireMaintenanceHandler(app))
+
+		// Outgoing webhook subscriptions
+		v1.POST("/webhooks", createWebhookHandler(app))
+		v1.GET("/webhooks", listWebhooksHandler(app))
+		v1.GET("/webhooks/:id", getWebhookHandler(app))
+		v1.PATCH("/webhooks/:id", updateWebhookHandler(app))
+		v1.DELETE("/webhooks/:id", deleteWebhookHandler(app))
+		v1.GET("/webhooks/:id/failures", listWebhookFailuresHandler(app))
+		v1.POST("/webhooks/:id/failures/:failure_id/redeliver", redeliverWebhookFailureHandler(app))
+		v1.GET("/webhooks/:id/attempts", listWebhookAttemptsHandler(app))
+		v1.POST("/webhooks/:id/attempts/:attempt_id/retry", retryWebhookAttemptHandler(app))
+
+		// Routing rules
+		v1.POST("/rules", createRuleHandler(app))
+		v1.GET("/rules", listRulesHandler(app))
+		v1.GET("/rules/:id", getRuleHandler(app))
+		v1.PATCH("/rules/:id", updateRuleHandler(app))
+		v1.DELETE("/rules/:id", deleteRuleHandler(app))
+
+		// Sender roles
+		v1.GET("/roles", listRolesHandler(app))
+		v1.PUT("/roles/:jid", setRoleHandler(app))
+		v1.DELETE("/roles/:jid", deleteRoleHandler(app))
 
 		// Contacts
+		v1.GET("/resolve", resolveRecipientHandler(app))
 		v1.GET("/contacts", listContactsHandler(app))
 		v1.GET("/contacts/search", searchContactsHandler(app))
+		v1.GET("/contacts/check", checkNumbersHandler(app, cfg))
 		v1.GET("/contacts/:jid", getContactHandler(app))
 		v1.POST("/contacts/:jid/alias", setContactAliasHandler(app))
+		v1.PUT("/contacts/:jid/tags/:tag", addContactTagHandler(app))
+		v1.DELETE("/contacts/:jid/tags/:tag", removeContactTagHandler(app))
+		v1.GET("/contacts/:jid/presence", contactPresenceHandler(app))
+		v1.GET("/contacts/:jid/avatar", avatarHandler(app, cfg, false))
+		v1.GET("/contacts/:jid/business", getBusinessProfileHandler(app))
+		v1.POST("/contacts/:jid/presence/subscribe", subscribeContactPresenceHandler(app))
 		v1.POST("/contacts/refresh", refreshContactsHandler(app))
+		v1.PATCH("/contacts/bulk", bulkUpdateContactsHandler(app))
+		v1.GET("/tags", listTagsHandler(app))
 
 		// Chats
 		v1.GET("/chats", listChatsHandler(app))
 		v1.GET("/chats/:jid", getChatHandler(app))
+		v1.DELETE("/chats/:jid", deleteChatHandler(app, cfg))
+		v1.POST("/chats/:jid/ephemeral", setChatEphemeralHandler(app))
+		v1.POST("/chats/:jid/typing", chatTypingHandler(app))
+		v1.POST("/chats/:jid/read", markChatReadHandler(app))
+		v1.POST("/chats/:jid/archive", archiveChatHandler(app, true))
+		v1.DELETE("/chats/:jid/archive", archiveChatHandler(app, false))
+		v1.POST("/chats/:jid/pin", pinChatHandler(app, true))
+		v1.DELETE("/chats/:jid/pin", pinChatHandler(app, false))
+		v1.POST("/chats/:jid/mute", muteChatHandler(app))
+		v1.DELETE("/chats/:jid/mute", unmuteChatHandler(app))
+		v1.POST("/chats/:jid/snooze", snoozeChatHandler(app))
+		v1.GET("/chats/:jid/snooze", getChatSnoozeHandler(app))
+		v1.DELETE("/chats/:jid/snooze", unsnoozeChatHandler(app))
+		v1.DELETE("/chats/:jid/messages", deleteChatMessagesHandler(app))
+		v1.GET("/chats/:jid/retention", getChatRetentionHandler(app, cfg))
+		v1.PUT("/chats/:jid/retention", setChatRetentionHandler(app, cfg))
+		v1.DELETE("/chats/:jid/retention", deleteChatRetentionHandler(app))
+		v1.GET("/retention", listChatRetentionHandler(app, cfg))
+
+		// Trash of deleted chats
+		v1.GET("/trash/chats", listTrashHandler(app, cfg))
+		v1.POST("/trash/chats/:jid/restore", restoreChatHandler(app))
+		v1.DELETE("/trash/chats/:jid", purgeChatHandler(app))
 
 		// Groups
 		v1.GET("/groups", listGroupsHandler(app))
 		v1.GET("/groups/:jid", getGroupHandler(app))
+		v1.GET("/groups/:jid/participants", groupParticipantsHandler(app))
 		v1.POST("/groups/:jid/participants", updateGroupParticipantsHandler(app))
 		v1.POST("/groups/:jid/name", updateGroupNameHandler(app))
+		v1.POST("/groups/:jid/description", updateGroupDescriptionHandler(app))
+		v1.POST("/groups/:jid/photo", updateGroupPhotoHandler(app))
+		v1.GET("/groups/:jid/avatar", avatarHandler(app, cfg, true))
+		v1.POST("/groups/:jid/settings", updateGroupSettingsHandler(app))
 		v1.GET("/groups/:jid/invite", getGroupInviteHandler(app))
+		v1.POST("/groups/:jid/invite/revoke", revokeGroupInviteHandler(app))
+		v1.GET("/groups/invite/:code/info", groupInviteInfoHandler(app))
+		v1.GET("/groups/:jid/heatmap", groupHeatmapHandler(app))
+		v1.GET("/groups/:jid/history", groupHistoryHandler(app))
+		v1.GET("/groups/:jid/membership/history", groupMembershipHistoryHandler(app, cfg))
 		v1.POST("/groups/join", joinGroupHandler(app))
+		v1.POST("/groups/batch", groupBatchHandler(app))
 		v1.POST("/groups/:jid/leave", leaveGroupHandler(app))
 
+		// Communities
+		v1.GET("/communities", listCommunitiesHandler(app))
+		v1.POST("/communities", createCommunityHandler(app))
+		v1.GET("/communities/:jid/groups", listCommunityGroupsHandler(app))
+		v1.POST("/communities/:jid/groups", linkCommunityGroupHandler(app))
+		v1.DELETE("/communities/:jid/groups/:group", unlinkCommunityGroupHandler(app))
+
 		// Auth & sync
 		v1.GET("/auth/status", authStatusHandler(app))
+		v1.GET("/auth/health", sessionHealthHandler(app))
 		v1.GET("/auth/qr", getQRCodeHandler(app))
+		v1.GET("/auth/qr/stream", qrStreamHandler(app))
 		v1.POST("/auth/pair", pairWithCodeHandler(app))
 		v1.GET("/auth/wait", waitForPairingHandler(app))
 		v1.POST("/auth/logout", logoutHandler(app))
-		v1.POST("/sync", syncHandler(app))
+		v1.POST("/sync", syncHandler(app, cfg))
 
 		// Media
 		v1.GET("/media/:id", downloadMediaHandler(app))
+		v1.HEAD("/media/:id", downloadMediaHandler(app))
+		v1.GET("/media/:id/thumbnail", mediaThumbnailHandler(app))
+		v1.POST("/media/:id/sign", signMediaHandler(app, cfg))
 
 		// History
 		v1.POST("/history/backfill", backfillHistoryHandler(app))
+
+		// Events
+		v1.GET("/events/ws", eventsWebSocketHandler(app))
+
+		// Stats
+		v1.GET("/stats/messages", messageStatsHandler(app))
+		v1.GET("/stats/sentiment", sentimentStatsHandler(app))
+		v1.GET("/stats/presence", presenceStatsHandler(app))
+		v1.GET("/stats/presence/:jid", presenceIntervalsHandler(app))
+
+		// Read model for BI tools
+		v1.GET("/export", listExportViewsHandler())
+		v1.GET("/export/:view", exportViewHandler(app))
+
+		// Away mode (do not disturb)
+		v1.GET("/away", getAwayHandler(app))
+		v1.POST("/away", setAwayHandler(app))
+		v1.POST("/away/slack", slackAwayHandler(app, cfg))
+
+		// Admin (keys from WACLI_API_KEYS only)
+		admin := v1.Group("/admin", adminOnly())
+		admin.POST("/backup/online", onlineBackupHandler(app, cfg))
+		admin.POST("/session/export", sessionExportHandler(app))
+		admin.POST("/session/import", sessionImportHandler(app))
+		admin.POST("/keys", createAPIKeyHandler(app))
+		admin.GET("/keys", listAPIKeysHandler(app))
+		admin.DELETE("/keys/:id", revokeAPIKeyHandler(app))
+		admin.GET("/fts", ftsStatusHandler(app))
+		admin.POST("/fts/rebuild", rebuildFTSHandler(app))
+		admin.POST("/media/gc", mediaGCHandler(app, cfg))
+		admin.POST("/notify/test", notifyTestHandler(app))
+		admin.GET("/preflight", preflightHandler(cfg))
+
+		// Presence watch (opt-in, see WACLI_API_PRESENCE_WATCH)
+		v1.GET("/presence/watch", listPresenceWatchHandler(app, cfg))
+		v1.POST("/presence/watch", addPresenceWatchHandler(app, cfg))
+		v1.DELETE("/presence/watch/:jid", removePresenceWatchHandler(app))
 	}
 }
 
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+)
+
+const (
+	mediaPathPrefix = "/api/v1/media/"
+	// defaultMediaURLTTL applies when a sign request gives no ttl.
+	defaultMediaURLTTL = time.Hour
+)
+
+// signMediaURL returns the signature of a media path and the query
+// parameters that change its response, valid until expires.
+func signMediaURL(secret, path, chat, disposition string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{path, chat, disposition, strconv.FormatInt(expires, 10)}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// mediaURLAuth lets GET and HEAD requests for media through when they carry
+// a valid signature from POST /media/:id/sign, so the link works without an
+// API key. Every other request goes to next.
+func mediaURLAuth(cfg *Config, next gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := c.Request.URL.Query()
+		sig := q.Get("sig")
+		if sig == "" || !strings.HasPrefix(c.Request.URL.Path, mediaPathPrefix) ||
+			(c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) {
+			next(c)
+			return
+		}
+		if cfg.MediaURLSecret == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "signed media URLs are not enabled"})
+			return
+		}
+		expires, err := strconv.ParseInt(q.Get("expires"), 10, 64)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "invalid signed URL"})
+			return
+		}
+		want := signMediaURL(cfg.MediaURLSecret, c.Request.URL.Path, q.Get("chat"), q.Get("disposition"), expires)
+		if !hmac.Equal([]byte(sig), []byte(want)) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "invalid signed URL"})
+			return
+		}
+		if time.Now().Unix() > expires {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "signed URL expired"})
+			return
+		}
+		c.Next()
+	}
+}
+
+type signMediaRequest struct {
+	Chat        string `json:"chat" binding:"required"`
+	TTLSeconds  int64  `json:"ttl_seconds"`
+	Disposition string `json:"disposition"`
+	Thumbnail   bool   `json:"thumbnail"`
+}
+
+// signMediaHandler mints an expiring link to a message's media (or its
+// thumbnail) that can be shared without the API key.
+func signMediaHandler(a *app.App, cfg *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.MediaURLSecret == "" {
+			c.JSON(http.StatusNotImplemented, gin.H{"error": "signed media URLs are not enabled (set WACLI_MEDIA_URL_SECRET)"})
+			return
+		}
+		var req signMediaRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ttl := defaultMediaURLTTL
+		if req.TTLSeconds != 0 {
+			ttl = time.Duration(req.TTLSeconds) * time.Second
+		}
+		if ttl <= 0 || ttl > cfg.mediaURLMaxTTL() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ttl_seconds must be between 1 and " + strconv.FormatInt(int64(cfg.mediaURLMaxTTL()/time.Second), 10)})
+			return
+		}
+		switch req.Disposition {
+		case "", "attachment", "inline":
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "disposition must be attachment or inline"})
+			return
+		}
+
+		mediaID := c.Param("id")
+		info, err := a.DB().GetMediaDownloadInfo(req.Chat, mediaID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "message not found"})
+			return
+		}
+		if info.MediaType == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "message has no media"})
+			return
+		}
+
+		path := mediaPathPrefix + mediaID
+		if req.Thumbnail {
+			path += "/thumbnail"
+		}
+		expires := time.Now().Add(ttl).Unix()
+		q := url.Values{}
+		q.Set("chat", req.Chat)
+		if req.Disposition != "" {
+			q.Set("disposition", req.Disposition)
+		}
+		q.Set("expires", strconv.FormatInt(expires, 10))
+		q.Set("sig", signMediaURL(cfg.MediaURLSecret, path, req.Chat, req.Disposition, expires))
+
+		u := url.URL{Scheme: requestScheme(c), Host: requestHost(c), Path: path, RawQuery: q.Encode()}
+		c.JSON(http.StatusOK, gin.H{
+			"url":        u.String(),
+			"expires_at": time.Unix(expires, 0).UTC(),
+		})
+	}
+}
+
+// requestScheme and requestHost rebuild the address the client used,
+// honouring the headers set by reverse proxies.
+func requestScheme(c *gin.Context) string {
+	if p := c.GetHeader("X-Forwarded-Proto"); p != "" {
+		return p
+	}
+	if c.Request.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+func requestHost(c *gin.Context) string {
+	if h := c.GetHeader("X-Forwarded-Host"); h != "" {
+		return h
+	}
+	return c.Request.Host
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultWebhookMaxBody bounds inbound webhook bodies unless
+// WACLI_WEBHOOK_MAX_BODY_KB says otherwise.
+const defaultWebhookMaxBody = 1 << 20
+
+func (c *Config) webhookMaxBody() int64 {
+	if c.WebhookMaxBodyBytes <= 0 {
+		return defaultWebhookMaxBody
+	}
+	return c.WebhookMaxBodyBytes
+}
+
+// webhookBody guards a webhook receiver: bodies larger than maxBytes are
+// rejected with 413 and non-empty bodies whose Content-Type is not one of
+// accepted with 415. The body is buffered so the handler can read it again.
+func webhookBody(maxBytes int64, accepted ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > maxBytes {
+			abortPayloadTooLarge(c, maxBytes)
+			return
+		}
+
+		raw, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				abortPayloadTooLarge(c, maxBytes)
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read body: " + err.Error()})
+			return
+		}
+
+		if len(raw) > 0 {
+			ct := strings.ToLower(c.ContentType())
+			ok := false
+			for _, a := range accepted {
+				if ct == a {
+					ok = true
+					break
+				}
+			}
+			if !ok {
+				got := ct
+				if got == "" {
+					got = "none"
+				}
+				c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{
+					"error":     "unsupported Content-Type " + got,
+					"code":      "unsupported_media_type",
+					"supported": accepted,
+				})
+				return
+			}
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(raw))
+		c.Next()
+	}
+}
+
+func abortPayloadTooLarge(c *gin.Context, maxBytes int64) {
+	c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error":     "request body too large",
+		"code":      "payload_too_large",
+		"max_bytes": maxBytes,
+	})
+}
+
+// payloadPreview shortens a webhook body for logging.
+func payloadPreview(raw []byte) string {
+	const limit = 512
+	if len(raw) <= limit {
+		return string(raw)
+	}
+	return strings.ToValidUTF8(string(raw[:limit]), "") + "…"
+}
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/steipete/wacli/internal/app"
+	"github.com/steipete/wacli/internal/store"
+)
+
+// The webhook inbox gives inbound webhooks at-least-once processing: the
+// request is stored and acknowledged with 202 before anything is sent, then
+// replayed through the router until a handler accepts it. Sending still
+// goes through the outbox while WhatsApp is down, so a replay only fails on
+// errors a later attempt may not hit again.
+
+const (
+	// inboxMaxAttempts is how many times a request is replayed before it is
+	// marked as failed.
+	inboxMaxAttempts = 12
+	// inboxRetention is how long processed requests are kept for inspection.
+	inboxRetention = 7 * 24 * time.Hour
+)
+
+// inboxKick wakes the inbox worker when a request was queued.
+var inboxKick = make(chan struct{}, 1)
+
+// inboxReplayKey marks requests replayed by the inbox worker so they are
+// processed instead of queued again.
+type inboxReplayKey struct{}
+
+// inboxReplayKeyHash carries the hash of the API key a replayed request was
+// queued with; APIKeyAuth authenticates it in place of a key.
+type inboxReplayKeyHash struct{}
+
+// credentialHeaders are not stored with queued requests; the API key is
+// kept as a hash and looked up again on replay.
+var credentialHeaders = []string{"X-Api-Key", "Authorization", "Cookie"}
+
+// webhookInbox queues the request when cfg.WebhookQueue is set or the
+// client asks with ?async=true (?async=false opts out), and answers 202.
+func webhookInbox(a *app.App, cfg *Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Context().Value(inboxReplayKey{}) != nil {
+			c.Next()
+			return
+		}
+		async := cfg.WebhookQueue
+		switch c.Query("async") {
+		case "true", "1":
+			async = true
+		case "false", "0":
+			async = false
+		}
+		if !async {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read body: " + err.Error()})
+			return
+		}
+		header := c.Request.Header.Clone()
+		for _, h := range credentialHeaders {
+			header.Del(h)
+		}
+		u := *c.Request.URL
+		q := u.Query()
+		q.Del("api_key")
+		q.Del("async")
+		u.RawQuery = q.Encode()
+
+		item, err := a.DB().EnqueueInbox(c.Request.Method, u.RequestURI(), header, body, c.GetString(apiKeyHashContextKey))
+		if err != nil {
+			// Not persisted, so don't acknowledge; the sender should retry.
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "could not queue webhook: " + err.Error()})
+			return
+		}
+		select {
+		case inboxKick <- struct{}{}:
+		default:
+		}
+		c.AbortWithStatusJSON(http.StatusAccepted, gin.H{"accepted": true, "queued": true, "inbox_id": item.ID})
+	}
+}
+
+// RunWebhookInbox replays queued webhook requests through the router until
+// ctx is cancelled.
+func (s *Server) RunWebhookInbox(ctx context.Context) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-prune.C:
+			if _, err := s.App.DB().PruneInbox(time.Now().Add(-inboxRetention)); err != nil {
+				log.Printf("WARN: webhook inbox: prune: %v", err)
+			}
+			continue
+		case <-inboxKick:
+		case <-ticker.C:
+		}
+		items, err := s.App.DB().DueInbox(time.Now(), 50)
+		if err != nil {
+			log.Printf("WARN: webhook inbox: %v", err)
+			continue
+		}
+		for _, item := range items {
+			if ctx.Err() != nil {
+				return
+			}
+			s.replayInbox(ctx, item)
+		}
+	}
+}
+
+func (s *Server) replayInbox(ctx context.Context, item store.InboxItem) {
+	db := s.App.DB()
+	if !s.keyActive(item.KeyHash) {
+		_ = db.MarkInboxError(item.ID, 0, "the API key it was sent with is no longer configured", time.Now(), true)
+		return
+	}
+
+	rctx := context.WithValue(context.WithValue(ctx, inboxReplayKey{}, item.ID), inboxReplayKeyHash{}, item.KeyHash)
+	rctx, cancel := context.WithTimeout(rctx, 5*time.Minute)
+	defer cancel()
+	r, err := http.NewRequestWithContext(rctx, item.Method, item.Path, bytes.NewReader(item.Body))
+	if err != nil {
+		_ = db.MarkInboxError(item.ID, 0, err.Error(), time.Now(), true)
+		return
+	}
+	r.Header = http.Header(item.Header)
+	if r.Header == nil {
+		r.Header = http.Header{}
+	}
+	r.RemoteAddr = "127.0.0.1:0"
+	rec := httptest.NewRecorder()
+	s.Router.ServeHTTP(rec, r)
+
+	status := rec.Code
+	if status >= 200 && status < 300 {
+		_ = db.MarkInboxDone(item.ID, status)
+		return
+	}
+	msg := strings.TrimSpace(payloadPreview(rec.Body.Bytes()))
+	if msg == "" {
+		msg = http.StatusText(status)
+	}
+	// Client errors are permanent, except timeouts and rate limiting.
+	giveUp := status >= 400 && status < 500 && status != http.StatusRequestTimeout && status != http.StatusTooManyRequests
+	if item.Attempts+1 >= inboxMaxAttempts {
+		giveUp = true
+	}
+	_ = db.MarkInboxError(item.ID, status, msg, time.Now().Add(inboxBackoff(item.Attempts)), giveUp)
+	if giveUp {
+		log.Printf("WARN: webhook inbox: giving up on request %d (%s) after %d attempts: %d %s", item.ID, item.Path, item.Attempts+1, status, msg)
+	}
+}
+
+// keyActive reports whether the API key with this hash is still configured
+// or a managed key that is neither revoked nor expired, or for requests
+// authenticated by client certificate, whether certificates still are.
+func (s *Server) keyActive(hash string) bool {
+	if strings.HasPrefix(hash, clientCertHashPrefix) {
+		return s.Config.certAuthenticates()
+	}
+	for _, k := range s.Config.APIKeys {
+		if store.HashAPIKey(k) == hash {
+			return true
+		}
+	}
+	k, err := s.App.DB().GetAPIKeyByHash(hash)
+	return err == nil && k.Active(time.Now())
+}
+
+// inboxBackoff is the delay before the next attempt: 30s doubling up to 30
+// minutes, so the attempt budget spans about four and a half hours.
+func inboxBackoff(attempts int) time.Duration {
+	d := 30 * time.Second
+	for i := 0; i < attempts && d < 30*time.Minute; i++ {
+		d *= 2
+	}
+	if d > 30*time.Minute {
+		d = 30 * time.Minute
+	}
+	return d
+}
+
+func inboxItemJSON(it store.InboxItem) gin.H {
+	out := gin.H{
+		"id":              it.ID,
+		"method":          it.Method,
+		"path":            it.Path,
+		"status":          it.Status,
+		"attempts":        it.Attempts,
+		"body_bytes":      len(it.Body),
+		"next_attempt_at": it.NextAttemptAt,
+		"created_at":      it.CreatedAt,
+		"updated_at":      it.UpdatedAt,
+	}
+	if it.LastStatus != 0 {
+		out["last_status"] = it.LastStatus
+	}
+	if it.LastError != "" {
+		out["last_error"] = it.LastError
+	}
+	if !it.DoneAt.IsZero() {
+		out["done_at"] = it.DoneAt
+	}
+	return out
+}
+
+func listWebhookInboxHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := c.Query("status")
+		switch status {
+		case "", store.InboxPending, store.InboxDone, store.InboxFailed:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be one of pending, done, failed"})
+			return
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+		items, err := a.DB().ListInbox(status, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		out := make([]gin.H, 0, len(items))
+		for _, it := range items {
+			out = append(out, inboxItemJSON(it))
+		}
+		c.JSON(http.StatusOK, gin.H{"requests": out})
+	}
+}
+
+// retryWebhookInboxHandler requeues a request that ran out of attempts.
+func retryWebhookInboxHandler(a *app.App) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		ok, err := a.DB().RetryInbox(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no failed request with this id"})
+			return
+		}
+		select {
+		case inboxKick <- struct{}{}:
+		default:
+		}
+		c.JSON(http.StatusOK, gin.H{"retrying": true, "id": id})
+	}
+}
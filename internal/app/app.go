@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
+	"github.com/steipete/wacli/internal/mediastore"
 	"github.com/steipete/wacli/internal/store"
 	"github.com/steipete/wacli/internal/wa"
 	"go.mau.fi/whatsmeow"
@@ -19,26 +21,52 @@ type WAClient interface {
 	Close()
 	IsAuthed() bool
 	IsConnected() bool
+	OwnJID() types.JID
 	Connect(ctx context.Context, opts wa.ConnectOptions) error
 
 	AddEventHandler(handler func(interface{})) uint32
 	RemoveEventHandler(id uint32)
 	ReconnectWithBackoff(ctx context.Context, minDelay, maxDelay time.Duration) error
+	SetAutoReconnect(enabled bool)
 
 	ResolveChatName(ctx context.Context, chat types.JID, pushName string) string
 	GetContact(ctx context.Context, jid types.JID) (types.ContactInfo, error)
 	GetAllContacts(ctx context.Context) (map[types.JID]types.ContactInfo, error)
+	IsOnWhatsApp(ctx context.Context, phones []string) ([]types.IsOnWhatsAppResponse, error)
+	GetBusinessProfile(ctx context.Context, jid types.JID) (*wa.BusinessProfile, error)
+	GetChatSettings(ctx context.Context, chat types.JID) (types.LocalChatSettings, error)
 
 	GetJoinedGroups(ctx context.Context) ([]*types.GroupInfo, error)
 	GetGroupInfo(ctx context.Context, jid types.JID) (*types.GroupInfo, error)
 	SetGroupName(ctx context.Context, jid types.JID, name string) error
+	SetGroupDescription(ctx context.Context, jid types.JID, description string) error
+	SetGroupPhoto(ctx context.Context, jid types.JID, jpeg []byte) (string, error)
+	SetGroupAnnounce(ctx context.Context, jid types.JID, announce bool) error
+	SetGroupLocked(ctx context.Context, jid types.JID, locked bool) error
 	UpdateGroupParticipants(ctx context.Context, group types.JID, users []types.JID, action wa.GroupParticipantAction) ([]types.GroupParticipant, error)
 	GetGroupInviteLink(ctx context.Context, group types.JID, reset bool) (string, error)
+	GetGroupInfoFromLink(ctx context.Context, code string) (*types.GroupInfo, error)
 	JoinGroupWithLink(ctx context.Context, code string) (types.JID, error)
 	LeaveGroup(ctx context.Context, group types.JID) error
+	CreateCommunity(ctx context.Context, name string) (*types.GroupInfo, error)
+	GetSubGroups(ctx context.Context, community types.JID) ([]*types.GroupLinkTarget, error)
+	LinkGroup(ctx context.Context, community, group types.JID) error
+	UnlinkGroup(ctx context.Context, community, group types.JID) error
+
+	SubscribePresence(ctx context.Context, jid types.JID) error
+	SendPresence(ctx context.Context, state types.Presence) error
+	SendChatPresence(ctx context.Context, chat types.JID, state types.ChatPresence, media types.ChatPresenceMedia) error
+	GetAbout(ctx context.Context) (string, error)
+	SetAbout(ctx context.Context, text string) error
+	GetProfilePicture(ctx context.Context, jid types.JID, existingID string) (*types.ProfilePictureInfo, error)
 
 	SendText(ctx context.Context, to types.JID, text string) (types.MessageID, error)
 	SendProtoMessage(ctx context.Context, to types.JID, msg *waProto.Message) (types.MessageID, error)
+	MarkRead(ctx context.Context, chat, sender types.JID, ids []types.MessageID) error
+	SetDisappearingTimer(ctx context.Context, chat types.JID, timer time.Duration) error
+	SetChatArchived(ctx context.Context, chat types.JID, archived bool) error
+	SetChatPinned(ctx context.Context, chat types.JID, pinned bool) error
+	SetChatMuted(ctx context.Context, chat types.JID, muted bool, d time.Duration) error
 	Upload(ctx context.Context, data []byte, mediaType whatsmeow.MediaType) (whatsmeow.UploadResponse, error)
 	DownloadMediaToFile(ctx context.Context, directPath string, encFileHash, fileHash, mediaKey []byte, fileLength uint64, mediaType, mmsType string, targetPath string) (int64, error)
 
@@ -53,12 +81,46 @@ type Options struct {
 	Version       string
 	JSON          bool
 	AllowUnauthed bool
+	// SandboxTo redirects every outgoing message to this test recipient.
+	SandboxTo string
+	// AdminTo receives operational alerts about wacli itself.
+	AdminTo string
+	// MediaStore keeps downloaded media; nil stores it in StoreDir/media.
+	MediaStore mediastore.Store
 }
 
 type App struct {
-	opts Options
-	wa   WAClient
-	db   *store.DB
+	opts   Options
+	wa     WAClient
+	db     *store.DB
+	media  mediastore.Store
+	events *EventBus
+	// conn supervises the connection in long-running processes.
+	conn connSupervisor
+	// sandbox is the parsed SandboxTo, empty when sends go out normally.
+	sandbox types.JID
+	// admin is the parsed AdminTo, empty when alerts are off.
+	admin types.JID
+
+	// ruleReplies remembers the last auto-reply per rule and chat.
+	ruleMu      sync.Mutex
+	ruleReplies map[string]time.Time
+
+	// webhookStreaks counts consecutive failed attempts per webhook.
+	webhookMu      sync.Mutex
+	webhookStreaks map[int64]int
+
+	// mediaDownloads holds in-flight DownloadMedia calls by message.
+	mediaMu        sync.Mutex
+	mediaDownloads map[string]*mediaDownload
+	// mediaGCMu keeps media garbage collections from overlapping.
+	mediaGCMu sync.Mutex
+	// outboxMu serializes outbox flushes so a message is sent once.
+	outboxMu sync.Mutex
+
+	// adminAlerted holds when each kind of admin alert was last sent.
+	adminMu      sync.Mutex
+	adminAlerted map[string]time.Time
 }
 
 func New(opts Options) (*App, error) {
@@ -69,6 +131,36 @@ func New(opts Options) (*App, error) {
 		return nil, fmt.Errorf("create store dir: %w", err)
 	}
 
+	var sandbox types.JID
+	if opts.SandboxTo != "" {
+		jid, err := wa.ParseUserOrJID(opts.SandboxTo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sandbox recipient: %w", err)
+		}
+		sandbox = jid
+	}
+	var admin types.JID
+	if opts.AdminTo != "" {
+		jid, err := wa.ParseUserOrJID(opts.AdminTo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid admin chat: %w", err)
+		}
+		admin = jid
+	}
+
+	media := opts.MediaStore
+	if media == nil {
+		fsStore, err := mediastore.NewFS(filepath.Join(opts.StoreDir, "media"))
+		if err != nil {
+			return nil, err
+		}
+		media = fsStore
+	}
+
+	if _, err := ApplyStagedImport(opts.StoreDir); err != nil {
+		return nil, err
+	}
+
 	indexPath := filepath.Join(opts.StoreDir, "wacli.db")
 
 	db, err := store.Open(indexPath)
@@ -76,7 +168,10 @@ func New(opts Options) (*App, error) {
 		return nil, err
 	}
 
-	return &App{opts: opts, db: db}, nil
+	a := &App{opts: opts, db: db, media: media, events: NewEventBus(), sandbox: sandbox, admin: admin}
+	// From here on, LIDs given to ParseUserOrJID resolve through this store.
+	wa.SetLIDResolver(a.PhoneForLID)
+	return a, nil
 }
 
 func (a *App) OpenWA() error {
@@ -92,6 +187,10 @@ func (a *App) OpenWA() error {
 	}
 
 	a.wa = cli
+	if !a.sandbox.IsEmpty() {
+		a.wa = newSandboxWA(cli, a.sandbox)
+	}
+	a.wa.AddEventHandler(a.publishWAEvent)
 	return nil
 }
 
@@ -116,14 +215,21 @@ func (a *App) EnsureAuthed() error {
 
 func (a *App) WA() WAClient        { return a.wa }
 func (a *App) DB() *store.DB       { return a.db }
+func (a *App) Events() *EventBus   { return a.events }
 func (a *App) StoreDir() string    { return a.opts.StoreDir }
 func (a *App) Version() string     { return a.opts.Version }
 func (a *App) AllowUnauthed() bool { return a.opts.AllowUnauthed }
 
+// Sandbox returns the recipient all sends are redirected to, if any.
+func (a *App) Sandbox() (types.JID, bool) { return a.sandbox, !a.sandbox.IsEmpty() }
+
 func (a *App) Connect(ctx context.Context, allowQR bool, qrWriter func(string)) error {
 	if err := a.OpenWA(); err != nil {
 		return err
 	}
+	if !allowQR && a.wa.IsAuthed() {
+		return a.awaitConnection(ctx)
+	}
 	return a.wa.Connect(ctx, wa.ConnectOptions{
 		AllowQR:  allowQR,
 		OnQRCode: qrWriter,
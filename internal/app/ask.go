@@ -0,0 +1,172 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/steipete/wacli/internal/ai"
+	"github.com/steipete/wacli/internal/privacy"
+	"github.com/steipete/wacli/internal/store"
+)
+
+type AskOptions struct {
+	ChatJID string
+	Limit   int // max messages handed to the model (default 20)
+	APIKey  string
+	Model   string
+	// Privacy drops excluded chats and redacts what is sent to the model.
+	Privacy privacy.Guard
+
+	// Complete overrides the LLM call (tests).
+	Complete func(ctx context.Context, system, user string) (string, error)
+}
+
+type AskCitation struct {
+	Ref     int
+	Message store.Message
+}
+
+type AskResult struct {
+	Answer    string
+	Citations []AskCitation
+	Sources   int
+}
+
+const askPrompt = `You answer questions about the user's WhatsApp message archive.
+Use only the numbered messages provided. Cite every fact with the message number in square brackets, e.g. [2].
+If the messages do not contain the answer, say so briefly. Answer in the language of the question.`
+
+// ErrPrivacyExcluded is returned when the requested chat may not be sent to
+// the AI provider.
+var ErrPrivacyExcluded = errors.New("chat is excluded from AI by privacy settings")
+
+var citationRe = regexp.MustCompile(`\[(\d+)\]`)
+
+// Ask answers a natural-language question over the local archive: it
+// retrieves matching messages, asks the LLM to answer from them, and maps the
+// citations in the answer back to message IDs.
+func (a *App) Ask(ctx context.Context, question string, opts AskOptions) (AskResult, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return AskResult{}, fmt.Errorf("question is required")
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 20
+	}
+	complete := opts.Complete
+	if complete == nil {
+		complete = func(ctx context.Context, system, user string) (string, error) {
+			return ai.Complete(ctx, opts.APIKey, opts.Model, system, user)
+		}
+	}
+
+	if opts.ChatJID != "" && !opts.Privacy.Allowed(opts.ChatJID) {
+		return AskResult{}, ErrPrivacyExcluded
+	}
+
+	terms := askKeywords(question)
+	if len(terms) == 0 {
+		return AskResult{}, fmt.Errorf("question has no searchable keywords")
+	}
+	msgs, err := a.db.SearchMessagesAny(terms, opts.ChatJID, opts.Limit)
+	if err != nil {
+		return AskResult{}, err
+	}
+	allowed := msgs[:0]
+	var names []string
+	for _, m := range msgs {
+		if !opts.Privacy.Allowed(m.ChatJID) {
+			continue
+		}
+		allowed = append(allowed, m)
+		if !strings.HasSuffix(m.ChatJID, "@g.us") {
+			names = append(names, m.ChatName)
+		}
+	}
+	msgs = allowed
+	if len(msgs) == 0 {
+		return AskResult{Answer: "No messages in the archive match this question."}, nil
+	}
+
+	var sb strings.Builder
+	for i, m := range msgs {
+		sender := m.SenderJID
+		if m.FromMe {
+			sender = "me"
+		}
+		text := m.Text
+		if strings.TrimSpace(text) == "" {
+			text = m.DisplayText
+		}
+		fmt.Fprintf(&sb, "[%d] %s | chat: %s | from: %s\n%s\n\n", i+1, m.Timestamp.Format("2006-01-02 15:04"),
+			opts.Privacy.Redact(firstNonEmpty(m.ChatName, m.ChatJID), names...),
+			opts.Privacy.Redact(sender, names...),
+			opts.Privacy.Redact(text, names...))
+	}
+	user := fmt.Sprintf("Messages:\n\n%s\nQuestion: %s", sb.String(), opts.Privacy.Redact(question, names...))
+
+	answer, err := complete(ctx, askPrompt, user)
+	if err != nil {
+		return AskResult{}, err
+	}
+
+	res := AskResult{Answer: answer, Sources: len(msgs)}
+	seen := map[int]bool{}
+	for _, m := range citationRe.FindAllStringSubmatch(answer, -1) {
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n < 1 || n > len(msgs) || seen[n] {
+			continue
+		}
+		seen[n] = true
+		res.Citations = append(res.Citations, AskCitation{Ref: n, Message: msgs[n-1]})
+	}
+	return res, nil
+}
+
+// Common English and Portuguese words that carry no search signal.
+var askStopwords = func() map[string]bool {
+	m := map[string]bool{}
+	for _, w := range strings.Fields(`
+		the and for are but not you your with what when where who why how did does was were has have had
+		that this these those from about into over said say says tell told any all can could would should
+		will there their them they our out its his her him she
+		que para com não uma uns umas por mais como mas foi ele ela eles elas isso esse essa este esta
+		quando onde quem qual quais porque sobre disse falou tem são está estava ser nos nas dos das pelo pela`) {
+		m[w] = true
+	}
+	return m
+}()
+
+// askKeywords picks the search terms out of a question.
+func askKeywords(question string) []string {
+	words := strings.FieldsFunc(strings.ToLower(question), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var out []string
+	seen := map[string]bool{}
+	for _, w := range words {
+		if len([]rune(w)) < 3 || askStopwords[w] || seen[w] {
+			continue
+		}
+		seen[w] = true
+		out = append(out, w)
+		if len(out) == 8 {
+			break
+		}
+	}
+	return out
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/privacy"
+	"github.com/steipete/wacli/internal/store"
+)
+
+func TestAskKeywords(t *testing.T) {
+	got := askKeywords("When did the landlord say the lease ends?")
+	if strings.Join(got, ",") != "landlord,lease,ends" {
+		t.Fatalf("unexpected keywords: %v", got)
+	}
+	if got := askKeywords("Quando o senhorio disse que o contrato termina?"); strings.Join(got, ",") != "senhorio,contrato,termina" {
+		t.Fatalf("unexpected pt keywords: %v", got)
+	}
+}
+
+func TestAskCitesRetrievedMessages(t *testing.T) {
+	a := newTestApp(t)
+
+	chat := "landlord@s.whatsapp.net"
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := a.db.UpsertChat(chat, "dm", "Landlord", base); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	for _, m := range []struct{ id, text string }{
+		{"m1", "The lease ends on March 31st"},
+		{"m2", "See you tomorrow"},
+	} {
+		if err := a.db.UpsertMessage(store.UpsertMessageParams{ChatJID: chat, MsgID: m.id, SenderJID: chat, Timestamp: base, Text: m.text}); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+
+	var prompt string
+	res, err := a.Ask(context.Background(), "When did the landlord say the lease ends?", AskOptions{
+		Complete: func(ctx context.Context, system, user string) (string, error) {
+			prompt = user
+			return "The lease ends on March 31st [1]. Also [7] [1].", nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Ask: %v", err)
+	}
+	if !strings.Contains(prompt, "[1]") || !strings.Contains(prompt, "The lease ends on March 31st") {
+		t.Fatalf("expected retrieved message in prompt, got %q", prompt)
+	}
+	if strings.Contains(prompt, "See you tomorrow") {
+		t.Fatalf("unrelated message should not be retrieved")
+	}
+	if res.Sources != 1 || len(res.Citations) != 1 || res.Citations[0].Message.MsgID != "m1" {
+		t.Fatalf("unexpected citations: %+v", res)
+	}
+}
+
+func TestAskWithoutMatches(t *testing.T) {
+	a := newTestApp(t)
+	called := false
+	res, err := a.Ask(context.Background(), "where is the treasure?", AskOptions{
+		Complete: func(ctx context.Context, system, user string) (string, error) {
+			called = true
+			return "", nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Ask: %v", err)
+	}
+	if called || res.Answer == "" || len(res.Citations) != 0 {
+		t.Fatalf("expected canned answer without LLM call, got %+v (called=%v)", res, called)
+	}
+}
+
+func TestAskAppliesPrivacyGuard(t *testing.T) {
+	a := newTestApp(t)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct{ jid, name, text string }{
+		{"5511911112222@s.whatsapp.net", "Alice Souza", "Alice: the invoice is 11 98765-4321 ready"},
+		{"5511933334444@s.whatsapp.net", "Doctor", "The invoice from the clinic"},
+	} {
+		if err := a.db.UpsertChat(c.jid, "dm", c.name, base); err != nil {
+			t.Fatalf("UpsertChat: %v", err)
+		}
+		if err := a.db.UpsertMessage(store.UpsertMessageParams{ChatJID: c.jid, MsgID: c.jid, SenderJID: c.jid, Timestamp: base, Text: c.text}); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+
+	var prompt string
+	opts := AskOptions{
+		Privacy: privacy.Guard{DenyChats: []string{"5511933334444"}, RedactPhones: true, RedactNames: true},
+		Complete: func(ctx context.Context, system, user string) (string, error) {
+			prompt = user
+			return "ok", nil
+		},
+	}
+	if _, err := a.Ask(context.Background(), "Where is the invoice?", opts); err != nil {
+		t.Fatalf("Ask: %v", err)
+	}
+	if strings.Contains(prompt, "clinic") {
+		t.Fatalf("denylisted chat leaked into prompt: %q", prompt)
+	}
+	for _, leak := range []string{"Alice", "Souza", "98765", "5511911112222"} {
+		if strings.Contains(prompt, leak) {
+			t.Fatalf("expected %q to be redacted, got %q", leak, prompt)
+		}
+	}
+
+	opts.ChatJID = "5511933334444@s.whatsapp.net"
+	if _, err := a.Ask(context.Background(), "Where is the invoice?", opts); err != ErrPrivacyExcluded {
+		t.Fatalf("expected ErrPrivacyExcluded, got %v", err)
+	}
+}
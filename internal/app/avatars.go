@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// ErrNoAvatar is returned for contacts and groups without a profile
+// picture, or whose privacy settings hide it from us.
+var ErrNoAvatar = errors.New("no profile picture")
+
+// DefaultAvatarMaxAge is how long a cached profile picture is served before
+// WhatsApp is asked whether it changed.
+const DefaultAvatarMaxAge = 24 * time.Hour
+
+var (
+	avatarHTTPClient = &http.Client{Timeout: 30 * time.Second}
+	// avatarMaxBytes bounds a downloaded picture; full-size profile
+	// pictures are 640x640 JPEGs.
+	avatarMaxBytes int64 = 5 << 20
+)
+
+// Avatar returns the profile picture of a contact or group, from the store
+// if it was checked within maxAge. Otherwise WhatsApp is asked for it,
+// passing the cached picture ID so an unchanged picture isn't downloaded
+// again. refresh skips the cache. Lookups need a connection.
+func (a *App) Avatar(ctx context.Context, jid types.JID, maxAge time.Duration, refresh bool) (store.Avatar, error) {
+	if maxAge <= 0 {
+		maxAge = DefaultAvatarMaxAge
+	}
+	jid = jid.ToNonAD()
+	key := jid.String()
+	cached, err := a.db.GetAvatar(key)
+	hasCached := err == nil
+	if err != nil && !store.IsNotFound(err) {
+		return store.Avatar{}, err
+	}
+	if hasCached && !refresh && time.Since(cached.FetchedAt) < maxAge {
+		if cached.Missing {
+			return store.Avatar{}, ErrNoAvatar
+		}
+		return cached, nil
+	}
+	if a.wa == nil {
+		return store.Avatar{}, fmt.Errorf("not connected")
+	}
+
+	existing := ""
+	if hasCached && !cached.Missing {
+		existing = cached.PictureID
+	}
+	now := time.Now().UTC()
+	info, err := a.wa.GetProfilePicture(ctx, jid, existing)
+	if errors.Is(err, wa.ErrNoProfilePicture) {
+		if err := a.db.PutAvatar(store.Avatar{JID: key, Missing: true, FetchedAt: now}); err != nil {
+			return store.Avatar{}, err
+		}
+		return store.Avatar{}, ErrNoAvatar
+	}
+	if err != nil {
+		return store.Avatar{}, err
+	}
+	if info == nil || (existing != "" && info.ID == existing) {
+		if existing == "" {
+			return store.Avatar{}, fmt.Errorf("WhatsApp returned no picture for %s", key)
+		}
+		if err := a.db.TouchAvatar(key, now); err != nil {
+			return store.Avatar{}, err
+		}
+		cached.FetchedAt = now
+		return cached, nil
+	}
+
+	data, err := downloadAvatar(ctx, info.URL)
+	if err != nil {
+		return store.Avatar{}, fmt.Errorf("download profile picture: %w", err)
+	}
+	av := store.Avatar{JID: key, PictureID: info.ID, Data: data, FetchedAt: now}
+	if err := a.db.PutAvatar(av); err != nil {
+		return store.Avatar{}, err
+	}
+	return av, nil
+}
+
+// downloadAvatar fetches a profile picture from WhatsApp's CDN; the URLs
+// are signed, so no credentials are needed.
+func downloadAvatar(ctx context.Context, url string) ([]byte, error) {
+	if url == "" {
+		return nil, fmt.Errorf("no picture URL")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := avatarHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, avatarMaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > avatarMaxBytes {
+		return nil, fmt.Errorf("picture larger than %d bytes", avatarMaxBytes)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty picture")
+	}
+	return data, nil
+}
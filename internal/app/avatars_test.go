@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestAvatarCachesPictures(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.connected = true
+	a.wa = f
+	downloads := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		_, _ = w.Write([]byte("jpeg:" + r.URL.Path))
+	}))
+	defer srv.Close()
+
+	alice := types.NewJID("5511999990000", types.DefaultUserServer)
+	f.pictures = map[types.JID]*types.ProfilePictureInfo{alice: {ID: "100", URL: srv.URL + "/100"}}
+	ctx := context.Background()
+
+	av, err := a.Avatar(ctx, alice, time.Hour, false)
+	if err != nil {
+		t.Fatalf("Avatar: %v", err)
+	}
+	if av.PictureID != "100" || string(av.Data) != "jpeg:/100" {
+		t.Fatalf("unexpected avatar: %+v", av)
+	}
+	if _, err := a.Avatar(ctx, alice, time.Hour, false); err != nil || f.pictureCalls != 1 {
+		t.Fatalf("expected the cached avatar, got %v after %d lookups", err, f.pictureCalls)
+	}
+
+	// Unchanged picture: asked again, not downloaded again.
+	if av, err = a.Avatar(ctx, alice, time.Hour, true); err != nil || av.PictureID != "100" {
+		t.Fatalf("refresh = %+v, %v", av, err)
+	}
+	if f.pictureCalls != 2 || downloads != 1 {
+		t.Fatalf("expected 2 lookups and 1 download, got %d and %d", f.pictureCalls, downloads)
+	}
+
+	f.pictures[alice] = &types.ProfilePictureInfo{ID: "101", URL: srv.URL + "/101"}
+	if av, err = a.Avatar(ctx, alice, time.Hour, true); err != nil || string(av.Data) != "jpeg:/101" {
+		t.Fatalf("changed picture = %+v, %v", av, err)
+	}
+
+	// No picture is cached too.
+	bob := types.NewJID("5511999990001", types.DefaultUserServer)
+	for i := 0; i < 2; i++ {
+		if _, err := a.Avatar(ctx, bob, time.Hour, false); !errors.Is(err, ErrNoAvatar) {
+			t.Fatalf("expected ErrNoAvatar, got %v", err)
+		}
+	}
+	if f.pictureCalls != 4 {
+		t.Fatalf("expected the missing picture to be cached, got %d lookups", f.pictureCalls)
+	}
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+const awaySettingKey = "away"
+
+// awayReplyCooldown limits away auto-replies to one per chat.
+var awayReplyCooldown = time.Hour
+
+const defaultAwayMessage = "I'm away right now and will reply as soon as I can."
+
+// AwayState is the do-not-disturb mode. While active, direct messages get an
+// auto-reply; with SetAbout the profile "about" text is swapped as well.
+type AwayState struct {
+	Active        bool      `json:"active"`
+	Message       string    `json:"message,omitempty"`
+	Source        string    `json:"source,omitempty"` // who set it, e.g. "slack", "calendar"
+	Until         time.Time `json:"until,omitempty"`
+	SetAbout      bool      `json:"set_about,omitempty"`
+	PreviousAbout string    `json:"previous_about,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Effective reports whether away mode applies at t (Until has not passed).
+func (s AwayState) Effective(t time.Time) bool {
+	return s.Active && (s.Until.IsZero() || t.Before(s.Until))
+}
+
+func (a *App) Away() (AwayState, error) {
+	raw, err := a.db.GetSetting(awaySettingKey)
+	if err != nil || raw == "" {
+		return AwayState{}, err
+	}
+	var s AwayState
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		return AwayState{}, fmt.Errorf("decode away state: %w", err)
+	}
+	return s, nil
+}
+
+// AwayNeedsConnection reports whether switching to next changes the profile
+// about text and therefore needs a WhatsApp connection.
+func (a *App) AwayNeedsConnection(next AwayState) bool {
+	prev, _ := a.Away()
+	return (next.Active && next.SetAbout) || (prev.Active && prev.SetAbout)
+}
+
+// SetAway switches away mode. When enabling with SetAbout, the current about
+// text is saved and replaced by the away message; it is restored when away
+// mode is turned off.
+func (a *App) SetAway(ctx context.Context, next AwayState) (AwayState, error) {
+	prev, err := a.Away()
+	if err != nil {
+		return AwayState{}, err
+	}
+	next.Message = strings.TrimSpace(next.Message)
+	next.UpdatedAt = time.Now().UTC()
+
+	switch {
+	case next.Active && next.SetAbout:
+		next.PreviousAbout = prev.PreviousAbout
+		if !(prev.Active && prev.SetAbout) {
+			about, err := a.wa.GetAbout(ctx)
+			if err != nil {
+				return AwayState{}, fmt.Errorf("read about: %w", err)
+			}
+			next.PreviousAbout = about
+		}
+		if err := a.wa.SetAbout(ctx, awayMessage(next)); err != nil {
+			return AwayState{}, fmt.Errorf("set about: %w", err)
+		}
+	case prev.Active && prev.SetAbout:
+		if err := a.wa.SetAbout(ctx, prev.PreviousAbout); err != nil {
+			return AwayState{}, fmt.Errorf("restore about: %w", err)
+		}
+		next.PreviousAbout = ""
+	}
+
+	raw, err := json.Marshal(next)
+	if err != nil {
+		return AwayState{}, err
+	}
+	if err := a.db.SetSetting(awaySettingKey, string(raw)); err != nil {
+		return AwayState{}, err
+	}
+	return next, nil
+}
+
+func awayMessage(s AwayState) string {
+	if s.Message != "" {
+		return s.Message
+	}
+	return defaultAwayMessage
+}
+
+// awayReply answers incoming direct messages while away mode is on.
+func (a *App) awayReply(e Event) {
+	if e.Type != EventMessage || a.wa == nil {
+		return
+	}
+	if fromMe, _ := e.Data["from_me"].(bool); fromMe {
+		return
+	}
+	chat, err := types.ParseJID(e.Chat)
+	if err != nil || chat.Server != types.DefaultUserServer {
+		return
+	}
+	s, err := a.Away()
+	if err != nil || !s.Effective(time.Now()) {
+		return
+	}
+
+	key := "away|" + e.Chat
+	a.ruleMu.Lock()
+	if a.ruleReplies == nil {
+		a.ruleReplies = map[string]time.Time{}
+	}
+	if last, ok := a.ruleReplies[key]; ok && time.Since(last) < awayReplyCooldown {
+		a.ruleMu.Unlock()
+		return
+	}
+	a.ruleReplies[key] = time.Now()
+	a.ruleMu.Unlock()
+
+	text := awayMessage(s)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		msgID, err := a.wa.SendText(ctx, chat, text)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "away: reply: %v\n", err)
+			return
+		}
+		a.storeSentText(ctx, chat, string(msgID), text, time.Now().UTC())
+	}()
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSetAwaySwapsAndRestoresAbout(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.about = "Hey there!"
+	a.wa = f
+	ctx := context.Background()
+
+	if _, err := a.SetAway(ctx, AwayState{Active: true, Message: "In a meeting", SetAbout: true, Source: "calendar"}); err != nil {
+		t.Fatalf("SetAway: %v", err)
+	}
+	// Re-enabling must not overwrite the saved original about text.
+	if _, err := a.SetAway(ctx, AwayState{Active: true, Message: "Still busy", SetAbout: true}); err != nil {
+		t.Fatalf("SetAway: %v", err)
+	}
+	if f.about != "Still busy" {
+		t.Fatalf("expected away about, got %q", f.about)
+	}
+	s, err := a.Away()
+	if err != nil || !s.Effective(time.Now()) || s.PreviousAbout != "Hey there!" {
+		t.Fatalf("unexpected state: %+v (%v)", s, err)
+	}
+
+	if _, err := a.SetAway(ctx, AwayState{Active: false}); err != nil {
+		t.Fatalf("SetAway: %v", err)
+	}
+	if f.about != "Hey there!" {
+		t.Fatalf("expected about to be restored, got %q", f.about)
+	}
+}
+
+func TestAwayReplyOncePerChat(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	a.wa = f
+
+	if _, err := a.SetAway(context.Background(), AwayState{Active: true, Message: "Back at 3pm"}); err != nil {
+		t.Fatalf("SetAway: %v", err)
+	}
+	dm := "5511999990000@s.whatsapp.net"
+	a.awayReply(messageEvent("123@g.us", "group message", nil))
+	a.awayReply(messageEvent(dm, "mine", map[string]any{"from_me": true}))
+	a.awayReply(messageEvent(dm, "hello", nil))
+	a.awayReply(messageEvent(dm, "hello again", nil))
+
+	waitFor(t, func() bool {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return len(f.sent) == 1
+	})
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.sent[0] != "Back at 3pm" {
+		t.Fatalf("unexpected reply: %v", f.sent)
+	}
+
+	if (AwayState{Active: true, Until: time.Now().Add(-time.Minute)}).Effective(time.Now()) {
+		t.Fatalf("expired away state must not be effective")
+	}
+}
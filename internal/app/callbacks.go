@@ -0,0 +1,112 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+)
+
+// callbackRetryDelays are the waits before each delivery attempt of a
+// message status callback.
+var callbackRetryDelays = []time.Duration{0, 5 * time.Second, 30 * time.Second, 2 * time.Minute}
+
+// MessageStatus is the body POSTed to a message's callback_url.
+type MessageStatus struct {
+	Event       string    `json:"event"` // always "message.status"
+	ChatJID     string    `json:"chat_jid"`
+	MsgID       string    `json:"msg_id"`
+	Status      string    `json:"status"` // delivered|read|played
+	Participant string    `json:"participant"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// impliedReceipts lists the statuses a receipt kind reaches, in order: a read
+// receipt also means the message was delivered.
+func impliedReceipts(kind string) []string {
+	switch kind {
+	case store.ReceiptPlayed:
+		return []string{store.ReceiptDelivered, store.ReceiptRead, store.ReceiptPlayed}
+	case store.ReceiptRead:
+		return []string{store.ReceiptDelivered, store.ReceiptRead}
+	}
+	return []string{kind}
+}
+
+// notifyMessageCallbacks POSTs every status a receipt newly reaches to the
+// callbacks registered for the messages. In groups the first participant to
+// reach a status triggers it.
+func (a *App) notifyMessageCallbacks(chatJID, participant, kind string, msgIDs []string, at time.Time) {
+	for _, id := range msgIDs {
+		cbs, err := a.db.ListMessageCallbacks(chatJID, id)
+		if err != nil || len(cbs) == 0 {
+			continue
+		}
+		for _, cb := range cbs {
+			for _, status := range impliedReceipts(kind) {
+				if ok, err := a.db.MarkMessageCallback(cb.ID, status, at); err != nil || !ok {
+					continue
+				}
+				body, err := json.Marshal(MessageStatus{
+					Event: "message.status", ChatJID: chatJID, MsgID: id,
+					Status: status, Participant: participant, Timestamp: at,
+				})
+				if err != nil {
+					continue
+				}
+				go deliverCallback(cb, body)
+			}
+		}
+	}
+}
+
+func deliverCallback(cb store.MessageCallback, body []byte) {
+	var err error
+	for _, delay := range callbackRetryDelays {
+		time.Sleep(delay)
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		var retry bool
+		retry, err = postCallback(ctx, cb, body)
+		cancel()
+		if err == nil || !retry {
+			break
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "callback %d: %v\n", cb.ID, err)
+	}
+}
+
+func postCallback(ctx context.Context, cb store.MessageCallback, body []byte) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cb.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "wacli-webhook")
+	req.Header.Set("X-Wacli-Event", "message.status")
+	if cb.Secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-Wacli-Timestamp", ts)
+		req.Header.Set("X-Wacli-Signature", "sha256="+signWebhook(cb.Secret, ts, body))
+	}
+
+	resp, err := webhookHTTPClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
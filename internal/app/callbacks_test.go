@@ -0,0 +1,63 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestReceiptsNotifyMessageCallbacks(t *testing.T) {
+	a := newTestApp(t)
+
+	var mu sync.Mutex
+	var got []MessageStatus
+	var signed bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var st MessageStatus
+		_ = json.NewDecoder(r.Body).Decode(&st)
+		mu.Lock()
+		got = append(got, st)
+		signed = r.Header.Get("X-Wacli-Signature") != ""
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	chat := types.NewJID("5511999990000", types.DefaultUserServer)
+	if _, err := a.db.CreateMessageCallback(store.CreateMessageCallbackParams{ChatJID: chat.String(), MsgID: "M1", URL: srv.URL, Secret: "s"}); err != nil {
+		t.Fatalf("CreateMessageCallback: %v", err)
+	}
+
+	read := &events.Receipt{
+		MessageSource: types.MessageSource{Chat: chat, Sender: chat},
+		MessageIDs:    []types.MessageID{"M1"},
+		Timestamp:     time.Now(),
+		Type:          types.ReceiptTypeRead,
+	}
+	if err := a.storeReceipt(read); err != nil {
+		t.Fatalf("storeReceipt: %v", err)
+	}
+	waitFor(t, func() bool { mu.Lock(); defer mu.Unlock(); return len(got) == 2 })
+
+	// Repeated receipts do not trigger the callback again.
+	if err := a.storeReceipt(read); err != nil {
+		t.Fatalf("storeReceipt: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 2 || !signed {
+		t.Fatalf("expected two signed callbacks, got %+v (signed=%v)", got, signed)
+	}
+	statuses := map[string]bool{got[0].Status: true, got[1].Status: true}
+	if !statuses[store.ReceiptDelivered] || !statuses[store.ReceiptRead] || got[0].MsgID != "M1" || got[0].Participant != chat.String() {
+		t.Fatalf("unexpected callbacks: %+v", got)
+	}
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+)
+
+// storeCallEvent records a call signal and mirrors the call into the chat's
+// message history as a "call" record, so message listings show it.
+func (a *App) storeCallEvent(ctx context.Context, ce wa.CallEvent) error {
+	c, err := a.db.RecordCallUpdate(store.CallUpdate{
+		ID:        ce.ID,
+		ChatJID:   ce.Chat.String(),
+		CallerJID: ce.Caller.String(),
+		Media:     ce.Media,
+		Group:     ce.Group,
+		State:     ce.State,
+		At:        ce.Timestamp,
+	})
+	if err != nil {
+		return err
+	}
+
+	chatName := a.wa.ResolveChatName(ctx, ce.Chat, "")
+	if err := a.db.UpsertChat(c.ChatJID, chatKind(ce.Chat), chatName, c.StartedAt); err != nil {
+		return err
+	}
+	return a.db.UpsertMessage(store.UpsertMessageParams{
+		ChatJID:     c.ChatJID,
+		ChatName:    chatName,
+		MsgID:       "call:" + c.ID,
+		SenderJID:   c.CallerJID,
+		Timestamp:   c.StartedAt,
+		DisplayText: callDisplayText(c),
+		MediaType:   "call",
+	})
+}
+
+func callDisplayText(c store.Call) string {
+	kind := "voice call"
+	if c.Media == "video" {
+		kind = "video call"
+	}
+	if c.Group {
+		kind = "group " + kind
+	}
+	switch c.Status {
+	case store.CallMissed:
+		return "📞 Missed " + kind
+	case store.CallRejected:
+		return "📞 Declined " + kind
+	case store.CallRinging:
+		return "📞 Incoming " + kind
+	default:
+		return "📞 " + upperFirst(kind)
+	}
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return string(s[0]-'a'+'A') + s[1:]
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestStoreCallEventMirrorsMessage(t *testing.T) {
+	a := newTestApp(t)
+	a.wa = newFakeWA()
+
+	caller := types.NewJID("5511999990000", types.DefaultUserServer)
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	if err := a.storeCallEvent(ctx, wa.CallEvent{ID: "C1", Chat: caller, Caller: caller, State: wa.CallStateOffer, Media: "audio", Timestamp: ts}); err != nil {
+		t.Fatalf("offer: %v", err)
+	}
+	if err := a.storeCallEvent(ctx, wa.CallEvent{ID: "C1", Chat: caller, Caller: caller, State: wa.CallStateTerminate, Timestamp: ts.Add(20 * time.Second)}); err != nil {
+		t.Fatalf("terminate: %v", err)
+	}
+
+	msgs, err := a.db.ListMessages(store.ListMessagesParams{ChatJID: caller.String(), Limit: 10})
+	if err != nil {
+		t.Fatalf("ListMessages: %v", err)
+	}
+	if len(msgs) != 1 || msgs[0].MsgID != "call:C1" || msgs[0].MediaType != "call" || msgs[0].DisplayText != "📞 Missed voice call" || !msgs[0].Timestamp.Equal(ts) {
+		t.Fatalf("unexpected call record: %+v", msgs)
+	}
+}
+
+func TestApplyRulesRepliesToCalls(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	a.wa = f
+
+	if _, err := a.db.CreateRule(store.CreateRuleParams{MediaTypes: []string{"call"}, Action: store.RuleReply, Arg: "Can't talk, text me"}); err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+	chat := "5511999990000@s.whatsapp.net"
+	a.applyRules(Event{Type: EventCall, Chat: chat, Sender: chat, Data: map[string]any{"call_id": "C1", "state": wa.CallStateTerminate}})
+	a.applyRules(messageEvent(chat, "hello", nil))
+	a.applyRules(Event{Type: EventCall, Chat: chat, Sender: chat, Data: map[string]any{"call_id": "C2", "state": wa.CallStateOffer}})
+
+	waitFor(t, func() bool {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return len(f.sent) == 1
+	})
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.sent[0] != "Can't talk, text me" {
+		t.Fatalf("unexpected reply: %v", f.sent)
+	}
+}
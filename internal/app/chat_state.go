@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// ArchiveChat archives or unarchives chat on WhatsApp and in the local
+// chats table.
+func (a *App) ArchiveChat(ctx context.Context, chat types.JID, archived bool) error {
+	if err := a.wa.SetChatArchived(ctx, chat, archived); err != nil {
+		return err
+	}
+	return a.db.SetChatArchived(chat.String(), archived)
+}
+
+// PinChat pins or unpins chat on WhatsApp and in the local chats table.
+func (a *App) PinChat(ctx context.Context, chat types.JID, pinned bool) error {
+	if err := a.wa.SetChatPinned(ctx, chat, pinned); err != nil {
+		return err
+	}
+	return a.db.SetChatPinned(chat.String(), pinned)
+}
+
+// MuteChat mutes chat for d, or forever when d is zero, or unmutes it, on
+// WhatsApp and in the local chats table.
+func (a *App) MuteChat(ctx context.Context, chat types.JID, muted bool, d time.Duration) error {
+	if err := a.wa.SetChatMuted(ctx, chat, muted, d); err != nil {
+		return err
+	}
+	var until time.Time
+	if muted && d > 0 {
+		until = time.Now().Add(d).UTC()
+	}
+	return a.db.SetChatMuted(chat.String(), muted, until)
+}
+
+// storeChatState records archive, pin and mute changes made on other
+// devices. Chats that aren't stored yet are skipped.
+func (a *App) storeChatState(evt interface{}) error {
+	var err error
+	switch v := evt.(type) {
+	case *events.Archive:
+		err = a.db.SetChatArchived(a.phoneJID(v.JID).String(), v.Action.GetArchived())
+	case *events.Pin:
+		err = a.db.SetChatPinned(a.phoneJID(v.JID).String(), v.Action.GetPinned())
+	case *events.Mute:
+		var until time.Time
+		if end := v.Action.GetMuteEndTimestamp(); end > 0 {
+			until = time.UnixMilli(end).UTC()
+		}
+		err = a.db.SetChatMuted(a.phoneJID(v.JID).String(), v.Action.GetMuted(), until)
+	}
+	if store.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waSyncAction"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestChatStateSentAndStored(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.connected = true
+	a.wa = f
+	ctx := context.Background()
+
+	chat := types.NewJID("5511999990000", types.DefaultUserServer)
+	if err := a.db.UpsertChat(chat.String(), "dm", "Alice", time.Now()); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+
+	if err := a.PinChat(ctx, chat, true); err != nil {
+		t.Fatalf("PinChat: %v", err)
+	}
+	if err := a.MuteChat(ctx, chat, true, 8*time.Hour); err != nil {
+		t.Fatalf("MuteChat: %v", err)
+	}
+	if err := a.ArchiveChat(ctx, chat, true); err != nil {
+		t.Fatalf("ArchiveChat: %v", err)
+	}
+	want := []string{
+		"pin " + chat.String() + " true",
+		"mute " + chat.String() + " true 8h0m0s",
+		"archive " + chat.String() + " true",
+	}
+	if len(f.appState) != len(want) {
+		t.Fatalf("patches = %v", f.appState)
+	}
+	for i := range want {
+		if f.appState[i] != want[i] {
+			t.Fatalf("patch %d = %q, want %q", i, f.appState[i], want[i])
+		}
+	}
+	c, err := a.db.GetChat(chat.String())
+	if err != nil {
+		t.Fatalf("GetChat: %v", err)
+	}
+	if !c.Archived || c.Pinned || !c.Muted || time.Until(c.MutedUntil) < 7*time.Hour {
+		t.Fatalf("unexpected state: %+v", c)
+	}
+
+	// Changes from the phone arrive as app state events.
+	for _, evt := range []interface{}{
+		&events.Archive{JID: chat, Action: &waSyncAction.ArchiveChatAction{Archived: proto.Bool(false)}},
+		&events.Pin{JID: chat, Action: &waSyncAction.PinAction{Pinned: proto.Bool(true)}},
+		&events.Mute{JID: chat, Action: &waSyncAction.MuteAction{Muted: proto.Bool(true), MuteEndTimestamp: proto.Int64(-1)}},
+		&events.Pin{JID: types.NewJID("999", types.DefaultUserServer), Action: &waSyncAction.PinAction{Pinned: proto.Bool(true)}},
+	} {
+		if err := a.storeChatState(evt); err != nil {
+			t.Fatalf("storeChatState(%T): %v", evt, err)
+		}
+	}
+	c, _ = a.db.GetChat(chat.String())
+	if c.Archived || !c.Pinned || !c.Muted || !c.MutedUntil.IsZero() {
+		t.Fatalf("unexpected state after sync: %+v", c)
+	}
+}
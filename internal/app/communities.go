@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"sort"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Community is a community with the linked groups this account has joined.
+type Community struct {
+	JID         string `json:"jid"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Joined is false when only some of the community's groups were joined,
+	// so its name and description are unknown.
+	Joined bool             `json:"joined"`
+	Groups []CommunityGroup `json:"groups"`
+}
+
+type CommunityGroup struct {
+	JID  string `json:"jid"`
+	Name string `json:"name"`
+	// Announcement marks the community's default group, where only admins
+	// post and every member is added.
+	Announcement bool `json:"announcement"`
+	Participants int  `json:"participants"`
+}
+
+// Communities arranges the joined groups into their communities, sorted by
+// name, announcement group first within each.
+func (a *App) Communities(ctx context.Context) ([]Community, error) {
+	groups, err := a.wa.GetJoinedGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return groupCommunities(groups), nil
+}
+
+func groupCommunities(groups []*types.GroupInfo) []Community {
+	byJID := map[types.JID]*Community{}
+	community := func(jid types.JID) *Community {
+		c := byJID[jid]
+		if c == nil {
+			c = &Community{JID: jid.String(), Groups: []CommunityGroup{}}
+			byJID[jid] = c
+		}
+		return c
+	}
+	for _, g := range groups {
+		switch {
+		case g.IsParent:
+			c := community(g.JID)
+			c.Name = g.Name
+			c.Description = g.Topic
+			c.Joined = true
+		case !g.LinkedParentJID.IsEmpty():
+			c := community(g.LinkedParentJID)
+			c.Groups = append(c.Groups, CommunityGroup{
+				JID:          g.JID.String(),
+				Name:         g.Name,
+				Announcement: g.IsDefaultSubGroup,
+				Participants: len(g.Participants),
+			})
+		}
+	}
+
+	out := make([]Community, 0, len(byJID))
+	for _, c := range byJID {
+		sort.Slice(c.Groups, func(i, j int) bool {
+			a, b := c.Groups[i], c.Groups[j]
+			if a.Announcement != b.Announcement {
+				return a.Announcement
+			}
+			return a.Name < b.Name
+		})
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].JID < out[j].JID
+	})
+	return out
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestCommunities(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	a.wa = f
+
+	ctx := context.Background()
+	eng, err := f.CreateCommunity(ctx, "Engineering")
+	if err != nil {
+		t.Fatalf("CreateCommunity: %v", err)
+	}
+	group := func(user, name string) types.JID {
+		jid := types.NewJID(user, types.GroupServer)
+		f.groups[jid] = &types.GroupInfo{JID: jid, GroupName: types.GroupName{Name: name}}
+		return jid
+	}
+	announce := group("1", "Engineering")
+	f.groups[announce].IsDefaultSubGroup = true
+	oncall := group("2", "On-call")
+	group("3", "Lunch")
+	other := group("4", "Design crit")
+	for _, g := range []types.JID{announce, oncall} {
+		if err := f.LinkGroup(ctx, eng.JID, g); err != nil {
+			t.Fatalf("LinkGroup: %v", err)
+		}
+	}
+	f.groups[other].LinkedParentJID = types.NewJID("99", types.GroupServer)
+
+	got, err := a.Communities(ctx)
+	if err != nil {
+		t.Fatalf("Communities: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("communities = %+v", got)
+	}
+	// The community whose parent was not joined has no name and sorts first.
+	if got[0].Joined || len(got[0].Groups) != 1 || got[0].Groups[0].Name != "Design crit" {
+		t.Fatalf("unjoined community = %+v", got[0])
+	}
+	c := got[1]
+	if c.Name != "Engineering" || !c.Joined || len(c.Groups) != 2 {
+		t.Fatalf("community = %+v", c)
+	}
+	if !c.Groups[0].Announcement || c.Groups[1].Name != "On-call" {
+		t.Fatalf("groups = %+v", c.Groups)
+	}
+
+	if err := f.UnlinkGroup(ctx, eng.JID, oncall); err != nil {
+		t.Fatalf("UnlinkGroup: %v", err)
+	}
+	if got, _ := a.Communities(ctx); len(got[1].Groups) != 1 {
+		t.Fatalf("after unlink = %+v", got[1])
+	}
+}
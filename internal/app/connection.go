@@ -0,0 +1,271 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// Connection states reported by ConnectionStatus.
+const (
+	ConnConnecting = "connecting"
+	ConnConnected  = "connected"
+	// ConnBackoff waits before the next attempt after a failed connect or
+	// a dropped connection.
+	ConnBackoff   = "backoff"
+	ConnUnpaired  = "unpaired"
+	ConnLoggedOut = "logged_out"
+	// ConnReplaced means another client took over the session; reconnecting
+	// would only kick it out again, so the supervisor waits for a restart or
+	// a new login.
+	ConnReplaced = "replaced"
+	ConnStopped  = "stopped"
+)
+
+// The reconnect delay doubles from connBackoffMin up to connBackoffMax, with
+// up to 20% jitter.
+var (
+	connBackoffMin = time.Second
+	connBackoffMax = 2 * time.Minute
+)
+
+// ConnectionStatus is the state of the connection supervisor.
+type ConnectionStatus struct {
+	State string `json:"state"`
+	// Attempts counts failed connects since the last successful one.
+	Attempts           int        `json:"attempts"`
+	LastError          string     `json:"last_error,omitempty"`
+	LastConnectedAt    *time.Time `json:"last_connected_at,omitempty"`
+	LastDisconnectedAt *time.Time `json:"last_disconnected_at,omitempty"`
+	NextRetryAt        *time.Time `json:"next_retry_at,omitempty"`
+}
+
+// connSupervisor keeps one WhatsApp connection alive for a long-running
+// process, so requests share it instead of each calling Connect.
+type connSupervisor struct {
+	mu      sync.Mutex
+	running bool
+	status  ConnectionStatus
+	// changed is closed and replaced on every status change.
+	changed chan struct{}
+	// wake interrupts the supervisor's wait after connection events.
+	wake chan struct{}
+}
+
+func (s *connSupervisor) update(fn func(st *ConnectionStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.status)
+	if s.changed != nil {
+		close(s.changed)
+	}
+	s.changed = make(chan struct{})
+}
+
+func (s *connSupervisor) snapshot() (ConnectionStatus, bool, chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.changed == nil {
+		s.changed = make(chan struct{})
+	}
+	return s.status, s.running, s.changed
+}
+
+func (s *connSupervisor) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// ConnectionStatus reports the supervised connection; ok is false when no
+// supervisor runs and requests connect on their own.
+func (a *App) ConnectionStatus() (status ConnectionStatus, ok bool) {
+	status, ok, _ = a.conn.snapshot()
+	return status, ok
+}
+
+// RunConnection connects to WhatsApp and keeps the connection up until ctx
+// ends, reconnecting with exponential backoff when it drops. While it runs,
+// Connect waits for this connection instead of dialing.
+func (a *App) RunConnection(ctx context.Context) error {
+	if err := a.OpenWA(); err != nil {
+		return err
+	}
+	s := &a.conn
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return fmt.Errorf("connection supervisor already running")
+	}
+	s.running = true
+	s.wake = make(chan struct{}, 1)
+	s.mu.Unlock()
+	defer func() {
+		s.update(func(st *ConnectionStatus) { st.State, st.NextRetryAt = ConnStopped, nil })
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	// Reconnects are ours; whatsmeow's would race them.
+	a.wa.SetAutoReconnect(false)
+	defer a.wa.SetAutoReconnect(true)
+	handlerID := a.wa.AddEventHandler(a.superviseEvent)
+	defer a.wa.RemoveEventHandler(handlerID)
+
+	for ctx.Err() == nil {
+		st, _, _ := s.snapshot()
+		switch {
+		case a.wa.IsConnected() && a.wa.IsAuthed():
+			if st.State != ConnConnected {
+				s.update(func(st *ConnectionStatus) { st.State, st.Attempts, st.NextRetryAt = ConnConnected, 0, nil })
+			}
+			a.waitConnEvent(ctx, 0)
+			continue
+		case !a.wa.IsAuthed():
+			if st.State != ConnLoggedOut && st.State != ConnUnpaired {
+				s.update(func(st *ConnectionStatus) { st.State, st.NextRetryAt = ConnUnpaired, nil })
+			}
+			// Pairing through /auth/qr connects and wakes us.
+			a.waitConnEvent(ctx, 0)
+			continue
+		case st.State == ConnReplaced:
+			a.waitConnEvent(ctx, 0)
+			continue
+		}
+
+		s.update(func(st *ConnectionStatus) { st.State, st.NextRetryAt = ConnConnecting, nil })
+		err := a.wa.Connect(ctx, wa.ConnectOptions{})
+		if err == nil {
+			now := time.Now().UTC()
+			s.update(func(st *ConnectionStatus) {
+				st.State, st.Attempts, st.LastError, st.NextRetryAt = ConnConnected, 0, "", nil
+				st.LastConnectedAt = &now
+			})
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		var delay time.Duration
+		s.update(func(st *ConnectionStatus) {
+			st.Attempts++
+			delay = connBackoff(st.Attempts)
+			next := time.Now().Add(delay).UTC()
+			st.State, st.LastError, st.NextRetryAt = ConnBackoff, err.Error(), &next
+		})
+		a.waitConnEvent(ctx, delay)
+	}
+	return ctx.Err()
+}
+
+// waitConnEvent blocks until a connection event, ctx ends or, if d > 0, d
+// passes.
+func (a *App) waitConnEvent(ctx context.Context, d time.Duration) {
+	var timeout <-chan time.Time
+	if d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case <-ctx.Done():
+	case <-a.conn.wake:
+	case <-timeout:
+	}
+}
+
+func connBackoff(attempt int) time.Duration {
+	d := connBackoffMax
+	if attempt < 32 {
+		if exp := connBackoffMin << (attempt - 1); exp > 0 && exp < connBackoffMax {
+			d = exp
+		}
+	}
+	return d - time.Duration(rand.Int64N(int64(d)/5+1))
+}
+
+// superviseEvent follows the connection between connects.
+func (a *App) superviseEvent(evt interface{}) {
+	s := &a.conn
+	now := time.Now().UTC()
+	switch v := evt.(type) {
+	case *events.Connected:
+		s.update(func(st *ConnectionStatus) {
+			st.State, st.Attempts, st.LastError, st.NextRetryAt = ConnConnected, 0, "", nil
+			st.LastConnectedAt = &now
+		})
+	case *events.Disconnected:
+		s.update(func(st *ConnectionStatus) {
+			// Reconnect at once; failures from here on back off.
+			st.State = ConnBackoff
+			st.LastDisconnectedAt = &now
+		})
+	case *events.StreamError:
+		s.update(func(st *ConnectionStatus) { st.LastError = "stream error " + v.Code })
+	case *events.ConnectFailure:
+		s.update(func(st *ConnectionStatus) { st.LastError = "connect failure: " + v.Reason.String() })
+	case *events.LoggedOut:
+		s.update(func(st *ConnectionStatus) {
+			st.State, st.LastError, st.NextRetryAt = ConnLoggedOut, "logged out", nil
+			st.LastDisconnectedAt = &now
+		})
+	case *events.StreamReplaced:
+		s.update(func(st *ConnectionStatus) {
+			st.State, st.LastError, st.NextRetryAt = ConnReplaced, "session opened on another client", nil
+			st.LastDisconnectedAt = &now
+		})
+	default:
+		return
+	}
+	s.signal()
+}
+
+// reconnect brings a dropped connection back, through the supervisor when
+// one runs.
+func (a *App) reconnect(ctx context.Context) error {
+	if _, ok := a.ConnectionStatus(); ok {
+		return a.awaitConnection(ctx)
+	}
+	return a.wa.ReconnectWithBackoff(ctx, 2*time.Second, 30*time.Second)
+}
+
+// awaitConnection waits for the supervised connection. It fails at once
+// when the session cannot come back on its own, or when the next retry is
+// after ctx's deadline.
+func (a *App) awaitConnection(ctx context.Context) error {
+	for {
+		st, running, changed := a.conn.snapshot()
+		if !running {
+			return a.wa.Connect(ctx, wa.ConnectOptions{})
+		}
+		switch st.State {
+		case ConnConnected:
+			if a.wa.IsConnected() {
+				return nil
+			}
+		case ConnLoggedOut:
+			return fmt.Errorf("not authenticated; run `wacli auth`")
+		case ConnReplaced:
+			return fmt.Errorf("not connected: %s", st.LastError)
+		case ConnBackoff:
+			if deadline, ok := ctx.Deadline(); ok && st.NextRetryAt != nil && st.NextRetryAt.After(deadline) {
+				return fmt.Errorf("not connected: %s; next attempt at %s", st.LastError, st.NextRetryAt.Format(time.RFC3339))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			if st.LastError != "" {
+				return fmt.Errorf("not connected: %s", st.LastError)
+			}
+			return fmt.Errorf("not connected: %w", ctx.Err())
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func setConnBackoff(t *testing.T, min, max time.Duration) {
+	t.Helper()
+	oldMin, oldMax := connBackoffMin, connBackoffMax
+	connBackoffMin, connBackoffMax = min, max
+	t.Cleanup(func() { connBackoffMin, connBackoffMax = oldMin, oldMax })
+}
+
+func startConnection(t *testing.T, a *App) (stop func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = a.RunConnection(ctx)
+		close(done)
+	}()
+	stop = func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+	return stop
+}
+
+func TestRunConnectionReconnectsWithBackoff(t *testing.T) {
+	setConnBackoff(t, 10*time.Millisecond, 40*time.Millisecond)
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.connectErr = errors.New("dial failed")
+	a.wa = f
+
+	stop := startConnection(t, a)
+	waitFor(t, func() bool {
+		st, _ := a.ConnectionStatus()
+		return st.State == ConnBackoff && st.Attempts >= 2 && st.LastError == "dial failed"
+	})
+	f.mu.Lock()
+	f.connectErr = nil
+	f.mu.Unlock()
+	waitFor(t, func() bool {
+		st, _ := a.ConnectionStatus()
+		return st.State == ConnConnected
+	})
+	if st, ok := a.ConnectionStatus(); !ok || st.Attempts != 0 || st.LastConnectedAt == nil {
+		t.Fatalf("status after connecting = %+v, %v", st, ok)
+	}
+	f.mu.Lock()
+	connects, auto := f.connects, f.autoReconnect
+	f.mu.Unlock()
+	if auto {
+		t.Fatalf("whatsmeow reconnects must be off while supervised")
+	}
+
+	// Requests share the supervised connection.
+	if err := a.Connect(context.Background(), false, nil); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	f.mu.Lock()
+	if f.connects != connects {
+		t.Fatalf("Connect dialed again while supervised")
+	}
+	f.connected = false
+	f.mu.Unlock()
+
+	f.emit(&events.Disconnected{})
+	waitFor(t, func() bool {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return f.connects > connects && f.connected
+	})
+	waitFor(t, func() bool {
+		st, _ := a.ConnectionStatus()
+		return st.State == ConnConnected && st.LastDisconnectedAt != nil
+	})
+
+	stop()
+	if st, ok := a.ConnectionStatus(); ok || st.State != ConnStopped {
+		t.Fatalf("status after stop = %+v, %v", st, ok)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.autoReconnect {
+		t.Fatalf("whatsmeow reconnects must be restored after stop")
+	}
+}
+
+func TestConnectFailsFastDuringLongBackoff(t *testing.T) {
+	setConnBackoff(t, time.Hour, time.Hour)
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.connectErr = errors.New("dial failed")
+	a.wa = f
+
+	startConnection(t, a)
+	waitFor(t, func() bool {
+		st, _ := a.ConnectionStatus()
+		return st.State == ConnBackoff
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	started := time.Now()
+	err := a.Connect(ctx, false, nil)
+	if err == nil || !strings.Contains(err.Error(), "dial failed") {
+		t.Fatalf("Connect err = %v", err)
+	}
+	if time.Since(started) > time.Second {
+		t.Fatalf("Connect waited for a retry after its deadline")
+	}
+}
+
+func TestRunConnectionStopsOnStreamReplaced(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	a.wa = f
+
+	startConnection(t, a)
+	waitFor(t, func() bool {
+		st, _ := a.ConnectionStatus()
+		return st.State == ConnConnected
+	})
+	f.mu.Lock()
+	f.connected = false
+	connects := f.connects
+	f.mu.Unlock()
+	f.emit(&events.StreamReplaced{})
+	waitFor(t, func() bool {
+		st, _ := a.ConnectionStatus()
+		return st.State == ConnReplaced
+	})
+	if err := a.Connect(context.Background(), false, nil); err == nil {
+		t.Fatalf("expected error while another client holds the session")
+	}
+	time.Sleep(20 * time.Millisecond)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.connects != connects {
+		t.Fatalf("supervisor reconnected after the session was replaced")
+	}
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+)
+
+// DeleteMessage removes a message from the local store along with its
+// downloaded media file. It does not delete anything on WhatsApp.
+func (a *App) DeleteMessage(chatJID, msgID string) (mediaRemoved int, err error) {
+	path, err := a.db.DeleteMessage(chatJID, msgID)
+	if err != nil {
+		return 0, err
+	}
+	return a.removeMedia([]string{path})
+}
+
+// DeleteMessagesBefore removes a chat's messages older than before from the
+// local store along with their downloaded media files.
+func (a *App) DeleteMessagesBefore(chatJID string, before time.Time) (deleted, mediaRemoved int, err error) {
+	deleted, paths, err := a.db.DeleteMessagesBefore(chatJID, before)
+	if err != nil {
+		return 0, 0, err
+	}
+	mediaRemoved, err = a.removeMedia(paths)
+	return deleted, mediaRemoved, err
+}
+
+// removeMedia deletes downloaded media by location, ignoring files that are
+// already gone, and returns how many it removed.
+func (a *App) removeMedia(locations []string) (int, error) {
+	ctx := context.Background()
+	removed := 0
+	var errs []error
+	for _, loc := range locations {
+		if loc == "" || !a.mediaExists(ctx, loc) {
+			continue
+		}
+		var err error
+		if key, ok := a.media.Key(loc); ok {
+			err = a.media.Delete(ctx, key)
+		} else {
+			err = os.Remove(loc)
+		}
+		if err != nil {
+			if !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		removed++
+	}
+	return removed, errors.Join(errs...)
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+)
+
+func TestDeleteMessagesRemovesMediaFiles(t *testing.T) {
+	a := newTestApp(t)
+	chat := "123@s.whatsapp.net"
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := a.db.UpsertChat(chat, "dm", "Alice", base); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	var files []string
+	for i, id := range []string{"m1", "m2", "m3"} {
+		if err := a.db.UpsertMessage(store.UpsertMessageParams{ChatJID: chat, MsgID: id, SenderJID: chat, Timestamp: base.Add(time.Duration(i) * time.Hour), Text: id}); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+		path := filepath.Join(a.StoreDir(), "media", id+".jpg")
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := a.db.MarkMediaDownloaded(chat, id, path, "", base); err != nil {
+			t.Fatalf("MarkMediaDownloaded: %v", err)
+		}
+		files = append(files, path)
+	}
+
+	if n, err := a.DeleteMessage(chat, "m3"); err != nil || n != 1 {
+		t.Fatalf("DeleteMessage = %d, %v", n, err)
+	}
+	if _, err := a.DeleteMessage(chat, "m3"); !store.IsNotFound(err) {
+		t.Fatalf("expected not found for deleted message, got %v", err)
+	}
+	deleted, removed, err := a.DeleteMessagesBefore(chat, base.Add(90*time.Minute))
+	if err != nil || deleted != 2 || removed != 2 {
+		t.Fatalf("DeleteMessagesBefore = %d, %d, %v", deleted, removed, err)
+	}
+	for _, f := range files {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Fatalf("media %s still present: %v", f, err)
+		}
+	}
+	if msgs, err := a.db.ListMessages(store.ListMessagesParams{ChatJID: chat}); err != nil || len(msgs) != 0 {
+		t.Fatalf("messages left: %+v, %v", msgs, err)
+	}
+}
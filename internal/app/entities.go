@@ -0,0 +1,28 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/steipete/wacli/internal/entity"
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+)
+
+// storeEntities extracts dates, amounts, addresses, tracking numbers, etc.
+// from a message's text and caption. Best-effort: errors are ignored.
+func (a *App) storeEntities(pm wa.ParsedMessage) {
+	if pm.ID == "" || pm.ReactionToID != "" {
+		return
+	}
+	text := pm.Text
+	if pm.Media != nil && strings.TrimSpace(pm.Media.Caption) != "" && pm.Media.Caption != pm.Text {
+		text = strings.TrimSpace(text + "\n" + pm.Media.Caption)
+	}
+
+	found := entity.Extract(text)
+	ents := make([]store.Entity, 0, len(found))
+	for _, e := range found {
+		ents = append(ents, store.Entity{Type: e.Type, Value: e.Value, Normalized: e.Normalized})
+	}
+	_ = a.db.ReplaceMessageEntities(pm.Chat.String(), pm.ID, pm.Timestamp, ents)
+}
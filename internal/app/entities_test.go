@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestStoreParsedMessageExtractsEntities(t *testing.T) {
+	a := newTestApp(t)
+	a.wa = newFakeWA()
+
+	chat := types.JID{User: "123", Server: types.DefaultUserServer}
+	pm := wa.ParsedMessage{
+		Chat:      chat,
+		ID:        "m1",
+		SenderJID: chat.String(),
+		Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Text:      "Código de rastreio: NB123456789BR",
+	}
+	if err := a.storeParsedMessage(context.Background(), pm); err != nil {
+		t.Fatalf("storeParsedMessage: %v", err)
+	}
+
+	got, err := a.db.ListEntities(store.ListEntitiesParams{Type: "tracking_number"})
+	if err != nil {
+		t.Fatalf("ListEntities: %v", err)
+	}
+	if len(got) != 1 || got[0].Normalized != "NB123456789BR" || got[0].MsgID != "m1" {
+		t.Fatalf("unexpected entities: %+v", got)
+	}
+
+	// Re-storing an edited message replaces its entities.
+	pm.Text = "never mind"
+	if err := a.storeParsedMessage(context.Background(), pm); err != nil {
+		t.Fatalf("storeParsedMessage: %v", err)
+	}
+	got, err = a.db.ListEntities(store.ListEntitiesParams{})
+	if err != nil {
+		t.Fatalf("ListEntities: %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("expected entities to be replaced, got %+v", got)
+	}
+}
+
+func TestStoreParsedMessageAppliesEditsAndRevokes(t *testing.T) {
+	a := newTestApp(t)
+	a.wa = newFakeWA()
+	ctx := context.Background()
+
+	chat := types.JID{User: "123", Server: types.DefaultUserServer}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := a.storeParsedMessage(ctx, wa.ParsedMessage{
+		Chat: chat, ID: "m1", SenderJID: chat.String(), Timestamp: base,
+		Text: "Código de rastreio: NB123456789BR",
+	}); err != nil {
+		t.Fatalf("storeParsedMessage: %v", err)
+	}
+
+	if err := a.storeParsedMessage(ctx, wa.ParsedMessage{
+		Chat: chat, ID: "p1", SenderJID: chat.String(), Timestamp: base.Add(time.Minute),
+		EditedID: "m1", Text: "Código de rastreio: NB987654321BR",
+	}); err != nil {
+		t.Fatalf("store edit: %v", err)
+	}
+	m, err := a.db.GetMessage(chat.String(), "m1")
+	if err != nil {
+		t.Fatalf("GetMessage: %v", err)
+	}
+	if !m.Edited || m.Revoked || m.Text != "Código de rastreio: NB987654321BR" {
+		t.Fatalf("unexpected edited message: %+v", m)
+	}
+	if _, err := a.db.GetMessage(chat.String(), "p1"); !store.IsNotFound(err) {
+		t.Fatalf("protocol message should not be stored, err=%v", err)
+	}
+	ents, _ := a.db.ListEntities(store.ListEntitiesParams{Type: "tracking_number"})
+	if len(ents) != 1 || ents[0].Normalized != "NB987654321BR" {
+		t.Fatalf("entities not refreshed after edit: %+v", ents)
+	}
+
+	if err := a.storeParsedMessage(ctx, wa.ParsedMessage{
+		Chat: chat, ID: "p2", SenderJID: chat.String(), Timestamp: base.Add(2 * time.Minute),
+		RevokedID: "m1",
+	}); err != nil {
+		t.Fatalf("store revoke: %v", err)
+	}
+	m, _ = a.db.GetMessage(chat.String(), "m1")
+	if !m.Revoked || m.Text != "" {
+		t.Fatalf("unexpected revoked message: %+v", m)
+	}
+	revs, err := a.db.ListMessageRevisions(chat.String(), "m1")
+	if err != nil {
+		t.Fatalf("ListMessageRevisions: %v", err)
+	}
+	if len(revs) != 2 || revs[0].Kind != store.RevisionEdited || revs[1].Kind != store.RevisionRevoked {
+		t.Fatalf("unexpected revisions: %+v", revs)
+	}
+	if ents, _ := a.db.ListEntities(store.ListEntitiesParams{}); len(ents) != 0 {
+		t.Fatalf("entities should be cleared on revoke: %+v", ents)
+	}
+
+	// Edits to unknown messages are dropped.
+	if err := a.storeParsedMessage(ctx, wa.ParsedMessage{
+		Chat: chat, ID: "p3", Timestamp: base, EditedID: "missing", Text: "x",
+	}); err != nil {
+		t.Fatalf("store unknown edit: %v", err)
+	}
+}
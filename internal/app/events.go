@@ -0,0 +1,254 @@
+package app
+
+import (
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+const (
+	EventMessage    = "message"
+	EventReceipt    = "receipt"
+	EventPresence   = "presence"
+	EventConnection = "connection"
+	EventCall       = "call"
+	// EventIdentityChange reports that a contact's security code changed.
+	EventIdentityChange = "identity_change"
+	// EventWebhookFailure alerts that a webhook subscription keeps failing.
+	EventWebhookFailure = "webhook_failure"
+	// EventMassLeave reports that many members left a group between two
+	// membership snapshots.
+	EventMassLeave = "mass_leave"
+	// EventSessionInvalid reports that WhatsApp logged the session out and
+	// it has to be paired again.
+	EventSessionInvalid = "session_invalid"
+)
+
+// IsEventType reports whether t is one of the Event* types.
+func IsEventType(t string) bool {
+	switch t {
+	case EventMessage, EventReceipt, EventPresence, EventConnection, EventCall, EventIdentityChange, EventWebhookFailure, EventMassLeave, EventSessionInvalid:
+		return true
+	}
+	return false
+}
+
+// Event is a WhatsApp event in the shape streamed to API clients.
+type Event struct {
+	Type      string         `json:"type"`
+	Chat      string         `json:"chat,omitempty"`
+	Sender    string         `json:"sender,omitempty"`
+	Timestamp time.Time      `json:"timestamp"`
+	Data      map[string]any `json:"data,omitempty"`
+}
+
+// EventFilter selects events by type and chat. Empty sets match everything.
+type EventFilter struct {
+	Types map[string]bool
+	Chats map[string]bool
+}
+
+// ParseEventFilter builds a filter from comma-separated type and chat lists.
+func ParseEventFilter(types, chats string) EventFilter {
+	return EventFilter{Types: splitSet(types), Chats: splitSet(chats)}
+}
+
+func (f EventFilter) Match(e Event) bool {
+	if len(f.Types) > 0 && !f.Types[e.Type] {
+		return false
+	}
+	// Connection, session and webhook events are not tied to a chat and
+	// always pass the chat filter.
+	if len(f.Chats) > 0 && e.Type != EventConnection && e.Type != EventSessionInvalid && e.Type != EventWebhookFailure && !f.Chats[e.Chat] {
+		return false
+	}
+	return true
+}
+
+func splitSet(s string) map[string]bool {
+	var out map[string]bool
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if out == nil {
+			out = map[string]bool{}
+		}
+		out[part] = true
+	}
+	return out
+}
+
+// EventBus fans out events to subscribers. Publishing never blocks: slow
+// subscribers miss events instead of stalling the WhatsApp event loop.
+type EventBus struct {
+	mu   sync.Mutex
+	next int
+	subs map[int]chan Event
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subs: map[int]chan Event{}}
+}
+
+// Subscribe returns a channel of events and a function that cancels the
+// subscription and closes the channel.
+func (b *EventBus) Subscribe(buffer int) (<-chan Event, func()) {
+	if buffer <= 0 {
+		buffer = 64
+	}
+	ch := make(chan Event, buffer)
+	b.mu.Lock()
+	id := b.next
+	b.next++
+	b.subs[id] = ch
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, id)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+func (b *EventBus) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// publishWAEvent converts a whatsmeow event and publishes it on the bus.
+// Message events carry the sender's role, so bots consuming them can check
+// permissions before acting on a command, and incoming media already in the
+// archive links to its original. LIDs with a known phone number are
+// published as the phone number JID. Logins and logouts are recorded for
+// SessionHealth, and presence updates are stored for GetPresenceState.
+func (a *App) publishWAEvent(evt interface{}) {
+	a.trackSession(evt)
+	switch v := evt.(type) {
+	case *events.Message:
+		// The message is stored by another handler; learn its LID mapping
+		// before the event goes out.
+		a.resolveLIDs(wa.ParseLiveMessage(v))
+	case *events.Presence:
+		_ = a.storePresence(v)
+	}
+	e, ok := convertWAEvent(evt)
+	if !ok {
+		return
+	}
+	e.Chat = a.phoneJIDString(e.Chat)
+	e.Sender = a.phoneJIDString(e.Sender)
+	if e.Type == EventMessage {
+		e.Data["sender_role"] = a.eventRole(e)
+		a.annotateDuplicate(e)
+	}
+	if a.applyRules(e) {
+		a.awayReply(e)
+		a.events.Publish(e)
+	}
+}
+
+func convertWAEvent(evt interface{}) (Event, bool) {
+	now := time.Now().UTC()
+	switch v := evt.(type) {
+	case *events.Message:
+		pm := wa.ParseLiveMessage(v)
+		data := map[string]any{
+			"id":        pm.ID,
+			"from_me":   pm.FromMe,
+			"push_name": pm.PushName,
+			"text":      pm.Text,
+		}
+		if pm.Media != nil {
+			data["media_type"] = pm.Media.Type
+			data["caption"] = pm.Media.Caption
+			if len(pm.Media.FileSHA256) > 0 {
+				data["file_sha256"] = hex.EncodeToString(pm.Media.FileSHA256)
+			}
+		}
+		if pm.ReplyToID != "" {
+			data["reply_to"] = pm.ReplyToID
+		}
+		if pm.ReactionToID != "" {
+			data["reaction_to"] = pm.ReactionToID
+			data["reaction"] = pm.ReactionEmoji
+		}
+		if pm.EditedID != "" {
+			data["edit_of"] = pm.EditedID
+		}
+		if pm.RevokedID != "" {
+			data["revoke_of"] = pm.RevokedID
+		}
+		if pm.System != "" {
+			data["system"] = pm.System
+		}
+		return Event{Type: EventMessage, Chat: pm.Chat.String(), Sender: pm.SenderJID, Timestamp: pm.Timestamp.UTC(), Data: data}, true
+	case *events.Receipt:
+		ids := make([]string, 0, len(v.MessageIDs))
+		for _, id := range v.MessageIDs {
+			ids = append(ids, string(id))
+		}
+		receiptType := string(v.Type)
+		if v.Type == types.ReceiptTypeDelivered {
+			receiptType = "delivered"
+		}
+		return Event{Type: EventReceipt, Chat: v.Chat.String(), Sender: v.Sender.String(), Timestamp: v.Timestamp.UTC(), Data: map[string]any{
+			"ids":     ids,
+			"receipt": receiptType,
+		}}, true
+	case *events.Presence:
+		data := map[string]any{"available": !v.Unavailable}
+		if !v.LastSeen.IsZero() {
+			data["last_seen"] = v.LastSeen.UTC()
+		}
+		return Event{Type: EventPresence, Chat: v.From.String(), Sender: v.From.String(), Timestamp: now, Data: data}, true
+	case *events.ChatPresence:
+		return Event{Type: EventPresence, Chat: v.Chat.String(), Sender: v.Sender.String(), Timestamp: now, Data: map[string]any{
+			"state": string(v.State),
+			"media": string(v.Media),
+		}}, true
+	case *events.CallOffer, *events.CallOfferNotice, *events.CallAccept, *events.CallReject, *events.CallTerminate:
+		ce, ok := wa.ParseCallEvent(v)
+		if !ok {
+			return Event{}, false
+		}
+		data := map[string]any{"call_id": ce.ID, "state": ce.State, "group": ce.Group}
+		if ce.Media != "" {
+			data["media"] = ce.Media
+		}
+		if ce.Reason != "" {
+			data["reason"] = ce.Reason
+		}
+		return Event{Type: EventCall, Chat: ce.Chat.String(), Sender: ce.Caller.String(), Timestamp: ce.Timestamp, Data: data}, true
+	case *events.IdentityChange:
+		jid := v.JID.ToNonAD().String()
+		return Event{Type: EventIdentityChange, Chat: jid, Sender: jid, Timestamp: v.Timestamp.UTC(), Data: map[string]any{
+			"implicit": v.Implicit,
+		}}, true
+	case *events.Connected:
+		return Event{Type: EventConnection, Timestamp: now, Data: map[string]any{"state": "connected"}}, true
+	case *events.Disconnected:
+		return Event{Type: EventConnection, Timestamp: now, Data: map[string]any{"state": "disconnected"}}, true
+	case *events.LoggedOut:
+		return Event{Type: EventConnection, Timestamp: now, Data: map[string]any{"state": "logged_out"}}, true
+	case *events.StreamReplaced:
+		return Event{Type: EventConnection, Timestamp: now, Data: map[string]any{"state": "stream_replaced"}}, true
+	}
+	return Event{}, false
+}
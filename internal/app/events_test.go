@@ -0,0 +1,98 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestEventBusFanOutAndUnsubscribe(t *testing.T) {
+	bus := NewEventBus()
+	a, stopA := bus.Subscribe(1)
+	b, stopB := bus.Subscribe(1)
+	defer stopB()
+
+	bus.Publish(Event{Type: EventMessage})
+	if (<-a).Type != EventMessage || (<-b).Type != EventMessage {
+		t.Fatalf("expected both subscribers to receive the event")
+	}
+
+	stopA()
+	stopA() // idempotent
+	if _, ok := <-a; ok {
+		t.Fatalf("expected channel to be closed")
+	}
+
+	// Full buffers drop instead of blocking.
+	bus.Publish(Event{Type: EventReceipt})
+	bus.Publish(Event{Type: EventPresence})
+	if got := (<-b).Type; got != EventReceipt {
+		t.Fatalf("expected first event to be kept, got %q", got)
+	}
+}
+
+func TestEventFilter(t *testing.T) {
+	f := ParseEventFilter("message, receipt", "1@s.whatsapp.net")
+	if !f.Match(Event{Type: EventMessage, Chat: "1@s.whatsapp.net"}) {
+		t.Fatalf("expected match")
+	}
+	if f.Match(Event{Type: EventMessage, Chat: "2@s.whatsapp.net"}) {
+		t.Fatalf("expected chat mismatch")
+	}
+	if f.Match(Event{Type: EventPresence, Chat: "1@s.whatsapp.net"}) {
+		t.Fatalf("expected type mismatch")
+	}
+	if !ParseEventFilter("", "1@s.whatsapp.net").Match(Event{Type: EventConnection}) {
+		t.Fatalf("connection events should pass chat filters")
+	}
+	if !ParseEventFilter("", "").Match(Event{Type: EventPresence, Chat: "x"}) {
+		t.Fatalf("empty filter should match everything")
+	}
+}
+
+func TestPublishWAEventConvertsMessagesAndReceipts(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	a.wa = f
+	f.AddEventHandler(a.publishWAEvent)
+
+	ch, stop := a.Events().Subscribe(8)
+	defer stop()
+
+	chat := types.JID{User: "123", Server: types.DefaultUserServer}
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f.emit(&events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{Chat: chat, Sender: chat},
+			ID:            "m1",
+			Timestamp:     ts,
+			PushName:      "Alice",
+		},
+		Message: &waProto.Message{Conversation: proto.String("hello")},
+	})
+	f.emit(&events.Receipt{
+		MessageSource: types.MessageSource{Chat: chat, Sender: chat},
+		MessageIDs:    []types.MessageID{"m0"},
+		Timestamp:     ts,
+		Type:          types.ReceiptTypeRead,
+	})
+	f.emit(&events.HistorySync{})
+
+	msg := <-ch
+	if msg.Type != EventMessage || msg.Chat != chat.String() || msg.Data["text"] != "hello" || msg.Data["id"] != "m1" {
+		t.Fatalf("unexpected message event: %+v", msg)
+	}
+	rcpt := <-ch
+	if rcpt.Type != EventReceipt || rcpt.Data["receipt"] != "read" {
+		t.Fatalf("unexpected receipt event: %+v", rcpt)
+	}
+	select {
+	case e := <-ch:
+		t.Fatalf("unexpected extra event: %+v", e)
+	default:
+	}
+}
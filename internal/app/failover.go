@@ -0,0 +1,209 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// A warm standby is a second linked device of the same account, paired ahead
+// of time into <store>/standby/session.db. Promoting it swaps it with the
+// serving session.db; the message archive is shared and stays in place.
+
+const standbyDir = "standby"
+
+// standbyConnectWait bounds how long PairStandby waits for the first login
+// after the QR code is scanned.
+var standbyConnectWait = 30 * time.Second
+
+// SessionSlot describes one session database in the store.
+type SessionSlot struct {
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+	JID    string `json:"jid,omitempty"`
+}
+
+// PromoteResult reports what PromoteStandby did.
+type PromoteResult struct {
+	JID        string `json:"jid"`
+	RetiredJID string `json:"retired_jid,omitempty"`
+	// RetiredPath is where the previous session was moved, empty if it was
+	// logged out or there was none.
+	RetiredPath string `json:"retired_path,omitempty"`
+	LoggedOut   bool   `json:"logged_out"`
+}
+
+func (a *App) activeSessionPath() string {
+	return filepath.Join(a.opts.StoreDir, "session.db")
+}
+
+func (a *App) standbySessionPath() string {
+	return filepath.Join(a.opts.StoreDir, standbyDir, "session.db")
+}
+
+// SessionSlots returns the serving and the standby session.
+func (a *App) SessionSlots() (active, standby SessionSlot, err error) {
+	if active, err = inspectSession(a.activeSessionPath()); err != nil {
+		return
+	}
+	standby, err = inspectSession(a.standbySessionPath())
+	return
+}
+
+func inspectSession(path string) (SessionSlot, error) {
+	slot := SessionSlot{Path: path}
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return slot, nil
+		}
+		return slot, err
+	}
+	slot.Exists = true
+	cli, err := wa.New(wa.Options{StorePath: path})
+	if err != nil {
+		return slot, err
+	}
+	defer cli.Close()
+	if jid := cli.OwnJID(); !jid.IsEmpty() {
+		slot.JID = jid.String()
+	}
+	return slot, nil
+}
+
+// PairStandby links a new device into the standby slot through the QR flow
+// while the serving session keeps running elsewhere.
+func (a *App) PairStandby(ctx context.Context, onQRCode func(code string)) (types.JID, error) {
+	path := a.standbySessionPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return types.EmptyJID, fmt.Errorf("create standby dir: %w", err)
+	}
+	cli, err := wa.New(wa.Options{StorePath: path})
+	if err != nil {
+		return types.EmptyJID, err
+	}
+	defer cli.Close()
+	if jid := cli.OwnJID(); !jid.IsEmpty() {
+		return types.EmptyJID, fmt.Errorf("standby slot is already paired as %s; promote it or remove %s", jid, filepath.Dir(path))
+	}
+
+	connected := make(chan struct{}, 1)
+	id := cli.AddEventHandler(func(evt interface{}) {
+		if _, ok := evt.(*events.Connected); ok {
+			select {
+			case connected <- struct{}{}:
+			default:
+			}
+		}
+	})
+	defer cli.RemoveEventHandler(id)
+
+	if err := cli.Connect(ctx, wa.ConnectOptions{AllowQR: true, OnQRCode: onQRCode}); err != nil {
+		return types.EmptyJID, err
+	}
+	// The device reconnects after pairing; wait for that login so it is
+	// fully registered before we disconnect.
+	select {
+	case <-connected:
+	case <-time.After(standbyConnectWait):
+	case <-ctx.Done():
+		return types.EmptyJID, ctx.Err()
+	}
+	jid := cli.OwnJID()
+	if jid.IsEmpty() {
+		return types.EmptyJID, fmt.Errorf("pairing did not complete")
+	}
+	return jid, nil
+}
+
+// PromoteStandby makes the standby session the serving one. The serving
+// session must not be in use (stop wacli-api and sync first). The previous
+// session is kept next to it for rollback, or logged out with logoutOld.
+func (a *App) PromoteStandby(ctx context.Context, logoutOld bool) (PromoteResult, error) {
+	if a.wa != nil {
+		return PromoteResult{}, fmt.Errorf("session is open; promote before connecting")
+	}
+	active, standby, err := a.SessionSlots()
+	if err != nil {
+		return PromoteResult{}, err
+	}
+	if standby.JID == "" {
+		return PromoteResult{}, fmt.Errorf("no paired standby session; run `wacli failover pair` first")
+	}
+
+	res := PromoteResult{JID: standby.JID, RetiredJID: active.JID}
+	retired, err := swapSessionFiles(a.activeSessionPath(), a.standbySessionPath(), time.Now().UTC())
+	if err != nil {
+		return res, err
+	}
+	res.RetiredPath = retired
+	if logoutOld && retired != "" && active.JID != "" {
+		if err := logoutSession(ctx, retired); err != nil {
+			return res, fmt.Errorf("promoted, but logging out the old session failed: %w", err)
+		}
+		res.LoggedOut = true
+		res.RetiredPath = ""
+	}
+	return res, nil
+}
+
+// sqliteSuffixes are the files that make up one SQLite database.
+var sqliteSuffixes = []string{"", "-wal", "-shm", "-journal"}
+
+// swapSessionFiles moves the active session aside as
+// session.db.retired-<timestamp> and the standby session into its place. It
+// returns the retired path, empty if there was no active session.
+func swapSessionFiles(active, standby string, now time.Time) (string, error) {
+	retired := ""
+	if _, err := os.Stat(active); err == nil {
+		retired = active + ".retired-" + now.Format("20060102T150405Z")
+		if err := moveDatabase(active, retired); err != nil {
+			return "", fmt.Errorf("retire active session: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	if err := moveDatabase(standby, active); err != nil {
+		if retired != "" {
+			_ = moveDatabase(retired, active)
+		}
+		return "", fmt.Errorf("promote standby session: %w", err)
+	}
+	return retired, nil
+}
+
+func moveDatabase(from, to string) error {
+	for _, suffix := range sqliteSuffixes {
+		err := os.Rename(from+suffix, to+suffix)
+		if err != nil && !(suffix != "" && errors.Is(err, os.ErrNotExist)) {
+			return err
+		}
+	}
+	return nil
+}
+
+// logoutSession unlinks the device stored at path and removes the file.
+func logoutSession(ctx context.Context, path string) error {
+	cli, err := wa.New(wa.Options{StorePath: path})
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+	if err := cli.Connect(ctx, wa.ConnectOptions{}); err != nil {
+		return err
+	}
+	if err := cli.Logout(ctx); err != nil {
+		return err
+	}
+	cli.Close()
+	for _, suffix := range sqliteSuffixes {
+		_ = os.Remove(path + suffix)
+	}
+	return nil
+}
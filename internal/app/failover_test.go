@@ -0,0 +1,64 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSwapSessionFiles(t *testing.T) {
+	dir := t.TempDir()
+	active := filepath.Join(dir, "session.db")
+	standby := filepath.Join(dir, "standby", "session.db")
+	if err := os.MkdirAll(filepath.Dir(standby), 0700); err != nil {
+		t.Fatal(err)
+	}
+	write := func(path, data string) {
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(active, "old")
+	write(active+"-wal", "old-wal")
+	write(standby, "new")
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	retired, err := swapSessionFiles(active, standby, now)
+	if err != nil {
+		t.Fatalf("swapSessionFiles: %v", err)
+	}
+	if want := active + ".retired-20240501T120000Z"; retired != want {
+		t.Fatalf("retired = %q, want %q", retired, want)
+	}
+	read := func(path string) string {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+		return string(b)
+	}
+	if read(active) != "new" || read(retired) != "old" || read(retired+"-wal") != "old-wal" {
+		t.Fatalf("files not swapped")
+	}
+	if _, err := os.Stat(active + "-wal"); !os.IsNotExist(err) {
+		t.Fatalf("stale WAL must move with the retired session")
+	}
+	if _, err := os.Stat(standby); !os.IsNotExist(err) {
+		t.Fatalf("standby slot should be empty after promotion")
+	}
+}
+
+func TestSwapSessionFilesWithoutStandby(t *testing.T) {
+	dir := t.TempDir()
+	active := filepath.Join(dir, "session.db")
+	if err := os.WriteFile(active, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := swapSessionFiles(active, filepath.Join(dir, "standby", "session.db"), time.Now()); err == nil {
+		t.Fatalf("expected error without standby")
+	}
+	if b, _ := os.ReadFile(active); string(b) != "old" {
+		t.Fatalf("active session must be restored, got %q", b)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,24 +21,51 @@ type fakeWA struct {
 
 	authed    bool
 	connected bool
+	own       types.JID
 
 	nextHandlerID uint32
 	handlers      map[uint32]func(interface{})
 
 	connectEvents []interface{}
-
-	contacts map[types.JID]types.ContactInfo
-	groups   map[types.JID]*types.GroupInfo
+	connectErr    error // returned by Connect while set
+	connects      int
+	autoReconnect bool
+	// qrSessions are the codes of successive QR pairing sessions; all but
+	// the last expire, and scanning the last one pairs.
+	qrSessions [][]string
+
+	contacts     map[types.JID]types.ContactInfo
+	notOnWA      map[string]bool // phone numbers without an account
+	groups       map[types.JID]*types.GroupInfo
+	chatSettings map[types.JID]types.LocalChatSettings
 
 	onDemandHistory func(lastKnown types.MessageInfo, count int) *events.HistorySync
+
+	sendErr    error
+	sendGate   chan struct{} // if set, SendText waits for it to close
+	sent       []string
+	sentTo     []types.JID
+	sentProtos []*waProto.Message
+	reads      []string
+	appState   []string // chat state patches as "archive|pin|mute jid value"
+
+	presenceSubs []string
+	about        string
+	pictures     map[types.JID]*types.ProfilePictureInfo // missing means no picture
+	pictureCalls int
+
+	downloads    int
+	downloadGate chan struct{} // if set, downloads wait for it to close
 }
 
 func newFakeWA() *fakeWA {
 	return &fakeWA{
 		authed:        true,
+		autoReconnect: true,
 		handlers:      map[uint32]func(interface{}){},
 		contacts:      map[types.JID]types.ContactInfo{},
 		groups:        map[types.JID]*types.GroupInfo{},
+		chatSettings:  map[types.JID]types.LocalChatSettings{},
 		nextHandlerID: 1,
 	}
 }
@@ -63,8 +91,20 @@ func (f *fakeWA) IsConnected() bool {
 	return f.connected
 }
 
+func (f *fakeWA) OwnJID() types.JID {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.own
+}
+
 func (f *fakeWA) Connect(ctx context.Context, opts wa.ConnectOptions) error {
 	f.mu.Lock()
+	f.connects++
+	if f.connectErr != nil {
+		err := f.connectErr
+		f.mu.Unlock()
+		return err
+	}
 	authed := f.authed
 	f.connected = true
 	eventsToEmit := append([]interface{}{}, f.connectEvents...)
@@ -73,6 +113,20 @@ func (f *fakeWA) Connect(ctx context.Context, opts wa.ConnectOptions) error {
 	if !authed && !opts.AllowQR {
 		return fmt.Errorf("not authenticated; run `wacli auth`")
 	}
+	if !authed && len(f.qrSessions) > 0 {
+		f.mu.Lock()
+		codes := f.qrSessions[0]
+		f.qrSessions = f.qrSessions[1:]
+		last := len(f.qrSessions) == 0
+		f.authed = last
+		f.mu.Unlock()
+		for _, code := range codes {
+			opts.OnQRCode(code)
+		}
+		if !last {
+			return wa.ErrQRTimeout
+		}
+	}
 	f.emit(&events.Connected{})
 	for _, e := range eventsToEmit {
 		f.emit(e)
@@ -95,6 +149,12 @@ func (f *fakeWA) RemoveEventHandler(id uint32) {
 	delete(f.handlers, id)
 }
 
+func (f *fakeWA) SetAutoReconnect(enabled bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.autoReconnect = enabled
+}
+
 func (f *fakeWA) ReconnectWithBackoff(ctx context.Context, minDelay, maxDelay time.Duration) error {
 	return f.Connect(ctx, wa.ConnectOptions{AllowQR: false})
 }
@@ -125,6 +185,17 @@ func (f *fakeWA) GetContact(ctx context.Context, jid types.JID) (types.ContactIn
 	return types.ContactInfo{Found: false}, nil
 }
 
+func (f *fakeWA) IsOnWhatsApp(ctx context.Context, phones []string) ([]types.IsOnWhatsAppResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make([]types.IsOnWhatsAppResponse, 0, len(phones))
+	for _, p := range phones {
+		user := strings.TrimPrefix(p, "+")
+		out = append(out, types.IsOnWhatsAppResponse{Query: p, JID: types.NewJID(user, types.DefaultUserServer), IsIn: !f.notOnWA[user]})
+	}
+	return out, nil
+}
+
 func (f *fakeWA) GetAllContacts(ctx context.Context) (map[types.JID]types.ContactInfo, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -163,6 +234,46 @@ func (f *fakeWA) SetGroupName(ctx context.Context, jid types.JID, name string) e
 	return nil
 }
 
+func (f *fakeWA) SetGroupDescription(ctx context.Context, jid types.JID, description string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	g := f.groups[jid]
+	if g == nil {
+		g = &types.GroupInfo{JID: jid}
+		f.groups[jid] = g
+	}
+	g.Topic = description
+	return nil
+}
+
+func (f *fakeWA) SetGroupPhoto(ctx context.Context, jid types.JID, jpeg []byte) (string, error) {
+	return "1", nil
+}
+
+func (f *fakeWA) SetGroupAnnounce(ctx context.Context, jid types.JID, announce bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	g := f.groups[jid]
+	if g == nil {
+		g = &types.GroupInfo{JID: jid}
+		f.groups[jid] = g
+	}
+	g.IsAnnounce = announce
+	return nil
+}
+
+func (f *fakeWA) SetGroupLocked(ctx context.Context, jid types.JID, locked bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	g := f.groups[jid]
+	if g == nil {
+		g = &types.GroupInfo{JID: jid}
+		f.groups[jid] = g
+	}
+	g.IsLocked = locked
+	return nil
+}
+
 func (f *fakeWA) UpdateGroupParticipants(ctx context.Context, group types.JID, users []types.JID, action wa.GroupParticipantAction) ([]types.GroupParticipant, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -198,20 +309,169 @@ func (f *fakeWA) GetGroupInviteLink(ctx context.Context, group types.JID, reset
 	return "https://chat.whatsapp.com/invite/test", nil
 }
 
+func (f *fakeWA) GetGroupInfoFromLink(ctx context.Context, code string) (*types.GroupInfo, error) {
+	jid, _ := types.ParseJID("12345@g.us")
+	return &types.GroupInfo{JID: jid}, nil
+}
+
 func (f *fakeWA) JoinGroupWithLink(ctx context.Context, code string) (types.JID, error) {
 	return types.ParseJID("12345@g.us")
 }
 
 func (f *fakeWA) LeaveGroup(ctx context.Context, group types.JID) error { return nil }
 
+func (f *fakeWA) CreateCommunity(ctx context.Context, name string) (*types.GroupInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	jid := types.NewJID(fmt.Sprintf("1203630000%08d", len(f.groups)+1), types.GroupServer)
+	g := &types.GroupInfo{JID: jid, GroupName: types.GroupName{Name: name}, GroupParent: types.GroupParent{IsParent: true}}
+	f.groups[jid] = g
+	return g, nil
+}
+
+func (f *fakeWA) GetSubGroups(ctx context.Context, community types.JID) ([]*types.GroupLinkTarget, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []*types.GroupLinkTarget
+	for _, g := range f.groups {
+		if g.LinkedParentJID == community {
+			out = append(out, &types.GroupLinkTarget{JID: g.JID, GroupName: g.GroupName, GroupIsDefaultSub: g.GroupIsDefaultSub})
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeWA) LinkGroup(ctx context.Context, community, group types.JID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	g := f.groups[group]
+	if g == nil {
+		return fmt.Errorf("unknown group %s", group)
+	}
+	g.LinkedParentJID = community
+	return nil
+}
+
+func (f *fakeWA) UnlinkGroup(ctx context.Context, community, group types.JID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if g := f.groups[group]; g != nil && g.LinkedParentJID == community {
+		g.LinkedParentJID = types.EmptyJID
+	}
+	return nil
+}
+
 func (f *fakeWA) SendText(ctx context.Context, to types.JID, text string) (types.MessageID, error) {
-	return types.MessageID("msgid"), nil
+	f.mu.Lock()
+	gate := f.sendGate
+	f.mu.Unlock()
+	if gate != nil {
+		<-gate
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.sendErr != nil {
+		return "", f.sendErr
+	}
+	f.sent = append(f.sent, text)
+	f.sentTo = append(f.sentTo, to)
+	return types.MessageID(fmt.Sprintf("msgid-%d", len(f.sent))), nil
+}
+
+func (f *fakeWA) GetBusinessProfile(ctx context.Context, jid types.JID) (*wa.BusinessProfile, error) {
+	return nil, wa.ErrNotBusiness
+}
+
+func (f *fakeWA) GetChatSettings(ctx context.Context, chat types.JID) (types.LocalChatSettings, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.chatSettings[chat], nil
 }
 
 func (f *fakeWA) SendProtoMessage(ctx context.Context, to types.JID, msg *waProto.Message) (types.MessageID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sentProtos = append(f.sentProtos, msg)
+	f.sentTo = append(f.sentTo, to)
 	return types.MessageID("msgid"), nil
 }
 
+func (f *fakeWA) MarkRead(ctx context.Context, chat, sender types.JID, ids []types.MessageID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, id := range ids {
+		f.reads = append(f.reads, sender.String()+"/"+string(id))
+	}
+	return nil
+}
+
+func (f *fakeWA) SubscribePresence(ctx context.Context, jid types.JID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.presenceSubs = append(f.presenceSubs, jid.String())
+	return nil
+}
+
+func (f *fakeWA) SendPresence(ctx context.Context, state types.Presence) error {
+	return nil
+}
+
+func (f *fakeWA) SendChatPresence(ctx context.Context, chat types.JID, state types.ChatPresence, media types.ChatPresenceMedia) error {
+	return nil
+}
+
+func (f *fakeWA) GetAbout(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.about, nil
+}
+
+func (f *fakeWA) SetAbout(ctx context.Context, text string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.about = text
+	return nil
+}
+
+func (f *fakeWA) GetProfilePicture(ctx context.Context, jid types.JID, existingID string) (*types.ProfilePictureInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pictureCalls++
+	p := f.pictures[jid]
+	if p == nil {
+		return nil, wa.ErrNoProfilePicture
+	}
+	if p.ID == existingID {
+		return nil, nil
+	}
+	return p, nil
+}
+
+func (f *fakeWA) SetDisappearingTimer(ctx context.Context, chat types.JID, timer time.Duration) error {
+	return nil
+}
+
+func (f *fakeWA) SetChatArchived(ctx context.Context, chat types.JID, archived bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.appState = append(f.appState, fmt.Sprintf("archive %s %t", chat, archived))
+	return nil
+}
+
+func (f *fakeWA) SetChatPinned(ctx context.Context, chat types.JID, pinned bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.appState = append(f.appState, fmt.Sprintf("pin %s %t", chat, pinned))
+	return nil
+}
+
+func (f *fakeWA) SetChatMuted(ctx context.Context, chat types.JID, muted bool, d time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.appState = append(f.appState, fmt.Sprintf("mute %s %t %s", chat, muted, d))
+	return nil
+}
+
 func (f *fakeWA) Upload(ctx context.Context, data []byte, mediaType whatsmeow.MediaType) (whatsmeow.UploadResponse, error) {
 	return whatsmeow.UploadResponse{}, nil
 }
@@ -221,6 +481,13 @@ func (f *fakeWA) DecryptReaction(ctx context.Context, reaction *events.Message)
 }
 
 func (f *fakeWA) DownloadMediaToFile(ctx context.Context, directPath string, encFileHash, fileHash, mediaKey []byte, fileLength uint64, mediaType, mmsType string, targetPath string) (int64, error) {
+	f.mu.Lock()
+	f.downloads++
+	gate := f.downloadGate
+	f.mu.Unlock()
+	if gate != nil {
+		<-gate
+	}
 	if err := os.MkdirAll(filepath.Dir(targetPath), 0o700); err != nil {
 		return 0, err
 	}
@@ -244,6 +511,10 @@ func (f *fakeWA) RequestHistorySyncOnDemand(ctx context.Context, lastKnown types
 	return types.MessageID("req"), nil
 }
 
+func (f *fakeWA) PairPhone(ctx context.Context, phoneNumber string) (string, error) {
+	return "ABCD-EFGH", nil
+}
+
 func (f *fakeWA) Logout(ctx context.Context) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
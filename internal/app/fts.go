@@ -0,0 +1,44 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+)
+
+// RebuildFTS rebuilds the search index, reporting progress to stderr in 10%
+// steps. Search falls back to LIKE while it runs.
+func (a *App) RebuildFTS(ctx context.Context) error {
+	start := time.Now()
+	lastStep := int64(-1)
+	err := a.db.RebuildFTS(ctx, func(done, total int64) {
+		if total == 0 {
+			return
+		}
+		if step := done * 10 / total; step != lastStep {
+			lastStep = step
+			fmt.Fprintf(os.Stderr, "fts: indexed %d/%d messages (%d%%)\n", done, total, done*100/total)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "fts: rebuild finished in %s\n", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// RunFTSRebuild rebuilds the search index if it was found missing, stale or
+// corrupt when the store was opened.
+func (a *App) RunFTSRebuild(ctx context.Context) {
+	if !a.db.FTSNeedsRebuild() {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "fts: %s; rebuilding in the background\n", a.db.FTSStatus().Error)
+	if err := a.RebuildFTS(ctx); err != nil && !errors.Is(err, store.ErrFTSRebuildRunning) && !errors.Is(err, context.Canceled) {
+		fmt.Fprintf(os.Stderr, "fts: rebuild: %v\n", err)
+	}
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// GIFToMP4 converts an animated GIF to an H.264 MP4 without audio, which
+// WhatsApp loops like a GIF when the video message sets GifPlayback. The
+// MP4 muxer needs a seekable output, so ffmpeg works on temp files.
+func GIFToMP4(ctx context.Context, ffmpegPath string, gif []byte) ([]byte, error) {
+	if strings.TrimSpace(ffmpegPath) == "" {
+		ffmpegPath = "ffmpeg"
+	}
+	if _, err := exec.LookPath(ffmpegPath); err != nil {
+		return nil, fmt.Errorf("ffmpeg not found: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "wacli-gif-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	inPath := filepath.Join(dir, "input.gif")
+	outPath := filepath.Join(dir, "video.mp4")
+	if err := os.WriteFile(inPath, gif, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write gif: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	// yuv420p with even dimensions is what phones can decode.
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, ffmpegPath,
+		"-hide_banner", "-loglevel", "error", "-y",
+		"-i", inPath,
+		"-an", "-c:v", "libx264", "-pix_fmt", "yuv420p",
+		"-vf", "scale=trunc(iw/2)*2:trunc(ih/2)*2",
+		"-movflags", "+faststart",
+		outPath,
+	)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	video, err := os.ReadFile(outPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read converted video: %w", err)
+	}
+	return video, nil
+}
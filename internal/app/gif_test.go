@@ -0,0 +1,49 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func testGIF(t *testing.T) []byte {
+	t.Helper()
+	palette := color.Palette{color.Black, color.White}
+	anim := &gif.GIF{}
+	for i := 0; i < 3; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 15, 9), palette)
+		frame.SetColorIndex(i, i, 1)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatalf("encode gif: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestGIFToMP4(t *testing.T) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("ffmpeg not installed")
+	}
+	video, err := GIFToMP4(context.Background(), "ffmpeg", testGIF(t))
+	if err != nil {
+		t.Fatalf("GIFToMP4: %v", err)
+	}
+	if len(video) < 12 || string(video[4:8]) != "ftyp" {
+		t.Fatalf("expected an MP4, got % x", video[:min(len(video), 12)])
+	}
+}
+
+func TestGIFToMP4MissingFFmpeg(t *testing.T) {
+	_, err := GIFToMP4(context.Background(), "/nonexistent/ffmpeg", testGIF(t))
+	if err == nil || !strings.Contains(err.Error(), "ffmpeg not found") {
+		t.Fatalf("expected ffmpeg not found error, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// GroupMember is a group participant with what the local stores know about
+// them.
+type GroupMember struct {
+	JID   string `json:"jid"`
+	Phone string `json:"phone,omitempty"`
+	LID   string `json:"lid,omitempty"`
+	// Role is "superadmin", "admin" or "member".
+	Role  string   `json:"role"`
+	Name  string   `json:"name,omitempty"`
+	Alias string   `json:"alias,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+// GroupMembers lists a group's current participants, admins first, then by
+// alias or name. Names come from the contact store, aliases and tags from
+// wacli's own contact metadata.
+func (a *App) GroupMembers(ctx context.Context, group types.JID) ([]GroupMember, error) {
+	info, err := a.wa.GetGroupInfo(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, fmt.Errorf("group %s not found", group)
+	}
+	out := make([]GroupMember, 0, len(info.Participants))
+	for _, p := range info.Participants {
+		m := GroupMember{JID: p.JID.String(), Role: "member", Name: p.DisplayName}
+		switch {
+		case p.IsSuperAdmin:
+			m.Role = "superadmin"
+		case p.IsAdmin:
+			m.Role = "admin"
+		}
+		// LID participants are known under their phone number JID, if at all.
+		known := p.JID
+		if !p.PhoneNumber.IsEmpty() {
+			m.Phone = p.PhoneNumber.User
+			known = p.PhoneNumber
+		} else if p.JID.Server == types.DefaultUserServer {
+			m.Phone = p.JID.User
+		}
+		if !p.LID.IsEmpty() {
+			m.LID = p.LID.String()
+		}
+		a.rememberLID(p.LID, p.PhoneNumber)
+		a.rememberLID(p.JID, p.PhoneNumber)
+		if c, err := a.db.GetContact(known.String()); err == nil {
+			if c.Name != "" {
+				m.Name = c.Name
+			}
+			m.Alias = c.Alias
+			m.Tags = c.Tags
+		} else if ci, err := a.wa.GetContact(ctx, known); err == nil {
+			if name := wa.BestContactName(ci); name != "" {
+				m.Name = name
+			}
+		}
+		out = append(out, m)
+	}
+	rank := map[string]int{"superadmin": 0, "admin": 1, "member": 2}
+	sort.SliceStable(out, func(i, j int) bool {
+		if rank[out[i].Role] != rank[out[j].Role] {
+			return rank[out[i].Role] < rank[out[j].Role]
+		}
+		return memberSortKey(out[i]) < memberSortKey(out[j])
+	})
+	return out, nil
+}
+
+func memberSortKey(m GroupMember) string {
+	switch {
+	case m.Alias != "":
+		return m.Alias
+	case m.Name != "":
+		return m.Name
+	}
+	return "~" + m.JID // unnamed members last
+}
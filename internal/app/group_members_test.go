@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestGroupMembers(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	a.wa = f
+
+	group := types.NewJID("120363000000000001", types.GroupServer)
+	owner := types.NewJID("5511000000001", types.DefaultUserServer)
+	ann := types.NewJID("5511000000002", types.DefaultUserServer)
+	lid := types.NewJID("98765", types.HiddenUserServer)
+	lidPhone := types.NewJID("5511000000003", types.DefaultUserServer)
+	stranger := types.NewJID("5511000000004", types.DefaultUserServer)
+	f.groups[group] = &types.GroupInfo{JID: group, Participants: []types.GroupParticipant{
+		{JID: stranger},
+		{JID: lid, LID: lid, PhoneNumber: lidPhone},
+		{JID: ann},
+		{JID: owner, IsAdmin: true, IsSuperAdmin: true},
+	}}
+
+	if err := a.db.UpsertContact(ann.String(), ann.User, "annie", "Ann Smith", "", ""); err != nil {
+		t.Fatalf("UpsertContact: %v", err)
+	}
+	if err := a.db.SetAlias(ann.String(), "Ann (ops)"); err != nil {
+		t.Fatalf("SetAlias: %v", err)
+	}
+	f.contacts[lidPhone] = types.ContactInfo{Found: true, PushName: "Bea"}
+
+	got, err := a.GroupMembers(context.Background(), group)
+	if err != nil {
+		t.Fatalf("GroupMembers: %v", err)
+	}
+	if len(got) != 4 {
+		t.Fatalf("members = %+v", got)
+	}
+	if got[0].JID != owner.String() || got[0].Role != "superadmin" || got[0].Phone != owner.User {
+		t.Fatalf("expected the owner first, got %+v", got[0])
+	}
+	if got[1].Alias != "Ann (ops)" || got[1].Name != "Ann Smith" {
+		t.Fatalf("expected Ann second, got %+v", got[1])
+	}
+	if got[2].Name != "Bea" || got[2].Phone != lidPhone.User || got[2].LID != lid.String() {
+		t.Fatalf("expected the LID member to be resolved by phone, got %+v", got[2])
+	}
+	if got[3].JID != stranger.String() || got[3].Name != "" {
+		t.Fatalf("expected unnamed members last, got %+v", got[3])
+	}
+
+	if _, err := a.GroupMembers(context.Background(), types.NewJID("404", types.GroupServer)); err == nil {
+		t.Fatalf("expected an error for an unknown group")
+	}
+}
@@ -0,0 +1,206 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Group snapshots record the participant list of every joined group on a
+// schedule. Diffing consecutive snapshots shows growth and churn, including
+// changes made while wacli was not connected to see them as events.
+
+// GroupSnapshots configures RunGroupSnapshots. A zero Interval disables it.
+type GroupSnapshots struct {
+	Interval time.Duration
+	// MassLeave flags a change where at least this many members left
+	// between two snapshots and alerts the admin chat; zero disables it.
+	MassLeave int
+}
+
+// MembershipChange is how a group's membership changed between a snapshot
+// and the one before it.
+type MembershipChange struct {
+	TakenAt     time.Time `json:"taken_at"`
+	MemberCount int       `json:"member_count"`
+	// Delta is the change in member count since the previous snapshot.
+	Delta  int      `json:"delta"`
+	Joined []string `json:"joined"`
+	Left   []string `json:"left"`
+	// Baseline marks the first snapshot, which has nothing to compare to.
+	Baseline  bool `json:"baseline,omitempty"`
+	MassLeave bool `json:"mass_leave,omitempty"`
+}
+
+// Changed reports whether anyone joined or left.
+func (c MembershipChange) Changed() bool {
+	return len(c.Joined) > 0 || len(c.Left) > 0
+}
+
+// SnapshotGroup stores the current participants of a group and returns the
+// change since its previous snapshot. A mass leave is reported to the admin
+// chat and as a mass_leave event.
+func (a *App) SnapshotGroup(ctx context.Context, info *types.GroupInfo, at time.Time, massLeave int) (MembershipChange, error) {
+	group := info.JID.String()
+	prev, err := a.db.LatestGroupSnapshot(group)
+	hasPrev := err == nil
+	if err != nil && !store.IsNotFound(err) {
+		return MembershipChange{}, err
+	}
+	s, err := a.db.AddGroupSnapshot(store.GroupSnapshot{
+		GroupJID:  group,
+		GroupName: info.Name,
+		Members:   snapshotMembers(info),
+		TakenAt:   at,
+	})
+	if err != nil {
+		return MembershipChange{}, err
+	}
+	if !hasPrev {
+		return membershipChange(nil, s, massLeave), nil
+	}
+	ch := membershipChange(&prev, s, massLeave)
+	if ch.MassLeave {
+		a.NotifyAdmin(ctx, AlertMassLeave+":"+group, fmt.Sprintf("%d members left %q since %s UTC (%d → %d members).",
+			len(ch.Left), firstNonEmpty(info.Name, group), prev.TakenAt.UTC().Format("Jan 2 15:04"), len(prev.Members), ch.MemberCount))
+		a.events.Publish(Event{Type: EventMassLeave, Chat: group, Timestamp: at.UTC(), Data: map[string]any{
+			"left":           ch.Left,
+			"member_count":   ch.MemberCount,
+			"previous_count": len(prev.Members),
+			"since":          prev.TakenAt.UTC(),
+		}})
+	}
+	return ch, nil
+}
+
+// SnapshotGroups snapshots every joined group and returns how many were
+// taken. It needs a connection.
+func (a *App) SnapshotGroups(ctx context.Context, massLeave int) (int, error) {
+	if a.wa == nil || !a.wa.IsConnected() {
+		return 0, fmt.Errorf("not connected")
+	}
+	groups, err := a.wa.GetJoinedGroups(ctx)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now().UTC()
+	taken := 0
+	for _, g := range groups {
+		if g == nil {
+			continue
+		}
+		if _, err := a.SnapshotGroup(ctx, g, now, massLeave); err != nil {
+			return taken, fmt.Errorf("snapshot %s: %w", g.JID, err)
+		}
+		taken++
+	}
+	return taken, nil
+}
+
+// GroupMembershipHistory diffs a group's snapshots, newest first. With
+// after, only snapshots taken later are returned, compared to the last one
+// before. limit bounds the snapshots returned (default 100); massLeave
+// flags changes as in SnapshotGroup.
+func (a *App) GroupMembershipHistory(group string, after *time.Time, limit, massLeave int) ([]MembershipChange, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	snaps, err := a.db.ListGroupSnapshots(group, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	var base *store.GroupSnapshot
+	if len(snaps) > limit {
+		base = &snaps[limit]
+		snaps = snaps[:limit]
+	} else if after != nil {
+		b, err := a.db.GroupSnapshotAt(group, *after)
+		if err != nil && !store.IsNotFound(err) {
+			return nil, err
+		}
+		if err == nil {
+			base = &b
+		}
+	}
+	out := make([]MembershipChange, 0, len(snaps))
+	for i, s := range snaps {
+		prev := base
+		if i+1 < len(snaps) {
+			prev = &snaps[i+1]
+		}
+		out = append(out, membershipChange(prev, s, massLeave))
+	}
+	return out, nil
+}
+
+// RunGroupSnapshots snapshots the joined groups every cfg.Interval while
+// connected, until ctx is cancelled.
+func (a *App) RunGroupSnapshots(ctx context.Context, cfg GroupSnapshots) {
+	if cfg.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	var last time.Time
+	for {
+		if time.Since(last) >= cfg.Interval && a.wa != nil && a.wa.IsConnected() {
+			if n, err := a.SnapshotGroups(ctx, cfg.MassLeave); err != nil {
+				fmt.Fprintf(os.Stderr, "group snapshots: %v\n", err)
+			} else {
+				last = time.Now()
+				if n > 0 {
+					fmt.Fprintf(os.Stderr, "group snapshots: took %d\n", n)
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// snapshotMembers lists a group's participants by phone number JID where
+// WhatsApp shares it, so members keep one identity across snapshots.
+func snapshotMembers(info *types.GroupInfo) []string {
+	out := make([]string, 0, len(info.Participants))
+	for _, p := range info.Participants {
+		jid := p.JID
+		if !p.PhoneNumber.IsEmpty() {
+			jid = p.PhoneNumber
+		}
+		out = append(out, jid.ToNonAD().String())
+	}
+	return out
+}
+
+func membershipChange(prev *store.GroupSnapshot, s store.GroupSnapshot, massLeave int) MembershipChange {
+	ch := MembershipChange{TakenAt: s.TakenAt, MemberCount: len(s.Members), Joined: []string{}, Left: []string{}}
+	if prev == nil {
+		ch.Baseline = true
+		return ch
+	}
+	before := make(map[string]bool, len(prev.Members))
+	for _, m := range prev.Members {
+		before[m] = true
+	}
+	for _, m := range s.Members {
+		if !before[m] {
+			ch.Joined = append(ch.Joined, m)
+		}
+		delete(before, m)
+	}
+	for m := range before {
+		ch.Left = append(ch.Left, m)
+	}
+	sort.Strings(ch.Left)
+	ch.Delta = len(s.Members) - len(prev.Members)
+	ch.MassLeave = massLeave > 0 && len(ch.Left) >= massLeave
+	return ch
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestGroupSnapshotsDiffMembership(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.connected = true
+	a.wa = f
+	events, unsubscribe := a.events.Subscribe(8)
+	defer unsubscribe()
+
+	group := types.NewJID("120363000000000001", types.GroupServer)
+	member := func(n string) types.GroupParticipant {
+		return types.GroupParticipant{JID: types.NewJID(n, types.DefaultUserServer)}
+	}
+	lid := types.GroupParticipant{JID: types.NewJID("98765", types.HiddenUserServer), PhoneNumber: types.NewJID("5", types.DefaultUserServer)}
+	info := &types.GroupInfo{JID: group, Participants: []types.GroupParticipant{member("1"), member("2"), member("3"), member("4")}}
+	info.Name = "Ops"
+	f.groups[group] = info
+	ctx := context.Background()
+	t0 := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, err := a.SnapshotGroup(ctx, info, t0, 2); err != nil {
+		t.Fatalf("SnapshotGroup: %v", err)
+	}
+	info.Participants = []types.GroupParticipant{member("1"), member("2"), member("3"), member("4"), lid}
+	ch, err := a.SnapshotGroup(ctx, info, t0.Add(24*time.Hour), 2)
+	if err != nil {
+		t.Fatalf("SnapshotGroup: %v", err)
+	}
+	if ch.Delta != 1 || len(ch.Joined) != 1 || ch.Joined[0] != "5@s.whatsapp.net" || len(ch.Left) != 0 || ch.MassLeave {
+		t.Fatalf("unexpected change: %+v", ch)
+	}
+	info.Participants = []types.GroupParticipant{member("1"), lid, member("6")}
+	ch, err = a.SnapshotGroup(ctx, info, t0.Add(48*time.Hour), 2)
+	if err != nil {
+		t.Fatalf("SnapshotGroup: %v", err)
+	}
+	if ch.Delta != -2 || len(ch.Left) != 3 || !ch.MassLeave {
+		t.Fatalf("expected a mass leave, got %+v", ch)
+	}
+	select {
+	case e := <-events:
+		if e.Type != EventMassLeave || e.Chat != group.String() {
+			t.Fatalf("unexpected event: %+v", e)
+		}
+	default:
+		t.Fatalf("expected a mass_leave event")
+	}
+
+	history, err := a.GroupMembershipHistory(group.String(), nil, 0, 2)
+	if err != nil {
+		t.Fatalf("GroupMembershipHistory: %v", err)
+	}
+	if len(history) != 3 || !history[2].Baseline || history[2].MemberCount != 4 || !history[0].MassLeave {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+
+	after := t0.Add(36 * time.Hour)
+	history, err = a.GroupMembershipHistory(group.String(), &after, 0, 2)
+	if err != nil {
+		t.Fatalf("GroupMembershipHistory: %v", err)
+	}
+	if len(history) != 1 || history[0].Baseline || history[0].Delta != -2 {
+		t.Fatalf("expected the last change compared to the snapshot before after, got %+v", history)
+	}
+
+	history, err = a.GroupMembershipHistory(group.String(), nil, 1, 0)
+	if err != nil {
+		t.Fatalf("GroupMembershipHistory: %v", err)
+	}
+	if len(history) != 1 || history[0].Baseline || history[0].MassLeave {
+		t.Fatalf("expected a limited history without mass leave flags, got %+v", history)
+	}
+
+	if n, err := a.SnapshotGroups(ctx, 0); err != nil || n != 1 {
+		t.Fatalf("SnapshotGroups = %d, %v", n, err)
+	}
+}
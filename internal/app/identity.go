@@ -0,0 +1,46 @@
+package app
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// storeIdentityChange records a contact's identity key change and leaves a
+// notice in their chat, like the "security code changed" line in the
+// official apps. Changes for contacts we have no chat with are only
+// recorded.
+func (a *App) storeIdentityChange(ctx context.Context, v *events.IdentityChange) error {
+	jid := v.JID.ToNonAD()
+	ic, err := a.db.RecordIdentityChange(jid.String(), v.Implicit, v.Timestamp)
+	if err != nil {
+		return err
+	}
+
+	chatName := ""
+	if c, err := a.db.GetChat(ic.JID); err == nil {
+		chatName = c.Name
+	} else if jid.Server != types.DefaultUserServer {
+		return nil
+	}
+	if chatName == "" {
+		chatName = a.wa.ResolveChatName(ctx, jid, "")
+	}
+	if err := a.db.UpsertChat(ic.JID, chatKind(jid), chatName, ic.ChangedAt); err != nil {
+		return err
+	}
+	return a.db.UpsertMessage(store.UpsertMessageParams{
+		ChatJID:     ic.JID,
+		ChatName:    chatName,
+		MsgID:       "identity:" + strconv.FormatInt(ic.ID, 10),
+		SenderJID:   ic.JID,
+		Timestamp:   ic.ChangedAt,
+		Text:        "Security code changed",
+		DisplayText: "🔐 Security code changed",
+		SystemType:  wa.SystemSecurityCodeChanged,
+	})
+}
@@ -0,0 +1,50 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestStoreIdentityChangeLeavesNotice(t *testing.T) {
+	a := newTestApp(t)
+	a.wa = newFakeWA()
+
+	contact := types.NewJID("5511999990000", types.DefaultUserServer)
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	if err := a.storeIdentityChange(ctx, &events.IdentityChange{JID: contact, Timestamp: ts}); err != nil {
+		t.Fatalf("storeIdentityChange: %v", err)
+	}
+	msgs, err := a.db.ListMessages(store.ListMessagesParams{ChatJID: contact.String(), Limit: 10})
+	if err != nil {
+		t.Fatalf("ListMessages: %v", err)
+	}
+	if len(msgs) != 1 || msgs[0].SystemType != wa.SystemSecurityCodeChanged || msgs[0].DisplayText != "🔐 Security code changed" || !msgs[0].Timestamp.Equal(ts) {
+		t.Fatalf("unexpected notice: %+v", msgs)
+	}
+
+	// Without a chat, LID identities are only recorded.
+	lid := types.NewJID("123456789", types.HiddenUserServer)
+	if err := a.storeIdentityChange(ctx, &events.IdentityChange{JID: lid, Timestamp: ts, Implicit: true}); err != nil {
+		t.Fatalf("storeIdentityChange: %v", err)
+	}
+	if _, err := a.db.GetChat(lid.String()); err == nil {
+		t.Fatalf("expected no chat for %s", lid)
+	}
+	changes, _ := a.db.ListIdentityChanges("", time.Time{}, 10)
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 recorded changes, got %+v", changes)
+	}
+
+	e, ok := convertWAEvent(&events.IdentityChange{JID: contact, Timestamp: ts, Implicit: true})
+	if !ok || e.Type != EventIdentityChange || e.Chat != contact.String() || e.Data["implicit"] != true {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+}
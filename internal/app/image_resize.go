@@ -0,0 +1,226 @@
+package app
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+
+	"golang.org/x/image/draw"
+)
+
+// ImageLimits bounds images before they are uploaded. Zero fields mean no
+// limit.
+type ImageLimits struct {
+	// MaxDimension bounds the longer side in pixels.
+	MaxDimension int
+	// MaxBytes bounds the file size.
+	MaxBytes int
+}
+
+// DefaultImageLimits matches what WhatsApp keeps of an image anyway; larger
+// ones are rejected or recompressed by the servers.
+var DefaultImageLimits = ImageLimits{MaxDimension: 4096, MaxBytes: 5 << 20}
+
+const resizeQuality = 85
+
+// Enabled reports whether any limit is set.
+func (l ImageLimits) Enabled() bool {
+	return l.MaxDimension > 0 || l.MaxBytes > 0
+}
+
+// ShrinkImage downscales an image exceeding the limits to fit MaxDimension
+// and re-encodes it as JPEG, applying its EXIF orientation since the
+// re-encoded file carries no metadata. ok is false, with data unchanged,
+// when the image is within the limits, in a format that cannot be decoded
+// (e.g. HEIC or animated GIF), or would not get smaller.
+func ShrinkImage(data []byte, limits ImageLimits) (out []byte, ok bool, err error) {
+	if !limits.Enabled() {
+		return data, false, nil
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if errors.Is(err, image.ErrFormat) || format == "gif" {
+		return data, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("decode image: %w", err)
+	}
+	tooLarge := limits.MaxDimension > 0 && max(cfg.Width, cfg.Height) > limits.MaxDimension
+	tooHeavy := limits.MaxBytes > 0 && len(data) > limits.MaxBytes
+	if !tooLarge && !tooHeavy {
+		return data, false, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, false, fmt.Errorf("decode image: %w", err)
+	}
+	maxSize := limits.MaxDimension
+	if maxSize <= 0 {
+		maxSize = max(cfg.Width, cfg.Height)
+	}
+	// The bounding box is square, so orienting after scaling gives the same
+	// result on far fewer pixels.
+	var dst image.Image = scaleToFit(src, maxSize)
+	if format == "jpeg" {
+		dst = applyOrientation(dst, jpegOrientation(data))
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: resizeQuality}); err != nil {
+		return nil, false, err
+	}
+	if !tooLarge && buf.Len() >= len(data) {
+		return data, false, nil
+	}
+	return buf.Bytes(), true, nil
+}
+
+// scaleToFit scales src to fit within maxSize×maxSize, flattening
+// transparency onto white for JPEG. Smaller images keep their size.
+func scaleToFit(src image.Image, maxSize int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > maxSize || h > maxSize {
+		if w >= h {
+			w, h = maxSize, max(1, h*maxSize/b.Dx())
+		} else {
+			w, h = max(1, w*maxSize/b.Dy()), maxSize
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+	return dst
+}
+
+// jpegOrientation returns the EXIF orientation (1-8) of a JPEG, or 1 if it
+// has none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 { // image data starts
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		seg := data[i+4 : i+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			return exifOrientation(seg[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+// exifOrientation reads the orientation tag from IFD0 of a TIFF structure.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	n := int(order.Uint16(tiff[ifd:]))
+	for k := 0; k < n; k++ {
+		e := ifd + 2 + 12*k
+		if e+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[e:]) == 0x0112 {
+			if o := int(order.Uint16(tiff[e+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyOrientation rotates and flips src so it displays upright for the
+// given EXIF orientation.
+func applyOrientation(src image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	// Orientations 5-8 swap width and height.
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored
+				dx, dy = w-1-x, y
+			case 3: // rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90° counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, src.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// GroupPhotoSize is the side of the square JPEG WhatsApp expects for group
+// and profile photos.
+const GroupPhotoSize = 640
+
+// SquareJPEG crops an image to its centred square, scales it to at most
+// size×size and encodes it as JPEG, applying its EXIF orientation.
+func SquareJPEG(data []byte, size int) ([]byte, error) {
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	if format == "jpeg" {
+		src = applyOrientation(src, jpegOrientation(data))
+	}
+	b := src.Bounds()
+	side := min(b.Dx(), b.Dy())
+	crop := image.Rect(0, 0, side, side).Add(b.Min).Add(image.Pt((b.Dx()-side)/2, (b.Dy()-side)/2))
+	out := min(side, size)
+	dst := image.NewRGBA(image.Rect(0, 0, out, out))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: resizeQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), uint8(x ^ y), 255})
+		}
+	}
+	return img
+}
+
+// withOrientation inserts an EXIF APP1 segment carrying the orientation
+// tag after the JPEG's start marker.
+func withOrientation(t *testing.T, jpg []byte, orientation uint16) []byte {
+	t.Helper()
+	var tiff bytes.Buffer
+	tiff.WriteString("MM")
+	binary.Write(&tiff, binary.BigEndian, uint16(42))
+	binary.Write(&tiff, binary.BigEndian, uint32(8))
+	binary.Write(&tiff, binary.BigEndian, uint16(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{0x0112, 3})
+	binary.Write(&tiff, binary.BigEndian, uint32(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{orientation, 0})
+	binary.Write(&tiff, binary.BigEndian, uint32(0))
+
+	seg := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	var out bytes.Buffer
+	out.Write(jpg[:2])
+	out.Write([]byte{0xFF, 0xE1})
+	binary.Write(&out, binary.BigEndian, uint16(len(seg)+2))
+	out.Write(seg)
+	out.Write(jpg[2:])
+	return out.Bytes()
+}
+
+func TestShrinkImage(t *testing.T) {
+	var pngBuf bytes.Buffer
+	if err := png.Encode(&pngBuf, testImage(400, 200)); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+
+	out, ok, err := ShrinkImage(pngBuf.Bytes(), ImageLimits{MaxDimension: 100})
+	if err != nil || !ok {
+		t.Fatalf("ShrinkImage = %v, %v", ok, err)
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("DecodeConfig: %v", err)
+	}
+	if format != "jpeg" || cfg.Width != 100 || cfg.Height != 50 {
+		t.Fatalf("expected a 100x50 jpeg, got %s %dx%d", format, cfg.Width, cfg.Height)
+	}
+
+	if out, ok, err := ShrinkImage(pngBuf.Bytes(), DefaultImageLimits); err != nil || ok || !bytes.Equal(out, pngBuf.Bytes()) {
+		t.Fatalf("expected an image within the limits unchanged, got ok=%v err=%v", ok, err)
+	}
+	if out, ok, err := ShrinkImage([]byte("not an image"), ImageLimits{MaxBytes: 1}); err != nil || ok || string(out) != "not an image" {
+		t.Fatalf("expected an unknown format unchanged, got ok=%v err=%v", ok, err)
+	}
+	if _, ok, _ := ShrinkImage(pngBuf.Bytes(), ImageLimits{}); ok {
+		t.Fatalf("expected no limits to leave the image alone")
+	}
+}
+
+func TestShrinkImageAppliesOrientation(t *testing.T) {
+	var jpgBuf bytes.Buffer
+	if err := jpeg.Encode(&jpgBuf, testImage(400, 200), nil); err != nil {
+		t.Fatalf("jpeg.Encode: %v", err)
+	}
+	rotated := withOrientation(t, jpgBuf.Bytes(), 6)
+	if o := jpegOrientation(rotated); o != 6 {
+		t.Fatalf("jpegOrientation = %d", o)
+	}
+
+	out, ok, err := ShrinkImage(rotated, ImageLimits{MaxDimension: 100})
+	if err != nil || !ok {
+		t.Fatalf("ShrinkImage = %v, %v", ok, err)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("DecodeConfig: %v", err)
+	}
+	if cfg.Width != 50 || cfg.Height != 100 {
+		t.Fatalf("expected the rotated image to be 50x100, got %dx%d", cfg.Width, cfg.Height)
+	}
+}
+
+func TestSquareJPEG(t *testing.T) {
+	var pngBuf bytes.Buffer
+	if err := png.Encode(&pngBuf, testImage(1000, 800)); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	out, err := SquareJPEG(pngBuf.Bytes(), GroupPhotoSize)
+	if err != nil {
+		t.Fatalf("SquareJPEG: %v", err)
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("DecodeConfig: %v", err)
+	}
+	if format != "jpeg" || cfg.Width != GroupPhotoSize || cfg.Height != GroupPhotoSize {
+		t.Fatalf("expected a 640x640 jpeg, got %s %dx%d", format, cfg.Width, cfg.Height)
+	}
+
+	pngBuf.Reset()
+	if err := png.Encode(&pngBuf, testImage(300, 200)); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	out, err = SquareJPEG(pngBuf.Bytes(), GroupPhotoSize)
+	if err != nil {
+		t.Fatalf("SquareJPEG: %v", err)
+	}
+	if cfg, _, _ := image.DecodeConfig(bytes.NewReader(out)); cfg.Width != 200 || cfg.Height != 200 {
+		t.Fatalf("expected a small image cropped but not enlarged, got %dx%d", cfg.Width, cfg.Height)
+	}
+
+	if _, err := SquareJPEG([]byte("not an image"), GroupPhotoSize); err == nil {
+		t.Fatalf("expected an error for data that is not an image")
+	}
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaOptions configures the Kafka event sink.
+type KafkaOptions struct {
+	Brokers []string
+	// Topic receives all events (default "wacli.events").
+	Topic string
+	// Events selects the event types to publish (default message and connection).
+	Events []string
+}
+
+type kafkaSink struct {
+	w *kafka.Writer
+}
+
+// NewKafkaSink creates an asynchronous producer. Messages are keyed by chat
+// JID, so events of one chat keep their order within a partition; the event
+// type is sent in the "type" header.
+func NewKafkaSink(opts KafkaOptions) (EventSink, error) {
+	if len(opts.Brokers) == 0 {
+		return nil, fmt.Errorf("kafka brokers are required")
+	}
+	topic := strings.TrimSpace(opts.Topic)
+	if topic == "" {
+		topic = "wacli.events"
+	}
+	w := &kafka.Writer{
+		Addr:                   kafka.TCP(opts.Brokers...),
+		Topic:                  topic,
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireOne,
+		BatchTimeout:           50 * time.Millisecond,
+		AllowAutoTopicCreation: true,
+		Async:                  true,
+		Completion: func(msgs []kafka.Message, err error) {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "kafka: dropped %d events: %v\n", len(msgs), err)
+			}
+		},
+	}
+	return &kafkaSink{w: w}, nil
+}
+
+func (s *kafkaSink) Publish(ctx context.Context, e Event, payload []byte) error {
+	return s.w.WriteMessages(ctx, kafka.Message{
+		Key:     []byte(e.Chat),
+		Value:   payload,
+		Time:    e.Timestamp,
+		Headers: []kafka.Header{{Key: "type", Value: []byte(e.Type)}},
+	})
+}
+
+func (s *kafkaSink) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// PhoneForLID returns the phone number JID of a LID from the mappings
+// learned so far. It is the LID resolver of wa.ParseUserOrJID.
+func (a *App) PhoneForLID(lid types.JID) (types.JID, bool) {
+	pn, err := a.db.PhoneForLID(lid.ToNonAD().String())
+	if err != nil {
+		return types.JID{}, false
+	}
+	jid, err := types.ParseJID(pn)
+	if err != nil {
+		return types.JID{}, false
+	}
+	return jid, true
+}
+
+// phoneJID returns the phone number JID of a LID with a known mapping,
+// keeping the device, and jid itself otherwise.
+func (a *App) phoneJID(jid types.JID) types.JID {
+	if jid.Server != types.HiddenUserServer {
+		return jid
+	}
+	pn, ok := a.PhoneForLID(jid)
+	if !ok {
+		return jid
+	}
+	pn.Device = jid.Device
+	return pn
+}
+
+// phoneJIDString is phoneJID for JIDs kept as strings.
+func (a *App) phoneJIDString(s string) string {
+	jid, err := types.ParseJID(s)
+	if err != nil || jid.Server != types.HiddenUserServer {
+		return s
+	}
+	return a.phoneJID(jid).String()
+}
+
+// rememberLID stores the mapping when x and y are the LID and phone number
+// JID of one account.
+func (a *App) rememberLID(x, y types.JID) {
+	lid, pn, ok := wa.IsLIDPair(x, y)
+	if !ok {
+		return
+	}
+	_, _ = a.db.PutLIDMapping(lid.String(), pn.String())
+}
+
+// rememberLIDStrings is rememberLID for the JID strings of history syncs.
+func (a *App) rememberLIDStrings(lid, pn string) {
+	l, err := types.ParseJID(lid)
+	if err != nil {
+		return
+	}
+	p, err := types.ParseJID(pn)
+	if err != nil {
+		return
+	}
+	a.rememberLID(l, p)
+}
+
+// resolveLIDs learns the mappings a message carries and rewrites its chat
+// and sender to phone number JIDs where they are known, so both forms of an
+// account end up in one chat and contact.
+func (a *App) resolveLIDs(pm wa.ParsedMessage) wa.ParsedMessage {
+	a.rememberLID(pm.Chat, pm.ChatAlt)
+	pm.Chat = a.phoneJID(pm.Chat)
+	if sender, err := types.ParseJID(pm.SenderJID); err == nil && pm.SenderJID != "" {
+		a.rememberLID(sender, pm.SenderAlt)
+		pm.SenderJID = a.phoneJID(sender).String()
+	}
+	return pm
+}
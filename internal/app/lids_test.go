@@ -0,0 +1,49 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestStoreParsedMessageMergesLIDChats(t *testing.T) {
+	a := newTestApp(t)
+	a.wa = newFakeWA()
+	ctx := context.Background()
+
+	lid := types.JID{User: "987654321", Server: types.HiddenUserServer}
+	pn := types.JID{User: "5511999990000", Server: types.DefaultUserServer}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// The first LID-addressed message carries the phone number.
+	if err := a.storeParsedMessage(ctx, wa.ParsedMessage{
+		Chat: lid, ChatAlt: pn, ID: "m1", SenderJID: lid.String(), SenderAlt: pn, Timestamp: base, Text: "hi",
+	}); err != nil {
+		t.Fatalf("storeParsedMessage: %v", err)
+	}
+	// Later ones may not; they still land in the same chat.
+	if err := a.storeParsedMessage(ctx, wa.ParsedMessage{
+		Chat: lid, ID: "m2", SenderJID: lid.String(), Timestamp: base.Add(time.Minute), Text: "again",
+	}); err != nil {
+		t.Fatalf("storeParsedMessage: %v", err)
+	}
+
+	for _, id := range []string{"m1", "m2"} {
+		m, err := a.db.GetMessage(pn.String(), id)
+		if err != nil || m.SenderJID != pn.String() {
+			t.Fatalf("%s: expected it under %s, got %+v (%v)", id, pn, m, err)
+		}
+	}
+	if _, err := a.db.GetChat(lid.String()); err == nil {
+		t.Fatalf("expected no separate LID chat")
+	}
+	if got, ok := a.PhoneForLID(lid); !ok || got != pn {
+		t.Fatalf("PhoneForLID = %s, %v", got, ok)
+	}
+	if j, err := wa.ParseUserOrJID(lid.String()); err != nil || j != pn {
+		t.Fatalf("ParseUserOrJID(%s) = %s (%v)", lid, j, err)
+	}
+}
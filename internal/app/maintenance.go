@@ -0,0 +1,221 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+)
+
+// Maintenance windows silence webhook alerts the way Alertmanager silences
+// do, but at the WhatsApp end: an alert whose labels match every matcher of
+// an active window is not sent. Batching windows keep what they held back
+// and send each recipient one summary when they end.
+
+// maintenanceSummaryMax is how many held-back alerts a summary lists.
+const maintenanceSummaryMax = 20
+
+// MaintenanceMatcher matches one label, in Alertmanager's silence format.
+// IsEqual defaults to true; false inverts the match.
+type MaintenanceMatcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex,omitempty"`
+	IsEqual *bool  `json:"isEqual,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// ParseMaintenanceMatchers decodes and compiles a JSON list of matchers.
+// Regular expressions are anchored at both ends.
+func ParseMaintenanceMatchers(raw string) ([]MaintenanceMatcher, error) {
+	var ms []MaintenanceMatcher
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&ms); err != nil {
+		return nil, err
+	}
+	if len(ms) == 0 {
+		return nil, fmt.Errorf("at least one matcher is required")
+	}
+	for i := range ms {
+		if strings.TrimSpace(ms[i].Name) == "" {
+			return nil, fmt.Errorf("matcher %d: name is required", i)
+		}
+		if ms[i].IsRegex {
+			re, err := regexp.Compile("^(?:" + ms[i].Value + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("matcher %s: %w", ms[i].Name, err)
+			}
+			ms[i].re = re
+		}
+	}
+	return ms, nil
+}
+
+func (m MaintenanceMatcher) matches(labels map[string]string) bool {
+	v := labels[m.Name]
+	var ok bool
+	if m.re != nil {
+		ok = m.re.MatchString(v)
+	} else {
+		ok = v == m.Value
+	}
+	if m.IsEqual != nil && !*m.IsEqual {
+		return !ok
+	}
+	return ok
+}
+
+// AlertLabels returns the labels maintenance matchers see for an alert:
+// its own labels plus "webhook" (the receiver) and "to" (the recipient as a
+// JID when it parses).
+func AlertLabels(webhook, to string, labels ...map[string]string) map[string]string {
+	out := map[string]string{}
+	for _, l := range labels {
+		for k, v := range l {
+			out[k] = v
+		}
+	}
+	out["webhook"] = webhook
+	out["to"] = to
+	if jid, err := wa.ParseUserOrJID(to); err == nil {
+		out["to"] = jid.String()
+	}
+	return out
+}
+
+// MaintenanceFor returns the active window silencing an alert with labels,
+// if any. Windows with matchers that no longer parse are skipped.
+func (a *App) MaintenanceFor(labels map[string]string, now time.Time) (store.MaintenanceWindow, bool, error) {
+	windows, err := a.db.ActiveMaintenanceWindows(now)
+	if err != nil {
+		return store.MaintenanceWindow{}, false, err
+	}
+	for _, w := range windows {
+		ms, err := ParseMaintenanceMatchers(w.Matchers)
+		if err != nil {
+			continue
+		}
+		all := true
+		for _, m := range ms {
+			if !m.matches(labels) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return w, true, nil
+		}
+	}
+	return store.MaintenanceWindow{}, false, nil
+}
+
+// SuppressAlert records that window w held back message for recipient.
+// Suppressing windows only count it; batching windows send it in their
+// summary.
+func (a *App) SuppressAlert(w store.MaintenanceWindow, recipient, message string) error {
+	if w.Mode != store.MaintenanceBatch {
+		message = ""
+	}
+	return a.db.AddSuppressedAlert(w.ID, recipient, message)
+}
+
+// SendMaintenanceSummaries queues one summary per recipient for each
+// batching window that has ended. Summaries go through the outbox, so they
+// wait for the connection like any other queued message.
+func (a *App) SendMaintenanceSummaries(now time.Time) (int, error) {
+	windows, err := a.db.DueMaintenanceSummaries(now)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, w := range windows {
+		alerts, err := a.db.ListSuppressedAlerts(w.ID)
+		if err != nil {
+			return sent, err
+		}
+		var recipients []string
+		byRecipient := map[string][]string{}
+		for _, al := range alerts {
+			if _, ok := byRecipient[al.Recipient]; !ok {
+				recipients = append(recipients, al.Recipient)
+			}
+			byRecipient[al.Recipient] = append(byRecipient[al.Recipient], al.Message)
+		}
+		for _, r := range recipients {
+			to, err := wa.ParseUserOrJID(r)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "maintenance: summary for %q: %v\n", r, err)
+				continue
+			}
+			if _, err := a.EnqueueText(to, maintenanceSummary(w, byRecipient[r])); err != nil {
+				return sent, err
+			}
+			sent++
+		}
+		if err := a.db.MarkMaintenanceSummarized(w.ID); err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+func maintenanceSummary(w store.MaintenanceWindow, messages []string) string {
+	var sb strings.Builder
+	sb.WriteString("🔧 Maintenance window ended")
+	if w.Comment != "" {
+		fmt.Fprintf(&sb, ": %s", w.Comment)
+	}
+	noun := "alerts were"
+	if len(messages) == 1 {
+		noun = "alert was"
+	}
+	fmt.Fprintf(&sb, "\n%d %s held back between %s and %s UTC:\n", len(messages), noun, w.StartsAt.UTC().Format("Jan 2 15:04"), w.EndsAt.UTC().Format("Jan 2 15:04"))
+	for i, m := range messages {
+		if i == maintenanceSummaryMax {
+			fmt.Fprintf(&sb, "\n…and %d more", len(messages)-i)
+			break
+		}
+		fmt.Fprintf(&sb, "\n• %s", summaryLine(m))
+	}
+	return sb.String()
+}
+
+// summaryLine puts a multi-line alert on one line.
+func summaryLine(m string) string {
+	var parts []string
+	for _, l := range strings.Split(m, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			parts = append(parts, l)
+		}
+	}
+	line := strings.Join(parts, " · ")
+	if r := []rune(line); len(r) > 200 {
+		line = string(r[:200]) + "…"
+	}
+	return line
+}
+
+// RunMaintenanceSummaries sends the summaries of ended batching windows
+// every minute until ctx is cancelled.
+func (a *App) RunMaintenanceSummaries(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		if _, err := a.SendMaintenanceSummaries(time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "maintenance: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
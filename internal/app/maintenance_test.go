@@ -0,0 +1,110 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+)
+
+func TestMaintenanceMatchers(t *testing.T) {
+	ms, err := ParseMaintenanceMatchers(`[
+		{"name": "alertname", "value": "Disk.*", "isRegex": true},
+		{"name": "env", "value": "staging", "isEqual": false}
+	]`)
+	if err != nil {
+		t.Fatalf("ParseMaintenanceMatchers: %v", err)
+	}
+	match := func(labels map[string]string) bool {
+		for _, m := range ms {
+			if !m.matches(labels) {
+				return false
+			}
+		}
+		return true
+	}
+	if !match(map[string]string{"alertname": "DiskFull", "env": "prod"}) {
+		t.Fatalf("expected DiskFull in prod to match")
+	}
+	if match(map[string]string{"alertname": "DiskFull", "env": "staging"}) {
+		t.Fatalf("expected the negative matcher to exclude staging")
+	}
+	if match(map[string]string{"alertname": "HighDiskFull"}) {
+		t.Fatalf("expected regexes to be anchored")
+	}
+
+	for _, bad := range []string{`[]`, `[{"value": "x"}]`, `[{"name": "a", "value": "(", "isRegex": true}]`, `[{"name": "a", "op": "="}]`} {
+		if _, err := ParseMaintenanceMatchers(bad); err == nil {
+			t.Fatalf("expected %s to be rejected", bad)
+		}
+	}
+
+	labels := AlertLabels("grafana", "5511999990000", map[string]string{"alertname": "DiskFull", "webhook": "spoofed"})
+	if labels["to"] != "5511999990000@s.whatsapp.net" || labels["webhook"] != "grafana" || labels["alertname"] != "DiskFull" {
+		t.Fatalf("labels = %v", labels)
+	}
+}
+
+func TestMaintenanceSummaries(t *testing.T) {
+	a := newTestApp(t)
+	now := time.Now().UTC()
+
+	w, err := a.db.AddMaintenanceWindow(store.MaintenanceWindow{
+		Matchers: `[{"name": "alertname", "value": "DiskFull"}]`,
+		Mode:     store.MaintenanceBatch,
+		Comment:  "db upgrade",
+		StartsAt: now.Add(-time.Hour),
+		EndsAt:   now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("AddMaintenanceWindow: %v", err)
+	}
+	quiet, err := a.db.AddMaintenanceWindow(store.MaintenanceWindow{
+		Matchers: `[{"name": "alertname", "value": "Noisy"}]`,
+		StartsAt: now.Add(-time.Hour),
+		EndsAt:   now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("AddMaintenanceWindow: %v", err)
+	}
+
+	got, ok, err := a.MaintenanceFor(AlertLabels("grafana", "5511999990000", map[string]string{"alertname": "DiskFull"}), now)
+	if err != nil || !ok || got.ID != w.ID {
+		t.Fatalf("MaintenanceFor = %+v %v %v", got, ok, err)
+	}
+	if _, ok, _ := a.MaintenanceFor(map[string]string{"alertname": "CPU"}, now); ok {
+		t.Fatalf("expected CPU alerts to go through")
+	}
+	if _, ok, _ := a.MaintenanceFor(map[string]string{"alertname": "DiskFull"}, now.Add(2*time.Hour)); ok {
+		t.Fatalf("expected the window to be over")
+	}
+
+	for _, m := range []string{"🔥 *FIRING*\nMonitor: db1", "🔥 *FIRING*\nMonitor: db2"} {
+		if err := a.SuppressAlert(got, "5511999990000@s.whatsapp.net", m); err != nil {
+			t.Fatalf("SuppressAlert: %v", err)
+		}
+	}
+	if err := a.SuppressAlert(quiet, "5511999990000@s.whatsapp.net", "Noisy"); err != nil {
+		t.Fatalf("SuppressAlert: %v", err)
+	}
+
+	if n, err := a.SendMaintenanceSummaries(now); err != nil || n != 0 {
+		t.Fatalf("expected no summary while the window is open: %d %v", n, err)
+	}
+	if n, err := a.SendMaintenanceSummaries(now.Add(2 * time.Hour)); err != nil || n != 1 {
+		t.Fatalf("SendMaintenanceSummaries = %d %v", n, err)
+	}
+	items, _ := a.db.ListOutbox(store.OutboxPending, 10)
+	if len(items) != 1 || items[0].ToJID != "5511999990000@s.whatsapp.net" {
+		t.Fatalf("outbox = %+v", items)
+	}
+	for _, want := range []string{"db upgrade", "2 alerts were held back", "• 🔥 *FIRING* · Monitor: db1", "Monitor: db2"} {
+		if !strings.Contains(items[0].Text, want) {
+			t.Fatalf("summary lacks %q:\n%s", want, items[0].Text)
+		}
+	}
+	if n, _ := a.SendMaintenanceSummaries(now.Add(3 * time.Hour)); n != 0 {
+		t.Fatalf("expected the summary to be sent once")
+	}
+}
@@ -2,17 +2,35 @@ package app
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"mime"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/steipete/wacli/internal/mediastore"
 	"github.com/steipete/wacli/internal/pathutil"
 	"github.com/steipete/wacli/internal/store"
+	"go.mau.fi/whatsmeow"
+)
+
+var (
+	// ErrNoMedia is returned for a message without downloadable media.
+	ErrNoMedia = errors.New("message has no downloadable media")
+	// ErrMediaExpired means WhatsApp's servers no longer have the media.
+	ErrMediaExpired = errors.New("media is no longer available on WhatsApp")
+	// ErrNotDownloaded means the media was never downloaded or its file has
+	// been removed since.
+	ErrNotDownloaded = errors.New("media has not been downloaded")
 )
 
 type mediaJob struct {
@@ -20,8 +38,11 @@ type mediaJob struct {
 	msgID   string
 }
 
+// ResolveMediaOutputPath returns the file to download the message's media
+// to: requested (a file, or a directory to put it in), or by default its
+// place in the store's media directory.
 func (a *App) ResolveMediaOutputPath(info store.MediaDownloadInfo, requested string) (string, error) {
-	filename := mediaFilename(info)
+	filename := MediaFilename(info)
 
 	if strings.TrimSpace(requested) != "" {
 		out := requested
@@ -39,17 +60,31 @@ func (a *App) ResolveMediaOutputPath(info store.MediaDownloadInfo, requested str
 		return out, nil
 	}
 
-	baseDir := filepath.Join(a.opts.StoreDir, "media", pathutil.SanitizeSegment(info.ChatJID), pathutil.SanitizeSegment(info.MsgID))
-	if info.MediaType != "" {
-		baseDir = filepath.Join(baseDir, pathutil.SanitizeSegment(info.MediaType))
+	out := filepath.Join(a.opts.StoreDir, "media", filepath.FromSlash(mediaKey(info)))
+	if abs, err := filepath.Abs(out); err == nil {
+		out = abs
 	}
-	if abs, err := filepath.Abs(baseDir); err == nil {
-		baseDir = abs
+	return out, nil
+}
+
+// mediaKey is where the message's media is kept in the media store. Media
+// with a known hash is stored once by content, so forwards of the same file
+// share it.
+func mediaKey(info store.MediaDownloadInfo) string {
+	filename := MediaFilename(info)
+	if sum := mediaSHA256(info); sum != "" {
+		return path.Join("sha256", sum[:2], sum+filepath.Ext(filename))
+	}
+	parts := []string{pathutil.SanitizeSegment(info.ChatJID), pathutil.SanitizeSegment(info.MsgID)}
+	if info.MediaType != "" {
+		parts = append(parts, pathutil.SanitizeSegment(info.MediaType))
 	}
-	return filepath.Join(baseDir, filename), nil
+	return path.Join(append(parts, filename)...)
 }
 
-func mediaFilename(info store.MediaDownloadInfo) string {
+// MediaFilename is the file name to offer for the message's media: the
+// document's name, or one derived from the message ID and MIME type.
+func MediaFilename(info store.MediaDownloadInfo) string {
 	name := strings.TrimSpace(info.Filename)
 	ext := ""
 	if strings.TrimSpace(info.MimeType) != "" {
@@ -121,22 +156,179 @@ func (a *App) downloadMediaJob(ctx context.Context, job mediaJob) error {
 	if strings.TrimSpace(info.LocalPath) != "" {
 		return nil
 	}
-	if strings.TrimSpace(info.MediaType) == "" || strings.TrimSpace(info.DirectPath) == "" || len(info.MediaKey) == 0 {
+	if !HasDownloadableMedia(info) {
 		return nil
 	}
+	_, err = a.DownloadMedia(ctx, info)
+	return err
+}
+
+// HasDownloadableMedia reports whether the stored metadata is enough to
+// download the message's media.
+func HasDownloadableMedia(info store.MediaDownloadInfo) bool {
+	return strings.TrimSpace(info.MediaType) != "" && strings.TrimSpace(info.DirectPath) != "" && len(info.MediaKey) > 0
+}
+
+// OpenMedia opens the message's downloaded media. It returns
+// ErrNotDownloaded if there is none.
+func (a *App) OpenMedia(ctx context.Context, info store.MediaDownloadInfo) (io.ReadSeekCloser, mediastore.Object, error) {
+	if strings.TrimSpace(info.LocalPath) == "" {
+		return nil, mediastore.Object{}, ErrNotDownloaded
+	}
+	r, obj, err := a.openMediaLocation(ctx, info.LocalPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, mediastore.Object{}, ErrNotDownloaded
+	}
+	return r, obj, err
+}
 
-	targetPath, err := a.ResolveMediaOutputPath(info, "")
+// openMediaLocation opens a recorded media location: an object in the media
+// store, or a local file saved elsewhere with --output or before the media
+// store changed.
+func (a *App) openMediaLocation(ctx context.Context, location string) (io.ReadSeekCloser, mediastore.Object, error) {
+	if key, ok := a.media.Key(location); ok {
+		return a.media.Open(ctx, key)
+	}
+	f, err := os.Open(location)
 	if err != nil {
-		return err
+		return nil, mediastore.Object{}, err
 	}
-	if err := os.MkdirAll(filepath.Dir(targetPath), 0700); err != nil {
-		return err
+	st, err := f.Stat()
+	if err == nil && st.IsDir() {
+		err = fmt.Errorf("%s: %w", location, fs.ErrNotExist)
 	}
+	if err != nil {
+		f.Close()
+		return nil, mediastore.Object{}, err
+	}
+	return f, mediastore.Object{Key: location, Size: st.Size(), ModTime: st.ModTime().UTC()}, nil
+}
 
-	if _, err := a.wa.DownloadMediaToFile(ctx, info.DirectPath, info.FileEncSHA256, info.FileSHA256, info.MediaKey, info.FileLength, info.MediaType, "", targetPath); err != nil {
-		return err
+// mediaExists reports whether a recorded media location still has its file.
+func (a *App) mediaExists(ctx context.Context, location string) bool {
+	if key, ok := a.media.Key(location); ok {
+		_, err := a.media.Stat(ctx, key)
+		return err == nil
+	}
+	st, err := os.Stat(location)
+	return err == nil && !st.IsDir()
+}
+
+// mediaDownload is an in-flight download that concurrent callers share.
+type mediaDownload struct {
+	done     chan struct{}
+	location string
+	err      error
+}
+
+// DownloadMedia downloads the message's media into the media store and
+// records it, returning its location, so later requests are served from the
+// store. The client must be connected. Files are stored by their SHA-256, so media
+// forwarded between chats is downloaded once. Concurrent calls for the same
+// media share one download, so e.g. a player issuing several range
+// requests at once does not fetch the file repeatedly.
+func (a *App) DownloadMedia(ctx context.Context, info store.MediaDownloadInfo) (string, error) {
+	if !HasDownloadableMedia(info) {
+		return "", ErrNoMedia
+	}
+	location, err := a.fetchMedia(ctx, info)
+	if err != nil {
+		return "", err
+	}
+	if err := a.db.MarkMediaDownloaded(info.ChatJID, info.MsgID, location, mediaSHA256(info), time.Now().UTC()); err != nil {
+		return "", err
+	}
+	if _, err := a.generateThumbnail(ctx, info, location); err != nil && !errors.Is(err, ErrNoThumbnail) {
+		fmt.Fprintf(os.Stderr, "thumbnails: %s/%s: %v\n", info.ChatJID, info.MsgID, err)
+	}
+	return location, nil
+}
+
+// fetchMedia returns the location of the message's media, downloading it
+// unless a file with the same SHA-256 is already stored.
+func (a *App) fetchMedia(ctx context.Context, info store.MediaDownloadInfo) (string, error) {
+	key := mediaSHA256(info)
+	if key == "" {
+		key = info.ChatJID + "/" + info.MsgID
+	}
+	a.mediaMu.Lock()
+	if a.mediaDownloads == nil {
+		a.mediaDownloads = map[string]*mediaDownload{}
 	}
+	if dl, ok := a.mediaDownloads[key]; ok {
+		a.mediaMu.Unlock()
+		select {
+		case <-dl.done:
+			return dl.location, dl.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	dl := &mediaDownload{done: make(chan struct{})}
+	a.mediaDownloads[key] = dl
+	a.mediaMu.Unlock()
 
-	now := time.Now().UTC()
-	return a.db.MarkMediaDownloaded(info.ChatJID, info.MsgID, targetPath, now)
+	if dl.location = a.storedMedia(ctx, info); dl.location == "" {
+		dl.location, dl.err = a.downloadMedia(ctx, info)
+	}
+	a.mediaMu.Lock()
+	delete(a.mediaDownloads, key)
+	a.mediaMu.Unlock()
+	close(dl.done)
+	return dl.location, dl.err
+}
+
+// storedMedia returns the location of stored media with the same content,
+// or "".
+func (a *App) storedMedia(ctx context.Context, info store.MediaDownloadInfo) string {
+	sum := mediaSHA256(info)
+	if sum == "" {
+		return ""
+	}
+	candidates, _ := a.db.MediaPathsBySHA256(sum)
+	candidates = append([]string{a.media.Location(mediaKey(info))}, candidates...)
+	for _, loc := range candidates {
+		if a.mediaExists(ctx, loc) {
+			return loc
+		}
+	}
+	return ""
+}
+
+// downloadMedia downloads the media to a temporary file and puts it into
+// the media store.
+func (a *App) downloadMedia(ctx context.Context, info store.MediaDownloadInfo) (string, error) {
+	// Download inside the media directory so the filesystem store can move
+	// the file into place.
+	root := filepath.Join(a.opts.StoreDir, "media")
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return "", err
+	}
+	dir, err := os.MkdirTemp(root, mediastore.TempPrefix)
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, MediaFilename(info))
+
+	if _, err := a.wa.DownloadMediaToFile(ctx, info.DirectPath, info.FileEncSHA256, info.FileSHA256, info.MediaKey, info.FileLength, info.MediaType, "", tmp); err != nil {
+		if errors.Is(err, whatsmeow.ErrMediaDownloadFailedWith404) || errors.Is(err, whatsmeow.ErrMediaDownloadFailedWith410) {
+			return "", fmt.Errorf("%w: %v", ErrMediaExpired, err)
+		}
+		return "", err
+	}
+	key := mediaKey(info)
+	if err := a.media.Put(ctx, key, tmp, info.MimeType); err != nil {
+		return "", fmt.Errorf("store media: %w", err)
+	}
+	return a.media.Location(key), nil
+}
+
+// mediaSHA256 is the hex SHA-256 of the media's plaintext, which WhatsApp
+// verifies on download, or "" if the message does not carry it.
+func mediaSHA256(info store.MediaDownloadInfo) string {
+	if len(info.FileSHA256) != sha256.Size {
+		return ""
+	}
+	return hex.EncodeToString(info.FileSHA256)
 }
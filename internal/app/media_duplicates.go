@@ -0,0 +1,53 @@
+package app
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+)
+
+// Incoming media is compared with the archive by the file hash WhatsApp
+// sends with every media message, so a resubmitted file (the same invoice
+// sent twice, to the same or another chat) is recognised without
+// downloading anything.
+
+// markMediaDuplicate links a stored incoming media message to the earliest
+// archived message with the same file, if there is one.
+func (a *App) markMediaDuplicate(chatJID, msgID string, sha []byte, ts time.Time) error {
+	if len(sha) == 0 {
+		return nil
+	}
+	orig, err := a.db.FindMediaOriginal(sha, chatJID, msgID, ts)
+	if store.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return a.db.MarkMediaDuplicate(chatJID, msgID, orig)
+}
+
+// annotateDuplicate adds data.duplicate_of to an incoming media message
+// event whose file is already in the archive, for subscribers and rules.
+func (a *App) annotateDuplicate(e Event) {
+	if fromMe, _ := e.Data["from_me"].(bool); fromMe {
+		return
+	}
+	s, _ := e.Data["file_sha256"].(string)
+	sha, err := hex.DecodeString(s)
+	if err != nil || len(sha) == 0 {
+		return
+	}
+	id, _ := e.Data["id"].(string)
+	orig, err := a.db.FindMediaOriginal(sha, e.Chat, id, e.Timestamp)
+	if err != nil {
+		return
+	}
+	e.Data["duplicate_of"] = map[string]any{
+		"chat":      orig.ChatJID,
+		"id":        orig.MsgID,
+		"sender":    orig.SenderJID,
+		"timestamp": orig.Timestamp,
+	}
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+)
+
+func TestDuplicateMediaFlaggedAndRouted(t *testing.T) {
+	a := newTestApp(t)
+	alice := "111@s.whatsapp.net"
+	bob := "222@s.whatsapp.net"
+	sha := []byte{0xca, 0xfe}
+	t0 := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	for _, chat := range []string{alice, bob} {
+		if err := a.db.UpsertChat(chat, "dm", "", t0); err != nil {
+			t.Fatalf("UpsertChat: %v", err)
+		}
+	}
+	if err := a.db.UpsertMessage(store.UpsertMessageParams{ChatJID: alice, MsgID: "orig", SenderJID: alice, Timestamp: t0, MediaType: "document", FileSHA256: sha}); err != nil {
+		t.Fatalf("UpsertMessage: %v", err)
+	}
+	if err := a.markMediaDuplicate(alice, "orig", sha, t0); err != nil {
+		t.Fatalf("markMediaDuplicate: %v", err)
+	}
+	if m, _ := a.db.GetMessage(alice, "orig"); m.DuplicateOf != nil {
+		t.Fatalf("first copy should not be a duplicate: %+v", m.DuplicateOf)
+	}
+
+	at := t0.Add(time.Hour)
+	if err := a.db.UpsertMessage(store.UpsertMessageParams{ChatJID: bob, MsgID: "dup", SenderJID: bob, Timestamp: at, MediaType: "document", FileSHA256: sha}); err != nil {
+		t.Fatalf("UpsertMessage: %v", err)
+	}
+	if err := a.markMediaDuplicate(bob, "dup", sha, at); err != nil {
+		t.Fatalf("markMediaDuplicate: %v", err)
+	}
+	if m, _ := a.db.GetMessage(bob, "dup"); m.DuplicateOf == nil || m.DuplicateOf.MsgID != "orig" {
+		t.Fatalf("expected duplicate of orig, got %+v", m.DuplicateOf)
+	}
+
+	if _, err := a.db.CreateRule(store.CreateRuleParams{DuplicateMedia: true, Action: store.RuleTag, Arg: "resubmitted"}); err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+
+	dup := messageEvent(bob, "", map[string]any{"id": "dup", "media_type": "document", "file_sha256": hex.EncodeToString(sha)})
+	dup.Timestamp = at
+	a.annotateDuplicate(dup)
+	d, _ := dup.Data["duplicate_of"].(map[string]any)
+	if d == nil || d["chat"] != alice || d["id"] != "orig" || d["sender"] != alice {
+		t.Fatalf("unexpected duplicate_of: %v", dup.Data["duplicate_of"])
+	}
+	a.applyRules(dup)
+	if tags, _ := dup.Data["tags"].([]string); len(tags) != 1 || tags[0] != "resubmitted" {
+		t.Fatalf("expected resubmitted tag, got %v", dup.Data["tags"])
+	}
+
+	fresh := messageEvent(bob, "", map[string]any{"id": "new", "media_type": "document", "file_sha256": hex.EncodeToString([]byte{1})})
+	fresh.Timestamp = at
+	a.annotateDuplicate(fresh)
+	a.applyRules(fresh)
+	if _, ok := fresh.Data["duplicate_of"]; ok {
+		t.Fatalf("new file flagged as duplicate")
+	}
+	if _, ok := fresh.Data["tags"]; ok {
+		t.Fatalf("rule should only match duplicates, got %v", fresh.Data["tags"])
+	}
+}
@@ -0,0 +1,210 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/steipete/wacli/internal/mediastore"
+)
+
+// ErrMediaGCRunning is returned when a media garbage collection is already
+// in progress.
+var ErrMediaGCRunning = errors.New("media gc already running")
+
+// mediaOrphanGrace keeps unreferenced files this young, so a download that
+// has been written but not yet recorded is not collected.
+var mediaOrphanGrace = time.Hour
+
+// MediaRetention limits the media downloaded into the store. Zero values
+// mean no limit.
+type MediaRetention struct {
+	// MaxAge removes files last downloaded longer ago than this.
+	MaxAge time.Duration
+	// MaxBytes removes the least recently downloaded files until the media
+	// directory fits.
+	MaxBytes int64
+}
+
+// Enabled reports whether any limit is set.
+func (r MediaRetention) Enabled() bool {
+	return r.MaxAge > 0 || r.MaxBytes > 0
+}
+
+// MediaGCResult reports what a media garbage collection reclaimed. Removed
+// files that messages referenced are downloaded again when next requested.
+type MediaGCResult struct {
+	DryRun         bool  `json:"dry_run"`
+	FilesRemoved   int   `json:"files_removed"`
+	BytesReclaimed int64 `json:"bytes_reclaimed"`
+	// Expired, OverQuota and Orphaned break FilesRemoved down by reason.
+	Expired   int `json:"expired"`
+	OverQuota int `json:"over_quota"`
+	Orphaned  int `json:"orphaned"`
+	// MessagesCleared counts messages whose media must be downloaded again.
+	MessagesCleared int64 `json:"messages_cleared"`
+	FilesKept       int   `json:"files_kept"`
+	BytesKept       int64 `json:"bytes_kept"`
+}
+
+type mediaFile struct {
+	key  string
+	size int64
+	// lastUsed is the newest download of the file by any message, or its
+	// modification time if no message references it.
+	lastUsed time.Time
+	// locations are the distinct references recorded for the file.
+	locations []string
+	// exempt keeps the file whatever the policy, because a message in a
+	// chat on legal hold or never pruned references it.
+	exempt bool
+}
+
+// CollectMediaGarbage applies the retention policy to the media store.
+// Files no message references are removed regardless of the policy, and
+// files of chats on legal hold or never pruned are always kept. Media
+// saved outside the store (wacli media download --output) is never
+// touched. With dryRun nothing is removed.
+func (a *App) CollectMediaGarbage(ctx context.Context, r MediaRetention, dryRun bool) (MediaGCResult, error) {
+	if !a.mediaGCMu.TryLock() {
+		return MediaGCResult{}, ErrMediaGCRunning
+	}
+	defer a.mediaGCMu.Unlock()
+
+	res := MediaGCResult{DryRun: dryRun}
+	files, err := a.scanMediaFiles(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	now := time.Now().UTC()
+	var total int64
+	var keep []*mediaFile
+	remove := func(f *mediaFile) error {
+		if !dryRun {
+			if err := a.media.Delete(ctx, f.key); err != nil {
+				return err
+			}
+			for _, loc := range f.locations {
+				n, err := a.db.ClearMediaDownloaded(loc)
+				if err != nil {
+					return err
+				}
+				res.MessagesCleared += n
+			}
+		}
+		res.FilesRemoved++
+		res.BytesReclaimed += f.size
+		return nil
+	}
+
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		switch {
+		case len(f.locations) == 0 && now.Sub(f.lastUsed) > mediaOrphanGrace:
+			res.Orphaned++
+		case len(f.locations) > 0 && !f.exempt && r.MaxAge > 0 && now.Sub(f.lastUsed) > r.MaxAge:
+			res.Expired++
+		default:
+			keep = append(keep, f)
+			total += f.size
+			continue
+		}
+		if err := remove(f); err != nil {
+			return res, err
+		}
+	}
+
+	if r.MaxBytes > 0 && total > r.MaxBytes {
+		sort.Slice(keep, func(i, j int) bool { return keep[i].lastUsed.Before(keep[j].lastUsed) })
+		var rest []*mediaFile
+		for i, f := range keep {
+			if total <= r.MaxBytes {
+				rest = append(rest, keep[i:]...)
+				break
+			}
+			if f.exempt {
+				rest = append(rest, f)
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return res, err
+			}
+			if err := remove(f); err != nil {
+				return res, err
+			}
+			res.OverQuota++
+			total -= f.size
+		}
+		keep = rest
+	}
+	res.FilesKept = len(keep)
+	res.BytesKept = total
+	return res, nil
+}
+
+// scanMediaFiles lists the media store's files with the downloads that
+// reference them.
+func (a *App) scanMediaFiles(ctx context.Context) ([]*mediaFile, error) {
+	byKey := map[string]*mediaFile{}
+	var files []*mediaFile
+	err := a.media.Walk(ctx, "", func(o mediastore.Object) error {
+		f := &mediaFile{key: o.Key, size: o.Size, lastUsed: o.ModTime}
+		byKey[o.Key] = f
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := a.db.ListDownloadedMedia()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range refs {
+		key, ok := a.media.Key(m.LocalPath)
+		if !ok || byKey[key] == nil {
+			continue
+		}
+		f := byKey[key]
+		if len(f.locations) == 0 || m.DownloadedAt.After(f.lastUsed) {
+			f.lastUsed = m.DownloadedAt
+		}
+		f.exempt = f.exempt || m.Exempt
+		if !slices.Contains(f.locations, m.LocalPath) {
+			f.locations = append(f.locations, m.LocalPath)
+		}
+	}
+	return files, nil
+}
+
+// RunMediaGC applies the retention policy hourly until ctx is cancelled.
+// It does nothing when no limit is set.
+func (a *App) RunMediaGC(ctx context.Context, r MediaRetention) {
+	if !r.Enabled() {
+		return
+	}
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		res, err := a.CollectMediaGarbage(ctx, r, false)
+		switch {
+		case err != nil && !errors.Is(err, ErrMediaGCRunning) && !errors.Is(err, context.Canceled):
+			fmt.Fprintf(os.Stderr, "media gc: %v\n", err)
+		case res.FilesRemoved > 0:
+			fmt.Fprintf(os.Stderr, "media gc: removed %d files (%d bytes)\n", res.FilesRemoved, res.BytesReclaimed)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+)
+
+// writeGCMedia creates a media file of size bytes and records it as msgID's
+// download at the given time.
+func writeGCMedia(t *testing.T, a *App, chat, msgID, name string, size int, at time.Time) string {
+	t.Helper()
+	path := filepath.Join(a.opts.StoreDir, "media", "sha256", name[:2], name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if msgID == "" {
+		old := time.Now().Add(-2 * mediaOrphanGrace)
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatalf("Chtimes: %v", err)
+		}
+		return path
+	}
+	if err := a.db.UpsertMessage(store.UpsertMessageParams{ChatJID: chat, MsgID: msgID, SenderJID: chat, Timestamp: at, MediaType: "image"}); err != nil {
+		t.Fatalf("UpsertMessage: %v", err)
+	}
+	if err := a.db.MarkMediaDownloaded(chat, msgID, path, "", at); err != nil {
+		t.Fatalf("MarkMediaDownloaded: %v", err)
+	}
+	return path
+}
+
+func TestCollectMediaGarbage(t *testing.T) {
+	a := newTestApp(t)
+	chat := "123@s.whatsapp.net"
+	if err := a.db.UpsertChat(chat, "dm", "Alice", time.Now()); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	now := time.Now().UTC()
+	expired := writeGCMedia(t, a, chat, "old", "aa01.jpg", 10, now.Add(-40*24*time.Hour))
+	oldest := writeGCMedia(t, a, chat, "mid", "bb01.jpg", 100, now.Add(-5*24*time.Hour))
+	newest := writeGCMedia(t, a, chat, "new", "cc01.jpg", 100, now.Add(-time.Hour))
+	orphan := writeGCMedia(t, a, chat, "", "dd01.jpg", 7, time.Time{})
+	partial := filepath.Join(a.opts.StoreDir, "media", ".wacli-download-123")
+	if err := os.WriteFile(partial, []byte("x"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	r := MediaRetention{MaxAge: 30 * 24 * time.Hour, MaxBytes: 150}
+	dry, err := a.CollectMediaGarbage(context.Background(), r, true)
+	if err != nil {
+		t.Fatalf("CollectMediaGarbage dry run: %v", err)
+	}
+	if dry.FilesRemoved != 3 || dry.BytesReclaimed != 117 {
+		t.Fatalf("unexpected dry run result: %+v", dry)
+	}
+	if _, err := os.Stat(expired); err != nil {
+		t.Fatalf("dry run removed a file: %v", err)
+	}
+
+	res, err := a.CollectMediaGarbage(context.Background(), r, false)
+	if err != nil {
+		t.Fatalf("CollectMediaGarbage: %v", err)
+	}
+	if res.Expired != 1 || res.OverQuota != 1 || res.Orphaned != 1 || res.MessagesCleared != 2 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if res.FilesKept != 1 || res.BytesKept != 100 {
+		t.Fatalf("unexpected remaining: %+v", res)
+	}
+	for _, p := range []string{expired, oldest, orphan} {
+		if _, err := os.Stat(p); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("expected %s removed, got %v", p, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Dir(expired)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected empty directory removed, got %v", err)
+	}
+	for _, p := range []string{newest, partial} {
+		if _, err := os.Stat(p); err != nil {
+			t.Fatalf("expected %s kept: %v", p, err)
+		}
+	}
+	info, err := a.db.GetMediaDownloadInfo(chat, "mid")
+	if err != nil {
+		t.Fatalf("GetMediaDownloadInfo: %v", err)
+	}
+	if info.LocalPath != "" {
+		t.Fatalf("expected download record cleared, got %q", info.LocalPath)
+	}
+}
+
+func TestCollectMediaGarbageRejectsOverlap(t *testing.T) {
+	a := newTestApp(t)
+	a.mediaGCMu.Lock()
+	defer a.mediaGCMu.Unlock()
+	if _, err := a.CollectMediaGarbage(context.Background(), MediaRetention{}, false); !errors.Is(err, ErrMediaGCRunning) {
+		t.Fatalf("expected ErrMediaGCRunning, got %v", err)
+	}
+}
@@ -1,8 +1,14 @@
 package app
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
+	"errors"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -54,3 +60,159 @@ func TestDownloadMediaJobMarksDownloaded(t *testing.T) {
 		t.Fatalf("expected downloaded file to exist: %v", err)
 	}
 }
+
+func TestDownloadMediaCachesFile(t *testing.T) {
+	a := newTestApp(t)
+	a.wa = newFakeWA()
+
+	chat := "123@s.whatsapp.net"
+	if err := a.db.UpsertChat(chat, "dm", "Alice", time.Now()); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	for _, p := range []store.UpsertMessageParams{
+		{ChatJID: chat, MsgID: "img", SenderJID: chat, Timestamp: time.Now(), MediaType: "image", MimeType: "image/jpeg", DirectPath: "/direct/path", MediaKey: []byte{1}},
+		{ChatJID: chat, MsgID: "bare", SenderJID: chat, Timestamp: time.Now(), MediaType: "image"},
+	} {
+		if err := a.db.UpsertMessage(p); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+
+	info, err := a.db.GetMediaDownloadInfo(chat, "img")
+	if err != nil {
+		t.Fatalf("GetMediaDownloadInfo: %v", err)
+	}
+	if _, _, err := a.OpenMedia(context.Background(), info); !errors.Is(err, ErrNotDownloaded) {
+		t.Fatalf("expected ErrNotDownloaded before download, got %v", err)
+	}
+	path, err := a.DownloadMedia(context.Background(), info)
+	if err != nil {
+		t.Fatalf("DownloadMedia: %v", err)
+	}
+	info, _ = a.db.GetMediaDownloadInfo(chat, "img")
+	if info.LocalPath != path {
+		t.Fatalf("LocalPath = %q, want %q", info.LocalPath, path)
+	}
+	f, obj, err := a.OpenMedia(context.Background(), info)
+	if err != nil {
+		t.Fatalf("OpenMedia: %v", err)
+	}
+	f.Close()
+	if obj.Size != int64(len("test")) {
+		t.Fatalf("expected the downloaded size, got %+v", obj)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, _, err := a.OpenMedia(context.Background(), info); !errors.Is(err, ErrNotDownloaded) {
+		t.Fatalf("expected a removed file not to count as downloaded, got %v", err)
+	}
+
+	bare, _ := a.db.GetMediaDownloadInfo(chat, "bare")
+	if _, err := a.DownloadMedia(context.Background(), bare); !errors.Is(err, ErrNoMedia) {
+		t.Fatalf("expected ErrNoMedia, got %v", err)
+	}
+}
+
+func TestDownloadMediaSharesConcurrentDownloads(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.downloadGate = make(chan struct{})
+	a.wa = f
+
+	chat := "123@s.whatsapp.net"
+	if err := a.db.UpsertChat(chat, "dm", "Alice", time.Now()); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	if err := a.db.UpsertMessage(store.UpsertMessageParams{ChatJID: chat, MsgID: "vid", SenderJID: chat, Timestamp: time.Now(), MediaType: "video", MimeType: "video/mp4", DirectPath: "/direct/path", MediaKey: []byte{1}}); err != nil {
+		t.Fatalf("UpsertMessage: %v", err)
+	}
+	info, err := a.db.GetMediaDownloadInfo(chat, "vid")
+	if err != nil {
+		t.Fatalf("GetMediaDownloadInfo: %v", err)
+	}
+
+	const n = 4
+	var wg sync.WaitGroup
+	paths := make([]string, n)
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			paths[i], errs[i] = a.DownloadMedia(context.Background(), info)
+		}(i)
+	}
+	// Wait until every caller has either started the download or joined it.
+	for {
+		a.mediaMu.Lock()
+		dl := a.mediaDownloads[chat+"/vid"]
+		a.mediaMu.Unlock()
+		f.mu.Lock()
+		started := f.downloads
+		f.mu.Unlock()
+		if dl != nil && started == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(f.downloadGate)
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		if errs[i] != nil {
+			t.Fatalf("DownloadMedia %d: %v", i, errs[i])
+		}
+		if paths[i] != paths[0] {
+			t.Fatalf("path %d = %q, want %q", i, paths[i], paths[0])
+		}
+	}
+	if f.downloads != 1 {
+		t.Fatalf("downloads = %d, want 1", f.downloads)
+	}
+}
+
+func TestDownloadMediaDedupesBySHA256(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	a.wa = f
+
+	sum := bytes.Repeat([]byte{0xab}, 32)
+	var infos []store.MediaDownloadInfo
+	for _, chat := range []string{"123@s.whatsapp.net", "456@s.whatsapp.net"} {
+		if err := a.db.UpsertChat(chat, "dm", "", time.Now()); err != nil {
+			t.Fatalf("UpsertChat: %v", err)
+		}
+		if err := a.db.UpsertMessage(store.UpsertMessageParams{ChatJID: chat, MsgID: "fwd", SenderJID: chat, Timestamp: time.Now(), MediaType: "document", MimeType: "application/pdf", Filename: "report.pdf", DirectPath: "/direct/path", MediaKey: []byte{1}, FileSHA256: sum}); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+		info, err := a.db.GetMediaDownloadInfo(chat, "fwd")
+		if err != nil {
+			t.Fatalf("GetMediaDownloadInfo: %v", err)
+		}
+		infos = append(infos, info)
+	}
+
+	first, err := a.DownloadMedia(context.Background(), infos[0])
+	if err != nil {
+		t.Fatalf("DownloadMedia: %v", err)
+	}
+	if want := filepath.Join("media", "sha256", "ab", hex.EncodeToString(sum)+".pdf"); !strings.HasSuffix(first, want) {
+		t.Fatalf("path = %q, want suffix %q", first, want)
+	}
+	second, err := a.DownloadMedia(context.Background(), infos[1])
+	if err != nil || second != first {
+		t.Fatalf("DownloadMedia = %q, %v; want %q", second, err, first)
+	}
+	if f.downloads != 1 {
+		t.Fatalf("downloads = %d, want 1", f.downloads)
+	}
+	info, _ := a.db.GetMediaDownloadInfo(infos[1].ChatJID, "fwd")
+	if info.LocalPath != first || info.LocalSHA256 != hex.EncodeToString(sum) {
+		t.Fatalf("unexpected download record: %+v", info)
+	}
+	if got := MediaFilename(info); got != "report.pdf" {
+		t.Fatalf("MediaFilename = %q", got)
+	}
+}
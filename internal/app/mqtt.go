@@ -0,0 +1,104 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// MQTTOptions configures the MQTT event sink.
+type MQTTOptions struct {
+	// Broker is the broker URL, e.g. tcp://localhost:1883 or ssl://host:8883.
+	Broker   string
+	ClientID string
+	Username string
+	Password string
+	// TopicPrefix is prepended to every topic (default "wacli").
+	TopicPrefix string
+	// Events selects the event types to publish (default message and connection).
+	Events []string
+	QoS    byte
+}
+
+func (o MQTTOptions) prefix() string {
+	if p := strings.Trim(o.TopicPrefix, "/"); p != "" {
+		return p
+	}
+	return "wacli"
+}
+
+type mqttSink struct {
+	client mqtt.Client
+	opts   MQTTOptions
+}
+
+// NewMQTTSink starts connecting to the broker. The connection is retried in
+// the background, so a broker that is down at startup does not block the
+// server. <prefix>/status is "online" while connected and "offline" (via the
+// broker's last will) otherwise.
+func NewMQTTSink(opts MQTTOptions) (EventSink, error) {
+	if strings.TrimSpace(opts.Broker) == "" {
+		return nil, fmt.Errorf("mqtt broker is required")
+	}
+	if opts.QoS > 2 {
+		return nil, fmt.Errorf("mqtt qos must be 0, 1 or 2")
+	}
+	statusTopic := opts.prefix() + "/status"
+	clientID := opts.ClientID
+	if clientID == "" {
+		host, _ := os.Hostname()
+		clientID = "wacli-" + host
+	}
+
+	co := mqtt.NewClientOptions().
+		AddBroker(opts.Broker).
+		SetClientID(clientID).
+		SetUsername(opts.Username).
+		SetPassword(opts.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(10*time.Second).
+		SetWill(statusTopic, "offline", opts.QoS, true).
+		SetOnConnectHandler(func(c mqtt.Client) {
+			c.Publish(statusTopic, opts.QoS, true, "online")
+		}).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			fmt.Fprintf(os.Stderr, "mqtt: connection lost: %v\n", err)
+		})
+	client := mqtt.NewClient(co)
+	client.Connect()
+	return &mqttSink{client: client, opts: opts}, nil
+}
+
+func (s *mqttSink) Publish(ctx context.Context, e Event, payload []byte) error {
+	topic, retained := mqttTopic(s.opts.prefix(), e)
+	s.client.Publish(topic, s.opts.QoS, retained, payload)
+	return nil
+}
+
+func (s *mqttSink) Close() error {
+	if s.client.IsConnected() {
+		s.client.Publish(s.opts.prefix()+"/status", s.opts.QoS, true, "offline").WaitTimeout(2 * time.Second)
+	}
+	s.client.Disconnect(250)
+	return nil
+}
+
+var mqttTopicEscaper = strings.NewReplacer("/", "_", "+", "_", "#", "_")
+
+// mqttTopic maps an event to its topic: <prefix>/connection (retained, so
+// dashboards see the current state) or <prefix>/<type>s/<chat JID>.
+func mqttTopic(prefix string, e Event) (string, bool) {
+	if e.Type == EventConnection {
+		return prefix + "/connection", true
+	}
+	topic := prefix + "/" + e.Type + "s"
+	if e.Chat != "" {
+		topic += "/" + mqttTopicEscaper.Replace(e.Chat)
+	}
+	return topic, false
+}
@@ -0,0 +1,167 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Admin alerts: wacli reports trouble with itself (a logged-out session, a
+// nearly full disk, a failed backup, a growing queue) to an admin chat, over
+// the same WhatsApp account its operator already watches.
+
+// Admin alert kinds. Alerts of one kind are sent at most once per
+// adminAlertInterval.
+const (
+	AlertLoggedOut      = "logged_out"
+	AlertDiskSpace      = "disk_space"
+	AlertBackupFailed   = "backup_failed"
+	AlertOutboxBacklog  = "outbox_backlog"
+	AlertWebhookBacklog = "webhook_backlog"
+	AlertInboxBacklog   = "inbox_backlog"
+	AlertWebhookFailure = "webhook_failure"
+	AlertMassLeave      = "mass_leave"
+)
+
+var (
+	adminAlertInterval = time.Hour
+	adminCheckInterval = time.Minute
+)
+
+// AdminAlerts configures the checks RunAdminAlerts performs. Zero fields
+// disable a check.
+type AdminAlerts struct {
+	// MinFreeDiskMB alerts when the store's filesystem has less space left.
+	MinFreeDiskMB int64
+	// MaxBacklog alerts when more messages wait in the outbox, more
+	// deliveries in the webhook queue, or more inbound webhooks in the inbox.
+	MaxBacklog int64
+}
+
+// AdminChat returns the chat operational alerts go to, if one is set.
+func (a *App) AdminChat() (string, bool) {
+	if a.admin.IsEmpty() {
+		return "", false
+	}
+	return a.admin.String(), true
+}
+
+// NotifyAdmin sends an operational alert to the admin chat, dropping it when
+// an alert of the same kind went out within adminAlertInterval. It does
+// nothing without an admin chat.
+func (a *App) NotifyAdmin(ctx context.Context, kind, text string) {
+	if a.admin.IsEmpty() {
+		return
+	}
+	a.adminMu.Lock()
+	if a.adminAlerted == nil {
+		a.adminAlerted = map[string]time.Time{}
+	}
+	if last, ok := a.adminAlerted[kind]; ok && time.Since(last) < adminAlertInterval {
+		a.adminMu.Unlock()
+		return
+	}
+	a.adminAlerted[kind] = time.Now()
+	a.adminMu.Unlock()
+
+	fmt.Fprintf(os.Stderr, "admin alert: %s\n", text)
+	if _, err := a.SendAdminAlert(ctx, text); err != nil {
+		fmt.Fprintf(os.Stderr, "admin alert: %v\n", err)
+	}
+}
+
+// SendAdminAlert sends text to the admin chat. Without a connection it waits
+// in the outbox instead, so a logout alert arrives once the session is paired
+// again; queued reports that.
+func (a *App) SendAdminAlert(ctx context.Context, text string) (queued bool, err error) {
+	if a.admin.IsEmpty() {
+		return false, fmt.Errorf("no admin chat configured")
+	}
+	return a.sendOrQueueText(ctx, a.admin, "[wacli] "+text)
+}
+
+// sendOrQueueText sends text right away when connected and queues it in the
+// outbox otherwise, or when sending fails; queued reports the latter.
+func (a *App) sendOrQueueText(ctx context.Context, to types.JID, text string) (queued bool, err error) {
+	if a.wa != nil && a.wa.IsConnected() {
+		if msgID, err := a.wa.SendText(ctx, to, text); err == nil {
+			a.storeSentText(ctx, to, string(msgID), text, time.Now().UTC())
+			return false, nil
+		}
+	}
+	if _, err := a.EnqueueText(to, text); err != nil {
+		return false, fmt.Errorf("queue message: %w", err)
+	}
+	return true, nil
+}
+
+// RunAdminAlerts watches for operational problems and reports them to the
+// admin chat until ctx is cancelled. It returns right away without one.
+func (a *App) RunAdminAlerts(ctx context.Context, cfg AdminAlerts) {
+	if a.admin.IsEmpty() {
+		return
+	}
+	events, unsubscribe := a.events.Subscribe(64)
+	defer unsubscribe()
+
+	ticker := time.NewTicker(adminCheckInterval)
+	defer ticker.Stop()
+	a.checkAdminAlerts(ctx, cfg)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case evt, ok := <-events:
+			if !ok {
+				return
+			}
+			a.alertOnEvent(ctx, evt)
+		case <-ticker.C:
+			a.checkAdminAlerts(ctx, cfg)
+		}
+	}
+}
+
+func (a *App) alertOnEvent(ctx context.Context, evt Event) {
+	switch evt.Type {
+	case EventConnection:
+		if evt.Data["state"] == "logged_out" {
+			a.NotifyAdmin(ctx, AlertLoggedOut, "WhatsApp logged out this session; pair it again with `wacli auth` or /api/v1/auth/qr.")
+		}
+	case EventWebhookFailure:
+		a.NotifyAdmin(ctx, fmt.Sprintf("%s:%v", AlertWebhookFailure, evt.Data["webhook_id"]),
+			fmt.Sprintf("webhook %v failed %v times in a row: %v", evt.Data["url"], evt.Data["consecutive_failures"], evt.Data["error"]))
+	}
+}
+
+func (a *App) checkAdminAlerts(ctx context.Context, cfg AdminAlerts) {
+	if cfg.MinFreeDiskMB > 0 {
+		if free, err := freeDiskMB(a.opts.StoreDir); err == nil && free < cfg.MinFreeDiskMB {
+			a.NotifyAdmin(ctx, AlertDiskSpace, fmt.Sprintf("only %d MB left on the disk of %s.", free, a.opts.StoreDir))
+		}
+	}
+	if cfg.MaxBacklog > 0 {
+		if n, err := a.db.CountOutbox(store.OutboxPending); err == nil && n > cfg.MaxBacklog {
+			a.NotifyAdmin(ctx, AlertOutboxBacklog, fmt.Sprintf("%d messages are waiting in the outbox.", n))
+		}
+		if n, err := a.db.CountWebhookBacklog(); err == nil && n > cfg.MaxBacklog {
+			a.NotifyAdmin(ctx, AlertWebhookBacklog, fmt.Sprintf("%d webhook deliveries are queued.", n))
+		}
+		if n, err := a.db.CountInbox(store.InboxPending); err == nil && n > cfg.MaxBacklog {
+			a.NotifyAdmin(ctx, AlertInboxBacklog, fmt.Sprintf("%d inbound webhooks are waiting to be processed.", n))
+		}
+	}
+}
+
+func freeDiskMB(dir string) (int64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, err
+	}
+	return int64(st.Bavail) * int64(st.Bsize) >> 20, nil
+}
@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/steipete/wacli/internal/store"
+)
+
+func newAdminTestApp(t *testing.T) (*App, *fakeWA) {
+	t.Helper()
+	a, err := New(Options{StoreDir: t.TempDir(), AdminTo: "999"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+	f := newFakeWA()
+	f.connected = true
+	a.wa = f
+	return a, f
+}
+
+func TestNotifyAdminThrottlesPerKind(t *testing.T) {
+	a, f := newAdminTestApp(t)
+	ctx := context.Background()
+
+	a.NotifyAdmin(ctx, AlertBackupFailed, "backup failed")
+	a.NotifyAdmin(ctx, AlertBackupFailed, "backup failed again")
+	a.NotifyAdmin(ctx, AlertDiskSpace, "disk full")
+	if len(f.sent) != 2 || f.sent[0] != "[wacli] backup failed" || f.sent[1] != "[wacli] disk full" {
+		t.Fatalf("sent = %q", f.sent)
+	}
+	if f.sentTo[0].String() != "999@s.whatsapp.net" {
+		t.Fatalf("sent to %s", f.sentTo[0])
+	}
+}
+
+func TestNotifyAdminQueuesWhenDisconnected(t *testing.T) {
+	a, f := newAdminTestApp(t)
+	f.connected = false
+
+	a.alertOnEvent(context.Background(), Event{Type: EventConnection, Data: map[string]any{"state": "logged_out"}})
+	if len(f.sent) != 0 {
+		t.Fatalf("sent while disconnected: %q", f.sent)
+	}
+	items, err := a.DB().ListOutbox(store.OutboxPending, 10)
+	if err != nil || len(items) != 1 || !strings.Contains(items[0].Text, "logged out") {
+		t.Fatalf("outbox = %+v (%v)", items, err)
+	}
+}
+
+func TestCheckAdminAlertsBacklog(t *testing.T) {
+	a, f := newAdminTestApp(t)
+	for i := 0; i < 3; i++ {
+		if _, err := a.DB().EnqueueOutbox("123@s.whatsapp.net", "hi"); err != nil {
+			t.Fatalf("EnqueueOutbox: %v", err)
+		}
+	}
+
+	a.checkAdminAlerts(context.Background(), AdminAlerts{MaxBacklog: 5})
+	if len(f.sent) != 0 {
+		t.Fatalf("alerted below the limit: %q", f.sent)
+	}
+	a.checkAdminAlerts(context.Background(), AdminAlerts{MaxBacklog: 2})
+	if len(f.sent) != 1 || !strings.Contains(f.sent[0], "3 messages are waiting in the outbox") {
+		t.Fatalf("sent = %q", f.sent)
+	}
+}
+
+func TestNotifyAdminWithoutAdminChat(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.connected = true
+	a.wa = f
+	a.NotifyAdmin(context.Background(), AlertBackupFailed, "backup failed")
+	if len(f.sent) != 0 {
+		t.Fatalf("sent without admin chat: %q", f.sent)
+	}
+	if _, err := a.SendAdminAlert(context.Background(), "test"); err == nil {
+		t.Fatalf("expected error without admin chat")
+	}
+}
@@ -0,0 +1,143 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+)
+
+// numberCheckBatch is how many numbers go into one lookup request.
+const numberCheckBatch = 50
+
+// DefaultNumberCheckMaxAge is how long a lookup answer is reused.
+const DefaultNumberCheckMaxAge = 7 * 24 * time.Hour
+
+// NumberCheckResult says whether a phone number can be messaged.
+type NumberCheckResult struct {
+	Input string `json:"input"`
+	// Phone is the input reduced to digits with country code.
+	Phone string `json:"phone,omitempty"`
+	// OnWhatsApp is nil when the input is not a phone number.
+	OnWhatsApp   *bool     `json:"on_whatsapp,omitempty"`
+	JID          string    `json:"jid,omitempty"`
+	BusinessName string    `json:"business_name,omitempty"`
+	Cached       bool      `json:"cached,omitempty"`
+	CheckedAt    time.Time `json:"checked_at,omitempty"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// CheckNumbers looks up which numbers have a WhatsApp account. Answers
+// younger than maxAge are served from the store, so repeated imports don't
+// hit WhatsApp, which limits how many numbers an account may look up.
+// refresh asks WhatsApp for every number. Lookups need a connection.
+func (a *App) CheckNumbers(ctx context.Context, inputs []string, maxAge time.Duration, refresh bool) ([]NumberCheckResult, error) {
+	if maxAge <= 0 {
+		maxAge = DefaultNumberCheckMaxAge
+	}
+	now := time.Now().UTC()
+	out := make([]NumberCheckResult, len(inputs))
+	var phones []string
+	seen := map[string]bool{}
+	for i, in := range inputs {
+		out[i].Input = in
+		phone, err := NormalizePhone(in)
+		if err != nil {
+			out[i].Error = err.Error()
+			continue
+		}
+		out[i].Phone = phone
+		if !seen[phone] {
+			seen[phone] = true
+			phones = append(phones, phone)
+		}
+	}
+
+	known := map[string]store.NumberCheck{}
+	if !refresh {
+		var err error
+		if known, err = a.db.GetNumberChecks(phones, now.Add(-maxAge)); err != nil {
+			return nil, err
+		}
+	}
+	var missing []string
+	for _, p := range phones {
+		if _, ok := known[p]; !ok {
+			missing = append(missing, p)
+		}
+	}
+	fresh := map[string]bool{}
+	for start := 0; start < len(missing); start += numberCheckBatch {
+		batch := missing[start:min(start+numberCheckBatch, len(missing))]
+		if a.wa == nil {
+			return nil, fmt.Errorf("not connected")
+		}
+		res, err := a.wa.IsOnWhatsApp(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		checks := make([]store.NumberCheck, 0, len(res))
+		for _, r := range res {
+			c := store.NumberCheck{Phone: strings.TrimPrefix(r.Query, "+"), OnWhatsApp: r.IsIn, CheckedAt: now}
+			if r.IsIn && !r.JID.IsEmpty() {
+				c.JID = r.JID.ToNonAD().String()
+			}
+			if r.VerifiedName != nil && r.VerifiedName.Details != nil {
+				c.BusinessName = r.VerifiedName.Details.GetVerifiedName()
+			}
+			checks = append(checks, c)
+			known[c.Phone] = c
+			fresh[c.Phone] = true
+		}
+		if err := a.db.SaveNumberChecks(checks); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range out {
+		if out[i].Phone == "" {
+			continue
+		}
+		c, ok := known[out[i].Phone]
+		if !ok {
+			out[i].Error = "WhatsApp did not answer for this number"
+			continue
+		}
+		in := c.OnWhatsApp
+		out[i].OnWhatsApp = &in
+		out[i].JID = c.JID
+		out[i].BusinessName = c.BusinessName
+		out[i].Cached = !fresh[c.Phone]
+		out[i].CheckedAt = c.CheckedAt
+	}
+	return out, nil
+}
+
+// NormalizePhone reduces a phone number as people write it, e.g.
+// "+49 (151) 234-5678" or "0049 151 2345678", to digits with country code.
+func NormalizePhone(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", fmt.Errorf("number is required")
+	}
+	var sb strings.Builder
+	for _, r := range strings.TrimPrefix(s, "+") {
+		switch {
+		case r >= '0' && r <= '9':
+			sb.WriteRune(r)
+		case r == ' ' || r == '-' || r == '.' || r == '(' || r == ')' || r == '/':
+		default:
+			return "", fmt.Errorf("not a phone number: %q", s)
+		}
+	}
+	phone := sb.String()
+	if !strings.HasPrefix(s, "+") {
+		phone = strings.TrimPrefix(phone, "00")
+	}
+	if n := len(phone); n < 8 || n > 15 {
+		return "", fmt.Errorf("%q is not a phone number with country code", s)
+	}
+	return phone, nil
+}
@@ -0,0 +1,72 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	for in, want := range map[string]string{
+		"+49 (151) 234-5678": "491512345678",
+		"0049 151 2345678":   "491512345678",
+		"5511999999999":      "5511999999999",
+		" +1 415.555.0100 ":  "14155550100",
+	} {
+		got, err := NormalizePhone(in)
+		if err != nil || got != want {
+			t.Fatalf("NormalizePhone(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "alice", "+49 151", "1234567890123456", "123@s.whatsapp.net"} {
+		if got, err := NormalizePhone(in); err == nil {
+			t.Fatalf("NormalizePhone(%q) = %q, expected an error", in, got)
+		}
+	}
+}
+
+func TestCheckNumbersCachesAnswers(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.connected = true
+	a.wa = f
+	f.notOnWA = map[string]bool{"491512345678": true}
+	ctx := context.Background()
+
+	res, err := a.CheckNumbers(ctx, []string{"+49 151 2345678", "5511999999999", "nope", "0049 151 2345678"}, 0, false)
+	if err != nil {
+		t.Fatalf("CheckNumbers: %v", err)
+	}
+	if len(res) != 4 {
+		t.Fatalf("results = %+v", res)
+	}
+	if res[0].OnWhatsApp == nil || *res[0].OnWhatsApp || res[0].Cached || res[0].JID != "" {
+		t.Fatalf("expected a fresh negative answer, got %+v", res[0])
+	}
+	if res[1].OnWhatsApp == nil || !*res[1].OnWhatsApp || res[1].JID != "5511999999999@s.whatsapp.net" {
+		t.Fatalf("expected a positive answer, got %+v", res[1])
+	}
+	if res[2].OnWhatsApp != nil || res[2].Error == "" {
+		t.Fatalf("expected an invalid number, got %+v", res[2])
+	}
+	if res[3].Phone != res[0].Phone || res[3].OnWhatsApp == nil || *res[3].OnWhatsApp {
+		t.Fatalf("expected the duplicate to share the answer, got %+v", res[3])
+	}
+
+	// The account appears, but the cached answer is served until refreshed.
+	delete(f.notOnWA, "491512345678")
+	res, err = a.CheckNumbers(ctx, []string{"+491512345678"}, time.Hour, false)
+	if err != nil {
+		t.Fatalf("CheckNumbers: %v", err)
+	}
+	if !res[0].Cached || *res[0].OnWhatsApp {
+		t.Fatalf("expected the cached answer, got %+v", res[0])
+	}
+	res, err = a.CheckNumbers(ctx, []string{"+491512345678"}, time.Hour, true)
+	if err != nil {
+		t.Fatalf("CheckNumbers: %v", err)
+	}
+	if res[0].Cached || !*res[0].OnWhatsApp {
+		t.Fatalf("expected a refreshed answer, got %+v", res[0])
+	}
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// OutboxMaxAttempts is how many times a queued message is tried before it is
+// marked as failed.
+const OutboxMaxAttempts = 10
+
+type OutboxFlushResult struct {
+	Sent   int
+	Failed int
+	Left   int
+}
+
+// EnqueueText stores a text message in the outbox to be sent once the
+// WhatsApp connection is available again.
+func (a *App) EnqueueText(to types.JID, text string) (store.OutboxItem, error) {
+	return a.db.EnqueueOutbox(to.String(), text)
+}
+
+// FlushOutbox sends all pending outbox messages. It expects the client to be
+// connected and stops at the first connectivity error, leaving the rest queued.
+// Concurrent flushes wait for each other.
+func (a *App) FlushOutbox(ctx context.Context) (OutboxFlushResult, error) {
+	var res OutboxFlushResult
+	if a.wa == nil || !a.wa.IsConnected() {
+		return res, fmt.Errorf("not connected")
+	}
+	a.outboxMu.Lock()
+	defer a.outboxMu.Unlock()
+
+	items, err := a.db.ListOutbox(store.OutboxPending, 500)
+	if err != nil {
+		return res, err
+	}
+	for i, item := range items {
+		if ctx.Err() != nil {
+			res.Left += len(items) - i
+			return res, ctx.Err()
+		}
+		to, err := types.ParseJID(item.ToJID)
+		if err != nil {
+			_ = a.db.MarkOutboxError(item.ID, "invalid recipient: "+err.Error(), true)
+			res.Failed++
+			continue
+		}
+
+		msgID, err := a.wa.SendText(ctx, to, item.Text)
+		if err != nil {
+			giveUp := item.Attempts+1 >= OutboxMaxAttempts
+			_ = a.db.MarkOutboxError(item.ID, err.Error(), giveUp)
+			if giveUp {
+				res.Failed++
+			} else {
+				res.Left++
+			}
+			if !a.wa.IsConnected() {
+				res.Left += len(items) - i - 1
+				return res, err
+			}
+			continue
+		}
+
+		now := time.Now().UTC()
+		_ = a.db.MarkOutboxSent(item.ID, string(msgID), now)
+		_ = a.db.AttachOutboxCallbacks(item.ID, string(msgID))
+		a.storeSentText(ctx, to, string(msgID), item.Text, now)
+		res.Sent++
+	}
+	return res, nil
+}
+
+// RunOutbox keeps flushing the outbox until ctx is cancelled. It flushes right
+// after every (re)connect and otherwise retries on the given interval,
+// reconnecting when there is queued work.
+func (a *App) RunOutbox(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	kick := make(chan struct{}, 1)
+	if err := a.OpenWA(); err == nil {
+		id := a.wa.AddEventHandler(func(evt interface{}) {
+			if _, ok := evt.(*events.Connected); ok {
+				select {
+				case kick <- struct{}{}:
+				default:
+				}
+			}
+		})
+		defer a.wa.RemoveEventHandler(id)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-kick:
+		case <-ticker.C:
+		}
+		a.flushOutboxOnce(ctx)
+	}
+}
+
+func (a *App) flushOutboxOnce(ctx context.Context) {
+	pending, err := a.db.CountOutbox(store.OutboxPending)
+	if err != nil || pending == 0 {
+		return
+	}
+	if err := a.EnsureAuthed(); err != nil {
+		return
+	}
+	if !a.wa.IsConnected() {
+		connCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		err := a.Connect(connCtx, false, nil)
+		cancel()
+		if err != nil {
+			return
+		}
+	}
+	_, _ = a.FlushOutbox(ctx)
+}
+
+func (a *App) storeSentText(ctx context.Context, chat types.JID, msgID, text string, ts time.Time) {
+	chatName := a.wa.ResolveChatName(ctx, chat, "")
+	_ = a.db.UpsertChat(chat.String(), chatKind(chat), chatName, ts)
+	_ = a.db.UpsertMessage(store.UpsertMessageParams{
+		ChatJID:    chat.String(),
+		ChatName:   chatName,
+		MsgID:      msgID,
+		SenderName: "me",
+		Timestamp:  ts,
+		FromMe:     true,
+		Text:       text,
+	})
+}
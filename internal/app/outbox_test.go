@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestFlushOutboxSendsPendingAndStoresMessages(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.connected = true
+	a.wa = f
+
+	to := types.JID{User: "123", Server: types.DefaultUserServer}
+	if _, err := a.EnqueueText(to, "one"); err != nil {
+		t.Fatalf("EnqueueText: %v", err)
+	}
+	if _, err := a.EnqueueText(to, "two"); err != nil {
+		t.Fatalf("EnqueueText: %v", err)
+	}
+
+	res, err := a.FlushOutbox(context.Background())
+	if err != nil {
+		t.Fatalf("FlushOutbox: %v", err)
+	}
+	if res.Sent != 2 || res.Failed != 0 || res.Left != 0 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if len(f.sent) != 2 || f.sent[0] != "one" || f.sent[1] != "two" {
+		t.Fatalf("unexpected send order: %v", f.sent)
+	}
+	if n, _ := a.db.CountOutbox(store.OutboxPending); n != 0 {
+		t.Fatalf("expected outbox drained, got %d pending", n)
+	}
+	if _, err := a.db.GetMessage(to.String(), "msgid-1"); err != nil {
+		t.Fatalf("expected sent message stored: %v", err)
+	}
+}
+
+func TestFlushOutboxKeepsItemsOnError(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.connected = true
+	f.sendErr = fmt.Errorf("server error")
+	a.wa = f
+
+	to := types.JID{User: "123", Server: types.DefaultUserServer}
+	item, err := a.EnqueueText(to, "hi")
+	if err != nil {
+		t.Fatalf("EnqueueText: %v", err)
+	}
+
+	res, err := a.FlushOutbox(context.Background())
+	if err != nil {
+		t.Fatalf("FlushOutbox: %v", err)
+	}
+	if res.Sent != 0 || res.Left != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	got, err := a.db.GetOutboxItem(item.ID)
+	if err != nil {
+		t.Fatalf("GetOutboxItem: %v", err)
+	}
+	if got.Status != store.OutboxPending || got.Attempts != 1 || got.LastError != "server error" {
+		t.Fatalf("unexpected item: %+v", got)
+	}
+
+	f.connected = false
+	if _, err := a.FlushOutbox(context.Background()); err == nil {
+		t.Fatalf("expected error when disconnected")
+	}
+}
+
+func TestFlushOutboxConcurrentFlushesSendOnce(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.connected = true
+	f.sendGate = make(chan struct{})
+	a.wa = f
+
+	to := types.JID{User: "123", Server: types.DefaultUserServer}
+	if _, err := a.EnqueueText(to, "once"); err != nil {
+		t.Fatalf("EnqueueText: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	results := make([]OutboxFlushResult, 2)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := a.FlushOutbox(context.Background())
+			if err != nil {
+				t.Errorf("FlushOutbox: %v", err)
+			}
+			results[i] = res
+		}()
+	}
+	// Let both flushes reach the send before it completes.
+	time.Sleep(50 * time.Millisecond)
+	close(f.sendGate)
+	wg.Wait()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.sent) != 1 {
+		t.Fatalf("message sent %d times", len(f.sent))
+	}
+	if results[0].Sent+results[1].Sent != 1 {
+		t.Fatalf("results = %+v", results)
+	}
+}
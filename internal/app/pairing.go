@@ -0,0 +1,38 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/steipete/wacli/internal/wa"
+)
+
+// qrRetryDelay gives an expired pairing session time to disconnect before
+// PairQR starts the next one.
+var qrRetryDelay = time.Second
+
+// PairQR links this device by QR code. onCode receives every code WhatsApp
+// issues, about one a minute at first and then every 20 seconds; when a
+// session's codes all expire, a new session starts, so codes keep coming
+// until the phone scans one or ctx ends.
+func (a *App) PairQR(ctx context.Context, onCode func(code string)) error {
+	for {
+		err := a.Connect(ctx, true, onCode)
+		if err == nil {
+			if a.wa.IsAuthed() {
+				return nil
+			}
+			// Connect found the expired session still open.
+			err = wa.ErrQRTimeout
+		}
+		if !errors.Is(err, wa.ErrQRTimeout) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(qrRetryDelay):
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPairQRRefreshesExpiredSessions(t *testing.T) {
+	old := qrRetryDelay
+	qrRetryDelay = time.Millisecond
+	t.Cleanup(func() { qrRetryDelay = old })
+
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.authed = false
+	f.qrSessions = [][]string{{"a1", "a2"}, {"b1"}, {"c1"}}
+	a.wa = f
+
+	var codes []string
+	if err := a.PairQR(context.Background(), func(code string) { codes = append(codes, code) }); err != nil {
+		t.Fatalf("PairQR: %v", err)
+	}
+	if got := fmt.Sprint(codes); got != "[a1 a2 b1 c1]" {
+		t.Fatalf("codes = %s", got)
+	}
+	if !f.IsAuthed() {
+		t.Fatalf("expected paired session")
+	}
+}
+
+func TestPairQRStopsWithContext(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.authed = false
+	f.qrSessions = [][]string{{"a1"}, {"b1"}}
+	a.wa = f
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := a.PairQR(ctx, func(string) { cancel() })
+	if err != context.Canceled {
+		t.Fatalf("PairQR = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// SchemaViolation is one reason a payload does not match its schema.
+type SchemaViolation struct {
+	// Path is the JSON pointer of the offending value ("" for the root).
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// CompilePayloadSchema parses a JSON Schema (draft 2020-12 unless $schema
+// says otherwise). Remote $refs are not followed.
+func CompilePayloadSchema(raw string) (*jsonschema.Schema, error) {
+	c := jsonschema.NewCompiler()
+	c.LoadURL = func(s string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("remote $ref %q is not allowed", s)
+	}
+	const url = "payload.json"
+	if err := c.AddResource(url, strings.NewReader(raw)); err != nil {
+		return nil, err
+	}
+	return c.Compile(url)
+}
+
+// ValidatePayload checks v (as decoded by encoding/json) against s and
+// returns every violation found, or nil if it matches.
+func ValidatePayload(s *jsonschema.Schema, v any) []SchemaViolation {
+	err := s.Validate(v)
+	if err == nil {
+		return nil
+	}
+	ve, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return []SchemaViolation{{Message: err.Error()}}
+	}
+	var out []SchemaViolation
+	var walk func(e *jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) == 0 {
+			out = append(out, SchemaViolation{Path: e.InstanceLocation, Message: e.Message})
+			return
+		}
+		for _, c := range e.Causes {
+			walk(c)
+		}
+	}
+	walk(ve)
+	return out
+}
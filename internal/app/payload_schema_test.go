@@ -0,0 +1,55 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidatePayload(t *testing.T) {
+	s, err := CompilePayloadSchema(`{
+		"type": "object",
+		"required": ["message", "data"],
+		"properties": {
+			"message": {"type": "string", "minLength": 1},
+			"data": {"type": "object", "required": ["severity"], "properties": {"severity": {"enum": ["info", "critical"]}}}
+		}
+	}`)
+	if err != nil {
+		t.Fatalf("CompilePayloadSchema: %v", err)
+	}
+
+	decode := func(raw string) any {
+		var v any
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	if v := ValidatePayload(s, decode(`{"message":"disk full","data":{"severity":"critical"}}`)); v != nil {
+		t.Fatalf("expected valid payload, got %+v", v)
+	}
+
+	violations := ValidatePayload(s, decode(`{"message":"","data":{"severity":"low"}}`))
+	if len(violations) != 2 {
+		t.Fatalf("expected 2 violations, got %+v", violations)
+	}
+	paths := map[string]bool{}
+	for _, v := range violations {
+		paths[v.Path] = true
+	}
+	if !paths["/message"] || !paths["/data/severity"] {
+		t.Fatalf("unexpected violation paths: %+v", violations)
+	}
+}
+
+func TestCompilePayloadSchemaRejectsInvalid(t *testing.T) {
+	if _, err := CompilePayloadSchema(`{"type": 5}`); err == nil {
+		t.Fatalf("expected error for invalid schema")
+	}
+	if _, err := CompilePayloadSchema(`not json`); err == nil {
+		t.Fatalf("expected error for malformed JSON")
+	}
+	if _, err := CompilePayloadSchema(`{"$ref": "https://example.com/schema.json"}`); err == nil {
+		t.Fatalf("expected remote $ref to be rejected")
+	}
+}
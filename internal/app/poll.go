@@ -0,0 +1,51 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+)
+
+// pollRecheck is how often PollMessages looks at the store while waiting.
+// Message events wake it up sooner, but they are published before the
+// message is stored and sends through the API publish none.
+var pollRecheck = time.Second
+
+// PollMessages returns messages stored after sinceCursor (see
+// store.ListMessagesSince), waiting up to timeout for at least one to arrive.
+// On timeout it returns no messages and the cursor to retry with.
+func (a *App) PollMessages(ctx context.Context, chatJID, sinceCursor string, limit int, timeout time.Duration) ([]store.Message, string, error) {
+	if sinceCursor == "" {
+		return a.db.ListMessagesSince(chatJID, "", limit)
+	}
+
+	events, unsubscribe := a.events.Subscribe(16)
+	defer unsubscribe()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	recheck := time.NewTimer(pollRecheck)
+	defer recheck.Stop()
+
+	for {
+		msgs, next, err := a.db.ListMessagesSince(chatJID, sinceCursor, limit)
+		if err != nil || len(msgs) > 0 {
+			return msgs, next, err
+		}
+		recheck.Reset(pollRecheck)
+		for waiting := true; waiting; {
+			select {
+			case <-ctx.Done():
+				return nil, next, nil
+			case <-recheck.C:
+				waiting = false
+			case e := <-events:
+				if e.Type == EventMessage {
+					// Give the sync loop a moment to store it.
+					recheck.Reset(50 * time.Millisecond)
+				}
+			}
+		}
+	}
+}
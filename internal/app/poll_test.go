@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+)
+
+func TestPollMessagesWaitsForNewMessages(t *testing.T) {
+	a := newTestApp(t)
+	chat := "123@s.whatsapp.net"
+	if err := a.db.UpsertChat(chat, "dm", "Alice", time.Now()); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	store1 := func(id string) {
+		if err := a.db.UpsertMessage(store.UpsertMessageParams{ChatJID: chat, MsgID: id, Timestamp: time.Now(), Text: id}); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+	store1("old")
+
+	ctx := context.Background()
+	msgs, cursor, err := a.PollMessages(ctx, "", "", 10, time.Second)
+	if err != nil || len(msgs) != 0 || cursor == "" {
+		t.Fatalf("initial poll = %v, %q, %v", msgs, cursor, err)
+	}
+
+	// Times out without new messages and keeps the cursor.
+	msgs, next, err := a.PollMessages(ctx, "", cursor, 10, 50*time.Millisecond)
+	if err != nil || len(msgs) != 0 || next != cursor {
+		t.Fatalf("idle poll = %v, %q, %v", msgs, next, err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		store1("new")
+		a.events.Publish(Event{Type: EventMessage, Chat: chat})
+	}()
+	start := time.Now()
+	msgs, next, err = a.PollMessages(ctx, "", cursor, 10, 5*time.Second)
+	if err != nil || len(msgs) != 1 || msgs[0].MsgID != "new" {
+		t.Fatalf("poll = %v, %v", msgs, err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("poll was not woken by the message event")
+	}
+
+	if msgs, _, _ := a.PollMessages(ctx, "", next, 10, 10*time.Millisecond); len(msgs) != 0 {
+		t.Fatalf("message delivered twice: %v", msgs)
+	}
+}
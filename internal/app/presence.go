@@ -0,0 +1,140 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// Presence watch throttling. Subscriptions are refreshed every
+// presenceResubscribeEvery (and after reconnects, at most once per
+// presenceMinResubscribe), one contact every presenceSubscribeGap.
+var (
+	presenceResubscribeEvery = 10 * time.Minute
+	presenceMinResubscribe   = time.Minute
+	presenceSubscribeGap     = 2 * time.Second
+)
+
+// RunPresenceWatch subscribes to the presence of watched contacts and records
+// their online/offline intervals until ctx is cancelled. Only contacts added
+// with AddPresenceWatch are tracked.
+func (a *App) RunPresenceWatch(ctx context.Context) {
+	evts, unsubscribe := a.events.Subscribe(256)
+	defer unsubscribe()
+
+	var running atomic.Bool
+	var last time.Time
+	resubscribe := func(force bool) {
+		if !force && time.Since(last) < presenceMinResubscribe {
+			return
+		}
+		if !running.CompareAndSwap(false, true) {
+			return
+		}
+		last = time.Now()
+		go func() {
+			defer running.Store(false)
+			a.subscribeWatchedPresence(ctx)
+		}()
+	}
+	resubscribe(true)
+
+	ticker := time.NewTicker(presenceResubscribeEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			resubscribe(true)
+		case evt, ok := <-evts:
+			if !ok {
+				return
+			}
+			switch evt.Type {
+			case EventConnection:
+				if evt.Data["state"] == "connected" {
+					resubscribe(false)
+				} else {
+					// Presence cannot be observed while offline.
+					_ = a.db.ClosePresenceIntervals(evt.Timestamp)
+				}
+			case EventPresence:
+				a.recordPresence(evt)
+			}
+		}
+	}
+}
+
+func (a *App) subscribeWatchedPresence(ctx context.Context) {
+	if a.wa == nil || !a.wa.IsConnected() {
+		return
+	}
+	watches, err := a.db.ListPresenceWatches()
+	if err != nil || len(watches) == 0 {
+		return
+	}
+	// WhatsApp only sends presence updates while we are available ourselves.
+	if err := a.wa.SendPresence(ctx, types.PresenceAvailable); err != nil {
+		fmt.Fprintf(os.Stderr, "presence: %v\n", err)
+		return
+	}
+	for i, w := range watches {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(presenceSubscribeGap):
+			}
+		}
+		jid, err := types.ParseJID(w.JID)
+		if err != nil {
+			continue
+		}
+		if err := a.wa.SubscribePresence(ctx, jid); err != nil {
+			fmt.Fprintf(os.Stderr, "presence: subscribe %s: %v\n", w.JID, err)
+		}
+	}
+}
+
+// recordPresence stores online/offline transitions of watched contacts.
+// Typing indicators (chat presence) carry no "available" flag and are skipped.
+func (a *App) recordPresence(evt Event) {
+	available, ok := evt.Data["available"].(bool)
+	if !ok {
+		return
+	}
+	if watched, err := a.db.IsPresenceWatched(evt.Chat); err != nil || !watched {
+		return
+	}
+	at := evt.Timestamp
+	if !available {
+		// Prefer the reported last-seen time when the contact shares it.
+		if ls, ok := evt.Data["last_seen"].(time.Time); ok && !ls.IsZero() && ls.Before(at) {
+			at = ls
+		}
+	}
+	_ = a.db.RecordPresence(evt.Chat, available, at)
+}
+
+// SubscribeContactPresence asks WhatsApp for presence updates of jid for the
+// rest of the current connection. Updates are stored by storePresence; use
+// AddPresenceWatch to keep the subscription across reconnects.
+func (a *App) SubscribeContactPresence(ctx context.Context, jid types.JID) error {
+	if err := a.wa.SendPresence(ctx, types.PresenceAvailable); err != nil {
+		return fmt.Errorf("set own presence: %w", err)
+	}
+	return a.wa.SubscribePresence(ctx, jid)
+}
+
+// storePresence keeps the latest online/last-seen state of any contact we
+// receive presence updates for, under the phone number JID when the LID
+// mapping is known.
+func (a *App) storePresence(p *events.Presence) error {
+	return a.db.SetPresenceState(a.phoneJID(p.From.ToNonAD()).String(), !p.Unavailable, p.LastSeen.UTC(), time.Now().UTC())
+}
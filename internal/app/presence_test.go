@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestRunPresenceWatchRecordsIntervals(t *testing.T) {
+	oldGap := presenceSubscribeGap
+	presenceSubscribeGap = time.Millisecond
+	t.Cleanup(func() { presenceSubscribeGap = oldGap })
+
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.connected = true
+	a.wa = f
+
+	watched := "5511999990000@s.whatsapp.net"
+	if err := a.db.AddPresenceWatch(watched); err != nil {
+		t.Fatalf("AddPresenceWatch: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		a.RunPresenceWatch(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitFor(t, func() bool {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return len(f.presenceSubs) == 1 && f.presenceSubs[0] == watched
+	})
+
+	start := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	a.events.Publish(Event{Type: EventPresence, Chat: watched, Timestamp: start, Data: map[string]any{"available": true}})
+	a.events.Publish(Event{Type: EventPresence, Chat: "other@s.whatsapp.net", Timestamp: start, Data: map[string]any{"available": true}})
+	a.events.Publish(Event{Type: EventPresence, Chat: watched, Timestamp: start, Data: map[string]any{"state": "composing"}})
+	a.events.Publish(Event{Type: EventPresence, Chat: watched, Timestamp: start.Add(30 * time.Minute), Data: map[string]any{
+		"available": false,
+		"last_seen": start.Add(20 * time.Minute),
+	}})
+
+	waitFor(t, func() bool {
+		ivs, _ := a.db.ListPresenceIntervals(watched, start.Add(-time.Hour), 10)
+		return len(ivs) == 1 && !ivs[0].OfflineAt.IsZero()
+	})
+	ivs, _ := a.db.ListPresenceIntervals(watched, start.Add(-time.Hour), 10)
+	if got := ivs[0].OfflineAt.Sub(ivs[0].OnlineAt); got != 20*time.Minute {
+		t.Fatalf("expected interval to end at last seen, got %v", got)
+	}
+	if ivs, _ := a.db.ListPresenceIntervals("other@s.whatsapp.net", start.Add(-time.Hour), 10); len(ivs) != 0 {
+		t.Fatalf("unwatched contact must not be recorded: %+v", ivs)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("condition not met in time")
+}
+
+func TestStorePresenceKeepsLatestState(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.connected = true
+	a.wa = f
+
+	jid := types.NewJID("5511999990000", types.DefaultUserServer)
+	if err := a.SubscribeContactPresence(context.Background(), jid); err != nil {
+		t.Fatalf("SubscribeContactPresence: %v", err)
+	}
+	if len(f.presenceSubs) != 1 || f.presenceSubs[0] != jid.String() {
+		t.Fatalf("expected subscription, got %v", f.presenceSubs)
+	}
+
+	seen := time.Now().UTC().Add(-3 * time.Minute).Truncate(time.Second)
+	if err := a.storePresence(&events.Presence{From: jid, Unavailable: true, LastSeen: seen}); err != nil {
+		t.Fatalf("storePresence: %v", err)
+	}
+	p, err := a.db.GetPresenceState(jid.String())
+	if err != nil {
+		t.Fatalf("GetPresenceState: %v", err)
+	}
+	if p.Available || !p.LastSeen.Equal(seen) {
+		t.Fatalf("unexpected state: %+v", p)
+	}
+}
+
+func TestPublishWAEventStoresPresenceUnderPhoneJID(t *testing.T) {
+	a := newTestApp(t)
+	a.wa = newFakeWA()
+
+	pn := types.NewJID("5511999990000", types.DefaultUserServer)
+	lid := types.NewJID("123456789", types.HiddenUserServer)
+	if _, err := a.db.PutLIDMapping(lid.String(), pn.String()); err != nil {
+		t.Fatalf("PutLIDMapping: %v", err)
+	}
+
+	a.publishWAEvent(&events.Presence{From: lid})
+	p, err := a.db.GetPresenceState(pn.String())
+	if err != nil {
+		t.Fatalf("GetPresenceState: %v", err)
+	}
+	if !p.Available {
+		t.Fatalf("unexpected state: %+v", p)
+	}
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// SendReaction reacts to message msgID in chat with emoji; an empty emoji
+// removes our reaction. Group messages must be in the store so the original
+// sender can be addressed. The reaction is stored like incoming ones.
+func (a *App) SendReaction(ctx context.Context, chat types.JID, msgID, emoji string) (string, error) {
+	key := &waProto.MessageKey{
+		RemoteJID: proto.String(chat.String()),
+		ID:        proto.String(msgID),
+	}
+	orig, err := a.db.GetMessage(chat.String(), msgID)
+	switch {
+	case err == nil:
+		key.FromMe = proto.Bool(orig.FromMe)
+		if chat.Server == types.GroupServer && !orig.FromMe {
+			key.Participant = proto.String(orig.SenderJID)
+		}
+	case store.IsNotFound(err) && chat.Server != types.GroupServer:
+		key.FromMe = proto.Bool(false)
+	case store.IsNotFound(err):
+		return "", fmt.Errorf("message %s not found in %s", msgID, chat)
+	default:
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	id, err := a.wa.SendProtoMessage(ctx, chat, &waProto.Message{
+		ReactionMessage: &waProto.ReactionMessage{
+			Key:               key,
+			Text:              proto.String(emoji),
+			SenderTimestampMS: proto.Int64(now.UnixMilli()),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	_ = a.storeParsedMessage(ctx, wa.ParsedMessage{
+		Chat:          chat,
+		ID:            string(id),
+		Timestamp:     now,
+		FromMe:        true,
+		ReactionToID:  msgID,
+		ReactionEmoji: emoji,
+	})
+	return string(id), nil
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestSendReactionAddressesOriginalSender(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.connected = true
+	a.wa = f
+
+	group := types.NewJID("120363000000000000", types.GroupServer)
+	if err := a.db.UpsertChat(group.String(), "group", "Ops", time.Time{}); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	if err := a.db.UpsertMessage(store.UpsertMessageParams{
+		ChatJID: group.String(), MsgID: "M1", SenderJID: "111@s.whatsapp.net",
+		Timestamp: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC), Text: "deploy done",
+	}); err != nil {
+		t.Fatalf("UpsertMessage: %v", err)
+	}
+
+	id, err := a.SendReaction(context.Background(), group, "M1", "👍")
+	if err != nil || id == "" {
+		t.Fatalf("SendReaction = %q, %v", id, err)
+	}
+	if len(f.sentProtos) != 1 {
+		t.Fatalf("sent %d messages", len(f.sentProtos))
+	}
+	r := f.sentProtos[0].GetReactionMessage()
+	if r.GetText() != "👍" || r.GetKey().GetID() != "M1" || r.GetKey().GetParticipant() != "111@s.whatsapp.net" || r.GetKey().GetFromMe() {
+		t.Fatalf("unexpected reaction: %+v", r)
+	}
+
+	stored, err := a.db.GetMessage(group.String(), id)
+	if err != nil {
+		t.Fatalf("GetMessage: %v", err)
+	}
+	if stored.DisplayText != "Reacted 👍 to deploy done" {
+		t.Fatalf("unexpected display text: %q", stored.DisplayText)
+	}
+
+	if _, err := a.SendReaction(context.Background(), group, "missing", "👍"); err == nil {
+		t.Fatalf("expected error for unknown group message")
+	}
+}
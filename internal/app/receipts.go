@@ -0,0 +1,262 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// storeReceipt records delivery, read and played receipts that recipients
+// send for our messages. Registered callbacks are notified. A read-self
+// receipt, sent when we read a chat on another device, moves the chat's read
+// watermark; other receipts from our own devices and retry/error receipts are
+// ignored.
+func (a *App) storeReceipt(r *events.Receipt) error {
+	if r.Type == types.ReceiptTypeReadSelf {
+		return a.storeReadSelf(r)
+	}
+	var kind string
+	switch r.Type {
+	case types.ReceiptTypeDelivered:
+		kind = store.ReceiptDelivered
+	case types.ReceiptTypeRead:
+		kind = store.ReceiptRead
+	case types.ReceiptTypePlayed:
+		kind = store.ReceiptPlayed
+	default:
+		return nil
+	}
+	if r.IsFromMe || len(r.MessageIDs) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(r.MessageIDs))
+	for _, id := range r.MessageIDs {
+		ids = append(ids, string(id))
+	}
+	chat, participant, at := r.Chat.String(), r.Sender.ToNonAD().String(), r.Timestamp.UTC()
+	if err := a.db.RecordReceipt(chat, participant, kind, ids, at); err != nil {
+		return err
+	}
+	a.notifyMessageCallbacks(chat, participant, kind, ids, at)
+	return nil
+}
+
+// MarkRead sends read receipts for messages in chat. Our own messages are
+// skipped. In groups each message must be in the store, since receipts are
+// addressed per sender. It returns how many messages were marked.
+func (a *App) MarkRead(ctx context.Context, chat types.JID, ids []string) (int, error) {
+	isGroup := chat.Server == types.GroupServer
+	bySender := map[string][]types.MessageID{}
+	var order []string
+	for _, id := range ids {
+		sender := ""
+		m, err := a.db.GetMessage(chat.String(), id)
+		switch {
+		case err == nil:
+			if m.FromMe {
+				continue
+			}
+			if isGroup {
+				sender = m.SenderJID
+			}
+		case store.IsNotFound(err) && !isGroup:
+		case store.IsNotFound(err):
+			return 0, fmt.Errorf("message %s not found in %s", id, chat)
+		default:
+			return 0, err
+		}
+		if _, ok := bySender[sender]; !ok {
+			order = append(order, sender)
+		}
+		bySender[sender] = append(bySender[sender], types.MessageID(id))
+	}
+
+	marked := 0
+	for _, s := range order {
+		var sender types.JID
+		if s != "" {
+			jid, err := types.ParseJID(s)
+			if err != nil {
+				return marked, fmt.Errorf("sender of %s: %w", bySender[s][0], err)
+			}
+			sender = jid
+		}
+		if err := a.wa.MarkRead(ctx, chat, sender, bySender[s]); err != nil {
+			return marked, err
+		}
+		marked += len(bySender[s])
+	}
+	return marked, nil
+}
+
+func (a *App) storeReadSelf(r *events.Receipt) error {
+	var upTo time.Time
+	for _, id := range r.MessageIDs {
+		m, err := a.db.GetMessage(r.Chat.String(), string(id))
+		if store.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if m.Timestamp.After(upTo) {
+			upTo = m.Timestamp
+		}
+	}
+	if upTo.IsZero() {
+		return nil
+	}
+	if err := a.db.MarkChatRead(r.Chat.String(), upTo); err != nil && !store.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// MarkChatRead moves the read watermark of chat to upTo, or to its latest
+// message when upTo is zero. With sendReceipts, read receipts go out for the
+// incoming messages the watermark passes; otherwise only the local unread
+// count changes. It returns the watermark and how many messages it passed.
+func (a *App) MarkChatRead(ctx context.Context, chat types.JID, upTo time.Time, sendReceipts bool) (time.Time, int, error) {
+	c, err := a.db.GetChat(chat.String())
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	if upTo.IsZero() {
+		upTo = c.LastMessageTS
+	}
+	if upTo.IsZero() {
+		upTo = time.Now().UTC()
+	}
+	if upTo.Before(c.ReadUntil) {
+		upTo = c.ReadUntil
+	}
+	unread, err := a.db.ListUnreadMessages(chat.String(), upTo)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	if sendReceipts && len(unread) > 0 {
+		ids := make([]string, 0, len(unread))
+		for _, m := range unread {
+			ids = append(ids, m.MsgID)
+		}
+		if _, err := a.MarkRead(ctx, chat, ids); err != nil {
+			return time.Time{}, 0, err
+		}
+	}
+	if err := a.db.MarkChatRead(chat.String(), upTo); err != nil {
+		return time.Time{}, 0, err
+	}
+	return upTo, len(unread), nil
+}
+
+// historyReadWatermark derives the read watermark of a history sync
+// conversation: all but its unreadCount newest incoming messages are read. It
+// reports false when the batch holds too few incoming messages to tell.
+func historyReadWatermark(incoming []time.Time, unreadCount int) (time.Time, bool) {
+	if len(incoming) <= unreadCount {
+		return time.Time{}, false
+	}
+	sorted := append([]time.Time(nil), incoming...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].After(sorted[j]) })
+	return sorted[unreadCount], true
+}
+
+// withReceiptDetail returns a copy of a delivered/read/played receipt event
+// with the per-recipient state of each message and delivered/read counts, so
+// webhook consumers can compute the reach of group messages. The receipt in
+// the event itself is counted even if the sync loop has not stored it yet.
+func (a *App) withReceiptDetail(evt Event) Event {
+	kind, _ := evt.Data["receipt"].(string)
+	ids, _ := evt.Data["ids"].([]string)
+	if (kind != store.ReceiptDelivered && kind != store.ReceiptRead && kind != store.ReceiptPlayed) || len(ids) == 0 {
+		return evt
+	}
+	sender, err := types.ParseJID(evt.Sender)
+	if err != nil {
+		return evt
+	}
+	participant, at := sender.ToNonAD().String(), evt.Timestamp
+
+	messages := make([]map[string]any, 0, len(ids))
+	for _, id := range ids {
+		rs, err := a.db.ListReceipts(evt.Chat, id)
+		if err != nil {
+			return evt
+		}
+		found := false
+		for i := range rs {
+			if rs[i].Participant == participant {
+				applyReceipt(&rs[i], kind, at)
+				found = true
+			}
+		}
+		if !found {
+			r := store.Receipt{ChatJID: evt.Chat, MsgID: id, Participant: participant}
+			applyReceipt(&r, kind, at)
+			rs = append(rs, r)
+		}
+
+		recipients := make([]map[string]any, 0, len(rs))
+		delivered, read, played := 0, 0, 0
+		for _, r := range rs {
+			entry := map[string]any{"participant": r.Participant}
+			if !r.DeliveredAt.IsZero() {
+				entry["delivered_at"] = r.DeliveredAt
+				delivered++
+			}
+			if !r.ReadAt.IsZero() {
+				entry["read_at"] = r.ReadAt
+				read++
+			}
+			if !r.PlayedAt.IsZero() {
+				entry["played_at"] = r.PlayedAt
+				played++
+			}
+			recipients = append(recipients, entry)
+		}
+		messages = append(messages, map[string]any{
+			"id":              id,
+			"delivered_count": delivered,
+			"read_count":      read,
+			"played_count":    played,
+			"recipients":      recipients,
+		})
+	}
+
+	data := make(map[string]any, len(evt.Data)+3)
+	for k, v := range evt.Data {
+		data[k] = v
+	}
+	data["messages"] = messages
+	if len(messages) == 1 {
+		data["delivered_count"] = messages[0]["delivered_count"]
+		data["read_count"] = messages[0]["read_count"]
+	}
+	evt.Data = data
+	return evt
+}
+
+// applyReceipt sets the first time of kind on r, following RecordReceipt:
+// played implies read, read implies delivered.
+func applyReceipt(r *store.Receipt, kind string, at time.Time) {
+	set := func(t *time.Time) {
+		if t.IsZero() {
+			*t = at
+		}
+	}
+	switch kind {
+	case store.ReceiptPlayed:
+		set(&r.PlayedAt)
+		fallthrough
+	case store.ReceiptRead:
+		set(&r.ReadAt)
+		fallthrough
+	case store.ReceiptDelivered:
+		set(&r.DeliveredAt)
+	}
+}
@@ -0,0 +1,174 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestStoreReceipt(t *testing.T) {
+	a := newTestApp(t)
+	chat := types.NewJID("5511999990000", types.DefaultUserServer)
+	device := chat
+	device.Device = 3
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	receipt := func(typ types.ReceiptType, fromMe bool) *events.Receipt {
+		return &events.Receipt{
+			MessageSource: types.MessageSource{Chat: chat, Sender: device, IsFromMe: fromMe},
+			MessageIDs:    []types.MessageID{"M1"},
+			Timestamp:     ts,
+			Type:          typ,
+		}
+	}
+	for _, r := range []*events.Receipt{
+		receipt(types.ReceiptTypeDelivered, false),
+		receipt(types.ReceiptTypeRead, false),
+		receipt(types.ReceiptTypeReadSelf, true),
+		receipt(types.ReceiptTypeRetry, false),
+	} {
+		if err := a.storeReceipt(r); err != nil {
+			t.Fatalf("storeReceipt(%q): %v", r.Type, err)
+		}
+	}
+
+	rs, err := a.db.ListReceipts(chat.String(), "M1")
+	if err != nil || len(rs) != 1 {
+		t.Fatalf("ListReceipts: %+v (%v)", rs, err)
+	}
+	if rs[0].Participant != chat.String() || !rs[0].ReadAt.Equal(ts) {
+		t.Fatalf("unexpected receipt: %+v", rs[0])
+	}
+}
+
+func TestMarkReadGroupsBySender(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.connected = true
+	a.wa = f
+
+	group := types.NewJID("120363000000000000", types.GroupServer)
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := a.db.UpsertChat(group.String(), "group", "Ops", time.Time{}); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	for _, m := range []store.UpsertMessageParams{
+		{MsgID: "A1", SenderJID: "111@s.whatsapp.net"},
+		{MsgID: "B1", SenderJID: "222@s.whatsapp.net"},
+		{MsgID: "A2", SenderJID: "111@s.whatsapp.net"},
+		{MsgID: "ME", FromMe: true},
+	} {
+		m.ChatJID, m.Timestamp, m.Text = group.String(), ts, "hi"
+		if err := a.db.UpsertMessage(m); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+
+	n, err := a.MarkRead(context.Background(), group, []string{"A1", "B1", "A2", "ME"})
+	if err != nil || n != 3 {
+		t.Fatalf("MarkRead = %d, %v", n, err)
+	}
+	want := []string{"111@s.whatsapp.net/A1", "111@s.whatsapp.net/A2", "222@s.whatsapp.net/B1"}
+	if len(f.reads) != len(want) {
+		t.Fatalf("reads = %v", f.reads)
+	}
+	for i := range want {
+		if f.reads[i] != want[i] {
+			t.Fatalf("reads = %v, want %v", f.reads, want)
+		}
+	}
+
+	if _, err := a.MarkRead(context.Background(), group, []string{"unknown"}); err == nil {
+		t.Fatalf("expected error for unknown group message")
+	}
+}
+
+func TestMarkChatReadMovesWatermark(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.connected = true
+	a.wa = f
+
+	chat := types.NewJID("5511999990000", types.DefaultUserServer)
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := a.db.UpsertChat(chat.String(), "dm", "Alice", ts.Add(3*time.Minute)); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	for i, id := range []string{"M1", "M2", "M3", "M4"} {
+		m := store.UpsertMessageParams{ChatJID: chat.String(), MsgID: id, SenderJID: chat.String(), Timestamp: ts.Add(time.Duration(i) * time.Minute), Text: "hi"}
+		if err := a.db.UpsertMessage(m); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+
+	// Reading M2 on another device marks M1 and M2 read without receipts.
+	self := &events.Receipt{
+		MessageSource: types.MessageSource{Chat: chat, Sender: chat, IsFromMe: true},
+		MessageIDs:    []types.MessageID{"M2"},
+		Type:          types.ReceiptTypeReadSelf,
+	}
+	if err := a.storeReceipt(self); err != nil {
+		t.Fatalf("storeReceipt: %v", err)
+	}
+	if c, _ := a.db.GetChat(chat.String()); c.UnreadCount != 2 {
+		t.Fatalf("unread after read-self = %d", c.UnreadCount)
+	}
+
+	readUntil, n, err := a.MarkChatRead(context.Background(), chat, time.Time{}, true)
+	if err != nil || n != 2 || !readUntil.Equal(ts.Add(3*time.Minute)) {
+		t.Fatalf("MarkChatRead = %v, %d, %v", readUntil, n, err)
+	}
+	if len(f.reads) != 2 {
+		t.Fatalf("reads = %v", f.reads)
+	}
+	if c, _ := a.db.GetChat(chat.String()); c.UnreadCount != 0 {
+		t.Fatalf("unread after MarkChatRead = %d", c.UnreadCount)
+	}
+}
+
+func TestHistoryReadWatermark(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	incoming := []time.Time{ts, ts.Add(2 * time.Minute), ts.Add(time.Minute)}
+	if got, ok := historyReadWatermark(incoming, 1); !ok || !got.Equal(ts.Add(time.Minute)) {
+		t.Fatalf("unread 1: %v, %v", got, ok)
+	}
+	if got, ok := historyReadWatermark(incoming, 0); !ok || !got.Equal(ts.Add(2*time.Minute)) {
+		t.Fatalf("unread 0: %v, %v", got, ok)
+	}
+	if _, ok := historyReadWatermark(incoming, 3); ok {
+		t.Fatalf("expected no watermark when every message is unread")
+	}
+}
+
+func TestWithReceiptDetailCountsGroupRecipients(t *testing.T) {
+	a := newTestApp(t)
+	group := "120363000000000000@g.us"
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := a.db.RecordReceipt(group, "111@s.whatsapp.net", store.ReceiptRead, []string{"M1"}, ts); err != nil {
+		t.Fatalf("RecordReceipt: %v", err)
+	}
+
+	// The event's own receipt is not stored yet and comes from a device JID.
+	evt := a.withReceiptDetail(Event{
+		Type: EventReceipt, Chat: group, Sender: "222:4@s.whatsapp.net", Timestamp: ts.Add(time.Minute),
+		Data: map[string]any{"ids": []string{"M1"}, "receipt": "delivered"},
+	})
+	if evt.Data["delivered_count"] != 2 || evt.Data["read_count"] != 1 {
+		t.Fatalf("unexpected counts: %+v", evt.Data)
+	}
+	msgs := evt.Data["messages"].([]map[string]any)
+	recipients := msgs[0]["recipients"].([]map[string]any)
+	if len(recipients) != 2 || recipients[1]["participant"] != "222@s.whatsapp.net" || recipients[1]["read_at"] != nil {
+		t.Fatalf("unexpected recipients: %+v", recipients)
+	}
+
+	// Other receipt types pass through unchanged.
+	self := Event{Type: EventReceipt, Chat: group, Sender: "me@s.whatsapp.net", Data: map[string]any{"ids": []string{"M1"}, "receipt": "read-self"}}
+	if out := a.withReceiptDetail(self); out.Data["messages"] != nil {
+		t.Fatalf("read-self receipt should not be expanded: %+v", out.Data)
+	}
+}
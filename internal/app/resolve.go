@@ -0,0 +1,208 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Resolution explains how a `to` value is turned into a recipient by the
+// send and webhook endpoints, without sending anything.
+type Resolution struct {
+	Input string        `json:"input"`
+	Steps []ResolveStep `json:"steps"`
+	// JID is the parsed recipient, empty when the input does not parse.
+	JID string `json:"jid,omitempty"`
+	// Kind is "user", "lid", "group", "broadcast" or "newsletter".
+	Kind string `json:"kind,omitempty"`
+	// DeliverTo differs from JID when a sandbox recipient is configured.
+	DeliverTo string `json:"deliver_to,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Alias     string `json:"alias,omitempty"`
+	// OnWhatsApp is nil when the check was skipped.
+	OnWhatsApp *bool    `json:"on_whatsapp,omitempty"`
+	Warnings   []string `json:"warnings,omitempty"`
+	// OK is false when sending to the input would fail.
+	OK bool `json:"ok"`
+}
+
+// ResolveStep is one stage of the resolution and what it produced.
+type ResolveStep struct {
+	Step   string `json:"step"`
+	Result string `json:"result"`
+}
+
+func (r *Resolution) step(name, format string, args ...interface{}) {
+	r.Steps = append(r.Steps, ResolveStep{Step: name, Result: fmt.Sprintf(format, args...)})
+}
+
+func (r *Resolution) warn(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// ResolveRecipient walks input through the same parsing the send path uses
+// and reports what it finds on the way. With check set and a connection,
+// it also asks WhatsApp whether the number has an account or the group
+// exists.
+func (a *App) ResolveRecipient(ctx context.Context, input string, check bool) Resolution {
+	r := Resolution{Input: input}
+
+	trimmed := strings.TrimSpace(input)
+	if trimmed != input {
+		r.step("normalize", "trimmed surrounding whitespace: %q", trimmed)
+	} else {
+		r.step("normalize", "unchanged")
+	}
+	if trimmed == "" {
+		r.step("parse", "failed: recipient is required")
+		return r
+	}
+
+	jid, err := wa.ParseUserOrJID(trimmed)
+	if err != nil {
+		r.step("parse", "failed: %v", err)
+		a.suggestAlias(&r, trimmed)
+		return r
+	}
+	if strings.Contains(trimmed, "@") {
+		r.step("parse", "parsed as a JID: %s", jid)
+	} else {
+		r.step("parse", "no @, read as a phone number: %s", jid)
+	}
+	if strings.HasSuffix(trimmed, "@"+types.HiddenUserServer) && jid.Server != types.HiddenUserServer {
+		r.step("lid", "the LID belongs to %s", jid)
+	}
+	r.JID = jid.String()
+	r.Kind = recipientKind(jid)
+	r.OK = true
+
+	if jid.Server == types.DefaultUserServer {
+		if !isDigits(jid.User) {
+			r.OK = false
+			r.warn("%q is not all digits; WhatsApp only accepts the number with country code, e.g. 5511999999999", jid.User)
+			a.suggestAlias(&r, trimmed)
+		} else if n := len(jid.User); n < 8 || n > 15 {
+			r.warn("%d digits is an unusual length for a number with country code", n)
+		}
+	}
+	if r.Kind == "" {
+		r.OK = false
+		r.warn("unknown server %q", jid.Server)
+	}
+
+	if c, err := a.db.GetContact(r.JID); err == nil {
+		r.Name, r.Alias = c.Name, c.Alias
+		r.step("contact", "known contact %q", firstNonEmpty(c.Alias, c.Name, r.JID))
+	} else {
+		r.step("contact", "not in the contact store")
+	}
+	if ps, err := a.db.GetPayloadSchema(r.JID); err == nil {
+		r.step("payload_schema", "webhook payloads are validated against the schema set %s", ps.UpdatedAt.Format("2006-01-02"))
+	}
+
+	r.DeliverTo = r.JID
+	if sb, ok := a.Sandbox(); ok && sb != jid.ToNonAD() {
+		r.DeliverTo = sb.String()
+		r.step("sandbox", "redirected to the sandbox recipient %s", sb)
+	}
+
+	if check && r.OK {
+		a.checkRecipient(ctx, &r, jid)
+	}
+	return r
+}
+
+func (a *App) checkRecipient(ctx context.Context, r *Resolution, jid types.JID) {
+	if a.wa == nil || !a.wa.IsConnected() {
+		r.step("on_whatsapp", "skipped: not connected")
+		return
+	}
+	switch jid.Server {
+	case types.DefaultUserServer:
+		res, err := a.wa.IsOnWhatsApp(ctx, []string{jid.User})
+		if err != nil || len(res) == 0 {
+			r.step("on_whatsapp", "check failed: %v", err)
+			return
+		}
+		in := res[0].IsIn
+		r.OnWhatsApp = &in
+		if !in {
+			r.OK = false
+			r.step("on_whatsapp", "no WhatsApp account for +%s", jid.User)
+			return
+		}
+		detail := "has a WhatsApp account"
+		if res[0].VerifiedName != nil && res[0].VerifiedName.Details != nil {
+			detail += fmt.Sprintf(" (business %q)", res[0].VerifiedName.Details.GetVerifiedName())
+		}
+		if canon := res[0].JID; !canon.IsEmpty() && canon.ToNonAD() != jid.ToNonAD() {
+			detail += "; WhatsApp knows it as " + canon.String()
+		}
+		r.step("on_whatsapp", "%s", detail)
+	case types.GroupServer:
+		info, err := a.wa.GetGroupInfo(ctx, jid)
+		in := err == nil && info != nil
+		r.OnWhatsApp = &in
+		if !in {
+			r.OK = false
+			if err != nil {
+				r.step("on_whatsapp", "group not found or not a member: %v", err)
+			} else {
+				r.step("on_whatsapp", "group not found or not a member")
+			}
+			return
+		}
+		if r.Name == "" {
+			r.Name = info.Name
+		}
+		r.step("on_whatsapp", "member of %q (%d participants)", info.Name, len(info.Participants))
+	default:
+		r.step("on_whatsapp", "skipped: not checked for %s recipients", r.Kind)
+	}
+}
+
+// suggestAlias points out contacts whose alias or name is the input, since
+// a common mistake is passing a name where a number is expected.
+func (a *App) suggestAlias(r *Resolution, input string) {
+	matches, err := a.db.SearchContacts(input, 10)
+	if err != nil {
+		return
+	}
+	for _, c := range matches {
+		if strings.EqualFold(c.Alias, input) || strings.EqualFold(c.Name, input) {
+			r.step("alias", "recipients are not looked up by name; %q is %s", input, c.JID)
+			return
+		}
+	}
+}
+
+func recipientKind(jid types.JID) string {
+	switch jid.Server {
+	case types.DefaultUserServer:
+		return "user"
+	case types.HiddenUserServer:
+		return "lid"
+	case types.GroupServer:
+		return "group"
+	case types.BroadcastServer:
+		return "broadcast"
+	case types.NewsletterServer:
+		return "newsletter"
+	}
+	return ""
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestResolveRecipient(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.connected = true
+	a.wa = f
+	ctx := context.Background()
+
+	ann := "5511999990000@s.whatsapp.net"
+	if err := a.db.UpsertContact(ann, "5511999990000", "annie", "Ann Smith", "", ""); err != nil {
+		t.Fatalf("UpsertContact: %v", err)
+	}
+	if err := a.db.SetAlias(ann, "ops-lead"); err != nil {
+		t.Fatalf("SetAlias: %v", err)
+	}
+
+	r := a.ResolveRecipient(ctx, " 5511999990000 ", true)
+	if !r.OK || r.JID != ann || r.Kind != "user" || r.DeliverTo != ann || r.Alias != "ops-lead" || r.OnWhatsApp == nil || !*r.OnWhatsApp {
+		t.Fatalf("resolution = %+v", r)
+	}
+	if r.Steps[0].Step != "normalize" || !strings.Contains(r.Steps[0].Result, "trimmed") {
+		t.Fatalf("steps = %+v", r.Steps)
+	}
+
+	r = a.ResolveRecipient(ctx, "ops-lead", false)
+	if r.OK || !hasStep(r, "alias", ann) {
+		t.Fatalf("expected a name to be rejected with a hint, got %+v", r)
+	}
+
+	r = a.ResolveRecipient(ctx, "+55 11 99999-0000", false)
+	if r.OK || len(r.Warnings) == 0 {
+		t.Fatalf("expected formatting to be flagged, got %+v", r)
+	}
+
+	f.notOnWA = map[string]bool{"5511888880000": true}
+	r = a.ResolveRecipient(ctx, "5511888880000", true)
+	if r.OK || r.OnWhatsApp == nil || *r.OnWhatsApp {
+		t.Fatalf("expected no account, got %+v", r)
+	}
+
+	group := types.NewJID("120363000000000001", types.GroupServer)
+	f.groups[group] = &types.GroupInfo{JID: group, GroupName: types.GroupName{Name: "Ops"}}
+	r = a.ResolveRecipient(ctx, group.String(), true)
+	if !r.OK || r.Kind != "group" || r.Name != "Ops" {
+		t.Fatalf("group resolution = %+v", r)
+	}
+	r = a.ResolveRecipient(ctx, "404@g.us", true)
+	if r.OK {
+		t.Fatalf("expected an unknown group to fail, got %+v", r)
+	}
+
+	a.sandbox = types.NewJID("5511000000000", types.DefaultUserServer)
+	r = a.ResolveRecipient(ctx, ann, false)
+	if r.DeliverTo != a.sandbox.String() || !hasStep(r, "sandbox", a.sandbox.String()) {
+		t.Fatalf("expected the sandbox redirect, got %+v", r)
+	}
+}
+
+func hasStep(r Resolution, step, contains string) bool {
+	for _, s := range r.Steps {
+		if s.Step == step && strings.Contains(s.Result, contains) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+)
+
+// PruneMessages deletes messages older than their chat's retention, with
+// their downloaded media: the chat's own retention if it has one, def
+// otherwise. A def of zero keeps chats without their own retention. Chats
+// on legal hold or never pruned are skipped. It returns how many messages
+// were deleted.
+func (a *App) PruneMessages(def time.Duration, now time.Time) (int, error) {
+	targets, err := a.db.ListPruneTargets()
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, t := range targets {
+		retention := def
+		if t.RetentionDays > 0 {
+			retention = time.Duration(t.RetentionDays) * 24 * time.Hour
+		}
+		if retention <= 0 {
+			continue
+		}
+		n, _, err := a.DeleteMessagesBefore(t.ChatJID, now.Add(-retention))
+		deleted += n
+		// A hold placed since the chats were listed wins.
+		if err != nil && !errors.Is(err, store.ErrLegalHold) {
+			return deleted, fmt.Errorf("prune %s: %w", t.ChatJID, err)
+		}
+	}
+	return deleted, nil
+}
+
+// RunMessagePrune prunes messages hourly until ctx is cancelled. It runs
+// even without a default retention, for chats with their own.
+func (a *App) RunMessagePrune(ctx context.Context, def time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		if n, err := a.PruneMessages(def, time.Now().UTC()); err != nil {
+			fmt.Fprintf(os.Stderr, "retention: prune: %v\n", err)
+		} else if n > 0 {
+			fmt.Fprintf(os.Stderr, "retention: pruned %d messages\n", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+)
+
+func TestPruneMessagesHonorsChatRetention(t *testing.T) {
+	a := newTestApp(t)
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	plain, held, never, short := "1@s.whatsapp.net", "2@s.whatsapp.net", "3@s.whatsapp.net", "4@s.whatsapp.net"
+	for _, chat := range []string{plain, held, never, short} {
+		if err := a.db.UpsertChat(chat, "dm", "", now); err != nil {
+			t.Fatalf("UpsertChat: %v", err)
+		}
+		for id, age := range map[string]time.Duration{"old": 100 * 24 * time.Hour, "week": 8 * 24 * time.Hour, "new": time.Hour} {
+			if err := a.db.UpsertMessage(store.UpsertMessageParams{ChatJID: chat, MsgID: id, SenderJID: chat, Timestamp: now.Add(-age), Text: id}); err != nil {
+				t.Fatalf("UpsertMessage: %v", err)
+			}
+		}
+	}
+	for _, r := range []store.ChatRetention{
+		{ChatJID: held, LegalHold: true, RetentionDays: 1},
+		{ChatJID: never, NeverPrune: true},
+		{ChatJID: short, RetentionDays: 7},
+	} {
+		if _, err := a.db.SetChatRetention(r); err != nil {
+			t.Fatalf("SetChatRetention: %v", err)
+		}
+	}
+
+	n, err := a.PruneMessages(30*24*time.Hour, now)
+	if err != nil {
+		t.Fatalf("PruneMessages: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 messages pruned, got %d", n)
+	}
+	want := map[string][]string{
+		plain: {"new", "week"},
+		held:  {"new", "old", "week"},
+		never: {"new", "old", "week"},
+		short: {"new"},
+	}
+	for chat, ids := range want {
+		for _, id := range []string{"old", "week", "new"} {
+			_, err := a.db.GetMessage(chat, id)
+			kept := err == nil
+			if kept != slices.Contains(ids, id) {
+				t.Fatalf("%s/%s kept = %v, want %v (%v)", chat, id, kept, !kept, err)
+			}
+		}
+	}
+
+	if _, _, err := a.DeleteMessagesBefore(held, now); !errors.Is(err, store.ErrLegalHold) {
+		t.Fatalf("expected ErrLegalHold deleting held messages, got %v", err)
+	}
+	if _, _, err := a.DeleteMessagesBefore(never, now); err != nil {
+		t.Fatalf("never-pruned chat should still be deletable on request: %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"github.com/steipete/wacli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// SenderRole returns the role of a sender: owner for messages sent from
+// this account, the role assigned to the sender's JID otherwise, and user
+// by default. Roles are assigned to phone number JIDs.
+func (a *App) SenderRole(sender string, fromMe bool) string {
+	if fromMe {
+		return store.RoleOwner
+	}
+	jid, err := types.ParseJID(sender)
+	if err != nil || jid.IsEmpty() {
+		return store.RoleUser
+	}
+	if r, err := a.db.GetSenderRole(jid.ToNonAD().String()); err == nil {
+		return r.Role
+	}
+	return store.RoleUser
+}
+
+// HasRole reports whether a sender has at least role.
+func (a *App) HasRole(sender string, fromMe bool, role string) bool {
+	return store.RoleRank(a.SenderRole(sender, fromMe)) >= store.RoleRank(role)
+}
+
+// eventRole is the sender role of a message or call event, as annotated
+// on message events by publishWAEvent or looked up.
+func (a *App) eventRole(e Event) string {
+	if role, ok := e.Data["sender_role"].(string); ok && role != "" {
+		return role
+	}
+	fromMe, _ := e.Data["from_me"].(bool)
+	return a.SenderRole(e.Sender, fromMe)
+}
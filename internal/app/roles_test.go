@@ -0,0 +1,53 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/steipete/wacli/internal/store"
+)
+
+func TestRulesEnforceMinRole(t *testing.T) {
+	a := newTestApp(t)
+	admin, stranger := "5511000000001@s.whatsapp.net", "5511000000002@s.whatsapp.net"
+	if _, err := a.db.SetSenderRole(admin, store.RoleAdmin, "ops"); err != nil {
+		t.Fatalf("SetSenderRole: %v", err)
+	}
+	if got := a.SenderRole(admin, false); got != store.RoleAdmin {
+		t.Fatalf("SenderRole(admin) = %q", got)
+	}
+	if got := a.SenderRole("5511000000001:3@s.whatsapp.net", false); got != store.RoleAdmin {
+		t.Fatalf("expected the device JID to share the role, got %q", got)
+	}
+	if got := a.SenderRole(stranger, false); got != store.RoleUser {
+		t.Fatalf("SenderRole(stranger) = %q", got)
+	}
+	if !a.HasRole(stranger, true, store.RoleOwner) {
+		t.Fatalf("own messages should have the owner role")
+	}
+
+	if _, err := a.db.CreateRule(store.CreateRuleParams{Keywords: []string{"/kick"}, Action: store.RuleTag, Arg: "command", MinRole: store.RoleAdmin}); err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+	tagged := func(e Event) bool {
+		a.applyRules(e)
+		tags, _ := e.Data["tags"].([]string)
+		return len(tags) == 1
+	}
+	userMsg := messageEvent(stranger, "/kick 5511000000003", nil)
+	userMsg.Sender = stranger
+	if tagged(userMsg) {
+		t.Fatalf("expected a user's command to be ignored")
+	}
+	adminMsg := messageEvent(stranger, "/kick 5511000000003", nil)
+	adminMsg.Sender = admin
+	if !tagged(adminMsg) {
+		t.Fatalf("expected an admin's command to match")
+	}
+	if !tagged(messageEvent(stranger, "/kick 5511000000003", map[string]any{"from_me": true})) {
+		t.Fatalf("expected the owner's command to match")
+	}
+	// An annotated role is trusted over a lookup.
+	if !tagged(messageEvent(stranger, "/kick 5511000000003", map[string]any{"sender_role": store.RoleOwner})) {
+		t.Fatalf("expected the annotated role to be used")
+	}
+}
@@ -0,0 +1,171 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// ruleReplyCooldown limits auto-replies to one per rule and chat, so two bots
+// cannot reply to each other forever.
+var ruleReplyCooldown = 10 * time.Minute
+
+// applyRules runs the enabled routing rules against an incoming message event
+// (or incoming call, which matches media type "call") in priority order. It
+// returns false when a rule drops the event; tags are added to e.Data["tags"].
+// Rules with a minimum role skip senders below it.
+func (a *App) applyRules(e Event) bool {
+	if e.Type != EventMessage && !(e.Type == EventCall && e.Data["state"] == wa.CallStateOffer) {
+		return true
+	}
+	rules, err := a.db.ListRules(true)
+	if err != nil || len(rules) == 0 {
+		return true
+	}
+	role := ""
+	for _, r := range rules {
+		if !ruleMatches(r, e) {
+			continue
+		}
+		if r.MinRole != "" {
+			if role == "" {
+				role = a.eventRole(e)
+			}
+			if store.RoleRank(role) < store.RoleRank(r.MinRole) {
+				continue
+			}
+		}
+		switch r.Action {
+		case store.RuleDrop:
+			return false
+		case store.RuleTag:
+			tags, _ := e.Data["tags"].([]string)
+			e.Data["tags"] = append(tags, r.Arg)
+		case store.RuleReply:
+			a.ruleReply(r, e)
+		case store.RuleWebhook:
+			a.ruleForward(r, e)
+		}
+	}
+	return true
+}
+
+func ruleMatches(r store.Rule, e Event) bool {
+	if _, dup := e.Data["duplicate_of"]; r.DuplicateMedia && !dup {
+		return false
+	}
+	if len(r.Chats) > 0 && !jidListMatch(r.Chats, e.Chat) {
+		return false
+	}
+	if len(r.Senders) > 0 && !jidListMatch(r.Senders, e.Sender) {
+		return false
+	}
+	if len(r.MediaTypes) > 0 {
+		media, _ := e.Data["media_type"].(string)
+		switch {
+		case e.Type == EventCall:
+			media = "call"
+		case media == "":
+			media = "text"
+		}
+		if !containsFold(r.MediaTypes, media) {
+			return false
+		}
+	}
+	if len(r.Keywords) > 0 {
+		text, _ := e.Data["text"].(string)
+		caption, _ := e.Data["caption"].(string)
+		haystack := strings.ToLower(text + "\n" + caption)
+		found := false
+		for _, k := range r.Keywords {
+			if strings.Contains(haystack, strings.ToLower(k)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// jidListMatch matches full JIDs or bare user parts ("1234567890").
+func jidListMatch(list []string, jid string) bool {
+	user := jid
+	if i := strings.IndexByte(user, '@'); i >= 0 {
+		user = user[:i]
+	}
+	for _, entry := range list {
+		entry = strings.TrimPrefix(entry, "+")
+		if entry == jid || (!strings.Contains(entry, "@") && entry == user) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, it := range list {
+		if strings.EqualFold(it, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *App) ruleReply(r store.Rule, e Event) {
+	if fromMe, _ := e.Data["from_me"].(bool); fromMe || a.wa == nil {
+		return
+	}
+	chat, err := types.ParseJID(e.Chat)
+	if err != nil {
+		return
+	}
+
+	key := fmt.Sprintf("%d|%s", r.ID, e.Chat)
+	a.ruleMu.Lock()
+	if a.ruleReplies == nil {
+		a.ruleReplies = map[string]time.Time{}
+	}
+	if last, ok := a.ruleReplies[key]; ok && time.Since(last) < ruleReplyCooldown {
+		a.ruleMu.Unlock()
+		return
+	}
+	a.ruleReplies[key] = time.Now()
+	a.ruleMu.Unlock()
+
+	// Event handlers must not block the WhatsApp event loop.
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		msgID, err := a.wa.SendText(ctx, chat, r.Arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "rules: reply for rule %d: %v\n", r.ID, err)
+			return
+		}
+		a.storeSentText(ctx, chat, string(msgID), r.Arg, time.Now().UTC())
+	}()
+}
+
+func (a *App) ruleForward(r store.Rule, e Event) {
+	id, err := strconv.ParseInt(r.Arg, 10, 64)
+	if err != nil {
+		return
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	if _, err := a.db.EnqueueWebhookDelivery(id, e.Type, body); err != nil {
+		fmt.Fprintf(os.Stderr, "rules: forward for rule %d: %v\n", r.ID, err)
+	}
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+)
+
+func messageEvent(chat, text string, data map[string]any) Event {
+	d := map[string]any{"text": text, "from_me": false}
+	for k, v := range data {
+		d[k] = v
+	}
+	return Event{Type: EventMessage, Chat: chat, Sender: chat, Data: d}
+}
+
+func TestRuleMatches(t *testing.T) {
+	r := store.Rule{
+		Chats:      []string{"5511999990000"},
+		Keywords:   []string{"Invoice"},
+		MediaTypes: []string{"text", "document"},
+	}
+	chat := "5511999990000@s.whatsapp.net"
+	if !ruleMatches(r, messageEvent(chat, "your INVOICE is attached", nil)) {
+		t.Fatalf("expected match")
+	}
+	if !ruleMatches(r, messageEvent(chat, "", map[string]any{"media_type": "document", "caption": "invoice.pdf"})) {
+		t.Fatalf("expected caption match")
+	}
+	if ruleMatches(r, messageEvent(chat, "invoice", map[string]any{"media_type": "image"})) {
+		t.Fatalf("media type should not match")
+	}
+	if ruleMatches(r, messageEvent("other@s.whatsapp.net", "invoice", nil)) {
+		t.Fatalf("chat should not match")
+	}
+	if ruleMatches(r, messageEvent(chat, "hello", nil)) {
+		t.Fatalf("keyword should not match")
+	}
+}
+
+func TestApplyRulesActions(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	a.wa = f
+
+	hook, err := a.db.CreateWebhook(store.CreateWebhookParams{URL: "https://example.com/hook"})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	for _, p := range []store.CreateRuleParams{
+		{Keywords: []string{"spam"}, Action: store.RuleDrop},
+		{Keywords: []string{"boleto"}, Action: store.RuleTag, Arg: "billing", Priority: 1},
+		{Keywords: []string{"boleto"}, Action: store.RuleWebhook, Priority: 2},
+		{Keywords: []string{"horário"}, Action: store.RuleReply, Arg: "Atendemos das 9h às 18h."},
+	} {
+		if p.Action == store.RuleWebhook {
+			p.Arg = strconv.FormatInt(hook.ID, 10)
+		}
+		if _, err := a.db.CreateRule(p); err != nil {
+			t.Fatalf("CreateRule: %v", err)
+		}
+	}
+
+	chat := "5511999990000@s.whatsapp.net"
+	if a.applyRules(messageEvent(chat, "buy spam now", nil)) {
+		t.Fatalf("expected spam to be dropped")
+	}
+
+	e := messageEvent(chat, "segue o boleto", nil)
+	if !a.applyRules(e) {
+		t.Fatalf("expected event to be kept")
+	}
+	if tags, _ := e.Data["tags"].([]string); len(tags) != 1 || tags[0] != "billing" {
+		t.Fatalf("expected billing tag, got %v", e.Data["tags"])
+	}
+	due, _ := a.db.DueWebhookDeliveries(time.Now().Add(time.Hour), 10)
+	if len(due) != 1 || due[0].WebhookID != hook.ID {
+		t.Fatalf("expected forwarded delivery, got %+v", due)
+	}
+
+	a.applyRules(messageEvent(chat, "qual o horário?", nil))
+	a.applyRules(messageEvent(chat, "e o horário de sábado?", nil)) // within cooldown
+	a.applyRules(messageEvent(chat, "horário", map[string]any{"from_me": true}))
+	waitFor(t, func() bool {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		return len(f.sent) == 1
+	})
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.sent) != 1 || f.sent[0] != "Atendemos das 9h às 18h." {
+		t.Fatalf("expected a single auto-reply, got %v", f.sent)
+	}
+}
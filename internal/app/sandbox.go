@@ -0,0 +1,185 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/steipete/wacli/internal/wa"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// errSandboxed is returned by calls the sandbox skips that would otherwise
+// return a result.
+var errSandboxed = errors.New("not sent to WhatsApp in sandbox mode")
+
+// sandboxWA redirects every outgoing message to one test recipient, so a
+// staging setup can run the full pipeline without messaging real contacts.
+// The message notes the original recipient; messages that cannot carry the
+// note (audio, stickers, locations, ...) are preceded by a text note instead.
+// Everything else, including what gets stored, still uses the original chat.
+//
+// Other calls that contacts would notice (receipts, typing, own presence and
+// profile, group changes) are skipped and logged, unless they only concern
+// the test recipient. Calls that only read or change this account's own
+// state go through.
+type sandboxWA struct {
+	WAClient
+	to types.JID
+}
+
+func newSandboxWA(cli WAClient, to types.JID) *sandboxWA {
+	return &sandboxWA{WAClient: cli, to: to}
+}
+
+func (s *sandboxWA) SendText(ctx context.Context, to types.JID, text string) (types.MessageID, error) {
+	if to.ToNonAD() == s.to {
+		return s.WAClient.SendText(ctx, to, text)
+	}
+	return s.WAClient.SendText(ctx, s.to, sandboxNote(to)+"\n"+text)
+}
+
+func (s *sandboxWA) SendProtoMessage(ctx context.Context, to types.JID, msg *waProto.Message) (types.MessageID, error) {
+	if to.ToNonAD() == s.to || msg == nil {
+		return s.WAClient.SendProtoMessage(ctx, to, msg)
+	}
+	msg = proto.Clone(msg).(*waProto.Message)
+	note := sandboxNote(to)
+	switch {
+	case msg.Conversation != nil:
+		msg.Conversation = proto.String(note + "\n" + msg.GetConversation())
+	case msg.ExtendedTextMessage != nil:
+		msg.ExtendedTextMessage.Text = proto.String(note + "\n" + msg.ExtendedTextMessage.GetText())
+	case msg.ImageMessage != nil:
+		msg.ImageMessage.Caption = proto.String(noteCaption(note, msg.ImageMessage.GetCaption()))
+	case msg.VideoMessage != nil:
+		msg.VideoMessage.Caption = proto.String(noteCaption(note, msg.VideoMessage.GetCaption()))
+	case msg.DocumentMessage != nil:
+		msg.DocumentMessage.Caption = proto.String(noteCaption(note, msg.DocumentMessage.GetCaption()))
+	default:
+		if _, err := s.WAClient.SendText(ctx, s.to, note); err != nil {
+			return "", err
+		}
+	}
+	return s.WAClient.SendProtoMessage(ctx, s.to, msg)
+}
+
+// skip logs a call the sandbox does not pass on.
+func (s *sandboxWA) skip(call, target string) {
+	fmt.Fprintf(os.Stderr, "sandbox: skipped %s for %s\n", call, target)
+}
+
+func (s *sandboxWA) MarkRead(ctx context.Context, chat, sender types.JID, ids []types.MessageID) error {
+	if chat.ToNonAD() == s.to {
+		return s.WAClient.MarkRead(ctx, chat, sender, ids)
+	}
+	s.skip("read receipt", chat.String())
+	return nil
+}
+
+func (s *sandboxWA) SendChatPresence(ctx context.Context, chat types.JID, state types.ChatPresence, media types.ChatPresenceMedia) error {
+	if chat.ToNonAD() == s.to {
+		return s.WAClient.SendChatPresence(ctx, chat, state, media)
+	}
+	s.skip("chat presence", chat.String())
+	return nil
+}
+
+func (s *sandboxWA) SetDisappearingTimer(ctx context.Context, chat types.JID, timer time.Duration) error {
+	if chat.ToNonAD() == s.to {
+		return s.WAClient.SetDisappearingTimer(ctx, chat, timer)
+	}
+	s.skip("disappearing timer", chat.String())
+	return nil
+}
+
+// SendPresence is skipped, so presence watching gets no updates in sandbox
+// mode.
+func (s *sandboxWA) SendPresence(ctx context.Context, state types.Presence) error {
+	s.skip("presence "+string(state), "this account")
+	return nil
+}
+
+func (s *sandboxWA) SetAbout(ctx context.Context, text string) error {
+	s.skip("about", "this account")
+	return nil
+}
+
+func (s *sandboxWA) SetGroupName(ctx context.Context, jid types.JID, name string) error {
+	s.skip("group name", jid.String())
+	return nil
+}
+
+func (s *sandboxWA) SetGroupDescription(ctx context.Context, jid types.JID, description string) error {
+	s.skip("group description", jid.String())
+	return nil
+}
+
+func (s *sandboxWA) SetGroupPhoto(ctx context.Context, jid types.JID, jpeg []byte) (string, error) {
+	s.skip("group photo", jid.String())
+	return "", errSandboxed
+}
+
+func (s *sandboxWA) SetGroupAnnounce(ctx context.Context, jid types.JID, announce bool) error {
+	s.skip("group announce setting", jid.String())
+	return nil
+}
+
+func (s *sandboxWA) SetGroupLocked(ctx context.Context, jid types.JID, locked bool) error {
+	s.skip("group locked setting", jid.String())
+	return nil
+}
+
+func (s *sandboxWA) UpdateGroupParticipants(ctx context.Context, group types.JID, users []types.JID, action wa.GroupParticipantAction) ([]types.GroupParticipant, error) {
+	s.skip("participant "+string(action), group.String())
+	return nil, errSandboxed
+}
+
+// GetGroupInviteLink only reads the link unless reset revokes the old one.
+func (s *sandboxWA) GetGroupInviteLink(ctx context.Context, group types.JID, reset bool) (string, error) {
+	if !reset {
+		return s.WAClient.GetGroupInviteLink(ctx, group, false)
+	}
+	s.skip("invite link reset", group.String())
+	return "", errSandboxed
+}
+
+func (s *sandboxWA) JoinGroupWithLink(ctx context.Context, code string) (types.JID, error) {
+	s.skip("group join", "this account")
+	return types.JID{}, errSandboxed
+}
+
+func (s *sandboxWA) LeaveGroup(ctx context.Context, group types.JID) error {
+	s.skip("group leave", group.String())
+	return nil
+}
+
+func (s *sandboxWA) CreateCommunity(ctx context.Context, name string) (*types.GroupInfo, error) {
+	s.skip("community creation", "this account")
+	return nil, errSandboxed
+}
+
+func (s *sandboxWA) LinkGroup(ctx context.Context, community, group types.JID) error {
+	s.skip("group link to "+community.String(), group.String())
+	return nil
+}
+
+func (s *sandboxWA) UnlinkGroup(ctx context.Context, community, group types.JID) error {
+	s.skip("group unlink from "+community.String(), group.String())
+	return nil
+}
+
+func sandboxNote(to types.JID) string {
+	return fmt.Sprintf("[sandbox: to %s]", to.String())
+}
+
+func noteCaption(note, caption string) string {
+	if caption == "" {
+		return note
+	}
+	return note + "\n" + caption
+}
@@ -0,0 +1,132 @@
+package app
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSandboxRedirectsSends(t *testing.T) {
+	f := newFakeWA()
+	sandbox := types.NewJID("999", types.DefaultUserServer)
+	customer := types.NewJID("123", types.DefaultUserServer)
+	s := newSandboxWA(f, sandbox)
+	ctx := context.Background()
+
+	if _, err := s.SendText(ctx, customer, "hello"); err != nil {
+		t.Fatalf("SendText: %v", err)
+	}
+	if f.sentTo[0] != sandbox || f.sent[0] != "[sandbox: to 123@s.whatsapp.net]\nhello" {
+		t.Fatalf("text sent to %s as %q", f.sentTo[0], f.sent[0])
+	}
+
+	img := &waProto.Message{ImageMessage: &waProto.ImageMessage{Caption: proto.String("chart")}}
+	if _, err := s.SendProtoMessage(ctx, customer, img); err != nil {
+		t.Fatalf("SendProtoMessage: %v", err)
+	}
+	if got := f.sentProtos[0].GetImageMessage().GetCaption(); got != "[sandbox: to 123@s.whatsapp.net]\nchart" {
+		t.Fatalf("caption = %q", got)
+	}
+	if img.GetImageMessage().GetCaption() != "chart" {
+		t.Fatalf("caller's message was modified")
+	}
+
+	// Audio has no caption, so a text note goes first.
+	if _, err := s.SendProtoMessage(ctx, customer, &waProto.Message{AudioMessage: &waProto.AudioMessage{}}); err != nil {
+		t.Fatalf("SendProtoMessage: %v", err)
+	}
+	if len(f.sent) != 2 || f.sent[1] != "[sandbox: to 123@s.whatsapp.net]" {
+		t.Fatalf("expected a note before audio, got %q", f.sent)
+	}
+	for i, to := range f.sentTo {
+		if to != sandbox {
+			t.Fatalf("send %d went to %s", i, to)
+		}
+	}
+
+	// Messages to the sandbox number itself are left alone.
+	if _, err := s.SendText(ctx, sandbox, "direct"); err != nil {
+		t.Fatalf("SendText: %v", err)
+	}
+	if f.sent[2] != "direct" {
+		t.Fatalf("sandbox message = %q", f.sent[2])
+	}
+}
+
+// sandboxPassThrough lists the WAClient methods the sandbox passes on
+// unchanged: reads, the session and this account's own state.
+var sandboxPassThrough = map[string]bool{
+	"Close": true, "IsAuthed": true, "IsConnected": true, "OwnJID": true, "Connect": true,
+	"AddEventHandler": true, "RemoveEventHandler": true, "ReconnectWithBackoff": true, "SetAutoReconnect": true,
+	"ResolveChatName": true, "GetContact": true, "GetAllContacts": true, "IsOnWhatsApp": true,
+	"GetBusinessProfile": true, "GetChatSettings": true,
+	"GetJoinedGroups": true, "GetGroupInfo": true, "GetGroupInfoFromLink": true, "GetSubGroups": true,
+	"SubscribePresence": true, "GetAbout": true, "GetProfilePicture": true,
+	"SetChatArchived": true, "SetChatPinned": true, "SetChatMuted": true,
+	"Upload": true, "DownloadMediaToFile": true, "DecryptReaction": true,
+	"RequestHistorySyncOnDemand": true, "PairPhone": true, "Logout": true,
+}
+
+// sandboxRedirected lists the sends sandboxWA redirects to the test
+// recipient; TestSandboxRedirectsSends covers them.
+var sandboxRedirected = map[string]bool{"SendText": true, "SendProtoMessage": true}
+
+// TestSandboxCoversWAClient makes every WAClient method either a listed
+// pass-through or one sandboxWA handles itself, so new methods with side
+// effects cannot slip past the sandbox. Handled methods are called on a
+// sandbox without a client behind it, which panics if they reach it.
+func TestSandboxCoversWAClient(t *testing.T) {
+	s := newSandboxWA(nil, types.NewJID("999", types.DefaultUserServer))
+	sv := reflect.ValueOf(s)
+	ctxType := reflect.TypeOf((*context.Context)(nil)).Elem()
+	iface := reflect.TypeOf((*WAClient)(nil)).Elem()
+	for i := 0; i < iface.NumMethod(); i++ {
+		name := iface.Method(i).Name
+		if sandboxPassThrough[name] || sandboxRedirected[name] {
+			continue
+		}
+		m := sv.MethodByName(name)
+		args := make([]reflect.Value, m.Type().NumIn())
+		for j := range args {
+			switch in := m.Type().In(j); {
+			case in == ctxType:
+				args[j] = reflect.ValueOf(context.Background())
+			case in.Kind() == reflect.Bool:
+				// GetGroupInviteLink only has a side effect when resetting.
+				args[j] = reflect.ValueOf(true)
+			default:
+				args[j] = reflect.Zero(in)
+			}
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("%s reaches the client in sandbox mode; handle it in sandboxWA or list it in sandboxPassThrough", name)
+				}
+			}()
+			m.Call(args)
+		}()
+	}
+}
+
+func TestSandboxSkipsReceiptsOutsideTheSandbox(t *testing.T) {
+	f := newFakeWA()
+	sandbox := types.NewJID("999", types.DefaultUserServer)
+	s := newSandboxWA(f, sandbox)
+	ctx := context.Background()
+
+	customer := types.NewJID("123", types.DefaultUserServer)
+	if err := s.MarkRead(ctx, customer, customer, []types.MessageID{"A"}); err != nil {
+		t.Fatalf("MarkRead: %v", err)
+	}
+	if err := s.MarkRead(ctx, sandbox, sandbox, []types.MessageID{"B"}); err != nil {
+		t.Fatalf("MarkRead: %v", err)
+	}
+	if len(f.reads) != 1 || f.reads[0] != sandbox.String()+"/B" {
+		t.Fatalf("expected only the sandbox chat to be marked read, got %v", f.reads)
+	}
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/steipete/wacli/internal/ai"
+	"github.com/steipete/wacli/internal/config"
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+)
+
+// sentimentText returns the text worth scoring for pm, or "" when the message
+// should be skipped (own messages, reactions, media without caption).
+func sentimentText(pm wa.ParsedMessage) string {
+	if pm.FromMe || pm.ReactionToID != "" || pm.ID == "" {
+		return ""
+	}
+	text := strings.TrimSpace(pm.Text)
+	if text == "" && pm.Media != nil {
+		text = strings.TrimSpace(pm.Media.Caption)
+	}
+	return text
+}
+
+// tagSentiment scores an inbound message and stores the result. Failures are
+// logged and otherwise ignored; sentiment is best-effort metadata.
+func (a *App) tagSentiment(ctx context.Context, cfg *config.Config, pm wa.ParsedMessage, text string) {
+	ctx, cancel := context.WithTimeout(ctx, 45*time.Second)
+	defer cancel()
+
+	text = cfg.AI.Privacy.Redact(text, pm.PushName)
+	s, err := ai.ScoreSentiment(ctx, text, cfg.AI.GroqAPIKey, cfg.AI.ChatModel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "\nsentiment: %v\n", err)
+		return
+	}
+	_ = a.db.UpsertMessageSentiment(store.MessageSentiment{
+		ChatJID: pm.Chat.String(),
+		MsgID:   pm.ID,
+		Label:   s.Label,
+		Score:   s.Score,
+		Urgency: s.Urgency,
+	})
+}
@@ -0,0 +1,479 @@
+package app
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+)
+
+// A session export moves a paired device to another host without pairing
+// again. It is a tar of a manifest, session.db (the device keys) and
+// optionally wacli.db, encrypted with AES-256-GCM under a key derived from a
+// passphrase. The tar is sealed in chunks so a large store never has to fit
+// in memory; each chunk's nonce carries its index and whether it is the
+// last, so reordered, dropped or truncated chunks fail to decrypt.
+//
+// Imports are staged in <store>/import and swapped in by the next App start,
+// as neither database can be replaced while it is open.
+
+const (
+	exportMagic     = "WACLI-SESSION"
+	exportVersion   = 1
+	exportChunkSize = 64 << 10
+	// maxExportIterations bounds the key derivation cost an archive can ask
+	// for.
+	maxExportIterations = 10_000_000
+
+	stagedImportDir = "import"
+	manifestName    = "manifest.json"
+	sessionDBName   = "session.db"
+	storeDBName     = "wacli.db"
+)
+
+// MinPassphraseLen is the shortest passphrase an export accepts.
+const MinPassphraseLen = 8
+
+// exportIterations is the PBKDF2-SHA256 work factor for new exports.
+var exportIterations = 600_000
+
+// ErrBadPassphrase is returned when an archive does not decrypt.
+var ErrBadPassphrase = errors.New("wrong passphrase or corrupted archive")
+
+// ErrSessionPaired is returned when an import would replace a paired
+// session without force.
+var ErrSessionPaired = errors.New("a session is already paired; import with force to replace it")
+
+// SessionManifest describes a session export.
+type SessionManifest struct {
+	Version       int       `json:"version"`
+	JID           string    `json:"jid"`
+	CreatedAt     time.Time `json:"created_at"`
+	IncludesStore bool      `json:"includes_store"`
+	WacliVersion  string    `json:"wacli_version,omitempty"`
+}
+
+// ExportSession writes the paired session, and the message store with
+// includeStore, to w as an encrypted archive. Both databases are
+// snapshotted, so the session may stay connected.
+func (a *App) ExportSession(ctx context.Context, w io.Writer, passphrase string, includeStore bool) (SessionManifest, error) {
+	if len(passphrase) < MinPassphraseLen {
+		return SessionManifest{}, fmt.Errorf("passphrase must be at least %d characters", MinPassphraseLen)
+	}
+	dir, err := os.MkdirTemp(a.opts.StoreDir, "export-")
+	if err != nil {
+		return SessionManifest{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	sessionPath := filepath.Join(dir, sessionDBName)
+	if err := store.BackupFile(ctx, a.activeSessionPath(), sessionPath); err != nil {
+		if os.IsNotExist(err) {
+			return SessionManifest{}, fmt.Errorf("no session to export; run `wacli auth` first")
+		}
+		return SessionManifest{}, fmt.Errorf("snapshot session: %w", err)
+	}
+	m := SessionManifest{
+		Version:       exportVersion,
+		CreatedAt:     time.Now().UTC(),
+		IncludesStore: includeStore,
+		WacliVersion:  a.opts.Version,
+	}
+	if a.wa != nil {
+		if jid := a.wa.OwnJID(); !jid.IsEmpty() {
+			m.JID = jid.ToNonAD().String()
+		}
+	}
+	if m.JID == "" {
+		slot, err := inspectSession(sessionPath)
+		if err != nil {
+			return SessionManifest{}, err
+		}
+		m.JID = slot.JID
+	}
+	if m.JID == "" {
+		return SessionManifest{}, fmt.Errorf("session is not paired; nothing to export")
+	}
+	files := []string{sessionPath}
+	if includeStore {
+		storePath := filepath.Join(dir, storeDBName)
+		if err := a.db.Backup(ctx, storePath); err != nil {
+			return SessionManifest{}, fmt.Errorf("snapshot store: %w", err)
+		}
+		files = append(files, storePath)
+	}
+
+	ew, err := newExportWriter(w, passphrase)
+	if err != nil {
+		return SessionManifest{}, err
+	}
+	tw := tar.NewWriter(ew)
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return SessionManifest{}, err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: manifestName, Mode: 0600, Size: int64(len(raw)), ModTime: m.CreatedAt}); err != nil {
+		return SessionManifest{}, err
+	}
+	if _, err := tw.Write(raw); err != nil {
+		return SessionManifest{}, err
+	}
+	for _, path := range files {
+		if err := ctx.Err(); err != nil {
+			return SessionManifest{}, err
+		}
+		if err := addTarFile(tw, path); err != nil {
+			return SessionManifest{}, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return SessionManifest{}, err
+	}
+	return m, ew.Close()
+}
+
+func addTarFile(tw *tar.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: filepath.Base(path), Mode: 0600, Size: st.Size(), ModTime: st.ModTime()}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// ImportSession decrypts an archive written by ExportSession and stages it
+// for the next start, see ApplyStagedImport. Replacing a paired session
+// needs force; the replaced databases are kept as *.retired-<timestamp>.
+func (a *App) ImportSession(ctx context.Context, r io.Reader, passphrase string, force bool) (SessionManifest, error) {
+	if !force {
+		paired, err := a.sessionPaired()
+		if err != nil {
+			return SessionManifest{}, err
+		}
+		if paired {
+			return SessionManifest{}, ErrSessionPaired
+		}
+	}
+	tmp, err := os.MkdirTemp(a.opts.StoreDir, "import-")
+	if err != nil {
+		return SessionManifest{}, err
+	}
+	defer os.RemoveAll(tmp)
+
+	m, err := readExport(ctx, r, passphrase, tmp)
+	if err != nil {
+		return SessionManifest{}, err
+	}
+	staged := filepath.Join(a.opts.StoreDir, stagedImportDir)
+	if err := os.RemoveAll(staged); err != nil {
+		return SessionManifest{}, err
+	}
+	if err := os.Rename(tmp, staged); err != nil {
+		return SessionManifest{}, err
+	}
+	return m, nil
+}
+
+func (a *App) sessionPaired() (bool, error) {
+	if a.wa != nil {
+		return a.wa.IsAuthed(), nil
+	}
+	slot, err := inspectSession(a.activeSessionPath())
+	return slot.JID != "", err
+}
+
+// readExport decrypts an archive into dir and checks it is complete.
+func readExport(ctx context.Context, r io.Reader, passphrase, dir string) (SessionManifest, error) {
+	er, err := newExportReader(r, passphrase)
+	if err != nil {
+		return SessionManifest{}, err
+	}
+	var m SessionManifest
+	seen := map[string]bool{}
+	tr := tar.NewReader(er)
+	for {
+		if err := ctx.Err(); err != nil {
+			return SessionManifest{}, err
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return SessionManifest{}, err
+		}
+		if seen[hdr.Name] {
+			return SessionManifest{}, fmt.Errorf("archive has %s twice", hdr.Name)
+		}
+		seen[hdr.Name] = true
+		switch hdr.Name {
+		case manifestName:
+			if err := json.NewDecoder(io.LimitReader(tr, 64<<10)).Decode(&m); err != nil {
+				return SessionManifest{}, fmt.Errorf("decode manifest: %w", err)
+			}
+		case sessionDBName, storeDBName:
+			if err := writeFile(filepath.Join(dir, hdr.Name), tr); err != nil {
+				return SessionManifest{}, err
+			}
+		default:
+			return SessionManifest{}, fmt.Errorf("unexpected file %q in archive", hdr.Name)
+		}
+	}
+	// Read past the tar trailer so the final chunk is authenticated too.
+	if _, err := io.Copy(io.Discard, er); err != nil {
+		return SessionManifest{}, err
+	}
+
+	switch {
+	case !seen[manifestName]:
+		return SessionManifest{}, fmt.Errorf("archive has no manifest")
+	case m.Version != exportVersion:
+		return SessionManifest{}, fmt.Errorf("unsupported archive version %d", m.Version)
+	case !seen[sessionDBName]:
+		return SessionManifest{}, fmt.Errorf("archive has no session")
+	case m.IncludesStore != seen[storeDBName]:
+		return SessionManifest{}, fmt.Errorf("archive does not match its manifest")
+	}
+	// The manifest goes in last: it marks the staged import as complete.
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return SessionManifest{}, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, manifestName), raw, 0600); err != nil {
+		return SessionManifest{}, err
+	}
+	return m, nil
+}
+
+func writeFile(path string, r io.Reader) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ApplyStagedImport swaps a staged import into storeDir, keeping the
+// databases it replaces as *.retired-<timestamp>. New calls it before
+// opening the store; it reports whether there was anything to apply.
+func ApplyStagedImport(storeDir string) (bool, error) {
+	staged := filepath.Join(storeDir, stagedImportDir)
+	if _, err := os.Stat(filepath.Join(staged, manifestName)); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	now := time.Now().UTC()
+	for _, name := range []string{sessionDBName, storeDBName} {
+		from := filepath.Join(staged, name)
+		if _, err := os.Stat(from); os.IsNotExist(err) {
+			// Not in the archive, or moved by an earlier, interrupted run.
+			continue
+		}
+		if _, err := swapSessionFiles(filepath.Join(storeDir, name), from, now); err != nil {
+			return false, fmt.Errorf("apply imported %s: %w", name, err)
+		}
+	}
+	return true, os.RemoveAll(staged)
+}
+
+// The archive header is the magic, a version byte, the PBKDF2 iterations,
+// the salt and the nonce prefix. A chunk's nonce is the prefix, its index
+// and a final-chunk flag.
+const (
+	exportSaltLen   = 16
+	exportPrefixLen = 7
+)
+
+func exportAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(prefix []byte, index uint32, last bool) []byte {
+	nonce := make([]byte, 0, exportPrefixLen+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, index)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+type exportWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	prefix []byte
+	index  uint32
+	buf    []byte
+	out    []byte
+}
+
+func newExportWriter(w io.Writer, passphrase string) (*exportWriter, error) {
+	salt := make([]byte, exportSaltLen)
+	prefix := make([]byte, exportPrefixLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	aead, err := exportAEAD(passphrase, salt, exportIterations)
+	if err != nil {
+		return nil, err
+	}
+	hdr := append([]byte(exportMagic), exportVersion)
+	hdr = binary.BigEndian.AppendUint32(hdr, uint32(exportIterations))
+	hdr = append(hdr, salt...)
+	hdr = append(hdr, prefix...)
+	if _, err := w.Write(hdr); err != nil {
+		return nil, err
+	}
+	return &exportWriter{w: w, aead: aead, prefix: prefix, buf: make([]byte, 0, exportChunkSize)}, nil
+}
+
+// Write seals a chunk only once more data follows it, so Close always has
+// the final chunk left to seal.
+func (e *exportWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if len(e.buf) == exportChunkSize {
+			if err := e.seal(false); err != nil {
+				return n - len(p), err
+			}
+		}
+		k := copy(e.buf[len(e.buf):exportChunkSize], p)
+		e.buf = e.buf[:len(e.buf)+k]
+		p = p[k:]
+	}
+	return n, nil
+}
+
+func (e *exportWriter) Close() error {
+	return e.seal(true)
+}
+
+func (e *exportWriter) seal(last bool) error {
+	e.out = e.aead.Seal(e.out[:0], chunkNonce(e.prefix, e.index, last), e.buf, nil)
+	e.index++
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(e.out)
+	return err
+}
+
+type exportReader struct {
+	r      *bufio.Reader
+	aead   cipher.AEAD
+	prefix []byte
+	index  uint32
+	buf    []byte
+	out    []byte
+	plain  []byte
+	done   bool
+}
+
+func newExportReader(r io.Reader, passphrase string) (*exportReader, error) {
+	hdr := make([]byte, len(exportMagic)+1+4+exportSaltLen+exportPrefixLen)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return nil, fmt.Errorf("not a wacli session export")
+	}
+	if string(hdr[:len(exportMagic)]) != exportMagic {
+		return nil, fmt.Errorf("not a wacli session export")
+	}
+	hdr = hdr[len(exportMagic):]
+	if hdr[0] != exportVersion {
+		return nil, fmt.Errorf("unsupported archive version %d", hdr[0])
+	}
+	iterations := binary.BigEndian.Uint32(hdr[1:5])
+	if iterations == 0 || iterations > maxExportIterations {
+		return nil, fmt.Errorf("invalid key derivation cost %d", iterations)
+	}
+	salt, prefix := hdr[5:5+exportSaltLen], hdr[5+exportSaltLen:]
+	aead, err := exportAEAD(passphrase, salt, int(iterations))
+	if err != nil {
+		return nil, err
+	}
+	return &exportReader{
+		r:      bufio.NewReader(r),
+		aead:   aead,
+		prefix: prefix,
+		buf:    make([]byte, exportChunkSize+aead.Overhead()),
+	}, nil
+}
+
+func (e *exportReader) Read(p []byte) (int, error) {
+	for len(e.plain) == 0 {
+		if e.done {
+			return 0, io.EOF
+		}
+		if err := e.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, e.plain)
+	e.plain = e.plain[n:]
+	return n, nil
+}
+
+func (e *exportReader) open() error {
+	n, err := io.ReadFull(e.r, e.buf)
+	last := false
+	switch {
+	case err == io.EOF:
+		// The previous chunk was not sealed as the last one.
+		return ErrBadPassphrase
+	case err == io.ErrUnexpectedEOF:
+		last = true
+	case err != nil:
+		return err
+	default:
+		if _, err := e.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+	plain, err := e.aead.Open(e.out[:0], chunkNonce(e.prefix, e.index, last), e.buf[:n], nil)
+	if err != nil {
+		return ErrBadPassphrase
+	}
+	e.out = plain
+	e.plain = plain
+	e.index++
+	e.done = last
+	return nil
+}
@@ -0,0 +1,183 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// writeTestSession stands in for whatsmeow's session.db; only its being a
+// SQLite database matters to the export.
+func writeTestSession(t *testing.T, dir, device string) {
+	t.Helper()
+	db, err := store.Open(filepath.Join(dir, "session.db"))
+	if err != nil {
+		t.Fatalf("open session: %v", err)
+	}
+	defer db.Close()
+	if err := db.SetSetting("device", device); err != nil {
+		t.Fatalf("SetSetting: %v", err)
+	}
+}
+
+func readTestSession(t *testing.T, path string) string {
+	t.Helper()
+	db, err := store.Open(path)
+	if err != nil {
+		t.Fatalf("open session: %v", err)
+	}
+	defer db.Close()
+	v, err := db.GetSetting("device")
+	if err != nil {
+		t.Fatalf("GetSetting: %v", err)
+	}
+	return v
+}
+
+func TestExportImportSession(t *testing.T) {
+	old := exportIterations
+	exportIterations = 1000
+	t.Cleanup(func() { exportIterations = old })
+	ctx := context.Background()
+
+	src := newTestApp(t)
+	f := newFakeWA()
+	f.own = types.NewADJID("5511999990000", 0, 3)
+	src.wa = f
+	writeTestSession(t, src.StoreDir(), "old host")
+	chat := "123@s.whatsapp.net"
+	if err := src.db.UpsertChat(chat, "dm", "Alice", time.Now()); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+
+	if _, err := src.ExportSession(ctx, &bytes.Buffer{}, "short", true); err == nil {
+		t.Fatalf("expected error for a short passphrase")
+	}
+	var buf bytes.Buffer
+	m, err := src.ExportSession(ctx, &buf, "correct horse", true)
+	if err != nil {
+		t.Fatalf("ExportSession: %v", err)
+	}
+	if m.JID != "5511999990000@s.whatsapp.net" || !m.IncludesStore {
+		t.Fatalf("manifest = %+v", m)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("old host")) {
+		t.Fatalf("archive is not encrypted")
+	}
+	archive := buf.Bytes()
+
+	dst := newTestApp(t)
+	if _, err := dst.ImportSession(ctx, bytes.NewReader(archive), "wrong horse", false); !errors.Is(err, ErrBadPassphrase) {
+		t.Fatalf("wrong passphrase: err = %v", err)
+	}
+	if _, err := dst.ImportSession(ctx, bytes.NewReader(archive[:len(archive)-1]), "correct horse", false); err == nil {
+		t.Fatalf("expected error for a truncated archive")
+	}
+	if _, err := os.Stat(filepath.Join(dst.StoreDir(), stagedImportDir)); !os.IsNotExist(err) {
+		t.Fatalf("failed imports must not stage anything")
+	}
+	got, err := dst.ImportSession(ctx, bytes.NewReader(archive), "correct horse", false)
+	if err != nil {
+		t.Fatalf("ImportSession: %v", err)
+	}
+	if got.JID != m.JID {
+		t.Fatalf("imported JID = %q, want %q", got.JID, m.JID)
+	}
+
+	dst.Close()
+	applied, err := ApplyStagedImport(dst.StoreDir())
+	if err != nil || !applied {
+		t.Fatalf("ApplyStagedImport = %v, %v", applied, err)
+	}
+	if v := readTestSession(t, filepath.Join(dst.StoreDir(), "session.db")); v != "old host" {
+		t.Fatalf("imported session = %q", v)
+	}
+	db, err := store.Open(filepath.Join(dst.StoreDir(), "wacli.db"))
+	if err != nil {
+		t.Fatalf("open imported store: %v", err)
+	}
+	defer db.Close()
+	if c, err := db.GetChat(chat); err != nil || c.Name != "Alice" {
+		t.Fatalf("imported chat = %+v, %v", c, err)
+	}
+	retired, _ := filepath.Glob(filepath.Join(dst.StoreDir(), "wacli.db.retired-*"))
+	if len(retired) == 0 {
+		t.Fatalf("replaced store should be kept")
+	}
+	if applied, err := ApplyStagedImport(dst.StoreDir()); err != nil || applied {
+		t.Fatalf("second ApplyStagedImport = %v, %v", applied, err)
+	}
+}
+
+func TestImportSessionRefusesPairedSession(t *testing.T) {
+	old := exportIterations
+	exportIterations = 1000
+	t.Cleanup(func() { exportIterations = old })
+	ctx := context.Background()
+
+	src := newTestApp(t)
+	f := newFakeWA()
+	f.own = types.NewJID("5511999990000", types.DefaultUserServer)
+	src.wa = f
+	writeTestSession(t, src.StoreDir(), "old host")
+	var buf bytes.Buffer
+	if _, err := src.ExportSession(ctx, &buf, "correct horse", false); err != nil {
+		t.Fatalf("ExportSession: %v", err)
+	}
+
+	dst := newTestApp(t)
+	dst.wa = newFakeWA()
+	_, err := dst.ImportSession(ctx, bytes.NewReader(buf.Bytes()), "correct horse", false)
+	if !errors.Is(err, ErrSessionPaired) {
+		t.Fatalf("err = %v, want ErrSessionPaired", err)
+	}
+	m, err := dst.ImportSession(ctx, bytes.NewReader(buf.Bytes()), "correct horse", true)
+	if err != nil {
+		t.Fatalf("forced ImportSession: %v", err)
+	}
+	if m.IncludesStore {
+		t.Fatalf("manifest = %+v, want session only", m)
+	}
+	if _, err := os.Stat(filepath.Join(dst.StoreDir(), stagedImportDir, "wacli.db")); !os.IsNotExist(err) {
+		t.Fatalf("session-only import must not stage a store")
+	}
+}
+
+func TestExportWriterChunkBoundaries(t *testing.T) {
+	old := exportIterations
+	exportIterations = 1000
+	t.Cleanup(func() { exportIterations = old })
+
+	for _, size := range []int{0, 1, exportChunkSize, 2*exportChunkSize + 7} {
+		data := bytes.Repeat([]byte{'x'}, size)
+		var buf bytes.Buffer
+		w, err := newExportWriter(&buf, "correct horse")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		r, err := newExportReader(bytes.NewReader(buf.Bytes()), "correct horse")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if _, err := out.ReadFrom(r); err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(out.Bytes(), data) {
+			t.Fatalf("size %d: round trip lost data", size)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+const sessionSettingKey = "session"
+
+// sessionRecord is what the session health report remembers across
+// restarts.
+type sessionRecord struct {
+	JID          string    `json:"jid,omitempty"`
+	ConnectedAt  time.Time `json:"connected_at,omitempty"`
+	LoggedOutAt  time.Time `json:"logged_out_at,omitempty"`
+	LogoutReason string    `json:"logout_reason,omitempty"`
+}
+
+// SessionHealth reports whether the WhatsApp session still works and when
+// it last did.
+type SessionHealth struct {
+	// JID is the paired device, or the last one after a logout.
+	JID           string `json:"jid"`
+	Authenticated bool   `json:"authenticated"`
+	Connected     bool   `json:"connected"`
+	// LastConnectedAt is the last successful login to WhatsApp.
+	LastConnectedAt *time.Time `json:"last_connected_at"`
+	// LastMessageAt is the latest incoming message in the archive.
+	LastMessageAt           *time.Time `json:"last_message_at"`
+	SinceLastMessageSeconds *int64     `json:"since_last_message_seconds"`
+	// LoggedOut is set when WhatsApp ended the session remotely, e.g. the
+	// device was removed on the phone.
+	LoggedOut    bool       `json:"logged_out"`
+	LoggedOutAt  *time.Time `json:"logged_out_at,omitempty"`
+	LogoutReason string     `json:"logout_reason,omitempty"`
+	// NeedsRepair is set when the session has to be paired (again) before
+	// it can send or receive.
+	NeedsRepair bool `json:"needs_repair"`
+}
+
+func (a *App) sessionRecord() (sessionRecord, error) {
+	raw, err := a.db.GetSetting(sessionSettingKey)
+	if err != nil || raw == "" {
+		return sessionRecord{}, err
+	}
+	var r sessionRecord
+	if err := json.Unmarshal([]byte(raw), &r); err != nil {
+		return sessionRecord{}, fmt.Errorf("decode session record: %w", err)
+	}
+	return r, nil
+}
+
+func (a *App) saveSessionRecord(r sessionRecord) error {
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return a.db.SetSetting(sessionSettingKey, string(raw))
+}
+
+// trackSession records logins and remote logouts. A logout publishes an
+// EventSessionInvalid so operators can pair again before messages are
+// missed.
+func (a *App) trackSession(evt interface{}) {
+	switch v := evt.(type) {
+	case *events.Connected:
+		r, _ := a.sessionRecord()
+		if jid := a.wa.OwnJID(); !jid.IsEmpty() {
+			r.JID = jid.ToNonAD().String()
+		}
+		r.ConnectedAt = time.Now().UTC()
+		r.LoggedOutAt, r.LogoutReason = time.Time{}, ""
+		_ = a.saveSessionRecord(r)
+	case *events.LoggedOut:
+		r, _ := a.sessionRecord()
+		r.LoggedOutAt = time.Now().UTC()
+		r.LogoutReason = "logged out from another device"
+		if v.OnConnect {
+			r.LogoutReason = v.Reason.String()
+		}
+		_ = a.saveSessionRecord(r)
+		a.events.Publish(Event{Type: EventSessionInvalid, Timestamp: r.LoggedOutAt, Data: map[string]any{
+			"jid":               r.JID,
+			"reason":            r.LogoutReason,
+			"last_connected_at": r.ConnectedAt,
+		}})
+	}
+}
+
+// SessionHealth reports the state of the WhatsApp session.
+func (a *App) SessionHealth() (SessionHealth, error) {
+	r, err := a.sessionRecord()
+	if err != nil {
+		return SessionHealth{}, err
+	}
+	h := SessionHealth{JID: r.JID}
+	if a.wa != nil {
+		h.Authenticated = a.wa.IsAuthed()
+		h.Connected = a.wa.IsConnected()
+		if jid := a.wa.OwnJID(); !jid.IsEmpty() {
+			h.JID = jid.ToNonAD().String()
+		}
+	}
+	if !r.ConnectedAt.IsZero() {
+		h.LastConnectedAt = &r.ConnectedAt
+	}
+	if !r.LoggedOutAt.IsZero() {
+		h.LoggedOut = true
+		h.LoggedOutAt = &r.LoggedOutAt
+		h.LogoutReason = r.LogoutReason
+	}
+	last, err := a.db.LastIncomingMessageTime()
+	if err != nil {
+		return SessionHealth{}, err
+	}
+	if !last.IsZero() {
+		since := int64(time.Since(last) / time.Second)
+		h.LastMessageAt = &last
+		h.SinceLastMessageSeconds = &since
+	}
+	h.NeedsRepair = h.LoggedOut || !h.Authenticated
+	return h, nil
+}
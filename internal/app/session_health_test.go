@@ -0,0 +1,73 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestSessionHealthTracksLoginAndLogout(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.connected = true
+	f.own = types.NewADJID("5511999990000", 0, 3)
+	a.wa = f
+
+	h, err := a.SessionHealth()
+	if err != nil {
+		t.Fatalf("SessionHealth: %v", err)
+	}
+	if h.LastConnectedAt != nil || h.LastMessageAt != nil || h.LoggedOut || h.NeedsRepair {
+		t.Fatalf("unexpected initial health: %+v", h)
+	}
+
+	a.publishWAEvent(&events.Connected{})
+	if err := a.db.UpsertChat("1@s.whatsapp.net", "dm", "", time.Now()); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	received := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	if err := a.db.UpsertMessage(store.UpsertMessageParams{ChatJID: "1@s.whatsapp.net", MsgID: "in", SenderJID: "1@s.whatsapp.net", Timestamp: received, Text: "hi"}); err != nil {
+		t.Fatalf("UpsertMessage: %v", err)
+	}
+	if err := a.db.UpsertMessage(store.UpsertMessageParams{ChatJID: "1@s.whatsapp.net", MsgID: "out", FromMe: true, Timestamp: time.Now(), Text: "hello"}); err != nil {
+		t.Fatalf("UpsertMessage: %v", err)
+	}
+	h, _ = a.SessionHealth()
+	if h.JID != "5511999990000@s.whatsapp.net" || h.LastConnectedAt == nil || h.LastMessageAt == nil || !h.LastMessageAt.Equal(received) {
+		t.Fatalf("unexpected health after login: %+v", h)
+	}
+	if *h.SinceLastMessageSeconds < 3599 {
+		t.Fatalf("since last message = %d", *h.SinceLastMessageSeconds)
+	}
+
+	evts, unsubscribe := a.events.Subscribe(8)
+	defer unsubscribe()
+	f.authed = false
+	f.own = types.EmptyJID
+	a.publishWAEvent(&events.LoggedOut{OnConnect: true, Reason: events.ConnectFailureLoggedOut})
+
+	var invalid *Event
+	for len(evts) > 0 {
+		if e := <-evts; e.Type == EventSessionInvalid {
+			invalid = &e
+		}
+	}
+	if invalid == nil || invalid.Data["jid"] != "5511999990000@s.whatsapp.net" || invalid.Data["reason"] == "" {
+		t.Fatalf("expected session_invalid event, got %+v", invalid)
+	}
+	h, _ = a.SessionHealth()
+	if !h.LoggedOut || !h.NeedsRepair || h.LoggedOutAt == nil || h.JID != "5511999990000@s.whatsapp.net" {
+		t.Fatalf("unexpected health after logout: %+v", h)
+	}
+
+	// Pairing again clears the logout.
+	f.authed = true
+	f.own = types.NewADJID("5511999990000", 0, 4)
+	a.publishWAEvent(&events.Connected{})
+	if h, _ = a.SessionHealth(); h.LoggedOut || h.NeedsRepair {
+		t.Fatalf("unexpected health after re-pair: %+v", h)
+	}
+}
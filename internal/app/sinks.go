@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EventSink forwards bus events to external infrastructure such as an MQTT
+// broker, NATS or Kafka. RunSink calls Publish from a single goroutine.
+type EventSink interface {
+	Publish(ctx context.Context, e Event, payload []byte) error
+	Close() error
+}
+
+// sinkFilter selects the event types a sink receives (default message and
+// connection).
+func sinkFilter(types []string) EventFilter {
+	if len(types) == 0 {
+		types = []string{EventMessage, EventConnection}
+	}
+	return ParseEventFilter(strings.Join(types, ","), "")
+}
+
+// RunSink publishes matching events to s until ctx is cancelled, then closes
+// it. Only incoming messages are forwarded; our own sends are already known
+// to the caller. Publish errors are logged and the event is dropped.
+func (a *App) RunSink(ctx context.Context, name string, s EventSink, types []string) {
+	defer func() {
+		if err := s.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: close: %v\n", name, err)
+		}
+	}()
+	evts, unsubscribe := a.events.Subscribe(1024)
+	defer unsubscribe()
+
+	filter := sinkFilter(types)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case evt, ok := <-evts:
+			if !ok {
+				return
+			}
+			if !filter.Match(evt) {
+				continue
+			}
+			if fromMe, _ := evt.Data["from_me"].(bool); evt.Type == EventMessage && fromMe {
+				continue
+			}
+			payload, err := json.Marshal(evt)
+			if err != nil {
+				continue
+			}
+			if err := s.Publish(ctx, evt, payload); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: publish %s: %v\n", name, evt.Type, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeSink struct {
+	mu     sync.Mutex
+	events []Event
+	closed bool
+}
+
+func (s *fakeSink) Publish(ctx context.Context, e Event, payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *fakeSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func TestRunSinkForwardsIncomingMessagesAndConnectionState(t *testing.T) {
+	a := newTestApp(t)
+	s := &fakeSink{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		a.RunSink(ctx, "test", s, nil)
+		close(done)
+	}()
+
+	// Wait for the subscription before publishing.
+	waitFor(t, func() bool {
+		a.events.mu.Lock()
+		defer a.events.mu.Unlock()
+		return len(a.events.subs) > 0
+	})
+
+	now := time.Now().UTC()
+	a.events.Publish(Event{Type: EventMessage, Chat: "123@s.whatsapp.net", Timestamp: now, Data: map[string]any{"text": "hi"}})
+	a.events.Publish(Event{Type: EventMessage, Chat: "123@s.whatsapp.net", Timestamp: now, Data: map[string]any{"text": "mine", "from_me": true}})
+	a.events.Publish(Event{Type: EventReceipt, Chat: "123@s.whatsapp.net", Timestamp: now})
+	a.events.Publish(Event{Type: EventConnection, Timestamp: now, Data: map[string]any{"state": "connected"}})
+
+	waitFor(t, func() bool { s.mu.Lock(); defer s.mu.Unlock(); return len(s.events) == 2 })
+	cancel()
+	<-done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.events[0].Data["text"] != "hi" || s.events[1].Type != EventConnection || !s.closed {
+		t.Fatalf("unexpected sink state: %+v closed=%v", s.events, s.closed)
+	}
+}
+
+func TestSinkTopics(t *testing.T) {
+	msg := Event{Type: EventMessage, Chat: "123@s.whatsapp.net"}
+	if topic, retained := mqttTopic("home/wa", msg); topic != "home/wa/messages/123@s.whatsapp.net" || retained {
+		t.Fatalf("mqtt topic = %q retained=%v", topic, retained)
+	}
+	if topic, retained := mqttTopic("wacli", Event{Type: EventConnection}); topic != "wacli/connection" || !retained {
+		t.Fatalf("mqtt topic = %q retained=%v", topic, retained)
+	}
+	if topic, _ := mqttTopic("wacli", Event{Type: EventCall, Chat: "a/b+c#"}); topic != "wacli/calls/a_b_c_" {
+		t.Fatalf("mqtt topic = %q", topic)
+	}
+	if subject := natsSubject("wacli", msg); subject != "wacli.messages.123@s_whatsapp_net" {
+		t.Fatalf("nats subject = %q", subject)
+	}
+}
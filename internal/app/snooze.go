@@ -0,0 +1,116 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// reminderQuoteLen caps the quoted last message in a snooze reminder.
+const reminderQuoteLen = 300
+
+// SnoozeChat hides a chat from chat listings until until, then sends a
+// reminder to remindTo: by default the admin chat, or this account's own
+// chat ("message yourself") without one. quoteLast quotes the chat's
+// latest message in the reminder.
+func (a *App) SnoozeChat(chat types.JID, until time.Time, remindTo types.JID, note string, quoteLast bool) (store.ChatSnooze, error) {
+	if !until.After(time.Now()) {
+		return store.ChatSnooze{}, fmt.Errorf("until must be in the future")
+	}
+	if remindTo.IsEmpty() {
+		remindTo = a.admin
+	}
+	if remindTo.IsEmpty() && a.wa != nil {
+		remindTo = a.wa.OwnJID().ToNonAD()
+	}
+	if remindTo.IsEmpty() {
+		return store.ChatSnooze{}, fmt.Errorf("no chat to remind: pass remind_to or pair the session")
+	}
+	return a.db.SnoozeChat(store.ChatSnooze{
+		ChatJID:   chat.String(),
+		Until:     until.UTC(),
+		RemindTo:  remindTo.String(),
+		Note:      note,
+		QuoteLast: quoteLast,
+	})
+}
+
+// SendDueReminders sends the reminders of snoozes that expired by now and
+// wakes their chats up. Reminders that can't be sent wait in the outbox.
+// It returns how many were sent or queued.
+func (a *App) SendDueReminders(ctx context.Context, now time.Time) (int, error) {
+	due, err := a.db.DueChatSnoozes(now)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, s := range due {
+		to, err := types.ParseJID(s.RemindTo)
+		if err != nil {
+			return sent, fmt.Errorf("snooze of %s: invalid remind_to: %w", s.ChatJID, err)
+		}
+		if _, err := a.sendOrQueueText(ctx, to, a.reminderText(s)); err != nil {
+			return sent, fmt.Errorf("remind %s: %w", s.ChatJID, err)
+		}
+		if _, err := a.db.DeleteChatSnooze(s.ChatJID); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// RunSnoozeReminders sends due snooze reminders every minute until ctx is
+// cancelled.
+func (a *App) RunSnoozeReminders(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		if n, err := a.SendDueReminders(ctx, time.Now().UTC()); err != nil {
+			fmt.Fprintf(os.Stderr, "snooze reminders: %v\n", err)
+		} else if n > 0 {
+			fmt.Fprintf(os.Stderr, "snooze reminders: sent %d\n", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *App) reminderText(s store.ChatSnooze) string {
+	name := s.ChatJID
+	if c, err := a.db.GetChat(s.ChatJID); err == nil && c.Name != "" {
+		name = c.Name
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "⏰ Reminder: follow up with %s", name)
+	if s.Note != "" {
+		fmt.Fprintf(&sb, "\n%s", s.Note)
+	}
+	if s.QuoteLast {
+		msgs, err := a.db.ListMessages(store.ListMessagesParams{ChatJID: s.ChatJID, Limit: 1, System: store.SystemExclude})
+		if err == nil && len(msgs) > 0 {
+			m := msgs[0]
+			who := "You"
+			if !m.FromMe {
+				who = m.SenderJID
+				if c, err := a.db.GetContact(m.SenderJID); err == nil {
+					who = firstNonEmpty(c.Alias, c.Name, who)
+				}
+			}
+			text := firstNonEmpty(m.DisplayText, m.Text, "<"+m.MediaType+">")
+			if r := []rune(text); len(r) > reminderQuoteLen {
+				text = string(r[:reminderQuoteLen]) + "…"
+			}
+			fmt.Fprintf(&sb, "\n\n%s, %s UTC:\n> %s", who, m.Timestamp.UTC().Format("Jan 2 15:04"), strings.ReplaceAll(text, "\n", "\n> "))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestSnoozeRemindsOwnChat(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.connected = true
+	f.own = types.NewADJID("5511000000000", 0, 7)
+	a.wa = f
+
+	chat := types.NewJID("5511999990000", types.DefaultUserServer)
+	base := time.Now().UTC().Add(-time.Hour)
+	if err := a.db.UpsertChat(chat.String(), "dm", "Landlord", base); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	if err := a.db.UpsertMessage(store.UpsertMessageParams{ChatJID: chat.String(), MsgID: "m1", SenderJID: chat.String(), Timestamp: base, Text: "Can you send the\ncontract?"}); err != nil {
+		t.Fatalf("UpsertMessage: %v", err)
+	}
+
+	if _, err := a.SnoozeChat(chat, time.Now().Add(-time.Minute), types.EmptyJID, "", false); err == nil {
+		t.Fatalf("expected a past until to be rejected")
+	}
+	s, err := a.SnoozeChat(chat, time.Now().Add(time.Hour), types.EmptyJID, "sign first", true)
+	if err != nil {
+		t.Fatalf("SnoozeChat: %v", err)
+	}
+	if s.RemindTo != "5511000000000@s.whatsapp.net" {
+		t.Fatalf("expected the own chat to be reminded, got %q", s.RemindTo)
+	}
+
+	ctx := context.Background()
+	if n, err := a.SendDueReminders(ctx, time.Now()); err != nil || n != 0 {
+		t.Fatalf("SendDueReminders before expiry = %d, %v", n, err)
+	}
+	if n, err := a.SendDueReminders(ctx, time.Now().Add(2*time.Hour)); err != nil || n != 1 {
+		t.Fatalf("SendDueReminders = %d, %v", n, err)
+	}
+	if len(f.sent) != 1 || f.sentTo[0] != types.NewJID("5511000000000", types.DefaultUserServer) {
+		t.Fatalf("expected one reminder to the own chat, got %v to %v", f.sent, f.sentTo)
+	}
+	for _, want := range []string{"Landlord", "sign first", "> Can you send the\n> contract?"} {
+		if !strings.Contains(f.sent[0], want) {
+			t.Fatalf("reminder %q is missing %q", f.sent[0], want)
+		}
+	}
+	if _, err := a.db.GetChatSnooze(chat.String()); !store.IsNotFound(err) {
+		t.Fatalf("expected the chat to wake up, got %v", err)
+	}
+}
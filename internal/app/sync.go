@@ -83,13 +83,17 @@ func (a *App) Sync(ctx context.Context, opts SyncOptions) (SyncResult, error) {
 		}
 	}
 
+	aiEnabled := opts.Config != nil && opts.Config.AI.Enabled && opts.Config.AI.GroqAPIKey != ""
+	// Limits concurrent sentiment requests during bursts of live messages.
+	sentimentSlots := make(chan struct{}, 4)
+
 	handlerID := a.wa.AddEventHandler(func(evt interface{}) {
 		lastEvent.Store(time.Now().UTC().UnixNano())
 
 		// AI handler for audio transcription
-		if opts.Config != nil && opts.Config.AI.Enabled && opts.Config.AI.GroqAPIKey != "" {
-			if waCli, ok := a.wa.(interface{ GetClient() interface{} }); ok {
-				if client, ok := waCli.GetClient().(*whatsmeow.Client); ok && client != nil {
+		if aiEnabled {
+			if waCli, ok := a.wa.(interface{ GetClient() *whatsmeow.Client }); ok {
+				if client := waCli.GetClient(); client != nil {
 					ai.HandleMessages(ctx, client, evt, opts.Config)
 				}
 			}
@@ -110,6 +114,19 @@ func (a *App) Sync(ctx context.Context, opts SyncOptions) (SyncResult, error) {
 			}
 			if err := a.storeParsedMessage(ctx, pm); err == nil {
 				messagesStored.Add(1)
+				if aiEnabled && opts.Config.AI.Sentiment && opts.Config.AI.Privacy.Allowed(pm.Chat.String()) {
+					if text := sentimentText(pm); text != "" {
+						go func() {
+							select {
+							case sentimentSlots <- struct{}{}:
+							case <-ctx.Done():
+								return
+							}
+							defer func() { <-sentimentSlots }()
+							a.tagSentiment(ctx, opts.Config, pm, text)
+						}()
+					}
+				}
 			}
 			if opts.DownloadMedia && pm.Media != nil && pm.ID != "" {
 				enqueueMedia(pm.Chat.String(), pm.ID)
@@ -119,12 +136,18 @@ func (a *App) Sync(ctx context.Context, opts SyncOptions) (SyncResult, error) {
 			}
 		case *events.HistorySync:
 			fmt.Fprintf(os.Stderr, "\nProcessing history sync (%d conversations)...\n", len(v.Data.Conversations))
+			for _, m := range v.Data.GetPhoneNumberToLidMappings() {
+				a.rememberLIDStrings(m.GetLidJID(), m.GetPnJID())
+			}
 			for _, conv := range v.Data.Conversations {
 				lastEvent.Store(time.Now().UTC().UnixNano())
 				chatID := strings.TrimSpace(conv.GetID())
 				if chatID == "" {
 					continue
 				}
+				a.rememberLIDStrings(conv.GetLidJID(), conv.GetPnJID())
+				var chat string
+				var incoming []time.Time
 				for _, m := range conv.Messages {
 					lastEvent.Store(time.Now().UTC().UnixNano())
 					if m.Message == nil {
@@ -136,13 +159,36 @@ func (a *App) Sync(ctx context.Context, opts SyncOptions) (SyncResult, error) {
 					}
 					if err := a.storeParsedMessage(ctx, pm); err == nil {
 						messagesStored.Add(1)
+						chat = pm.Chat.String()
+						if !pm.FromMe {
+							incoming = append(incoming, pm.Timestamp)
+						}
 					}
 					if opts.DownloadMedia && pm.Media != nil && pm.ID != "" {
 						enqueueMedia(pm.Chat.String(), pm.ID)
 					}
 				}
+				if upTo, ok := historyReadWatermark(incoming, int(conv.GetUnreadCount())); ok && chat != "" {
+					_ = a.db.MarkChatRead(chat, upTo)
+				}
 			}
 			fmt.Fprintf(os.Stderr, "\rSynced %d messages...", messagesStored.Load())
+		case *events.CallOffer, *events.CallOfferNotice, *events.CallAccept, *events.CallReject, *events.CallTerminate:
+			if ce, ok := wa.ParseCallEvent(v); ok {
+				_ = a.storeCallEvent(ctx, ce)
+			}
+		case *events.IdentityChange:
+			_ = a.storeIdentityChange(ctx, v)
+		case *events.JoinedGroup:
+			if v.Type == "new" {
+				_ = a.storeGroupCreated(ctx, v)
+			}
+		case *events.GroupInfo:
+			_ = a.storeGroupParticipantEvents(v)
+		case *events.Receipt:
+			_ = a.storeReceipt(v)
+		case *events.Archive, *events.Pin, *events.Mute:
+			_ = a.storeChatState(v)
 		case *events.Connected:
 			fmt.Fprintln(os.Stderr, "\nConnected.")
 		case *events.Disconnected:
@@ -189,7 +235,7 @@ func (a *App) Sync(ctx context.Context, opts SyncOptions) (SyncResult, error) {
 				return SyncResult{MessagesStored: messagesStored.Load()}, nil
 			case <-disconnected:
 				fmt.Fprintln(os.Stderr, "Reconnecting...")
-				if err := a.wa.ReconnectWithBackoff(ctx, 2*time.Second, 30*time.Second); err != nil {
+				if err := a.reconnect(ctx); err != nil {
 					return SyncResult{MessagesStored: messagesStored.Load()}, err
 				}
 			}
@@ -210,7 +256,7 @@ func (a *App) Sync(ctx context.Context, opts SyncOptions) (SyncResult, error) {
 			return SyncResult{MessagesStored: messagesStored.Load()}, nil
 		case <-disconnected:
 			fmt.Fprintln(os.Stderr, "Reconnecting...")
-			if err := a.wa.ReconnectWithBackoff(ctx, 2*time.Second, 30*time.Second); err != nil {
+			if err := a.reconnect(ctx); err != nil {
 				return SyncResult{MessagesStored: messagesStored.Load()}, err
 			}
 		case <-ticker.C:
@@ -230,14 +276,18 @@ func chatKind(chat types.JID) string {
 	if chat.IsBroadcastList() {
 		return "broadcast"
 	}
-	if chat.Server == types.DefaultUserServer {
+	if chat.Server == types.DefaultUserServer || chat.Server == types.HiddenUserServer {
 		return "dm"
 	}
 	return "unknown"
 }
 
 func (a *App) storeParsedMessage(ctx context.Context, pm wa.ParsedMessage) error {
+	pm = a.resolveLIDs(pm)
 	chatJID := pm.Chat.String()
+	if pm.RevokedID != "" || pm.EditedID != "" {
+		return a.storeRevision(chatJID, pm)
+	}
 	chatName := a.wa.ResolveChatName(ctx, pm.Chat, pm.PushName)
 	if err := a.db.UpsertChat(chatJID, chatKind(pm.Chat), chatName, pm.Timestamp); err != nil {
 		return err
@@ -293,9 +343,10 @@ func (a *App) storeParsedMessage(ctx context.Context, pm wa.ParsedMessage) error
 				} else if p.IsAdmin {
 					role = "admin"
 				}
+				a.rememberLID(p.JID, p.PhoneNumber)
 				ps = append(ps, store.GroupParticipant{
 					GroupJID: pm.Chat.String(),
-					UserJID:  p.JID.String(),
+					UserJID:  a.phoneJID(p.JID).String(),
 					Role:     role,
 				})
 			}
@@ -319,8 +370,12 @@ func (a *App) storeParsedMessage(ctx context.Context, pm wa.ParsedMessage) error
 	}
 
 	displayText := a.buildDisplayText(ctx, pm)
+	quotedSnippet := strings.TrimSpace(pm.ReplyToDisplay)
+	if pm.ReplyToID != "" && quotedSnippet == "" {
+		quotedSnippet = a.lookupMessageDisplayText(chatJID, pm.ReplyToID)
+	}
 
-	return a.db.UpsertMessage(store.UpsertMessageParams{
+	if err := a.db.UpsertMessage(store.UpsertMessageParams{
 		ChatJID:       chatJID,
 		ChatName:      chatName,
 		MsgID:         pm.ID,
@@ -339,7 +394,61 @@ func (a *App) storeParsedMessage(ctx context.Context, pm wa.ParsedMessage) error
 		FileSHA256:    fileSha,
 		FileEncSHA256: fileEncSha,
 		FileLength:    fileLen,
-	})
+		QuotedID:      pm.ReplyToID,
+		QuotedSnippet: quotedSnippet,
+		SystemType:    pm.System,
+	}); err != nil {
+		return err
+	}
+
+	if pm.Media != nil {
+		_ = a.storeEmbeddedThumbnail(chatJID, pm.ID, pm.Media.Thumbnail)
+		if !pm.FromMe {
+			_ = a.markMediaDuplicate(chatJID, pm.ID, pm.Media.FileSHA256, pm.Timestamp)
+		}
+	}
+	if len(pm.ParticipantChanges) > 0 {
+		_ = a.db.AddGroupEvents(groupEventRows(chatJID, pm.ParticipantChanges))
+	}
+	a.storeEntities(pm)
+	return nil
+}
+
+// storeRevision applies a peer's edit or delete-for-everyone to the stored
+// message. Changes to messages we never stored are dropped.
+func (a *App) storeRevision(chatJID string, pm wa.ParsedMessage) error {
+	targetID := pm.EditedID
+	if pm.RevokedID != "" {
+		targetID = pm.RevokedID
+	}
+	orig, err := a.db.GetMessage(chatJID, targetID)
+	if store.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var changed bool
+	if pm.RevokedID != "" {
+		pm.Text = ""
+		changed, err = a.db.RevokeMessage(chatJID, targetID, pm.Timestamp)
+	} else {
+		display := strings.TrimSpace(pm.Text)
+		if orig.MediaType != "" {
+			display = "Sent " + mediaLabel(orig.MediaType)
+		}
+		changed, err = a.db.EditMessage(chatJID, targetID, pm.Text, display, pm.Timestamp)
+	}
+	if err != nil || !changed {
+		return err
+	}
+
+	// Re-extract entities so search by entity follows the current text.
+	pm.ID = targetID
+	pm.Timestamp = orig.Timestamp
+	a.storeEntities(pm)
+	return nil
 }
 
 func (a *App) buildDisplayText(ctx context.Context, pm wa.ParsedMessage) string {
@@ -225,6 +225,9 @@ func TestSyncStoresDisplayText(t *testing.T) {
 	if msg.DisplayText != "> quoted text\nreply text" {
 		t.Fatalf("unexpected reply display text: %q", msg.DisplayText)
 	}
+	if msg.Quoted == nil || msg.Quoted.ID != "m-text" || msg.Quoted.Snippet != "quoted text" {
+		t.Fatalf("unexpected quoted message: %+v", msg.Quoted)
+	}
 
 	msg, err = a.db.GetMessage(chat.String(), "m-react")
 	if err != nil {
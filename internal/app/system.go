@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// storeGroupCreated records a "group created" notice for a group we were
+// added to on creation. History sync delivers the same notice as a message
+// stub, which wa.ParseHistoryMessage handles.
+func (a *App) storeGroupCreated(ctx context.Context, v *events.JoinedGroup) error {
+	chat := v.JID.String()
+	ts := v.GroupCreated.UTC()
+	if ts.IsZero() {
+		ts = time.Now().UTC()
+	}
+	name := v.GroupName.Name
+	if err := a.db.UpsertChat(chat, "group", name, ts); err != nil {
+		return err
+	}
+	text := "Group created"
+	if name != "" {
+		text = fmt.Sprintf("Group %q created", name)
+	}
+	sender := ""
+	if v.Sender != nil {
+		sender = v.Sender.String()
+	}
+	return a.db.UpsertMessage(store.UpsertMessageParams{
+		ChatJID:     chat,
+		ChatName:    name,
+		MsgID:       "group_created:" + chat,
+		SenderJID:   sender,
+		Timestamp:   ts,
+		Text:        text,
+		DisplayText: text,
+		SystemType:  wa.SystemGroupCreated,
+	})
+}
+
+// storeGroupParticipantEvents records the membership changes in a live
+// group update. History sync delivers them as message stubs, which
+// storeParsedMessage records.
+func (a *App) storeGroupParticipantEvents(v *events.GroupInfo) error {
+	return a.db.AddGroupEvents(groupEventRows(v.JID.String(), wa.ParseGroupInfoEvent(v)))
+}
+
+func groupEventRows(group string, changes []wa.GroupParticipantChange) []store.GroupEvent {
+	out := make([]store.GroupEvent, 0, len(changes))
+	for _, ch := range changes {
+		e := store.GroupEvent{
+			GroupJID:       group,
+			Action:         ch.Action,
+			ParticipantJID: ch.Participant.String(),
+			Timestamp:      ch.Timestamp,
+		}
+		if !ch.Actor.IsEmpty() {
+			e.ActorJID = ch.Actor.String()
+		}
+		out = append(out, e)
+	}
+	return out
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSystemNoticesAreStoredTyped(t *testing.T) {
+	a := newTestApp(t)
+	a.wa = newFakeWA()
+	ctx := context.Background()
+
+	group := types.NewJID("120363000000000001", types.GroupServer)
+	creator := types.NewJID("5511999990000", types.DefaultUserServer)
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := a.storeGroupCreated(ctx, &events.JoinedGroup{Type: "new", Sender: &creator, GroupInfo: types.GroupInfo{
+		JID:          group,
+		GroupName:    types.GroupName{Name: "Team"},
+		GroupCreated: created,
+	}}); err != nil {
+		t.Fatalf("storeGroupCreated: %v", err)
+	}
+	if err := a.storeParsedMessage(ctx, wa.ParsedMessage{
+		Chat: group, ID: "eph", SenderJID: creator.String(), Timestamp: created.Add(time.Minute),
+		Text: "Disappearing messages set to 24 hours", System: wa.SystemEphemeralTimer, EphemeralTimer: 24 * time.Hour,
+	}); err != nil {
+		t.Fatalf("storeParsedMessage: %v", err)
+	}
+
+	msgs, err := a.db.ListMessages(store.ListMessagesParams{ChatJID: group.String(), System: store.SystemOnly})
+	if err != nil {
+		t.Fatalf("ListMessages: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].SystemType != wa.SystemEphemeralTimer || msgs[1].SystemType != wa.SystemGroupCreated || msgs[1].Text != `Group "Team" created` || !msgs[1].Timestamp.Equal(created) {
+		t.Fatalf("unexpected notices: %+v", msgs)
+	}
+	if msgs[0].DisplayText != "Disappearing messages set to 24 hours" {
+		t.Fatalf("DisplayText = %q", msgs[0].DisplayText)
+	}
+}
+
+func TestGroupMembershipChangesAreRecorded(t *testing.T) {
+	a := newTestApp(t)
+	a.wa = newFakeWA()
+	ctx := context.Background()
+
+	group := types.NewJID("120363000000000001", types.GroupServer)
+	admin := types.NewJID("5511999990000", types.DefaultUserServer)
+	bob := types.NewJID("5511888880000", types.DefaultUserServer)
+	carol := types.NewJID("5511777770000", types.DefaultUserServer)
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if err := a.storeGroupParticipantEvents(&events.GroupInfo{
+		JID: group, Sender: &admin, Timestamp: at,
+		Join: []types.JID{bob}, Promote: []types.JID{bob},
+	}); err != nil {
+		t.Fatalf("storeGroupParticipantEvents: %v", err)
+	}
+	if err := a.storeGroupParticipantEvents(&events.GroupInfo{
+		JID: group, Sender: &carol, Timestamp: at.Add(time.Minute), Leave: []types.JID{carol},
+	}); err != nil {
+		t.Fatalf("storeGroupParticipantEvents: %v", err)
+	}
+
+	hist := &waProto.WebMessageInfo{
+		Key:                   &waProto.MessageKey{RemoteJID: proto.String(group.String()), ID: proto.String("stub1"), Participant: proto.String(admin.String())},
+		MessageTimestamp:      proto.Uint64(uint64(at.Add(time.Hour).Unix())),
+		MessageStubType:       waProto.WebMessageInfo_GROUP_PARTICIPANT_REMOVE.Enum(),
+		MessageStubParameters: []string{bob.String()},
+	}
+	pm := wa.ParseHistoryMessage(group.String(), hist)
+	if pm.System != wa.SystemGroupParticipants || pm.Text != "+5511888880000 removed" {
+		t.Fatalf("parsed stub = %+v", pm)
+	}
+	if err := a.storeParsedMessage(ctx, pm); err != nil {
+		t.Fatalf("storeParsedMessage: %v", err)
+	}
+
+	got, err := a.db.ListGroupEvents(store.ListGroupEventsParams{GroupJID: group.String()})
+	if err != nil {
+		t.Fatalf("ListGroupEvents: %v", err)
+	}
+	var actions []string
+	for _, e := range got {
+		actions = append(actions, e.Action+":"+e.ParticipantJID+":"+e.ActorJID)
+	}
+	want := []string{
+		"remove:" + bob.String() + ":" + admin.String(),
+		"leave:" + carol.String() + ":" + carol.String(),
+		"promote:" + bob.String() + ":" + admin.String(),
+		"add:" + bob.String() + ":" + admin.String(),
+	}
+	if len(actions) != len(want) {
+		t.Fatalf("events = %q", actions)
+	}
+	// add and promote share a timestamp; compare them as a set.
+	if actions[0] != want[0] || actions[1] != want[1] || !(actions[2] == want[2] && actions[3] == want[3] || actions[2] == want[3] && actions[3] == want[2]) {
+		t.Fatalf("events = %q", actions)
+	}
+}
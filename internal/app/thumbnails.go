@@ -0,0 +1,109 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"io/fs"
+	"os"
+
+	// Formats WhatsApp images and stickers come in.
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/steipete/wacli/internal/store"
+	_ "golang.org/x/image/webp"
+)
+
+// ErrNoThumbnail is returned when a message has no thumbnail and none can be
+// made without downloading its media.
+var ErrNoThumbnail = errors.New("no thumbnail available")
+
+// thumbnailMaxSize bounds the longer side of generated thumbnails.
+var thumbnailMaxSize = 320
+
+const thumbnailQuality = 80
+
+// storeEmbeddedThumbnail keeps the preview embedded in an image or video
+// message so galleries can render it before the media is downloaded.
+func (a *App) storeEmbeddedThumbnail(chatJID, msgID string, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	t := store.Thumbnail{ChatJID: chatJID, MsgID: msgID, Data: data, Source: store.ThumbnailEmbedded}
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		t.Width, t.Height = cfg.Width, cfg.Height
+	}
+	return a.db.PutThumbnail(t)
+}
+
+// generateThumbnail resizes the downloaded image at location into a
+// thumbnail. Videos are left to their embedded thumbnail.
+func (a *App) generateThumbnail(ctx context.Context, info store.MediaDownloadInfo, location string) (store.Thumbnail, error) {
+	if info.MediaType != "image" && info.MediaType != "sticker" {
+		return store.Thumbnail{}, ErrNoThumbnail
+	}
+	r, _, err := a.openMediaLocation(ctx, location)
+	if err != nil {
+		return store.Thumbnail{}, err
+	}
+	defer r.Close()
+	data, w, h, err := makeThumbnail(r, thumbnailMaxSize)
+	if err != nil {
+		return store.Thumbnail{}, err
+	}
+	t := store.Thumbnail{ChatJID: info.ChatJID, MsgID: info.MsgID, Data: data, Width: w, Height: h, Source: store.ThumbnailGenerated}
+	if err := a.db.PutThumbnail(t); err != nil {
+		return store.Thumbnail{}, err
+	}
+	return a.db.GetThumbnail(info.ChatJID, info.MsgID)
+}
+
+// Thumbnail returns a message's thumbnail. If its image was downloaded
+// before a thumbnail was generated, one is generated now; otherwise the
+// embedded thumbnail is returned. It never downloads media.
+func (a *App) Thumbnail(ctx context.Context, info store.MediaDownloadInfo) (store.Thumbnail, error) {
+	t, err := a.db.GetThumbnail(info.ChatJID, info.MsgID)
+	if err == nil && t.Source == store.ThumbnailGenerated {
+		return t, nil
+	}
+	if err != nil && !store.IsNotFound(err) {
+		return store.Thumbnail{}, err
+	}
+	if info.LocalPath != "" {
+		if gen, genErr := a.generateThumbnail(ctx, info, info.LocalPath); genErr == nil {
+			return gen, nil
+		} else if !errors.Is(genErr, ErrNoThumbnail) && !errors.Is(genErr, fs.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "thumbnails: %s/%s: %v\n", info.ChatJID, info.MsgID, genErr)
+		}
+	}
+	if err != nil {
+		return store.Thumbnail{}, ErrNoThumbnail
+	}
+	return t, nil
+}
+
+// makeThumbnail decodes the image read from r and scales it to fit within
+// maxSize×maxSize, returning it JPEG-encoded with its dimensions. Images
+// already small enough are re-encoded at their size.
+func makeThumbnail(r io.Reader, maxSize int) ([]byte, int, int, error) {
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("decode image: %w", err)
+	}
+
+	if b := src.Bounds(); b.Dx() == 0 || b.Dy() == 0 {
+		return nil, 0, 0, fmt.Errorf("decode image: empty image")
+	}
+	dst := scaleToFit(src, maxSize)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, 0, 0, err
+	}
+	return buf.Bytes(), dst.Bounds().Dx(), dst.Bounds().Dy(), nil
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestThumbnailEmbeddedThenGenerated(t *testing.T) {
+	a := newTestApp(t)
+	a.wa = newFakeWA()
+	ctx := context.Background()
+
+	var embedded bytes.Buffer
+	if err := jpeg.Encode(&embedded, image.NewRGBA(image.Rect(0, 0, 32, 24)), nil); err != nil {
+		t.Fatalf("jpeg.Encode: %v", err)
+	}
+	chat := types.NewJID("123", types.DefaultUserServer)
+	if err := a.storeParsedMessage(ctx, wa.ParsedMessage{
+		Chat: chat, ID: "img", SenderJID: chat.String(), Timestamp: time.Now(),
+		Media: &wa.Media{Type: "image", MimeType: "image/png", DirectPath: "/p", MediaKey: []byte{1}, Thumbnail: embedded.Bytes()},
+	}); err != nil {
+		t.Fatalf("storeParsedMessage: %v", err)
+	}
+
+	info, err := a.db.GetMediaDownloadInfo(chat.String(), "img")
+	if err != nil {
+		t.Fatalf("GetMediaDownloadInfo: %v", err)
+	}
+	th, err := a.Thumbnail(context.Background(), info)
+	if err != nil || th.Source != store.ThumbnailEmbedded || th.Width != 32 || th.Height != 24 {
+		t.Fatalf("expected embedded thumbnail: %+v (%v)", th, err)
+	}
+
+	// Once the image is on disk, a larger thumbnail is generated from it.
+	src := image.NewRGBA(image.Rect(0, 0, 640, 480))
+	for y := 0; y < 480; y++ {
+		for x := 0; x < 640; x++ {
+			src.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	path := filepath.Join(t.TempDir(), "img.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := png.Encode(f, src); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+	f.Close()
+	if err := a.db.MarkMediaDownloaded(chat.String(), "img", path, "", time.Now()); err != nil {
+		t.Fatalf("MarkMediaDownloaded: %v", err)
+	}
+	info, _ = a.db.GetMediaDownloadInfo(chat.String(), "img")
+	th, err = a.Thumbnail(context.Background(), info)
+	if err != nil || th.Source != store.ThumbnailGenerated || th.Width != 320 || th.Height != 240 {
+		t.Fatalf("expected generated thumbnail: %+v (%v)", th, err)
+	}
+	if cfg, err := jpeg.DecodeConfig(bytes.NewReader(th.Data)); err != nil || cfg.Width != 320 || cfg.Height != 240 {
+		t.Fatalf("thumbnail is not a 320x240 JPEG: %+v (%v)", cfg, err)
+	}
+
+	if err := a.storeParsedMessage(ctx, wa.ParsedMessage{
+		Chat: chat, ID: "vid", SenderJID: chat.String(), Timestamp: time.Now(),
+		Media: &wa.Media{Type: "video", MimeType: "video/mp4", DirectPath: "/p", MediaKey: []byte{1}},
+	}); err != nil {
+		t.Fatalf("storeParsedMessage: %v", err)
+	}
+	info, _ = a.db.GetMediaDownloadInfo(chat.String(), "vid")
+	if _, err := a.Thumbnail(context.Background(), info); !errors.Is(err, ErrNoThumbnail) {
+		t.Fatalf("expected ErrNoThumbnail, got %v", err)
+	}
+}
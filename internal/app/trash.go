@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/steipete/wacli/internal/mediastore"
+	"github.com/steipete/wacli/internal/pathutil"
+)
+
+// DefaultTrashRetention is how long deleted chats can be restored.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// PurgeChat permanently deletes a chat in the trash, including media that
+// was downloaded into the store for it. Media stored by content may be
+// shared with other chats and is left to the media garbage collection.
+func (a *App) PurgeChat(jid string) error {
+	if err := a.db.PurgeChat(jid); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	err := a.media.Walk(ctx, pathutil.SanitizeSegment(jid)+"/", func(o mediastore.Object) error {
+		return a.media.Delete(ctx, o.Key)
+	})
+	if err != nil {
+		return fmt.Errorf("remove media: %w", err)
+	}
+	return nil
+}
+
+// PurgeTrash purges the chats that have been in the trash longer than
+// retention and returns how many were purged.
+func (a *App) PurgeTrash(retention time.Duration) (int, error) {
+	jids, err := a.db.ListExpiredTrash(time.Now().UTC().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, jid := range jids {
+		if err := a.PurgeChat(jid); err != nil {
+			return purged, fmt.Errorf("purge %s: %w", jid, err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// RunTrashPurge purges expired trash hourly until ctx is cancelled.
+func (a *App) RunTrashPurge(ctx context.Context, retention time.Duration) {
+	if retention <= 0 {
+		retention = DefaultTrashRetention
+	}
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		if _, err := a.PurgeTrash(retention); err != nil {
+			fmt.Fprintf(os.Stderr, "trash: purge: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
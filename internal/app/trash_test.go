@@ -0,0 +1,46 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/pathutil"
+)
+
+func TestPurgeTrashRemovesExpiredChats(t *testing.T) {
+	a := newTestApp(t)
+	old, recent := "111@g.us", "222@g.us"
+	now := time.Now().UTC()
+	for _, jid := range []string{old, recent} {
+		if err := a.db.UpsertChat(jid, "group", "", now); err != nil {
+			t.Fatalf("UpsertChat: %v", err)
+		}
+	}
+	if err := a.db.TrashChat(old, now.Add(-31*24*time.Hour)); err != nil {
+		t.Fatalf("TrashChat: %v", err)
+	}
+	if err := a.db.TrashChat(recent, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("TrashChat: %v", err)
+	}
+	media := filepath.Join(a.StoreDir(), "media", pathutil.SanitizeSegment(old), "m1")
+	if err := os.MkdirAll(media, 0700); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(media, "photo.jpg"), []byte("x"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	n, err := a.PurgeTrash(DefaultTrashRetention)
+	if err != nil || n != 1 {
+		t.Fatalf("PurgeTrash = %d, %v", n, err)
+	}
+	if _, err := os.Stat(filepath.Dir(media)); !os.IsNotExist(err) {
+		t.Fatalf("media of purged chat still present: %v", err)
+	}
+	trash, err := a.db.ListTrashedChats()
+	if err != nil || len(trash) != 1 || trash[0].JID != recent {
+		t.Fatalf("trash = %+v, %v", trash, err)
+	}
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/steipete/wacli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// ValidateAutomation checks the stored webhook subscriptions and rules for
+// problems that would only show up when an event arrives, e.g. a chat filter
+// that can never match or a rule forwarding to a disabled webhook. It reports
+// every problem found.
+func (a *App) ValidateAutomation() ([]error, error) {
+	hooks, err := a.db.ListWebhooks(false)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := a.db.ListRules(false)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	enabled := map[string]bool{}
+	for _, h := range hooks {
+		id := strconv.FormatInt(h.ID, 10)
+		enabled[id] = h.Enabled
+		errs = append(errs, validateWebhookFilters("webhook "+id, h.Events, h.Chats)...)
+	}
+	for _, r := range rules {
+		label := fmt.Sprintf("rule %d", r.ID)
+		if r.Name != "" {
+			label += fmt.Sprintf(" (%s)", r.Name)
+		}
+		errs = append(errs, validateRuleFilters(label, r.Chats, r.Senders)...)
+		if r.Action == store.RuleWebhook {
+			if on, ok := enabled[r.Arg]; !ok {
+				errs = append(errs, fmt.Errorf("%s: forwards to missing webhook %s", label, r.Arg))
+			} else if !on {
+				errs = append(errs, fmt.Errorf("%s: forwards to disabled webhook %s", label, r.Arg))
+			}
+		}
+	}
+	return errs, nil
+}
+
+// ValidateBundle checks a bundle as ImportBundle would, plus the event types
+// and JIDs it filters on.
+func ValidateBundle(b store.Bundle) []error {
+	errs := b.Validate()
+	for i, w := range b.Webhooks {
+		errs = append(errs, validateWebhookFilters(fmt.Sprintf("webhook %d", i+1), w.Events, w.Chats)...)
+	}
+	for i, r := range b.Rules {
+		errs = append(errs, validateRuleFilters(fmt.Sprintf("rule %d", i+1), r.Chats, r.Senders)...)
+	}
+	return errs
+}
+
+func validateWebhookFilters(label string, events, chats []string) []error {
+	var errs []error
+	for _, e := range events {
+		if !IsEventType(e) {
+			errs = append(errs, fmt.Errorf("%s: unknown event type %q", label, e))
+		}
+	}
+	// Webhook chat filters compare full JIDs, so a bare phone number never matches.
+	for _, c := range chats {
+		if !strings.Contains(c, "@") {
+			errs = append(errs, fmt.Errorf("%s: chat %q is not a full JID (e.g. %s@s.whatsapp.net)", label, c, c))
+		} else if _, err := types.ParseJID(c); err != nil {
+			errs = append(errs, fmt.Errorf("%s: chat %q: %v", label, c, err))
+		}
+	}
+	return errs
+}
+
+func validateRuleFilters(label string, chats, senders []string) []error {
+	var errs []error
+	for _, list := range [][]string{chats, senders} {
+		for _, j := range list {
+			if _, err := types.ParseJID(strings.TrimPrefix(j, "+")); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid JID %q: %v", label, j, err))
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/steipete/wacli/internal/store"
+)
+
+func TestValidateAutomationReportsAllProblems(t *testing.T) {
+	a := newTestApp(t)
+	h, err := a.db.CreateWebhook(store.CreateWebhookParams{URL: "https://example.com", Events: []string{"message", "mesage"}, Chats: []string{"5511999999999"}})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	if _, err := a.db.CreateRule(store.CreateRuleParams{Name: "fwd", Senders: []string{"a.b.c@s.whatsapp.net"}, Action: store.RuleWebhook, Arg: strconv.FormatInt(h.ID, 10)}); err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+	if _, err := a.db.CreateRule(store.CreateRuleParams{Chats: []string{"+5511999999999"}, Action: store.RuleDrop}); err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+
+	errs, err := a.ValidateAutomation()
+	if err != nil {
+		t.Fatalf("ValidateAutomation: %v", err)
+	}
+	var msgs []string
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	joined := strings.Join(msgs, "\n")
+	if len(errs) != 3 || !strings.Contains(joined, `unknown event type "mesage"`) || !strings.Contains(joined, "not a full JID") || !strings.Contains(joined, "rule 1 (fwd): invalid JID") {
+		t.Fatalf("unexpected problems:\n%s", joined)
+	}
+}
+
+func TestValidateBundleChecksEventTypes(t *testing.T) {
+	b := store.Bundle{
+		Version:  store.BundleVersion,
+		Webhooks: []store.BundleWebhook{{Ref: "a", URL: "https://example.com", Events: []string{"calls"}}},
+		Rules:    []store.BundleRule{{Action: store.RuleWebhook, Arg: "a"}},
+	}
+	if errs := ValidateBundle(b); len(errs) != 1 || !strings.Contains(errs[0].Error(), `"calls"`) {
+		t.Fatalf("unexpected problems: %v", errs)
+	}
+}
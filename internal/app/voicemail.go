@@ -0,0 +1,363 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/steipete/wacli/internal/ai"
+	"github.com/steipete/wacli/internal/privacy"
+	"github.com/steipete/wacli/internal/store"
+	"github.com/steipete/wacli/internal/wa"
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// Voicemail: voice notes arriving on a spare number (or in chosen chats) are
+// transcribed and forwarded, audio and transcript, to a WhatsApp chat and/or
+// by email, so the number works as a voicemail box.
+
+// Voicemail configures RunVoicemail. It is enabled when ForwardTo or Email
+// is set.
+type Voicemail struct {
+	// Chats limits voicemail to these numbers or JIDs; empty takes voice
+	// notes from every direct chat.
+	Chats []string
+	// ForwardTo is the number or group JID voicemails are forwarded to.
+	ForwardTo string
+	// Email lists addresses voicemails are mailed to through SMTP.
+	Email []string
+	SMTP  SMTPOptions
+	// GroqAPIKey transcribes the voice notes; without it they are forwarded
+	// without a transcript, as they are for chats Privacy excludes.
+	GroqAPIKey string
+	Privacy    privacy.Guard
+}
+
+// SMTPOptions is the mail server voicemail emails are sent through.
+type SMTPOptions struct {
+	// Addr is host:port, e.g. smtp.example.com:587. STARTTLS is used when
+	// the server offers it.
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+// Enabled reports whether voicemails go anywhere.
+func (v Voicemail) Enabled() bool {
+	return v.ForwardTo != "" || len(v.Email) > 0
+}
+
+// Validate reports configuration problems, e.g. email without a mail server.
+func (v Voicemail) Validate() []error {
+	var errs []error
+	if v.ForwardTo != "" {
+		if _, err := wa.ParseUserOrJID(v.ForwardTo); err != nil {
+			errs = append(errs, fmt.Errorf("WACLI_VOICEMAIL_FORWARD_TO: %w", err))
+		}
+	}
+	for _, c := range v.Chats {
+		if _, err := wa.ParseUserOrJID(c); err != nil {
+			errs = append(errs, fmt.Errorf("WACLI_VOICEMAIL_CHATS: %q: %w", c, err))
+		}
+	}
+	if len(v.Email) > 0 {
+		if _, _, err := net.SplitHostPort(v.SMTP.Addr); err != nil {
+			errs = append(errs, fmt.Errorf("WACLI_VOICEMAIL_EMAIL needs WACLI_SMTP_ADDR as host:port"))
+		}
+		if v.SMTP.From == "" {
+			errs = append(errs, fmt.Errorf("WACLI_VOICEMAIL_EMAIL needs WACLI_SMTP_FROM"))
+		}
+	}
+	return errs
+}
+
+var (
+	// voicemailLookupWait bounds how long a voicemail waits for its message
+	// to be stored by sync, which handles the same event.
+	voicemailLookupWait = 10 * time.Second
+	transcribeVoicemail = ai.TranscribeAudio
+	sendMail            = smtp.SendMail
+)
+
+// RunVoicemail forwards incoming voice notes as configured until ctx is
+// cancelled. It returns right away when voicemail is off. Messages are only
+// seen while the server follows the live connection.
+func (a *App) RunVoicemail(ctx context.Context, v Voicemail) {
+	if !v.Enabled() {
+		return
+	}
+	chats := map[string]bool{}
+	for _, c := range v.Chats {
+		if jid, err := wa.ParseUserOrJID(c); err == nil {
+			chats[jid.String()] = true
+		}
+	}
+	events, unsubscribe := a.events.Subscribe(64)
+	defer unsubscribe()
+
+	// Transcription takes a while; a few voicemails run at once.
+	slots := make(chan struct{}, 2)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case evt, ok := <-events:
+			if !ok {
+				return
+			}
+			if !isVoicemail(evt, chats) {
+				continue
+			}
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func() {
+				defer func() { <-slots }()
+				if err := a.forwardVoicemail(ctx, v, evt); err != nil {
+					fmt.Fprintf(os.Stderr, "voicemail: %s/%v: %v\n", evt.Chat, evt.Data["id"], err)
+				}
+			}()
+		}
+	}
+}
+
+// isVoicemail reports whether evt is an incoming voice note in one of chats,
+// or in any direct chat when chats is empty.
+func isVoicemail(evt Event, chats map[string]bool) bool {
+	if evt.Type != EventMessage || evt.Data["media_type"] != "audio" {
+		return false
+	}
+	if fromMe, _ := evt.Data["from_me"].(bool); fromMe {
+		return false
+	}
+	if len(chats) > 0 {
+		return chats[evt.Chat]
+	}
+	jid, err := types.ParseJID(evt.Chat)
+	return err == nil && (jid.Server == types.DefaultUserServer || jid.Server == types.HiddenUserServer)
+}
+
+func (a *App) forwardVoicemail(ctx context.Context, v Voicemail, evt Event) error {
+	msgID, _ := evt.Data["id"].(string)
+	info, err := a.waitForMedia(ctx, evt.Chat, msgID)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	if _, err := a.DownloadMedia(ctx, info); err != nil {
+		return fmt.Errorf("download: %w", err)
+	}
+	info, err = a.db.GetMediaDownloadInfo(evt.Chat, msgID)
+	if err != nil {
+		return err
+	}
+	r, _, err := a.OpenMedia(ctx, info)
+	if err != nil {
+		return err
+	}
+	audio, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return err
+	}
+
+	transcript := ""
+	if v.GroqAPIKey != "" && v.Privacy.Allowed(evt.Chat) {
+		if transcript, err = transcribeVoicemail(audio, v.GroqAPIKey); err != nil {
+			// Still forward the recording; it matters more than the text.
+			fmt.Fprintf(os.Stderr, "voicemail: transcribe %s/%s: %v\n", evt.Chat, msgID, err)
+		}
+		transcript = strings.TrimSpace(transcript)
+	}
+
+	caller := a.voicemailCaller(ctx, evt)
+	var errs []error
+	if v.ForwardTo != "" {
+		if err := a.forwardVoicemailToChat(ctx, v.ForwardTo, info, caller, transcript, evt.Timestamp); err != nil {
+			errs = append(errs, fmt.Errorf("forward: %w", err))
+		}
+	}
+	if len(v.Email) > 0 {
+		if err := mailVoicemail(v, info, audio, caller, transcript, evt.Timestamp); err != nil {
+			errs = append(errs, fmt.Errorf("email: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// waitForMedia returns the stored media metadata of a message, waiting
+// briefly for sync to store it.
+func (a *App) waitForMedia(ctx context.Context, chat, msgID string) (store.MediaDownloadInfo, error) {
+	deadline := time.Now().Add(voicemailLookupWait)
+	for {
+		info, err := a.db.GetMediaDownloadInfo(chat, msgID)
+		if err == nil && HasDownloadableMedia(info) {
+			return info, nil
+		}
+		if time.Now().After(deadline) {
+			if err == nil {
+				err = ErrNoMedia
+			}
+			return store.MediaDownloadInfo{}, fmt.Errorf("message not stored: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return store.MediaDownloadInfo{}, ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// voicemailCaller names the sender as "Name (+phone)".
+func (a *App) voicemailCaller(ctx context.Context, evt Event) string {
+	phone := evt.Sender
+	if jid, err := types.ParseJID(evt.Sender); err == nil && jid.Server == types.DefaultUserServer {
+		phone = "+" + jid.User
+	}
+	name, _ := evt.Data["push_name"].(string)
+	if jid, err := types.ParseJID(evt.Chat); err == nil && a.wa != nil {
+		if n := a.wa.ResolveChatName(ctx, jid, name); n != "" && n != jid.String() {
+			name = n
+		}
+	}
+	if name == "" {
+		return phone
+	}
+	return fmt.Sprintf("%s (%s)", name, phone)
+}
+
+func voicemailText(caller, transcript string, at time.Time) string {
+	text := fmt.Sprintf("Voicemail from %s at %s", caller, at.UTC().Format("2006-01-02 15:04 UTC"))
+	if transcript != "" {
+		text += "\n\n\"" + transcript + "\""
+	} else {
+		text += "\n\n(no transcript)"
+	}
+	return text
+}
+
+// forwardVoicemailToChat sends the transcript, then the recording itself,
+// reusing the uploaded media so nothing is uploaded again.
+func (a *App) forwardVoicemailToChat(ctx context.Context, to string, info store.MediaDownloadInfo, caller, transcript string, at time.Time) error {
+	jid, err := wa.ParseUserOrJID(to)
+	if err != nil {
+		return err
+	}
+	if a.wa == nil || !a.wa.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+	text := "🎙️ " + voicemailText(caller, transcript, at)
+	msgID, err := a.wa.SendText(ctx, jid, text)
+	if err != nil {
+		return err
+	}
+	a.storeSentText(ctx, jid, string(msgID), text, time.Now().UTC())
+
+	mimeType := info.MimeType
+	if mimeType == "" {
+		mimeType = "audio/ogg; codecs=opus"
+	}
+	_, err = a.wa.SendProtoMessage(ctx, jid, &waProto.Message{
+		AudioMessage: &waProto.AudioMessage{
+			DirectPath:    proto.String(info.DirectPath),
+			MediaKey:      info.MediaKey,
+			FileEncSHA256: info.FileEncSHA256,
+			FileSHA256:    info.FileSHA256,
+			FileLength:    proto.Uint64(info.FileLength),
+			Mimetype:      proto.String(mimeType),
+			PTT:           proto.Bool(true),
+			ContextInfo:   &waProto.ContextInfo{IsForwarded: proto.Bool(true)},
+		},
+	})
+	return err
+}
+
+// mailVoicemail emails the transcript with the recording attached.
+func mailVoicemail(v Voicemail, info store.MediaDownloadInfo, audio []byte, caller, transcript string, at time.Time) error {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	subject := mime.QEncoding.Encode("utf-8", "Voicemail from "+caller)
+	fmt.Fprintf(&body, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=%q\r\n\r\n",
+		v.SMTP.From, strings.Join(v.Email, ", "), subject, at.Format(time.RFC1123Z), mw.Boundary())
+
+	text, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	if err != nil {
+		return err
+	}
+	io.WriteString(text, voicemailText(caller, transcript, at)+"\r\n")
+
+	mimeType := info.MimeType
+	if mimeType == "" {
+		mimeType = "audio/ogg"
+	}
+	attachment, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {mimeType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {fmt.Sprintf(`attachment; filename="voicemail-%s.ogg"`, info.MsgID)},
+	})
+	if err != nil {
+		return err
+	}
+	enc := base64.NewEncoder(base64.StdEncoding, &lineWrapper{w: attachment})
+	enc.Write(audio)
+	enc.Close()
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if v.SMTP.Username != "" {
+		host, _, _ := net.SplitHostPort(v.SMTP.Addr)
+		auth = smtp.PlainAuth("", v.SMTP.Username, v.SMTP.Password, host)
+	}
+	return sendMail(v.SMTP.Addr, auth, v.SMTP.From, v.Email, body.Bytes())
+}
+
+// lineWrapper breaks base64 output into 76-character lines as MIME
+// requires.
+type lineWrapper struct {
+	w   io.Writer
+	col int
+}
+
+func (l *lineWrapper) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		chunk := min(len(p), 76-l.col)
+		if _, err := l.w.Write(p[:chunk]); err != nil {
+			return n, err
+		}
+		n += chunk
+		l.col += chunk
+		p = p[chunk:]
+		if l.col == 76 {
+			if _, err := l.w.Write([]byte("\r\n")); err != nil {
+				return n, err
+			}
+			l.col = 0
+		}
+	}
+	return n, nil
+}
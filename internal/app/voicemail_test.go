@@ -0,0 +1,112 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+)
+
+func TestIsVoicemail(t *testing.T) {
+	audio := func(chat string, fromMe bool) Event {
+		return Event{Type: EventMessage, Chat: chat, Data: map[string]any{"media_type": "audio", "from_me": fromMe}}
+	}
+	all := map[string]bool{}
+	if !isVoicemail(audio("123@s.whatsapp.net", false), all) {
+		t.Fatalf("expected a direct voice note to be a voicemail")
+	}
+	if isVoicemail(audio("123@g.us", false), all) || isVoicemail(audio("123@s.whatsapp.net", true), all) {
+		t.Fatalf("expected group and outgoing voice notes to be skipped")
+	}
+	if isVoicemail(Event{Type: EventMessage, Chat: "123@s.whatsapp.net", Data: map[string]any{"media_type": "image"}}, all) {
+		t.Fatalf("expected images to be skipped")
+	}
+	only := map[string]bool{"123@g.us": true}
+	if !isVoicemail(audio("123@g.us", false), only) || isVoicemail(audio("456@s.whatsapp.net", false), only) {
+		t.Fatalf("expected the chat list to decide")
+	}
+}
+
+func TestForwardVoicemail(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	f.connected = true
+	a.wa = f
+
+	chat := "5511999990000@s.whatsapp.net"
+	at := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := a.db.UpsertChat(chat, "dm", "Alice", at); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	if err := a.db.UpsertMessage(store.UpsertMessageParams{
+		ChatJID:       chat,
+		MsgID:         "vm1",
+		SenderJID:     chat,
+		SenderName:    "Alice",
+		Timestamp:     at,
+		MediaType:     "audio",
+		MimeType:      "audio/ogg; codecs=opus",
+		DirectPath:    "/direct/path",
+		MediaKey:      []byte{1, 2, 3},
+		FileSHA256:    []byte{4, 5},
+		FileEncSHA256: []byte{6, 7},
+		FileLength:    4,
+	}); err != nil {
+		t.Fatalf("UpsertMessage: %v", err)
+	}
+
+	oldTranscribe, oldMail := transcribeVoicemail, sendMail
+	t.Cleanup(func() { transcribeVoicemail, sendMail = oldTranscribe, oldMail })
+	transcribeVoicemail = func(audio []byte, apiKey string) (string, error) {
+		if string(audio) != "test" || apiKey != "key" {
+			t.Errorf("transcribe(%q, %q)", audio, apiKey)
+		}
+		return " please call me back ", nil
+	}
+	var mail string
+	var rcpt []string
+	sendMail = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		mail, rcpt = string(msg), to
+		return nil
+	}
+
+	v := Voicemail{
+		ForwardTo:  "120363000000000000@g.us",
+		Email:      []string{"ops@example.com"},
+		SMTP:       SMTPOptions{Addr: "smtp.example.com:587", From: "wacli@example.com"},
+		GroqAPIKey: "key",
+	}
+	evt := Event{Type: EventMessage, Chat: chat, Sender: chat, Timestamp: at, Data: map[string]any{"id": "vm1", "media_type": "audio", "push_name": "Alice"}}
+	if err := a.forwardVoicemail(context.Background(), v, evt); err != nil {
+		t.Fatalf("forwardVoicemail: %v", err)
+	}
+
+	if len(f.sent) != 1 || !strings.Contains(f.sent[0], "Voicemail from Alice (+5511999990000)") || !strings.Contains(f.sent[0], `"please call me back"`) {
+		t.Fatalf("sent = %q", f.sent)
+	}
+	if len(f.sentProtos) != 1 {
+		t.Fatalf("expected the recording to be forwarded, got %d messages", len(f.sentProtos))
+	}
+	am := f.sentProtos[0].GetAudioMessage()
+	if am == nil || am.GetDirectPath() != "/direct/path" || !am.GetPTT() || !am.GetContextInfo().GetIsForwarded() {
+		t.Fatalf("forwarded = %+v", f.sentProtos[0])
+	}
+	for _, to := range f.sentTo {
+		if to.String() != v.ForwardTo {
+			t.Fatalf("sent to %s", to)
+		}
+	}
+
+	if len(rcpt) != 1 || rcpt[0] != "ops@example.com" {
+		t.Fatalf("mail recipients = %q", rcpt)
+	}
+	for _, want := range []string{"please call me back", `filename="voicemail-vm1.ogg"`, base64.StdEncoding.EncodeToString([]byte("test"))} {
+		if !strings.Contains(mail, want) {
+			t.Fatalf("mail lacks %q:\n%s", want, mail)
+		}
+	}
+}
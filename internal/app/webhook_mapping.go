@@ -0,0 +1,262 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WebhookMapping adapts an arbitrary JSON payload to a generic webhook
+// message. Each field is one of:
+//
+//   - a path into the payload: "$.incident.title", "$.alerts[0].labels.phone",
+//     "$['dotted.key']"; [*] or .* match every element and are joined by ", "
+//   - a template embedding paths: "{{$.status}}: {{$.incident.title}}"
+//   - a literal: "120363000000000000@g.us"
+type WebhookMapping struct {
+	To          string `json:"to,omitempty"`
+	Message     string `json:"message"`
+	CallbackURL string `json:"callback_url,omitempty"`
+}
+
+// MappedWebhook is a payload after a WebhookMapping was applied to it.
+type MappedWebhook struct {
+	To          string
+	Message     string
+	CallbackURL string
+}
+
+// ParseWebhookMapping decodes a mapping and checks that every expression
+// in it compiles.
+func ParseWebhookMapping(raw string) (WebhookMapping, error) {
+	var m WebhookMapping
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&m); err != nil {
+		return WebhookMapping{}, err
+	}
+	if strings.TrimSpace(m.Message) == "" {
+		return WebhookMapping{}, fmt.Errorf("message is required")
+	}
+	for field, expr := range map[string]string{"to": m.To, "message": m.Message, "callback_url": m.CallbackURL} {
+		if _, err := compileMappingExpr(expr); err != nil {
+			return WebhookMapping{}, fmt.Errorf("%s: %w", field, err)
+		}
+	}
+	return m, nil
+}
+
+// Apply evaluates the mapping against a JSON payload. Paths that match
+// nothing yield empty strings.
+func (m WebhookMapping) Apply(payload []byte) (MappedWebhook, error) {
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return MappedWebhook{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+	eval := func(expr string) string {
+		e, err := compileMappingExpr(expr)
+		if err != nil {
+			return "" // rejected by ParseWebhookMapping
+		}
+		return e.eval(v)
+	}
+	return MappedWebhook{
+		To:          strings.TrimSpace(eval(m.To)),
+		Message:     eval(m.Message),
+		CallbackURL: strings.TrimSpace(eval(m.CallbackURL)),
+	}, nil
+}
+
+// mappingExpr is a compiled field: literal text interleaved with paths.
+type mappingExpr struct {
+	parts []mappingPart
+}
+
+type mappingPart struct {
+	literal string
+	path    []pathStep // nil for literal parts
+}
+
+func compileMappingExpr(expr string) (mappingExpr, error) {
+	var e mappingExpr
+	if strings.HasPrefix(strings.TrimSpace(expr), "$") && !strings.Contains(expr, "{{") {
+		p, err := compileJSONPath(strings.TrimSpace(expr))
+		if err != nil {
+			return e, err
+		}
+		e.parts = append(e.parts, mappingPart{path: p})
+		return e, nil
+	}
+	rest := expr
+	for {
+		i := strings.Index(rest, "{{")
+		if i < 0 {
+			break
+		}
+		j := strings.Index(rest[i:], "}}")
+		if j < 0 {
+			return e, fmt.Errorf("unclosed {{ in %q", expr)
+		}
+		if i > 0 {
+			e.parts = append(e.parts, mappingPart{literal: rest[:i]})
+		}
+		p, err := compileJSONPath(strings.TrimSpace(rest[i+2 : i+j]))
+		if err != nil {
+			return e, err
+		}
+		e.parts = append(e.parts, mappingPart{path: p})
+		rest = rest[i+j+2:]
+	}
+	if rest != "" {
+		e.parts = append(e.parts, mappingPart{literal: rest})
+	}
+	return e, nil
+}
+
+func (e mappingExpr) eval(v any) string {
+	var sb strings.Builder
+	for _, p := range e.parts {
+		if p.path == nil {
+			sb.WriteString(p.literal)
+			continue
+		}
+		var vals []string
+		for _, m := range p.path[0].match(v, p.path[1:]) {
+			if s := mappingString(m); s != "" {
+				vals = append(vals, s)
+			}
+		}
+		sb.WriteString(strings.Join(vals, ", "))
+	}
+	return sb.String()
+}
+
+// pathStep is one segment of a path. The leading $ compiles to a step that
+// matches the root itself.
+type pathStep struct {
+	root     bool
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+func compileJSONPath(s string) ([]pathStep, error) {
+	if !strings.HasPrefix(s, "$") {
+		return nil, fmt.Errorf("path %q must start with $", s)
+	}
+	steps := []pathStep{{root: true}}
+	rest := s[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			n := strings.IndexAny(rest, ".[")
+			if n < 0 {
+				n = len(rest)
+			}
+			key := rest[:n]
+			rest = rest[n:]
+			switch key {
+			case "":
+				return nil, fmt.Errorf("empty key in path %q", s)
+			case "*":
+				steps = append(steps, pathStep{wildcard: true})
+			default:
+				steps = append(steps, pathStep{key: key})
+			}
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in path %q", s)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+			switch {
+			case inner == "*":
+				steps = append(steps, pathStep{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				steps = append(steps, pathStep{key: inner[1 : len(inner)-1]})
+			default:
+				i, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index [%s] in path %q", inner, s)
+				}
+				steps = append(steps, pathStep{index: i, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q in path %q", rest[0], s)
+		}
+	}
+	return steps, nil
+}
+
+// match returns the values selected by this step and the ones after it.
+func (st pathStep) match(v any, next []pathStep) []any {
+	var hits []any
+	switch {
+	case st.root:
+		hits = []any{v}
+	case st.wildcard:
+		switch t := v.(type) {
+		case []any:
+			hits = t
+		case map[string]any:
+			keys := make([]string, 0, len(t))
+			for k := range t {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				hits = append(hits, t[k])
+			}
+		}
+	case st.isIndex:
+		if a, ok := v.([]any); ok {
+			i := st.index
+			if i < 0 {
+				i += len(a) // negative indexes count from the end
+			}
+			if i >= 0 && i < len(a) {
+				hits = []any{a[i]}
+			}
+		}
+	default:
+		if o, ok := v.(map[string]any); ok {
+			if x, ok := o[st.key]; ok {
+				hits = []any{x}
+			}
+		}
+	}
+	if len(next) == 0 {
+		return hits
+	}
+	var out []any
+	for _, h := range hits {
+		out = append(out, next[0].match(h, next[1:])...)
+	}
+	return out
+}
+
+// mappingString renders a matched value: strings as is, objects and arrays
+// as compact JSON, null as nothing.
+func mappingString(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		b, _ := json.Marshal(t)
+		return string(b)
+	}
+}
@@ -0,0 +1,55 @@
+package app
+
+import "testing"
+
+func TestWebhookMapping(t *testing.T) {
+	m, err := ParseWebhookMapping(`{
+		"to": "$.labels.phone",
+		"message": "{{ $.status }}: {{$.incident.title}} ({{$.alerts[*].host}}, last {{$.alerts[-1].host}})",
+		"callback_url": "https://hooks.example.com/{{$['incident.id']}}"
+	}`)
+	if err != nil {
+		t.Fatalf("ParseWebhookMapping: %v", err)
+	}
+	got, err := m.Apply([]byte(`{
+		"status": "triggered",
+		"incident": {"title": "Disk full", "severity": 2},
+		"incident.id": 42,
+		"labels": {"phone": " 5511999999999 "},
+		"alerts": [{"host": "db-1"}, {"host": null}, {"host": "db-2"}]
+	}`))
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	want := MappedWebhook{
+		To:          "5511999999999",
+		Message:     "triggered: Disk full (db-1, db-2, last db-2)",
+		CallbackURL: "https://hooks.example.com/42",
+	}
+	if got != want {
+		t.Fatalf("Apply = %+v, want %+v", got, want)
+	}
+
+	m, _ = ParseWebhookMapping(`{"message": "$.incident"}`)
+	if got, _ := m.Apply([]byte(`{"incident": {"id": 1}}`)); got.Message != `{"id":1}` || got.To != "" {
+		t.Fatalf("object value = %+v", got)
+	}
+	if got, _ := m.Apply([]byte(`{}`)); got.Message != "" {
+		t.Fatalf("missing path = %q", got.Message)
+	}
+	if _, err := m.Apply([]byte(`not json`)); err == nil {
+		t.Fatalf("expected invalid JSON error")
+	}
+
+	for _, bad := range []string{
+		`{"to": "$.a"}`,
+		`{"message": "$.a[x]"}`,
+		`{"message": "{{$.a"}`,
+		`{"message": "{{title}}"}`,
+		`{"message": "$.a", "extra": "$.b"}`,
+	} {
+		if _, err := ParseWebhookMapping(bad); err == nil {
+			t.Errorf("expected %s to be rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Webhook delivery tuning. Failed attempts are retried after
+// webhookBaseDelay, 2x, 4x, ... (capped at webhookMaxDelay); after
+// webhookMaxAttempts the delivery moves to the dead-letter table.
+var (
+	webhookMaxAttempts  = 8
+	webhookBaseDelay    = 5 * time.Second
+	webhookMaxDelay     = 10 * time.Minute
+	webhookPollInterval = time.Second
+	webhookWorkers      = 4
+	webhookHTTPClient   = &http.Client{Timeout: 15 * time.Second}
+
+	// webhookAlertAfter consecutive failed attempts raise an
+	// EventWebhookFailure alert (once per failure streak).
+	webhookAlertAfter = 5
+	// webhookAttemptRetention is how long the delivery log is kept.
+	webhookAttemptRetention = 7 * 24 * time.Hour
+	// webhookSnippetLen caps the stored start of response bodies.
+	webhookSnippetLen = 512
+)
+
+// RunWebhooks queues events from the event bus for every matching webhook
+// subscription and delivers the queue until ctx is cancelled. The queue is
+// persistent, so deliveries pending at shutdown are retried on the next start.
+func (a *App) RunWebhooks(ctx context.Context) {
+	events, unsubscribe := a.events.Subscribe(1024)
+	defer unsubscribe()
+
+	go a.runWebhookQueue(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case evt, ok := <-events:
+			if !ok {
+				return
+			}
+			a.enqueueWebhooks(evt)
+		}
+	}
+}
+
+// runWebhookQueue delivers the queue until ctx is cancelled. It runs apart
+// from the event loop, so slow endpoints do not hold up queueing events.
+func (a *App) runWebhookQueue(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	var lastPrune time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.processWebhookQueue(ctx)
+			if time.Since(lastPrune) > time.Hour {
+				lastPrune = time.Now()
+				if _, err := a.db.PruneWebhookAttempts(time.Now().UTC().Add(-webhookAttemptRetention)); err != nil {
+					fmt.Fprintf(os.Stderr, "webhooks: prune attempts: %v\n", err)
+				}
+				if _, err := a.db.DeleteOrphanedWebhookDeliveries(); err != nil {
+					fmt.Fprintf(os.Stderr, "webhooks: drop orphaned deliveries: %v\n", err)
+				}
+			}
+		}
+	}
+}
+
+// chatMuted reports whether chat is muted on the phone, per the synced app
+// state. Unknown chats and lookup errors count as not muted.
+func (a *App) chatMuted(chat string) bool {
+	jid, err := types.ParseJID(chat)
+	if err != nil || chat == "" {
+		return false
+	}
+	settings, err := a.wa.GetChatSettings(context.Background(), jid)
+	return err == nil && settings.Found && settings.MutedUntil.After(time.Now())
+}
+
+// enqueueWebhooks stores evt once per enabled subscription that matches it.
+func (a *App) enqueueWebhooks(evt Event) {
+	hooks, err := a.db.ListWebhooks(true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "webhooks: list: %v\n", err)
+		return
+	}
+	var body []byte
+	var muted, mutedKnown bool
+	for _, h := range hooks {
+		if !webhookFilter(h).Match(evt) {
+			continue
+		}
+		// A failing webhook is not told about its own failures.
+		if evt.Type == EventWebhookFailure && evt.Data["webhook_id"] == h.ID {
+			continue
+		}
+		if h.SkipMuted {
+			if !mutedKnown {
+				muted, mutedKnown = a.chatMuted(evt.Chat), true
+			}
+			if muted {
+				continue
+			}
+		}
+		if body == nil {
+			if evt.Type == EventReceipt {
+				evt = a.withReceiptDetail(evt)
+			}
+			if body, err = json.Marshal(evt); err != nil {
+				fmt.Fprintf(os.Stderr, "webhooks: encode event: %v\n", err)
+				return
+			}
+		}
+		if _, err := a.db.EnqueueWebhookDelivery(h.ID, evt.Type, body); err != nil {
+			fmt.Fprintf(os.Stderr, "webhooks: enqueue for %s: %v\n", h.URL, err)
+		}
+	}
+}
+
+func webhookFilter(h store.Webhook) EventFilter {
+	return ParseEventFilter(strings.Join(h.Events, ","), strings.Join(h.Chats, ","))
+}
+
+// processWebhookQueue attempts every due delivery once. Deliveries of
+// disabled subscriptions stay queued until the subscription is enabled again;
+// those of deleted ones are dropped by runWebhookQueue.
+func (a *App) processWebhookQueue(ctx context.Context) {
+	due, err := a.db.DueWebhookDeliveries(time.Now().UTC(), 100)
+	if err != nil || len(due) == 0 {
+		return
+	}
+	hooks, err := a.db.ListWebhooks(true)
+	if err != nil {
+		return
+	}
+	byID := make(map[int64]store.Webhook, len(hooks))
+	for _, h := range hooks {
+		byID[h.ID] = h
+	}
+
+	slots := make(chan struct{}, webhookWorkers)
+	var wg sync.WaitGroup
+	for _, d := range due {
+		h, ok := byID[d.WebhookID]
+		if !ok {
+			continue
+		}
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(h store.Webhook, d store.WebhookDelivery) {
+			defer func() { <-slots; wg.Done() }()
+			a.attemptWebhookDelivery(ctx, h, d)
+		}(h, d)
+	}
+	wg.Wait()
+}
+
+func (a *App) attemptWebhookDelivery(ctx context.Context, h store.Webhook, d store.WebhookDelivery) {
+	started := time.Now()
+	resp, err := postWebhook(ctx, h, d.EventType, d.Payload)
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	a.logWebhookAttempt(h, d, resp, err, time.Since(started))
+	if err == nil {
+		_ = a.db.CompleteWebhookDelivery(d.ID)
+		return
+	}
+	if !resp.Retry || d.Attempts+1 >= webhookMaxAttempts {
+		fmt.Fprintf(os.Stderr, "webhooks: delivery to %s failed: %v\n", h.URL, err)
+		_ = a.db.DeadLetterWebhookDelivery(d.ID, err.Error())
+		return
+	}
+	_ = a.db.RetryWebhookDelivery(d.ID, err.Error(), time.Now().UTC().Add(webhookBackoff(d.Attempts+1)))
+}
+
+// logWebhookAttempt records an attempt in the delivery log and raises an
+// alert when a subscription reaches webhookAlertAfter failures in a row.
+func (a *App) logWebhookAttempt(h store.Webhook, d store.WebhookDelivery, resp webhookResponse, err error, latency time.Duration) {
+	var ref struct {
+		Chat string `json:"chat"`
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	_ = json.Unmarshal(d.Payload, &ref)
+	attempt := store.WebhookAttempt{
+		WebhookID:       h.ID,
+		DeliveryID:      d.ID,
+		EventType:       d.EventType,
+		ChatJID:         ref.Chat,
+		Payload:         d.Payload,
+		Attempt:         d.Attempts + 1,
+		Success:         err == nil,
+		StatusCode:      resp.StatusCode,
+		Latency:         latency,
+		ResponseSnippet: resp.Snippet,
+		AttemptedAt:     time.Now().UTC(),
+	}
+	if d.EventType == EventMessage {
+		attempt.MsgID = ref.Data.ID
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	if _, err := a.db.RecordWebhookAttempt(attempt); err != nil {
+		fmt.Fprintf(os.Stderr, "webhooks: log attempt: %v\n", err)
+	}
+
+	a.webhookMu.Lock()
+	if a.webhookStreaks == nil {
+		a.webhookStreaks = map[int64]int{}
+	}
+	streak := 0
+	if err != nil {
+		streak = a.webhookStreaks[h.ID] + 1
+	}
+	a.webhookStreaks[h.ID] = streak
+	a.webhookMu.Unlock()
+
+	if streak == webhookAlertAfter {
+		fmt.Fprintf(os.Stderr, "webhooks: %s failed %d times in a row: %v\n", h.URL, streak, err)
+		a.events.Publish(Event{Type: EventWebhookFailure, Timestamp: attempt.AttemptedAt, Data: map[string]any{
+			"webhook_id":           h.ID,
+			"url":                  h.URL,
+			"consecutive_failures": streak,
+			"status_code":          resp.StatusCode,
+			"error":                attempt.Error,
+		}})
+	}
+}
+
+// webhookBackoff returns the delay after the given number of failed attempts.
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookBaseDelay
+	for i := 1; i < attempts && delay < webhookMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > webhookMaxDelay {
+		delay = webhookMaxDelay
+	}
+	return delay
+}
+
+// webhookResponse describes the receiver's answer to one attempt.
+type webhookResponse struct {
+	StatusCode int
+	Snippet    string
+	// Retry reports whether a failed attempt is worth repeating.
+	Retry bool
+}
+
+func postWebhook(ctx context.Context, h store.Webhook, eventType string, body []byte) (webhookResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return webhookResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "wacli-webhook")
+	req.Header.Set("X-Wacli-Event", eventType)
+	req.Header.Set("X-Wacli-Webhook-Id", fmt.Sprint(h.ID))
+	// Signed per attempt so receivers can reject stale timestamps.
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("X-Wacli-Timestamp", ts)
+	req.Header.Set("X-Wacli-Signature", "sha256="+signWebhook(h.Secret, ts, body))
+
+	resp, err := webhookHTTPClient.Do(req)
+	if err != nil {
+		return webhookResponse{Retry: true}, err
+	}
+	defer resp.Body.Close()
+	snippet := make([]byte, webhookSnippetLen)
+	n, _ := io.ReadFull(resp.Body, snippet)
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	out := webhookResponse{StatusCode: resp.StatusCode, Snippet: strings.ToValidUTF8(string(snippet[:n]), "")}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return out, nil
+	}
+	out.Retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return out, fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
+
+// signWebhook returns the hex HMAC-SHA256 of "<timestamp>.<body>".
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,264 @@
+package app
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/steipete/wacli/internal/store"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func createTestWebhook(t *testing.T, a *App, url string, events ...string) store.Webhook {
+	t.Helper()
+	h, err := a.db.CreateWebhook(store.CreateWebhookParams{URL: url, Events: events})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	return h
+}
+
+// drainWebhookQueue processes the queue until it is empty, ignoring backoff.
+func drainWebhookQueue(t *testing.T, a *App, h store.Webhook) {
+	t.Helper()
+	for i := 0; i < 20; i++ {
+		if n, _ := a.db.CountWebhookQueue(h.ID); n == 0 {
+			return
+		}
+		due, _ := a.db.DueWebhookDeliveries(time.Now().Add(24*time.Hour), 10)
+		for _, d := range due {
+			a.attemptWebhookDelivery(context.Background(), h, d)
+		}
+	}
+	t.Fatalf("queue not drained")
+}
+
+func TestWebhookQueueRetriesServerErrors(t *testing.T) {
+	a := newTestApp(t)
+
+	var calls atomic.Int32
+	var got Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if r.Header.Get("X-Wacli-Event") != EventMessage {
+			t.Errorf("missing event header")
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	h := createTestWebhook(t, a, srv.URL, EventMessage)
+	evt := Event{Type: EventMessage, Chat: "1@s.whatsapp.net", Data: map[string]any{"text": "hi"}}
+	a.enqueueWebhooks(evt)
+	a.enqueueWebhooks(Event{Type: EventReceipt, Chat: "1@s.whatsapp.net"})
+	if n, _ := a.db.CountWebhookQueue(h.ID); n != 1 {
+		t.Fatalf("expected only the matching event to be queued, got %d", n)
+	}
+
+	drainWebhookQueue(t, a, h)
+	if calls.Load() != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls.Load())
+	}
+	if got.Chat != evt.Chat || got.Data["text"] != "hi" {
+		t.Fatalf("unexpected payload: %+v", got)
+	}
+	if failures, _ := a.db.ListWebhookFailures(h.ID, 10); len(failures) != 0 {
+		t.Fatalf("unexpected dead letters: %+v", failures)
+	}
+}
+
+func TestWebhookQueueDeadLettersClientErrors(t *testing.T) {
+	a := newTestApp(t)
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	h := createTestWebhook(t, a, srv.URL)
+	a.enqueueWebhooks(Event{Type: EventMessage})
+	drainWebhookQueue(t, a, h)
+
+	if calls.Load() != 1 {
+		t.Fatalf("expected a single attempt, got %d", calls.Load())
+	}
+	failures, _ := a.db.ListWebhookFailures(h.ID, 10)
+	if len(failures) != 1 || failures[0].LastError != "unexpected status 404" {
+		t.Fatalf("expected dead letter, got %+v", failures)
+	}
+}
+
+func TestWebhookQueueGivesUpAfterMaxAttempts(t *testing.T) {
+	oldMax := webhookMaxAttempts
+	webhookMaxAttempts = 3
+	t.Cleanup(func() { webhookMaxAttempts = oldMax })
+
+	a := newTestApp(t)
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	h := createTestWebhook(t, a, srv.URL)
+	a.enqueueWebhooks(Event{Type: EventMessage})
+	drainWebhookQueue(t, a, h)
+
+	if calls.Load() != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls.Load())
+	}
+	if failures, _ := a.db.ListWebhookFailures(h.ID, 10); len(failures) != 1 || failures[0].Attempts != 3 {
+		t.Fatalf("unexpected dead letters: %+v", failures)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	if webhookBackoff(1) != webhookBaseDelay || webhookBackoff(3) != 4*webhookBaseDelay {
+		t.Fatalf("unexpected backoff: %v %v", webhookBackoff(1), webhookBackoff(3))
+	}
+	if webhookBackoff(50) != webhookMaxDelay {
+		t.Fatalf("expected backoff to be capped, got %v", webhookBackoff(50))
+	}
+}
+
+func TestWebhookFilterUsesSubscription(t *testing.T) {
+	f := webhookFilter(store.Webhook{Events: []string{"message"}, Chats: []string{"1@s.whatsapp.net"}})
+	if !f.Match(Event{Type: EventMessage, Chat: "1@s.whatsapp.net"}) {
+		t.Fatalf("expected match")
+	}
+	if f.Match(Event{Type: EventReceipt, Chat: "1@s.whatsapp.net"}) || f.Match(Event{Type: EventMessage, Chat: "2@s.whatsapp.net"}) {
+		t.Fatalf("expected mismatch")
+	}
+}
+
+func TestPostWebhookSignsPayload(t *testing.T) {
+	var sig, ts string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sig = r.Header.Get("X-Wacli-Signature")
+		ts = r.Header.Get("X-Wacli-Timestamp")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	h := store.Webhook{ID: 7, URL: srv.URL, Secret: "topsecret"}
+	if _, err := postWebhook(context.Background(), h, EventMessage, []byte(`{"type":"message"}`)); err != nil {
+		t.Fatalf("postWebhook: %v", err)
+	}
+
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || time.Since(time.Unix(sec, 0)) > time.Minute {
+		t.Fatalf("unexpected timestamp %q", ts)
+	}
+	mac := hmac.New(sha256.New, []byte("topsecret"))
+	mac.Write([]byte(ts + "." + string(body)))
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); sig != want {
+		t.Fatalf("signature mismatch: got %q want %q", sig, want)
+	}
+}
+
+func TestEnqueueWebhooksSkipsMutedChats(t *testing.T) {
+	a := newTestApp(t)
+	f := newFakeWA()
+	a.wa = f
+
+	muted := types.NewJID("111", types.DefaultUserServer)
+	expired := types.NewJID("222", types.DefaultUserServer)
+	f.chatSettings[muted] = types.LocalChatSettings{Found: true, MutedUntil: time.Now().Add(time.Hour)}
+	f.chatSettings[expired] = types.LocalChatSettings{Found: true, MutedUntil: time.Now().Add(-time.Hour)}
+
+	all := createTestWebhook(t, a, "https://example.com/all", EventMessage)
+	quiet, err := a.db.CreateWebhook(store.CreateWebhookParams{URL: "https://example.com/quiet", Events: []string{EventMessage}, SkipMuted: true})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+
+	for _, chat := range []types.JID{muted, expired} {
+		a.enqueueWebhooks(Event{Type: EventMessage, Chat: chat.String()})
+	}
+	if n, _ := a.db.CountWebhookQueue(all.ID); n != 2 {
+		t.Fatalf("expected 2 deliveries without skip_muted, got %d", n)
+	}
+	if n, _ := a.db.CountWebhookQueue(quiet.ID); n != 1 {
+		t.Fatalf("expected the muted chat to be skipped, got %d deliveries", n)
+	}
+}
+
+func TestWebhookAttemptsAreLoggedAndAlerted(t *testing.T) {
+	oldAlert := webhookAlertAfter
+	webhookAlertAfter = 2
+	t.Cleanup(func() { webhookAlertAfter = oldAlert })
+
+	a := newTestApp(t)
+	var fail atomic.Bool
+	fail.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("upstream down"))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	alerts, unsubscribe := a.events.Subscribe(4)
+	defer unsubscribe()
+
+	h := createTestWebhook(t, a, srv.URL, EventMessage)
+	a.enqueueWebhooks(Event{Type: EventMessage, Chat: "1@s.whatsapp.net", Data: map[string]any{"id": "MSG1"}})
+	due, _ := a.db.DueWebhookDeliveries(time.Now().Add(time.Hour), 10)
+	for i := 0; i < 2; i++ {
+		a.attemptWebhookDelivery(context.Background(), h, due[0])
+		due[0].Attempts++
+	}
+
+	select {
+	case evt := <-alerts:
+		if evt.Type != EventWebhookFailure || evt.Data["webhook_id"] != h.ID || evt.Data["consecutive_failures"] != 2 {
+			t.Fatalf("unexpected alert: %+v", evt)
+		}
+	default:
+		t.Fatalf("expected a webhook failure alert")
+	}
+
+	failed := false
+	attempts, err := a.db.ListWebhookAttempts(store.ListWebhookAttemptsParams{WebhookID: h.ID, MsgID: "MSG1", Success: &failed})
+	if err != nil || len(attempts) != 2 {
+		t.Fatalf("ListWebhookAttempts = %+v, %v", attempts, err)
+	}
+	if at := attempts[0]; at.Attempt != 2 || at.StatusCode != http.StatusBadGateway || at.ResponseSnippet != "upstream down" || at.ChatJID != "1@s.whatsapp.net" {
+		t.Fatalf("unexpected attempt: %+v", at)
+	}
+
+	fail.Store(false)
+	a.attemptWebhookDelivery(context.Background(), h, due[0])
+	health, err := a.db.WebhookHealth(h.ID)
+	if err != nil || health.ConsecutiveFailures != 0 || health.LastSuccessAt.IsZero() {
+		t.Fatalf("WebhookHealth = %+v, %v", health, err)
+	}
+
+	// Retrying a logged attempt queues its payload again.
+	if _, err := a.db.RetryWebhookAttempt(h.ID, attempts[0].ID); err != nil {
+		t.Fatalf("RetryWebhookAttempt: %v", err)
+	}
+	if n, _ := a.db.CountWebhookQueue(h.ID); n != 1 {
+		t.Fatalf("expected the retried delivery to be queued, got %d", n)
+	}
+}
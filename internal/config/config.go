@@ -1,9 +1,14 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strconv"
+	"strings"
+
+	"github.com/steipete/wacli/internal/privacy"
 )
 
 type Config struct {
@@ -14,14 +19,38 @@ type Config struct {
 type AIConfig struct {
 	Enabled    bool
 	GroqAPIKey string
+	// ChatModel is the Groq chat model used for summaries.
+	ChatModel string
+	// SummaryMinSeconds enables a TL;DR for voice notes at least this long (0 disables).
+	SummaryMinSeconds int
+	// TranscribeVideo also transcribes the audio track of incoming videos.
+	TranscribeVideo bool
+	// FFmpegPath is the ffmpeg binary used to extract audio from videos.
+	FFmpegPath string
+	// Sentiment scores inbound text messages for sentiment and urgency.
+	Sentiment bool
+	// Privacy limits which chats and what content reach the AI provider.
+	Privacy privacy.Guard
 }
 
 func Load() *Config {
 	return &Config{
 		StoreDir: DefaultStoreDir(),
 		AI: AIConfig{
-			Enabled:    getEnvBool("WACLI_AI_ENABLED", false),
-			GroqAPIKey: os.Getenv("GROQ_API_KEY"),
+			Enabled:           getEnvBool("WACLI_AI_ENABLED", false),
+			GroqAPIKey:        os.Getenv("GROQ_API_KEY"),
+			ChatModel:         os.Getenv("WACLI_AI_CHAT_MODEL"),
+			SummaryMinSeconds: getEnvInt("WACLI_AI_SUMMARY_MIN_SECONDS", 60),
+			TranscribeVideo:   getEnvBool("WACLI_AI_TRANSCRIBE_VIDEO", false),
+			FFmpegPath:        getEnvString("WACLI_FFMPEG_PATH", "ffmpeg"),
+			Sentiment:         getEnvBool("WACLI_AI_SENTIMENT", false),
+			Privacy: privacy.Guard{
+				DenyChats:     getEnvList("WACLI_AI_DENY_CHATS"),
+				AllowChats:    getEnvList("WACLI_AI_ALLOW_CHATS"),
+				AllowlistOnly: getEnvBool("WACLI_AI_ALLOWLIST_ONLY", false),
+				RedactPhones:  getEnvBool("WACLI_AI_REDACT_PHONES", false),
+				RedactNames:   getEnvBool("WACLI_AI_REDACT_NAMES", false),
+			},
 		},
 	}
 }
@@ -42,3 +71,54 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+func getEnvString(key, defaultValue string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getEnvList(key string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Validate checks the AI settings and returns every problem found, so a
+// misconfiguration is reported at startup rather than on the first message.
+func (c *Config) Validate() []error {
+	var errs []error
+	ai := c.AI
+	if !ai.Enabled {
+		return nil
+	}
+	if ai.GroqAPIKey == "" {
+		errs = append(errs, fmt.Errorf("WACLI_AI_ENABLED requires GROQ_API_KEY"))
+	}
+	if ai.SummaryMinSeconds < 0 {
+		errs = append(errs, fmt.Errorf("WACLI_AI_SUMMARY_MIN_SECONDS must not be negative"))
+	}
+	if ai.TranscribeVideo {
+		if _, err := exec.LookPath(ai.FFmpegPath); err != nil {
+			errs = append(errs, fmt.Errorf("WACLI_AI_TRANSCRIBE_VIDEO needs ffmpeg: %w", err))
+		}
+	}
+	if ai.Privacy.AllowlistOnly && len(ai.Privacy.AllowChats) == 0 {
+		errs = append(errs, fmt.Errorf("WACLI_AI_ALLOWLIST_ONLY is set but WACLI_AI_ALLOW_CHATS is empty, so no chat reaches the AI"))
+	}
+	return errs
+}
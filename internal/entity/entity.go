@@ -0,0 +1,188 @@
+package entity
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	TypeDate           = "date"
+	TypeAmount         = "amount"
+	TypeAddress        = "address"
+	TypePostalCode     = "postal_code"
+	TypeTrackingNumber = "tracking_number"
+	TypeEmail          = "email"
+	TypeURL            = "url"
+)
+
+// Types lists every entity type Extract can return.
+var Types = []string{TypeDate, TypeAmount, TypeAddress, TypePostalCode, TypeTrackingNumber, TypeEmail, TypeURL}
+
+type Entity struct {
+	Type  string
+	Value string // as written in the message
+	// Normalized is a canonical form when one exists: ISO dates, amounts as
+	// "<CUR> 1234.56", tracking numbers upper-cased.
+	Normalized string
+}
+
+type matcher struct {
+	typ       string
+	re        *regexp.Regexp
+	normalize func(m []string) (string, bool)
+}
+
+var matchers = []matcher{
+	// Correios (AA123456789BR), UPS (1Z...), USPS (20-22 digits starting with 9).
+	{TypeTrackingNumber, regexp.MustCompile(`(?i)\b([A-Z]{2}\d{9}[A-Z]{2}|1Z[0-9A-Z]{16}|9[2-5]\d{18,20})\b`), func(m []string) (string, bool) {
+		return strings.ToUpper(m[1]), true
+	}},
+	{TypeEmail, regexp.MustCompile(`(?i)\b[A-Z0-9._%+\-]+@[A-Z0-9.\-]+\.[A-Z]{2,}\b`), func(m []string) (string, bool) {
+		return strings.ToLower(m[0]), true
+	}},
+	{TypeURL, regexp.MustCompile(`\bhttps?://[^\s<>"']+`), func(m []string) (string, bool) {
+		return strings.TrimRight(m[0], ".,;:!?)"), true
+	}},
+	// ISO dates (2024-03-15).
+	{TypeDate, regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`), func(m []string) (string, bool) {
+		return isoDate(m[1], m[2], m[3])
+	}},
+	// Day-first dates (15/03/2024, 15.03.24).
+	{TypeDate, regexp.MustCompile(`\b(\d{1,2})[/.](\d{1,2})[/.](\d{2}|\d{4})\b`), func(m []string) (string, bool) {
+		year := m[3]
+		if len(year) == 2 {
+			year = "20" + year
+		}
+		return isoDate(year, m[2], m[1])
+	}},
+	{TypeAmount, regexp.MustCompile(`(?i)(R\$|US\$|\$|€|£|\b(?:BRL|USD|EUR|GBP)\b)\s?(\d{1,3}(?:[.,\s]\d{3})*(?:[.,]\d{1,2})?|\d+(?:[.,]\d{1,2})?)`), func(m []string) (string, bool) {
+		cur := currencyCode(m[1])
+		amount, ok := normalizeAmount(m[2])
+		if !ok {
+			return "", false
+		}
+		return cur + " " + amount, true
+	}},
+	// Brazilian CEP (01310-100) and US ZIP+4 (94105-1234).
+	{TypePostalCode, regexp.MustCompile(`\b(\d{5}-\d{3,4})\b`), func(m []string) (string, bool) {
+		return m[1], true
+	}},
+	// Street addresses: "Rua Augusta, 1500", "Av. Paulista 1000", "221B Baker Street".
+	{TypeAddress, regexp.MustCompile(`(?i)\b(?:(?:rua|r\.|avenida|av\.|alameda|al\.|travessa|tv\.|estrada|rodovia|praça)\s+[^\d\n,]{2,60}?,?\s*(?:n[º°o.]?\s*)?\d{1,5}[A-Z]?|\d{1,5}[A-Z]?\s+(?:[A-Z][a-z]+\s+){1,4}(?:street|st\.|avenue|ave\.|road|rd\.|boulevard|blvd\.|lane|ln\.|drive|dr\.))`), func(m []string) (string, bool) {
+		return strings.Join(strings.Fields(m[0]), " "), true
+	}},
+}
+
+// Extract finds entities in text. Results are ordered by position and
+// de-duplicated per type and normalized value.
+func Extract(text string) []Entity {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+
+	type hit struct {
+		pos int
+		e   Entity
+	}
+	var hits []hit
+	seen := map[string]bool{}
+	var taken [][2]int
+
+	for _, m := range matchers {
+		for _, loc := range m.re.FindAllStringSubmatchIndex(text, -1) {
+			if overlaps(taken, loc[0], loc[1]) {
+				continue
+			}
+			groups := make([]string, len(loc)/2)
+			for i := range groups {
+				if loc[2*i] >= 0 {
+					groups[i] = text[loc[2*i]:loc[2*i+1]]
+				}
+			}
+			norm, ok := m.normalize(groups)
+			if !ok {
+				continue
+			}
+			key := m.typ + "\x00" + norm
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			taken = append(taken, [2]int{loc[0], loc[1]})
+			hits = append(hits, hit{pos: loc[0], e: Entity{Type: m.typ, Value: strings.TrimSpace(groups[0]), Normalized: norm}})
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].pos < hits[j].pos })
+	out := make([]Entity, 0, len(hits))
+	for _, h := range hits {
+		out = append(out, h.e)
+	}
+	return out
+}
+
+// ValidType reports whether t is a known entity type.
+func ValidType(t string) bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+func overlaps(taken [][2]int, start, end int) bool {
+	for _, r := range taken {
+		if start < r[1] && end > r[0] {
+			return true
+		}
+	}
+	return false
+}
+
+func isoDate(year, month, day string) (string, bool) {
+	t, err := time.Parse("2006-1-2", year+"-"+strings.TrimLeft(month, "0")+"-"+strings.TrimLeft(day, "0"))
+	if err != nil {
+		return "", false
+	}
+	return t.Format("2006-01-02"), true
+}
+
+func currencyCode(sym string) string {
+	switch strings.ToUpper(sym) {
+	case "R$", "BRL":
+		return "BRL"
+	case "€", "EUR":
+		return "EUR"
+	case "£", "GBP":
+		return "GBP"
+	default:
+		return "USD"
+	}
+}
+
+// normalizeAmount turns "1.234,56", "1,234.56" or "1234" into "1234.56"-style
+// decimals, treating a trailing separator with 1-2 digits as the decimal mark.
+func normalizeAmount(raw string) (string, bool) {
+	raw = strings.ReplaceAll(strings.TrimSpace(raw), " ", "")
+	if raw == "" {
+		return "", false
+	}
+	intPart, frac := raw, ""
+	if i := strings.LastIndexAny(raw, ".,"); i >= 0 && len(raw)-i-1 <= 2 {
+		intPart, frac = raw[:i], raw[i+1:]
+	}
+	intPart = strings.NewReplacer(".", "", ",", "").Replace(intPart)
+	if intPart == "" {
+		intPart = "0"
+	}
+	if frac == "" {
+		return intPart, true
+	}
+	if len(frac) == 1 {
+		frac += "0"
+	}
+	return intPart + "." + frac, true
+}
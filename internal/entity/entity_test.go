@@ -0,0 +1,74 @@
+package entity
+
+import "testing"
+
+func find(es []Entity, typ string) []Entity {
+	var out []Entity
+	for _, e := range es {
+		if e.Type == typ {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func TestExtractTrackingNumbers(t *testing.T) {
+	es := Extract("Seu pedido foi enviado: rastreio nb123456789br. UPS: 1Z999AA10123456784")
+	got := find(es, TypeTrackingNumber)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 tracking numbers, got %+v", es)
+	}
+	if got[0].Normalized != "NB123456789BR" || got[1].Normalized != "1Z999AA10123456784" {
+		t.Fatalf("unexpected tracking numbers: %+v", got)
+	}
+}
+
+func TestExtractDatesAndAmounts(t *testing.T) {
+	es := Extract("O aluguel de R$ 1.234,56 vence em 05/03/2024; the lease ends 2025-01-31 and costs $99.5")
+	dates := find(es, TypeDate)
+	if len(dates) != 2 || dates[0].Normalized != "2024-03-05" || dates[1].Normalized != "2025-01-31" {
+		t.Fatalf("unexpected dates: %+v", dates)
+	}
+	amounts := find(es, TypeAmount)
+	if len(amounts) != 2 || amounts[0].Normalized != "BRL 1234.56" || amounts[1].Normalized != "USD 99.50" {
+		t.Fatalf("unexpected amounts: %+v", amounts)
+	}
+	if len(find(es, TypePostalCode)) != 0 {
+		t.Fatalf("dates must not be reported as postal codes: %+v", es)
+	}
+}
+
+func TestExtractInvalidDateSkipped(t *testing.T) {
+	if got := find(Extract("version 31/31/2024"), TypeDate); len(got) != 0 {
+		t.Fatalf("expected invalid date to be skipped, got %+v", got)
+	}
+}
+
+func TestExtractAddressPostalEmailURL(t *testing.T) {
+	es := Extract("Entregar na Rua Augusta, 1500 - CEP 01310-100. Dúvidas: Suporte@Loja.com ou https://loja.com/pedido/1.")
+	if got := find(es, TypeAddress); len(got) != 1 || got[0].Normalized != "Rua Augusta, 1500" {
+		t.Fatalf("unexpected address: %+v", got)
+	}
+	if got := find(es, TypePostalCode); len(got) != 1 || got[0].Normalized != "01310-100" {
+		t.Fatalf("unexpected postal code: %+v", got)
+	}
+	if got := find(es, TypeEmail); len(got) != 1 || got[0].Normalized != "suporte@loja.com" {
+		t.Fatalf("unexpected email: %+v", got)
+	}
+	if got := find(es, TypeURL); len(got) != 1 || got[0].Normalized != "https://loja.com/pedido/1" {
+		t.Fatalf("unexpected url: %+v", got)
+	}
+}
+
+func TestExtractDedupAndEmpty(t *testing.T) {
+	if es := Extract("   "); es != nil {
+		t.Fatalf("expected nil for empty text")
+	}
+	es := Extract("AA123456789BR and again AA123456789BR")
+	if len(es) != 1 {
+		t.Fatalf("expected duplicate to be collapsed, got %+v", es)
+	}
+	if !ValidType(TypeAmount) || ValidType("nope") {
+		t.Fatalf("ValidType mismatch")
+	}
+}
@@ -1,6 +1,8 @@
 package lock
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -15,32 +17,68 @@ type Lock struct {
 }
 
 func Acquire(storeDir string) (*Lock, error) {
+	l, info, err := tryAcquire(storeDir, "LOCK")
+	if err != nil {
+		if info != "" {
+			return nil, fmt.Errorf("store is locked (another wacli is running?): %w (%s)", err, info)
+		}
+		return nil, fmt.Errorf("store is locked (another wacli is running?): %w", err)
+	}
+	return l, nil
+}
+
+// WaitLeader blocks until this process holds the leader lock of storeDir,
+// polling every poll interval. The kernel releases the lock when the holder
+// exits, so a waiting standby takes over as soon as the leader dies. onWait is
+// called once with the current holder's info if the lock is taken.
+func WaitLeader(ctx context.Context, storeDir string, poll time.Duration, onWait func(holder string)) (*Lock, error) {
+	waiting := false
+	for {
+		l, info, err := tryAcquire(storeDir, "LEADER")
+		if err == nil {
+			return l, nil
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, err
+		}
+		if !waiting && onWait != nil {
+			onWait(strings.ReplaceAll(info, "\n", " "))
+		}
+		waiting = true
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(poll):
+		}
+	}
+}
+
+// tryAcquire takes an exclusive lock on storeDir/name without blocking. When
+// the lock is held elsewhere it returns the holder's info.
+func tryAcquire(storeDir, name string) (*Lock, string, error) {
 	if err := os.MkdirAll(storeDir, 0700); err != nil {
-		return nil, fmt.Errorf("create store dir: %w", err)
+		return nil, "", fmt.Errorf("create store dir: %w", err)
 	}
-	path := filepath.Join(storeDir, "LOCK")
+	path := filepath.Join(storeDir, name)
 	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
 	if err != nil {
-		return nil, fmt.Errorf("open lock file: %w", err)
+		return nil, "", fmt.Errorf("open lock file: %w", err)
 	}
 
 	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
 		_, _ = f.Seek(0, 0)
 		b, _ := os.ReadFile(path)
 		_ = f.Close()
-		info := strings.TrimSpace(string(b))
-		if info != "" {
-			return nil, fmt.Errorf("store is locked (another wacli is running?): %w (%s)", err, info)
-		}
-		return nil, fmt.Errorf("store is locked (another wacli is running?): %w", err)
+		return nil, strings.TrimSpace(string(b)), err
 	}
 
+	host, _ := os.Hostname()
 	_ = f.Truncate(0)
 	_, _ = f.Seek(0, 0)
-	_, _ = fmt.Fprintf(f, "pid=%d\nacquired_at=%s\n", os.Getpid(), time.Now().Format(time.RFC3339Nano))
+	_, _ = fmt.Fprintf(f, "pid=%d\nhost=%s\nacquired_at=%s\n", os.Getpid(), host, time.Now().Format(time.RFC3339Nano))
 	_ = f.Sync()
 
-	return &Lock{path: path, f: f}, nil
+	return &Lock{path: path, f: f}, "", nil
 }
 
 func (l *Lock) Release() error {
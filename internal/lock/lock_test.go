@@ -55,3 +55,54 @@ func TestLockBlocksOtherProcess(t *testing.T) {
 		t.Fatalf("expected helper to report locked; output=%q", strings.TrimSpace(got))
 	}
 }
+
+func TestWaitLeaderTakesOverAfterRelease(t *testing.T) {
+	dir := t.TempDir()
+
+	leader, err := WaitLeader(context.Background(), dir, 10*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("first leader: %v", err)
+	}
+
+	var holder string
+	done := make(chan *Lock, 1)
+	go func() {
+		lk, err := WaitLeader(context.Background(), dir, 10*time.Millisecond, func(h string) { holder = h })
+		if err != nil {
+			t.Errorf("standby: %v", err)
+		}
+		done <- lk
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("standby became leader while the lock was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+	_ = leader.Release()
+
+	select {
+	case lk := <-done:
+		defer lk.Release()
+	case <-time.After(2 * time.Second):
+		t.Fatalf("standby did not take over")
+	}
+	if !strings.Contains(holder, fmt.Sprintf("pid=%d", os.Getpid())) {
+		t.Fatalf("expected holder info, got %q", holder)
+	}
+}
+
+func TestWaitLeaderCanceled(t *testing.T) {
+	dir := t.TempDir()
+	leader, err := WaitLeader(context.Background(), dir, 10*time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("leader: %v", err)
+	}
+	defer leader.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if _, err := WaitLeader(ctx, dir, 10*time.Millisecond, nil); err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package mediastore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TempPrefix starts the names of files and directories that are still being
+// written; Walk skips them.
+const TempPrefix = ".wacli-download-"
+
+// FS stores media as files below a root directory. Locations are absolute
+// file paths.
+type FS struct {
+	root string
+}
+
+func NewFS(root string) (*FS, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	return &FS{root: abs}, nil
+}
+
+// Root is the directory media is stored in.
+func (s *FS) Root() string { return s.root }
+
+func (s *FS) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
+
+func (s *FS) Location(key string) string { return s.path(key) }
+
+func (s *FS) Key(location string) (string, bool) {
+	abs, err := filepath.Abs(location)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(s.root, abs)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+func (s *FS) Put(ctx context.Context, key, src, contentType string) error {
+	dst := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	// src is on another filesystem: copy next to dst, then rename.
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(dst), TempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (s *FS) Open(ctx context.Context, key string) (io.ReadSeekCloser, Object, error) {
+	f, err := os.Open(s.path(key))
+	if err != nil {
+		return nil, Object{}, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, Object{}, err
+	}
+	if st.IsDir() {
+		f.Close()
+		return nil, Object{}, fmt.Errorf("%s: %w", key, ErrNotExist)
+	}
+	return f, Object{Key: key, Size: st.Size(), ModTime: st.ModTime().UTC()}, nil
+}
+
+func (s *FS) Stat(ctx context.Context, key string) (Object, error) {
+	st, err := os.Stat(s.path(key))
+	if err != nil {
+		return Object{}, err
+	}
+	if st.IsDir() {
+		return Object{}, fmt.Errorf("%s: %w", key, ErrNotExist)
+	}
+	return Object{Key: key, Size: st.Size(), ModTime: st.ModTime().UTC()}, nil
+}
+
+// Delete removes the file and then its parent directories while they are
+// empty.
+func (s *FS) Delete(ctx context.Context, key string) error {
+	p := s.path(key)
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for dir := filepath.Dir(p); strings.HasPrefix(dir, s.root+string(os.PathSeparator)); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *FS) Walk(ctx context.Context, prefix string, fn func(Object) error) error {
+	start := s.root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		start = s.path(prefix[:i])
+	}
+	err := filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), TempPrefix) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		key, ok := s.Key(path)
+		if !ok || !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // removed while walking
+		}
+		return fn(Object{Key: key, Size: info.Size(), ModTime: info.ModTime().UTC()})
+	})
+	return err
+}
@@ -0,0 +1,104 @@
+package mediastore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestFSPutOpenDelete(t *testing.T) {
+	ctx := context.Background()
+	root := filepath.Join(t.TempDir(), "media")
+	s, err := NewFS(root)
+	if err != nil {
+		t.Fatalf("NewFS: %v", err)
+	}
+
+	src := filepath.Join(t.TempDir(), "in.jpg")
+	if err := os.WriteFile(src, []byte("hello"), 0600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	key := "sha256/ab/abcd.jpg"
+	if err := s.Put(ctx, key, src, "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	loc := s.Location(key)
+	if loc != filepath.Join(root, "sha256", "ab", "abcd.jpg") {
+		t.Fatalf("Location = %q", loc)
+	}
+	if got, ok := s.Key(loc); !ok || got != key {
+		t.Fatalf("Key(%q) = %q, %v", loc, got, ok)
+	}
+	if _, ok := s.Key(filepath.Join(filepath.Dir(root), "elsewhere.jpg")); ok {
+		t.Fatalf("expected a path outside the root not to be managed")
+	}
+
+	r, obj, err := s.Open(ctx, key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "hello" || obj.Size != 5 || obj.Key != key {
+		t.Fatalf("unexpected object %+v with %q", obj, data)
+	}
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Stat(ctx, key); !errors.Is(err, ErrNotExist) {
+		t.Fatalf("expected ErrNotExist after Delete, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "sha256")); !os.IsNotExist(err) {
+		t.Fatalf("expected empty directories removed, got %v", err)
+	}
+	if _, err := os.Stat(root); err != nil {
+		t.Fatalf("expected the root to stay: %v", err)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete of a missing key: %v", err)
+	}
+}
+
+func TestFSWalk(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	s, err := NewFS(root)
+	if err != nil {
+		t.Fatalf("NewFS: %v", err)
+	}
+	for _, p := range []string{"a@s.whatsapp.net/m1/image/x.jpg", "a@s.whatsapp.net/m2/y.bin", "b@g.us/m3/z.bin", "sha256/ab/ab.jpg", TempPrefix + "1/partial.jpg", "b@g.us/" + TempPrefix + "2"} {
+		full := filepath.Join(root, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(full), 0700); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+		if err := os.WriteFile(full, []byte("x"), 0600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	walk := func(prefix string) []string {
+		var keys []string
+		if err := s.Walk(ctx, prefix, func(o Object) error {
+			keys = append(keys, o.Key)
+			return nil
+		}); err != nil {
+			t.Fatalf("Walk(%q): %v", prefix, err)
+		}
+		sort.Strings(keys)
+		return keys
+	}
+	if got := walk(""); len(got) != 4 {
+		t.Fatalf("expected 4 objects without temporary files, got %v", got)
+	}
+	if got := walk("a@s.whatsapp.net/"); len(got) != 2 || got[0] != "a@s.whatsapp.net/m1/image/x.jpg" {
+		t.Fatalf("unexpected prefix walk: %v", got)
+	}
+	if got := walk("missing/"); len(got) != 0 {
+		t.Fatalf("expected nothing under a missing prefix, got %v", got)
+	}
+}
@@ -0,0 +1,43 @@
+// Package mediastore keeps downloaded media on the local filesystem or in an
+// S3-compatible bucket.
+package mediastore
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"time"
+)
+
+// ErrNotExist is returned (wrapped) for keys with no stored object.
+var ErrNotExist = fs.ErrNotExist
+
+// Object describes a stored media file. Keys are slash-separated paths
+// relative to the store, e.g. "sha256/ab/ab12….jpg".
+type Object struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Store holds downloaded media. The message store records each file by its
+// location, so a Store also maps between keys and locations.
+type Store interface {
+	// Location is the reference recorded for key in the message store.
+	Location(key string) string
+	// Key returns the key of a location recorded by this store; ok is false
+	// for locations it does not manage, such as files saved elsewhere with
+	// wacli media download --output.
+	Key(location string) (key string, ok bool)
+	// Put stores the local file src under key, replacing any existing
+	// object. src may be moved; the caller removes it if it is left behind.
+	Put(ctx context.Context, key, src, contentType string) error
+	// Open returns the object for reading. Seeking lets range requests read
+	// only part of it.
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, Object, error)
+	Stat(ctx context.Context, key string) (Object, error)
+	// Delete removes key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// Walk calls fn for every object whose key starts with prefix.
+	Walk(ctx context.Context, prefix string, fn func(Object) error) error
+}
@@ -0,0 +1,133 @@
+package mediastore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Options configures an S3-compatible bucket (AWS S3, MinIO, ...).
+type S3Options struct {
+	// Endpoint is the host[:port] of the service, e.g. s3.amazonaws.com.
+	Endpoint string
+	Bucket   string
+	// Prefix is prepended to every key, e.g. "wacli/media/".
+	Prefix string
+	Region string
+	// AccessKey and SecretKey authenticate requests. Without them the
+	// AWS_* environment variables and instance credentials are tried.
+	AccessKey string
+	SecretKey string
+	// Insecure talks plain HTTP, for a local MinIO.
+	Insecure bool
+}
+
+// S3 stores media as objects in a bucket. Locations are s3://bucket/key
+// URLs.
+type S3 struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+func NewS3(opts S3Options) (*S3, error) {
+	if opts.Endpoint == "" || opts.Bucket == "" {
+		return nil, fmt.Errorf("s3 endpoint and bucket are required")
+	}
+	creds := credentials.NewStaticV4(opts.AccessKey, opts.SecretKey, "")
+	if opts.AccessKey == "" {
+		creds = credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.IAM{Client: &http.Client{Transport: http.DefaultTransport}},
+		})
+	}
+	client, err := minio.New(opts.Endpoint, &minio.Options{
+		Creds:  creds,
+		Secure: !opts.Insecure,
+		Region: opts.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &S3{client: client, bucket: opts.Bucket, prefix: opts.Prefix}, nil
+}
+
+// Check verifies that the bucket exists and is reachable.
+func (s *S3) Check(ctx context.Context) error {
+	ok, err := s.client.BucketExists(ctx, s.bucket)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("bucket %q does not exist", s.bucket)
+	}
+	return nil
+}
+
+func (s *S3) Location(key string) string {
+	return "s3://" + s.bucket + "/" + s.prefix + key
+}
+
+func (s *S3) Key(location string) (string, bool) {
+	key, ok := strings.CutPrefix(location, "s3://"+s.bucket+"/"+s.prefix)
+	return key, ok && key != ""
+}
+
+func (s *S3) Put(ctx context.Context, key, src, contentType string) error {
+	_, err := s.client.FPutObject(ctx, s.bucket, s.prefix+key, src, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *S3) Open(ctx context.Context, key string) (io.ReadSeekCloser, Object, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, s.prefix+key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, Object{}, s.err(key, err)
+	}
+	st, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, Object{}, s.err(key, err)
+	}
+	return obj, Object{Key: key, Size: st.Size, ModTime: st.LastModified.UTC()}, nil
+}
+
+func (s *S3) Stat(ctx context.Context, key string) (Object, error) {
+	st, err := s.client.StatObject(ctx, s.bucket, s.prefix+key, minio.StatObjectOptions{})
+	if err != nil {
+		return Object{}, s.err(key, err)
+	}
+	return Object{Key: key, Size: st.Size, ModTime: st.LastModified.UTC()}, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, s.prefix+key, minio.RemoveObjectOptions{})
+}
+
+func (s *S3) Walk(ctx context.Context, prefix string, fn func(Object) error) error {
+	// Cancelling stops the listing if fn fails part way.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for o := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: s.prefix + prefix, Recursive: true}) {
+		if o.Err != nil {
+			return o.Err
+		}
+		key := strings.TrimPrefix(o.Key, s.prefix)
+		if err := fn(Object{Key: key, Size: o.Size, ModTime: o.LastModified.UTC()}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// err maps a missing object to ErrNotExist.
+func (s *S3) err(key string, err error) error {
+	if resp := minio.ToErrorResponse(err); resp.Code == "NoSuchKey" || resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%s: %w", key, ErrNotExist)
+	}
+	return err
+}
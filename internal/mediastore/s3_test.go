@@ -0,0 +1,25 @@
+package mediastore
+
+import "testing"
+
+func TestS3Locations(t *testing.T) {
+	s, err := NewS3(S3Options{Endpoint: "localhost:9000", Bucket: "media", Prefix: "wacli/", AccessKey: "k", SecretKey: "s"})
+	if err != nil {
+		t.Fatalf("NewS3: %v", err)
+	}
+	loc := s.Location("sha256/ab/ab.jpg")
+	if loc != "s3://media/wacli/sha256/ab/ab.jpg" {
+		t.Fatalf("Location = %q", loc)
+	}
+	if key, ok := s.Key(loc); !ok || key != "sha256/ab/ab.jpg" {
+		t.Fatalf("Key = %q, %v", key, ok)
+	}
+	for _, other := range []string{"/var/lib/wacli/media/x.jpg", "s3://other/wacli/x.jpg", "s3://media/wacli/"} {
+		if _, ok := s.Key(other); ok {
+			t.Fatalf("expected %q not to be managed", other)
+		}
+	}
+	if _, err := NewS3(S3Options{Bucket: "media"}); err == nil {
+		t.Fatalf("expected an error without endpoint")
+	}
+}
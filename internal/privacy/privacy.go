@@ -0,0 +1,160 @@
+// Package privacy decides what may be sent to external AI providers.
+package privacy
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Guard filters chats and redacts message text before it leaves the machine.
+// The zero value allows every chat and redacts nothing.
+type Guard struct {
+	// DenyChats are never sent to AI providers. Entries are chat JIDs or bare
+	// phone numbers.
+	DenyChats []string
+	// AllowChats lists the only chats sent when AllowlistOnly is set.
+	AllowChats    []string
+	AllowlistOnly bool
+	// RedactPhones replaces phone numbers and user JIDs with "[phone]".
+	RedactPhones bool
+	// RedactNames replaces the names passed to Redact with "[name]".
+	RedactNames bool
+}
+
+var (
+	jidRe = regexp.MustCompile(`\b\d+(?::\d+)?@(?:s\.whatsapp\.net|c\.us|lid)\b`)
+	// Candidate phone numbers; digit count is checked separately so dates and
+	// short amounts survive.
+	phoneRe = regexp.MustCompile(`(?:\+|\(|\b)\d[\d ().\-]{6,}\d\b`)
+)
+
+// Allowed reports whether content from chatJID may be sent to an AI provider.
+func (g Guard) Allowed(chatJID string) bool {
+	if contains(g.DenyChats, chatJID) {
+		return false
+	}
+	if g.AllowlistOnly {
+		return contains(g.AllowChats, chatJID)
+	}
+	return true
+}
+
+// Redact strips phone numbers and the given names from text according to the
+// guard settings.
+func (g Guard) Redact(text string, names ...string) string {
+	if g.RedactNames {
+		text = redactNames(text, names)
+	}
+	if g.RedactPhones {
+		text = jidRe.ReplaceAllString(text, "[phone]")
+		text = phoneRe.ReplaceAllStringFunc(text, func(m string) string {
+			digits := 0
+			for _, r := range m {
+				if r >= '0' && r <= '9' {
+					digits++
+				}
+			}
+			if digits >= 9 || (strings.HasPrefix(m, "+") && digits >= 7) {
+				return "[phone]"
+			}
+			return m
+		})
+	}
+	return text
+}
+
+// Enabled reports whether the guard changes anything.
+func (g Guard) Enabled() bool {
+	return len(g.DenyChats) > 0 || g.AllowlistOnly || g.RedactPhones || g.RedactNames
+}
+
+func redactNames(text string, names []string) string {
+	var terms []string
+	seen := map[string]bool{}
+	add := func(s string) {
+		s = strings.TrimSpace(s)
+		key := strings.ToLower(s)
+		if utf8.RuneCountInString(s) < 3 || seen[key] {
+			return
+		}
+		seen[key] = true
+		terms = append(terms, s)
+	}
+	for _, n := range names {
+		add(n)
+		for _, part := range strings.Fields(n) {
+			add(part)
+		}
+	}
+	if len(terms) == 0 {
+		return text
+	}
+	// Longest first so "Alice Smith" wins over "Alice".
+	sort.SliceStable(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+	res := make([]*regexp.Regexp, len(terms))
+	for i, t := range terms {
+		res[i] = regexp.MustCompile(`(?i)^` + regexp.QuoteMeta(t))
+	}
+
+	// Go's \b only knows ASCII letters, so word boundaries are checked on
+	// runes here; "José" and "Zoë" are whole words, "Zoëy" is not "Zoë".
+	var b strings.Builder
+	prev := ' '
+	for i := 0; i < len(text); {
+		if !isWordRune(prev) {
+			if n := matchName(text[i:], res); n > 0 {
+				b.WriteString("[name]")
+				prev, _ = utf8.DecodeLastRuneInString(text[:i+n])
+				i += n
+				continue
+			}
+		}
+		r, size := utf8.DecodeRuneInString(text[i:])
+		b.WriteString(text[i : i+size])
+		prev = r
+		i += size
+	}
+	return b.String()
+}
+
+// matchName returns the length of the first name in res that s starts with
+// as a whole word, or 0.
+func matchName(s string, res []*regexp.Regexp) int {
+	for _, re := range res {
+		loc := re.FindStringIndex(s)
+		if loc == nil {
+			continue
+		}
+		if next, _ := utf8.DecodeRuneInString(s[loc[1]:]); loc[1] == len(s) || !isWordRune(next) {
+			return loc[1]
+		}
+	}
+	return 0
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '_'
+}
+
+// contains matches chatJID against entries given either as full JIDs or as
+// the user part ("1234567890" matches "1234567890@s.whatsapp.net").
+func contains(list []string, chatJID string) bool {
+	chatJID = strings.TrimSpace(chatJID)
+	user := chatJID
+	if i := strings.IndexByte(user, '@'); i >= 0 {
+		user = user[:i]
+	}
+	for _, entry := range list {
+		entry = strings.TrimPrefix(strings.TrimSpace(entry), "+")
+		if entry == "" {
+			continue
+		}
+		if entry == chatJID || (!strings.Contains(entry, "@") && entry == user) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package privacy
+
+import "testing"
+
+func TestAllowed(t *testing.T) {
+	g := Guard{DenyChats: []string{"+5511999990000", "123@g.us"}}
+	if g.Allowed("5511999990000@s.whatsapp.net") || g.Allowed("123@g.us") {
+		t.Fatalf("denylisted chats must be refused")
+	}
+	if !g.Allowed("5511888880000@s.whatsapp.net") {
+		t.Fatalf("other chats must be allowed")
+	}
+
+	g = Guard{AllowlistOnly: true, AllowChats: []string{"5511888880000"}, DenyChats: []string{"5511888880000"}}
+	if g.Allowed("5511888880000@s.whatsapp.net") {
+		t.Fatalf("denylist must win over allowlist")
+	}
+	g.DenyChats = nil
+	if !g.Allowed("5511888880000@s.whatsapp.net") || g.Allowed("5511777770000@s.whatsapp.net") {
+		t.Fatalf("allowlist-only mode mismatch")
+	}
+	if (Guard{AllowlistOnly: true}).Allowed("5511888880000@s.whatsapp.net") {
+		t.Fatalf("empty allowlist must refuse everything")
+	}
+}
+
+func TestRedactPhones(t *testing.T) {
+	g := Guard{RedactPhones: true}
+	got := g.Redact("Liga pro (11) 98765-4321 ou +1 415 555 0100, jid 5511987654321@s.whatsapp.net. Vence 2024-03-05, total 1.234")
+	want := "Liga pro [phone] ou [phone], jid [phone]. Vence 2024-03-05, total 1.234"
+	if got != want {
+		t.Fatalf("unexpected redaction:\n got %q\nwant %q", got, want)
+	}
+	if got := g.Redact("rastreio NB123456789BR"); got != "rastreio NB123456789BR" {
+		t.Fatalf("tracking numbers must survive, got %q", got)
+	}
+}
+
+func TestRedactNames(t *testing.T) {
+	g := Guard{RedactNames: true}
+	got := g.Redact("alice smith said Alice will call Bob; Alicia too", "Alice Smith", "Bo")
+	if got != "[name] said [name] will call Bob; Alicia too" {
+		t.Fatalf("unexpected redaction: %q", got)
+	}
+	got = g.Redact("José met Zoë, not Zoëy or MaríaJosé; ann leex asked Ann", "José", "Zoë", "Ann Lee")
+	if got != "[name] met [name], not Zoëy or MaríaJosé; [name] leex asked [name]" {
+		t.Fatalf("unexpected non-ASCII redaction: %q", got)
+	}
+	if (Guard{}).Redact("Alice 11987654321", "Alice") != "Alice 11987654321" {
+		t.Fatalf("zero guard must not redact")
+	}
+}
@@ -0,0 +1,134 @@
+package store
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// APIKey is an API key managed through the API, next to the ones configured
+// in WACLI_API_KEYS. Only its hash is stored.
+type APIKey struct {
+	ID   int64
+	Name string
+	// Prefix is the start of the key, to tell keys apart.
+	Prefix  string
+	KeyHash string
+	// RateLimit is the number of requests allowed per minute; 0 means no
+	// limit.
+	RateLimit  int
+	ExpiresAt  time.Time
+	RevokedAt  time.Time
+	LastUsedAt time.Time
+	CreatedAt  time.Time
+}
+
+// Active reports whether the key authenticates requests at t.
+func (k APIKey) Active(t time.Time) bool {
+	return k.RevokedAt.IsZero() && (k.ExpiresAt.IsZero() || t.Before(k.ExpiresAt))
+}
+
+// HashAPIKey is how API keys are identified in the store: the hex SHA-256
+// of the key.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+const apiKeyColumns = `id, name, prefix, key_hash, rate_limit, COALESCE(expires_at,0), COALESCE(revoked_at,0), COALESCE(last_used_at,0), created_at`
+
+// AddAPIKey stores k. KeyHash and Prefix are required; ID and CreatedAt are
+// set by the store.
+func (d *DB) AddAPIKey(k APIKey) (APIKey, error) {
+	if k.KeyHash == "" || k.Prefix == "" {
+		return APIKey{}, fmt.Errorf("key hash and prefix are required")
+	}
+	if k.RateLimit < 0 {
+		return APIKey{}, fmt.Errorf("rate limit must not be negative")
+	}
+	var expires any
+	if !k.ExpiresAt.IsZero() {
+		expires = unix(k.ExpiresAt)
+	}
+	res, err := d.sql.Exec(`
+		INSERT INTO api_keys(name, prefix, key_hash, rate_limit, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, strings.TrimSpace(k.Name), k.Prefix, k.KeyHash, k.RateLimit, expires, unix(time.Now().UTC()))
+	if err != nil {
+		return APIKey{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return APIKey{}, err
+	}
+	return d.GetAPIKey(id)
+}
+
+// GetAPIKey returns key id, or an error matching IsNotFound.
+func (d *DB) GetAPIKey(id int64) (APIKey, error) {
+	return scanAPIKey(d.sql.QueryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ?`, id))
+}
+
+// GetAPIKeyByHash returns the key with hash, revoked or not, or an error
+// matching IsNotFound.
+func (d *DB) GetAPIKeyByHash(hash string) (APIKey, error) {
+	return scanAPIKey(d.sql.QueryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = ?`, hash))
+}
+
+// ListAPIKeys returns the keys, newest first. Revoked keys are only
+// included with includeRevoked.
+func (d *DB) ListAPIKeys(includeRevoked bool) ([]APIKey, error) {
+	q := `SELECT ` + apiKeyColumns + ` FROM api_keys`
+	if !includeRevoked {
+		q += ` WHERE revoked_at IS NULL`
+	}
+	rows, err := d.sql.Query(q + ` ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, k)
+	}
+	return out, rows.Err()
+}
+
+// RevokeAPIKey revokes key id at t. Revoking a revoked key keeps the first
+// revocation time. It returns sql.ErrNoRows if the key is unknown.
+func (d *DB) RevokeAPIKey(id int64, t time.Time) (APIKey, error) {
+	res, err := d.sql.Exec(`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?`, unix(t), id)
+	if err != nil {
+		return APIKey{}, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return APIKey{}, sql.ErrNoRows
+	}
+	return d.GetAPIKey(id)
+}
+
+// TouchAPIKey records that key id was used at t.
+func (d *DB) TouchAPIKey(id int64, t time.Time) error {
+	_, err := d.sql.Exec(`UPDATE api_keys SET last_used_at = ? WHERE id = ?`, unix(t), id)
+	return err
+}
+
+func scanAPIKey(row rowScanner) (APIKey, error) {
+	var k APIKey
+	var expires, revoked, used, created int64
+	if err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.KeyHash, &k.RateLimit, &expires, &revoked, &used, &created); err != nil {
+		return APIKey{}, err
+	}
+	k.ExpiresAt = fromUnix(expires)
+	k.RevokedAt = fromUnix(revoked)
+	k.LastUsedAt = fromUnix(used)
+	k.CreatedAt = fromUnix(created)
+	return k, nil
+}
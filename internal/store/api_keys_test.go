@@ -0,0 +1,66 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAPIKeys(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	if _, err := db.AddAPIKey(APIKey{Name: "no hash"}); err == nil {
+		t.Fatalf("expected error without a hash")
+	}
+	ci, err := db.AddAPIKey(APIKey{Name: " ci ", Prefix: "wacli_abc", KeyHash: HashAPIKey("wacli_abc123"), RateLimit: 60, ExpiresAt: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("AddAPIKey: %v", err)
+	}
+	if ci.Name != "ci" || ci.RateLimit != 60 || !ci.ExpiresAt.Equal(now.Add(time.Hour)) || ci.CreatedAt.IsZero() {
+		t.Fatalf("stored key = %+v", ci)
+	}
+	if _, err := db.AddAPIKey(APIKey{Prefix: "wacli_abc", KeyHash: HashAPIKey("wacli_abc123")}); err == nil {
+		t.Fatalf("expected error for a duplicate hash")
+	}
+	bot, err := db.AddAPIKey(APIKey{Name: "bot", Prefix: "wacli_def", KeyHash: HashAPIKey("wacli_def456")})
+	if err != nil {
+		t.Fatalf("AddAPIKey: %v", err)
+	}
+
+	got, err := db.GetAPIKeyByHash(HashAPIKey("wacli_abc123"))
+	if err != nil || got.ID != ci.ID {
+		t.Fatalf("GetAPIKeyByHash = %+v, %v", got, err)
+	}
+	if _, err := db.GetAPIKeyByHash(HashAPIKey("unknown")); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if !ci.Active(now) || ci.Active(now.Add(2*time.Hour)) {
+		t.Fatalf("expiry not applied: %+v", ci)
+	}
+
+	if err := db.TouchAPIKey(bot.ID, now); err != nil {
+		t.Fatalf("TouchAPIKey: %v", err)
+	}
+	revoked, err := db.RevokeAPIKey(bot.ID, now)
+	if err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	if revoked.Active(now) || !revoked.LastUsedAt.Equal(now) {
+		t.Fatalf("revoked key = %+v", revoked)
+	}
+	if again, err := db.RevokeAPIKey(bot.ID, now.Add(time.Hour)); err != nil || !again.RevokedAt.Equal(now) {
+		t.Fatalf("second revoke = %+v, %v", again, err)
+	}
+	if _, err := db.RevokeAPIKey(999, now); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	keys, err := db.ListAPIKeys(false)
+	if err != nil || len(keys) != 1 || keys[0].ID != ci.ID {
+		t.Fatalf("ListAPIKeys(false) = %+v, %v", keys, err)
+	}
+	keys, err = db.ListAPIKeys(true)
+	if err != nil || len(keys) != 2 || keys[0].ID != bot.ID {
+		t.Fatalf("ListAPIKeys(true) = %+v, %v", keys, err)
+	}
+}
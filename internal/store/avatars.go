@@ -0,0 +1,64 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// Avatar is a cached profile picture. Missing avatars have no data: the
+// JID has no picture, or its privacy settings hide it from us.
+type Avatar struct {
+	JID       string
+	PictureID string
+	Data      []byte
+	Missing   bool
+	FetchedAt time.Time
+}
+
+// PutAvatar caches a JID's profile picture, replacing the previous one.
+func (d *DB) PutAvatar(a Avatar) error {
+	if a.JID == "" {
+		return fmt.Errorf("jid is required")
+	}
+	if !a.Missing && (a.PictureID == "" || len(a.Data) == 0) {
+		return fmt.Errorf("picture id and data are required")
+	}
+	if a.Missing {
+		a.PictureID, a.Data = "", nil
+	}
+	if a.FetchedAt.IsZero() {
+		a.FetchedAt = time.Now().UTC()
+	}
+	_, err := d.sql.Exec(`
+		INSERT INTO avatars(jid, picture_id, data, missing, fetched_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(jid) DO UPDATE SET
+			picture_id=excluded.picture_id,
+			data=excluded.data,
+			missing=excluded.missing,
+			fetched_at=excluded.fetched_at
+	`, a.JID, a.PictureID, a.Data, boolToInt(a.Missing), unix(a.FetchedAt))
+	return err
+}
+
+// TouchAvatar marks a cached avatar as checked at t, for when WhatsApp
+// reports the picture unchanged.
+func (d *DB) TouchAvatar(jid string, t time.Time) error {
+	_, err := d.sql.Exec(`UPDATE avatars SET fetched_at = ? WHERE jid = ?`, unix(t), jid)
+	return err
+}
+
+// GetAvatar returns the cached avatar of jid, or an error matching
+// IsNotFound.
+func (d *DB) GetAvatar(jid string) (Avatar, error) {
+	a := Avatar{JID: jid}
+	var missing int
+	var fetched int64
+	err := d.sql.QueryRow(`SELECT picture_id, data, missing, fetched_at FROM avatars WHERE jid = ?`, jid).
+		Scan(&a.PictureID, &a.Data, &missing, &fetched)
+	if err != nil {
+		return Avatar{}, err
+	}
+	a.Missing = missing != 0
+	a.FetchedAt = fromUnix(fetched)
+	return a, nil
+}
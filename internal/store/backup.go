@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Backup writes a consistent snapshot of the database to destPath with
+// SQLite's online backup API, so it is safe while the server keeps writing;
+// copying the live file (and its WAL) is not. destPath must not exist. A
+// failed backup leaves no file behind.
+func (d *DB) Backup(ctx context.Context, destPath string) error {
+	return backup(ctx, d.sql, destPath)
+}
+
+// BackupFile snapshots the SQLite database at srcPath, such as the session
+// database another connection keeps open, the same way Backup does.
+func BackupFile(ctx context.Context, srcPath, destPath string) error {
+	if _, err := os.Stat(srcPath); err != nil {
+		return err
+	}
+	src, err := sql.Open("sqlite3", "file:"+srcPath+"?_busy_timeout=5000")
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return backup(ctx, src, destPath)
+}
+
+func backup(ctx context.Context, src *sql.DB, destPath string) (err error) {
+	if _, err := os.Stat(destPath); err == nil {
+		return fmt.Errorf("%s already exists", destPath)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	dest, err := sql.Open("sqlite3", "file:"+destPath)
+	if err != nil {
+		return fmt.Errorf("open backup: %w", err)
+	}
+	defer func() {
+		_ = dest.Close()
+		if err != nil {
+			_ = os.Remove(destPath)
+		}
+	}()
+
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("open backup: %w", err)
+	}
+	defer destConn.Close()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(dc any) error {
+		return srcConn.Raw(func(sc any) error {
+			dst, ok := dc.(*sqlite3.SQLiteConn)
+			src, ok2 := sc.(*sqlite3.SQLiteConn)
+			if !ok || !ok2 {
+				return fmt.Errorf("backup needs the sqlite3 driver")
+			}
+			b, err := dst.Backup("main", src, "main")
+			if err != nil {
+				return fmt.Errorf("start backup: %w", err)
+			}
+			for {
+				// Copy everything in one step so the snapshot is taken under a
+				// single read transaction; Step reports busy as not done.
+				done, err := b.Step(-1)
+				if err != nil {
+					_ = b.Finish()
+					return fmt.Errorf("backup: %w", err)
+				}
+				if done {
+					break
+				}
+				select {
+				case <-ctx.Done():
+					_ = b.Finish()
+					return ctx.Err()
+				case <-time.After(50 * time.Millisecond):
+				}
+			}
+			return b.Finish()
+		})
+	})
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackup(t *testing.T) {
+	db := openTestDB(t)
+	chat := "123@s.whatsapp.net"
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := db.UpsertChat(chat, "dm", "Alice", now); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	if err := db.UpsertMessage(UpsertMessageParams{ChatJID: chat, MsgID: "m1", SenderJID: chat, Timestamp: now, Text: "hello"}); err != nil {
+		t.Fatalf("UpsertMessage: %v", err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "backup.db")
+	if err := db.Backup(context.Background(), dest); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if err := db.Backup(context.Background(), dest); err == nil {
+		t.Fatalf("expected error when the destination exists")
+	}
+
+	restored, err := Open(dest)
+	if err != nil {
+		t.Fatalf("Open backup: %v", err)
+	}
+	defer restored.Close()
+	m, err := restored.GetMessage(chat, "m1")
+	if err != nil || m.Text != "hello" {
+		t.Fatalf("GetMessage from backup = %+v, %v", m, err)
+	}
+}
+
+func TestBackupFile(t *testing.T) {
+	dir := t.TempDir()
+	src, err := Open(filepath.Join(dir, "src.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer src.Close()
+	if err := src.SetSetting("k", "v"); err != nil {
+		t.Fatalf("SetSetting: %v", err)
+	}
+
+	dest := filepath.Join(dir, "copy.db")
+	if err := BackupFile(context.Background(), filepath.Join(dir, "src.db"), dest); err != nil {
+		t.Fatalf("BackupFile: %v", err)
+	}
+	if err := BackupFile(context.Background(), filepath.Join(dir, "missing.db"), filepath.Join(dir, "other.db")); err == nil {
+		t.Fatalf("expected error for a missing source")
+	}
+
+	copied, err := Open(dest)
+	if err != nil {
+		t.Fatalf("Open copy: %v", err)
+	}
+	defer copied.Close()
+	if v, err := copied.GetSetting("k"); err != nil || v != "v" {
+		t.Fatalf("GetSetting from copy = %q, %v", v, err)
+	}
+}
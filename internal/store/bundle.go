@@ -0,0 +1,233 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BundleVersion is the automation bundle format written by ExportBundle.
+const BundleVersion = 1
+
+// Bundle is a portable snapshot of the automation config (webhook
+// subscriptions and routing rules) that can be shared between deployments
+// and checked into version control.
+type Bundle struct {
+	Version    int             `json:"version"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Webhooks   []BundleWebhook `json:"webhooks"`
+	Rules      []BundleRule    `json:"rules"`
+}
+
+type BundleWebhook struct {
+	// Ref identifies the webhook within the bundle; webhook rules use it as
+	// their arg instead of a store-specific id.
+	Ref     string   `json:"ref"`
+	URL     string   `json:"url"`
+	Events  []string `json:"events,omitempty"`
+	Chats   []string `json:"chats,omitempty"`
+	Enabled bool     `json:"enabled"`
+	Secret  string   `json:"secret,omitempty"`
+	// SkipMuted drops events of chats muted on the phone.
+	SkipMuted bool `json:"skip_muted,omitempty"`
+}
+
+type BundleRule struct {
+	Name       string   `json:"name,omitempty"`
+	Priority   int      `json:"priority"`
+	Enabled    bool     `json:"enabled"`
+	Chats      []string `json:"chats,omitempty"`
+	Senders    []string `json:"senders,omitempty"`
+	Keywords   []string `json:"keywords,omitempty"`
+	MediaTypes []string `json:"media_types,omitempty"`
+	Action     string   `json:"action"`
+	Arg        string   `json:"arg,omitempty"`
+	// MinRole limits the rule to senders with at least this role.
+	MinRole string `json:"min_role,omitempty"`
+	// DuplicateMedia limits the rule to media already in the archive.
+	DuplicateMedia bool `json:"duplicate_media,omitempty"`
+}
+
+// BundleImportResult counts what ImportBundle created and what it skipped
+// because an identical entry already existed.
+type BundleImportResult struct {
+	Webhooks int `json:"webhooks"`
+	Rules    int `json:"rules"`
+	Skipped  int `json:"skipped"`
+}
+
+// ExportBundle snapshots webhooks and rules. Webhook secrets are only
+// included with includeSecrets; without them, imports generate new ones.
+func (d *DB) ExportBundle(includeSecrets bool) (Bundle, error) {
+	b := Bundle{Version: BundleVersion, ExportedAt: time.Now().UTC(), Webhooks: []BundleWebhook{}, Rules: []BundleRule{}}
+
+	hooks, err := d.ListWebhooks(false)
+	if err != nil {
+		return Bundle{}, err
+	}
+	refs := map[string]string{}
+	for _, h := range hooks {
+		ref := "webhook-" + strconv.FormatInt(h.ID, 10)
+		refs[strconv.FormatInt(h.ID, 10)] = ref
+		bw := BundleWebhook{Ref: ref, URL: h.URL, Events: h.Events, Chats: h.Chats, Enabled: h.Enabled, SkipMuted: h.SkipMuted}
+		if includeSecrets {
+			bw.Secret = h.Secret
+		}
+		b.Webhooks = append(b.Webhooks, bw)
+	}
+
+	rules, err := d.ListRules(false)
+	if err != nil {
+		return Bundle{}, err
+	}
+	for _, r := range rules {
+		arg := r.Arg
+		if r.Action == RuleWebhook {
+			arg = refs[r.Arg]
+		}
+		b.Rules = append(b.Rules, BundleRule{
+			Name: r.Name, Priority: r.Priority, Enabled: r.Enabled,
+			Chats: r.Chats, Senders: r.Senders, Keywords: r.Keywords, MediaTypes: r.MediaTypes,
+			Action: r.Action, Arg: arg, MinRole: r.MinRole, DuplicateMedia: r.DuplicateMedia,
+		})
+	}
+	return b, nil
+}
+
+// Validate checks the bundle without touching the store and returns every
+// problem found.
+func (b Bundle) Validate() []error {
+	var errs []error
+	if b.Version < 1 || b.Version > BundleVersion {
+		errs = append(errs, fmt.Errorf("unsupported bundle version %d (want 1..%d)", b.Version, BundleVersion))
+	}
+	refs := map[string]bool{}
+	for i, w := range b.Webhooks {
+		if _, err := ParseHTTPURL(w.URL); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %d: %w", i+1, err))
+		}
+		ref := strings.TrimSpace(w.Ref)
+		if refs[ref] {
+			errs = append(errs, fmt.Errorf("webhook %d: duplicate ref %q", i+1, ref))
+		}
+		if ref != "" {
+			refs[ref] = true
+		}
+	}
+	for i, r := range b.Rules {
+		p := CreateRuleParams{Action: r.Action, Arg: r.Arg, MinRole: r.MinRole}
+		if err := normalizeRule(&p); err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %w", i+1, err))
+			continue
+		}
+		if p.Action == RuleWebhook && !refs[p.Arg] {
+			errs = append(errs, fmt.Errorf("rule %d: unknown webhook ref %q", i+1, p.Arg))
+		}
+	}
+	return errs
+}
+
+// ImportBundle applies b in a single transaction. With replace, existing
+// webhooks (including their queues) and rules are deleted first; otherwise
+// webhooks with the same URL and rules with the same name, action and arg
+// are kept and skipped, so importing the same bundle twice is a no-op.
+func (d *DB) ImportBundle(b Bundle, replace bool) (BundleImportResult, error) {
+	var res BundleImportResult
+	if errs := b.Validate(); len(errs) > 0 {
+		return res, errors.Join(errs...)
+	}
+
+	tx, err := d.sql.Begin()
+	if err != nil {
+		return res, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if replace {
+		for _, table := range []string{"rules", "webhook_queue", "webhook_dead_letters", "webhooks"} {
+			if _, err := tx.Exec(`DELETE FROM ` + table); err != nil {
+				return res, err
+			}
+		}
+	}
+
+	now := unix(time.Now().UTC())
+	ids := map[string]int64{}
+	for i, w := range b.Webhooks {
+		u, err := ParseHTTPURL(w.URL)
+		if err != nil {
+			return res, fmt.Errorf("webhook %d: %w", i+1, err)
+		}
+		ref := strings.TrimSpace(w.Ref)
+		if _, dup := ids[ref]; dup && ref != "" {
+			return res, fmt.Errorf("webhook %d: duplicate ref %q", i+1, ref)
+		}
+
+		var id int64
+		err = tx.QueryRow(`SELECT id FROM webhooks WHERE url = ? ORDER BY id LIMIT 1`, u).Scan(&id)
+		switch {
+		case err == nil:
+			res.Skipped++
+		case IsNotFound(err):
+			secret := strings.TrimSpace(w.Secret)
+			if secret == "" {
+				if secret, err = newWebhookSecret(); err != nil {
+					return res, err
+				}
+			}
+			r, err := tx.Exec(`
+				INSERT INTO webhooks(url, events, chats, enabled, secret, skip_muted, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			`, u, joinList(w.Events), joinList(w.Chats), boolToInt(w.Enabled), secret, boolToInt(w.SkipMuted), now, now)
+			if err != nil {
+				return res, err
+			}
+			if id, err = r.LastInsertId(); err != nil {
+				return res, err
+			}
+			res.Webhooks++
+		default:
+			return res, err
+		}
+		if ref != "" {
+			ids[ref] = id
+		}
+	}
+
+	for i, r := range b.Rules {
+		p := CreateRuleParams{
+			Name: r.Name, Priority: r.Priority,
+			Chats: r.Chats, Senders: r.Senders, Keywords: r.Keywords, MediaTypes: r.MediaTypes,
+			Action: r.Action, Arg: r.Arg, MinRole: r.MinRole, DuplicateMedia: r.DuplicateMedia,
+		}
+		if err := normalizeRule(&p); err != nil {
+			return res, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if p.Action == RuleWebhook {
+			id, ok := ids[p.Arg]
+			if !ok {
+				return res, fmt.Errorf("rule %d: unknown webhook ref %q", i+1, p.Arg)
+			}
+			p.Arg = strconv.FormatInt(id, 10)
+		}
+
+		var n int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM rules WHERE name = ? AND action = ? AND arg = ?`, p.Name, p.Action, p.Arg).Scan(&n); err != nil {
+			return res, err
+		}
+		if n > 0 {
+			res.Skipped++
+			continue
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO rules(name, priority, enabled, chats, senders, keywords, media_types, action, arg, min_role, duplicate_media, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, p.Name, p.Priority, boolToInt(r.Enabled), joinList(p.Chats), joinList(p.Senders), joinList(p.Keywords), joinList(p.MediaTypes), p.Action, p.Arg, p.MinRole, boolToInt(p.DuplicateMedia), now, now); err != nil {
+			return res, err
+		}
+		res.Rules++
+	}
+	return res, tx.Commit()
+}
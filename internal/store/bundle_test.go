@@ -0,0 +1,90 @@
+package store
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	src := openTestDB(t)
+	// Burn an id so the webhook gets a different id in the destination.
+	spare, _ := src.CreateWebhook(CreateWebhookParams{URL: "https://spare.example.com"})
+	h, err := src.CreateWebhook(CreateWebhookParams{URL: "https://hooks.example.com/wa", Events: []string{"message"}, Secret: "s3cret", SkipMuted: true})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	if _, err := src.DeleteWebhook(spare.ID); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	if _, err := src.CreateRule(CreateRuleParams{Name: "forward", Action: RuleWebhook, Arg: strconv.FormatInt(h.ID, 10), DuplicateMedia: true}); err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+	if _, err := src.CreateRule(CreateRuleParams{Name: "billing", Priority: 5, Keywords: []string{"invoice"}, Action: RuleTag, Arg: "billing", MinRole: RoleAdmin}); err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+
+	b, err := src.ExportBundle(false)
+	if err != nil {
+		t.Fatalf("ExportBundle: %v", err)
+	}
+	if b.Version != BundleVersion || len(b.Webhooks) != 1 || len(b.Rules) != 2 {
+		t.Fatalf("unexpected bundle: %+v", b)
+	}
+	if b.Webhooks[0].Secret != "" || b.Rules[0].Arg != b.Webhooks[0].Ref {
+		t.Fatalf("expected secret omitted and rule to reference %q: %+v", b.Webhooks[0].Ref, b)
+	}
+
+	dst := openTestDB(t)
+	res, err := dst.ImportBundle(b, false)
+	if err != nil {
+		t.Fatalf("ImportBundle: %v", err)
+	}
+	if res != (BundleImportResult{Webhooks: 1, Rules: 2}) {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	hooks, _ := dst.ListWebhooks(false)
+	rules, _ := dst.ListRules(false)
+	if len(hooks) != 1 || hooks[0].Secret == "" || hooks[0].Secret == "s3cret" || !hooks[0].SkipMuted {
+		t.Fatalf("unexpected webhooks: %+v", hooks)
+	}
+	if len(rules) != 2 || rules[0].Arg != strconv.FormatInt(hooks[0].ID, 10) || rules[1].Keywords[0] != "invoice" || rules[1].MinRole != RoleAdmin || !rules[0].DuplicateMedia || rules[1].DuplicateMedia {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+
+	res, err = dst.ImportBundle(b, false)
+	if err != nil || res != (BundleImportResult{Skipped: 3}) {
+		t.Fatalf("re-import: res=%+v err=%v", res, err)
+	}
+	res, err = dst.ImportBundle(b, true)
+	if err != nil || res != (BundleImportResult{Webhooks: 1, Rules: 2}) {
+		t.Fatalf("replace: res=%+v err=%v", res, err)
+	}
+	if rules, _ := dst.ListRules(false); len(rules) != 2 {
+		t.Fatalf("expected replace to leave 2 rules, got %d", len(rules))
+	}
+}
+
+func TestImportBundleRejectsInvalid(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.ImportBundle(Bundle{Version: BundleVersion + 1}, false); err == nil {
+		t.Fatalf("expected version error")
+	}
+	b := Bundle{
+		Version:  BundleVersion,
+		Webhooks: []BundleWebhook{{Ref: "a", URL: "https://example.com", Enabled: true}},
+		Rules:    []BundleRule{{Action: RuleWebhook, Arg: "missing"}},
+	}
+	if errs := b.Validate(); len(errs) != 1 {
+		t.Fatalf("expected one validation error, got %v", errs)
+	}
+	b.Webhooks = append(b.Webhooks, BundleWebhook{Ref: "a", URL: "ftp://example.com"})
+	if errs := b.Validate(); len(errs) != 3 {
+		t.Fatalf("expected all problems reported, got %v", errs)
+	}
+	if _, err := db.ImportBundle(b, false); err == nil {
+		t.Fatalf("expected unknown ref error")
+	}
+	if hooks, _ := db.ListWebhooks(false); len(hooks) != 0 {
+		t.Fatalf("expected rollback, got %+v", hooks)
+	}
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MessageCallback is a URL to notify when receipts for one of our messages
+// arrive. Each status (delivered, read, played) is reported once.
+type MessageCallback struct {
+	ID       int64
+	ChatJID  string
+	MsgID    string
+	OutboxID int64
+	URL      string
+	// Secret signs callbacks like webhook deliveries (optional).
+	Secret    string
+	CreatedAt time.Time
+}
+
+type CreateMessageCallbackParams struct {
+	ChatJID string
+	// Either MsgID (already sent) or OutboxID (queued) is set.
+	MsgID    string
+	OutboxID int64
+	URL      string
+	Secret   string
+}
+
+func (d *DB) CreateMessageCallback(p CreateMessageCallbackParams) (MessageCallback, error) {
+	u, err := ParseHTTPURL(p.URL)
+	if err != nil {
+		return MessageCallback{}, fmt.Errorf("callback_url: %w", err)
+	}
+	if strings.TrimSpace(p.ChatJID) == "" || (p.MsgID == "" && p.OutboxID == 0) {
+		return MessageCallback{}, fmt.Errorf("chat and message or outbox id are required")
+	}
+	var msgID, outboxID interface{}
+	if p.MsgID != "" {
+		msgID = p.MsgID
+	}
+	if p.OutboxID != 0 {
+		outboxID = p.OutboxID
+	}
+	now := time.Now().UTC()
+	res, err := d.sql.Exec(`
+		INSERT INTO message_callbacks(chat_jid, msg_id, outbox_id, url, secret, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, p.ChatJID, msgID, outboxID, u, strings.TrimSpace(p.Secret), unix(now))
+	if err != nil {
+		return MessageCallback{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return MessageCallback{}, err
+	}
+	return MessageCallback{ID: id, ChatJID: p.ChatJID, MsgID: p.MsgID, OutboxID: p.OutboxID, URL: u, Secret: strings.TrimSpace(p.Secret), CreatedAt: now}, nil
+}
+
+// AttachOutboxCallbacks sets the message id of callbacks registered for a
+// queued message once it has been sent.
+func (d *DB) AttachOutboxCallbacks(outboxID int64, msgID string) error {
+	_, err := d.sql.Exec(`UPDATE message_callbacks SET msg_id = ? WHERE outbox_id = ? AND msg_id IS NULL`, msgID, outboxID)
+	return err
+}
+
+func (d *DB) ListMessageCallbacks(chatJID, msgID string) ([]MessageCallback, error) {
+	rows, err := d.sql.Query(`
+		SELECT id, chat_jid, COALESCE(msg_id,''), COALESCE(outbox_id,0), url, secret, created_at
+		FROM message_callbacks WHERE chat_jid = ? AND msg_id = ?
+		ORDER BY id
+	`, chatJID, msgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []MessageCallback
+	for rows.Next() {
+		var cb MessageCallback
+		var created int64
+		if err := rows.Scan(&cb.ID, &cb.ChatJID, &cb.MsgID, &cb.OutboxID, &cb.URL, &cb.Secret, &created); err != nil {
+			return nil, err
+		}
+		cb.CreatedAt = fromUnix(created)
+		out = append(out, cb)
+	}
+	return out, rows.Err()
+}
+
+// MarkMessageCallback records that status (a Receipt* kind) was reported and
+// returns false if it already had been.
+func (d *DB) MarkMessageCallback(id int64, status string, at time.Time) (bool, error) {
+	var column string
+	switch status {
+	case ReceiptDelivered:
+		column = "delivered_at"
+	case ReceiptRead:
+		column = "read_at"
+	case ReceiptPlayed:
+		column = "played_at"
+	default:
+		return false, fmt.Errorf("unknown receipt kind %q", status)
+	}
+	res, err := d.sql.Exec(`UPDATE message_callbacks SET `+column+` = ? WHERE id = ? AND `+column+` IS NULL`, unix(at), id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMessageCallbacks(t *testing.T) {
+	db := openTestDB(t)
+	chat := "5511999990000@s.whatsapp.net"
+
+	if _, err := db.CreateMessageCallback(CreateMessageCallbackParams{ChatJID: chat, MsgID: "M1", URL: "ftp://x"}); err == nil {
+		t.Fatalf("expected url error")
+	}
+	sent, err := db.CreateMessageCallback(CreateMessageCallbackParams{ChatJID: chat, MsgID: "M1", URL: "https://example.com/cb"})
+	if err != nil {
+		t.Fatalf("CreateMessageCallback: %v", err)
+	}
+	if _, err := db.CreateMessageCallback(CreateMessageCallbackParams{ChatJID: chat, OutboxID: 7, URL: "https://example.com/queued"}); err != nil {
+		t.Fatalf("CreateMessageCallback: %v", err)
+	}
+
+	if cbs, _ := db.ListMessageCallbacks(chat, "M2"); len(cbs) != 0 {
+		t.Fatalf("expected queued callback to be unattached, got %+v", cbs)
+	}
+	if err := db.AttachOutboxCallbacks(7, "M2"); err != nil {
+		t.Fatalf("AttachOutboxCallbacks: %v", err)
+	}
+	if cbs, _ := db.ListMessageCallbacks(chat, "M2"); len(cbs) != 1 || cbs[0].URL != "https://example.com/queued" || cbs[0].OutboxID != 7 {
+		t.Fatalf("unexpected callbacks: %+v", cbs)
+	}
+
+	now := time.Now()
+	if ok, err := db.MarkMessageCallback(sent.ID, ReceiptRead, now); err != nil || !ok {
+		t.Fatalf("MarkMessageCallback: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := db.MarkMessageCallback(sent.ID, ReceiptRead, now); ok {
+		t.Fatalf("expected read to be reported once")
+	}
+	if ok, _ := db.MarkMessageCallback(sent.ID, ReceiptDelivered, now); !ok {
+		t.Fatalf("expected delivered to be reported separately")
+	}
+}
@@ -0,0 +1,165 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Call statuses.
+const (
+	CallRinging  = "ringing"
+	CallAnswered = "answered"
+	CallRejected = "rejected"
+	CallMissed   = "missed"
+	CallEnded    = "ended"
+)
+
+type Call struct {
+	ID        string
+	ChatJID   string
+	CallerJID string
+	Media     string
+	Group     bool
+	Status    string
+	StartedAt time.Time
+	EndedAt   time.Time
+}
+
+// CallUpdate is one call signal: "offer", "accept", "reject" or "terminate".
+type CallUpdate struct {
+	ID        string
+	ChatJID   string
+	CallerJID string
+	Media     string
+	Group     bool
+	State     string
+	At        time.Time
+}
+
+// RecordCallUpdate applies a call signal and returns the resulting call. A
+// call that terminates while still ringing is recorded as missed.
+func (d *DB) RecordCallUpdate(u CallUpdate) (Call, error) {
+	if strings.TrimSpace(u.ID) == "" {
+		return Call{}, fmt.Errorf("call id is required")
+	}
+	if u.At.IsZero() {
+		u.At = time.Now().UTC()
+	}
+
+	cur, err := d.GetCall(u.ID)
+	exists := err == nil
+	if err != nil && err != sql.ErrNoRows {
+		return Call{}, err
+	}
+	if !exists {
+		cur = Call{ID: u.ID, ChatJID: u.ChatJID, CallerJID: u.CallerJID, Status: CallRinging, StartedAt: u.At}
+	}
+	if u.Media != "" {
+		cur.Media = u.Media
+	}
+	cur.Group = cur.Group || u.Group
+
+	switch u.State {
+	case "offer":
+	case "accept":
+		if cur.Status == CallRinging {
+			cur.Status = CallAnswered
+		}
+	case "reject":
+		if cur.Status == CallRinging {
+			cur.Status = CallRejected
+			cur.EndedAt = u.At
+		}
+	case "terminate":
+		switch cur.Status {
+		case CallRinging:
+			cur.Status = CallMissed
+		case CallAnswered:
+			cur.Status = CallEnded
+		}
+		if cur.EndedAt.IsZero() {
+			cur.EndedAt = u.At
+		}
+	default:
+		return Call{}, fmt.Errorf("unknown call state %q", u.State)
+	}
+	if cur.ChatJID == "" {
+		return Call{}, fmt.Errorf("chat is required")
+	}
+
+	var ended interface{}
+	if !cur.EndedAt.IsZero() {
+		ended = unix(cur.EndedAt)
+	}
+	_, err = d.sql.Exec(`
+		INSERT INTO calls(call_id, chat_jid, caller_jid, media, is_group, status, started_at, ended_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(call_id) DO UPDATE SET
+			media=excluded.media,
+			is_group=excluded.is_group,
+			status=excluded.status,
+			ended_at=excluded.ended_at,
+			updated_at=excluded.updated_at
+	`, cur.ID, cur.ChatJID, cur.CallerJID, cur.Media, boolToInt(cur.Group), cur.Status, unix(cur.StartedAt), ended, unix(time.Now().UTC()))
+	if err != nil {
+		return Call{}, err
+	}
+	return cur, nil
+}
+
+func (d *DB) GetCall(id string) (Call, error) {
+	return scanCall(d.sql.QueryRow(`
+		SELECT call_id, chat_jid, caller_jid, media, is_group, status, started_at, COALESCE(ended_at,0)
+		FROM calls WHERE call_id = ?
+	`, id))
+}
+
+// ListCalls returns calls newest first, optionally limited to one chat and
+// status.
+func (d *DB) ListCalls(chatJID, status string, limit int) ([]Call, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	q := `SELECT call_id, chat_jid, caller_jid, media, is_group, status, started_at, COALESCE(ended_at,0) FROM calls WHERE 1=1`
+	var args []interface{}
+	if strings.TrimSpace(chatJID) != "" {
+		q += ` AND chat_jid = ?`
+		args = append(args, chatJID)
+	}
+	if strings.TrimSpace(status) != "" {
+		q += ` AND status = ?`
+		args = append(args, status)
+	}
+	q += ` ORDER BY started_at DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := d.sql.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Call
+	for rows.Next() {
+		c, err := scanCall(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func scanCall(row rowScanner) (Call, error) {
+	var c Call
+	var group int
+	var started, ended int64
+	if err := row.Scan(&c.ID, &c.ChatJID, &c.CallerJID, &c.Media, &group, &c.Status, &started, &ended); err != nil {
+		return Call{}, err
+	}
+	c.Group = group != 0
+	c.StartedAt = fromUnix(started)
+	c.EndedAt = fromUnix(ended)
+	return c, nil
+}
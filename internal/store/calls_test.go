@@ -0,0 +1,44 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordCallUpdate(t *testing.T) {
+	db := openTestDB(t)
+	chat := "5511999990000@s.whatsapp.net"
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	c, err := db.RecordCallUpdate(CallUpdate{ID: "A", ChatJID: chat, CallerJID: chat, Media: "video", State: "offer", At: start})
+	if err != nil || c.Status != CallRinging {
+		t.Fatalf("offer: %+v (%v)", c, err)
+	}
+	c, err = db.RecordCallUpdate(CallUpdate{ID: "A", ChatJID: chat, State: "terminate", At: start.Add(30 * time.Second)})
+	if err != nil || c.Status != CallMissed || c.Media != "video" || c.EndedAt.Sub(c.StartedAt) != 30*time.Second {
+		t.Fatalf("missed: %+v (%v)", c, err)
+	}
+
+	if _, err := db.RecordCallUpdate(CallUpdate{ID: "B", ChatJID: chat, State: "offer", At: start.Add(time.Hour)}); err != nil {
+		t.Fatalf("offer: %v", err)
+	}
+	if _, err := db.RecordCallUpdate(CallUpdate{ID: "B", ChatJID: chat, State: "accept", At: start.Add(time.Hour + 5*time.Second)}); err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	if c, _ := db.RecordCallUpdate(CallUpdate{ID: "B", ChatJID: chat, State: "terminate", At: start.Add(2 * time.Hour)}); c.Status != CallEnded {
+		t.Fatalf("expected ended, got %+v", c)
+	}
+
+	if _, err := db.RecordCallUpdate(CallUpdate{ID: "C", ChatJID: chat, State: "dial"}); err == nil {
+		t.Fatalf("expected unknown state error")
+	}
+
+	calls, err := db.ListCalls(chat, "", 10)
+	if err != nil || len(calls) != 2 || calls[0].ID != "B" {
+		t.Fatalf("unexpected calls: %+v (%v)", calls, err)
+	}
+	missed, _ := db.ListCalls("", CallMissed, 10)
+	if len(missed) != 1 || missed[0].ID != "A" {
+		t.Fatalf("unexpected missed calls: %+v", missed)
+	}
+}
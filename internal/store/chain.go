@@ -0,0 +1,319 @@
+package store
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// The archive chain is an append-only hash chain over the stored messages.
+// Every change the store makes to a message's content (ingestion, edit,
+// revoke, delete) appends an entry with a digest of the new content, and each
+// entry's hash covers the previous one. VerifyArchive then shows whether the
+// messages still match their last entries, i.e. whether anything changed them
+// outside wacli, and whether the chain itself was altered. Display names and
+// download state are left out of the digest since they change routinely.
+
+// ArchiveIssue is a message that does not match the archive chain.
+type ArchiveIssue struct {
+	ChatJID string `json:"chat_jid"`
+	MsgID   string `json:"msg_id"`
+	// Seq is the chain entry the message was checked against.
+	Seq int64 `json:"seq,omitempty"`
+}
+
+// ArchiveReport is the result of VerifyArchive.
+type ArchiveReport struct {
+	Entries int64  `json:"entries"`
+	HeadSeq int64  `json:"head_seq"`
+	Head    string `json:"head"`
+	// BrokenAt is the first chain entry whose hash does not follow from its
+	// predecessor (0 when the chain is intact).
+	BrokenAt int64 `json:"broken_at,omitempty"`
+	Messages int64 `json:"messages"`
+	Verified int64 `json:"verified"`
+	// Modified messages differ from their last chained content; Missing ones
+	// were chained and never deleted through wacli but are gone.
+	Modified []ArchiveIssue `json:"modified"`
+	Missing  []ArchiveIssue `json:"missing"`
+	// Unchained messages have no entry, e.g. because they were stored
+	// before the chain existed (see SealArchive).
+	Unchained int64 `json:"unchained"`
+}
+
+// OK reports whether the chain is intact and every message matches it.
+func (r ArchiveReport) OK() bool {
+	return r.BrokenAt == 0 && len(r.Modified) == 0 && len(r.Missing) == 0 && r.Unchained == 0
+}
+
+// archiveContent is the part of a message the chain vouches for.
+type archiveContent struct {
+	ChatJID    string `json:"chat_jid"`
+	MsgID      string `json:"msg_id"`
+	SenderJID  string `json:"sender_jid"`
+	Timestamp  int64  `json:"ts"`
+	FromMe     bool   `json:"from_me"`
+	Text       string `json:"text"`
+	MediaType  string `json:"media_type"`
+	Caption    string `json:"media_caption"`
+	Filename   string `json:"filename"`
+	MimeType   string `json:"mime_type"`
+	FileSHA256 string `json:"file_sha256"`
+	QuotedID   string `json:"quoted_id"`
+	SystemType string `json:"system_type"`
+	EditedAt   int64  `json:"edited_at"`
+	RevokedAt  int64  `json:"revoked_at"`
+}
+
+const archiveContentColumns = `chat_jid, msg_id, COALESCE(sender_jid,''), ts, from_me, COALESCE(text,''),
+	COALESCE(media_type,''), COALESCE(media_caption,''), COALESCE(filename,''), COALESCE(mime_type,''),
+	file_sha256, COALESCE(quoted_id,''), COALESCE(system_type,''), COALESCE(edited_at,0), COALESCE(revoked_at,0)`
+
+// scanArchiveDigest scans archiveContentColumns, followed by any extra
+// columns into extra, and returns the content digest.
+func scanArchiveDigest(row rowScanner, extra ...any) (chatJID, msgID, digest string, err error) {
+	var c archiveContent
+	var fromMe int
+	var fileSHA []byte
+	dest := append([]any{&c.ChatJID, &c.MsgID, &c.SenderJID, &c.Timestamp, &fromMe, &c.Text,
+		&c.MediaType, &c.Caption, &c.Filename, &c.MimeType, &fileSHA, &c.QuotedID, &c.SystemType, &c.EditedAt, &c.RevokedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return "", "", "", err
+	}
+	c.FromMe = fromMe != 0
+	c.FileSHA256 = hex.EncodeToString(fileSHA)
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", "", "", err
+	}
+	sum := sha256.Sum256(data)
+	return c.ChatJID, c.MsgID, hex.EncodeToString(sum[:]), nil
+}
+
+func archiveEntryHash(prev string, seq int64, chatJID, msgID, digest string, createdAt int64) string {
+	h := sha256.New()
+	for _, part := range []string{prev, strconv.FormatInt(seq, 10), chatJID, msgID, digest, strconv.FormatInt(createdAt, 10)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// chainMessage appends the message's current content to the archive chain
+// unless it is already the message's last entry. A message that no longer
+// exists is recorded as deleted.
+func (d *DB) chainMessage(chatJID, msgID string) error {
+	d.chainMu.Lock()
+	defer d.chainMu.Unlock()
+	return chainMessageIn(d.sql, chatJID, msgID)
+}
+
+// sqlExecer is a *sql.DB or *sql.Tx.
+type sqlExecer interface {
+	rowQuerier
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// chainMessageIn is chainMessage on q, for changes chained in the
+// transaction that makes them. It must be called with chainMu held.
+func chainMessageIn(q sqlExecer, chatJID, msgID string) error {
+	_, _, digest, err := scanArchiveDigest(q.QueryRow(`SELECT `+archiveContentColumns+` FROM messages WHERE chat_jid = ? AND msg_id = ?`, chatJID, msgID))
+	if errors.Is(err, sql.ErrNoRows) {
+		digest = ""
+	} else if err != nil {
+		return err
+	}
+	var last string
+	err = q.QueryRow(`SELECT digest FROM archive_chain WHERE chat_jid = ? AND msg_id = ? ORDER BY seq DESC LIMIT 1`, chatJID, msgID).Scan(&last)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if digest == "" {
+			return nil
+		}
+	case err != nil:
+		return err
+	case last == digest:
+		return nil
+	}
+	return appendArchiveEntry(q, chatJID, msgID, digest)
+}
+
+// chainDeleted records deleted messages in the archive chain.
+func (d *DB) chainDeleted(keys [][2]string) error {
+	for _, k := range keys {
+		if err := d.chainMessage(k[0], k[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chainPurgedChat records the deletion of a purged chat's messages.
+func (d *DB) chainPurgedChat(chatJID string) error {
+	rows, err := d.sql.Query(`SELECT DISTINCT msg_id FROM archive_chain WHERE chat_jid = ?`, chatJID)
+	if err != nil {
+		return err
+	}
+	var keys [][2]string
+	for rows.Next() {
+		var msgID string
+		if err := rows.Scan(&msgID); err != nil {
+			rows.Close()
+			return err
+		}
+		keys = append(keys, [2]string{chatJID, msgID})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return d.chainDeleted(keys)
+}
+
+// appendArchiveEntry must be called with chainMu held.
+func appendArchiveEntry(q sqlExecer, chatJID, msgID, digest string) error {
+	var seq int64
+	var prev string
+	err := q.QueryRow(`SELECT seq, hash FROM archive_chain ORDER BY seq DESC LIMIT 1`).Scan(&seq, &prev)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	seq++
+	now := unix(time.Now().UTC())
+	_, err = q.Exec(`INSERT INTO archive_chain(seq, chat_jid, msg_id, digest, hash, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		seq, chatJID, msgID, digest, archiveEntryHash(prev, seq, chatJID, msgID, digest, now), now)
+	if err != nil {
+		return fmt.Errorf("archive chain: %w", err)
+	}
+	return nil
+}
+
+// SealArchive chains every message that has no entry yet, such as those
+// stored before the chain existed, and returns how many it added. Sealing
+// vouches for their current content, so check them first.
+func (d *DB) SealArchive() (int64, error) {
+	rows, err := d.sql.Query(`
+		SELECT chat_jid, msg_id FROM messages m
+		WHERE NOT EXISTS (SELECT 1 FROM archive_chain c WHERE c.chat_jid = m.chat_jid AND c.msg_id = m.msg_id)
+		ORDER BY rowid
+	`)
+	if err != nil {
+		return 0, err
+	}
+	var keys [][2]string
+	for rows.Next() {
+		var k [2]string
+		if err := rows.Scan(&k[0], &k[1]); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		keys = append(keys, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, k := range keys {
+		if err := d.chainMessage(k[0], k[1]); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(keys)), nil
+}
+
+// ArchiveEntryHash returns the hash of chain entry seq, for comparing with a
+// head recorded earlier: a chain rebuilt after tampering does not reproduce it.
+func (d *DB) ArchiveEntryHash(seq int64) (string, error) {
+	var hash string
+	err := d.sql.QueryRow(`SELECT hash FROM archive_chain WHERE seq = ?`, seq).Scan(&hash)
+	return hash, err
+}
+
+// VerifyArchive walks the archive chain, checking that every entry follows
+// from its predecessor, and compares each stored message with its last
+// entry.
+func (d *DB) VerifyArchive() (ArchiveReport, error) {
+	d.chainMu.Lock()
+	defer d.chainMu.Unlock()
+
+	var r ArchiveReport
+	rows, err := d.sql.Query(`SELECT seq, chat_jid, msg_id, digest, hash, created_at FROM archive_chain ORDER BY seq`)
+	if err != nil {
+		return r, err
+	}
+	prev := ""
+	for rows.Next() {
+		var seq, created int64
+		var chatJID, msgID, digest, hash string
+		if err := rows.Scan(&seq, &chatJID, &msgID, &digest, &hash, &created); err != nil {
+			rows.Close()
+			return r, err
+		}
+		r.Entries++
+		if r.BrokenAt == 0 && (seq != r.HeadSeq+1 || hash != archiveEntryHash(prev, seq, chatJID, msgID, digest, created)) {
+			r.BrokenAt = seq
+		}
+		r.HeadSeq, r.Head, prev = seq, hash, hash
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return r, err
+	}
+
+	rows, err = d.sql.Query(`
+		SELECT ` + archiveContentColumns + `,
+			(SELECT seq FROM archive_chain c WHERE c.chat_jid = m.chat_jid AND c.msg_id = m.msg_id ORDER BY seq DESC LIMIT 1),
+			(SELECT digest FROM archive_chain c WHERE c.chat_jid = m.chat_jid AND c.msg_id = m.msg_id ORDER BY seq DESC LIMIT 1)
+		FROM messages m ORDER BY rowid
+	`)
+	if err != nil {
+		return r, err
+	}
+	for rows.Next() {
+		var seq sql.NullInt64
+		var chained sql.NullString
+		chatJID, msgID, digest, err := scanArchiveDigest(rows, &seq, &chained)
+		if err != nil {
+			rows.Close()
+			return r, err
+		}
+		r.Messages++
+		switch {
+		case !seq.Valid:
+			r.Unchained++
+		case chained.String != digest:
+			r.Modified = append(r.Modified, ArchiveIssue{ChatJID: chatJID, MsgID: msgID, Seq: seq.Int64})
+		default:
+			r.Verified++
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return r, err
+	}
+
+	rows, err = d.sql.Query(`
+		SELECT c.chat_jid, c.msg_id, c.seq FROM archive_chain c
+		WHERE c.digest != ''
+			AND c.seq = (SELECT MAX(seq) FROM archive_chain l WHERE l.chat_jid = c.chat_jid AND l.msg_id = c.msg_id)
+			AND NOT EXISTS (SELECT 1 FROM messages m WHERE m.chat_jid = c.chat_jid AND m.msg_id = c.msg_id)
+		ORDER BY c.seq
+	`)
+	if err != nil {
+		return r, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var issue ArchiveIssue
+		if err := rows.Scan(&issue.ChatJID, &issue.MsgID, &issue.Seq); err != nil {
+			return r, err
+		}
+		r.Missing = append(r.Missing, issue)
+	}
+	return r, rows.Err()
+}
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestArchiveChainVerify(t *testing.T) {
+	db := openTestDB(t)
+	chat := "5511111111111@s.whatsapp.net"
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := db.UpsertChat(chat, "dm", "Alice", t0); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	for _, id := range []string{"M1", "M2", "M3"} {
+		if err := db.UpsertMessage(UpsertMessageParams{ChatJID: chat, MsgID: id, SenderJID: chat, Timestamp: t0, Text: "hi " + id}); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+	// Re-syncing unchanged content and renaming the sender add no entries.
+	if err := db.UpsertMessage(UpsertMessageParams{ChatJID: chat, MsgID: "M1", SenderJID: chat, SenderName: "Alice B.", Timestamp: t0, Text: "hi M1"}); err != nil {
+		t.Fatalf("UpsertMessage: %v", err)
+	}
+	// Changes made through the store are chained.
+	if ok, err := db.EditMessage(chat, "M2", "edited", "edited", t0.Add(time.Minute)); err != nil || !ok {
+		t.Fatalf("EditMessage: %v %v", ok, err)
+	}
+	if _, err := db.DeleteMessage(chat, "M3"); err != nil {
+		t.Fatalf("DeleteMessage: %v", err)
+	}
+
+	r, err := db.VerifyArchive()
+	if err != nil {
+		t.Fatalf("VerifyArchive: %v", err)
+	}
+	if !r.OK() || r.Entries != 5 || r.Messages != 2 || r.Verified != 2 || r.HeadSeq != 5 {
+		t.Fatalf("unexpected report: %+v", r)
+	}
+	head := r.Head
+
+	// Changes made behind the store's back are reported.
+	if _, err := db.sql.Exec(`UPDATE messages SET text = 'forged' WHERE msg_id = 'M1'`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.sql.Exec(`DELETE FROM messages WHERE msg_id = 'M2'`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.sql.Exec(`INSERT INTO messages(chat_jid, msg_id, ts, from_me, text) VALUES (?, 'M4', ?, 0, 'planted')`, chat, unix(t0)); err != nil {
+		t.Fatal(err)
+	}
+	r, err = db.VerifyArchive()
+	if err != nil {
+		t.Fatalf("VerifyArchive: %v", err)
+	}
+	if r.OK() || r.BrokenAt != 0 || len(r.Modified) != 1 || r.Modified[0].MsgID != "M1" ||
+		len(r.Missing) != 1 || r.Missing[0].MsgID != "M2" || r.Unchained != 1 {
+		t.Fatalf("unexpected report: %+v", r)
+	}
+
+	// Rewriting an entry breaks the chain from there on.
+	if _, err := db.sql.Exec(`UPDATE archive_chain SET digest = 'x' WHERE seq = 2`); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ = db.VerifyArchive(); r.BrokenAt != 2 {
+		t.Fatalf("expected chain broken at 2, got %+v", r)
+	}
+	if got, _ := db.ArchiveEntryHash(5); got != head {
+		t.Fatalf("ArchiveEntryHash(5) = %q, want %q", got, head)
+	}
+}
+
+func TestSealArchive(t *testing.T) {
+	db := openTestDB(t)
+	chat := "5511111111111@s.whatsapp.net"
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := db.UpsertChat(chat, "dm", "Alice", t0); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	// Messages stored before the chain existed.
+	for _, id := range []string{"M1", "M2"} {
+		if _, err := db.sql.Exec(`INSERT INTO messages(chat_jid, msg_id, ts, from_me, text) VALUES (?, ?, ?, 0, 'old')`, chat, id, unix(t0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r, _ := db.VerifyArchive(); r.Unchained != 2 {
+		t.Fatalf("expected 2 unchained, got %+v", r)
+	}
+	n, err := db.SealArchive()
+	if err != nil || n != 2 {
+		t.Fatalf("SealArchive = %d, %v", n, err)
+	}
+	if r, _ := db.VerifyArchive(); !r.OK() || r.Verified != 2 {
+		t.Fatalf("unexpected report after sealing: %+v", r)
+	}
+	if n, _ := db.SealArchive(); n != 0 {
+		t.Fatalf("second seal added %d", n)
+	}
+}
+
+func TestPurgeChatChainsDeletion(t *testing.T) {
+	db := openTestDB(t)
+	chat := "5511111111111@s.whatsapp.net"
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := db.UpsertChat(chat, "dm", "Alice", t0); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	if err := db.UpsertMessage(UpsertMessageParams{ChatJID: chat, MsgID: "M1", Timestamp: t0, Text: "hi"}); err != nil {
+		t.Fatalf("UpsertMessage: %v", err)
+	}
+	if err := db.TrashChat(chat, t0); err != nil {
+		t.Fatalf("TrashChat: %v", err)
+	}
+	if err := db.PurgeChat(chat); err != nil {
+		t.Fatalf("PurgeChat: %v", err)
+	}
+	if r, _ := db.VerifyArchive(); !r.OK() || r.Entries != 2 {
+		t.Fatalf("unexpected report after purge: %+v", r)
+	}
+}
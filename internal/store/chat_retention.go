@@ -0,0 +1,159 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrLegalHold is returned when erasing history of a chat on legal hold.
+var ErrLegalHold = errors.New("chat is on legal hold")
+
+// ChatRetention is a chat's exception to the store-wide pruning.
+type ChatRetention struct {
+	ChatJID string
+	// LegalHold keeps everything: the janitor skips the chat and deleting
+	// its messages, trashing or purging it fails with ErrLegalHold.
+	LegalHold bool
+	// NeverPrune exempts the chat from automatic pruning only; it can still
+	// be deleted on request.
+	NeverPrune bool
+	// RetentionDays overrides the default message retention; zero keeps
+	// the default.
+	RetentionDays int
+	Reason        string
+	UpdatedBy     string
+	UpdatedAt     time.Time
+}
+
+// Exempt reports whether the janitor must leave the chat alone.
+func (r ChatRetention) Exempt() bool {
+	return r.LegalHold || r.NeverPrune
+}
+
+const chatRetentionColumns = `chat_jid, legal_hold, never_prune, retention_days, reason, updated_by, updated_at`
+
+// SetChatRetention stores r, replacing the chat's previous settings.
+func (d *DB) SetChatRetention(r ChatRetention) (ChatRetention, error) {
+	r.ChatJID = strings.TrimSpace(r.ChatJID)
+	if r.ChatJID == "" {
+		return ChatRetention{}, fmt.Errorf("chat is required")
+	}
+	if r.RetentionDays < 0 {
+		return ChatRetention{}, fmt.Errorf("retention_days must not be negative")
+	}
+	_, err := d.sql.Exec(`
+		INSERT INTO chat_retention(`+chatRetentionColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(chat_jid) DO UPDATE SET
+			legal_hold = excluded.legal_hold,
+			never_prune = excluded.never_prune,
+			retention_days = excluded.retention_days,
+			reason = excluded.reason,
+			updated_by = excluded.updated_by,
+			updated_at = excluded.updated_at
+	`, r.ChatJID, boolToInt(r.LegalHold), boolToInt(r.NeverPrune), r.RetentionDays, r.Reason, r.UpdatedBy, unix(time.Now().UTC()))
+	if err != nil {
+		return ChatRetention{}, err
+	}
+	return d.GetChatRetention(r.ChatJID)
+}
+
+// GetChatRetention returns the settings of chat, or an error matching
+// IsNotFound if it follows the defaults.
+func (d *DB) GetChatRetention(chatJID string) (ChatRetention, error) {
+	return scanChatRetention(d.sql.QueryRow(`SELECT `+chatRetentionColumns+` FROM chat_retention WHERE chat_jid = ?`, chatJID))
+}
+
+// ListChatRetention returns every chat with its own settings, legal holds
+// first.
+func (d *DB) ListChatRetention() ([]ChatRetention, error) {
+	rows, err := d.sql.Query(`SELECT ` + chatRetentionColumns + ` FROM chat_retention ORDER BY legal_hold DESC, chat_jid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ChatRetention
+	for rows.Next() {
+		r, err := scanChatRetention(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// DeleteChatRetention returns chat to the defaults, releasing any legal
+// hold. It reports whether the chat had settings.
+func (d *DB) DeleteChatRetention(chatJID string) (bool, error) {
+	res, err := d.sql.Exec(`DELETE FROM chat_retention WHERE chat_jid = ?`, chatJID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// rowQuerier is a *sql.DB or *sql.Tx.
+type rowQuerier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// checkLegalHold returns ErrLegalHold if chat is on legal hold.
+func checkLegalHold(q rowQuerier, chatJID string) error {
+	var n int
+	if err := q.QueryRow(`SELECT COUNT(*) FROM chat_retention WHERE chat_jid = ? AND legal_hold = 1`, chatJID).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrLegalHold
+	}
+	return nil
+}
+
+// PruneTarget is a chat the janitor may prune, with its own retention
+// override if it has one.
+type PruneTarget struct {
+	ChatJID       string
+	RetentionDays int
+}
+
+// ListPruneTargets returns the chats not in the trash that are neither on
+// legal hold nor exempt from pruning.
+func (d *DB) ListPruneTargets() ([]PruneTarget, error) {
+	rows, err := d.sql.Query(`
+		SELECT c.jid, COALESCE(r.retention_days,0)
+		FROM chats c
+		LEFT JOIN chat_retention r ON r.chat_jid = c.jid
+		WHERE c.deleted_at IS NULL AND COALESCE(r.legal_hold,0) = 0 AND COALESCE(r.never_prune,0) = 0
+		ORDER BY c.jid
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []PruneTarget
+	for rows.Next() {
+		var t PruneTarget
+		if err := rows.Scan(&t.ChatJID, &t.RetentionDays); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func scanChatRetention(row rowScanner) (ChatRetention, error) {
+	var r ChatRetention
+	var hold, never int
+	var updated int64
+	if err := row.Scan(&r.ChatJID, &hold, &never, &r.RetentionDays, &r.Reason, &r.UpdatedBy, &updated); err != nil {
+		return ChatRetention{}, err
+	}
+	r.LegalHold = hold != 0
+	r.NeverPrune = never != 0
+	r.UpdatedAt = fromUnix(updated)
+	return r, nil
+}
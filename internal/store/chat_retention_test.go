@@ -0,0 +1,112 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChatRetentionCRUD(t *testing.T) {
+	db := openTestDB(t)
+	chat := "123@s.whatsapp.net"
+
+	if _, err := db.GetChatRetention(chat); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if _, err := db.SetChatRetention(ChatRetention{ChatJID: chat, RetentionDays: -1}); err == nil {
+		t.Fatalf("expected negative retention to be rejected")
+	}
+	r, err := db.SetChatRetention(ChatRetention{ChatJID: chat, LegalHold: true, Reason: "case 42", UpdatedBy: "legal"})
+	if err != nil {
+		t.Fatalf("SetChatRetention: %v", err)
+	}
+	if !r.LegalHold || r.Reason != "case 42" || r.UpdatedAt.IsZero() || !r.Exempt() {
+		t.Fatalf("unexpected retention: %+v", r)
+	}
+	r, err = db.SetChatRetention(ChatRetention{ChatJID: chat, RetentionDays: 90})
+	if err != nil {
+		t.Fatalf("SetChatRetention: %v", err)
+	}
+	if r.LegalHold || r.RetentionDays != 90 || r.Reason != "" || r.Exempt() {
+		t.Fatalf("settings not replaced: %+v", r)
+	}
+	list, err := db.ListChatRetention()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("ListChatRetention = %+v, %v", list, err)
+	}
+	if ok, err := db.DeleteChatRetention(chat); err != nil || !ok {
+		t.Fatalf("DeleteChatRetention = %v, %v", ok, err)
+	}
+	if ok, _ := db.DeleteChatRetention(chat); ok {
+		t.Fatalf("expected second delete to report nothing removed")
+	}
+}
+
+func TestLegalHoldBlocksErasure(t *testing.T) {
+	db := openTestDB(t)
+	held, free := "111@g.us", "222@g.us"
+	when := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, chat := range []string{held, free} {
+		if err := db.UpsertChat(chat, "group", "", when); err != nil {
+			t.Fatalf("UpsertChat: %v", err)
+		}
+		if err := db.UpsertMessage(UpsertMessageParams{ChatJID: chat, MsgID: "m1", SenderJID: chat, Timestamp: when, MediaType: "image"}); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+		if err := db.MarkMediaDownloaded(chat, "m1", "/m/"+chat+".jpg", "", when); err != nil {
+			t.Fatalf("MarkMediaDownloaded: %v", err)
+		}
+		if err := db.TrashChat(chat, when); err != nil {
+			t.Fatalf("TrashChat: %v", err)
+		}
+	}
+	// The hold is placed after the chat went to the trash.
+	if _, err := db.SetChatRetention(ChatRetention{ChatJID: held, LegalHold: true}); err != nil {
+		t.Fatalf("SetChatRetention: %v", err)
+	}
+
+	expired, err := db.ListExpiredTrash(when.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("ListExpiredTrash: %v", err)
+	}
+	if len(expired) != 1 || expired[0] != free {
+		t.Fatalf("expected only %s to expire, got %v", free, expired)
+	}
+	if err := db.PurgeChat(held); !errors.Is(err, ErrLegalHold) {
+		t.Fatalf("PurgeChat on hold = %v", err)
+	}
+	if _, err := db.DeleteMessage(held, "m1"); !errors.Is(err, ErrLegalHold) {
+		t.Fatalf("DeleteMessage on hold = %v", err)
+	}
+	if _, _, err := db.DeleteMessagesBefore(held, when.Add(time.Hour)); !errors.Is(err, ErrLegalHold) {
+		t.Fatalf("DeleteMessagesBefore on hold = %v", err)
+	}
+	if err := db.RestoreChat(held); err != nil {
+		t.Fatalf("RestoreChat: %v", err)
+	}
+	if err := db.TrashChat(held, when); !errors.Is(err, ErrLegalHold) {
+		t.Fatalf("TrashChat on hold = %v", err)
+	}
+
+	media, err := db.ListDownloadedMedia()
+	if err != nil {
+		t.Fatalf("ListDownloadedMedia: %v", err)
+	}
+	for _, m := range media {
+		if m.Exempt != (m.ChatJID == held) {
+			t.Fatalf("unexpected exempt flag: %+v", m)
+		}
+	}
+
+	targets, err := db.ListPruneTargets()
+	if err != nil {
+		t.Fatalf("ListPruneTargets: %v", err)
+	}
+	if len(targets) != 0 {
+		t.Fatalf("expected no prune targets (one held, one trashed), got %+v", targets)
+	}
+
+	if err := db.PurgeChat(free); err != nil {
+		t.Fatalf("PurgeChat: %v", err)
+	}
+}
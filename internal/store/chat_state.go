@@ -0,0 +1,65 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// chatStateSQL selects the archive, pin and mute state of chats c, in the
+// order applyChatState takes them.
+const chatStateSQL = `c.archived, c.pinned, COALESCE(c.muted_until,0)`
+
+// mutedForever is the muted_until of a chat muted without an end.
+const mutedForever = -1
+
+func applyChatState(c *Chat, archived, pinned int, mutedUntil int64) {
+	c.Archived = archived != 0
+	c.Pinned = pinned != 0
+	switch {
+	case mutedUntil == mutedForever:
+		c.Muted = true
+	case mutedUntil > time.Now().Unix():
+		c.Muted = true
+		c.MutedUntil = fromUnix(mutedUntil)
+	}
+}
+
+// SetChatArchived archives or unarchives a chat. Archiving also unpins it,
+// as on the phone. It returns sql.ErrNoRows if the chat is unknown.
+func (d *DB) SetChatArchived(chatJID string, archived bool) error {
+	q := `UPDATE chats SET archived = ? WHERE jid = ?`
+	if archived {
+		q = `UPDATE chats SET archived = ?, pinned = 0 WHERE jid = ?`
+	}
+	return d.updateChatState(q, boolToInt(archived), chatJID)
+}
+
+// SetChatPinned pins or unpins a chat. It returns sql.ErrNoRows if the chat
+// is unknown.
+func (d *DB) SetChatPinned(chatJID string, pinned bool) error {
+	return d.updateChatState(`UPDATE chats SET pinned = ? WHERE jid = ?`, boolToInt(pinned), chatJID)
+}
+
+// SetChatMuted mutes a chat until until, or forever when until is zero, or
+// unmutes it. It returns sql.ErrNoRows if the chat is unknown.
+func (d *DB) SetChatMuted(chatJID string, muted bool, until time.Time) error {
+	var v any
+	switch {
+	case muted && until.IsZero():
+		v = mutedForever
+	case muted:
+		v = unix(until)
+	}
+	return d.updateChatState(`UPDATE chats SET muted_until = ? WHERE jid = ?`, v, chatJID)
+}
+
+func (d *DB) updateChatState(q string, v any, chatJID string) error {
+	res, err := d.sql.Exec(q, v, chatJID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
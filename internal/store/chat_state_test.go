@@ -0,0 +1,62 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChatState(t *testing.T) {
+	db := openTestDB(t)
+	chat := "123@s.whatsapp.net"
+	if err := db.UpsertChat(chat, "dm", "Alice", time.Now()); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+
+	if err := db.SetChatPinned(chat, true); err != nil {
+		t.Fatalf("SetChatPinned: %v", err)
+	}
+	until := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	if err := db.SetChatMuted(chat, true, until); err != nil {
+		t.Fatalf("SetChatMuted: %v", err)
+	}
+	c, err := db.GetChat(chat)
+	if err != nil {
+		t.Fatalf("GetChat: %v", err)
+	}
+	if !c.Pinned || c.Archived || !c.Muted || !c.MutedUntil.Equal(until) {
+		t.Fatalf("unexpected state: %+v", c)
+	}
+
+	// Archiving unpins, as on the phone.
+	if err := db.SetChatArchived(chat, true); err != nil {
+		t.Fatalf("SetChatArchived: %v", err)
+	}
+	if err := db.SetChatMuted(chat, true, time.Time{}); err != nil {
+		t.Fatalf("SetChatMuted: %v", err)
+	}
+	chats, _, _, err := db.ListChatsPage(ListPageParams{})
+	if err != nil || len(chats) != 1 {
+		t.Fatalf("ListChatsPage: %d (%v)", len(chats), err)
+	}
+	if c := chats[0]; !c.Archived || c.Pinned || !c.Muted || !c.MutedUntil.IsZero() {
+		t.Fatalf("unexpected state after archive: %+v", c)
+	}
+
+	// A mute that ended is no mute.
+	if err := db.SetChatMuted(chat, true, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("SetChatMuted: %v", err)
+	}
+	if c, _ := db.GetChat(chat); c.Muted {
+		t.Fatalf("expired mute still muted: %+v", c)
+	}
+	if err := db.SetChatMuted(chat, false, time.Time{}); err != nil {
+		t.Fatalf("SetChatMuted: %v", err)
+	}
+	if c, _ := db.GetChat(chat); c.Muted || !c.MutedUntil.IsZero() {
+		t.Fatalf("unmuted chat still muted: %+v", c)
+	}
+
+	if err := db.SetChatPinned("999@s.whatsapp.net", true); !IsNotFound(err) {
+		t.Fatalf("expected not found for unknown chat, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidContactUpdate is returned by UpdateContacts for a malformed update.
+var ErrInvalidContactUpdate = errors.New("invalid contact update")
+
+// ContactUpdate changes a contact's local metadata. Nil members are left
+// as they are.
+type ContactUpdate struct {
+	JID string
+	// Alias sets the alias; an empty string removes it.
+	Alias *string
+	// Tags replaces the contact's tags.
+	Tags []string
+	// ReplaceTags must be set for Tags to apply, so an empty list can clear
+	// them.
+	ReplaceTags bool
+	// Fields sets metadata fields; a nil value removes the field.
+	Fields map[string]*string
+}
+
+// ContactFields returns a contact's metadata fields, or nil if it has none.
+func (d *DB) ContactFields(jid string) (map[string]string, error) {
+	rows, err := d.sql.Query(`SELECT key, value FROM contact_fields WHERE jid = ?`, jid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out map[string]string
+	for rows.Next() {
+		var k, v string
+		if err := rows.Scan(&k, &v); err != nil {
+			return nil, err
+		}
+		if out == nil {
+			out = map[string]string{}
+		}
+		out[k] = v
+	}
+	return out, rows.Err()
+}
+
+// UpdateContacts applies the updates in one transaction: if any of them is
+// invalid, none is applied.
+func (d *DB) UpdateContacts(updates []ContactUpdate) error {
+	tx, err := d.sql.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC().Unix()
+	for i, u := range updates {
+		jid := strings.TrimSpace(u.JID)
+		if jid == "" {
+			return fmt.Errorf("%w %d: jid is required", ErrInvalidContactUpdate, i)
+		}
+		if u.Alias != nil {
+			if alias := strings.TrimSpace(*u.Alias); alias == "" {
+				if _, err := tx.Exec(`DELETE FROM contact_aliases WHERE jid = ?`, jid); err != nil {
+					return err
+				}
+			} else if _, err := tx.Exec(`
+				INSERT INTO contact_aliases(jid, alias, notes, updated_at)
+				VALUES (?, ?, NULL, ?)
+				ON CONFLICT(jid) DO UPDATE SET alias=excluded.alias, updated_at=excluded.updated_at
+			`, jid, alias, now); err != nil {
+				return err
+			}
+		}
+		if u.ReplaceTags {
+			if _, err := tx.Exec(`DELETE FROM contact_tags WHERE jid = ?`, jid); err != nil {
+				return err
+			}
+			for _, tag := range u.Tags {
+				tag = strings.TrimSpace(tag)
+				if tag == "" {
+					return fmt.Errorf("%w %d (%s): tags must not be empty", ErrInvalidContactUpdate, i, jid)
+				}
+				if _, err := tx.Exec(`INSERT OR IGNORE INTO contact_tags(jid, tag, updated_at) VALUES(?, ?, ?)`, jid, tag, now); err != nil {
+					return err
+				}
+			}
+		}
+		for key, value := range u.Fields {
+			key = strings.TrimSpace(key)
+			if key == "" {
+				return fmt.Errorf("%w %d (%s): field names must not be empty", ErrInvalidContactUpdate, i, jid)
+			}
+			if value == nil {
+				if _, err := tx.Exec(`DELETE FROM contact_fields WHERE jid = ? AND key = ?`, jid, key); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := tx.Exec(`
+				INSERT INTO contact_fields(jid, key, value, updated_at) VALUES(?, ?, ?, ?)
+				ON CONFLICT(jid, key) DO UPDATE SET value=excluded.value, updated_at=excluded.updated_at
+			`, jid, key, *value, now); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUpdateContactsAppliesAllOrNothing(t *testing.T) {
+	db := openTestDB(t)
+	alice, bob := "1@s.whatsapp.net", "2@s.whatsapp.net"
+	if err := db.UpsertContact(alice, "1", "Alice", "", "", ""); err != nil {
+		t.Fatalf("UpsertContact: %v", err)
+	}
+	if err := db.AddTag(alice, "old"); err != nil {
+		t.Fatalf("AddTag: %v", err)
+	}
+	alias, crm, tier := "Ally", "c-1", "gold"
+	err := db.UpdateContacts([]ContactUpdate{
+		{JID: alice, Alias: &alias, Tags: []string{"vip", "lead"}, ReplaceTags: true, Fields: map[string]*string{"crm_id": &crm, "tier": &tier}},
+		{JID: bob, Alias: &alias},
+	})
+	if err != nil {
+		t.Fatalf("UpdateContacts: %v", err)
+	}
+	c, err := db.GetContact(alice)
+	if err != nil {
+		t.Fatalf("GetContact: %v", err)
+	}
+	if c.Alias != "Ally" || !reflect.DeepEqual(c.Tags, []string{"lead", "vip"}) || !reflect.DeepEqual(c.Fields, map[string]string{"crm_id": "c-1", "tier": "gold"}) {
+		t.Fatalf("unexpected contact: %+v", c)
+	}
+
+	// An invalid update rolls back the valid ones before it.
+	empty := ""
+	err = db.UpdateContacts([]ContactUpdate{
+		{JID: alice, Alias: &empty, Fields: map[string]*string{"tier": nil}},
+		{JID: bob, Fields: map[string]*string{" ": &crm}},
+	})
+	if err == nil {
+		t.Fatalf("expected error for empty field name")
+	}
+	if c, _ := db.GetContact(alice); c.Alias != "Ally" || c.Fields["tier"] != "gold" {
+		t.Fatalf("failed batch was partially applied: %+v", c)
+	}
+
+	if err := db.UpdateContacts([]ContactUpdate{{JID: alice, Alias: &empty, ReplaceTags: true, Fields: map[string]*string{"tier": nil}}}); err != nil {
+		t.Fatalf("UpdateContacts: %v", err)
+	}
+	if c, _ := db.GetContact(alice); c.Alias != "" || len(c.Tags) != 0 || !reflect.DeepEqual(c.Fields, map[string]string{"crm_id": "c-1"}) {
+		t.Fatalf("unexpected contact after removals: %+v", c)
+	}
+}
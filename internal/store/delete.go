@@ -0,0 +1,114 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// messageDerivedTables hold per-message data keyed by (chat_jid, msg_id)
+// that goes with a deleted message.
+var messageDerivedTables = []string{"entities", "message_sentiment", "receipts", "message_revisions", "message_callbacks", "media_thumbnails", "media_duplicates"}
+
+// chatScopedTables hold per-chat data keyed by chat_jid that goes with a
+// purged chat, in addition to messageDerivedTables.
+var chatScopedTables = []string{"calls", "chat_retention", "chat_snoozes"}
+
+// DeleteMessage removes one message and the data derived from it from the
+// local store; nothing is deleted on WhatsApp. It returns the path of the
+// downloaded media file, if no other message shares it, for the caller to
+// remove, sql.ErrNoRows if the message is unknown and ErrLegalHold if its
+// chat is on legal hold.
+func (d *DB) DeleteMessage(chatJID, msgID string) (string, error) {
+	if err := checkLegalHold(d.sql, chatJID); err != nil {
+		return "", err
+	}
+	n, paths, err := d.deleteMessagesWhere(`chat_jid = ? AND msg_id = ?`, chatJID, msgID)
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return "", sql.ErrNoRows
+	}
+	if len(paths) > 0 {
+		return paths[0], nil
+	}
+	return "", nil
+}
+
+// DeleteMessagesBefore removes a chat's messages older than before from the
+// local store, like DeleteMessage. It returns how many were deleted and the
+// downloaded media files only they referenced.
+func (d *DB) DeleteMessagesBefore(chatJID string, before time.Time) (int, []string, error) {
+	if err := checkLegalHold(d.sql, chatJID); err != nil {
+		return 0, nil, err
+	}
+	return d.deleteMessagesWhere(`chat_jid = ? AND ts < ?`, chatJID, unix(before))
+}
+
+func (d *DB) deleteMessagesWhere(where string, args ...interface{}) (int, []string, error) {
+	tx, err := d.sql.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.Query(`SELECT chat_jid, msg_id, COALESCE(local_path,'') FROM messages WHERE `+where, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	var paths []string
+	var keys [][2]string
+	for rows.Next() {
+		var k [2]string
+		var p string
+		if err := rows.Scan(&k[0], &k[1], &p); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		keys = append(keys, k)
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	for _, table := range messageDerivedTables {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE (chat_jid, msg_id) IN (SELECT chat_jid, msg_id FROM messages WHERE `+where+`)`, args...); err != nil {
+			return 0, nil, fmt.Errorf("delete %s: %w", table, err)
+		}
+	}
+	res, err := tx.Exec(`DELETE FROM messages WHERE `+where, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	n, _ := res.RowsAffected()
+
+	// Media is stored once per content hash, so a file may still be used by
+	// messages that remain.
+	var unused []string
+	seen := map[string]bool{}
+	for _, p := range paths {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		var inUse int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM messages WHERE local_path = ?`, p).Scan(&inUse); err != nil {
+			return 0, nil, err
+		}
+		if inUse == 0 {
+			unused = append(unused, p)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, nil, err
+	}
+	if err := d.chainDeleted(keys); err != nil {
+		return 0, nil, err
+	}
+	return int(n), unused, nil
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeleteMessagesBeforeRemovesDerivedRows(t *testing.T) {
+	db := openTestDB(t)
+	chat, other := "123@s.whatsapp.net", "456@s.whatsapp.net"
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, jid := range []string{chat, other} {
+		if err := db.UpsertChat(jid, "dm", "", base); err != nil {
+			t.Fatalf("UpsertChat: %v", err)
+		}
+		for i, id := range []string{"old", "new"} {
+			if err := db.UpsertMessage(UpsertMessageParams{ChatJID: jid, MsgID: id, SenderJID: jid, Timestamp: base.Add(time.Duration(i) * time.Hour), Text: id}); err != nil {
+				t.Fatalf("UpsertMessage: %v", err)
+			}
+		}
+	}
+	if err := db.MarkMediaDownloaded(chat, "old", "/tmp/old.jpg", "", base); err != nil {
+		t.Fatalf("MarkMediaDownloaded: %v", err)
+	}
+	if err := db.RecordReceipt(chat, chat, "read", []string{"old", "new"}, base); err != nil {
+		t.Fatalf("RecordReceipt: %v", err)
+	}
+
+	n, paths, err := db.DeleteMessagesBefore(chat, base.Add(30*time.Minute))
+	if err != nil || n != 1 || len(paths) != 1 || paths[0] != "/tmp/old.jpg" {
+		t.Fatalf("DeleteMessagesBefore = %d, %v, %v", n, paths, err)
+	}
+	if got := countRows(t, db.sql, `SELECT COUNT(*) FROM receipts WHERE chat_jid = ?`, chat); got != 1 {
+		t.Fatalf("receipts left = %d, want 1", got)
+	}
+	if got := countRows(t, db.sql, `SELECT COUNT(*) FROM messages WHERE chat_jid = ?`, other); got != 2 {
+		t.Fatalf("other chat lost messages: %d", got)
+	}
+	if _, err := db.GetMessage(chat, "old"); !IsNotFound(err) {
+		t.Fatalf("expected deleted message to be gone, got %v", err)
+	}
+}
+
+func TestDeleteMessageKeepsSharedMedia(t *testing.T) {
+	db := openTestDB(t)
+	a, b := "123@s.whatsapp.net", "456@s.whatsapp.net"
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	const sum = "ab12"
+	for _, jid := range []string{a, b} {
+		if err := db.UpsertChat(jid, "dm", "", base); err != nil {
+			t.Fatalf("UpsertChat: %v", err)
+		}
+		if err := db.UpsertMessage(UpsertMessageParams{ChatJID: jid, MsgID: "fwd", SenderJID: jid, Timestamp: base, MediaType: "image"}); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+		if err := db.MarkMediaDownloaded(jid, "fwd", "/tmp/media/ab12.jpg", sum, base); err != nil {
+			t.Fatalf("MarkMediaDownloaded: %v", err)
+		}
+	}
+	if paths, err := db.MediaPathsBySHA256(sum); err != nil || len(paths) != 1 || paths[0] != "/tmp/media/ab12.jpg" {
+		t.Fatalf("MediaPathsBySHA256 = %v, %v", paths, err)
+	}
+	if info, _ := db.GetMediaDownloadInfo(a, "fwd"); info.LocalSHA256 != sum {
+		t.Fatalf("LocalSHA256 = %q, want %q", info.LocalSHA256, sum)
+	}
+
+	if path, err := db.DeleteMessage(a, "fwd"); err != nil || path != "" {
+		t.Fatalf("DeleteMessage = %q, %v; want the shared file kept", path, err)
+	}
+	if path, err := db.DeleteMessage(b, "fwd"); err != nil || path != "/tmp/media/ab12.jpg" {
+		t.Fatalf("DeleteMessage = %q, %v; want the file released", path, err)
+	}
+}
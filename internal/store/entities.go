@@ -0,0 +1,102 @@
+package store
+
+import (
+	"strings"
+	"time"
+)
+
+type Entity struct {
+	ID         int64
+	ChatJID    string
+	ChatName   string
+	MsgID      string
+	Type       string
+	Value      string
+	Normalized string
+	Timestamp  time.Time
+}
+
+// ReplaceMessageEntities stores the entities found in one message, replacing
+// whatever was extracted from it before (edits, re-syncs).
+func (d *DB) ReplaceMessageEntities(chatJID, msgID string, ts time.Time, entities []Entity) error {
+	tx, err := d.sql.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`DELETE FROM entities WHERE chat_jid = ? AND msg_id = ?`, chatJID, msgID); err != nil {
+		return err
+	}
+	for _, e := range entities {
+		if _, err := tx.Exec(`
+			INSERT OR IGNORE INTO entities(chat_jid, msg_id, type, value, normalized, ts)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, chatJID, msgID, e.Type, e.Value, e.Normalized, unix(ts)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+type ListEntitiesParams struct {
+	Type    string
+	ChatJID string
+	Query   string
+	After   *time.Time
+	Before  *time.Time
+	Limit   int
+}
+
+// ListEntities returns extracted entities, newest first.
+func (d *DB) ListEntities(p ListEntitiesParams) ([]Entity, error) {
+	if p.Limit <= 0 {
+		p.Limit = 50
+	}
+	query := `
+		SELECT e.id, e.chat_jid, COALESCE(c.name,''), e.msg_id, e.type, e.value, e.normalized, e.ts
+		FROM entities e
+		LEFT JOIN chats c ON c.jid = e.chat_jid
+		WHERE 1=1` + notTrashedSQL
+	var args []interface{}
+	if strings.TrimSpace(p.Type) != "" {
+		query += " AND e.type = ?"
+		args = append(args, p.Type)
+	}
+	if strings.TrimSpace(p.ChatJID) != "" {
+		query += " AND e.chat_jid = ?"
+		args = append(args, p.ChatJID)
+	}
+	if q := strings.TrimSpace(p.Query); q != "" {
+		query += " AND (e.value LIKE ? OR e.normalized LIKE ?)"
+		args = append(args, "%"+q+"%", "%"+q+"%")
+	}
+	if p.After != nil {
+		query += " AND e.ts > ?"
+		args = append(args, unix(*p.After))
+	}
+	if p.Before != nil {
+		query += " AND e.ts < ?"
+		args = append(args, unix(*p.Before))
+	}
+	query += " ORDER BY e.ts DESC, e.id DESC LIMIT ?"
+	args = append(args, p.Limit)
+
+	rows, err := d.sql.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Entity
+	for rows.Next() {
+		var e Entity
+		var ts int64
+		if err := rows.Scan(&e.ID, &e.ChatJID, &e.ChatName, &e.MsgID, &e.Type, &e.Value, &e.Normalized, &ts); err != nil {
+			return nil, err
+		}
+		e.Timestamp = fromUnix(ts)
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEntitiesReplaceAndFilter(t *testing.T) {
+	db := openTestDB(t)
+
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := db.UpsertChat("c@s.whatsapp.net", "dm", "Carol", ts); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	if err := db.ReplaceMessageEntities("c@s.whatsapp.net", "m1", ts, []Entity{
+		{Type: "tracking_number", Value: "aa123456789br", Normalized: "AA123456789BR"},
+		{Type: "amount", Value: "R$ 10", Normalized: "BRL 10"},
+	}); err != nil {
+		t.Fatalf("ReplaceMessageEntities: %v", err)
+	}
+	if err := db.ReplaceMessageEntities("c@s.whatsapp.net", "m2", ts.Add(time.Hour), []Entity{
+		{Type: "amount", Value: "$5", Normalized: "USD 5"},
+	}); err != nil {
+		t.Fatalf("ReplaceMessageEntities: %v", err)
+	}
+
+	amounts, err := db.ListEntities(ListEntitiesParams{Type: "amount"})
+	if err != nil {
+		t.Fatalf("ListEntities: %v", err)
+	}
+	if len(amounts) != 2 || amounts[0].MsgID != "m2" || amounts[1].ChatName != "Carol" {
+		t.Fatalf("unexpected amounts: %+v", amounts)
+	}
+
+	if err := db.ReplaceMessageEntities("c@s.whatsapp.net", "m1", ts, nil); err != nil {
+		t.Fatalf("ReplaceMessageEntities clear: %v", err)
+	}
+	all, err := db.ListEntities(ListEntitiesParams{Query: "usd"})
+	if err != nil {
+		t.Fatalf("ListEntities: %v", err)
+	}
+	if len(all) != 1 || all[0].Normalized != "USD 5" {
+		t.Fatalf("unexpected entities after replace: %+v", all)
+	}
+}
@@ -0,0 +1,276 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FTS index states reported by FTSStatus.
+const (
+	FTSReady        = "ready"
+	FTSRebuilding   = "rebuilding"
+	FTSNeedsRebuild = "needs_rebuild"
+	// FTSUnavailable means SQLite was built without FTS5; search always
+	// uses LIKE and a rebuild cannot help.
+	FTSUnavailable = "unavailable"
+)
+
+// ftsRebuildBatch is how many messages rowids one rebuild step covers.
+const ftsRebuildBatch = 5000
+
+// ErrFTSRebuildRunning is returned when a rebuild is already in progress.
+var ErrFTSRebuildRunning = errors.New("fts rebuild already running")
+
+// FTSStatus describes the full-text index. While it is not ready, search
+// falls back to LIKE.
+type FTSStatus struct {
+	State string
+	// Done and Total count indexed messages during a rebuild.
+	Done  int64
+	Total int64
+	// Error is why the index needs a rebuild, or why the last one failed.
+	Error string
+}
+
+func (d *DB) FTSStatus() FTSStatus {
+	d.ftsMu.Lock()
+	defer d.ftsMu.Unlock()
+	return d.ftsStatus
+}
+
+// FTSNeedsRebuild reports whether the index is missing, stale or corrupt.
+func (d *DB) FTSNeedsRebuild() bool {
+	return d.FTSStatus().State == FTSNeedsRebuild
+}
+
+func (d *DB) setFTSStatus(st FTSStatus) {
+	d.ftsMu.Lock()
+	d.ftsStatus = st
+	d.ftsMu.Unlock()
+}
+
+// ensureMessagesFTS sets up the FTS index on open. A healthy index is used
+// as is; a missing, stale or corrupt one is left for RebuildFTS so opening
+// a large database does not block on reindexing.
+func (d *DB) ensureMessagesFTS() error {
+	ftsExists, err := d.tableExists("messages_fts")
+	if err != nil {
+		return err
+	}
+	if ftsExists {
+		hasDisplay, err := d.tableHasColumn("messages_fts", "display_text")
+		if err != nil {
+			return err
+		}
+		if !hasDisplay {
+			if _, err := d.sql.Exec(`DROP TABLE IF EXISTS messages_fts`); err != nil {
+				return fmt.Errorf("drop messages_fts: %w", err)
+			}
+			ftsExists = false
+		}
+	}
+
+	if ftsExists {
+		if err := d.checkFTS(); err != nil {
+			// Writes go through the triggers, so a corrupt index must not
+			// stay attached to messages until it is rebuilt.
+			if dropErr := d.dropFTSTriggers(); dropErr != nil {
+				return dropErr
+			}
+			state := FTSNeedsRebuild
+			if strings.Contains(err.Error(), "no such module") {
+				state = FTSUnavailable
+			}
+			d.setFTSStatus(FTSStatus{State: state, Error: err.Error()})
+			return nil
+		}
+		if err := d.createFTSTriggers(); err != nil {
+			d.setFTSStatus(FTSStatus{State: FTSUnavailable, Error: err.Error()})
+			return nil
+		}
+		d.setFTSStatus(FTSStatus{State: FTSReady})
+		d.ftsEnabled.Store(true)
+		return nil
+	}
+
+	if err := d.createFTSTable(); err != nil {
+		// Continue without FTS (fallback to LIKE).
+		d.setFTSStatus(FTSStatus{State: FTSUnavailable, Error: err.Error()})
+		return nil
+	}
+	if err := d.createFTSTriggers(); err != nil {
+		d.setFTSStatus(FTSStatus{State: FTSUnavailable, Error: err.Error()})
+		return nil
+	}
+	var n int64
+	if err := d.sql.QueryRow(`SELECT COUNT(*) FROM messages`).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		// New messages are indexed by the triggers already; existing ones
+		// need a rebuild before search can use the index.
+		d.setFTSStatus(FTSStatus{State: FTSNeedsRebuild, Error: "fts index missing"})
+		return nil
+	}
+	d.setFTSStatus(FTSStatus{State: FTSReady})
+	d.ftsEnabled.Store(true)
+	return nil
+}
+
+// checkFTS verifies the index covers every message and can be queried.
+func (d *DB) checkFTS() error {
+	var indexed, total int64
+	if err := d.sql.QueryRow(`SELECT (SELECT COUNT(*) FROM messages_fts), (SELECT COUNT(*) FROM messages)`).Scan(&indexed, &total); err != nil {
+		return fmt.Errorf("fts index unreadable: %w", err)
+	}
+	if indexed != total {
+		return fmt.Errorf("fts index is stale: %d of %d messages indexed", indexed, total)
+	}
+	var rowid int64
+	err := d.sql.QueryRow(`SELECT rowid FROM messages_fts WHERE messages_fts MATCH 'a*' LIMIT 1`).Scan(&rowid)
+	if err != nil && !IsNotFound(err) {
+		return fmt.Errorf("fts index unreadable: %w", err)
+	}
+	return nil
+}
+
+// isFTSCorrupt reports whether a search error means the index is damaged,
+// as opposed to e.g. a malformed MATCH query.
+func isFTSCorrupt(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "malformed") || strings.Contains(msg, "corrupt") || strings.Contains(msg, "no such table: messages_fts")
+}
+
+// markFTSBroken switches search to LIKE after the index failed at runtime.
+func (d *DB) markFTSBroken(err error) {
+	d.ftsEnabled.Store(false)
+	_ = d.dropFTSTriggers()
+	if !d.ftsRebuilding.Load() {
+		d.setFTSStatus(FTSStatus{State: FTSNeedsRebuild, Error: err.Error()})
+	}
+}
+
+// RebuildFTS recreates the full-text index from the messages table. It
+// works in batches so the database stays writable, and calls progress after
+// each batch. Search uses LIKE until the rebuild completes. An interrupted
+// rebuild leaves the index marked as needing a rebuild.
+func (d *DB) RebuildFTS(ctx context.Context, progress func(done, total int64)) (err error) {
+	if !d.ftsRebuilding.CompareAndSwap(false, true) {
+		return ErrFTSRebuildRunning
+	}
+	defer d.ftsRebuilding.Store(false)
+
+	d.ftsEnabled.Store(false)
+	var maxRowid, total int64
+	if err := d.sql.QueryRowContext(ctx, `SELECT COALESCE(MAX(rowid),0), COUNT(*) FROM messages`).Scan(&maxRowid, &total); err != nil {
+		return err
+	}
+	d.setFTSStatus(FTSStatus{State: FTSRebuilding, Total: total})
+	var done int64
+	unavailable := false
+	defer func() {
+		if err != nil && !unavailable {
+			d.setFTSStatus(FTSStatus{State: FTSNeedsRebuild, Done: done, Total: total, Error: err.Error()})
+		}
+	}()
+
+	if err := d.dropFTSTriggers(); err != nil {
+		return err
+	}
+	if _, err := d.sql.ExecContext(ctx, `DROP TABLE IF EXISTS messages_fts`); err != nil {
+		return fmt.Errorf("drop messages_fts: %w", err)
+	}
+	if err := d.createFTSTable(); err != nil {
+		unavailable = true
+		d.setFTSStatus(FTSStatus{State: FTSUnavailable, Error: err.Error()})
+		return fmt.Errorf("fts5 unavailable: %w", err)
+	}
+	// Messages written during the rebuild are indexed by the triggers; the
+	// backfill replaces rows it meets again, so nothing is indexed twice.
+	if err := d.createFTSTriggers(); err != nil {
+		return err
+	}
+
+	for last := int64(0); last < maxRowid; last += ftsRebuildBatch {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		res, err := d.sql.ExecContext(ctx, `
+			INSERT OR REPLACE INTO messages_fts(rowid, text, media_caption, filename, chat_name, sender_name, display_text)
+			SELECT rowid,
+			       COALESCE(text,''),
+			       COALESCE(media_caption,''),
+			       COALESCE(filename,''),
+			       COALESCE(chat_name,''),
+			       COALESCE(sender_name,''),
+			       COALESCE(display_text,'')
+			FROM messages
+			WHERE rowid > ? AND rowid <= ?
+		`, last, last+ftsRebuildBatch)
+		if err != nil {
+			return fmt.Errorf("index messages: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		done = min(done+n, total)
+		d.setFTSStatus(FTSStatus{State: FTSRebuilding, Done: done, Total: total})
+		if progress != nil {
+			progress(done, total)
+		}
+	}
+
+	d.setFTSStatus(FTSStatus{State: FTSReady, Done: total, Total: total})
+	d.ftsEnabled.Store(true)
+	return nil
+}
+
+func (d *DB) createFTSTable() error {
+	_, err := d.sql.Exec(`
+		CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(
+			text,
+			media_caption,
+			filename,
+			chat_name,
+			sender_name,
+			display_text
+		);
+	`)
+	return err
+}
+
+func (d *DB) dropFTSTriggers() error {
+	if _, err := d.sql.Exec(`
+		DROP TRIGGER IF EXISTS messages_ai;
+		DROP TRIGGER IF EXISTS messages_ad;
+		DROP TRIGGER IF EXISTS messages_au;
+	`); err != nil {
+		return fmt.Errorf("drop fts triggers: %w", err)
+	}
+	return nil
+}
+
+// createFTSTriggers keeps messages_fts in step with messages (FTS5 supports
+// DELETE directly).
+func (d *DB) createFTSTriggers() error {
+	if err := d.dropFTSTriggers(); err != nil {
+		return err
+	}
+	_, err := d.sql.Exec(`
+		CREATE TRIGGER messages_ai AFTER INSERT ON messages BEGIN
+			INSERT INTO messages_fts(rowid, text, media_caption, filename, chat_name, sender_name, display_text)
+			VALUES (new.rowid, COALESCE(new.text,''), COALESCE(new.media_caption,''), COALESCE(new.filename,''), COALESCE(new.chat_name,''), COALESCE(new.sender_name,''), COALESCE(new.display_text,''));
+		END;
+
+		CREATE TRIGGER messages_ad AFTER DELETE ON messages BEGIN
+			DELETE FROM messages_fts WHERE rowid = old.rowid;
+		END;
+
+		CREATE TRIGGER messages_au AFTER UPDATE ON messages BEGIN
+			DELETE FROM messages_fts WHERE rowid = old.rowid;
+			INSERT INTO messages_fts(rowid, text, media_caption, filename, chat_name, sender_name, display_text)
+			VALUES (new.rowid, COALESCE(new.text,''), COALESCE(new.media_caption,''), COALESCE(new.filename,''), COALESCE(new.chat_name,''), COALESCE(new.sender_name,''), COALESCE(new.display_text,''));
+		END;
+	`)
+	return err
+}
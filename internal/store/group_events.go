@@ -0,0 +1,118 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GroupEvent is one recorded group membership change. ActorJID is empty
+// when WhatsApp did not say who made it.
+type GroupEvent struct {
+	ID             int64
+	GroupJID       string
+	Action         string
+	ParticipantJID string
+	ActorJID       string
+	// ParticipantName and ActorName come from contacts and aliases and are
+	// empty when unknown.
+	ParticipantName string
+	ActorName       string
+	Timestamp       time.Time
+}
+
+// AddGroupEvents records membership changes. The same change seen again,
+// e.g. from a live event and a later history sync, is stored once.
+func (d *DB) AddGroupEvents(events []GroupEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	tx, err := d.sql.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	now := unix(time.Now().UTC())
+	for _, e := range events {
+		if strings.TrimSpace(e.GroupJID) == "" || strings.TrimSpace(e.ParticipantJID) == "" {
+			return fmt.Errorf("group and participant are required")
+		}
+		if strings.TrimSpace(e.Action) == "" {
+			return fmt.Errorf("action is required")
+		}
+		ts := e.Timestamp
+		if ts.IsZero() {
+			ts = time.Now().UTC()
+		}
+		if _, err := tx.Exec(`
+			INSERT OR IGNORE INTO group_events(group_jid, action, participant_jid, actor_jid, ts, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, e.GroupJID, e.Action, e.ParticipantJID, e.ActorJID, unix(ts), now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+type ListGroupEventsParams struct {
+	GroupJID       string
+	ParticipantJID string // changes to or made by this participant
+	Action         string
+	After          *time.Time
+	Before         *time.Time
+	Limit          int
+}
+
+// ListGroupEvents returns a group's membership changes, newest first.
+func (d *DB) ListGroupEvents(p ListGroupEventsParams) ([]GroupEvent, error) {
+	if p.Limit <= 0 {
+		p.Limit = 100
+	}
+	q := `
+		SELECT e.id, e.group_jid, e.action, e.participant_jid, e.actor_jid,
+			COALESCE(NULLIF(pa.alias,''), NULLIF(pc.full_name,''), NULLIF(pc.push_name,''), NULLIF(pc.business_name,''), ''),
+			COALESCE(NULLIF(aa.alias,''), NULLIF(ac.full_name,''), NULLIF(ac.push_name,''), NULLIF(ac.business_name,''), ''),
+			e.ts
+		FROM group_events e
+		LEFT JOIN contacts pc ON pc.jid = e.participant_jid
+		LEFT JOIN contact_aliases pa ON pa.jid = e.participant_jid
+		LEFT JOIN contacts ac ON ac.jid = e.actor_jid
+		LEFT JOIN contact_aliases aa ON aa.jid = e.actor_jid
+		WHERE e.group_jid = ?`
+	args := []interface{}{p.GroupJID}
+	if strings.TrimSpace(p.ParticipantJID) != "" {
+		q += ` AND (e.participant_jid = ? OR e.actor_jid = ?)`
+		args = append(args, p.ParticipantJID, p.ParticipantJID)
+	}
+	if strings.TrimSpace(p.Action) != "" {
+		q += ` AND e.action = ?`
+		args = append(args, p.Action)
+	}
+	if p.After != nil {
+		q += ` AND e.ts > ?`
+		args = append(args, unix(*p.After))
+	}
+	if p.Before != nil {
+		q += ` AND e.ts < ?`
+		args = append(args, unix(*p.Before))
+	}
+	q += ` ORDER BY e.ts DESC, e.id DESC LIMIT ?`
+	args = append(args, p.Limit)
+
+	rows, err := d.sql.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []GroupEvent
+	for rows.Next() {
+		var e GroupEvent
+		var ts int64
+		if err := rows.Scan(&e.ID, &e.GroupJID, &e.Action, &e.ParticipantJID, &e.ActorJID, &e.ParticipantName, &e.ActorName, &ts); err != nil {
+			return nil, err
+		}
+		e.Timestamp = fromUnix(ts)
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
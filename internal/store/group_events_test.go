@@ -0,0 +1,60 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGroupEvents(t *testing.T) {
+	db := openTestDB(t)
+	group := "120363000000000000@g.us"
+	admin := "5511999990000@s.whatsapp.net"
+	bob := "5511888880000@s.whatsapp.net"
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	if err := db.UpsertContact(bob, "5511888880000", "Bob", "", "", ""); err != nil {
+		t.Fatalf("UpsertContact: %v", err)
+	}
+	if err := db.SetAlias(admin, "Boss"); err != nil {
+		t.Fatalf("SetAlias: %v", err)
+	}
+
+	evs := []GroupEvent{
+		{GroupJID: group, Action: "add", ParticipantJID: bob, ActorJID: admin, Timestamp: at},
+		{GroupJID: group, Action: "promote", ParticipantJID: bob, ActorJID: admin, Timestamp: at.Add(time.Hour)},
+		{GroupJID: group, Action: "remove", ParticipantJID: bob, ActorJID: admin, Timestamp: at.Add(2 * time.Hour)},
+		{GroupJID: "other@g.us", Action: "add", ParticipantJID: bob, Timestamp: at},
+	}
+	if err := db.AddGroupEvents(evs); err != nil {
+		t.Fatalf("AddGroupEvents: %v", err)
+	}
+	// Seen again from history sync.
+	if err := db.AddGroupEvents(evs[:1]); err != nil {
+		t.Fatalf("AddGroupEvents again: %v", err)
+	}
+	if err := db.AddGroupEvents([]GroupEvent{{GroupJID: group, Action: "add"}}); err == nil {
+		t.Fatalf("expected an error without participant")
+	}
+
+	got, err := db.ListGroupEvents(ListGroupEventsParams{GroupJID: group})
+	if err != nil || len(got) != 3 {
+		t.Fatalf("ListGroupEvents: %+v (%v)", got, err)
+	}
+	if got[0].Action != "remove" || got[0].ParticipantName != "Bob" || got[0].ActorName != "Boss" || !got[0].Timestamp.Equal(at.Add(2*time.Hour)) {
+		t.Fatalf("newest = %+v", got[0])
+	}
+
+	removed, _ := db.ListGroupEvents(ListGroupEventsParams{GroupJID: group, Action: "remove"})
+	if len(removed) != 1 {
+		t.Fatalf("removed = %+v", removed)
+	}
+	after := at.Add(30 * time.Minute)
+	recent, _ := db.ListGroupEvents(ListGroupEventsParams{GroupJID: group, After: &after, Limit: 1})
+	if len(recent) != 1 || recent[0].Action != "remove" {
+		t.Fatalf("recent = %+v", recent)
+	}
+	byActor, _ := db.ListGroupEvents(ListGroupEventsParams{GroupJID: group, ParticipantJID: admin})
+	if len(byActor) != 3 {
+		t.Fatalf("by actor = %+v", byActor)
+	}
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GroupSnapshot is a group's participant list at one point in time.
+type GroupSnapshot struct {
+	ID        int64
+	GroupJID  string
+	GroupName string
+	// Members are participant JIDs, sorted.
+	Members []string
+	TakenAt time.Time
+}
+
+// AddGroupSnapshot stores a snapshot; TakenAt defaults to now.
+func (d *DB) AddGroupSnapshot(s GroupSnapshot) (GroupSnapshot, error) {
+	if strings.TrimSpace(s.GroupJID) == "" {
+		return GroupSnapshot{}, fmt.Errorf("group is required")
+	}
+	if s.TakenAt.IsZero() {
+		s.TakenAt = time.Now().UTC()
+	}
+	members := append([]string{}, s.Members...)
+	sort.Strings(members)
+	s.Members = members
+	raw, err := json.Marshal(members)
+	if err != nil {
+		return GroupSnapshot{}, err
+	}
+	res, err := d.sql.Exec(`
+		INSERT INTO group_snapshots(group_jid, group_name, members, member_count, taken_at) VALUES (?, ?, ?, ?, ?)
+	`, s.GroupJID, s.GroupName, string(raw), len(members), unix(s.TakenAt))
+	if err != nil {
+		return GroupSnapshot{}, err
+	}
+	s.ID, err = res.LastInsertId()
+	return s, err
+}
+
+// LatestGroupSnapshot returns the newest snapshot of group, or an error
+// matching IsNotFound if none was taken.
+func (d *DB) LatestGroupSnapshot(groupJID string) (GroupSnapshot, error) {
+	return scanGroupSnapshot(d.sql.QueryRow(`
+		SELECT id, group_jid, group_name, members, taken_at FROM group_snapshots
+		WHERE group_jid = ? ORDER BY taken_at DESC, id DESC LIMIT 1
+	`, groupJID))
+}
+
+// GroupSnapshotAt returns the newest snapshot of group taken at or before
+// t, or an error matching IsNotFound.
+func (d *DB) GroupSnapshotAt(groupJID string, t time.Time) (GroupSnapshot, error) {
+	return scanGroupSnapshot(d.sql.QueryRow(`
+		SELECT id, group_jid, group_name, members, taken_at FROM group_snapshots
+		WHERE group_jid = ? AND taken_at <= ? ORDER BY taken_at DESC, id DESC LIMIT 1
+	`, groupJID, unix(t)))
+}
+
+// ListGroupSnapshots returns up to limit snapshots of group, newest first,
+// optionally only those taken after a time.
+func (d *DB) ListGroupSnapshots(groupJID string, after *time.Time, limit int) ([]GroupSnapshot, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	q := `SELECT id, group_jid, group_name, members, taken_at FROM group_snapshots WHERE group_jid = ?`
+	args := []interface{}{groupJID}
+	if after != nil {
+		q += ` AND taken_at > ?`
+		args = append(args, unix(*after))
+	}
+	q += ` ORDER BY taken_at DESC, id DESC LIMIT ?`
+	args = append(args, limit)
+	rows, err := d.sql.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []GroupSnapshot
+	for rows.Next() {
+		s, err := scanGroupSnapshot(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func scanGroupSnapshot(row rowScanner) (GroupSnapshot, error) {
+	var s GroupSnapshot
+	var raw string
+	var taken int64
+	if err := row.Scan(&s.ID, &s.GroupJID, &s.GroupName, &raw, &taken); err != nil {
+		return GroupSnapshot{}, err
+	}
+	if err := json.Unmarshal([]byte(raw), &s.Members); err != nil {
+		return GroupSnapshot{}, fmt.Errorf("snapshot %d: %w", s.ID, err)
+	}
+	s.TakenAt = fromUnix(taken)
+	return s, nil
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGroupSnapshots(t *testing.T) {
+	db := openTestDB(t)
+	group := "120363000000000001@g.us"
+	t0 := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, err := db.LatestGroupSnapshot(group); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	for i, members := range [][]string{{"2@s.whatsapp.net", "1@s.whatsapp.net"}, {"1@s.whatsapp.net"}, {"1@s.whatsapp.net", "3@s.whatsapp.net"}} {
+		if _, err := db.AddGroupSnapshot(GroupSnapshot{GroupJID: group, GroupName: "Ops", Members: members, TakenAt: t0.Add(time.Duration(i) * time.Hour)}); err != nil {
+			t.Fatalf("AddGroupSnapshot: %v", err)
+		}
+	}
+	if _, err := db.AddGroupSnapshot(GroupSnapshot{GroupJID: "other@g.us", TakenAt: t0}); err != nil {
+		t.Fatalf("AddGroupSnapshot: %v", err)
+	}
+
+	latest, err := db.LatestGroupSnapshot(group)
+	if err != nil {
+		t.Fatalf("LatestGroupSnapshot: %v", err)
+	}
+	if !latest.TakenAt.Equal(t0.Add(2*time.Hour)) || len(latest.Members) != 2 || latest.GroupName != "Ops" {
+		t.Fatalf("unexpected latest snapshot: %+v", latest)
+	}
+
+	all, err := db.ListGroupSnapshots(group, nil, 0)
+	if err != nil {
+		t.Fatalf("ListGroupSnapshots: %v", err)
+	}
+	if len(all) != 3 || all[2].Members[0] != "1@s.whatsapp.net" {
+		t.Fatalf("expected 3 snapshots newest first with sorted members, got %+v", all)
+	}
+	after := t0.Add(30 * time.Minute)
+	later, err := db.ListGroupSnapshots(group, &after, 0)
+	if err != nil || len(later) != 2 {
+		t.Fatalf("ListGroupSnapshots after = %+v, %v", later, err)
+	}
+	at, err := db.GroupSnapshotAt(group, after)
+	if err != nil || !at.TakenAt.Equal(t0) {
+		t.Fatalf("GroupSnapshotAt = %+v, %v", at, err)
+	}
+	if _, err := db.GroupSnapshotAt(group, t0.Add(-time.Hour)); !IsNotFound(err) {
+		t.Fatalf("expected not found before the first snapshot, got %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"sort"
+	"time"
+)
+
+type ActivityHeatmapParams struct {
+	ChatJID string
+	After   time.Time
+	Before  time.Time
+	// Location buckets messages by local weekday and hour (default UTC).
+	Location *time.Location
+}
+
+// HourlyCounts counts messages by weekday (Sunday = 0) and hour of day.
+type HourlyCounts [7][24]int
+
+// ActivityHeatmap is a chat's message activity by weekday and hour, overall
+// and per member.
+type ActivityHeatmap struct {
+	Total   int
+	Counts  HourlyCounts
+	Members []MemberActivity
+}
+
+type MemberActivity struct {
+	SenderJID  string
+	SenderName string
+	FromMe     bool
+	Total      int
+	Counts     HourlyCounts
+}
+
+// ActivityHeatmap buckets a chat's messages by sender, weekday and hour,
+// busiest member first. System notices are not counted, and all messages
+// sent from this account count as one member.
+func (d *DB) ActivityHeatmap(p ActivityHeatmapParams) (ActivityHeatmap, error) {
+	loc := p.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	q := `SELECT COALESCE(m.sender_jid,''), COALESCE(m.sender_name,''), m.from_me != 0, m.ts
+		FROM messages m JOIN chats c ON c.jid = m.chat_jid
+		WHERE m.chat_jid = ? AND COALESCE(m.system_type,'') = ''` + notTrashedSQL
+	args := []interface{}{p.ChatJID}
+	if !p.After.IsZero() {
+		q += " AND m.ts >= ?"
+		args = append(args, unix(p.After))
+	}
+	if !p.Before.IsZero() {
+		q += " AND m.ts < ?"
+		args = append(args, unix(p.Before))
+	}
+	rows, err := d.sql.Query(q+" ORDER BY m.ts", args...)
+	if err != nil {
+		return ActivityHeatmap{}, err
+	}
+	defer rows.Close()
+
+	type memberKey struct {
+		jid    string
+		fromMe bool
+	}
+	var out ActivityHeatmap
+	members := map[memberKey]*MemberActivity{}
+	for rows.Next() {
+		var (
+			jid, name string
+			fromMe    bool
+			ts        int64
+		)
+		if err := rows.Scan(&jid, &name, &fromMe, &ts); err != nil {
+			return ActivityHeatmap{}, err
+		}
+		if fromMe {
+			jid = ""
+		}
+		k := memberKey{jid, fromMe}
+		m := members[k]
+		if m == nil {
+			m = &MemberActivity{SenderJID: jid, FromMe: fromMe}
+			members[k] = m
+		}
+		if name != "" {
+			// Rows are oldest first, so the latest name wins.
+			m.SenderName = name
+		}
+		t := time.Unix(ts, 0).In(loc)
+		day, hour := int(t.Weekday()), t.Hour()
+		m.Counts[day][hour]++
+		m.Total++
+		out.Counts[day][hour]++
+		out.Total++
+	}
+	if err := rows.Err(); err != nil {
+		return ActivityHeatmap{}, err
+	}
+
+	for _, m := range members {
+		out.Members = append(out.Members, *m)
+	}
+	sort.Slice(out.Members, func(i, j int) bool {
+		a, b := out.Members[i], out.Members[j]
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		return a.SenderJID < b.SenderJID
+	})
+
+	return out, nil
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActivityHeatmap(t *testing.T) {
+	db := openTestDB(t)
+	group := "123@g.us"
+	// 2024-01-01 was a Monday.
+	mon9 := time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)
+	if err := db.UpsertChat(group, "group", "Team", mon9); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	for i, p := range []UpsertMessageParams{
+		{SenderJID: "ann@s.whatsapp.net", SenderName: "Ann", Timestamp: mon9},
+		{SenderJID: "ann@s.whatsapp.net", SenderName: "Annie", Timestamp: mon9.Add(10 * time.Minute)},
+		{SenderJID: "bob@s.whatsapp.net", Timestamp: mon9.Add(24 * time.Hour)},
+		{FromMe: true, SenderJID: "me@s.whatsapp.net", Timestamp: mon9},
+		{SenderJID: "bob@s.whatsapp.net", Timestamp: mon9, SystemType: "group_participants"},
+		{SenderJID: "bob@s.whatsapp.net", Timestamp: mon9.Add(-48 * time.Hour)},
+	} {
+		p.ChatJID = group
+		p.MsgID = string(rune('a' + i))
+		p.Text = "x"
+		if err := db.UpsertMessage(p); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+
+	hm, err := db.ActivityHeatmap(ActivityHeatmapParams{ChatJID: group, After: mon9.Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("ActivityHeatmap: %v", err)
+	}
+	if hm.Total != 4 || hm.Counts[1][9] != 3 || hm.Counts[2][9] != 1 {
+		t.Fatalf("heatmap = %d %v", hm.Total, hm.Counts)
+	}
+	if len(hm.Members) != 3 {
+		t.Fatalf("members = %+v", hm.Members)
+	}
+	ann := hm.Members[0]
+	if ann.SenderJID != "ann@s.whatsapp.net" || ann.SenderName != "Annie" || ann.Total != 2 || ann.Counts[1][9] != 2 {
+		t.Fatalf("ann = %+v", ann)
+	}
+	if me := hm.Members[1]; !me.FromMe || me.SenderJID != "" || me.Total != 1 {
+		t.Fatalf("me = %+v", me)
+	}
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+	hm, err = db.ActivityHeatmap(ActivityHeatmapParams{ChatJID: group, After: mon9.Add(-time.Hour), Location: ny})
+	if err != nil {
+		t.Fatalf("ActivityHeatmap: %v", err)
+	}
+	if hm.Counts[1][4] != 3 {
+		t.Fatalf("New York heatmap = %v", hm.Counts)
+	}
+}
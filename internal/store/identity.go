@@ -0,0 +1,75 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// IdentityChange records that a contact's identity key changed, i.e. their
+// security code with us is different from before.
+type IdentityChange struct {
+	ID  int64
+	JID string
+	// Implicit is set when the change was noticed on an incoming message
+	// rather than announced by the server.
+	Implicit  bool
+	ChangedAt time.Time
+}
+
+func (d *DB) RecordIdentityChange(jid string, implicit bool, at time.Time) (IdentityChange, error) {
+	if strings.TrimSpace(jid) == "" {
+		return IdentityChange{}, fmt.Errorf("jid is required")
+	}
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+	res, err := d.sql.Exec(`INSERT INTO identity_changes(jid, implicit, changed_at) VALUES (?, ?, ?)`, jid, boolToInt(implicit), unix(at))
+	if err != nil {
+		return IdentityChange{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return IdentityChange{}, err
+	}
+	return IdentityChange{ID: id, JID: jid, Implicit: implicit, ChangedAt: fromUnix(unix(at))}, nil
+}
+
+// ListIdentityChanges returns identity changes newest first, optionally
+// limited to one contact and to changes after since.
+func (d *DB) ListIdentityChanges(jid string, since time.Time, limit int) ([]IdentityChange, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	q := `SELECT id, jid, implicit, changed_at FROM identity_changes WHERE 1=1`
+	var args []interface{}
+	if strings.TrimSpace(jid) != "" {
+		q += ` AND jid = ?`
+		args = append(args, jid)
+	}
+	if !since.IsZero() {
+		q += ` AND changed_at > ?`
+		args = append(args, unix(since))
+	}
+	q += ` ORDER BY changed_at DESC, id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := d.sql.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []IdentityChange
+	for rows.Next() {
+		var c IdentityChange
+		var implicit int
+		var at int64
+		if err := rows.Scan(&c.ID, &c.JID, &implicit, &at); err != nil {
+			return nil, err
+		}
+		c.Implicit = implicit != 0
+		c.ChangedAt = fromUnix(at)
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
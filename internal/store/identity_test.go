@@ -0,0 +1,40 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdentityChanges(t *testing.T) {
+	db := openTestDB(t)
+	alice := "5511999990000@s.whatsapp.net"
+	bob := "5511999990001@s.whatsapp.net"
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, err := db.RecordIdentityChange(alice, false, t0); err != nil {
+		t.Fatalf("RecordIdentityChange: %v", err)
+	}
+	if _, err := db.RecordIdentityChange(bob, true, t0.Add(time.Hour)); err != nil {
+		t.Fatalf("RecordIdentityChange: %v", err)
+	}
+	ic, err := db.RecordIdentityChange(alice, true, t0.Add(2*time.Hour))
+	if err != nil || ic.ID == 0 || !ic.ChangedAt.Equal(t0.Add(2*time.Hour)) {
+		t.Fatalf("unexpected change: %+v (%v)", ic, err)
+	}
+	if _, err := db.RecordIdentityChange(" ", false, t0); err == nil {
+		t.Fatalf("expected error for empty jid")
+	}
+
+	all, err := db.ListIdentityChanges("", time.Time{}, 10)
+	if err != nil || len(all) != 3 || all[0].ID != ic.ID || !all[0].Implicit {
+		t.Fatalf("unexpected changes: %+v (%v)", all, err)
+	}
+	mine, _ := db.ListIdentityChanges(alice, time.Time{}, 10)
+	if len(mine) != 2 {
+		t.Fatalf("expected 2 changes for alice, got %+v", mine)
+	}
+	recent, _ := db.ListIdentityChanges("", t0, 10)
+	if len(recent) != 2 || recent[1].JID != bob {
+		t.Fatalf("unexpected changes since t0: %+v", recent)
+	}
+}
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// WhatsApp addresses accounts by phone number JID (@s.whatsapp.net) or by
+// LID (@lid), depending on the chat and the client. wacli stores both forms
+// of an account under its phone number JID once the pair is known, so a
+// person does not appear as two contacts.
+
+// contactLIDSQL selects a contact's LID, and notMappedLIDSQL leaves out LID
+// contacts whose phone number JID is known, in queries on contacts c.
+const (
+	contactLIDSQL   = `COALESCE((SELECT l.lid FROM lid_map l WHERE l.phone_jid = c.jid ORDER BY l.updated_at DESC LIMIT 1), '')`
+	notMappedLIDSQL = ` AND NOT EXISTS (SELECT 1 FROM lid_map l WHERE l.lid = c.jid)`
+)
+
+// PutLIDMapping records that lid and phoneJID (both without device) are the
+// same account. It reports whether the mapping is new or changed; the first
+// time, messages stored with the LID as sender are moved to the phone number
+// JID and a chat with the LID is merged into the phone number's chat. Moved
+// messages are re-chained in the same transaction, so the archive chain
+// still verifies.
+func (d *DB) PutLIDMapping(lid, phoneJID string) (bool, error) {
+	d.chainMu.Lock()
+	defer d.chainMu.Unlock()
+
+	tx, err := d.sql.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	var current string
+	err = tx.QueryRow(`SELECT phone_jid FROM lid_map WHERE lid = ?`, lid).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+	if current == phoneJID {
+		return false, nil
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO lid_map(lid, phone_jid, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(lid) DO UPDATE SET phone_jid=excluded.phone_jid, updated_at=excluded.updated_at
+	`, lid, phoneJID, unix(time.Now().UTC())); err != nil {
+		return false, err
+	}
+
+	// Only messages already in the chain are re-chained; unchained ones are
+	// left for SealArchive.
+	rows, err := tx.Query(`
+		SELECT chat_jid, msg_id FROM messages m
+		WHERE (sender_jid = ? OR chat_jid = ?)
+			AND EXISTS (SELECT 1 FROM archive_chain c WHERE c.chat_jid = m.chat_jid AND c.msg_id = m.msg_id)
+	`, lid, lid)
+	if err != nil {
+		return false, err
+	}
+	var keys [][2]string
+	for rows.Next() {
+		var k [2]string
+		if err := rows.Scan(&k[0], &k[1]); err != nil {
+			rows.Close()
+			return false, err
+		}
+		keys = append(keys, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	if err := mergeLIDChat(tx, lid, phoneJID); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`UPDATE messages SET sender_jid = ? WHERE sender_jid = ?`, phoneJID, lid); err != nil {
+		return false, err
+	}
+	for _, k := range keys {
+		if err := chainMessageIn(tx, k[0], k[1]); err != nil {
+			return false, err
+		}
+		if k[0] == lid {
+			if err := chainMessageIn(tx, phoneJID, k[1]); err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, tx.Commit()
+}
+
+// mergeLIDChat moves the chat stored under lid, with its messages and
+// everything derived from them, to phoneJID. Messages the phone number's
+// chat already has are dropped with the LID chat.
+func mergeLIDChat(tx *sql.Tx, lid, phoneJID string) error {
+	var n int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM chats WHERE jid = ?`, lid).Scan(&n); err != nil || n == 0 {
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO chats(jid, kind, name, last_message_ts, read_ts, deleted_at, archived, pinned, muted_until)
+		SELECT ?, kind, name, last_message_ts, read_ts, deleted_at, archived, pinned, muted_until FROM chats WHERE jid = ?
+		ON CONFLICT(jid) DO UPDATE SET
+			name=COALESCE(NULLIF(chats.name,''), excluded.name),
+			last_message_ts=MAX(COALESCE(chats.last_message_ts,0), COALESCE(excluded.last_message_ts,0)),
+			read_ts=MAX(COALESCE(chats.read_ts,0), COALESCE(excluded.read_ts,0))
+	`, phoneJID, lid); err != nil {
+		return fmt.Errorf("merge chat: %w", err)
+	}
+	// A legal hold on either chat holds the merged one.
+	if _, err := tx.Exec(`
+		UPDATE chat_retention SET legal_hold = 1
+		WHERE chat_jid = ? AND EXISTS (SELECT 1 FROM chat_retention WHERE chat_jid = ? AND legal_hold = 1)
+	`, phoneJID, lid); err != nil {
+		return fmt.Errorf("merge chat_retention: %w", err)
+	}
+	tables := append(append([]string{"messages"}, messageDerivedTables...), chatScopedTables...)
+	for _, table := range tables {
+		if _, err := tx.Exec(`UPDATE OR IGNORE `+table+` SET chat_jid = ? WHERE chat_jid = ?`, phoneJID, lid); err != nil {
+			return fmt.Errorf("merge %s: %w", table, err)
+		}
+	}
+	if _, err := tx.Exec(`UPDATE media_duplicates SET original_chat_jid = ? WHERE original_chat_jid = ?`, phoneJID, lid); err != nil {
+		return fmt.Errorf("merge media_duplicates: %w", err)
+	}
+	for _, table := range tables[1:] {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE chat_jid = ?`, lid); err != nil {
+			return fmt.Errorf("merge %s: %w", table, err)
+		}
+	}
+	// Leftover messages go with the chat (ON DELETE CASCADE).
+	_, err := tx.Exec(`DELETE FROM chats WHERE jid = ?`, lid)
+	return err
+}
+
+// PhoneForLID returns the phone number JID of a LID, or an error matching
+// IsNotFound.
+func (d *DB) PhoneForLID(lid string) (string, error) {
+	var pn string
+	err := d.sql.QueryRow(`SELECT phone_jid FROM lid_map WHERE lid = ?`, lid).Scan(&pn)
+	return pn, err
+}
+
+// LIDForPhone returns the most recently seen LID of a phone number JID, or
+// an error matching IsNotFound.
+func (d *DB) LIDForPhone(phoneJID string) (string, error) {
+	var lid string
+	err := d.sql.QueryRow(`SELECT lid FROM lid_map WHERE phone_jid = ? ORDER BY updated_at DESC LIMIT 1`, phoneJID).Scan(&lid)
+	return lid, err
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLIDMappingMergesContacts(t *testing.T) {
+	db := openTestDB(t)
+	lid := "987654321@lid"
+	pn := "5511999990000@s.whatsapp.net"
+
+	if err := db.UpsertContact(pn, "5511999990000", "Ana", "Ana Souza", "", ""); err != nil {
+		t.Fatalf("UpsertContact: %v", err)
+	}
+	if err := db.UpsertContact(lid, "987654321", "Ana", "", "", ""); err != nil {
+		t.Fatalf("UpsertContact: %v", err)
+	}
+	if err := db.UpsertChat("123@g.us", "group", "Team", time.Now()); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	if err := db.UpsertMessage(UpsertMessageParams{ChatJID: "123@g.us", MsgID: "m1", SenderJID: lid, Timestamp: time.Now(), Text: "hi"}); err != nil {
+		t.Fatalf("UpsertMessage: %v", err)
+	}
+	if _, _, total, _ := db.ListContactsPage(ListPageParams{}); total != 2 {
+		t.Fatalf("expected both forms before the mapping, got %d", total)
+	}
+
+	if _, err := db.PhoneForLID(lid); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if changed, err := db.PutLIDMapping(lid, pn); err != nil || !changed {
+		t.Fatalf("PutLIDMapping: changed=%v err=%v", changed, err)
+	}
+	if changed, err := db.PutLIDMapping(lid, pn); err != nil || changed {
+		t.Fatalf("repeated PutLIDMapping: changed=%v err=%v", changed, err)
+	}
+	if got, err := db.PhoneForLID(lid); err != nil || got != pn {
+		t.Fatalf("PhoneForLID = %q (%v)", got, err)
+	}
+	if got, err := db.LIDForPhone(pn); err != nil || got != lid {
+		t.Fatalf("LIDForPhone = %q (%v)", got, err)
+	}
+
+	page, _, total, err := db.ListContactsPage(ListPageParams{})
+	if err != nil || total != 1 || len(page) != 1 || page[0].JID != pn || page[0].LID != lid {
+		t.Fatalf("expected one merged contact, got total=%d %+v (%v)", total, page, err)
+	}
+	if found, _ := db.SearchContacts("Ana", 10); len(found) != 1 || found[0].JID != pn {
+		t.Fatalf("SearchContacts = %+v", found)
+	}
+	c, err := db.GetContact(lid)
+	if err != nil || c.JID != pn || c.Name != "Ana Souza" || c.LID != lid {
+		t.Fatalf("GetContact by LID = %+v (%v)", c, err)
+	}
+	m, err := db.GetMessage("123@g.us", "m1")
+	if err != nil || m.SenderJID != pn {
+		t.Fatalf("expected sender moved to the phone number JID, got %q (%v)", m.SenderJID, err)
+	}
+}
+
+func TestLIDMappingKeepsArchiveChain(t *testing.T) {
+	db := openTestDB(t)
+	lid := "987654321@lid"
+	pn := "5511999990000@s.whatsapp.net"
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if err := db.UpsertChat("123@g.us", "group", "Team", t0); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	if err := db.UpsertMessage(UpsertMessageParams{ChatJID: "123@g.us", MsgID: "g1", SenderJID: lid, Timestamp: t0, Text: "hi"}); err != nil {
+		t.Fatalf("UpsertMessage: %v", err)
+	}
+	// The same conversation stored under both forms; D1 is in both.
+	for _, chat := range []string{lid, pn} {
+		if err := db.UpsertChat(chat, "dm", "Ana", t0); err != nil {
+			t.Fatalf("UpsertChat: %v", err)
+		}
+		if err := db.UpsertMessage(UpsertMessageParams{ChatJID: chat, MsgID: "D1", SenderJID: chat, Timestamp: t0, Text: "hello"}); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+	if err := db.UpsertMessage(UpsertMessageParams{ChatJID: lid, MsgID: "D2", SenderJID: lid, Timestamp: t0.Add(time.Minute), Text: "later"}); err != nil {
+		t.Fatalf("UpsertMessage: %v", err)
+	}
+
+	if _, err := db.PutLIDMapping(lid, pn); err != nil {
+		t.Fatalf("PutLIDMapping: %v", err)
+	}
+	r, err := db.VerifyArchive()
+	if err != nil {
+		t.Fatalf("VerifyArchive: %v", err)
+	}
+	if !r.OK() || r.Messages != 3 || r.Verified != 3 {
+		t.Fatalf("unexpected report: %+v", r)
+	}
+	if _, err := db.GetChat(lid); !IsNotFound(err) {
+		t.Fatalf("expected the LID chat merged away, got %v", err)
+	}
+	for _, id := range []string{"D1", "D2"} {
+		m, err := db.GetMessage(pn, id)
+		if err != nil || m.SenderJID != pn {
+			t.Fatalf("GetMessage(%s) = %+v (%v)", id, m, err)
+		}
+	}
+}
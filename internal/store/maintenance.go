@@ -0,0 +1,200 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Maintenance window modes.
+const (
+	// MaintenanceSuppress drops matching webhook alerts.
+	MaintenanceSuppress = "suppress"
+	// MaintenanceBatch holds matching alerts back and sends one summary per
+	// recipient once the window ends.
+	MaintenanceBatch = "batch"
+)
+
+// MaintenanceWindow silences webhook alerts whose labels match Matchers
+// between StartsAt and EndsAt. Matchers is the JSON the caller stored; see
+// app.ParseMaintenanceMatchers.
+type MaintenanceWindow struct {
+	ID           int64
+	Matchers     string
+	Mode         string
+	Comment      string
+	CreatedBy    string
+	StartsAt     time.Time
+	EndsAt       time.Time
+	SummarizedAt time.Time
+	CreatedAt    time.Time
+	// Suppressed is the number of alerts the window held back.
+	Suppressed int64
+}
+
+// Active reports whether the window silences alerts at t.
+func (w MaintenanceWindow) Active(t time.Time) bool {
+	return !t.Before(w.StartsAt) && t.Before(w.EndsAt)
+}
+
+// SuppressedAlert is a webhook alert a maintenance window held back.
+type SuppressedAlert struct {
+	ID        int64
+	WindowID  int64
+	Recipient string
+	Message   string
+	CreatedAt time.Time
+}
+
+const maintenanceWindowColumns = `
+	w.id, w.matchers, w.mode, w.comment, w.created_by, w.starts_at, w.ends_at, COALESCE(w.summarized_at,0), w.created_at,
+	(SELECT COUNT(*) FROM maintenance_suppressed s WHERE s.window_id = w.id)`
+
+// AddMaintenanceWindow stores a new window. The caller is responsible for
+// checking that the matchers parse.
+func (d *DB) AddMaintenanceWindow(w MaintenanceWindow) (MaintenanceWindow, error) {
+	if strings.TrimSpace(w.Matchers) == "" {
+		return MaintenanceWindow{}, fmt.Errorf("matchers are required")
+	}
+	switch w.Mode {
+	case "":
+		w.Mode = MaintenanceSuppress
+	case MaintenanceSuppress, MaintenanceBatch:
+	default:
+		return MaintenanceWindow{}, fmt.Errorf("mode must be %s or %s", MaintenanceSuppress, MaintenanceBatch)
+	}
+	if !w.EndsAt.After(w.StartsAt) {
+		return MaintenanceWindow{}, fmt.Errorf("window must end after it starts")
+	}
+	res, err := d.sql.Exec(`
+		INSERT INTO maintenance_windows(matchers, mode, comment, created_by, starts_at, ends_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, w.Matchers, w.Mode, w.Comment, w.CreatedBy, unix(w.StartsAt), unix(w.EndsAt), unix(time.Now().UTC()))
+	if err != nil {
+		return MaintenanceWindow{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return MaintenanceWindow{}, err
+	}
+	return d.GetMaintenanceWindow(id)
+}
+
+// GetMaintenanceWindow returns window id, or an error matching IsNotFound.
+func (d *DB) GetMaintenanceWindow(id int64) (MaintenanceWindow, error) {
+	return scanMaintenanceWindow(d.sql.QueryRow(`SELECT `+maintenanceWindowColumns+` FROM maintenance_windows w WHERE w.id = ?`, id))
+}
+
+// ListMaintenanceWindows returns windows that have not ended by now, soonest
+// ending first, plus ended ones when includeExpired is set.
+func (d *DB) ListMaintenanceWindows(now time.Time, includeExpired bool) ([]MaintenanceWindow, error) {
+	q := `SELECT ` + maintenanceWindowColumns + ` FROM maintenance_windows w`
+	var args []interface{}
+	if !includeExpired {
+		q += ` WHERE w.ends_at > ?`
+		args = append(args, unix(now))
+	}
+	q += ` ORDER BY w.ends_at, w.id`
+	return d.queryMaintenanceWindows(q, args...)
+}
+
+// ActiveMaintenanceWindows returns the windows silencing alerts at now.
+func (d *DB) ActiveMaintenanceWindows(now time.Time) ([]MaintenanceWindow, error) {
+	return d.queryMaintenanceWindows(`SELECT `+maintenanceWindowColumns+` FROM maintenance_windows w
+		WHERE w.starts_at <= ? AND w.ends_at > ? ORDER BY w.id`, unix(now), unix(now))
+}
+
+// DueMaintenanceSummaries returns batching windows that ended by now and
+// whose summary has not been sent.
+func (d *DB) DueMaintenanceSummaries(now time.Time) ([]MaintenanceWindow, error) {
+	return d.queryMaintenanceWindows(`SELECT `+maintenanceWindowColumns+` FROM maintenance_windows w
+		WHERE w.mode = ? AND w.ends_at <= ? AND w.summarized_at IS NULL ORDER BY w.ends_at, w.id`, MaintenanceBatch, unix(now))
+}
+
+// ExpireMaintenanceWindow ends window id at now. A window that has not
+// started yet is deleted instead. It reports whether the window existed.
+func (d *DB) ExpireMaintenanceWindow(id int64, now time.Time) (bool, error) {
+	w, err := d.GetMaintenanceWindow(id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if now.Before(w.StartsAt) {
+		_, err = d.sql.Exec(`DELETE FROM maintenance_windows WHERE id = ?`, id)
+		return true, err
+	}
+	if w.EndsAt.After(now) {
+		_, err = d.sql.Exec(`UPDATE maintenance_windows SET ends_at = ? WHERE id = ?`, unix(now), id)
+	}
+	return true, err
+}
+
+// MarkMaintenanceSummarized records that the summary of window id was sent.
+func (d *DB) MarkMaintenanceSummarized(id int64) error {
+	_, err := d.sql.Exec(`UPDATE maintenance_windows SET summarized_at = ? WHERE id = ?`, unix(time.Now().UTC()), id)
+	return err
+}
+
+// AddSuppressedAlert records an alert window id held back.
+func (d *DB) AddSuppressedAlert(windowID int64, recipient, message string) error {
+	_, err := d.sql.Exec(`
+		INSERT INTO maintenance_suppressed(window_id, recipient, message, created_at) VALUES (?, ?, ?, ?)
+	`, windowID, recipient, message, unix(time.Now().UTC()))
+	return err
+}
+
+// ListSuppressedAlerts returns the alerts window id held back, oldest first.
+func (d *DB) ListSuppressedAlerts(windowID int64) ([]SuppressedAlert, error) {
+	rows, err := d.sql.Query(`
+		SELECT id, window_id, recipient, message, created_at FROM maintenance_suppressed
+		WHERE window_id = ? ORDER BY id
+	`, windowID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []SuppressedAlert
+	for rows.Next() {
+		var s SuppressedAlert
+		var created int64
+		if err := rows.Scan(&s.ID, &s.WindowID, &s.Recipient, &s.Message, &created); err != nil {
+			return nil, err
+		}
+		s.CreatedAt = fromUnix(created)
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func (d *DB) queryMaintenanceWindows(q string, args ...interface{}) ([]MaintenanceWindow, error) {
+	rows, err := d.sql.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []MaintenanceWindow
+	for rows.Next() {
+		w, err := scanMaintenanceWindow(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
+
+func scanMaintenanceWindow(row rowScanner) (MaintenanceWindow, error) {
+	var w MaintenanceWindow
+	var starts, ends, summarized, created int64
+	if err := row.Scan(&w.ID, &w.Matchers, &w.Mode, &w.Comment, &w.CreatedBy, &starts, &ends, &summarized, &created, &w.Suppressed); err != nil {
+		return MaintenanceWindow{}, err
+	}
+	w.StartsAt = fromUnix(starts)
+	w.EndsAt = fromUnix(ends)
+	w.SummarizedAt = fromUnix(summarized)
+	w.CreatedAt = fromUnix(created)
+	return w, nil
+}
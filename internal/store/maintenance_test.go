@@ -0,0 +1,81 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindows(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	matchers := `[{"name":"alertname","value":"DiskFull"}]`
+
+	if _, err := db.AddMaintenanceWindow(MaintenanceWindow{Matchers: matchers, StartsAt: now, EndsAt: now}); err == nil {
+		t.Fatalf("expected an error for an empty window")
+	}
+	if _, err := db.AddMaintenanceWindow(MaintenanceWindow{Matchers: matchers, Mode: "mute", StartsAt: now, EndsAt: now.Add(time.Hour)}); err == nil {
+		t.Fatalf("expected an error for an unknown mode")
+	}
+
+	active, err := db.AddMaintenanceWindow(MaintenanceWindow{Matchers: matchers, Mode: MaintenanceBatch, Comment: "db upgrade", StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("AddMaintenanceWindow: %v", err)
+	}
+	later, err := db.AddMaintenanceWindow(MaintenanceWindow{Matchers: matchers, StartsAt: now.Add(24 * time.Hour), EndsAt: now.Add(25 * time.Hour)})
+	if err != nil || later.Mode != MaintenanceSuppress {
+		t.Fatalf("AddMaintenanceWindow: %+v (%v)", later, err)
+	}
+
+	got, _ := db.ActiveMaintenanceWindows(now)
+	if len(got) != 1 || got[0].ID != active.ID || !got[0].Active(now) {
+		t.Fatalf("active = %+v", got)
+	}
+
+	if err := db.AddSuppressedAlert(active.ID, "5511999990000@s.whatsapp.net", "DiskFull on db1"); err != nil {
+		t.Fatalf("AddSuppressedAlert: %v", err)
+	}
+	if err := db.AddSuppressedAlert(active.ID, "5511999990000@s.whatsapp.net", "DiskFull on db2"); err != nil {
+		t.Fatalf("AddSuppressedAlert: %v", err)
+	}
+	if w, _ := db.GetMaintenanceWindow(active.ID); w.Suppressed != 2 {
+		t.Fatalf("suppressed = %d", w.Suppressed)
+	}
+
+	if due, _ := db.DueMaintenanceSummaries(now); len(due) != 0 {
+		t.Fatalf("expected no summary before the window ends, got %+v", due)
+	}
+	if ok, err := db.ExpireMaintenanceWindow(active.ID, now); !ok || err != nil {
+		t.Fatalf("ExpireMaintenanceWindow: %v %v", ok, err)
+	}
+	due, _ := db.DueMaintenanceSummaries(now)
+	if len(due) != 1 || due[0].ID != active.ID {
+		t.Fatalf("due = %+v", due)
+	}
+	alerts, _ := db.ListSuppressedAlerts(active.ID)
+	if len(alerts) != 2 || alerts[0].Message != "DiskFull on db1" {
+		t.Fatalf("alerts = %+v", alerts)
+	}
+	if err := db.MarkMaintenanceSummarized(active.ID); err != nil {
+		t.Fatalf("MarkMaintenanceSummarized: %v", err)
+	}
+	if due, _ := db.DueMaintenanceSummaries(now); len(due) != 0 {
+		t.Fatalf("expected the summary to be sent once, got %+v", due)
+	}
+
+	// A window that has not started is deleted rather than expired.
+	if ok, _ := db.ExpireMaintenanceWindow(later.ID, now); !ok {
+		t.Fatalf("expected the pending window to exist")
+	}
+	if _, err := db.GetMaintenanceWindow(later.ID); !IsNotFound(err) {
+		t.Fatalf("expected the pending window to be deleted, got %v", err)
+	}
+	if ok, _ := db.ExpireMaintenanceWindow(later.ID, now); ok {
+		t.Fatalf("expected no window")
+	}
+
+	current, _ := db.ListMaintenanceWindows(now, false)
+	all, _ := db.ListMaintenanceWindows(now, true)
+	if len(current) != 0 || len(all) != 1 {
+		t.Fatalf("current = %+v, all = %+v", current, all)
+	}
+}
@@ -0,0 +1,61 @@
+package store
+
+import "time"
+
+// MediaOriginal is the earliest message carrying the same file as a
+// duplicate.
+type MediaOriginal struct {
+	ChatJID   string
+	MsgID     string
+	SenderJID string
+	Timestamp time.Time
+}
+
+// FindMediaOriginal returns the earliest message other than chatJID/msgID
+// whose file has the plaintext SHA-256 sha and that was sent at or before
+// before, or an error matching IsNotFound. Files are compared by the hash
+// WhatsApp sends with the message, so nothing has to be downloaded.
+func (d *DB) FindMediaOriginal(sha []byte, chatJID, msgID string, before time.Time) (MediaOriginal, error) {
+	var o MediaOriginal
+	var ts int64
+	err := d.sql.QueryRow(`
+		SELECT chat_jid, msg_id, COALESCE(sender_jid,''), ts FROM messages
+		WHERE file_sha256 = ? AND ts <= ? AND NOT (chat_jid = ? AND msg_id = ?) AND revoked_at IS NULL
+		ORDER BY ts, rowid LIMIT 1
+	`, sha, unix(before), chatJID, msgID).Scan(&o.ChatJID, &o.MsgID, &o.SenderJID, &ts)
+	if err != nil {
+		return MediaOriginal{}, err
+	}
+	o.Timestamp = fromUnix(ts)
+	return o, nil
+}
+
+// MarkMediaDuplicate links a message to the original of its file.
+func (d *DB) MarkMediaDuplicate(chatJID, msgID string, orig MediaOriginal) error {
+	_, err := d.sql.Exec(`
+		INSERT INTO media_duplicates(chat_jid, msg_id, original_chat_jid, original_msg_id) VALUES (?, ?, ?, ?)
+		ON CONFLICT(chat_jid, msg_id) DO NOTHING
+	`, chatJID, msgID, orig.ChatJID, orig.MsgID)
+	return err
+}
+
+// mediaOriginalColumns and mediaOriginalJoin add a message's original to a
+// message query, scanned into a mediaOriginalRow.
+const (
+	mediaOriginalColumns = `COALESCE(md.original_chat_jid,''), COALESCE(md.original_msg_id,''), COALESCE(mo.sender_jid,''), COALESCE(mo.ts,0)`
+	mediaOriginalJoin    = `
+		LEFT JOIN media_duplicates md ON md.chat_jid = m.chat_jid AND md.msg_id = m.msg_id
+		LEFT JOIN messages mo ON mo.chat_jid = md.original_chat_jid AND mo.msg_id = md.original_msg_id`
+)
+
+type mediaOriginalRow struct {
+	chatJID, msgID, senderJID string
+	ts                        int64
+}
+
+func (r mediaOriginalRow) orNil() *MediaOriginal {
+	if r.msgID == "" {
+		return nil
+	}
+	return &MediaOriginal{ChatJID: r.chatJID, MsgID: r.msgID, SenderJID: r.senderJID, Timestamp: fromUnix(r.ts)}
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMediaDuplicates(t *testing.T) {
+	db := openTestDB(t)
+	alice := "111@s.whatsapp.net"
+	bob := "222@s.whatsapp.net"
+	sha := []byte{1, 2, 3, 4}
+	t0 := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	for _, chat := range []string{alice, bob} {
+		if err := db.UpsertChat(chat, "dm", "", t0); err != nil {
+			t.Fatalf("UpsertChat: %v", err)
+		}
+	}
+	for _, m := range []UpsertMessageParams{
+		{ChatJID: alice, MsgID: "orig", SenderJID: alice, Timestamp: t0, MediaType: "document", FileSHA256: sha},
+		{ChatJID: alice, MsgID: "other", SenderJID: alice, Timestamp: t0, MediaType: "document", FileSHA256: []byte{9}},
+		{ChatJID: bob, MsgID: "dup", SenderJID: bob, Timestamp: t0.Add(time.Hour), MediaType: "document", FileSHA256: sha},
+	} {
+		if err := db.UpsertMessage(m); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+
+	// The original itself has nothing earlier to point at.
+	if _, err := db.FindMediaOriginal(sha, alice, "orig", t0); !IsNotFound(err) {
+		t.Fatalf("expected not found for the original, got %v", err)
+	}
+	orig, err := db.FindMediaOriginal(sha, bob, "dup", t0.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("FindMediaOriginal: %v", err)
+	}
+	if orig.ChatJID != alice || orig.MsgID != "orig" || orig.SenderJID != alice || !orig.Timestamp.Equal(t0) {
+		t.Fatalf("unexpected original: %+v", orig)
+	}
+	// Messages sent later than before don't count as originals.
+	if _, err := db.FindMediaOriginal(sha, bob, "new", t0.Add(-time.Minute)); !IsNotFound(err) {
+		t.Fatalf("expected not found before the original, got %v", err)
+	}
+
+	if err := db.MarkMediaDuplicate(bob, "dup", orig); err != nil {
+		t.Fatalf("MarkMediaDuplicate: %v", err)
+	}
+	// Marking again keeps the first link.
+	if err := db.MarkMediaDuplicate(bob, "dup", MediaOriginal{ChatJID: alice, MsgID: "other"}); err != nil {
+		t.Fatalf("MarkMediaDuplicate: %v", err)
+	}
+
+	m, err := db.GetMessage(bob, "dup")
+	if err != nil {
+		t.Fatalf("GetMessage: %v", err)
+	}
+	if m.DuplicateOf == nil || m.DuplicateOf.MsgID != "orig" || m.DuplicateOf.ChatJID != alice || !m.DuplicateOf.Timestamp.Equal(t0) {
+		t.Fatalf("unexpected DuplicateOf: %+v", m.DuplicateOf)
+	}
+	if m, err := db.GetMessage(alice, "orig"); err != nil || m.DuplicateOf != nil {
+		t.Fatalf("original should not be a duplicate: %+v (%v)", m.DuplicateOf, err)
+	}
+
+	msgs, err := db.ListMessages(ListMessagesParams{ChatJID: bob})
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("ListMessages: %d (%v)", len(msgs), err)
+	}
+	if msgs[0].DuplicateOf == nil || msgs[0].DuplicateOf.SenderJID != alice {
+		t.Fatalf("expected DuplicateOf in list, got %+v", msgs[0].DuplicateOf)
+	}
+}
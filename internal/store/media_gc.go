@@ -0,0 +1,51 @@
+package store
+
+import "time"
+
+// DownloadedMedia is a message whose media was saved to disk.
+type DownloadedMedia struct {
+	ChatJID      string
+	MsgID        string
+	LocalPath    string
+	DownloadedAt time.Time
+	// Exempt is set when the chat is on legal hold or never pruned.
+	Exempt bool
+}
+
+// ListDownloadedMedia returns every message with a recorded local media
+// file. Several messages may share a file.
+func (d *DB) ListDownloadedMedia() ([]DownloadedMedia, error) {
+	rows, err := d.sql.Query(`
+		SELECT m.chat_jid, m.msg_id, m.local_path, COALESCE(m.downloaded_at,0),
+		       COALESCE(r.legal_hold,0) + COALESCE(r.never_prune,0) > 0
+		FROM messages m
+		LEFT JOIN chat_retention r ON r.chat_jid = m.chat_jid
+		WHERE COALESCE(m.local_path,'') != ''
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []DownloadedMedia
+	for rows.Next() {
+		var m DownloadedMedia
+		var at int64
+		if err := rows.Scan(&m.ChatJID, &m.MsgID, &m.LocalPath, &at, &m.Exempt); err != nil {
+			return nil, err
+		}
+		m.DownloadedAt = fromUnix(at)
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// ClearMediaDownloaded forgets the local file of every message that
+// references path, so the media is downloaded again when next requested.
+// It returns how many messages were updated.
+func (d *DB) ClearMediaDownloaded(path string) (int64, error) {
+	res, err := d.sql.Exec(`UPDATE messages SET local_path = NULL, local_sha256 = NULL, downloaded_at = NULL WHERE local_path = ?`, path)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
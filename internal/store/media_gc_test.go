@@ -0,0 +1,55 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClearMediaDownloadedForgetsSharedFile(t *testing.T) {
+	db := openTestDB(t)
+	chat := "123@s.whatsapp.net"
+	if err := db.UpsertChat(chat, "dm", "Alice", time.Now()); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	when := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, id := range []string{"a", "b", "c"} {
+		if err := db.UpsertMessage(UpsertMessageParams{ChatJID: chat, MsgID: id, SenderJID: chat, Timestamp: when, MediaType: "image"}); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+	for id, path := range map[string]string{"a": "/m/shared.jpg", "b": "/m/shared.jpg", "c": "/m/other.jpg"} {
+		if err := db.MarkMediaDownloaded(chat, id, path, "", when); err != nil {
+			t.Fatalf("MarkMediaDownloaded: %v", err)
+		}
+	}
+
+	got, err := db.ListDownloadedMedia()
+	if err != nil {
+		t.Fatalf("ListDownloadedMedia: %v", err)
+	}
+	if len(got) != 3 || !got[0].DownloadedAt.Equal(when) {
+		t.Fatalf("unexpected downloads: %+v", got)
+	}
+
+	n, err := db.ClearMediaDownloaded("/m/shared.jpg")
+	if err != nil {
+		t.Fatalf("ClearMediaDownloaded: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 messages cleared, got %d", n)
+	}
+	got, err = db.ListDownloadedMedia()
+	if err != nil {
+		t.Fatalf("ListDownloadedMedia: %v", err)
+	}
+	if len(got) != 1 || got[0].MsgID != "c" {
+		t.Fatalf("expected only c to remain downloaded, got %+v", got)
+	}
+	info, err := db.GetMediaDownloadInfo(chat, "a")
+	if err != nil {
+		t.Fatalf("GetMediaDownloadInfo: %v", err)
+	}
+	if info.LocalPath != "" || !info.DownloadedAt.IsZero() {
+		t.Fatalf("expected download record cleared, got %+v", info)
+	}
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"strings"
+	"time"
+)
+
+// NumberCheck is WhatsApp's answer to whether a phone number has an
+// account. JID is the account WhatsApp returned, which can differ from the
+// number asked for (e.g. a missing mobile prefix digit).
+type NumberCheck struct {
+	Phone        string
+	OnWhatsApp   bool
+	JID          string
+	BusinessName string
+	CheckedAt    time.Time
+}
+
+// SaveNumberChecks stores lookup results, replacing older answers for the
+// same numbers.
+func (d *DB) SaveNumberChecks(checks []NumberCheck) error {
+	if len(checks) == 0 {
+		return nil
+	}
+	tx, err := d.sql.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	for _, c := range checks {
+		at := c.CheckedAt
+		if at.IsZero() {
+			at = time.Now().UTC()
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO number_checks(phone, on_whatsapp, jid, business_name, checked_at) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(phone) DO UPDATE SET
+				on_whatsapp = excluded.on_whatsapp,
+				jid = excluded.jid,
+				business_name = excluded.business_name,
+				checked_at = excluded.checked_at
+		`, c.Phone, boolToInt(c.OnWhatsApp), c.JID, c.BusinessName, unix(at)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetNumberChecks returns the stored answers for phones checked at or after
+// since, keyed by phone. Numbers without a recent answer are missing.
+func (d *DB) GetNumberChecks(phones []string, since time.Time) (map[string]NumberCheck, error) {
+	out := map[string]NumberCheck{}
+	if len(phones) == 0 {
+		return out, nil
+	}
+	args := make([]interface{}, 0, len(phones)+1)
+	for _, p := range phones {
+		args = append(args, p)
+	}
+	args = append(args, unix(since))
+	rows, err := d.sql.Query(`
+		SELECT phone, on_whatsapp, jid, business_name, checked_at FROM number_checks
+		WHERE phone IN (?`+strings.Repeat(",?", len(phones)-1)+`) AND checked_at >= ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c NumberCheck
+		var in int
+		var at int64
+		if err := rows.Scan(&c.Phone, &in, &c.JID, &c.BusinessName, &at); err != nil {
+			return nil, err
+		}
+		c.OnWhatsApp = in != 0
+		c.CheckedAt = fromUnix(at)
+		out[c.Phone] = c
+	}
+	return out, rows.Err()
+}
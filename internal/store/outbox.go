@@ -0,0 +1,140 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxFailed  = "failed"
+)
+
+type OutboxItem struct {
+	ID        int64
+	ToJID     string
+	Text      string
+	Status    string
+	Attempts  int
+	LastError string
+	MsgID     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	SentAt    time.Time
+}
+
+func (d *DB) EnqueueOutbox(toJID, text string) (OutboxItem, error) {
+	toJID = strings.TrimSpace(toJID)
+	if toJID == "" {
+		return OutboxItem{}, fmt.Errorf("recipient is required")
+	}
+	if strings.TrimSpace(text) == "" {
+		return OutboxItem{}, fmt.Errorf("text is required")
+	}
+	now := time.Now().UTC()
+	res, err := d.sql.Exec(`
+		INSERT INTO outbox(to_jid, text, status, attempts, created_at, updated_at)
+		VALUES (?, ?, ?, 0, ?, ?)
+	`, toJID, text, OutboxPending, unix(now), unix(now))
+	if err != nil {
+		return OutboxItem{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return OutboxItem{}, err
+	}
+	return d.GetOutboxItem(id)
+}
+
+func (d *DB) GetOutboxItem(id int64) (OutboxItem, error) {
+	row := d.sql.QueryRow(`
+		SELECT id, to_jid, text, status, attempts, COALESCE(last_error,''), COALESCE(msg_id,''), created_at, updated_at, COALESCE(sent_at,0)
+		FROM outbox WHERE id = ?
+	`, id)
+	return scanOutboxItem(row)
+}
+
+// ListOutbox returns outbox items oldest first. An empty status lists everything.
+func (d *DB) ListOutbox(status string, limit int) ([]OutboxItem, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	q := `SELECT id, to_jid, text, status, attempts, COALESCE(last_error,''), COALESCE(msg_id,''), created_at, updated_at, COALESCE(sent_at,0) FROM outbox WHERE 1=1`
+	var args []interface{}
+	if strings.TrimSpace(status) != "" {
+		q += ` AND status = ?`
+		args = append(args, status)
+	}
+	q += ` ORDER BY id ASC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := d.sql.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []OutboxItem
+	for rows.Next() {
+		item, err := scanOutboxItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+func (d *DB) CountOutbox(status string) (int64, error) {
+	row := d.sql.QueryRow(`SELECT COUNT(1) FROM outbox WHERE status = ?`, status)
+	var n int64
+	if err := row.Scan(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (d *DB) MarkOutboxSent(id int64, msgID string, sentAt time.Time) error {
+	_, err := d.sql.Exec(`
+		UPDATE outbox
+		SET status = ?, msg_id = ?, sent_at = ?, updated_at = ?, attempts = attempts + 1, last_error = NULL
+		WHERE id = ?
+	`, OutboxSent, msgID, unix(sentAt), unix(time.Now()), id)
+	return err
+}
+
+// MarkOutboxError records a failed attempt. When giveUp is set the item is
+// moved to the failed state and no longer retried.
+func (d *DB) MarkOutboxError(id int64, errMsg string, giveUp bool) error {
+	status := OutboxPending
+	if giveUp {
+		status = OutboxFailed
+	}
+	_, err := d.sql.Exec(`
+		UPDATE outbox
+		SET status = ?, last_error = ?, updated_at = ?, attempts = attempts + 1
+		WHERE id = ?
+	`, status, errMsg, unix(time.Now()), id)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOutboxItem(row rowScanner) (OutboxItem, error) {
+	var item OutboxItem
+	var created, updated, sent int64
+	var lastErr, msgID sql.NullString
+	if err := row.Scan(&item.ID, &item.ToJID, &item.Text, &item.Status, &item.Attempts, &lastErr, &msgID, &created, &updated, &sent); err != nil {
+		return OutboxItem{}, err
+	}
+	item.LastError = lastErr.String
+	item.MsgID = msgID.String
+	item.CreatedAt = fromUnix(created)
+	item.UpdatedAt = fromUnix(updated)
+	item.SentAt = fromUnix(sent)
+	return item, nil
+}
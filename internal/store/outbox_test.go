@@ -0,0 +1,65 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOutboxLifecycle(t *testing.T) {
+	db := openTestDB(t)
+
+	a, err := db.EnqueueOutbox("111@s.whatsapp.net", "hello")
+	if err != nil {
+		t.Fatalf("EnqueueOutbox: %v", err)
+	}
+	b, err := db.EnqueueOutbox("222@s.whatsapp.net", "world")
+	if err != nil {
+		t.Fatalf("EnqueueOutbox: %v", err)
+	}
+	if a.Status != OutboxPending || a.Attempts != 0 {
+		t.Fatalf("unexpected new item: %+v", a)
+	}
+	if _, err := db.EnqueueOutbox("", "x"); err == nil {
+		t.Fatalf("expected error for empty recipient")
+	}
+
+	if err := db.MarkOutboxSent(a.ID, "mid", time.Now()); err != nil {
+		t.Fatalf("MarkOutboxSent: %v", err)
+	}
+	if err := db.MarkOutboxError(b.ID, "boom", false); err != nil {
+		t.Fatalf("MarkOutboxError: %v", err)
+	}
+
+	pending, err := db.ListOutbox(OutboxPending, 10)
+	if err != nil {
+		t.Fatalf("ListOutbox: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != b.ID || pending[0].Attempts != 1 || pending[0].LastError != "boom" {
+		t.Fatalf("unexpected pending: %+v", pending)
+	}
+
+	got, err := db.GetOutboxItem(a.ID)
+	if err != nil {
+		t.Fatalf("GetOutboxItem: %v", err)
+	}
+	if got.Status != OutboxSent || got.MsgID != "mid" || got.SentAt.IsZero() {
+		t.Fatalf("unexpected sent item: %+v", got)
+	}
+
+	if err := db.MarkOutboxError(b.ID, "boom again", true); err != nil {
+		t.Fatalf("MarkOutboxError giveUp: %v", err)
+	}
+	if n, _ := db.CountOutbox(OutboxPending); n != 0 {
+		t.Fatalf("expected no pending items, got %d", n)
+	}
+	if n, _ := db.CountOutbox(OutboxFailed); n != 1 {
+		t.Fatalf("expected 1 failed item, got %d", n)
+	}
+	all, err := db.ListOutbox("", 10)
+	if err != nil {
+		t.Fatalf("ListOutbox all: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(all))
+	}
+}
@@ -0,0 +1,321 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Sort orders for ListChatsPage and ListContactsPage.
+const (
+	// SortByName orders A-Z by display name, case-insensitively.
+	SortByName = "name"
+	// SortByLastActivity orders by the latest message, newest first.
+	SortByLastActivity = "last_activity"
+	// SortByUnread orders by unread messages, most first, then like
+	// SortByLastActivity. Chat listings only.
+	SortByUnread = "unread"
+)
+
+type ListPageParams struct {
+	// Query filters by name or JID (substring match).
+	Query string
+	// Sort is SortByName, SortByLastActivity or SortByUnread; the default
+	// depends on the listing.
+	Sort string
+	// Reverse flips the sort order.
+	Reverse bool
+	Limit   int
+	// Cursor continues a listing from the next cursor of a previous page.
+	Cursor string
+	// Snoozed lists only snoozed chats; otherwise they are left out until
+	// their snooze expires. Chat listings only.
+	Snoozed bool
+	// Tags keeps contacts and chats with any of these tags.
+	Tags []string
+}
+
+// tagFilter restricts a listing to JIDs carrying any of tags.
+func tagFilter(tags []string, jidExpr string) (string, []interface{}) {
+	var args []interface{}
+	for _, t := range tags {
+		if t = strings.TrimSpace(t); t != "" {
+			args = append(args, t)
+		}
+	}
+	if len(args) == 0 {
+		return "", nil
+	}
+	return ` AND EXISTS (SELECT 1 FROM contact_tags t WHERE t.jid = ` + jidExpr + ` AND t.tag IN (?` + strings.Repeat(`, ?`, len(args)-1) + `))`, args
+}
+
+// pageCursor is the position after the last row of a page. It records the
+// sort it was issued for so it cannot be replayed against another order.
+type pageCursor struct {
+	Sort    string `json:"s"`
+	Reverse bool   `json:"r,omitempty"`
+	Name    string `json:"n,omitempty"`
+	TS      int64  `json:"t,omitempty"`
+	Unread  int64  `json:"u,omitempty"`
+	JID     string `json:"j"`
+}
+
+func encodePageCursor(c pageCursor) string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodePageCursor(s string) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageCursor{}, ErrInvalidCursor
+	}
+	var c pageCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.JID == "" {
+		return pageCursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// pageOrder builds the keyset condition and ORDER BY for a page. Rows are
+// ordered by the sort keys with the JID breaking ties, so pages never skip or
+// repeat a row even when names or timestamps collide. Listings without an
+// unread count pass an empty unreadExpr.
+func pageOrder(p ListPageParams, nameExpr, tsExpr, unreadExpr, jidExpr string) (cond, order string, args []interface{}, err error) {
+	var keys []string
+	desc := false
+	switch {
+	case p.Sort == SortByName:
+		keys = []string{nameExpr, jidExpr}
+	case p.Sort == SortByLastActivity:
+		keys = []string{tsExpr, jidExpr}
+		desc = true
+	case p.Sort == SortByUnread && unreadExpr != "":
+		keys = []string{unreadExpr, tsExpr, jidExpr}
+		desc = true
+	default:
+		return "", "", nil, fmt.Errorf("unknown sort %q", p.Sort)
+	}
+	if p.Reverse {
+		desc = !desc
+	}
+	cmp, dir := ">", "ASC"
+	if desc {
+		cmp, dir = "<", "DESC"
+	}
+	terms := make([]string, len(keys))
+	for i, k := range keys {
+		terms[i] = k + " " + dir
+	}
+	order = " ORDER BY " + strings.Join(terms, ", ")
+
+	if p.Cursor == "" {
+		return "", order, nil, nil
+	}
+	c, err := decodePageCursor(p.Cursor)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if c.Sort != p.Sort || c.Reverse != p.Reverse {
+		return "", "", nil, ErrInvalidCursor
+	}
+	var vals []interface{}
+	switch p.Sort {
+	case SortByName:
+		vals = []interface{}{c.Name, c.JID}
+	case SortByLastActivity:
+		vals = []interface{}{c.TS, c.JID}
+	case SortByUnread:
+		vals = []interface{}{c.Unread, c.TS, c.JID}
+	}
+	cond, args = keysetCond(keys, vals, cmp)
+	return " AND " + cond, order, args, nil
+}
+
+// keysetCond matches the rows after vals in the order of keys, e.g.
+// (a > ? OR (a = ? AND b > ?)) for two keys.
+func keysetCond(keys []string, vals []interface{}, cmp string) (string, []interface{}) {
+	if len(keys) == 1 {
+		return fmt.Sprintf("%s %s ?", keys[0], cmp), vals[:1]
+	}
+	rest, restArgs := keysetCond(keys[1:], vals[1:], cmp)
+	return fmt.Sprintf("(%s %s ? OR (%s = ? AND %s))", keys[0], cmp, keys[0], rest), append([]interface{}{vals[0], vals[0]}, restArgs...)
+}
+
+func (p ListPageParams) cursorAfter(name string, ts, unread int64, jid string) string {
+	c := pageCursor{Sort: p.Sort, Reverse: p.Reverse, JID: jid}
+	switch p.Sort {
+	case SortByLastActivity:
+		c.TS = ts
+	case SortByUnread:
+		c.TS, c.Unread = ts, unread
+	default:
+		c.Name = name
+	}
+	return encodePageCursor(c)
+}
+
+const chatSortNameSQL = `LOWER(COALESCE(NULLIF(c.name,''), c.jid))`
+
+// chatPreviewLen caps the message text in chat previews, in runes.
+const chatPreviewLen = 100
+
+// chatPreviewJoin joins the latest message of chats c as lm; revoked
+// messages are skipped.
+const chatPreviewJoin = ` LEFT JOIN messages lm ON lm.rowid = (SELECT m.rowid FROM messages m WHERE m.chat_jid = c.jid AND m.revoked_at IS NULL ORDER BY m.ts DESC, m.rowid DESC LIMIT 1)`
+
+const chatPreviewSQL = `COALESCE(lm.msg_id,''), COALESCE(lm.sender_jid,''), COALESCE(lm.from_me,0), COALESCE(lm.ts,0), COALESCE(lm.media_type,''), COALESCE(NULLIF(lm.text,''), lm.display_text, '')`
+
+// ListChatsPage lists chats outside the trash one page at a time, each with
+// a preview of its latest message. It returns the cursor of the next page
+// (empty on the last page) and the number of chats matching the query. Sort
+// defaults to SortByLastActivity.
+func (d *DB) ListChatsPage(p ListPageParams) ([]Chat, string, int, error) {
+	if p.Limit <= 0 {
+		p.Limit = 50
+	}
+	if p.Sort == "" {
+		p.Sort = SortByLastActivity
+	}
+	filter := ` WHERE c.deleted_at IS NULL`
+	var args []interface{}
+	if p.Snoozed {
+		filter += ` AND EXISTS`
+	} else {
+		filter += ` AND NOT EXISTS`
+	}
+	filter += ` (SELECT 1 FROM chat_snoozes s WHERE s.chat_jid = c.jid AND s.until > ?)`
+	args = append(args, unix(time.Now().UTC()))
+	if strings.TrimSpace(p.Query) != "" {
+		filter += ` AND (LOWER(c.name) LIKE LOWER(?) OR LOWER(c.jid) LIKE LOWER(?))`
+		needle := "%" + p.Query + "%"
+		args = append(args, needle, needle)
+	}
+	tagCond, tagArgs := tagFilter(p.Tags, `c.jid`)
+	filter += tagCond
+	args = append(args, tagArgs...)
+	cond, order, cursorArgs, err := pageOrder(p, chatSortNameSQL, `COALESCE(c.last_message_ts,0)`, unreadCountSQL, `c.jid`)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	var total int
+	if err := d.sql.QueryRow(`SELECT COUNT(*) FROM chats c`+filter, args...).Scan(&total); err != nil {
+		return nil, "", 0, err
+	}
+
+	q := `SELECT c.jid, c.kind, COALESCE(c.name,''), COALESCE(c.last_message_ts,0), COALESCE(c.read_ts,0), ` + unreadCountSQL + `, ` + chatStateSQL + `, ` + chatPreviewSQL + `, ` + chatSortNameSQL +
+		` FROM chats c` + chatPreviewJoin + filter + cond + order + ` LIMIT ?`
+	args = append(append(args, cursorArgs...), p.Limit+1)
+	rows, err := d.sql.Query(q, args...)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	defer rows.Close()
+	var out []Chat
+	var next, lastName string
+	var lastTS int64
+	for rows.Next() {
+		if len(out) == p.Limit {
+			last := out[len(out)-1]
+			next = p.cursorAfter(lastName, lastTS, int64(last.UnreadCount), last.JID)
+			break
+		}
+		var c Chat
+		var readTS, mutedUntil, previewTS int64
+		var archived, pinned, fromMe int
+		var preview ChatPreview
+		if err := rows.Scan(&c.JID, &c.Kind, &c.Name, &lastTS, &readTS, &c.UnreadCount, &archived, &pinned, &mutedUntil,
+			&preview.MsgID, &preview.SenderJID, &fromMe, &previewTS, &preview.MediaType, &preview.Text, &lastName); err != nil {
+			return nil, "", 0, err
+		}
+		c.LastMessageTS = fromUnix(lastTS)
+		c.ReadUntil = fromUnix(readTS)
+		applyChatState(&c, archived, pinned, mutedUntil)
+		if preview.MsgID != "" {
+			preview.FromMe = fromMe != 0
+			preview.Timestamp = fromUnix(previewTS)
+			preview.Text = truncateRunes([]rune(strings.Join(strings.Fields(preview.Text), " ")), chatPreviewLen)
+			c.LastMessage = &preview
+		}
+		out = append(out, c)
+	}
+	return out, next, total, rows.Err()
+}
+
+const contactSortNameSQL = `LOWER(COALESCE(NULLIF(a.alias,''), NULLIF(c.full_name,''), NULLIF(c.push_name,''), NULLIF(c.business_name,''), NULLIF(c.first_name,''), c.jid))`
+
+// ListContactsPage lists contacts one page at a time, like ListChatsPage,
+// with their tags. A contact's last activity is the latest message in its
+// direct chat. Sort defaults to SortByName.
+func (d *DB) ListContactsPage(p ListPageParams) ([]Contact, string, int, error) {
+	if p.Limit <= 0 {
+		p.Limit = 50
+	}
+	if p.Sort == "" {
+		p.Sort = SortByName
+	}
+	filter := `
+		FROM contacts c
+		LEFT JOIN contact_aliases a ON a.jid = c.jid
+		LEFT JOIN chats ch ON ch.jid = c.jid
+		WHERE 1=1` + notMappedLIDSQL
+	var args []interface{}
+	if strings.TrimSpace(p.Query) != "" {
+		filter += ` AND (LOWER(COALESCE(a.alias,'')) LIKE LOWER(?) OR LOWER(COALESCE(c.full_name,'')) LIKE LOWER(?) OR LOWER(COALESCE(c.push_name,'')) LIKE LOWER(?) OR LOWER(COALESCE(c.phone,'')) LIKE LOWER(?) OR LOWER(c.jid) LIKE LOWER(?))`
+		needle := "%" + p.Query + "%"
+		args = append(args, needle, needle, needle, needle, needle)
+	}
+	tagCond, tagArgs := tagFilter(p.Tags, `c.jid`)
+	filter += tagCond
+	args = append(args, tagArgs...)
+	cond, order, cursorArgs, err := pageOrder(p, contactSortNameSQL, `COALESCE(ch.last_message_ts,0)`, "", `c.jid`)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	var total int
+	if err := d.sql.QueryRow(`SELECT COUNT(*)`+filter, args...).Scan(&total); err != nil {
+		return nil, "", 0, err
+	}
+
+	q := `
+		SELECT c.jid,
+		       COALESCE(c.phone,''),
+		       COALESCE(NULLIF(a.alias,''), ''),
+		       COALESCE(NULLIF(c.full_name,''), NULLIF(c.push_name,''), NULLIF(c.business_name,''), NULLIF(c.first_name,''), ''),
+		       c.updated_at,
+		       COALESCE(ch.last_message_ts,0),
+		       COALESCE((SELECT group_concat(tag, char(31)) FROM (SELECT tag FROM contact_tags t WHERE t.jid = c.jid ORDER BY tag)), ''),
+		       ` + contactLIDSQL + `,
+		       ` + contactSortNameSQL + filter + cond + order + ` LIMIT ?`
+	args = append(append(args, cursorArgs...), p.Limit+1)
+	rows, err := d.sql.Query(q, args...)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	defer rows.Close()
+	var out []Contact
+	var next, lastName string
+	var lastTS int64
+	for rows.Next() {
+		if len(out) == p.Limit {
+			next = p.cursorAfter(lastName, lastTS, 0, out[len(out)-1].JID)
+			break
+		}
+		var c Contact
+		var updated int64
+		var tags string
+		if err := rows.Scan(&c.JID, &c.Phone, &c.Alias, &c.Name, &updated, &lastTS, &tags, &c.LID, &lastName); err != nil {
+			return nil, "", 0, err
+		}
+		c.UpdatedAt = fromUnix(updated)
+		if tags != "" {
+			c.Tags = strings.Split(tags, "\x1f")
+		}
+		out = append(out, c)
+	}
+	return out, next, total, rows.Err()
+}
@@ -0,0 +1,219 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListChatsPageSortsAndPages(t *testing.T) {
+	db := openTestDB(t)
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	// Two chats share a timestamp and two share a name to exercise ties.
+	chats := []struct {
+		jid, name string
+		ts        time.Time
+	}{
+		{"1@s.whatsapp.net", "Carol", base.Add(3 * time.Minute)},
+		{"2@s.whatsapp.net", "alice", base.Add(2 * time.Minute)},
+		{"3@s.whatsapp.net", "Bob", base.Add(2 * time.Minute)},
+		{"4@s.whatsapp.net", "Bob", base.Add(1 * time.Minute)},
+		{"5@s.whatsapp.net", "Dave", base},
+	}
+	for _, c := range chats {
+		if err := db.UpsertChat(c.jid, "dm", c.name, c.ts); err != nil {
+			t.Fatalf("UpsertChat: %v", err)
+		}
+	}
+
+	collect := func(p ListPageParams) []string {
+		t.Helper()
+		var jids []string
+		for i := 0; i < 10; i++ {
+			page, next, total, err := db.ListChatsPage(p)
+			if err != nil {
+				t.Fatalf("ListChatsPage: %v", err)
+			}
+			if total != len(chats) {
+				t.Fatalf("total = %d, want %d", total, len(chats))
+			}
+			for _, c := range page {
+				jids = append(jids, c.JID)
+			}
+			if next == "" {
+				return jids
+			}
+			p.Cursor = next
+		}
+		t.Fatalf("pagination did not terminate")
+		return nil
+	}
+
+	if got := fmt.Sprint(collect(ListPageParams{Limit: 2})); got != "[1@s.whatsapp.net 3@s.whatsapp.net 2@s.whatsapp.net 4@s.whatsapp.net 5@s.whatsapp.net]" {
+		t.Fatalf("last_activity order = %s", got)
+	}
+	if got := fmt.Sprint(collect(ListPageParams{Limit: 2, Sort: SortByName})); got != "[2@s.whatsapp.net 3@s.whatsapp.net 4@s.whatsapp.net 1@s.whatsapp.net 5@s.whatsapp.net]" {
+		t.Fatalf("name order = %s", got)
+	}
+	if got := fmt.Sprint(collect(ListPageParams{Limit: 3, Sort: SortByName, Reverse: true})); got != "[5@s.whatsapp.net 1@s.whatsapp.net 4@s.whatsapp.net 3@s.whatsapp.net 2@s.whatsapp.net]" {
+		t.Fatalf("reversed name order = %s", got)
+	}
+
+	_, next, _, err := db.ListChatsPage(ListPageParams{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListChatsPage: %v", err)
+	}
+	if _, _, _, err := db.ListChatsPage(ListPageParams{Limit: 2, Sort: SortByName, Cursor: next}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor for a cursor from another sort, got %v", err)
+	}
+	if _, _, total, err := db.ListChatsPage(ListPageParams{Query: "bob"}); err != nil || total != 2 {
+		t.Fatalf("query total = %d, %v", total, err)
+	}
+}
+
+func TestListContactsPage(t *testing.T) {
+	db := openTestDB(t)
+	for i, name := range []string{"Zed", "Amy", "Mia"} {
+		jid := fmt.Sprintf("%d@s.whatsapp.net", i+1)
+		if err := db.UpsertContact(jid, fmt.Sprint(i+1), name, "", "", ""); err != nil {
+			t.Fatalf("UpsertContact: %v", err)
+		}
+	}
+	if err := db.SetAlias("1@s.whatsapp.net", "Bea"); err != nil {
+		t.Fatalf("SetAlias: %v", err)
+	}
+	if err := db.UpsertChat("3@s.whatsapp.net", "dm", "Mia", time.Now()); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+
+	page, next, total, err := db.ListContactsPage(ListPageParams{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListContactsPage: %v", err)
+	}
+	if total != 3 || len(page) != 2 || page[0].Name != "Amy" || page[1].Alias != "Bea" || next == "" {
+		t.Fatalf("unexpected first page: total=%d next=%q %+v", total, next, page)
+	}
+	page, next, _, err = db.ListContactsPage(ListPageParams{Limit: 2, Cursor: next})
+	if err != nil || len(page) != 1 || page[0].Name != "Mia" || next != "" {
+		t.Fatalf("unexpected second page: %v next=%q %+v", err, next, page)
+	}
+
+	page, _, _, err = db.ListContactsPage(ListPageParams{Sort: SortByLastActivity})
+	if err != nil || len(page) != 3 || page[0].JID != "3@s.whatsapp.net" {
+		t.Fatalf("unexpected last_activity order: %v %+v", err, page)
+	}
+}
+
+func TestListPagesFilterByTag(t *testing.T) {
+	db := openTestDB(t)
+	for i, name := range []string{"Amy", "Bob", "Cat"} {
+		jid := fmt.Sprintf("%d@s.whatsapp.net", i+1)
+		if err := db.UpsertContact(jid, fmt.Sprint(i+1), name, "", "", ""); err != nil {
+			t.Fatalf("UpsertContact: %v", err)
+		}
+		if err := db.UpsertChat(jid, "dm", name, time.Now()); err != nil {
+			t.Fatalf("UpsertChat: %v", err)
+		}
+	}
+	for _, tag := range []struct{ jid, tag string }{
+		{"1@s.whatsapp.net", "customer"},
+		{"1@s.whatsapp.net", "vip, gold"},
+		{"2@s.whatsapp.net", "oncall"},
+		{"3@s.whatsapp.net", "customer"},
+	} {
+		if err := db.AddTag(tag.jid, tag.tag); err != nil {
+			t.Fatalf("AddTag: %v", err)
+		}
+	}
+
+	page, _, total, err := db.ListContactsPage(ListPageParams{Tags: []string{"customer"}})
+	if err != nil || total != 2 || len(page) != 2 || page[0].Name != "Amy" || page[1].Name != "Cat" {
+		t.Fatalf("unexpected customers: total=%d %v %+v", total, err, page)
+	}
+	if got := fmt.Sprint(page[0].Tags); got != "[customer vip, gold]" {
+		t.Fatalf("tags = %s", got)
+	}
+	if _, _, total, _ := db.ListContactsPage(ListPageParams{Tags: []string{"oncall", "vip, gold"}}); total != 2 {
+		t.Fatalf("any-of total = %d, want 2", total)
+	}
+	chats, _, total, err := db.ListChatsPage(ListPageParams{Tags: []string{"oncall"}})
+	if err != nil || total != 1 || len(chats) != 1 || chats[0].JID != "2@s.whatsapp.net" {
+		t.Fatalf("unexpected oncall chats: total=%d %v %+v", total, err, chats)
+	}
+	if _, _, total, _ := db.ListChatsPage(ListPageParams{Tags: []string{" "}}); total != 3 {
+		t.Fatalf("blank tag should not filter, total = %d", total)
+	}
+
+	tags, err := db.ListAllTags()
+	if err != nil || fmt.Sprint(tags) != "[{customer 2} {oncall 1} {vip, gold 1}]" {
+		t.Fatalf("ListAllTags = %v (%v)", tags, err)
+	}
+}
+
+func TestListChatsPagePreviewAndUnreadSort(t *testing.T) {
+	db := openTestDB(t)
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		jid    string
+		ts     time.Time
+		unread int
+	}{
+		{"1@s.whatsapp.net", base.Add(1 * time.Minute), 1},
+		{"2@s.whatsapp.net", base, 3},
+		{"3@s.whatsapp.net", base.Add(5 * time.Minute), 0},
+		{"4@s.whatsapp.net", base.Add(2 * time.Minute), 1},
+	} {
+		if err := db.UpsertChat(c.jid, "dm", "", c.ts); err != nil {
+			t.Fatalf("UpsertChat: %v", err)
+		}
+		for i := 0; i < c.unread; i++ {
+			m := UpsertMessageParams{ChatJID: c.jid, MsgID: fmt.Sprintf("%s-%d", c.jid, i), SenderJID: c.jid, Timestamp: c.ts.Add(time.Duration(i-c.unread) * time.Second), Text: fmt.Sprintf("msg %d", i)}
+			if err := db.UpsertMessage(m); err != nil {
+				t.Fatalf("UpsertMessage: %v", err)
+			}
+		}
+	}
+	long := "line one\n\n  line two " + strings.Repeat("x", 200)
+	if err := db.UpsertMessage(UpsertMessageParams{ChatJID: "2@s.whatsapp.net", MsgID: "last", FromMe: true, Timestamp: base, Text: long}); err != nil {
+		t.Fatalf("UpsertMessage: %v", err)
+	}
+
+	var jids []string
+	p := ListPageParams{Limit: 1, Sort: SortByUnread}
+	for i := 0; i < 10; i++ {
+		page, next, _, err := db.ListChatsPage(p)
+		if err != nil {
+			t.Fatalf("ListChatsPage: %v", err)
+		}
+		for _, c := range page {
+			jids = append(jids, c.JID)
+		}
+		if next == "" {
+			break
+		}
+		p.Cursor = next
+	}
+	if got := fmt.Sprint(jids); got != "[2@s.whatsapp.net 4@s.whatsapp.net 1@s.whatsapp.net 3@s.whatsapp.net]" {
+		t.Fatalf("unread order = %s", got)
+	}
+
+	chats, _, _, err := db.ListChatsPage(ListPageParams{Sort: SortByUnread})
+	if err != nil {
+		t.Fatalf("ListChatsPage: %v", err)
+	}
+	preview := chats[0].LastMessage
+	if preview == nil || preview.MsgID != "last" || !preview.FromMe || !preview.Timestamp.Equal(base) {
+		t.Fatalf("unexpected preview: %+v", preview)
+	}
+	if !strings.HasPrefix(preview.Text, "line one line two xxx") || len([]rune(preview.Text)) != chatPreviewLen+1 {
+		t.Fatalf("preview text = %q", preview.Text)
+	}
+	if chats[1].LastMessage == nil || chats[1].LastMessage.Text != "msg 0" {
+		t.Fatalf("unexpected preview: %+v", chats[1].LastMessage)
+	}
+	if chats[3].LastMessage != nil {
+		t.Fatalf("chat without messages has a preview: %+v", chats[3].LastMessage)
+	}
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PayloadSchema is the JSON Schema that generic webhook payloads addressed to
+// a chat must match.
+type PayloadSchema struct {
+	Chat      string
+	Schema    string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// SetPayloadSchema attaches schema to chat, replacing an existing one. The
+// caller is responsible for checking that schema compiles.
+func (d *DB) SetPayloadSchema(chat, schema string) (PayloadSchema, error) {
+	chat = strings.TrimSpace(chat)
+	if chat == "" {
+		return PayloadSchema{}, fmt.Errorf("chat is required")
+	}
+	if strings.TrimSpace(schema) == "" {
+		return PayloadSchema{}, fmt.Errorf("schema is required")
+	}
+	now := unix(time.Now().UTC())
+	if _, err := d.sql.Exec(`
+		INSERT INTO payload_schemas(chat_jid, schema, created_at, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(chat_jid) DO UPDATE SET schema = excluded.schema, updated_at = excluded.updated_at
+	`, chat, schema, now, now); err != nil {
+		return PayloadSchema{}, err
+	}
+	return d.GetPayloadSchema(chat)
+}
+
+// GetPayloadSchema returns the schema of chat, or an error matching
+// IsNotFound if it has none.
+func (d *DB) GetPayloadSchema(chat string) (PayloadSchema, error) {
+	return scanPayloadSchema(d.sql.QueryRow(`SELECT chat_jid, schema, created_at, updated_at FROM payload_schemas WHERE chat_jid = ?`, chat))
+}
+
+func (d *DB) ListPayloadSchemas() ([]PayloadSchema, error) {
+	rows, err := d.sql.Query(`SELECT chat_jid, schema, created_at, updated_at FROM payload_schemas ORDER BY chat_jid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []PayloadSchema
+	for rows.Next() {
+		s, err := scanPayloadSchema(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func (d *DB) DeletePayloadSchema(chat string) (bool, error) {
+	res, err := d.sql.Exec(`DELETE FROM payload_schemas WHERE chat_jid = ?`, chat)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func scanPayloadSchema(row rowScanner) (PayloadSchema, error) {
+	var s PayloadSchema
+	var created, updated int64
+	if err := row.Scan(&s.Chat, &s.Schema, &created, &updated); err != nil {
+		return PayloadSchema{}, err
+	}
+	s.CreatedAt = fromUnix(created)
+	s.UpdatedAt = fromUnix(updated)
+	return s, nil
+}
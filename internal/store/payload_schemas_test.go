@@ -0,0 +1,37 @@
+package store
+
+import "testing"
+
+func TestPayloadSchemas(t *testing.T) {
+	db := openTestDB(t)
+	chat := "120363000000000000@g.us"
+
+	if _, err := db.GetPayloadSchema(chat); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if _, err := db.SetPayloadSchema(chat, `{"type":"object"}`); err != nil {
+		t.Fatalf("SetPayloadSchema: %v", err)
+	}
+	s, err := db.SetPayloadSchema(chat, `{"required":["message"]}`)
+	if err != nil {
+		t.Fatalf("SetPayloadSchema replace: %v", err)
+	}
+	if s.Schema != `{"required":["message"]}` || s.CreatedAt.IsZero() {
+		t.Fatalf("unexpected schema: %+v", s)
+	}
+	if _, err := db.SetPayloadSchema("", `{}`); err == nil {
+		t.Fatalf("expected error for empty chat")
+	}
+
+	list, err := db.ListPayloadSchemas()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("ListPayloadSchemas: %v %+v", err, list)
+	}
+
+	if ok, err := db.DeletePayloadSchema(chat); err != nil || !ok {
+		t.Fatalf("DeletePayloadSchema: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := db.DeletePayloadSchema(chat); ok {
+		t.Fatalf("second delete should report false")
+	}
+}
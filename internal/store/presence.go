@@ -0,0 +1,233 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type PresenceWatch struct {
+	JID       string
+	Name      string
+	CreatedAt time.Time
+}
+
+type PresenceInterval struct {
+	JID       string
+	OnlineAt  time.Time
+	OfflineAt time.Time // zero while still online
+}
+
+// PresenceStat summarizes a watched contact's online intervals.
+type PresenceStat struct {
+	JID           string
+	Name          string
+	Sessions      int64
+	OnlineSeconds int64
+	FirstOnline   time.Time
+	LastOnline    time.Time
+	Online        bool
+}
+
+// PresenceState is the latest presence update received for a contact.
+type PresenceState struct {
+	JID       string
+	Available bool
+	LastSeen  time.Time // zero if the contact doesn't share it
+	UpdatedAt time.Time
+}
+
+// SetPresenceState stores the latest presence of jid. A zero lastSeen keeps
+// the previously reported one.
+func (d *DB) SetPresenceState(jid string, available bool, lastSeen, at time.Time) error {
+	var ls any
+	if !lastSeen.IsZero() {
+		ls = unix(lastSeen)
+	}
+	_, err := d.sql.Exec(`
+		INSERT INTO presence_state(jid, available, last_seen, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(jid) DO UPDATE SET
+			available = excluded.available,
+			last_seen = COALESCE(excluded.last_seen, presence_state.last_seen),
+			updated_at = excluded.updated_at
+		WHERE excluded.updated_at >= presence_state.updated_at
+	`, jid, boolToInt(available), ls, unix(at))
+	return err
+}
+
+// GetPresenceState returns the latest known presence of jid, or an error
+// matching IsNotFound if none was received.
+func (d *DB) GetPresenceState(jid string) (PresenceState, error) {
+	var p PresenceState
+	var available int
+	var lastSeen sql.NullInt64
+	var updated int64
+	err := d.sql.QueryRow(`SELECT jid, available, last_seen, updated_at FROM presence_state WHERE jid = ?`, jid).
+		Scan(&p.JID, &available, &lastSeen, &updated)
+	if err != nil {
+		return PresenceState{}, err
+	}
+	p.Available = available != 0
+	if lastSeen.Valid {
+		p.LastSeen = fromUnix(lastSeen.Int64)
+	}
+	p.UpdatedAt = fromUnix(updated)
+	return p, nil
+}
+
+func (d *DB) AddPresenceWatch(jid string) error {
+	if strings.TrimSpace(jid) == "" {
+		return fmt.Errorf("jid is required")
+	}
+	_, err := d.sql.Exec(`INSERT INTO presence_watch(jid, created_at) VALUES (?, ?) ON CONFLICT(jid) DO NOTHING`, jid, unix(time.Now().UTC()))
+	return err
+}
+
+// RemovePresenceWatch stops watching jid. Recorded intervals are kept.
+func (d *DB) RemovePresenceWatch(jid string) (bool, error) {
+	res, err := d.sql.Exec(`DELETE FROM presence_watch WHERE jid = ?`, jid)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	if n > 0 {
+		if _, err := d.sql.Exec(`UPDATE presence_intervals SET offline_at = ? WHERE jid = ? AND offline_at IS NULL`, unix(time.Now().UTC()), jid); err != nil {
+			return true, err
+		}
+	}
+	return n > 0, nil
+}
+
+func (d *DB) ListPresenceWatches() ([]PresenceWatch, error) {
+	rows, err := d.sql.Query(`
+		SELECT w.jid, COALESCE(NULLIF(a.alias,''), NULLIF(c.full_name,''), NULLIF(c.push_name,''), ''), w.created_at
+		FROM presence_watch w
+		LEFT JOIN contacts c ON c.jid = w.jid
+		LEFT JOIN contact_aliases a ON a.jid = w.jid
+		ORDER BY w.created_at, w.jid
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []PresenceWatch
+	for rows.Next() {
+		var w PresenceWatch
+		var created int64
+		if err := rows.Scan(&w.JID, &w.Name, &created); err != nil {
+			return nil, err
+		}
+		w.CreatedAt = fromUnix(created)
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
+
+func (d *DB) IsPresenceWatched(jid string) (bool, error) {
+	var one int
+	err := d.sql.QueryRow(`SELECT 1 FROM presence_watch WHERE jid = ?`, jid).Scan(&one)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RecordPresence opens an interval when a watched contact comes online and
+// closes it when they go offline. Repeated updates in the same state are
+// ignored.
+func (d *DB) RecordPresence(jid string, online bool, at time.Time) error {
+	var id int64
+	err := d.sql.QueryRow(`SELECT id FROM presence_intervals WHERE jid = ? AND offline_at IS NULL ORDER BY online_at DESC LIMIT 1`, jid).Scan(&id)
+	open := err == nil
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	switch {
+	case online && !open:
+		_, err := d.sql.Exec(`INSERT INTO presence_intervals(jid, online_at) VALUES (?, ?)`, jid, unix(at))
+		return err
+	case !online && open:
+		_, err := d.sql.Exec(`UPDATE presence_intervals SET offline_at = MAX(online_at, ?) WHERE id = ?`, unix(at), id)
+		return err
+	}
+	return nil
+}
+
+// ClosePresenceIntervals ends every open interval, e.g. when the connection
+// drops and presence can no longer be observed.
+func (d *DB) ClosePresenceIntervals(at time.Time) error {
+	_, err := d.sql.Exec(`UPDATE presence_intervals SET offline_at = MAX(online_at, ?) WHERE offline_at IS NULL`, unix(at))
+	return err
+}
+
+// ListPresenceIntervals returns intervals for jid that overlap [since, now],
+// newest first.
+func (d *DB) ListPresenceIntervals(jid string, since time.Time, limit int) ([]PresenceInterval, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := d.sql.Query(`
+		SELECT jid, online_at, COALESCE(offline_at, 0)
+		FROM presence_intervals
+		WHERE jid = ? AND (offline_at IS NULL OR offline_at >= ?)
+		ORDER BY online_at DESC
+		LIMIT ?
+	`, jid, unix(since), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []PresenceInterval
+	for rows.Next() {
+		var iv PresenceInterval
+		var on, off int64
+		if err := rows.Scan(&iv.JID, &on, &off); err != nil {
+			return nil, err
+		}
+		iv.OnlineAt = fromUnix(on)
+		iv.OfflineAt = fromUnix(off)
+		out = append(out, iv)
+	}
+	return out, rows.Err()
+}
+
+// PresenceStats rolls up online time per watched contact since the given
+// time. Intervals are clipped to [since, now]; open intervals count as online
+// until now.
+func (d *DB) PresenceStats(since time.Time) ([]PresenceStat, error) {
+	now := unix(time.Now().UTC())
+	from := unix(since)
+	rows, err := d.sql.Query(`
+		SELECT w.jid,
+		       COALESCE(NULLIF(a.alias,''), NULLIF(c.full_name,''), NULLIF(c.push_name,''), ''),
+		       COUNT(i.id),
+		       COALESCE(SUM(MAX(0, COALESCE(i.offline_at, ?) - MAX(i.online_at, ?))), 0),
+		       COALESCE(MIN(i.online_at), 0),
+		       COALESCE(MAX(i.online_at), 0),
+		       COALESCE(SUM(CASE WHEN i.id IS NOT NULL AND i.offline_at IS NULL THEN 1 ELSE 0 END), 0)
+		FROM presence_watch w
+		LEFT JOIN contacts c ON c.jid = w.jid
+		LEFT JOIN contact_aliases a ON a.jid = w.jid
+		LEFT JOIN presence_intervals i ON i.jid = w.jid AND (i.offline_at IS NULL OR i.offline_at >= ?)
+		GROUP BY w.jid
+		ORDER BY w.jid
+	`, now, from, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []PresenceStat
+	for rows.Next() {
+		var s PresenceStat
+		var first, last, open int64
+		if err := rows.Scan(&s.JID, &s.Name, &s.Sessions, &s.OnlineSeconds, &first, &last, &open); err != nil {
+			return nil, err
+		}
+		s.FirstOnline = fromUnix(first)
+		s.LastOnline = fromUnix(last)
+		s.Online = open > 0
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPresenceIntervals(t *testing.T) {
+	db := openTestDB(t)
+	jid := "5511999990000@s.whatsapp.net"
+
+	if err := db.AddPresenceWatch(jid); err != nil {
+		t.Fatalf("AddPresenceWatch: %v", err)
+	}
+	if err := db.AddPresenceWatch(jid); err != nil {
+		t.Fatalf("AddPresenceWatch twice: %v", err)
+	}
+	if ok, err := db.IsPresenceWatched(jid); err != nil || !ok {
+		t.Fatalf("IsPresenceWatched: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := db.IsPresenceWatched("other@s.whatsapp.net"); ok {
+		t.Fatalf("unexpected watch")
+	}
+
+	base := time.Now().UTC().Add(-2 * time.Hour).Truncate(time.Second)
+	steps := []struct {
+		online bool
+		at     time.Duration
+	}{
+		{true, 0},
+		{true, time.Minute}, // duplicate online, ignored
+		{false, 10 * time.Minute},
+		{false, 11 * time.Minute}, // duplicate offline, ignored
+		{true, 60 * time.Minute},
+	}
+	for _, s := range steps {
+		if err := db.RecordPresence(jid, s.online, base.Add(s.at)); err != nil {
+			t.Fatalf("RecordPresence: %v", err)
+		}
+	}
+
+	ivs, err := db.ListPresenceIntervals(jid, base.Add(-time.Hour), 10)
+	if err != nil {
+		t.Fatalf("ListPresenceIntervals: %v", err)
+	}
+	if len(ivs) != 2 || !ivs[0].OfflineAt.IsZero() || ivs[1].OfflineAt.Sub(ivs[1].OnlineAt) != 10*time.Minute {
+		t.Fatalf("unexpected intervals: %+v", ivs)
+	}
+
+	stats, err := db.PresenceStats(base.Add(5 * time.Minute))
+	if err != nil {
+		t.Fatalf("PresenceStats: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Sessions != 2 || !stats[0].Online {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	// 5 clipped minutes of the first session plus ~60 minutes of the open one.
+	if got := stats[0].OnlineSeconds; got < 64*60 || got > 66*60 {
+		t.Fatalf("unexpected online seconds: %d", got)
+	}
+
+	if err := db.ClosePresenceIntervals(time.Now().UTC()); err != nil {
+		t.Fatalf("ClosePresenceIntervals: %v", err)
+	}
+	if ok, err := db.RemovePresenceWatch(jid); err != nil || !ok {
+		t.Fatalf("RemovePresenceWatch: ok=%v err=%v", ok, err)
+	}
+	if watches, _ := db.ListPresenceWatches(); len(watches) != 0 {
+		t.Fatalf("expected no watches, got %+v", watches)
+	}
+}
+
+func TestPresenceState(t *testing.T) {
+	db := openTestDB(t)
+	jid := "5511999990000@s.whatsapp.net"
+
+	if _, err := db.GetPresenceState(jid); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	base := time.Now().UTC().Truncate(time.Second)
+	seen := base.Add(-5 * time.Minute)
+	if err := db.SetPresenceState(jid, false, seen, base); err != nil {
+		t.Fatalf("SetPresenceState: %v", err)
+	}
+	if err := db.SetPresenceState(jid, true, time.Time{}, base.Add(time.Minute)); err != nil {
+		t.Fatalf("SetPresenceState online: %v", err)
+	}
+	// Out-of-order updates don't overwrite newer state.
+	if err := db.SetPresenceState(jid, false, time.Time{}, base.Add(-time.Hour)); err != nil {
+		t.Fatalf("SetPresenceState stale: %v", err)
+	}
+
+	p, err := db.GetPresenceState(jid)
+	if err != nil {
+		t.Fatalf("GetPresenceState: %v", err)
+	}
+	if !p.Available || !p.LastSeen.Equal(seen) || !p.UpdatedAt.Equal(base.Add(time.Minute)) {
+		t.Fatalf("unexpected state: %+v", p)
+	}
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The read model is a set of views with stable, documented columns for BI
+// tools (Metabase, Superset, ...) that query the archive directly. The
+// tables behind them may change between releases; the views keep their
+// columns, and new columns are only ever appended. Timestamps are UTC ISO
+// 8601 text, chats in the trash are left out, and media keys and other
+// binary data are never exposed.
+
+// ErrUnknownView is returned by ExportReadModel for a name that is not a
+// read-model view.
+var ErrUnknownView = errors.New("unknown read-model view")
+
+// ReadModelView describes one view of the read model.
+type ReadModelView struct {
+	Name        string
+	Description string
+	sql         string
+}
+
+// isoSQL formats a unix-seconds column as UTC ISO 8601, NULL for unset.
+func isoSQL(col string) string {
+	return `CASE WHEN ` + col + ` > 0 THEN strftime('%Y-%m-%dT%H:%M:%SZ', ` + col + `, 'unixepoch') END`
+}
+
+var readModelViews = []ReadModelView{
+	{
+		Name:        "bi_chats",
+		Description: "One row per chat with its message count",
+		sql: `SELECT c.jid AS chat_jid,
+			c.kind AS kind,
+			COALESCE(c.name, '') AS name,
+			` + isoSQL("c.last_message_ts") + ` AS last_message_at,
+			` + isoSQL("c.read_ts") + ` AS read_until,
+			(SELECT COUNT(1) FROM messages m WHERE m.chat_jid = c.jid) AS message_count
+		FROM chats c WHERE 1=1` + notTrashedSQL,
+	},
+	{
+		Name:        "bi_contacts",
+		Description: "One row per contact with its alias and tags",
+		sql: `SELECT c.jid AS jid,
+			COALESCE(c.phone, '') AS phone,
+			COALESCE(NULLIF(a.alias, ''), NULLIF(c.full_name, ''), NULLIF(c.push_name, ''), NULLIF(c.business_name, ''), c.jid) AS display_name,
+			COALESCE(a.alias, '') AS alias,
+			COALESCE(c.full_name, '') AS full_name,
+			COALESCE(c.push_name, '') AS push_name,
+			COALESCE(c.business_name, '') AS business_name,
+			COALESCE((SELECT group_concat(tag, ',') FROM (SELECT tag FROM contact_tags t WHERE t.jid = c.jid ORDER BY tag)), '') AS tags,
+			` + isoSQL("c.updated_at") + ` AS updated_at
+		FROM contacts c
+		LEFT JOIN contact_aliases a ON a.jid = c.jid`,
+	},
+	{
+		Name:        "bi_messages",
+		Description: "One row per message",
+		sql: `SELECT m.chat_jid AS chat_jid,
+			COALESCE(NULLIF(c.name, ''), m.chat_name, '') AS chat_name,
+			c.kind AS chat_kind,
+			m.msg_id AS msg_id,
+			COALESCE(m.sender_jid, '') AS sender_jid,
+			COALESCE(m.sender_name, '') AS sender_name,
+			m.from_me AS from_me,
+			` + isoSQL("m.ts") + ` AS sent_at,
+			date(m.ts, 'unixepoch') AS sent_date,
+			COALESCE(NULLIF(m.display_text, ''), m.text, '') AS text,
+			COALESCE(m.media_type, '') AS media_type,
+			COALESCE(m.media_caption, '') AS media_caption,
+			COALESCE(m.filename, '') AS filename,
+			COALESCE(m.mime_type, '') AS mime_type,
+			m.file_length AS file_size,
+			COALESCE(m.system_type, '') AS system_type,
+			COALESCE(m.quoted_id, '') AS quoted_id,
+			` + isoSQL("m.edited_at") + ` AS edited_at,
+			` + isoSQL("m.revoked_at") + ` AS revoked_at
+		FROM messages m
+		JOIN chats c ON c.jid = m.chat_jid
+		WHERE 1=1` + notTrashedSQL,
+	},
+}
+
+// ReadModelViews lists the views of the read model.
+func ReadModelViews() []ReadModelView {
+	return append([]ReadModelView(nil), readModelViews...)
+}
+
+// ensureReadModelViews recreates the views so their definitions follow the
+// running version.
+func (d *DB) ensureReadModelViews() error {
+	tx, err := d.sql.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	for _, v := range readModelViews {
+		if _, err := tx.Exec(`DROP VIEW IF EXISTS ` + v.Name); err != nil {
+			return fmt.Errorf("drop view %s: %w", v.Name, err)
+		}
+		if _, err := tx.Exec(`CREATE VIEW ` + v.Name + ` AS ` + v.sql); err != nil {
+			return fmt.Errorf("create view %s: %w", v.Name, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ExportReadModel streams a read-model view: header gets the column names
+// once, then row is called with the values of each row (nil, int64, float64
+// or string).
+func (d *DB) ExportReadModel(view string, header func(columns []string) error, row func(values []interface{}) error) error {
+	known := false
+	for _, v := range readModelViews {
+		if v.Name == view {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("%w: %s", ErrUnknownView, view)
+	}
+
+	rows, err := d.sql.Query(`SELECT * FROM ` + view)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if err := header(cols); err != nil {
+		return err
+	}
+	for rows.Next() {
+		vals := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		for i, v := range vals {
+			if b, ok := v.([]byte); ok {
+				vals[i] = string(b)
+			}
+		}
+		if err := row(vals); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReadModelViews(t *testing.T) {
+	db := openTestDB(t)
+	ts := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	chat, trashed := "111@s.whatsapp.net", "222@g.us"
+	if err := db.UpsertChat(chat, "dm", "Ann", ts); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	if err := db.UpsertChat(trashed, "group", "Noise", ts); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	for _, p := range []UpsertMessageParams{
+		{ChatJID: chat, MsgID: "m1", SenderJID: chat, SenderName: "Ann", Timestamp: ts, Text: "hello", MediaType: "image", MediaKey: []byte{1, 2, 3}},
+		{ChatJID: trashed, MsgID: "m2", Timestamp: ts, Text: "spam"},
+	} {
+		if err := db.UpsertMessage(p); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+	if err := db.TrashChat(trashed, ts); err != nil {
+		t.Fatalf("TrashChat: %v", err)
+	}
+	if err := db.UpsertContact(chat, "111", "annie", "Ann Example", "Ann", ""); err != nil {
+		t.Fatalf("UpsertContact: %v", err)
+	}
+	for _, tag := range []string{"vip", "customer"} {
+		if err := db.AddTag(chat, tag); err != nil {
+			t.Fatalf("AddTag: %v", err)
+		}
+	}
+
+	export := func(view string) []map[string]interface{} {
+		t.Helper()
+		var cols []string
+		var out []map[string]interface{}
+		err := db.ExportReadModel(view, func(c []string) error {
+			cols = c
+			return nil
+		}, func(vals []interface{}) error {
+			row := map[string]interface{}{}
+			for i, c := range cols {
+				row[c] = vals[i]
+			}
+			out = append(out, row)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ExportReadModel(%s): %v", view, err)
+		}
+		return out
+	}
+
+	msgs := export("bi_messages")
+	if len(msgs) != 1 {
+		t.Fatalf("bi_messages = %+v", msgs)
+	}
+	m := msgs[0]
+	if m["msg_id"] != "m1" || m["chat_name"] != "Ann" || m["sent_at"] != "2024-01-02T15:04:05Z" || m["sent_date"] != "2024-01-02" || m["text"] != "hello" || m["edited_at"] != nil {
+		t.Fatalf("message row = %+v", m)
+	}
+	if _, ok := m["media_key"]; ok {
+		t.Fatalf("media key exposed: %+v", m)
+	}
+
+	chats := export("bi_chats")
+	if len(chats) != 1 || chats[0]["chat_jid"] != chat || chats[0]["message_count"] != int64(1) {
+		t.Fatalf("bi_chats = %+v", chats)
+	}
+
+	contacts := export("bi_contacts")
+	if len(contacts) != 1 || contacts[0]["display_name"] != "Ann Example" || contacts[0]["tags"] != "customer,vip" {
+		t.Fatalf("bi_contacts = %+v", contacts)
+	}
+
+	if err := db.ExportReadModel("messages", func([]string) error { return nil }, func([]interface{}) error { return nil }); !errors.Is(err, ErrUnknownView) {
+		t.Fatalf("expected ErrUnknownView, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// Receipt kinds.
+const (
+	ReceiptDelivered = "delivered"
+	ReceiptRead      = "read"
+	ReceiptPlayed    = "played"
+)
+
+// Receipt is the delivery state of one of our messages for one recipient
+// (the other party in a direct chat, each participant in a group).
+type Receipt struct {
+	ChatJID     string
+	MsgID       string
+	Participant string
+	DeliveredAt time.Time
+	ReadAt      time.Time
+	PlayedAt    time.Time
+}
+
+// RecordReceipt stores a receipt for msgIDs. Only the first time of each kind
+// is kept, and a later kind implies the earlier ones (played means read, read
+// means delivered), since WhatsApp does not always send all of them.
+func (d *DB) RecordReceipt(chatJID, participant, kind string, msgIDs []string, at time.Time) error {
+	if chatJID == "" || participant == "" {
+		return fmt.Errorf("chat and participant are required")
+	}
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+	var delivered, read, played interface{}
+	switch kind {
+	case ReceiptPlayed:
+		played = unix(at)
+		read = unix(at)
+		delivered = unix(at)
+	case ReceiptRead:
+		read = unix(at)
+		delivered = unix(at)
+	case ReceiptDelivered:
+		delivered = unix(at)
+	default:
+		return fmt.Errorf("unknown receipt kind %q", kind)
+	}
+
+	tx, err := d.sql.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	for _, id := range msgIDs {
+		if _, err := tx.Exec(`
+			INSERT INTO receipts(chat_jid, msg_id, participant, delivered_at, read_at, played_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(chat_jid, msg_id, participant) DO UPDATE SET
+				delivered_at=COALESCE(receipts.delivered_at, excluded.delivered_at),
+				read_at=COALESCE(receipts.read_at, excluded.read_at),
+				played_at=COALESCE(receipts.played_at, excluded.played_at)
+		`, chatJID, id, participant, delivered, read, played); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListReceipts returns the receipts of one message, ordered by participant.
+func (d *DB) ListReceipts(chatJID, msgID string) ([]Receipt, error) {
+	rows, err := d.sql.Query(`
+		SELECT chat_jid, msg_id, participant, COALESCE(delivered_at,0), COALESCE(read_at,0), COALESCE(played_at,0)
+		FROM receipts WHERE chat_jid = ? AND msg_id = ?
+		ORDER BY participant
+	`, chatJID, msgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Receipt
+	for rows.Next() {
+		var r Receipt
+		var delivered, read, played int64
+		if err := rows.Scan(&r.ChatJID, &r.MsgID, &r.Participant, &delivered, &read, &played); err != nil {
+			return nil, err
+		}
+		r.DeliveredAt = fromUnix(delivered)
+		r.ReadAt = fromUnix(read)
+		r.PlayedAt = fromUnix(played)
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordReceipt(t *testing.T) {
+	db := openTestDB(t)
+	group := "123@g.us"
+	alice := "5511111111111@s.whatsapp.net"
+	bob := "5522222222222@s.whatsapp.net"
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if err := db.RecordReceipt(group, alice, ReceiptDelivered, []string{"M1", "M2"}, t0); err != nil {
+		t.Fatalf("RecordReceipt: %v", err)
+	}
+	if err := db.RecordReceipt(group, alice, ReceiptRead, []string{"M1"}, t0.Add(time.Minute)); err != nil {
+		t.Fatalf("RecordReceipt: %v", err)
+	}
+	// A duplicate delivery receipt must not move the timestamp.
+	if err := db.RecordReceipt(group, alice, ReceiptDelivered, []string{"M1"}, t0.Add(time.Hour)); err != nil {
+		t.Fatalf("RecordReceipt: %v", err)
+	}
+	// Read without a prior delivery receipt implies delivery.
+	if err := db.RecordReceipt(group, bob, ReceiptRead, []string{"M1"}, t0.Add(2*time.Minute)); err != nil {
+		t.Fatalf("RecordReceipt: %v", err)
+	}
+	if err := db.RecordReceipt(group, bob, "seen", []string{"M1"}, t0); err == nil {
+		t.Fatalf("expected unknown kind error")
+	}
+
+	rs, err := db.ListReceipts(group, "M1")
+	if err != nil || len(rs) != 2 {
+		t.Fatalf("ListReceipts: %+v (%v)", rs, err)
+	}
+	if rs[0].Participant != alice || !rs[0].DeliveredAt.Equal(t0) || !rs[0].ReadAt.Equal(t0.Add(time.Minute)) || !rs[0].PlayedAt.IsZero() {
+		t.Fatalf("unexpected alice receipt: %+v", rs[0])
+	}
+	if rs[1].Participant != bob || !rs[1].DeliveredAt.Equal(t0.Add(2*time.Minute)) {
+		t.Fatalf("unexpected bob receipt: %+v", rs[1])
+	}
+	if rs, _ := db.ListReceipts(group, "M2"); len(rs) != 1 || !rs[0].ReadAt.IsZero() {
+		t.Fatalf("unexpected M2 receipts: %+v", rs)
+	}
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SearchMessagesAny returns messages matching any of the given terms, best
+// matches first. With FTS5 the terms are OR-ed and ranked by bm25; without it
+// messages are ranked by how many distinct terms they contain.
+func (d *DB) SearchMessagesAny(terms []string, chatJID string, limit int) ([]Message, error) {
+	var clean []string
+	for _, t := range terms {
+		t = strings.TrimSpace(t)
+		if t != "" {
+			clean = append(clean, t)
+		}
+	}
+	if len(clean) == 0 {
+		return nil, fmt.Errorf("at least one search term is required")
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+
+	if d.ftsEnabled.Load() {
+		quoted := make([]string, 0, len(clean))
+		for _, t := range clean {
+			quoted = append(quoted, `"`+strings.ReplaceAll(t, `"`, `""`)+`"`)
+		}
+		return d.searchFTS(SearchMessagesParams{
+			Query:   strings.Join(quoted, " OR "),
+			ChatJID: chatJID,
+			Limit:   limit,
+		})
+	}
+
+	var score []string
+	var args []interface{}
+	for _, t := range clean {
+		score = append(score, "(LOWER(COALESCE(m.text,'') || ' ' || COALESCE(m.media_caption,'')) LIKE LOWER(?))")
+		args = append(args, "%"+t+"%")
+	}
+	query := `
+		SELECT chat_jid, chat_name, msg_id, sender_jid, ts, from_me, text, display_text, media_type, edited, revoked, quoted_id, quoted_snippet, system_type, '', hits FROM (
+			SELECT m.chat_jid, COALESCE(c.name,'') AS chat_name, m.msg_id, COALESCE(m.sender_jid,'') AS sender_jid, m.ts, m.from_me,
+			       COALESCE(m.text,'') AS text, COALESCE(m.display_text,'') AS display_text, COALESCE(m.media_type,'') AS media_type,
+			       m.edited_at IS NOT NULL AS edited, m.revoked_at IS NOT NULL AS revoked,
+			       COALESCE(m.quoted_id,'') AS quoted_id, COALESCE(m.quoted_snippet,'') AS quoted_snippet, COALESCE(m.system_type,'') AS system_type,
+			       (` + strings.Join(score, " + ") + `) AS hits
+			FROM messages m
+			LEFT JOIN chats c ON c.jid = m.chat_jid
+			WHERE 1=1` + notTrashedSQL
+	if strings.TrimSpace(chatJID) != "" {
+		query += " AND m.chat_jid = ?"
+		args = append(args, chatJID)
+	}
+	query += `
+		) WHERE hits > 0
+		ORDER BY hits DESC, ts DESC
+		LIMIT ?`
+	args = append(args, limit)
+	return d.scanMessages(query, args...)
+}
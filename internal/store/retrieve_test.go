@@ -0,0 +1,47 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSearchMessagesAnyRanksByMatchedTerms(t *testing.T) {
+	db := openTestDB(t)
+
+	chat := "landlord@s.whatsapp.net"
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := db.UpsertChat(chat, "dm", "Landlord", base); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	for i, text := range []string{
+		"the lease ends on March 31st",
+		"rent is due on the 5th",
+		"new lease terms attached",
+		"unrelated chatter",
+	} {
+		if err := db.UpsertMessage(UpsertMessageParams{
+			ChatJID:   chat,
+			MsgID:     string(rune('a' + i)),
+			SenderJID: chat,
+			Timestamp: base.Add(time.Duration(i) * time.Hour),
+			Text:      text,
+		}); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+
+	ms, err := db.SearchMessagesAny([]string{"lease", "ends"}, "", 10)
+	if err != nil {
+		t.Fatalf("SearchMessagesAny: %v", err)
+	}
+	if len(ms) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(ms))
+	}
+	if ms[0].Text != "the lease ends on March 31st" {
+		t.Fatalf("expected best match first, got %q", ms[0].Text)
+	}
+
+	if _, err := db.SearchMessagesAny([]string{" "}, "", 10); err == nil {
+		t.Fatalf("expected error without terms")
+	}
+}
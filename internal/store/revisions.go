@@ -0,0 +1,96 @@
+package store
+
+import (
+	"time"
+)
+
+const (
+	RevisionEdited  = "edited"
+	RevisionRevoked = "revoked"
+)
+
+// MessageRevision is a previous version of a message that its sender edited
+// or deleted.
+type MessageRevision struct {
+	Kind         string
+	Text         string
+	DisplayText  string
+	MediaCaption string
+	ReplacedAt   time.Time
+}
+
+// EditMessage replaces the text of a message after its sender edited it,
+// keeping the previous version as a revision. It reports false if the
+// message is unknown or was deleted.
+func (d *DB) EditMessage(chatJID, msgID, text, displayText string, at time.Time) (bool, error) {
+	return d.reviseMessage(chatJID, msgID, RevisionEdited, at, `
+		UPDATE messages
+		SET text = ?, display_text = ?, media_caption = CASE WHEN media_type IS NULL THEN NULL ELSE ? END, edited_at = ?
+		WHERE chat_jid = ? AND msg_id = ? AND revoked_at IS NULL
+	`, nullIfEmpty(text), nullIfEmpty(displayText), nullIfEmpty(text), unix(at), chatJID, msgID)
+}
+
+// RevokeMessage turns a message its sender deleted for everyone into a
+// tombstone: the text is cleared and kept as a revision. It reports false if
+// the message is unknown or already revoked.
+func (d *DB) RevokeMessage(chatJID, msgID string, at time.Time) (bool, error) {
+	return d.reviseMessage(chatJID, msgID, RevisionRevoked, at, `
+		UPDATE messages
+		SET text = NULL, display_text = NULL, media_caption = NULL, revoked_at = ?
+		WHERE chat_jid = ? AND msg_id = ? AND revoked_at IS NULL
+	`, unix(at), chatJID, msgID)
+}
+
+func (d *DB) reviseMessage(chatJID, msgID, kind string, at time.Time, update string, args ...any) (bool, error) {
+	tx, err := d.sql.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.Exec(`
+		INSERT INTO message_revisions(chat_jid, msg_id, kind, text, display_text, media_caption, replaced_at)
+		SELECT chat_jid, msg_id, ?, text, display_text, media_caption, ?
+		FROM messages
+		WHERE chat_jid = ? AND msg_id = ? AND revoked_at IS NULL
+	`, kind, unix(at), chatJID, msgID)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if _, err := tx.Exec(update, args...); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, d.chainMessage(chatJID, msgID)
+}
+
+// ListMessageRevisions returns the previous versions of a message, oldest
+// first.
+func (d *DB) ListMessageRevisions(chatJID, msgID string) ([]MessageRevision, error) {
+	rows, err := d.sql.Query(`
+		SELECT kind, COALESCE(text,''), COALESCE(display_text,''), COALESCE(media_caption,''), replaced_at
+		FROM message_revisions
+		WHERE chat_jid = ? AND msg_id = ?
+		ORDER BY replaced_at, id
+	`, chatJID, msgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []MessageRevision
+	for rows.Next() {
+		var r MessageRevision
+		var at int64
+		if err := rows.Scan(&r.Kind, &r.Text, &r.DisplayText, &r.MediaCaption, &at); err != nil {
+			return nil, err
+		}
+		r.ReplacedAt = fromUnix(at)
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEditAndRevokeMessage(t *testing.T) {
+	db := openTestDB(t)
+	chat := "5511111111111@s.whatsapp.net"
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := db.UpsertChat(chat, "dm", "Alice", t0); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	upsert := func() {
+		if err := db.UpsertMessage(UpsertMessageParams{ChatJID: chat, MsgID: "M1", SenderJID: chat, Timestamp: t0, Text: "helo", DisplayText: "helo"}); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+	upsert()
+
+	if ok, err := db.EditMessage(chat, "M1", "hello", "hello", t0.Add(time.Minute)); err != nil || !ok {
+		t.Fatalf("EditMessage: ok=%v err=%v", ok, err)
+	}
+	// A re-synced copy of the original must not undo the edit.
+	upsert()
+	m, err := db.GetMessage(chat, "M1")
+	if err != nil {
+		t.Fatalf("GetMessage: %v", err)
+	}
+	if m.Text != "hello" || !m.Edited || m.Revoked {
+		t.Fatalf("unexpected edited message: %+v", m)
+	}
+
+	if ok, err := db.RevokeMessage(chat, "M1", t0.Add(2*time.Minute)); err != nil || !ok {
+		t.Fatalf("RevokeMessage: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := db.RevokeMessage(chat, "M1", t0.Add(3*time.Minute)); ok {
+		t.Fatalf("second revoke should be a no-op")
+	}
+	if ok, _ := db.EditMessage(chat, "M1", "again", "again", t0.Add(4*time.Minute)); ok {
+		t.Fatalf("revoked messages cannot be edited")
+	}
+	if ok, _ := db.RevokeMessage(chat, "unknown", t0); ok {
+		t.Fatalf("unknown message should report false")
+	}
+
+	msgs, err := db.ListMessages(ListMessagesParams{ChatJID: chat})
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("ListMessages: %v %+v", err, msgs)
+	}
+	if msgs[0].Text != "" || !msgs[0].Revoked || !msgs[0].Edited {
+		t.Fatalf("expected tombstone, got %+v", msgs[0])
+	}
+
+	revs, err := db.ListMessageRevisions(chat, "M1")
+	if err != nil || len(revs) != 2 {
+		t.Fatalf("ListMessageRevisions: %v %+v", err, revs)
+	}
+	if revs[0].Kind != RevisionEdited || revs[0].Text != "helo" || revs[1].Kind != RevisionRevoked || revs[1].Text != "hello" {
+		t.Fatalf("unexpected revisions: %+v", revs)
+	}
+}
@@ -0,0 +1,104 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Sender roles, from most to least trusted. Senders without a stored role
+// are users.
+const (
+	RoleOwner = "owner"
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// RoleRank orders roles for comparison: owner 3, admin 2, user 1, and 0 for
+// anything else.
+func RoleRank(role string) int {
+	switch role {
+	case RoleOwner:
+		return 3
+	case RoleAdmin:
+		return 2
+	case RoleUser:
+		return 1
+	}
+	return 0
+}
+
+// SenderRole is the role assigned to a sender JID.
+type SenderRole struct {
+	JID       string
+	Role      string
+	Note      string
+	UpdatedAt time.Time
+}
+
+// SetSenderRole assigns role to jid, replacing its previous role.
+func (d *DB) SetSenderRole(jid, role, note string) (SenderRole, error) {
+	jid = strings.TrimSpace(jid)
+	role = strings.ToLower(strings.TrimSpace(role))
+	if jid == "" {
+		return SenderRole{}, fmt.Errorf("jid is required")
+	}
+	if RoleRank(role) == 0 {
+		return SenderRole{}, fmt.Errorf("unknown role %q (want owner, admin or user)", role)
+	}
+	if _, err := d.sql.Exec(`
+		INSERT INTO sender_roles(jid, role, note, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(jid) DO UPDATE SET role = excluded.role, note = excluded.note, updated_at = excluded.updated_at
+	`, jid, role, strings.TrimSpace(note), unix(time.Now().UTC())); err != nil {
+		return SenderRole{}, err
+	}
+	return d.GetSenderRole(jid)
+}
+
+// GetSenderRole returns the role stored for jid, or an error matching
+// IsNotFound.
+func (d *DB) GetSenderRole(jid string) (SenderRole, error) {
+	var r SenderRole
+	var updated int64
+	err := d.sql.QueryRow(`SELECT jid, role, note, updated_at FROM sender_roles WHERE jid = ?`, jid).
+		Scan(&r.JID, &r.Role, &r.Note, &updated)
+	if err != nil {
+		return SenderRole{}, err
+	}
+	r.UpdatedAt = fromUnix(updated)
+	return r, nil
+}
+
+// ListSenderRoles returns the assigned roles, most trusted first.
+func (d *DB) ListSenderRoles() ([]SenderRole, error) {
+	rows, err := d.sql.Query(`
+		SELECT jid, role, note, updated_at FROM sender_roles
+		ORDER BY CASE role WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 ELSE 2 END, jid
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []SenderRole
+	for rows.Next() {
+		var r SenderRole
+		var updated int64
+		if err := rows.Scan(&r.JID, &r.Role, &r.Note, &updated); err != nil {
+			return nil, err
+		}
+		r.UpdatedAt = fromUnix(updated)
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// DeleteSenderRole makes jid a plain user again. It reports whether a role
+// was stored.
+func (d *DB) DeleteSenderRole(jid string) (bool, error) {
+	res, err := d.sql.Exec(`DELETE FROM sender_roles WHERE jid = ?`, jid)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
@@ -0,0 +1,48 @@
+package store
+
+import "testing"
+
+func TestSenderRoles(t *testing.T) {
+	db := openTestDB(t)
+	jid := "5511000000001@s.whatsapp.net"
+
+	if _, err := db.SetSenderRole(jid, "root", ""); err == nil {
+		t.Fatalf("expected an unknown role to be rejected")
+	}
+	if _, err := db.GetSenderRole(jid); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	r, err := db.SetSenderRole(jid, " Admin ", "ops on-call")
+	if err != nil {
+		t.Fatalf("SetSenderRole: %v", err)
+	}
+	if r.Role != RoleAdmin || r.Note != "ops on-call" || r.UpdatedAt.IsZero() {
+		t.Fatalf("unexpected role: %+v", r)
+	}
+	if _, err := db.SetSenderRole("5511000000002@s.whatsapp.net", RoleOwner, ""); err != nil {
+		t.Fatalf("SetSenderRole: %v", err)
+	}
+	list, err := db.ListSenderRoles()
+	if err != nil || len(list) != 2 || list[0].Role != RoleOwner {
+		t.Fatalf("ListSenderRoles = %+v, %v", list, err)
+	}
+	if ok, err := db.DeleteSenderRole(jid); err != nil || !ok {
+		t.Fatalf("DeleteSenderRole = %v, %v", ok, err)
+	}
+	if ok, _ := db.DeleteSenderRole(jid); ok {
+		t.Fatalf("expected nothing left to delete")
+	}
+
+	if _, err := db.CreateRule(CreateRuleParams{Action: RuleDrop, MinRole: "root"}); err == nil {
+		t.Fatalf("expected an unknown min_role to be rejected")
+	}
+	rule, err := db.CreateRule(CreateRuleParams{Action: RuleDrop, MinRole: " ADMIN "})
+	if err != nil || rule.MinRole != RoleAdmin {
+		t.Fatalf("CreateRule = %+v, %v", rule, err)
+	}
+	empty := ""
+	rule, err = db.UpdateRule(rule.ID, UpdateRuleParams{MinRole: &empty})
+	if err != nil || rule.MinRole != "" {
+		t.Fatalf("UpdateRule = %+v, %v", rule, err)
+	}
+}
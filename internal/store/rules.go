@@ -0,0 +1,251 @@
+package store
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule actions.
+const (
+	RuleDrop    = "drop"
+	RuleTag     = "tag"
+	RuleReply   = "reply"
+	RuleWebhook = "webhook"
+)
+
+// Rule routes matching incoming messages to an action. Empty match lists
+// match anything; keywords match case-insensitively anywhere in the text.
+type Rule struct {
+	ID         int64
+	Name       string
+	Priority   int
+	Enabled    bool
+	Chats      []string
+	Senders    []string
+	Keywords   []string
+	MediaTypes []string
+	Action     string
+	Arg        string // tag name, reply text or webhook id
+	// MinRole limits the rule to senders with at least this role; empty
+	// matches any sender.
+	MinRole string
+	// DuplicateMedia limits the rule to incoming media already in the
+	// archive, e.g. an invoice sent twice.
+	DuplicateMedia bool
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type CreateRuleParams struct {
+	Name       string
+	Priority   int
+	Chats      []string
+	Senders    []string
+	Keywords   []string
+	MediaTypes []string
+	Action     string
+	Arg        string
+	MinRole    string
+	// DuplicateMedia only matches media already in the archive.
+	DuplicateMedia bool
+	// Disabled creates the rule switched off.
+	Disabled bool
+}
+
+func (d *DB) CreateRule(p CreateRuleParams) (Rule, error) {
+	if err := d.checkRule(&p); err != nil {
+		return Rule{}, err
+	}
+
+	now := time.Now().UTC()
+	res, err := d.sql.Exec(`
+		INSERT INTO rules(name, priority, enabled, chats, senders, keywords, media_types, action, arg, min_role, duplicate_media, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, p.Name, p.Priority, boolToInt(!p.Disabled), joinList(p.Chats), joinList(p.Senders), joinList(p.Keywords), joinList(p.MediaTypes), p.Action, p.Arg, p.MinRole, boolToInt(p.DuplicateMedia), unix(now), unix(now))
+	if err != nil {
+		return Rule{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Rule{}, err
+	}
+	return d.GetRule(id)
+}
+
+// UpdateRuleParams changes the non-nil fields of a rule.
+type UpdateRuleParams struct {
+	Name       *string
+	Priority   *int
+	Enabled    *bool
+	Chats      *[]string
+	Senders    *[]string
+	Keywords   *[]string
+	MediaTypes *[]string
+	Action     *string
+	Arg        *string
+	MinRole    *string
+	// DuplicateMedia changes whether the rule only matches media already
+	// in the archive.
+	DuplicateMedia *bool
+}
+
+// UpdateRule applies a partial update; the result is validated like a new
+// rule.
+func (d *DB) UpdateRule(id int64, u UpdateRuleParams) (Rule, error) {
+	r, err := d.GetRule(id)
+	if err != nil {
+		return Rule{}, err
+	}
+	p := CreateRuleParams{
+		Name: r.Name, Priority: r.Priority,
+		Chats: r.Chats, Senders: r.Senders, Keywords: r.Keywords, MediaTypes: r.MediaTypes,
+		Action: r.Action, Arg: r.Arg, MinRole: r.MinRole, DuplicateMedia: r.DuplicateMedia, Disabled: !r.Enabled,
+	}
+	if u.Name != nil {
+		p.Name = *u.Name
+	}
+	if u.Priority != nil {
+		p.Priority = *u.Priority
+	}
+	if u.Enabled != nil {
+		p.Disabled = !*u.Enabled
+	}
+	if u.Chats != nil {
+		p.Chats = *u.Chats
+	}
+	if u.Senders != nil {
+		p.Senders = *u.Senders
+	}
+	if u.Keywords != nil {
+		p.Keywords = *u.Keywords
+	}
+	if u.MediaTypes != nil {
+		p.MediaTypes = *u.MediaTypes
+	}
+	if u.Action != nil {
+		p.Action = *u.Action
+	}
+	if u.Arg != nil {
+		p.Arg = *u.Arg
+	}
+	if u.MinRole != nil {
+		p.MinRole = *u.MinRole
+	}
+	if u.DuplicateMedia != nil {
+		p.DuplicateMedia = *u.DuplicateMedia
+	}
+	if err := d.checkRule(&p); err != nil {
+		return Rule{}, err
+	}
+
+	if _, err := d.sql.Exec(`
+		UPDATE rules SET name=?, priority=?, enabled=?, chats=?, senders=?, keywords=?, media_types=?, action=?, arg=?, min_role=?, duplicate_media=?, updated_at=?
+		WHERE id = ?
+	`, p.Name, p.Priority, boolToInt(!p.Disabled), joinList(p.Chats), joinList(p.Senders), joinList(p.Keywords), joinList(p.MediaTypes), p.Action, p.Arg, p.MinRole, boolToInt(p.DuplicateMedia), unix(time.Now().UTC()), id); err != nil {
+		return Rule{}, err
+	}
+	return d.GetRule(id)
+}
+
+// checkRule normalizes p and checks that a webhook rule points at an
+// existing webhook.
+func (d *DB) checkRule(p *CreateRuleParams) error {
+	if err := normalizeRule(p); err != nil {
+		return err
+	}
+	if p.Action != RuleWebhook {
+		return nil
+	}
+	id, err := strconv.ParseInt(p.Arg, 10, 64)
+	if err != nil {
+		return fmt.Errorf("webhook rules require a webhook id as arg")
+	}
+	if _, err := d.GetWebhook(id); err != nil {
+		if IsNotFound(err) {
+			return fmt.Errorf("webhook %d not found", id)
+		}
+		return err
+	}
+	return nil
+}
+
+// normalizeRule trims p and checks the action and that it has the arg it
+// needs. Webhook ids are checked by the caller.
+func normalizeRule(p *CreateRuleParams) error {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Action = strings.ToLower(strings.TrimSpace(p.Action))
+	p.Arg = strings.TrimSpace(p.Arg)
+	p.MinRole = strings.ToLower(strings.TrimSpace(p.MinRole))
+	if p.MinRole != "" && RoleRank(p.MinRole) == 0 {
+		return fmt.Errorf("unknown min_role %q (want owner, admin or user)", p.MinRole)
+	}
+	switch p.Action {
+	case RuleDrop:
+	case RuleTag, RuleReply, RuleWebhook:
+		if p.Arg == "" {
+			return fmt.Errorf("%s rules require an arg", p.Action)
+		}
+	default:
+		return fmt.Errorf("unknown action %q (want drop, tag, reply or webhook)", p.Action)
+	}
+	return nil
+}
+
+const ruleColumns = `id, name, priority, enabled, chats, senders, keywords, media_types, action, arg, min_role, duplicate_media, created_at, updated_at`
+
+func (d *DB) GetRule(id int64) (Rule, error) {
+	return scanRule(d.sql.QueryRow(`SELECT `+ruleColumns+` FROM rules WHERE id = ?`, id))
+}
+
+// ListRules returns rules in evaluation order (priority, then id).
+func (d *DB) ListRules(enabledOnly bool) ([]Rule, error) {
+	q := `SELECT ` + ruleColumns + ` FROM rules`
+	if enabledOnly {
+		q += ` WHERE enabled = 1`
+	}
+	q += ` ORDER BY priority, id`
+	rows, err := d.sql.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Rule
+	for rows.Next() {
+		r, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func (d *DB) DeleteRule(id int64) (bool, error) {
+	res, err := d.sql.Exec(`DELETE FROM rules WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func scanRule(row rowScanner) (Rule, error) {
+	var r Rule
+	var enabled, duplicate int
+	var chats, senders, keywords, media string
+	var created, updated int64
+	if err := row.Scan(&r.ID, &r.Name, &r.Priority, &enabled, &chats, &senders, &keywords, &media, &r.Action, &r.Arg, &r.MinRole, &duplicate, &created, &updated); err != nil {
+		return Rule{}, err
+	}
+	r.Enabled = enabled != 0
+	r.DuplicateMedia = duplicate != 0
+	r.Chats = splitList(chats)
+	r.Senders = splitList(senders)
+	r.Keywords = splitList(keywords)
+	r.MediaTypes = splitList(media)
+	r.CreatedAt = fromUnix(created)
+	r.UpdatedAt = fromUnix(updated)
+	return r, nil
+}
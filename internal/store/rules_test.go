@@ -0,0 +1,76 @@
+package store
+
+import "testing"
+
+func TestRuleCRUD(t *testing.T) {
+	db := openTestDB(t)
+
+	for _, p := range []CreateRuleParams{
+		{Action: "explode"},
+		{Action: RuleReply},
+		{Action: RuleWebhook, Arg: "abc"},
+		{Action: RuleWebhook, Arg: "42"},
+	} {
+		if _, err := db.CreateRule(p); err == nil {
+			t.Fatalf("expected error for %+v", p)
+		}
+	}
+
+	late, err := db.CreateRule(CreateRuleParams{Name: "late", Priority: 10, Action: RuleDrop})
+	if err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+	early, err := db.CreateRule(CreateRuleParams{
+		Name:     "invoices",
+		Keywords: []string{"invoice", " boleto "},
+		Action:   " TAG ",
+		Arg:      "billing",
+	})
+	if err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+	if early.Action != RuleTag || len(early.Keywords) != 2 || early.Keywords[1] != "boleto" || !early.Enabled {
+		t.Fatalf("unexpected rule: %+v", early)
+	}
+
+	rules, err := db.ListRules(true)
+	if err != nil {
+		t.Fatalf("ListRules: %v", err)
+	}
+	if len(rules) != 2 || rules[0].ID != early.ID || rules[1].ID != late.ID {
+		t.Fatalf("expected priority order, got %+v", rules)
+	}
+
+	if ok, err := db.DeleteRule(late.ID); err != nil || !ok {
+		t.Fatalf("DeleteRule: ok=%v err=%v", ok, err)
+	}
+	if _, err := db.GetRule(late.ID); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestUpdateRule(t *testing.T) {
+	db := openTestDB(t)
+	r, err := db.CreateRule(CreateRuleParams{Name: "quiet", Action: RuleDrop, Disabled: true})
+	if err != nil || r.Enabled {
+		t.Fatalf("CreateRule: %+v (%v)", r, err)
+	}
+
+	on, prio, keywords := true, 3, []string{"spam"}
+	r, err = db.UpdateRule(r.ID, UpdateRuleParams{Enabled: &on, Priority: &prio, Keywords: &keywords})
+	if err != nil {
+		t.Fatalf("UpdateRule: %v", err)
+	}
+	if !r.Enabled || r.Priority != 3 || r.Name != "quiet" || len(r.Keywords) != 1 || r.Action != RuleDrop {
+		t.Fatalf("unexpected rule: %+v", r)
+	}
+
+	// Switching to an action that needs an arg is validated like a new rule.
+	reply := RuleReply
+	if _, err := db.UpdateRule(r.ID, UpdateRuleParams{Action: &reply}); err == nil {
+		t.Fatalf("expected missing arg error")
+	}
+	if _, err := db.UpdateRule(999, UpdateRuleParams{Enabled: &on}); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
@@ -3,6 +3,9 @@
 package store
 
 import (
+	"context"
+	"fmt"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -40,4 +43,89 @@ func TestSearchMessagesUsesFTSWhenEnabled(t *testing.T) {
 	if ms[0].Snippet == "" {
 		t.Fatalf("expected snippet for FTS search, got empty")
 	}
+	if ms[0].Score <= 0 {
+		t.Fatalf("expected a positive relevance score, got %v", ms[0].Score)
+	}
+
+	ms, err = db.SearchMessages(SearchMessagesParams{Query: "hello", Limit: 10, HighlightStart: "<mark>", HighlightEnd: "</mark>"})
+	if err != nil {
+		t.Fatalf("SearchMessages: %v", err)
+	}
+	if len(ms) != 1 || ms[0].Snippet != "<mark>hello</mark> world" {
+		t.Fatalf("unexpected highlighted snippet: %+v", ms)
+	}
+}
+
+func TestRebuildFTSRecoversMissingAndStaleIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wacli.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	chat := "123@s.whatsapp.net"
+	if err := db.UpsertChat(chat, "dm", "Alice", time.Now()); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	for i, text := range []string{"hello world", "another hello"} {
+		if err := db.UpsertMessage(UpsertMessageParams{ChatJID: chat, MsgID: fmt.Sprintf("m%d", i), SenderJID: chat, Timestamp: time.Now(), Text: text}); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+	// Stale: one message missing from the index.
+	if _, err := db.sql.Exec(`DELETE FROM messages_fts WHERE rowid = (SELECT MIN(rowid) FROM messages)`); err != nil {
+		t.Fatalf("delete from fts: %v", err)
+	}
+	_ = db.Close()
+
+	db, err = Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if db.HasFTS() || !db.FTSNeedsRebuild() {
+		t.Fatalf("expected stale index to need a rebuild, got %+v", db.FTSStatus())
+	}
+	// Search keeps working through LIKE, and so do writes.
+	ms, err := db.SearchMessages(SearchMessagesParams{Query: "hello"})
+	if err != nil || len(ms) != 2 {
+		t.Fatalf("LIKE fallback: %v %+v", err, ms)
+	}
+	if err := db.UpsertMessage(UpsertMessageParams{ChatJID: chat, MsgID: "m3", SenderJID: chat, Timestamp: time.Now(), Text: "hello again"}); err != nil {
+		t.Fatalf("UpsertMessage while degraded: %v", err)
+	}
+
+	var lastDone, lastTotal int64
+	if err := db.RebuildFTS(context.Background(), func(done, total int64) { lastDone, lastTotal = done, total }); err != nil {
+		t.Fatalf("RebuildFTS: %v", err)
+	}
+	if lastDone != 3 || lastTotal != 3 {
+		t.Fatalf("progress = %d/%d, want 3/3", lastDone, lastTotal)
+	}
+	if st := db.FTSStatus(); !db.HasFTS() || st.State != FTSReady {
+		t.Fatalf("expected ready index, got %+v", st)
+	}
+	ms, err = db.SearchMessages(SearchMessagesParams{Query: "hello"})
+	if err != nil || len(ms) != 3 || ms[0].Snippet == "" {
+		t.Fatalf("FTS search after rebuild: %v %+v", err, ms)
+	}
+
+	// Missing: the index table is gone entirely.
+	if _, err := db.sql.Exec(`DROP TABLE messages_fts`); err != nil {
+		t.Fatalf("drop fts: %v", err)
+	}
+	_ = db.Close()
+	db, err = Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	if !db.FTSNeedsRebuild() {
+		t.Fatalf("expected missing index to need a rebuild, got %+v", db.FTSStatus())
+	}
+	if err := db.RebuildFTS(context.Background(), nil); err != nil {
+		t.Fatalf("RebuildFTS: %v", err)
+	}
+	if ms, err := db.SearchMessages(SearchMessagesParams{Query: "again"}); err != nil || len(ms) != 1 {
+		t.Fatalf("search after rebuild: %v %+v", err, ms)
+	}
 }
@@ -3,6 +3,7 @@
 package store
 
 import (
+	"context"
 	"testing"
 	"time"
 )
@@ -37,7 +38,20 @@ func TestSearchMessagesUsesLIKEWhenFTSDisabled(t *testing.T) {
 	if len(ms) != 1 {
 		t.Fatalf("expected 1 result, got %d", len(ms))
 	}
-	if ms[0].Snippet != "" {
-		t.Fatalf("expected empty snippet for LIKE search, got %q", ms[0].Snippet)
+	if ms[0].Snippet != "[hello] world" || ms[0].Score != 1 {
+		t.Fatalf("unexpected LIKE snippet %q (score %v)", ms[0].Snippet, ms[0].Score)
+	}
+}
+
+func TestRebuildFTSUnavailableWithoutFTS5(t *testing.T) {
+	db := openTestDB(t)
+	if st := db.FTSStatus(); st.State != FTSUnavailable {
+		t.Fatalf("expected unavailable FTS, got %+v", st)
+	}
+	if err := db.RebuildFTS(context.Background(), nil); err == nil {
+		t.Fatalf("expected RebuildFTS to fail without fts5")
+	}
+	if st := db.FTSStatus(); st.State != FTSUnavailable || db.FTSNeedsRebuild() {
+		t.Fatalf("expected FTS to stay unavailable, got %+v", st)
 	}
 }
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	SentimentPositive = "positive"
+	SentimentNeutral  = "neutral"
+	SentimentNegative = "negative"
+)
+
+type MessageSentiment struct {
+	ChatJID  string
+	MsgID    string
+	Label    string
+	Score    float64
+	Urgency  float64
+	ScoredAt time.Time
+}
+
+// ChatSentiment is the per-chat rollup of scored inbound messages.
+type ChatSentiment struct {
+	ChatJID       string
+	ChatName      string
+	Scored        int64
+	Negative      int64
+	AvgScore      float64
+	AvgUrgency    float64
+	MaxUrgency    float64
+	LastNegative  time.Time
+	LastMessageTS time.Time
+}
+
+func (d *DB) UpsertMessageSentiment(s MessageSentiment) error {
+	if strings.TrimSpace(s.ChatJID) == "" || strings.TrimSpace(s.MsgID) == "" {
+		return fmt.Errorf("chat and message id are required")
+	}
+	switch s.Label {
+	case SentimentPositive, SentimentNeutral, SentimentNegative:
+	default:
+		return fmt.Errorf("invalid sentiment label %q", s.Label)
+	}
+	if s.ScoredAt.IsZero() {
+		s.ScoredAt = time.Now()
+	}
+	_, err := d.sql.Exec(`
+		INSERT INTO message_sentiment(chat_jid, msg_id, label, score, urgency, scored_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(chat_jid, msg_id) DO UPDATE SET
+			label=excluded.label,
+			score=excluded.score,
+			urgency=excluded.urgency,
+			scored_at=excluded.scored_at
+	`, s.ChatJID, s.MsgID, s.Label, clamp(s.Score, -1, 1), clamp(s.Urgency, 0, 1), unix(s.ScoredAt))
+	return err
+}
+
+func (d *DB) GetMessageSentiment(chatJID, msgID string) (MessageSentiment, error) {
+	row := d.sql.QueryRow(`
+		SELECT chat_jid, msg_id, label, score, urgency, scored_at
+		FROM message_sentiment WHERE chat_jid = ? AND msg_id = ?
+	`, chatJID, msgID)
+	var s MessageSentiment
+	var scored int64
+	if err := row.Scan(&s.ChatJID, &s.MsgID, &s.Label, &s.Score, &s.Urgency, &scored); err != nil {
+		return MessageSentiment{}, err
+	}
+	s.ScoredAt = fromUnix(scored)
+	return s, nil
+}
+
+// ChatSentimentStats rolls up scored messages per chat, most negative and
+// most urgent chats first. Only messages newer than since are counted.
+func (d *DB) ChatSentimentStats(since time.Time, limit int) ([]ChatSentiment, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := d.sql.Query(`
+		SELECT s.chat_jid,
+		       COALESCE(c.name,''),
+		       COUNT(1),
+		       SUM(CASE WHEN s.label = 'negative' THEN 1 ELSE 0 END),
+		       AVG(s.score),
+		       AVG(s.urgency),
+		       MAX(s.urgency),
+		       COALESCE(MAX(CASE WHEN s.label = 'negative' THEN m.ts END), 0),
+		       COALESCE(MAX(m.ts), 0)
+		FROM message_sentiment s
+		JOIN messages m ON m.chat_jid = s.chat_jid AND m.msg_id = s.msg_id
+		LEFT JOIN chats c ON c.jid = s.chat_jid
+		WHERE m.ts >= ?
+		GROUP BY s.chat_jid
+		ORDER BY AVG(s.score) ASC, MAX(s.urgency) DESC, MAX(m.ts) DESC
+		LIMIT ?
+	`, unix(since), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ChatSentiment
+	for rows.Next() {
+		var cs ChatSentiment
+		var lastNeg, lastTS int64
+		if err := rows.Scan(&cs.ChatJID, &cs.ChatName, &cs.Scored, &cs.Negative, &cs.AvgScore, &cs.AvgUrgency, &cs.MaxUrgency, &lastNeg, &lastTS); err != nil {
+			return nil, err
+		}
+		cs.LastNegative = fromUnix(lastNeg)
+		cs.LastMessageTS = fromUnix(lastTS)
+		out = append(out, cs)
+	}
+	return out, rows.Err()
+}
+
+func clamp(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
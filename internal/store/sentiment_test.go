@@ -0,0 +1,60 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChatSentimentStatsRollup(t *testing.T) {
+	db := openTestDB(t)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []string{"a@s.whatsapp.net", "b@s.whatsapp.net"} {
+		if err := db.UpsertChat(c, "dm", c, base); err != nil {
+			t.Fatalf("UpsertChat: %v", err)
+		}
+	}
+	msgs := []struct {
+		chat, id string
+		ts       time.Time
+		label    string
+		score    float64
+		urgency  float64
+	}{
+		{"a@s.whatsapp.net", "a1", base.Add(time.Hour), SentimentNegative, -0.8, 0.9},
+		{"a@s.whatsapp.net", "a2", base.Add(2 * time.Hour), SentimentNeutral, 0, 0.2},
+		{"b@s.whatsapp.net", "b1", base.Add(time.Hour), SentimentPositive, 0.9, 0.1},
+		{"b@s.whatsapp.net", "old", base.Add(-48 * time.Hour), SentimentNegative, -1, 1},
+	}
+	for _, m := range msgs {
+		if err := db.UpsertMessage(UpsertMessageParams{ChatJID: m.chat, MsgID: m.id, Timestamp: m.ts, Text: "x"}); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+		if err := db.UpsertMessageSentiment(MessageSentiment{ChatJID: m.chat, MsgID: m.id, Label: m.label, Score: m.score, Urgency: m.urgency}); err != nil {
+			t.Fatalf("UpsertMessageSentiment: %v", err)
+		}
+	}
+
+	if err := db.UpsertMessageSentiment(MessageSentiment{ChatJID: "a@s.whatsapp.net", MsgID: "a1", Label: "furious"}); err == nil {
+		t.Fatalf("expected invalid label error")
+	}
+
+	stats, err := db.ChatSentimentStats(base, 10)
+	if err != nil {
+		t.Fatalf("ChatSentimentStats: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 chats, got %d", len(stats))
+	}
+	a := stats[0]
+	if a.ChatJID != "a@s.whatsapp.net" || a.Scored != 2 || a.Negative != 1 || a.MaxUrgency != 0.9 {
+		t.Fatalf("unexpected first rollup: %+v", a)
+	}
+	if !a.LastNegative.Equal(base.Add(time.Hour)) {
+		t.Fatalf("unexpected last negative: %s", a.LastNegative)
+	}
+	b := stats[1]
+	if b.Scored != 1 || b.Negative != 0 {
+		t.Fatalf("old message should be excluded: %+v", b)
+	}
+}
@@ -0,0 +1,24 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// GetSetting returns the value stored under key, or "" when it is unset.
+func (d *DB) GetSetting(key string) (string, error) {
+	var v string
+	err := d.sql.QueryRow(`SELECT value FROM settings WHERE key = ?`, key).Scan(&v)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return v, err
+}
+
+func (d *DB) SetSetting(key, value string) error {
+	_, err := d.sql.Exec(`
+		INSERT INTO settings(key, value, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value=excluded.value, updated_at=excluded.updated_at
+	`, key, value, unix(time.Now().UTC()))
+	return err
+}
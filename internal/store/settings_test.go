@@ -0,0 +1,20 @@
+package store
+
+import "testing"
+
+func TestSettings(t *testing.T) {
+	db := openTestDB(t)
+
+	if v, err := db.GetSetting("away"); err != nil || v != "" {
+		t.Fatalf("expected empty setting, got %q (%v)", v, err)
+	}
+	if err := db.SetSetting("away", "a"); err != nil {
+		t.Fatalf("SetSetting: %v", err)
+	}
+	if err := db.SetSetting("away", "b"); err != nil {
+		t.Fatalf("SetSetting: %v", err)
+	}
+	if v, _ := db.GetSetting("away"); v != "b" {
+		t.Fatalf("expected overwritten value, got %q", v)
+	}
+}
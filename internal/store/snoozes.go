@@ -0,0 +1,100 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ChatSnooze hides a chat from chat listings until Until, when a reminder
+// is sent to RemindTo.
+type ChatSnooze struct {
+	ChatJID  string
+	Until    time.Time
+	RemindTo string
+	Note     string
+	// QuoteLast quotes the chat's latest message in the reminder.
+	QuoteLast bool
+	CreatedAt time.Time
+}
+
+// SnoozeChat snoozes a chat, replacing an earlier snooze of it.
+func (d *DB) SnoozeChat(s ChatSnooze) (ChatSnooze, error) {
+	s.ChatJID = strings.TrimSpace(s.ChatJID)
+	s.RemindTo = strings.TrimSpace(s.RemindTo)
+	s.Note = strings.TrimSpace(s.Note)
+	if s.ChatJID == "" {
+		return ChatSnooze{}, fmt.Errorf("chat is required")
+	}
+	if s.RemindTo == "" {
+		return ChatSnooze{}, fmt.Errorf("remind_to is required")
+	}
+	if s.Until.IsZero() {
+		return ChatSnooze{}, fmt.Errorf("until is required")
+	}
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = time.Now().UTC()
+	}
+	if _, err := d.sql.Exec(`
+		INSERT INTO chat_snoozes(chat_jid, until, remind_to, note, quote_last, created_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(chat_jid) DO UPDATE SET
+			until=excluded.until,
+			remind_to=excluded.remind_to,
+			note=excluded.note,
+			quote_last=excluded.quote_last,
+			created_at=excluded.created_at
+	`, s.ChatJID, unix(s.Until), s.RemindTo, s.Note, boolToInt(s.QuoteLast), unix(s.CreatedAt)); err != nil {
+		return ChatSnooze{}, err
+	}
+	return d.GetChatSnooze(s.ChatJID)
+}
+
+const chatSnoozeColumns = `chat_jid, until, remind_to, note, quote_last, created_at`
+
+func scanChatSnooze(r rowScanner) (ChatSnooze, error) {
+	var s ChatSnooze
+	var until, created int64
+	var quote int
+	if err := r.Scan(&s.ChatJID, &until, &s.RemindTo, &s.Note, &quote, &created); err != nil {
+		return ChatSnooze{}, err
+	}
+	s.Until = fromUnix(until)
+	s.QuoteLast = quote != 0
+	s.CreatedAt = fromUnix(created)
+	return s, nil
+}
+
+// GetChatSnooze returns a chat's snooze, or an error matching IsNotFound.
+// Expired snoozes are returned until their reminder is sent.
+func (d *DB) GetChatSnooze(chatJID string) (ChatSnooze, error) {
+	return scanChatSnooze(d.sql.QueryRow(`SELECT `+chatSnoozeColumns+` FROM chat_snoozes WHERE chat_jid = ?`, chatJID))
+}
+
+// DueChatSnoozes returns the snoozes that expired by now, oldest first.
+func (d *DB) DueChatSnoozes(now time.Time) ([]ChatSnooze, error) {
+	rows, err := d.sql.Query(`SELECT `+chatSnoozeColumns+` FROM chat_snoozes WHERE until <= ? ORDER BY until, chat_jid`, unix(now))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ChatSnooze
+	for rows.Next() {
+		s, err := scanChatSnooze(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// DeleteChatSnooze wakes a chat up without a reminder. It reports whether
+// the chat was snoozed.
+func (d *DB) DeleteChatSnooze(chatJID string) (bool, error) {
+	res, err := d.sql.Exec(`DELETE FROM chat_snoozes WHERE chat_jid = ?`, chatJID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
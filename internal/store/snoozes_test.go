@@ -0,0 +1,55 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChatSnoozesHideChats(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Now().UTC()
+	for _, jid := range []string{"1@s.whatsapp.net", "2@s.whatsapp.net", "3@s.whatsapp.net"} {
+		if err := db.UpsertChat(jid, "dm", jid, now); err != nil {
+			t.Fatalf("UpsertChat: %v", err)
+		}
+	}
+	if _, err := db.SnoozeChat(ChatSnooze{ChatJID: "1@s.whatsapp.net", Until: now.Add(time.Hour)}); err == nil {
+		t.Fatalf("expected remind_to to be required")
+	}
+	s, err := db.SnoozeChat(ChatSnooze{ChatJID: "1@s.whatsapp.net", Until: now.Add(time.Hour), RemindTo: "9@s.whatsapp.net", Note: " answer ", QuoteLast: true})
+	if err != nil {
+		t.Fatalf("SnoozeChat: %v", err)
+	}
+	if s.Note != "answer" || !s.QuoteLast || s.Until.Unix() != now.Add(time.Hour).Unix() {
+		t.Fatalf("unexpected snooze: %+v", s)
+	}
+	// An expired snooze no longer hides its chat, even before its reminder.
+	if _, err := db.SnoozeChat(ChatSnooze{ChatJID: "2@s.whatsapp.net", Until: now.Add(-time.Minute), RemindTo: "9@s.whatsapp.net"}); err != nil {
+		t.Fatalf("SnoozeChat: %v", err)
+	}
+
+	page, _, total, err := db.ListChatsPage(ListPageParams{})
+	if err != nil || total != 2 || len(page) != 2 {
+		t.Fatalf("ListChatsPage = %d chats (total %d), %v", len(page), total, err)
+	}
+	for _, c := range page {
+		if c.JID == "1@s.whatsapp.net" {
+			t.Fatalf("expected the snoozed chat to be hidden")
+		}
+	}
+	page, _, _, err = db.ListChatsPage(ListPageParams{Snoozed: true})
+	if err != nil || len(page) != 1 || page[0].JID != "1@s.whatsapp.net" {
+		t.Fatalf("snoozed chats = %+v, %v", page, err)
+	}
+
+	due, err := db.DueChatSnoozes(now)
+	if err != nil || len(due) != 1 || due[0].ChatJID != "2@s.whatsapp.net" {
+		t.Fatalf("DueChatSnoozes = %+v, %v", due, err)
+	}
+	if ok, err := db.DeleteChatSnooze("1@s.whatsapp.net"); err != nil || !ok {
+		t.Fatalf("DeleteChatSnooze = %v, %v", ok, err)
+	}
+	if _, err := db.GetChatSnooze("1@s.whatsapp.net"); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+type MessageStatsParams struct {
+	After   time.Time
+	Before  time.Time
+	ChatJID string
+	// Limit caps the per-chat and per-sender lists (default 20).
+	Limit int
+}
+
+// MessageStats aggregates message volume over a time range. Days are UTC
+// calendar days and only include days with messages.
+type MessageStats struct {
+	Total    int
+	Incoming int
+	Outgoing int
+	Days     []DayMessageCount
+	Chats    []ChatMessageCount
+	Senders  []SenderMessageCount
+}
+
+type DayMessageCount struct {
+	Day      string // YYYY-MM-DD
+	Total    int
+	Incoming int
+	Outgoing int
+}
+
+type ChatMessageCount struct {
+	ChatJID       string
+	ChatName      string
+	Kind          string
+	Total         int
+	Incoming      int
+	Outgoing      int
+	LastMessageTS time.Time
+}
+
+type SenderMessageCount struct {
+	SenderJID  string
+	SenderName string
+	FromMe     bool
+	Total      int
+}
+
+// MessageStats counts messages per day, per chat and per sender, busiest
+// first. Chats in the trash are left out.
+func (d *DB) MessageStats(p MessageStatsParams) (MessageStats, error) {
+	if p.Limit <= 0 {
+		p.Limit = 20
+	}
+	where := ` FROM messages m LEFT JOIN chats c ON c.jid = m.chat_jid WHERE 1=1` + notTrashedSQL
+	var args []interface{}
+	if !p.After.IsZero() {
+		where += " AND m.ts >= ?"
+		args = append(args, unix(p.After))
+	}
+	if !p.Before.IsZero() {
+		where += " AND m.ts < ?"
+		args = append(args, unix(p.Before))
+	}
+	if strings.TrimSpace(p.ChatJID) != "" {
+		where += " AND m.chat_jid = ?"
+		args = append(args, p.ChatJID)
+	}
+	const counts = `COUNT(1), COALESCE(SUM(m.from_me = 0), 0), COALESCE(SUM(m.from_me != 0), 0)`
+
+	var out MessageStats
+	if err := d.sql.QueryRow(`SELECT `+counts+where, args...).Scan(&out.Total, &out.Incoming, &out.Outgoing); err != nil {
+		return MessageStats{}, err
+	}
+
+	rows, err := d.sql.Query(`SELECT date(m.ts, 'unixepoch') AS day, `+counts+where+` GROUP BY day ORDER BY day`, args...)
+	if err != nil {
+		return MessageStats{}, err
+	}
+	for rows.Next() {
+		var dc DayMessageCount
+		if err := rows.Scan(&dc.Day, &dc.Total, &dc.Incoming, &dc.Outgoing); err != nil {
+			rows.Close()
+			return MessageStats{}, err
+		}
+		out.Days = append(out.Days, dc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return MessageStats{}, err
+	}
+
+	rows, err = d.sql.Query(`SELECT m.chat_jid, COALESCE(MAX(c.name),''), COALESCE(MAX(c.kind),''), `+counts+`, MAX(m.ts)`+where+`
+		GROUP BY m.chat_jid ORDER BY COUNT(1) DESC, MAX(m.ts) DESC LIMIT ?`, append(args, p.Limit)...)
+	if err != nil {
+		return MessageStats{}, err
+	}
+	for rows.Next() {
+		var cc ChatMessageCount
+		var last int64
+		if err := rows.Scan(&cc.ChatJID, &cc.ChatName, &cc.Kind, &cc.Total, &cc.Incoming, &cc.Outgoing, &last); err != nil {
+			rows.Close()
+			return MessageStats{}, err
+		}
+		cc.LastMessageTS = fromUnix(last)
+		out.Chats = append(out.Chats, cc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return MessageStats{}, err
+	}
+
+	rows, err = d.sql.Query(`SELECT COALESCE(m.sender_jid,''), COALESCE(MAX(m.sender_name),''), m.from_me != 0, COUNT(1)`+where+`
+		GROUP BY m.from_me != 0, COALESCE(m.sender_jid,'') ORDER BY COUNT(1) DESC LIMIT ?`, append(args, p.Limit)...)
+	if err != nil {
+		return MessageStats{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var sc SenderMessageCount
+		if err := rows.Scan(&sc.SenderJID, &sc.SenderName, &sc.FromMe, &sc.Total); err != nil {
+			return MessageStats{}, err
+		}
+		out.Senders = append(out.Senders, sc)
+	}
+	return out, rows.Err()
+}
+
+// LastIncomingMessageTime returns the time of the latest message received,
+// or zero without one.
+func (d *DB) LastIncomingMessageTime() (time.Time, error) {
+	var ts int64
+	err := d.sql.QueryRow(`SELECT ts FROM messages WHERE from_me = 0 ORDER BY ts DESC LIMIT 1`).Scan(&ts)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return fromUnix(ts), err
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMessageStats(t *testing.T) {
+	db := openTestDB(t)
+	alerts, team := "111@g.us", "222@g.us"
+	day1 := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+	for _, jid := range []string{alerts, team} {
+		if err := db.UpsertChat(jid, "group", jid, day1); err != nil {
+			t.Fatalf("UpsertChat: %v", err)
+		}
+	}
+	for i, p := range []UpsertMessageParams{
+		{ChatJID: alerts, SenderJID: "bot@s.whatsapp.net", SenderName: "Bot", Timestamp: day1},
+		{ChatJID: alerts, SenderJID: "bot@s.whatsapp.net", SenderName: "Bot", Timestamp: day1.Add(time.Hour)},
+		{ChatJID: alerts, SenderJID: "bot@s.whatsapp.net", SenderName: "Bot", Timestamp: day2},
+		{ChatJID: team, FromMe: true, Timestamp: day2},
+		{ChatJID: team, SenderJID: "ann@s.whatsapp.net", Timestamp: day2.Add(48 * time.Hour)},
+	} {
+		p.MsgID = string(rune('a' + i))
+		p.Text = "x"
+		if err := db.UpsertMessage(p); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+
+	st, err := db.MessageStats(MessageStatsParams{After: day1, Before: day2.Add(24 * time.Hour)})
+	if err != nil {
+		t.Fatalf("MessageStats: %v", err)
+	}
+	if st.Total != 4 || st.Incoming != 3 || st.Outgoing != 1 {
+		t.Fatalf("totals = %d/%d/%d", st.Total, st.Incoming, st.Outgoing)
+	}
+	if len(st.Days) != 2 || st.Days[0].Day != "2024-01-01" || st.Days[0].Total != 2 || st.Days[1].Outgoing != 1 {
+		t.Fatalf("days = %+v", st.Days)
+	}
+	if len(st.Chats) != 2 || st.Chats[0].ChatJID != alerts || st.Chats[0].Total != 3 || !st.Chats[0].LastMessageTS.Equal(day2) {
+		t.Fatalf("chats = %+v", st.Chats)
+	}
+	if len(st.Senders) != 2 || st.Senders[0].SenderName != "Bot" || st.Senders[0].Total != 3 || !st.Senders[1].FromMe {
+		t.Fatalf("senders = %+v", st.Senders)
+	}
+
+	st, err = db.MessageStats(MessageStatsParams{ChatJID: team})
+	if err != nil || st.Total != 2 || len(st.Chats) != 1 {
+		t.Fatalf("chat filter = %+v, %v", st, err)
+	}
+}
@@ -2,11 +2,15 @@ package store
 
 import (
 	"database/sql"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -15,7 +19,14 @@ import (
 type DB struct {
 	path       string
 	sql        *sql.DB
-	ftsEnabled bool
+	ftsEnabled atomic.Bool
+
+	ftsMu         sync.Mutex
+	ftsStatus     FTSStatus
+	ftsRebuilding atomic.Bool
+
+	// chainMu serializes appends to the archive chain.
+	chainMu sync.Mutex
 }
 
 func Open(path string) (*DB, error) {
@@ -65,7 +76,12 @@ func (d *DB) ensureSchema() error {
 			jid TEXT PRIMARY KEY,
 			kind TEXT NOT NULL, -- dm|group|broadcast|unknown
 			name TEXT,
-			last_message_ts INTEGER
+			last_message_ts INTEGER,
+			read_ts INTEGER, -- read watermark; incoming messages after it are unread
+			deleted_at INTEGER, -- set while the chat is in the trash
+			archived INTEGER NOT NULL DEFAULT 0,
+			pinned INTEGER NOT NULL DEFAULT 0,
+			muted_until INTEGER -- -1 while muted forever
 		);
 
 		CREATE TABLE IF NOT EXISTS contacts (
@@ -109,6 +125,14 @@ func (d *DB) ensureSchema() error {
 			PRIMARY KEY (jid, tag)
 		);
 
+		CREATE TABLE IF NOT EXISTS contact_fields (
+			jid TEXT NOT NULL,
+			key TEXT NOT NULL,
+			value TEXT NOT NULL,
+			updated_at INTEGER NOT NULL,
+			PRIMARY KEY (jid, key)
+		);
+
 		CREATE TABLE IF NOT EXISTS messages (
 			rowid INTEGER PRIMARY KEY AUTOINCREMENT,
 			chat_jid TEXT NOT NULL,
@@ -130,13 +154,395 @@ func (d *DB) ensureSchema() error {
 			file_enc_sha256 BLOB,
 			file_length INTEGER,
 			local_path TEXT,
+			local_sha256 TEXT,
 			downloaded_at INTEGER,
+			edited_at INTEGER,
+			revoked_at INTEGER,
+			quoted_id TEXT,
+			quoted_snippet TEXT,
+			system_type TEXT,
 			UNIQUE(chat_jid, msg_id),
 			FOREIGN KEY (chat_jid) REFERENCES chats(jid) ON DELETE CASCADE
 		);
 
 		CREATE INDEX IF NOT EXISTS idx_messages_chat_ts ON messages(chat_jid, ts);
 		CREATE INDEX IF NOT EXISTS idx_messages_ts ON messages(ts);
+		CREATE INDEX IF NOT EXISTS idx_messages_sender_ts ON messages(sender_jid, ts);
+		CREATE INDEX IF NOT EXISTS idx_messages_media_ts ON messages(media_type, ts);
+		CREATE INDEX IF NOT EXISTS idx_messages_file_sha256 ON messages(file_sha256) WHERE file_sha256 IS NOT NULL;
+
+		CREATE TABLE IF NOT EXISTS outbox (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			to_jid TEXT NOT NULL,
+			text TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending', -- pending|sent|failed
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT,
+			msg_id TEXT,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL,
+			sent_at INTEGER
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_outbox_status ON outbox(status, id);
+
+		CREATE TABLE IF NOT EXISTS message_sentiment (
+			chat_jid TEXT NOT NULL,
+			msg_id TEXT NOT NULL,
+			label TEXT NOT NULL, -- positive|neutral|negative
+			score REAL NOT NULL, -- -1 (very negative) .. 1 (very positive)
+			urgency REAL NOT NULL DEFAULT 0, -- 0 .. 1
+			scored_at INTEGER NOT NULL,
+			PRIMARY KEY (chat_jid, msg_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS entities (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			chat_jid TEXT NOT NULL,
+			msg_id TEXT NOT NULL,
+			type TEXT NOT NULL,
+			value TEXT NOT NULL,
+			normalized TEXT NOT NULL,
+			ts INTEGER NOT NULL,
+			UNIQUE(chat_jid, msg_id, type, normalized)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_entities_type_ts ON entities(type, ts);
+		CREATE INDEX IF NOT EXISTS idx_entities_chat_ts ON entities(chat_jid, ts);
+
+		CREATE TABLE IF NOT EXISTS webhooks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			url TEXT NOT NULL,
+			events TEXT NOT NULL DEFAULT '', -- comma-separated event types, empty = all
+			chats TEXT NOT NULL DEFAULT '', -- comma-separated chat JIDs, empty = all
+			enabled INTEGER NOT NULL DEFAULT 1,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS presence_watch (
+			jid TEXT PRIMARY KEY,
+			created_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS presence_intervals (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			jid TEXT NOT NULL,
+			online_at INTEGER NOT NULL,
+			offline_at INTEGER -- NULL while the contact is online
+		);
+		CREATE INDEX IF NOT EXISTS idx_presence_intervals_jid_online ON presence_intervals(jid, online_at);
+
+		CREATE TABLE IF NOT EXISTS presence_state (
+			jid TEXT PRIMARY KEY,
+			available INTEGER NOT NULL,
+			last_seen INTEGER, -- NULL unless the contact shares it
+			updated_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS webhook_queue (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			webhook_id INTEGER NOT NULL,
+			event_type TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT,
+			next_attempt_at INTEGER NOT NULL,
+			created_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_webhook_queue_next ON webhook_queue(next_attempt_at, id);
+
+		CREATE TABLE IF NOT EXISTS webhook_dead_letters (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			webhook_id INTEGER NOT NULL,
+			event_type TEXT NOT NULL,
+			payload TEXT NOT NULL,
+			attempts INTEGER NOT NULL,
+			last_error TEXT,
+			created_at INTEGER NOT NULL,
+			failed_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_webhook_dead_letters_webhook ON webhook_dead_letters(webhook_id, id);
+
+		CREATE TABLE IF NOT EXISTS webhook_attempts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			webhook_id INTEGER NOT NULL,
+			delivery_id INTEGER NOT NULL, -- webhook_queue id
+			event_type TEXT NOT NULL,
+			chat_jid TEXT,
+			msg_id TEXT,
+			payload TEXT NOT NULL,
+			attempt INTEGER NOT NULL,
+			success INTEGER NOT NULL,
+			status_code INTEGER,
+			error TEXT,
+			latency_ms INTEGER NOT NULL,
+			response_snippet TEXT,
+			attempted_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_webhook_attempts_webhook ON webhook_attempts(webhook_id, id);
+		CREATE INDEX IF NOT EXISTS idx_webhook_attempts_msg ON webhook_attempts(msg_id);
+		CREATE INDEX IF NOT EXISTS idx_webhook_attempts_at ON webhook_attempts(attempted_at);
+
+		CREATE TABLE IF NOT EXISTS rules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL DEFAULT '',
+			priority INTEGER NOT NULL DEFAULT 0, -- lower runs first
+			enabled INTEGER NOT NULL DEFAULT 1,
+			chats TEXT NOT NULL DEFAULT '', -- comma-separated, empty = any
+			senders TEXT NOT NULL DEFAULT '',
+			keywords TEXT NOT NULL DEFAULT '',
+			media_types TEXT NOT NULL DEFAULT '',
+			action TEXT NOT NULL, -- drop|tag|reply|webhook
+			arg TEXT NOT NULL DEFAULT '',
+			min_role TEXT NOT NULL DEFAULT '', -- empty = any sender
+			duplicate_media INTEGER NOT NULL DEFAULT 0,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS sender_roles (
+			jid TEXT PRIMARY KEY,
+			role TEXT NOT NULL, -- owner|admin|user
+			note TEXT NOT NULL DEFAULT '',
+			updated_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS calls (
+			call_id TEXT PRIMARY KEY,
+			chat_jid TEXT NOT NULL,
+			caller_jid TEXT NOT NULL DEFAULT '',
+			media TEXT NOT NULL DEFAULT '', -- audio|video
+			is_group INTEGER NOT NULL DEFAULT 0,
+			status TEXT NOT NULL, -- ringing|answered|rejected|missed|ended
+			started_at INTEGER NOT NULL,
+			ended_at INTEGER,
+			updated_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_calls_chat_started ON calls(chat_jid, started_at);
+
+		CREATE TABLE IF NOT EXISTS media_thumbnails (
+			chat_jid TEXT NOT NULL,
+			msg_id TEXT NOT NULL,
+			data BLOB NOT NULL,
+			width INTEGER NOT NULL DEFAULT 0,
+			height INTEGER NOT NULL DEFAULT 0,
+			source TEXT NOT NULL, -- embedded|generated
+			created_at INTEGER NOT NULL,
+			PRIMARY KEY (chat_jid, msg_id)
+		);
+
+		CREATE TABLE IF NOT EXISTS identity_changes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			jid TEXT NOT NULL,
+			implicit INTEGER NOT NULL DEFAULT 0,
+			changed_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_identity_changes_jid ON identity_changes(jid, changed_at);
+
+		CREATE TABLE IF NOT EXISTS settings (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL,
+			updated_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS receipts (
+			chat_jid TEXT NOT NULL,
+			msg_id TEXT NOT NULL,
+			participant TEXT NOT NULL,
+			delivered_at INTEGER,
+			read_at INTEGER,
+			played_at INTEGER,
+			PRIMARY KEY (chat_jid, msg_id, participant)
+		);
+
+		CREATE TABLE IF NOT EXISTS message_revisions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			chat_jid TEXT NOT NULL,
+			msg_id TEXT NOT NULL,
+			kind TEXT NOT NULL, -- edited|revoked
+			text TEXT,
+			display_text TEXT,
+			media_caption TEXT,
+			replaced_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_message_revisions_msg ON message_revisions(chat_jid, msg_id);
+
+		CREATE TABLE IF NOT EXISTS archive_chain (
+			seq INTEGER PRIMARY KEY,
+			chat_jid TEXT NOT NULL,
+			msg_id TEXT NOT NULL,
+			digest TEXT NOT NULL, -- empty once the message was deleted
+			hash TEXT NOT NULL,
+			created_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_archive_chain_msg ON archive_chain(chat_jid, msg_id, seq);
+
+		CREATE TABLE IF NOT EXISTS payload_schemas (
+			chat_jid TEXT PRIMARY KEY,
+			schema TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS webhook_inbox (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			method TEXT NOT NULL,
+			path TEXT NOT NULL, -- including the query string
+			header TEXT NOT NULL DEFAULT '{}', -- JSON, without credentials
+			body BLOB,
+			key_hash TEXT NOT NULL DEFAULT '', -- SHA-256 of the API key it was sent with
+			status TEXT NOT NULL DEFAULT 'pending', -- pending|done|failed
+			attempts INTEGER NOT NULL DEFAULT 0,
+			last_status INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT,
+			next_attempt_at INTEGER NOT NULL,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL,
+			done_at INTEGER
+		);
+		CREATE INDEX IF NOT EXISTS idx_webhook_inbox_due ON webhook_inbox(status, next_attempt_at);
+
+		CREATE TABLE IF NOT EXISTS webhook_routes (
+			name TEXT PRIMARY KEY,
+			mapping TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS maintenance_windows (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			matchers TEXT NOT NULL,
+			mode TEXT NOT NULL,
+			comment TEXT NOT NULL DEFAULT '',
+			created_by TEXT NOT NULL DEFAULT '',
+			starts_at INTEGER NOT NULL,
+			ends_at INTEGER NOT NULL,
+			summarized_at INTEGER,
+			created_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_maintenance_windows_ends ON maintenance_windows(ends_at);
+
+		CREATE TABLE IF NOT EXISTS maintenance_suppressed (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			window_id INTEGER NOT NULL REFERENCES maintenance_windows(id) ON DELETE CASCADE,
+			recipient TEXT NOT NULL,
+			message TEXT NOT NULL,
+			created_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_maintenance_suppressed_window ON maintenance_suppressed(window_id, id);
+
+		CREATE TABLE IF NOT EXISTS group_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			group_jid TEXT NOT NULL,
+			action TEXT NOT NULL,
+			participant_jid TEXT NOT NULL,
+			actor_jid TEXT NOT NULL DEFAULT '',
+			ts INTEGER NOT NULL,
+			created_at INTEGER NOT NULL,
+			UNIQUE(group_jid, participant_jid, action, ts)
+		);
+		CREATE INDEX IF NOT EXISTS idx_group_events_group_ts ON group_events(group_jid, ts);
+
+		-- Cached answers of WhatsApp's "is this number on WhatsApp" lookup.
+		CREATE TABLE IF NOT EXISTS number_checks (
+			phone TEXT PRIMARY KEY,
+			on_whatsapp INTEGER NOT NULL,
+			jid TEXT NOT NULL DEFAULT '',
+			business_name TEXT NOT NULL DEFAULT '',
+			checked_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS group_snapshots (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			group_jid TEXT NOT NULL,
+			group_name TEXT NOT NULL DEFAULT '',
+			members TEXT NOT NULL, -- JSON array of participant JIDs, sorted
+			member_count INTEGER NOT NULL,
+			taken_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_group_snapshots_group_taken ON group_snapshots(group_jid, taken_at);
+
+		-- Cached profile pictures of contacts and groups. missing marks a
+		-- JID without a picture (or one hidden from us) so it is not asked
+		-- for again until the cache expires.
+		CREATE TABLE IF NOT EXISTS avatars (
+			jid TEXT PRIMARY KEY,
+			picture_id TEXT NOT NULL DEFAULT '',
+			data BLOB,
+			missing INTEGER NOT NULL DEFAULT 0,
+			fetched_at INTEGER NOT NULL
+		);
+
+		-- LID (@lid) JIDs and the phone number JIDs of the same accounts,
+		-- learned from messages and group participant lists.
+		CREATE TABLE IF NOT EXISTS lid_map (
+			lid TEXT PRIMARY KEY,
+			phone_jid TEXT NOT NULL,
+			updated_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_lid_map_phone ON lid_map(phone_jid);
+
+		-- Incoming media whose file was already in the archive when it
+		-- arrived, linked to the earliest message with the same file.
+		CREATE TABLE IF NOT EXISTS media_duplicates (
+			chat_jid TEXT NOT NULL,
+			msg_id TEXT NOT NULL,
+			original_chat_jid TEXT NOT NULL,
+			original_msg_id TEXT NOT NULL,
+			PRIMARY KEY (chat_jid, msg_id)
+		);
+
+		-- Snoozed chats are hidden from chat listings until "until", when a
+		-- reminder is sent to remind_to and the row is removed.
+		CREATE TABLE IF NOT EXISTS chat_snoozes (
+			chat_jid TEXT PRIMARY KEY,
+			until INTEGER NOT NULL,
+			remind_to TEXT NOT NULL,
+			note TEXT NOT NULL DEFAULT '',
+			quote_last INTEGER NOT NULL DEFAULT 0,
+			created_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_chat_snoozes_until ON chat_snoozes(until);
+
+		-- Per-chat exceptions to pruning. Not keyed to chats so a hold can be
+		-- placed on a chat before its first message is stored.
+		CREATE TABLE IF NOT EXISTS chat_retention (
+			chat_jid TEXT PRIMARY KEY,
+			legal_hold INTEGER NOT NULL DEFAULT 0,
+			never_prune INTEGER NOT NULL DEFAULT 0,
+			retention_days INTEGER NOT NULL DEFAULT 0,
+			reason TEXT NOT NULL DEFAULT '',
+			updated_by TEXT NOT NULL DEFAULT '',
+			updated_at INTEGER NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS message_callbacks (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			chat_jid TEXT NOT NULL,
+			msg_id TEXT, -- NULL until a queued (outbox) message is sent
+			outbox_id INTEGER,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL DEFAULT '',
+			delivered_at INTEGER,
+			read_at INTEGER,
+			played_at INTEGER,
+			created_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_message_callbacks_msg ON message_callbacks(chat_jid, msg_id);
+		CREATE INDEX IF NOT EXISTS idx_message_callbacks_outbox ON message_callbacks(outbox_id);
+
+		CREATE TABLE IF NOT EXISTS api_keys (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL DEFAULT '',
+			prefix TEXT NOT NULL,
+			key_hash TEXT NOT NULL UNIQUE, -- hex SHA-256 of the key
+			rate_limit INTEGER NOT NULL DEFAULT 0, -- requests per minute, 0 for none
+			expires_at INTEGER,
+			revoked_at INTEGER,
+			last_used_at INTEGER,
+			created_at INTEGER NOT NULL
+		);
 	`); err != nil {
 		return fmt.Errorf("create tables: %w", err)
 	}
@@ -145,107 +551,138 @@ func (d *DB) ensureSchema() error {
 		return err
 	}
 
+	if err := d.ensureWebhookColumns(); err != nil {
+		return err
+	}
+
+	if err := d.ensureChatColumns(); err != nil {
+		return err
+	}
+
+	if err := d.ensureRuleColumns(); err != nil {
+		return err
+	}
+
 	if err := d.ensureMessagesFTS(); err != nil {
 		return err
 	}
 
+	if err := d.ensureReadModelViews(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func (d *DB) ensureMessageColumns() error {
-	ok, err := d.tableHasColumn("messages", "display_text")
-	if err != nil {
-		return err
-	}
-	if ok {
-		return nil
+	for _, col := range []struct{ name, def string }{
+		{"display_text", "TEXT"},
+		{"edited_at", "INTEGER"},
+		{"revoked_at", "INTEGER"},
+		{"quoted_id", "TEXT"},
+		{"quoted_snippet", "TEXT"},
+		{"local_sha256", "TEXT"},
+		{"system_type", "TEXT"},
+	} {
+		ok, err := d.tableHasColumn("messages", col.name)
+		if err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+		if _, err := d.sql.Exec(`ALTER TABLE messages ADD COLUMN ` + col.name + ` ` + col.def); err != nil {
+			return fmt.Errorf("add %s column: %w", col.name, err)
+		}
 	}
-	if _, err := d.sql.Exec(`ALTER TABLE messages ADD COLUMN display_text TEXT`); err != nil {
-		return fmt.Errorf("add display_text column: %w", err)
+	// Created here rather than in the schema so they follow the columns on
+	// databases from before quoted_id and local_sha256 existed.
+	if _, err := d.sql.Exec(`CREATE INDEX IF NOT EXISTS idx_messages_quoted ON messages(chat_jid, quoted_id) WHERE quoted_id IS NOT NULL`); err != nil {
+		return err
 	}
-	return nil
+	_, err := d.sql.Exec(`CREATE INDEX IF NOT EXISTS idx_messages_local_sha256 ON messages(local_sha256) WHERE local_sha256 IS NOT NULL`)
+	return err
 }
 
-func (d *DB) ensureMessagesFTS() error {
-	ftsExists, err := d.tableExists("messages_fts")
+func (d *DB) ensureChatColumns() error {
+	ok, err := d.tableHasColumn("chats", "read_ts")
 	if err != nil {
 		return err
 	}
-	if ftsExists {
-		hasDisplay, err := d.tableHasColumn("messages_fts", "display_text")
+	if !ok {
+		if _, err := d.sql.Exec(`ALTER TABLE chats ADD COLUMN read_ts INTEGER`); err != nil {
+			return fmt.Errorf("add read_ts column: %w", err)
+		}
+		// Existing archives start fully read rather than with their whole
+		// history unread.
+		if _, err := d.sql.Exec(`UPDATE chats SET read_ts = last_message_ts`); err != nil {
+			return fmt.Errorf("backfill read watermarks: %w", err)
+		}
+	}
+
+	for _, col := range []struct{ name, def string }{
+		{"deleted_at", "INTEGER"},
+		{"archived", "INTEGER NOT NULL DEFAULT 0"},
+		{"pinned", "INTEGER NOT NULL DEFAULT 0"},
+		{"muted_until", "INTEGER"},
+	} {
+		ok, err := d.tableHasColumn("chats", col.name)
 		if err != nil {
 			return err
 		}
-		if !hasDisplay {
-			if _, err := d.sql.Exec(`DROP TABLE IF EXISTS messages_fts`); err != nil {
-				return fmt.Errorf("drop messages_fts: %w", err)
-			}
-			ftsExists = false
+		if ok {
+			continue
+		}
+		if _, err := d.sql.Exec(`ALTER TABLE chats ADD COLUMN ` + col.name + ` ` + col.def); err != nil {
+			return fmt.Errorf("add %s column: %w", col.name, err)
 		}
 	}
+	return nil
+}
 
-	created := false
-	if !ftsExists {
-		if _, err := d.sql.Exec(`
-			CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(
-				text,
-				media_caption,
-				filename,
-				chat_name,
-				sender_name,
-				display_text
-			);
-		`); err != nil {
-			// Continue without FTS (fallback to LIKE).
-			d.ftsEnabled = false
-			return nil
+func (d *DB) ensureWebhookColumns() error {
+	ok, err := d.tableHasColumn("webhooks", "secret")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if _, err := d.sql.Exec(`ALTER TABLE webhooks ADD COLUMN secret TEXT NOT NULL DEFAULT ''`); err != nil {
+			return fmt.Errorf("add secret column: %w", err)
+		}
+		// Subscriptions created before signing existed get a random secret.
+		if _, err := d.sql.Exec(`UPDATE webhooks SET secret = lower(hex(randomblob(32))) WHERE secret = ''`); err != nil {
+			return fmt.Errorf("backfill webhook secrets: %w", err)
 		}
-		created = true
 	}
 
-	// Ensure triggers match our expected semantics (FTS5 supports DELETE directly).
-	if _, err := d.sql.Exec(`
-		DROP TRIGGER IF EXISTS messages_ai;
-		DROP TRIGGER IF EXISTS messages_ad;
-		DROP TRIGGER IF EXISTS messages_au;
-
-		CREATE TRIGGER messages_ai AFTER INSERT ON messages BEGIN
-			INSERT INTO messages_fts(rowid, text, media_caption, filename, chat_name, sender_name, display_text)
-			VALUES (new.rowid, COALESCE(new.text,''), COALESCE(new.media_caption,''), COALESCE(new.filename,''), COALESCE(new.chat_name,''), COALESCE(new.sender_name,''), COALESCE(new.display_text,''));
-		END;
-
-		CREATE TRIGGER messages_ad AFTER DELETE ON messages BEGIN
-			DELETE FROM messages_fts WHERE rowid = old.rowid;
-		END;
-
-		CREATE TRIGGER messages_au AFTER UPDATE ON messages BEGIN
-			DELETE FROM messages_fts WHERE rowid = old.rowid;
-			INSERT INTO messages_fts(rowid, text, media_caption, filename, chat_name, sender_name, display_text)
-			VALUES (new.rowid, COALESCE(new.text,''), COALESCE(new.media_caption,''), COALESCE(new.filename,''), COALESCE(new.chat_name,''), COALESCE(new.sender_name,''), COALESCE(new.display_text,''));
-		END;
-	`); err != nil {
-		d.ftsEnabled = false
-		return nil
+	ok, err = d.tableHasColumn("webhooks", "skip_muted")
+	if err != nil {
+		return err
 	}
-
-	if created {
-		if _, err := d.sql.Exec(`
-			INSERT INTO messages_fts(rowid, text, media_caption, filename, chat_name, sender_name, display_text)
-			SELECT rowid,
-			       COALESCE(text,''),
-			       COALESCE(media_caption,''),
-			       COALESCE(filename,''),
-			       COALESCE(chat_name,''),
-			       COALESCE(sender_name,''),
-			       COALESCE(display_text,'')
-			FROM messages;
-		`); err != nil {
-			d.ftsEnabled = false
-			return nil
+	if !ok {
+		if _, err := d.sql.Exec(`ALTER TABLE webhooks ADD COLUMN skip_muted INTEGER NOT NULL DEFAULT 0`); err != nil {
+			return fmt.Errorf("add skip_muted column: %w", err)
 		}
 	}
+	return nil
+}
 
-	d.ftsEnabled = true
+func (d *DB) ensureRuleColumns() error {
+	for _, col := range []struct{ name, def string }{
+		{"min_role", "TEXT NOT NULL DEFAULT ''"},
+		{"duplicate_media", "INTEGER NOT NULL DEFAULT 0"},
+	} {
+		ok, err := d.tableHasColumn("rules", col.name)
+		if err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+		if _, err := d.sql.Exec(`ALTER TABLE rules ADD COLUMN ` + col.name + ` ` + col.def); err != nil {
+			return fmt.Errorf("add %s column: %w", col.name, err)
+		}
+	}
 	return nil
 }
 
@@ -291,6 +728,31 @@ type Chat struct {
 	Kind          string
 	Name          string
 	LastMessageTS time.Time
+	// ReadUntil is the read watermark; UnreadCount counts the incoming
+	// messages after it.
+	ReadUntil   time.Time
+	UnreadCount int
+	// Archived, Pinned and Muted mirror the chat's state on the phone.
+	// MutedUntil is zero while muted forever.
+	Archived   bool
+	Pinned     bool
+	Muted      bool
+	MutedUntil time.Time
+	// LastMessage previews the latest message. Only ListChatsPage fills it
+	// in.
+	LastMessage *ChatPreview
+}
+
+// ChatPreview is the latest message of a chat, for rendering an inbox.
+type ChatPreview struct {
+	MsgID     string
+	SenderJID string
+	FromMe    bool
+	Timestamp time.Time
+	MediaType string
+	// Text is the message text, or a description such as "Sent image",
+	// on one line and shortened to 100 characters.
+	Text string
 }
 
 type Group struct {
@@ -321,7 +783,9 @@ type MediaDownloadInfo struct {
 	FileEncSHA256 []byte
 	FileLength    uint64
 	LocalPath     string
-	DownloadedAt  time.Time
+	// LocalSHA256 is the hex SHA-256 of the file at LocalPath.
+	LocalSHA256  string
+	DownloadedAt time.Time
 }
 
 type Message struct {
@@ -334,7 +798,38 @@ type Message struct {
 	Text        string
 	DisplayText string
 	MediaType   string
-	Snippet     string
+	// SystemType is set for system notices, e.g. "ephemeral_timer" or
+	// "group_created"; their description is in Text.
+	SystemType string
+	// Edited and Revoked are set when the sender changed or deleted the
+	// message; earlier versions are kept as MessageRevisions.
+	Edited  bool
+	Revoked bool
+	// Quoted is the message this one replies to, if any.
+	Quoted *QuotedMessage
+	// DuplicateOf is set on incoming media whose file was already in the
+	// archive: the earliest message with the same file. Only GetMessage
+	// and ListMessages fill it in.
+	DuplicateOf *MediaOriginal
+	// Snippet and Score are set by searches: the matching part of the
+	// message with the matched terms highlighted, and its relevance (higher
+	// is better; only comparable within one result list).
+	Snippet string
+	Score   float64
+}
+
+// QuotedMessage is the message a reply quotes, as captured when the reply
+// was synced. Snippet is the quoted text at that time.
+type QuotedMessage struct {
+	ID      string
+	Snippet string
+}
+
+func (q QuotedMessage) orNil() *QuotedMessage {
+	if q.ID == "" {
+		return nil
+	}
+	return &q
 }
 
 type MessageInfo struct {
@@ -347,11 +842,15 @@ type MessageInfo struct {
 }
 
 type Contact struct {
-	JID       string
-	Phone     string
-	Name      string
-	Alias     string
-	Tags      []string
+	JID   string
+	Phone string
+	// LID is the contact's LID JID, if it is known.
+	LID   string
+	Name  string
+	Alias string
+	Tags  []string
+	// Fields are free-form metadata set through the API, e.g. CRM ids.
+	Fields    map[string]string
 	UpdatedAt time.Time
 }
 
@@ -410,6 +909,9 @@ type UpsertMessageParams struct {
 	FileSHA256    []byte
 	FileEncSHA256 []byte
 	FileLength    uint64
+	QuotedID      string
+	QuotedSnippet string
+	SystemType    string
 }
 
 func (d *DB) UpsertMessage(p UpsertMessageParams) error {
@@ -417,30 +919,36 @@ func (d *DB) UpsertMessage(p UpsertMessageParams) error {
 		INSERT INTO messages(
 			chat_jid, chat_name, msg_id, sender_jid, sender_name, ts, from_me, text, display_text,
 			media_type, media_caption, filename, mime_type, direct_path,
-			media_key, file_sha256, file_enc_sha256, file_length
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			media_key, file_sha256, file_enc_sha256, file_length, quoted_id, quoted_snippet, system_type
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(chat_jid, msg_id) DO UPDATE SET
 			chat_name=COALESCE(NULLIF(excluded.chat_name,''), messages.chat_name),
 			sender_jid=excluded.sender_jid,
 			sender_name=COALESCE(NULLIF(excluded.sender_name,''), messages.sender_name),
 			ts=excluded.ts,
 			from_me=excluded.from_me,
-			text=excluded.text,
-			display_text=CASE WHEN excluded.display_text IS NOT NULL AND excluded.display_text != '' THEN excluded.display_text ELSE messages.display_text END,
+			text=CASE WHEN messages.edited_at IS NOT NULL OR messages.revoked_at IS NOT NULL THEN messages.text ELSE excluded.text END,
+			display_text=CASE WHEN messages.edited_at IS NOT NULL OR messages.revoked_at IS NOT NULL THEN messages.display_text WHEN excluded.display_text IS NOT NULL AND excluded.display_text != '' THEN excluded.display_text ELSE messages.display_text END,
 			media_type=excluded.media_type,
-			media_caption=excluded.media_caption,
+			media_caption=CASE WHEN messages.edited_at IS NOT NULL OR messages.revoked_at IS NOT NULL THEN messages.media_caption ELSE excluded.media_caption END,
 			filename=COALESCE(NULLIF(excluded.filename,''), messages.filename),
 			mime_type=COALESCE(NULLIF(excluded.mime_type,''), messages.mime_type),
 			direct_path=COALESCE(NULLIF(excluded.direct_path,''), messages.direct_path),
 			media_key=CASE WHEN excluded.media_key IS NOT NULL AND length(excluded.media_key)>0 THEN excluded.media_key ELSE messages.media_key END,
 			file_sha256=CASE WHEN excluded.file_sha256 IS NOT NULL AND length(excluded.file_sha256)>0 THEN excluded.file_sha256 ELSE messages.file_sha256 END,
 			file_enc_sha256=CASE WHEN excluded.file_enc_sha256 IS NOT NULL AND length(excluded.file_enc_sha256)>0 THEN excluded.file_enc_sha256 ELSE messages.file_enc_sha256 END,
-			file_length=CASE WHEN excluded.file_length>0 THEN excluded.file_length ELSE messages.file_length END
+			file_length=CASE WHEN excluded.file_length>0 THEN excluded.file_length ELSE messages.file_length END,
+			quoted_id=COALESCE(excluded.quoted_id, messages.quoted_id),
+			quoted_snippet=COALESCE(excluded.quoted_snippet, messages.quoted_snippet),
+			system_type=COALESCE(excluded.system_type, messages.system_type)
 	`, p.ChatJID, nullIfEmpty(p.ChatName), p.MsgID, nullIfEmpty(p.SenderJID), nullIfEmpty(p.SenderName), unix(p.Timestamp), boolToInt(p.FromMe), nullIfEmpty(p.Text), nullIfEmpty(p.DisplayText),
 		nullIfEmpty(p.MediaType), nullIfEmpty(p.MediaCaption), nullIfEmpty(p.Filename), nullIfEmpty(p.MimeType), nullIfEmpty(p.DirectPath),
-		p.MediaKey, p.FileSHA256, p.FileEncSHA256, int64(p.FileLength),
+		p.MediaKey, p.FileSHA256, p.FileEncSHA256, int64(p.FileLength), nullIfEmpty(p.QuotedID), nullIfEmpty(p.QuotedSnippet), nullIfEmpty(p.SystemType),
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	return d.chainMessage(p.ChatJID, p.MsgID)
 }
 
 func nullIfEmpty(s string) interface{} {
@@ -456,17 +964,66 @@ type ListMessagesParams struct {
 	Limit   int
 	Before  *time.Time
 	After   *time.Time
+	// Cursor continues a listing from the NextCursor of a previous page.
+	Cursor string
+	// System is SystemInclude (the default), SystemExclude or SystemOnly.
+	System string
+}
+
+// System message filters for ListMessagesParams.
+const (
+	SystemInclude = "include"
+	SystemExclude = "exclude"
+	SystemOnly    = "only"
+)
+
+// ErrInvalidCursor is returned for a malformed pagination cursor.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrInvalidSystemFilter is returned for an unknown ListMessagesParams.System.
+var ErrInvalidSystemFilter = errors.New("system must be include, exclude or only")
+
+// encodeMessageCursor returns an opaque token for the position after the
+// message at (ts, rowid). Messages are ordered newest first with rowid
+// breaking ties, so a page boundary never skips or repeats a message.
+func encodeMessageCursor(ts, rowid int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", ts, rowid)))
+}
+
+func decodeMessageCursor(cursor string) (ts, rowid int64, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+	tsStr, rowStr, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return 0, 0, ErrInvalidCursor
+	}
+	if ts, err = strconv.ParseInt(tsStr, 10, 64); err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+	if rowid, err = strconv.ParseInt(rowStr, 10, 64); err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+	return ts, rowid, nil
 }
 
 func (d *DB) ListMessages(p ListMessagesParams) ([]Message, error) {
+	msgs, _, err := d.ListMessagesPage(p)
+	return msgs, err
+}
+
+// ListMessagesPage lists messages newest first and returns the cursor of the
+// next page, or "" when there are no more messages.
+func (d *DB) ListMessagesPage(p ListMessagesParams) ([]Message, string, error) {
 	if p.Limit <= 0 {
 		p.Limit = 50
 	}
 	query := `
-		SELECT m.chat_jid, COALESCE(c.name,''), m.msg_id, COALESCE(m.sender_jid,''), m.ts, m.from_me, COALESCE(m.text,''), COALESCE(m.display_text,''), COALESCE(m.media_type,'')
+		SELECT m.rowid, m.chat_jid, COALESCE(c.name,''), m.msg_id, COALESCE(m.sender_jid,''), m.ts, m.from_me, COALESCE(m.text,''), COALESCE(m.display_text,''), COALESCE(m.media_type,''), m.edited_at IS NOT NULL, m.revoked_at IS NOT NULL, COALESCE(m.quoted_id,''), COALESCE(m.quoted_snippet,''), COALESCE(m.system_type,''), ` + mediaOriginalColumns + `
 		FROM messages m
-		LEFT JOIN chats c ON c.jid = m.chat_jid
-		WHERE 1=1`
+		LEFT JOIN chats c ON c.jid = m.chat_jid` + mediaOriginalJoin + `
+		WHERE 1=1` + notTrashedSQL
 	var args []interface{}
 	if strings.TrimSpace(p.ChatJID) != "" {
 		query += " AND m.chat_jid = ?"
@@ -480,28 +1037,133 @@ func (d *DB) ListMessages(p ListMessagesParams) ([]Message, error) {
 		query += " AND m.ts < ?"
 		args = append(args, unix(*p.Before))
 	}
-	query += " ORDER BY m.ts DESC LIMIT ?"
-	args = append(args, p.Limit)
+	switch p.System {
+	case "", SystemInclude:
+	case SystemExclude:
+		query += " AND m.system_type IS NULL"
+	case SystemOnly:
+		query += " AND m.system_type IS NOT NULL"
+	default:
+		return nil, "", ErrInvalidSystemFilter
+	}
+	if p.Cursor != "" {
+		ts, rowid, err := decodeMessageCursor(p.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		query += " AND (m.ts < ? OR (m.ts = ? AND m.rowid < ?))"
+		args = append(args, ts, ts, rowid)
+	}
+	query += " ORDER BY m.ts DESC, m.rowid DESC LIMIT ?"
+	args = append(args, p.Limit+1)
 
 	rows, err := d.sql.Query(query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
 	var out []Message
+	var next string
+	var lastTS, lastRowID int64
 	for rows.Next() {
+		if len(out) == p.Limit {
+			next = encodeMessageCursor(lastTS, lastRowID)
+			break
+		}
 		var m Message
 		var ts int64
 		var fromMe int
-		if err := rows.Scan(&m.ChatJID, &m.ChatName, &m.MsgID, &m.SenderJID, &ts, &fromMe, &m.Text, &m.DisplayText, &m.MediaType); err != nil {
-			return nil, err
+		var quoted QuotedMessage
+		var orig mediaOriginalRow
+		if err := rows.Scan(&lastRowID, &m.ChatJID, &m.ChatName, &m.MsgID, &m.SenderJID, &ts, &fromMe, &m.Text, &m.DisplayText, &m.MediaType, &m.Edited, &m.Revoked, &quoted.ID, &quoted.Snippet, &m.SystemType, &orig.chatJID, &orig.msgID, &orig.senderJID, &orig.ts); err != nil {
+			return nil, "", err
 		}
+		lastTS = ts
 		m.Timestamp = fromUnix(ts)
 		m.FromMe = fromMe != 0
+		m.Quoted = quoted.orNil()
+		m.DuplicateOf = orig.orNil()
 		out = append(out, m)
 	}
-	return out, rows.Err()
+	return out, next, rows.Err()
+}
+
+// ListMessagesSince returns up to limit messages stored after the position
+// of sinceCursor, oldest first, with the cursor to continue from. Unlike
+// ListMessagesPage the order is the order messages were stored in, so
+// backfilled history shows up as new too. An empty sinceCursor starts at the
+// current end of the archive and returns no messages.
+func (d *DB) ListMessagesSince(chatJID, sinceCursor string, limit int) ([]Message, string, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	var since int64
+	if sinceCursor == "" {
+		if err := d.sql.QueryRow(`SELECT COALESCE(MAX(rowid), 0) FROM messages`).Scan(&since); err != nil {
+			return nil, "", err
+		}
+		return nil, encodeSinceCursor(since), nil
+	}
+	since, err := decodeSinceCursor(sinceCursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := `
+		SELECT m.rowid, m.chat_jid, COALESCE(c.name,''), m.msg_id, COALESCE(m.sender_jid,''), m.ts, m.from_me, COALESCE(m.text,''), COALESCE(m.display_text,''), COALESCE(m.media_type,''), m.edited_at IS NOT NULL, m.revoked_at IS NOT NULL, COALESCE(m.quoted_id,''), COALESCE(m.quoted_snippet,''), COALESCE(m.system_type,'')
+		FROM messages m
+		LEFT JOIN chats c ON c.jid = m.chat_jid
+		WHERE m.rowid > ?` + notTrashedSQL
+	args := []interface{}{since}
+	if strings.TrimSpace(chatJID) != "" {
+		query += " AND m.chat_jid = ?"
+		args = append(args, chatJID)
+	}
+	query += " ORDER BY m.rowid LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := d.sql.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var out []Message
+	for rows.Next() {
+		var m Message
+		var ts int64
+		var fromMe int
+		var quoted QuotedMessage
+		if err := rows.Scan(&since, &m.ChatJID, &m.ChatName, &m.MsgID, &m.SenderJID, &ts, &fromMe, &m.Text, &m.DisplayText, &m.MediaType, &m.Edited, &m.Revoked, &quoted.ID, &quoted.Snippet, &m.SystemType); err != nil {
+			return nil, "", err
+		}
+		m.Timestamp = fromUnix(ts)
+		m.FromMe = fromMe != 0
+		m.Quoted = quoted.orNil()
+		out = append(out, m)
+	}
+	return out, encodeSinceCursor(since), rows.Err()
+}
+
+func encodeSinceCursor(rowid int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("s:" + strconv.FormatInt(rowid, 10)))
+}
+
+func decodeSinceCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	n, ok := strings.CutPrefix(string(raw), "s:")
+	if !ok {
+		return 0, ErrInvalidCursor
+	}
+	rowid, err := strconv.ParseInt(n, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	return rowid, nil
 }
 
 type SearchMessagesParams struct {
@@ -511,7 +1173,27 @@ type SearchMessagesParams struct {
 	Limit   int
 	Before  *time.Time
 	After   *time.Time
-	Type    string
+	// Type is a media type; "text" matches messages without media.
+	Type string
+	// FromMe and HasLink filter when set. HasLink looks for http(s) URLs in
+	// the text and caption.
+	FromMe  *bool
+	HasLink *bool
+	// ChatKind is dm, group or broadcast.
+	ChatKind string
+	// HighlightStart and HighlightEnd surround matched terms in snippets
+	// (default "[" and "]"). They are inserted as is; the message text is
+	// not escaped.
+	HighlightStart string
+	HighlightEnd   string
+}
+
+func (p SearchMessagesParams) highlight() (string, string) {
+	start, end := p.HighlightStart, p.HighlightEnd
+	if start == "" && end == "" {
+		start, end = "[", "]"
+	}
+	return start, end
 }
 
 func (d *DB) SearchMessages(p SearchMessagesParams) ([]Message, error) {
@@ -522,15 +1204,19 @@ func (d *DB) SearchMessages(p SearchMessagesParams) ([]Message, error) {
 		p.Limit = 50
 	}
 
-	if d.ftsEnabled {
-		return d.searchFTS(p)
+	if d.ftsEnabled.Load() {
+		msgs, err := d.searchFTS(p)
+		if err == nil || !isFTSCorrupt(err) {
+			return msgs, err
+		}
+		d.markFTSBroken(err)
 	}
 	return d.searchLIKE(p)
 }
 
 func (d *DB) searchLIKE(p SearchMessagesParams) ([]Message, error) {
 	query := `
-		SELECT m.chat_jid, COALESCE(c.name,''), m.msg_id, COALESCE(m.sender_jid,''), m.ts, m.from_me, COALESCE(m.text,''), COALESCE(m.display_text,''), COALESCE(m.media_type,''), ''
+		SELECT m.chat_jid, COALESCE(c.name,''), m.msg_id, COALESCE(m.sender_jid,''), m.ts, m.from_me, COALESCE(m.text,''), COALESCE(m.display_text,''), COALESCE(m.media_type,''), m.edited_at IS NOT NULL, m.revoked_at IS NOT NULL, COALESCE(m.quoted_id,''), COALESCE(m.quoted_snippet,''), COALESCE(m.system_type,''), '', 0
 		FROM messages m
 		LEFT JOIN chats c ON c.jid = m.chat_jid
 		WHERE (LOWER(m.text) LIKE LOWER(?) OR LOWER(m.display_text) LIKE LOWER(?) OR LOWER(m.media_caption) LIKE LOWER(?) OR LOWER(m.filename) LIKE LOWER(?) OR LOWER(COALESCE(m.chat_name,'')) LIKE LOWER(?) OR LOWER(COALESCE(m.sender_name,'')) LIKE LOWER(?) OR LOWER(COALESCE(c.name,'')) LIKE LOWER(?))`
@@ -539,18 +1225,85 @@ func (d *DB) searchLIKE(p SearchMessagesParams) ([]Message, error) {
 	query, args = applyMessageFilters(query, args, p)
 	query += " ORDER BY m.ts DESC LIMIT ?"
 	args = append(args, p.Limit)
-	return d.scanMessages(query, args...)
+	msgs, err := d.scanMessages(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	start, end := p.highlight()
+	for i := range msgs {
+		text := msgs[i].Text
+		if text == "" {
+			text = msgs[i].DisplayText
+		}
+		msgs[i].Snippet, msgs[i].Score = likeSnippet(text, p.Query, start, end)
+	}
+	return msgs, nil
+}
+
+// likeSnippetContext is how many runes likeSnippet keeps around a match.
+const likeSnippetContext = 40
+
+// likeSnippet approximates the FTS snippet for LIKE searches: the text
+// around the first case-insensitive occurrence of query, highlighted, and
+// the number of occurrences as score. Matches only in other fields (chat
+// or sender name, filename) return the start of the text unhighlighted.
+func likeSnippet(text, query, start, end string) (string, float64) {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	q := []rune(strings.ToLower(strings.TrimSpace(query)))
+	if len(lower) != len(runes) || len(q) == 0 {
+		// Lowercasing changed the length; fall back to the plain text.
+		return truncateRunes(runes, 2*likeSnippetContext), 0
+	}
+
+	first, count := -1, 0
+	for i := 0; i+len(q) <= len(lower); i++ {
+		if string(lower[i:i+len(q)]) == string(q) {
+			if first < 0 {
+				first = i
+			}
+			count++
+			i += len(q) - 1
+		}
+	}
+	if first < 0 {
+		return truncateRunes(runes, 2*likeSnippetContext), 0
+	}
+
+	from := max(0, first-likeSnippetContext)
+	to := min(len(runes), first+len(q)+likeSnippetContext)
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	b.WriteString(string(runes[from:first]))
+	b.WriteString(start)
+	b.WriteString(string(runes[first : first+len(q)]))
+	b.WriteString(end)
+	b.WriteString(string(runes[first+len(q) : to]))
+	if to < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String(), float64(count)
+}
+
+func truncateRunes(r []rune, n int) string {
+	if len(r) <= n {
+		return string(r)
+	}
+	return string(r[:n]) + "…"
 }
 
 func (d *DB) searchFTS(p SearchMessagesParams) ([]Message, error) {
 	query := `
-		SELECT m.chat_jid, COALESCE(c.name,''), m.msg_id, COALESCE(m.sender_jid,''), m.ts, m.from_me, COALESCE(m.text,''), COALESCE(m.display_text,''), COALESCE(m.media_type,''),
-		       snippet(messages_fts, 0, '[', ']', '…', 12)
+		SELECT m.chat_jid, COALESCE(c.name,''), m.msg_id, COALESCE(m.sender_jid,''), m.ts, m.from_me, COALESCE(m.text,''), COALESCE(m.display_text,''), COALESCE(m.media_type,''), m.edited_at IS NOT NULL, m.revoked_at IS NOT NULL, COALESCE(m.quoted_id,''), COALESCE(m.quoted_snippet,''), COALESCE(m.system_type,''),
+		       snippet(messages_fts, -1, ?, ?, '…', 16), -bm25(messages_fts)
 		FROM messages_fts
 		JOIN messages m ON messages_fts.rowid = m.rowid
 		LEFT JOIN chats c ON c.jid = m.chat_jid
 		WHERE messages_fts MATCH ?`
-	args := []interface{}{p.Query}
+	start, end := p.highlight()
+	args := []interface{}{start, end, p.Query}
 	query, args = applyMessageFilters(query, args, p)
 	query += " ORDER BY bm25(messages_fts) LIMIT ?"
 	args = append(args, p.Limit)
@@ -558,6 +1311,7 @@ func (d *DB) searchFTS(p SearchMessagesParams) ([]Message, error) {
 }
 
 func applyMessageFilters(query string, args []interface{}, p SearchMessagesParams) (string, []interface{}) {
+	query += notTrashedSQL
 	if strings.TrimSpace(p.ChatJID) != "" {
 		query += " AND m.chat_jid = ?"
 		args = append(args, p.ChatJID)
@@ -574,9 +1328,27 @@ func applyMessageFilters(query string, args []interface{}, p SearchMessagesParam
 		query += " AND m.ts < ?"
 		args = append(args, unix(*p.Before))
 	}
-	if strings.TrimSpace(p.Type) != "" {
+	if t := strings.TrimSpace(p.Type); t == "text" {
+		query += " AND COALESCE(m.media_type,'') = ''"
+	} else if t != "" {
 		query += " AND COALESCE(m.media_type,'') = ?"
-		args = append(args, p.Type)
+		args = append(args, t)
+	}
+	if p.FromMe != nil {
+		query += " AND m.from_me = ?"
+		args = append(args, boolToInt(*p.FromMe))
+	}
+	if p.HasLink != nil {
+		hasLink := `(COALESCE(m.text,'') || ' ' || COALESCE(m.media_caption,'')) LIKE '%http://%' OR (COALESCE(m.text,'') || ' ' || COALESCE(m.media_caption,'')) LIKE '%https://%'`
+		if *p.HasLink {
+			query += " AND (" + hasLink + ")"
+		} else {
+			query += " AND NOT (" + hasLink + ")"
+		}
+	}
+	if strings.TrimSpace(p.ChatKind) != "" {
+		query += " AND COALESCE(c.kind,'') = ?"
+		args = append(args, p.ChatKind)
 	}
 	return query, args
 }
@@ -593,11 +1365,13 @@ func (d *DB) scanMessages(query string, args ...interface{}) ([]Message, error)
 		var m Message
 		var ts int64
 		var fromMe int
-		if err := rows.Scan(&m.ChatJID, &m.ChatName, &m.MsgID, &m.SenderJID, &ts, &fromMe, &m.Text, &m.DisplayText, &m.MediaType, &m.Snippet); err != nil {
+		var quoted QuotedMessage
+		if err := rows.Scan(&m.ChatJID, &m.ChatName, &m.MsgID, &m.SenderJID, &ts, &fromMe, &m.Text, &m.DisplayText, &m.MediaType, &m.Edited, &m.Revoked, &quoted.ID, &quoted.Snippet, &m.SystemType, &m.Snippet, &m.Score); err != nil {
 			return nil, err
 		}
 		m.Timestamp = fromUnix(ts)
 		m.FromMe = fromMe != 0
+		m.Quoted = quoted.orNil()
 		out = append(out, m)
 	}
 	return out, rows.Err()
@@ -605,22 +1379,58 @@ func (d *DB) scanMessages(query string, args ...interface{}) ([]Message, error)
 
 func (d *DB) GetMessage(chatJID, msgID string) (Message, error) {
 	row := d.sql.QueryRow(`
-		SELECT m.chat_jid, COALESCE(c.name,''), m.msg_id, COALESCE(m.sender_jid,''), m.ts, m.from_me, COALESCE(m.text,''), COALESCE(m.display_text,''), COALESCE(m.media_type,'')
+		SELECT m.chat_jid, COALESCE(c.name,''), m.msg_id, COALESCE(m.sender_jid,''), m.ts, m.from_me, COALESCE(m.text,''), COALESCE(m.display_text,''), COALESCE(m.media_type,''), m.edited_at IS NOT NULL, m.revoked_at IS NOT NULL, COALESCE(m.quoted_id,''), COALESCE(m.quoted_snippet,''), COALESCE(m.system_type,''), `+mediaOriginalColumns+`
 		FROM messages m
-		LEFT JOIN chats c ON c.jid = m.chat_jid
+		LEFT JOIN chats c ON c.jid = m.chat_jid`+mediaOriginalJoin+`
 		WHERE m.chat_jid = ? AND m.msg_id = ?
 	`, chatJID, msgID)
 	var m Message
 	var ts int64
 	var fromMe int
-	if err := row.Scan(&m.ChatJID, &m.ChatName, &m.MsgID, &m.SenderJID, &ts, &fromMe, &m.Text, &m.DisplayText, &m.MediaType); err != nil {
+	var quoted QuotedMessage
+	var orig mediaOriginalRow
+	if err := row.Scan(&m.ChatJID, &m.ChatName, &m.MsgID, &m.SenderJID, &ts, &fromMe, &m.Text, &m.DisplayText, &m.MediaType, &m.Edited, &m.Revoked, &quoted.ID, &quoted.Snippet, &m.SystemType, &orig.chatJID, &orig.msgID, &orig.senderJID, &orig.ts); err != nil {
 		return Message{}, err
 	}
 	m.Timestamp = fromUnix(ts)
 	m.FromMe = fromMe != 0
+	m.Quoted = quoted.orNil()
+	m.DuplicateOf = orig.orNil()
 	return m, nil
 }
 
+// maxThreadDepth bounds how far MessageThread follows replies.
+const maxThreadDepth = 200
+
+// MessageThread returns the reply chain msgID belongs to, oldest first: the
+// messages it quotes up to the first stored one, and every stored reply to
+// any of them.
+func (d *DB) MessageThread(chatJID, msgID string) ([]Message, error) {
+	return d.scanMessages(`
+		WITH RECURSIVE
+		up(msg_id, quoted_id, depth) AS (
+			SELECT msg_id, quoted_id, 0 FROM messages WHERE chat_jid = ?1 AND msg_id = ?2
+			UNION
+			SELECT m.msg_id, m.quoted_id, up.depth + 1
+			FROM messages m JOIN up ON m.chat_jid = ?1 AND m.msg_id = up.quoted_id
+			WHERE up.depth < ?3
+		),
+		root AS (SELECT msg_id FROM up ORDER BY depth DESC LIMIT 1),
+		down(msg_id, depth) AS (
+			SELECT msg_id, 0 FROM root
+			UNION
+			SELECT m.msg_id, down.depth + 1
+			FROM messages m JOIN down ON m.chat_jid = ?1 AND m.quoted_id = down.msg_id
+			WHERE down.depth < ?3
+		)
+		SELECT m.chat_jid, COALESCE(c.name,''), m.msg_id, COALESCE(m.sender_jid,''), m.ts, m.from_me, COALESCE(m.text,''), COALESCE(m.display_text,''), COALESCE(m.media_type,''), m.edited_at IS NOT NULL, m.revoked_at IS NOT NULL, COALESCE(m.quoted_id,''), COALESCE(m.quoted_snippet,''), COALESCE(m.system_type,''), '', 0
+		FROM messages m
+		LEFT JOIN chats c ON c.jid = m.chat_jid
+		WHERE m.chat_jid = ?1 AND m.msg_id IN (SELECT msg_id FROM up UNION SELECT msg_id FROM down)
+		ORDER BY m.ts, m.rowid
+	`, chatJID, msgID, maxThreadDepth)
+}
+
 func (d *DB) CountMessages() (int64, error) {
 	row := d.sql.QueryRow(`SELECT COUNT(1) FROM messages`)
 	var n int64
@@ -667,6 +1477,7 @@ func (d *DB) GetMediaDownloadInfo(chatJID, msgID string) (MediaDownloadInfo, err
 		       m.file_enc_sha256,
 		       COALESCE(m.file_length,0),
 		       COALESCE(m.local_path,''),
+		       COALESCE(m.local_sha256,''),
 		       COALESCE(m.downloaded_at,0)
 		FROM messages m
 		LEFT JOIN chats c ON c.jid = m.chat_jid
@@ -689,6 +1500,7 @@ func (d *DB) GetMediaDownloadInfo(chatJID, msgID string) (MediaDownloadInfo, err
 		&info.FileEncSHA256,
 		&fileLen,
 		&info.LocalPath,
+		&info.LocalSHA256,
 		&downloadedAt,
 	); err != nil {
 		return MediaDownloadInfo{}, err
@@ -700,15 +1512,39 @@ func (d *DB) GetMediaDownloadInfo(chatJID, msgID string) (MediaDownloadInfo, err
 	return info, nil
 }
 
-func (d *DB) MarkMediaDownloaded(chatJID, msgID, localPath string, downloadedAt time.Time) error {
+// MarkMediaDownloaded records where a message's media was saved and the hex
+// SHA-256 of the file, if known.
+func (d *DB) MarkMediaDownloaded(chatJID, msgID, localPath, sha256Hex string, downloadedAt time.Time) error {
 	_, err := d.sql.Exec(`
 		UPDATE messages
-		SET local_path = ?, downloaded_at = ?
+		SET local_path = ?, local_sha256 = ?, downloaded_at = ?
 		WHERE chat_jid = ? AND msg_id = ?
-	`, localPath, unix(downloadedAt), chatJID, msgID)
+	`, localPath, nullIfEmpty(sha256Hex), unix(downloadedAt), chatJID, msgID)
 	return err
 }
 
+// MediaPathsBySHA256 returns the distinct local files recorded for media
+// with the given hex SHA-256. The files may have been removed since.
+func (d *DB) MediaPathsBySHA256(sha256Hex string) ([]string, error) {
+	if sha256Hex == "" {
+		return nil, nil
+	}
+	rows, err := d.sql.Query(`SELECT DISTINCT local_path FROM messages WHERE local_sha256 = ? AND COALESCE(local_path,'') != ''`, sha256Hex)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
 func (d *DB) MessageContext(chatJID, msgID string, before, after int) ([]Message, error) {
 	if before < 0 {
 		before = 0
@@ -722,7 +1558,7 @@ func (d *DB) MessageContext(chatJID, msgID string, before, after int) ([]Message
 	}
 
 	beforeRows, err := d.sql.Query(`
-		SELECT m.chat_jid, COALESCE(c.name,''), m.msg_id, COALESCE(m.sender_jid,''), m.ts, m.from_me, COALESCE(m.text,''), COALESCE(m.display_text,''), COALESCE(m.media_type,''), ''
+		SELECT m.chat_jid, COALESCE(c.name,''), m.msg_id, COALESCE(m.sender_jid,''), m.ts, m.from_me, COALESCE(m.text,''), COALESCE(m.display_text,''), COALESCE(m.media_type,''), m.edited_at IS NOT NULL, m.revoked_at IS NOT NULL, COALESCE(m.quoted_id,''), COALESCE(m.quoted_snippet,''), COALESCE(m.system_type,''), ''
 		FROM messages m
 		LEFT JOIN chats c ON c.jid = m.chat_jid
 		WHERE m.chat_jid = ? AND m.ts < ?
@@ -739,11 +1575,13 @@ func (d *DB) MessageContext(chatJID, msgID string, before, after int) ([]Message
 		var m Message
 		var ts int64
 		var fromMe int
-		if err := beforeRows.Scan(&m.ChatJID, &m.ChatName, &m.MsgID, &m.SenderJID, &ts, &fromMe, &m.Text, &m.DisplayText, &m.MediaType, &m.Snippet); err != nil {
+		var quoted QuotedMessage
+		if err := beforeRows.Scan(&m.ChatJID, &m.ChatName, &m.MsgID, &m.SenderJID, &ts, &fromMe, &m.Text, &m.DisplayText, &m.MediaType, &m.Edited, &m.Revoked, &quoted.ID, &quoted.Snippet, &m.SystemType, &m.Snippet); err != nil {
 			return nil, err
 		}
 		m.Timestamp = fromUnix(ts)
 		m.FromMe = fromMe != 0
+		m.Quoted = quoted.orNil()
 		prev = append(prev, m)
 	}
 	if err := beforeRows.Err(); err != nil {
@@ -751,7 +1589,7 @@ func (d *DB) MessageContext(chatJID, msgID string, before, after int) ([]Message
 	}
 
 	afterRows, err := d.sql.Query(`
-		SELECT m.chat_jid, COALESCE(c.name,''), m.msg_id, COALESCE(m.sender_jid,''), m.ts, m.from_me, COALESCE(m.text,''), COALESCE(m.display_text,''), COALESCE(m.media_type,''), ''
+		SELECT m.chat_jid, COALESCE(c.name,''), m.msg_id, COALESCE(m.sender_jid,''), m.ts, m.from_me, COALESCE(m.text,''), COALESCE(m.display_text,''), COALESCE(m.media_type,''), m.edited_at IS NOT NULL, m.revoked_at IS NOT NULL, COALESCE(m.quoted_id,''), COALESCE(m.quoted_snippet,''), COALESCE(m.system_type,''), ''
 		FROM messages m
 		LEFT JOIN chats c ON c.jid = m.chat_jid
 		WHERE m.chat_jid = ? AND m.ts > ?
@@ -768,11 +1606,13 @@ func (d *DB) MessageContext(chatJID, msgID string, before, after int) ([]Message
 		var m Message
 		var ts int64
 		var fromMe int
-		if err := afterRows.Scan(&m.ChatJID, &m.ChatName, &m.MsgID, &m.SenderJID, &ts, &fromMe, &m.Text, &m.DisplayText, &m.MediaType, &m.Snippet); err != nil {
+		var quoted QuotedMessage
+		if err := afterRows.Scan(&m.ChatJID, &m.ChatName, &m.MsgID, &m.SenderJID, &ts, &fromMe, &m.Text, &m.DisplayText, &m.MediaType, &m.Edited, &m.Revoked, &quoted.ID, &quoted.Snippet, &m.SystemType, &m.Snippet); err != nil {
 			return nil, err
 		}
 		m.Timestamp = fromUnix(ts)
 		m.FromMe = fromMe != 0
+		m.Quoted = quoted.orNil()
 		next = append(next, m)
 	}
 	if err := afterRows.Err(); err != nil {
@@ -795,14 +1635,14 @@ func (d *DB) ListChats(query string, limit int) ([]Chat, error) {
 	if limit <= 0 {
 		limit = 50
 	}
-	q := `SELECT jid, kind, COALESCE(name,''), COALESCE(last_message_ts,0) FROM chats WHERE 1=1`
+	q := `SELECT c.jid, c.kind, COALESCE(c.name,''), COALESCE(c.last_message_ts,0), COALESCE(c.read_ts,0), ` + unreadCountSQL + `, ` + chatStateSQL + ` FROM chats c WHERE c.deleted_at IS NULL`
 	var args []interface{}
 	if strings.TrimSpace(query) != "" {
-		q += ` AND (LOWER(name) LIKE LOWER(?) OR LOWER(jid) LIKE LOWER(?))`
+		q += ` AND (LOWER(c.name) LIKE LOWER(?) OR LOWER(c.jid) LIKE LOWER(?))`
 		needle := "%" + query + "%"
 		args = append(args, needle, needle)
 	}
-	q += ` ORDER BY last_message_ts DESC LIMIT ?`
+	q += ` ORDER BY c.last_message_ts DESC LIMIT ?`
 	args = append(args, limit)
 
 	rows, err := d.sql.Query(q, args...)
@@ -813,24 +1653,30 @@ func (d *DB) ListChats(query string, limit int) ([]Chat, error) {
 	var out []Chat
 	for rows.Next() {
 		var c Chat
-		var ts int64
-		if err := rows.Scan(&c.JID, &c.Kind, &c.Name, &ts); err != nil {
+		var ts, readTS, mutedUntil int64
+		var archived, pinned int
+		if err := rows.Scan(&c.JID, &c.Kind, &c.Name, &ts, &readTS, &c.UnreadCount, &archived, &pinned, &mutedUntil); err != nil {
 			return nil, err
 		}
 		c.LastMessageTS = fromUnix(ts)
+		c.ReadUntil = fromUnix(readTS)
+		applyChatState(&c, archived, pinned, mutedUntil)
 		out = append(out, c)
 	}
 	return out, rows.Err()
 }
 
 func (d *DB) GetChat(jid string) (Chat, error) {
-	row := d.sql.QueryRow(`SELECT jid, kind, COALESCE(name,''), COALESCE(last_message_ts,0) FROM chats WHERE jid = ?`, jid)
+	row := d.sql.QueryRow(`SELECT c.jid, c.kind, COALESCE(c.name,''), COALESCE(c.last_message_ts,0), COALESCE(c.read_ts,0), `+unreadCountSQL+`, `+chatStateSQL+` FROM chats c WHERE c.jid = ?`+notTrashedSQL, jid)
 	var c Chat
-	var ts int64
-	if err := row.Scan(&c.JID, &c.Kind, &c.Name, &ts); err != nil {
+	var ts, readTS, mutedUntil int64
+	var archived, pinned int
+	if err := row.Scan(&c.JID, &c.Kind, &c.Name, &ts, &readTS, &c.UnreadCount, &archived, &pinned, &mutedUntil); err != nil {
 		return Chat{}, err
 	}
 	c.LastMessageTS = fromUnix(ts)
+	c.ReadUntil = fromUnix(readTS)
+	applyChatState(&c, archived, pinned, mutedUntil)
 	return c, nil
 }
 
@@ -846,10 +1692,11 @@ func (d *DB) SearchContacts(query string, limit int) ([]Contact, error) {
 		       COALESCE(c.phone,''),
 		       COALESCE(NULLIF(a.alias,''), ''),
 		       COALESCE(NULLIF(c.full_name,''), NULLIF(c.push_name,''), NULLIF(c.business_name,''), NULLIF(c.first_name,''), ''),
-		       c.updated_at
+		       c.updated_at,
+		       ` + contactLIDSQL + `
 		FROM contacts c
 		LEFT JOIN contact_aliases a ON a.jid = c.jid
-		WHERE LOWER(COALESCE(a.alias,'')) LIKE LOWER(?) OR LOWER(COALESCE(c.full_name,'')) LIKE LOWER(?) OR LOWER(COALESCE(c.push_name,'')) LIKE LOWER(?) OR LOWER(COALESCE(c.phone,'')) LIKE LOWER(?) OR LOWER(c.jid) LIKE LOWER(?)
+		WHERE (LOWER(COALESCE(a.alias,'')) LIKE LOWER(?) OR LOWER(COALESCE(c.full_name,'')) LIKE LOWER(?) OR LOWER(COALESCE(c.push_name,'')) LIKE LOWER(?) OR LOWER(COALESCE(c.phone,'')) LIKE LOWER(?) OR LOWER(c.jid) LIKE LOWER(?))` + notMappedLIDSQL + `
 		ORDER BY COALESCE(NULLIF(a.alias,''), NULLIF(c.full_name,''), NULLIF(c.push_name,''), c.jid)
 		LIMIT ?`
 	needle := "%" + query + "%"
@@ -862,7 +1709,7 @@ func (d *DB) SearchContacts(query string, limit int) ([]Contact, error) {
 	for rows.Next() {
 		var c Contact
 		var updated int64
-		if err := rows.Scan(&c.JID, &c.Phone, &c.Alias, &c.Name, &updated); err != nil {
+		if err := rows.Scan(&c.JID, &c.Phone, &c.Alias, &c.Name, &updated, &c.LID); err != nil {
 			return nil, err
 		}
 		c.UpdatedAt = fromUnix(updated)
@@ -871,25 +1718,33 @@ func (d *DB) SearchContacts(query string, limit int) ([]Contact, error) {
 	return out, rows.Err()
 }
 
+// GetContact returns a contact by JID. A LID with a known phone number
+// returns the contact of the phone number JID.
 func (d *DB) GetContact(jid string) (Contact, error) {
+	if pn, err := d.PhoneForLID(jid); err == nil {
+		jid = pn
+	}
 	row := d.sql.QueryRow(`
 		SELECT c.jid,
 		       COALESCE(c.phone,''),
 		       COALESCE(NULLIF(a.alias,''), ''),
 		       COALESCE(NULLIF(c.full_name,''), NULLIF(c.push_name,''), NULLIF(c.business_name,''), NULLIF(c.first_name,''), ''),
-		       c.updated_at
+		       c.updated_at,
+		       `+contactLIDSQL+`
 		FROM contacts c
 		LEFT JOIN contact_aliases a ON a.jid = c.jid
 		WHERE c.jid = ?
 	`, jid)
 	var c Contact
 	var updated int64
-	if err := row.Scan(&c.JID, &c.Phone, &c.Alias, &c.Name, &updated); err != nil {
+	if err := row.Scan(&c.JID, &c.Phone, &c.Alias, &c.Name, &updated, &c.LID); err != nil {
 		return Contact{}, err
 	}
 	c.UpdatedAt = fromUnix(updated)
 	tags, _ := d.ListTags(jid)
 	c.Tags = tags
+	fields, _ := d.ContactFields(jid)
+	c.Fields = fields
 	return c, nil
 }
 
@@ -1041,7 +1896,31 @@ func (d *DB) RemoveTag(jid, tag string) error {
 	return err
 }
 
-func (d *DB) HasFTS() bool { return d.ftsEnabled }
+// TagCount is a tag and how many contacts and chats carry it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// ListAllTags lists the tags in use, A-Z.
+func (d *DB) ListAllTags() ([]TagCount, error) {
+	rows, err := d.sql.Query(`SELECT tag, COUNT(*) FROM contact_tags GROUP BY tag ORDER BY tag`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []TagCount
+	for rows.Next() {
+		var t TagCount
+		if err := rows.Scan(&t.Tag, &t.Count); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func (d *DB) HasFTS() bool { return d.ftsEnabled.Load() }
 
 func IsNotFound(err error) bool {
 	return errors.Is(err, sql.ErrNoRows)
@@ -2,7 +2,10 @@ package store
 
 import (
 	"database/sql"
+	"errors"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 )
@@ -170,7 +173,7 @@ func TestMediaDownloadInfoAndMarkDownloaded(t *testing.T) {
 	}
 
 	when := time.Date(2024, 3, 1, 0, 0, 1, 0, time.UTC)
-	if err := db.MarkMediaDownloaded(chat, "mid", "/tmp/file", when); err != nil {
+	if err := db.MarkMediaDownloaded(chat, "mid", "/tmp/file", "", when); err != nil {
 		t.Fatalf("MarkMediaDownloaded: %v", err)
 	}
 	info, err = db.GetMediaDownloadInfo(chat, "mid")
@@ -290,6 +293,144 @@ func TestCountMessagesAndOldestMessageInfo(t *testing.T) {
 	}
 }
 
+func TestListMessagesPageCursor(t *testing.T) {
+	db := openTestDB(t)
+
+	chat := "123@s.whatsapp.net"
+	if err := db.UpsertChat(chat, "dm", "Alice", time.Now()); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	// Several messages share a timestamp so pages split inside a second.
+	base := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		if err := db.UpsertMessage(UpsertMessageParams{
+			ChatJID: chat, MsgID: id, Timestamp: base.Add(time.Duration(i/3) * time.Second), Text: id,
+		}); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+
+	var got []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatalf("pagination did not terminate: %v", got)
+		}
+		msgs, next, err := db.ListMessagesPage(ListMessagesParams{ChatJID: chat, Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("ListMessagesPage: %v", err)
+		}
+		for _, m := range msgs {
+			got = append(got, m.MsgID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if want := "e d c b a"; strings.Join(got, " ") != want {
+		t.Fatalf("pages = %v, want %s", got, want)
+	}
+
+	if _, _, err := db.ListMessagesPage(ListMessagesParams{Cursor: "not a cursor"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestListMessagesSystemFilter(t *testing.T) {
+	db := openTestDB(t)
+
+	chat := "123@s.whatsapp.net"
+	if err := db.UpsertChat(chat, "dm", "Alice", time.Now()); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	base := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	for i, p := range []UpsertMessageParams{
+		{ChatJID: chat, MsgID: "hi", Text: "hi"},
+		{ChatJID: chat, MsgID: "eph", Text: "Disappearing messages set to 7 days", SystemType: "ephemeral_timer"},
+	} {
+		p.Timestamp = base.Add(time.Duration(i) * time.Second)
+		if err := db.UpsertMessage(p); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+
+	ids := func(system string) string {
+		msgs, err := db.ListMessages(ListMessagesParams{ChatJID: chat, System: system})
+		if err != nil {
+			t.Fatalf("ListMessages(%q): %v", system, err)
+		}
+		var out []string
+		for _, m := range msgs {
+			out = append(out, m.MsgID+"/"+m.SystemType)
+		}
+		return strings.Join(out, " ")
+	}
+	if got := ids(""); got != "eph/ephemeral_timer hi/" {
+		t.Fatalf("include = %q", got)
+	}
+	if got := ids(SystemExclude); got != "hi/" {
+		t.Fatalf("exclude = %q", got)
+	}
+	if got := ids(SystemOnly); got != "eph/ephemeral_timer" {
+		t.Fatalf("only = %q", got)
+	}
+	if _, err := db.ListMessages(ListMessagesParams{System: "some"}); !errors.Is(err, ErrInvalidSystemFilter) {
+		t.Fatalf("expected ErrInvalidSystemFilter, got %v", err)
+	}
+}
+
+func TestMessageThreadWalksReplyChain(t *testing.T) {
+	db := openTestDB(t)
+
+	chat := "123@g.us"
+	if err := db.UpsertChat(chat, "group", "Ops", time.Now()); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	base := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	// root <- r1 <- r2, root <- r3; "other" is unrelated and "r4" quotes a
+	// message that was never stored.
+	for i, m := range []struct{ id, quoted string }{
+		{"root", ""}, {"r1", "root"}, {"other", ""}, {"r2", "r1"}, {"r3", "root"}, {"r4", "gone"},
+	} {
+		if err := db.UpsertMessage(UpsertMessageParams{
+			ChatJID: chat, MsgID: m.id, Timestamp: base.Add(time.Duration(i) * time.Second), Text: m.id,
+			QuotedID: m.quoted, QuotedSnippet: m.quoted,
+		}); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+
+	got := func(id string) string {
+		msgs, err := db.MessageThread(chat, id)
+		if err != nil {
+			t.Fatalf("MessageThread: %v", err)
+		}
+		var ids []string
+		for _, m := range msgs {
+			ids = append(ids, m.MsgID)
+		}
+		return strings.Join(ids, " ")
+	}
+	if g := got("r2"); g != "root r1 r2 r3" {
+		t.Fatalf("thread of r2 = %q", g)
+	}
+	if g := got("root"); g != "root r1 r2 r3" {
+		t.Fatalf("thread of root = %q", g)
+	}
+	if g := got("r4"); g != "r4" {
+		t.Fatalf("thread of r4 = %q", g)
+	}
+
+	m, err := db.GetMessage(chat, "r2")
+	if err != nil || m.Quoted == nil || m.Quoted.ID != "r1" || m.Quoted.Snippet != "r1" {
+		t.Fatalf("GetMessage quoted = %+v (%v)", m.Quoted, err)
+	}
+	if m, _ := db.GetMessage(chat, "root"); m.Quoted != nil {
+		t.Fatalf("root should not quote anything: %+v", m.Quoted)
+	}
+}
+
 func TestGroupsUpsertListAndParticipantsReplace(t *testing.T) {
 	db := openTestDB(t)
 
@@ -319,3 +460,79 @@ func TestGroupsUpsertListAndParticipantsReplace(t *testing.T) {
 		t.Fatalf("expected roles admin=1 member=1, got admin=%d member=%d", admins, members)
 	}
 }
+
+func TestLikeSnippet(t *testing.T) {
+	long := strings.Repeat("x", 60) + " Deploy finished " + strings.Repeat("y", 60)
+	for _, tc := range []struct {
+		text, query, want string
+		score             float64
+	}{
+		{"hello world", "WORLD", "hello [world]", 1},
+		{"ping ping ping", "ping", "[ping] ping ping", 3},
+		{"no match here", "deploy", "no match here", 0},
+		{long, "deploy", "…" + strings.Repeat("x", 39) + " [Deploy] finished " + strings.Repeat("y", 30) + "…", 1},
+	} {
+		got, score := likeSnippet(tc.text, tc.query, "[", "]")
+		if got != tc.want || score != tc.score {
+			t.Fatalf("likeSnippet(%q, %q) = %q, %v; want %q, %v", tc.text, tc.query, got, score, tc.want, tc.score)
+		}
+	}
+}
+
+func TestSearchMessagesFilters(t *testing.T) {
+	db := openTestDB(t)
+	dm := "123@s.whatsapp.net"
+	group := "456@g.us"
+	if err := db.UpsertChat(dm, "dm", "Alice", time.Now()); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	if err := db.UpsertChat(group, "group", "Team", time.Now()); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, p := range []UpsertMessageParams{
+		{ChatJID: dm, MsgID: "dm-in", SenderJID: dm, Text: "report draft"},
+		{ChatJID: dm, MsgID: "dm-out", SenderJID: "me@s.whatsapp.net", FromMe: true, Text: "report at https://example.com/r"},
+		{ChatJID: group, MsgID: "g-img", SenderJID: "789@s.whatsapp.net", MediaType: "image", MediaCaption: "report http://example.com/img"},
+		{ChatJID: group, MsgID: "g-text", SenderJID: dm, Text: "report final"},
+	} {
+		p.Timestamp = base.Add(time.Duration(i) * time.Hour)
+		if err := db.UpsertMessage(p); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+
+	yes, no := true, false
+	after := base.Add(90 * time.Minute)
+	cases := []struct {
+		name string
+		p    SearchMessagesParams
+		want []string
+	}{
+		{"sender", SearchMessagesParams{From: dm}, []string{"dm-in", "g-text"}},
+		{"from_me", SearchMessagesParams{FromMe: &yes}, []string{"dm-out"}},
+		{"received", SearchMessagesParams{FromMe: &no}, []string{"dm-in", "g-img", "g-text"}},
+		{"media", SearchMessagesParams{Type: "image"}, []string{"g-img"}},
+		{"text only", SearchMessagesParams{Type: "text", ChatKind: "group"}, []string{"g-text"}},
+		{"has link", SearchMessagesParams{HasLink: &yes}, []string{"dm-out", "g-img"}},
+		{"no link", SearchMessagesParams{HasLink: &no}, []string{"dm-in", "g-text"}},
+		{"after", SearchMessagesParams{After: &after}, []string{"g-img", "g-text"}},
+		{"chat kind", SearchMessagesParams{ChatKind: "dm", HasLink: &no}, []string{"dm-in"}},
+	}
+	for _, tc := range cases {
+		tc.p.Query = "report"
+		tc.p.Limit = 10
+		ms, err := db.SearchMessages(tc.p)
+		if err != nil {
+			t.Fatalf("%s: SearchMessages: %v", tc.name, err)
+		}
+		var got []string
+		for _, m := range ms {
+			got = append(got, m.MsgID)
+		}
+		sort.Strings(got)
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Fatalf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
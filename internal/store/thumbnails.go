@@ -0,0 +1,66 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// Thumbnail sources. A generated thumbnail is made from the downloaded media
+// and replaces the lower-quality one embedded in the message.
+const (
+	ThumbnailEmbedded  = "embedded"
+	ThumbnailGenerated = "generated"
+)
+
+// Thumbnail is a JPEG preview of a message's image or video.
+type Thumbnail struct {
+	ChatJID   string
+	MsgID     string
+	Data      []byte
+	Width     int
+	Height    int
+	Source    string
+	CreatedAt time.Time
+}
+
+// PutThumbnail stores a message's thumbnail. An embedded thumbnail never
+// replaces a generated one.
+func (d *DB) PutThumbnail(t Thumbnail) error {
+	if len(t.Data) == 0 {
+		return fmt.Errorf("thumbnail data is required")
+	}
+	if t.Source != ThumbnailEmbedded && t.Source != ThumbnailGenerated {
+		return fmt.Errorf("unknown thumbnail source %q", t.Source)
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now().UTC()
+	}
+	_, err := d.sql.Exec(`
+		INSERT INTO media_thumbnails(chat_jid, msg_id, data, width, height, source, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(chat_jid, msg_id) DO UPDATE SET
+			data=excluded.data,
+			width=excluded.width,
+			height=excluded.height,
+			source=excluded.source,
+			created_at=excluded.created_at
+		WHERE excluded.source = 'generated' OR media_thumbnails.source = 'embedded'
+	`, t.ChatJID, t.MsgID, t.Data, t.Width, t.Height, t.Source, unix(t.CreatedAt))
+	return err
+}
+
+// GetThumbnail returns a message's thumbnail, or sql.ErrNoRows if it has
+// none.
+func (d *DB) GetThumbnail(chatJID, msgID string) (Thumbnail, error) {
+	t := Thumbnail{ChatJID: chatJID, MsgID: msgID}
+	var created int64
+	err := d.sql.QueryRow(`
+		SELECT data, width, height, source, created_at FROM media_thumbnails
+		WHERE chat_jid = ? AND msg_id = ?
+	`, chatJID, msgID).Scan(&t.Data, &t.Width, &t.Height, &t.Source, &created)
+	if err != nil {
+		return Thumbnail{}, err
+	}
+	t.CreatedAt = fromUnix(created)
+	return t, nil
+}
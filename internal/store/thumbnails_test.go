@@ -0,0 +1,37 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPutThumbnailPrefersGenerated(t *testing.T) {
+	db := openTestDB(t)
+	chat := "123@s.whatsapp.net"
+
+	if _, err := db.GetThumbnail(chat, "m1"); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if err := db.PutThumbnail(Thumbnail{ChatJID: chat, MsgID: "m1", Data: []byte("small"), Width: 32, Height: 24, Source: ThumbnailEmbedded}); err != nil {
+		t.Fatalf("PutThumbnail: %v", err)
+	}
+	if err := db.PutThumbnail(Thumbnail{ChatJID: chat, MsgID: "m1", Data: []byte("big"), Width: 320, Height: 240, Source: ThumbnailGenerated, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("PutThumbnail: %v", err)
+	}
+	// A later embedded thumbnail (e.g. from a history resync) must not
+	// replace the generated one.
+	if err := db.PutThumbnail(Thumbnail{ChatJID: chat, MsgID: "m1", Data: []byte("small"), Source: ThumbnailEmbedded}); err != nil {
+		t.Fatalf("PutThumbnail: %v", err)
+	}
+	th, err := db.GetThumbnail(chat, "m1")
+	if err != nil || th.Source != ThumbnailGenerated || string(th.Data) != "big" || th.Width != 320 || th.Height != 240 {
+		t.Fatalf("unexpected thumbnail: %+v (%v)", th, err)
+	}
+
+	if err := db.PutThumbnail(Thumbnail{ChatJID: chat, MsgID: "m2", Source: ThumbnailEmbedded}); err == nil {
+		t.Fatalf("expected error for empty data")
+	}
+	if err := db.PutThumbnail(Thumbnail{ChatJID: chat, MsgID: "m2", Data: []byte("x"), Source: "other"}); err == nil {
+		t.Fatalf("expected error for unknown source")
+	}
+}
@@ -0,0 +1,141 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// notTrashedSQL hides chats in the trash from queries joining chats as c.
+const notTrashedSQL = " AND c.deleted_at IS NULL"
+
+// TrashedChat is a chat whose local history was deleted and can still be
+// restored until it is purged.
+type TrashedChat struct {
+	JID           string
+	Kind          string
+	Name          string
+	LastMessageTS time.Time
+	DeletedAt     time.Time
+	MessageCount  int
+}
+
+// TrashChat moves a chat to the trash: it and its messages disappear from
+// listings and search but stay stored until purged. It returns
+// sql.ErrNoRows if the chat is unknown or already in the trash, and
+// ErrLegalHold if it is on legal hold.
+func (d *DB) TrashChat(jid string, at time.Time) error {
+	if err := checkLegalHold(d.sql, jid); err != nil {
+		return err
+	}
+	res, err := d.sql.Exec(`UPDATE chats SET deleted_at = ? WHERE jid = ? AND deleted_at IS NULL`, unix(at), jid)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RestoreChat takes a chat out of the trash. It returns sql.ErrNoRows if the
+// chat is not in the trash.
+func (d *DB) RestoreChat(jid string) error {
+	res, err := d.sql.Exec(`UPDATE chats SET deleted_at = NULL WHERE jid = ? AND deleted_at IS NOT NULL`, jid)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListTrashedChats returns the chats in the trash, most recently deleted first.
+func (d *DB) ListTrashedChats() ([]TrashedChat, error) {
+	rows, err := d.sql.Query(`
+		SELECT c.jid, c.kind, COALESCE(c.name,''), COALESCE(c.last_message_ts,0), c.deleted_at,
+		       (SELECT COUNT(*) FROM messages m WHERE m.chat_jid = c.jid)
+		FROM chats c
+		WHERE c.deleted_at IS NOT NULL
+		ORDER BY c.deleted_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []TrashedChat
+	for rows.Next() {
+		var c TrashedChat
+		var ts, deleted int64
+		if err := rows.Scan(&c.JID, &c.Kind, &c.Name, &ts, &deleted, &c.MessageCount); err != nil {
+			return nil, err
+		}
+		c.LastMessageTS = fromUnix(ts)
+		c.DeletedAt = fromUnix(deleted)
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// PurgeChat permanently deletes a chat in the trash with its messages and
+// everything derived from them. It returns sql.ErrNoRows if the chat is not
+// in the trash and ErrLegalHold if it is on legal hold.
+func (d *DB) PurgeChat(jid string) error {
+	tx, err := d.sql.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var n int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM chats WHERE jid = ? AND deleted_at IS NOT NULL`, jid).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	if err := checkLegalHold(tx, jid); err != nil {
+		return err
+	}
+	for _, table := range append(append([]string{}, messageDerivedTables...), chatScopedTables...) {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE chat_jid = ?`, jid); err != nil {
+			return fmt.Errorf("purge %s: %w", table, err)
+		}
+	}
+	// Duplicates elsewhere no longer point at the purged originals.
+	if _, err := tx.Exec(`DELETE FROM media_duplicates WHERE original_chat_jid = ?`, jid); err != nil {
+		return fmt.Errorf("purge media_duplicates: %w", err)
+	}
+	// Messages go with the chat (ON DELETE CASCADE).
+	if _, err := tx.Exec(`DELETE FROM chats WHERE jid = ?`, jid); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return d.chainPurgedChat(jid)
+}
+
+// ListExpiredTrash returns the chats moved to the trash before cutoff,
+// except those put on legal hold since.
+func (d *DB) ListExpiredTrash(cutoff time.Time) ([]string, error) {
+	rows, err := d.sql.Query(`
+		SELECT jid FROM chats
+		WHERE deleted_at IS NOT NULL AND deleted_at < ?
+		  AND jid NOT IN (SELECT chat_jid FROM chat_retention WHERE legal_hold = 1)
+	`, unix(cutoff))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var jid string
+		if err := rows.Scan(&jid); err != nil {
+			return nil, err
+		}
+		out = append(out, jid)
+	}
+	return out, rows.Err()
+}
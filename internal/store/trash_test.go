@@ -0,0 +1,97 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrashChat(t *testing.T) {
+	db := openTestDB(t)
+	noisy, kept := "111@g.us", "222@s.whatsapp.net"
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, jid := range []string{noisy, kept} {
+		if err := db.UpsertChat(jid, "group", jid, now); err != nil {
+			t.Fatalf("UpsertChat: %v", err)
+		}
+		if err := db.UpsertMessage(UpsertMessageParams{ChatJID: jid, MsgID: "m-" + jid, SenderJID: "333@s.whatsapp.net", Timestamp: now, Text: "deploy done"}); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+
+	if err := db.TrashChat(noisy, now); err != nil {
+		t.Fatalf("TrashChat: %v", err)
+	}
+	if err := db.TrashChat(noisy, now); !IsNotFound(err) {
+		t.Fatalf("second TrashChat: %v", err)
+	}
+
+	chats, err := db.ListChats("", 10)
+	if err != nil || len(chats) != 1 || chats[0].JID != kept {
+		t.Fatalf("ListChats = %+v, %v", chats, err)
+	}
+	if _, err := db.GetChat(noisy); !IsNotFound(err) {
+		t.Fatalf("GetChat of trashed chat: %v", err)
+	}
+	msgs, err := db.ListMessages(ListMessagesParams{Limit: 10})
+	if err != nil || len(msgs) != 1 || msgs[0].ChatJID != kept {
+		t.Fatalf("ListMessages = %+v, %v", msgs, err)
+	}
+	found, err := db.SearchMessages(SearchMessagesParams{Query: "deploy", Limit: 10})
+	if err != nil || len(found) != 1 || found[0].ChatJID != kept {
+		t.Fatalf("SearchMessages = %+v, %v", found, err)
+	}
+
+	trash, err := db.ListTrashedChats()
+	if err != nil || len(trash) != 1 || trash[0].JID != noisy || trash[0].MessageCount != 1 || !trash[0].DeletedAt.Equal(now) {
+		t.Fatalf("ListTrashedChats = %+v, %v", trash, err)
+	}
+
+	if err := db.RestoreChat(noisy); err != nil {
+		t.Fatalf("RestoreChat: %v", err)
+	}
+	if chats, _ := db.ListChats("", 10); len(chats) != 2 {
+		t.Fatalf("restored chat missing: %+v", chats)
+	}
+
+	if err := db.PurgeChat(noisy); !IsNotFound(err) {
+		t.Fatalf("PurgeChat outside trash: %v", err)
+	}
+	if err := db.TrashChat(noisy, now); err != nil {
+		t.Fatalf("TrashChat: %v", err)
+	}
+	expired, err := db.ListExpiredTrash(now.Add(time.Hour))
+	if err != nil || len(expired) != 1 || expired[0] != noisy {
+		t.Fatalf("ListExpiredTrash = %v, %v", expired, err)
+	}
+	if err := db.PutThumbnail(Thumbnail{ChatJID: noisy, MsgID: "m-" + noisy, Data: []byte{1}, Source: ThumbnailEmbedded}); err != nil {
+		t.Fatalf("PutThumbnail: %v", err)
+	}
+	if err := db.MarkMediaDuplicate(noisy, "m-"+noisy, MediaOriginal{ChatJID: kept, MsgID: "m-" + kept}); err != nil {
+		t.Fatalf("MarkMediaDuplicate: %v", err)
+	}
+	if err := db.MarkMediaDuplicate(kept, "m-"+kept, MediaOriginal{ChatJID: noisy, MsgID: "m-" + noisy}); err != nil {
+		t.Fatalf("MarkMediaDuplicate: %v", err)
+	}
+	if _, err := db.SnoozeChat(ChatSnooze{ChatJID: noisy, Until: now.Add(time.Hour), RemindTo: kept}); err != nil {
+		t.Fatalf("SnoozeChat: %v", err)
+	}
+	if err := db.PurgeChat(noisy); err != nil {
+		t.Fatalf("PurgeChat: %v", err)
+	}
+	for _, table := range append(append([]string{}, messageDerivedTables...), chatScopedTables...) {
+		var n int
+		if err := db.sql.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE chat_jid = ?`, noisy).Scan(&n); err != nil || n != 0 {
+			t.Fatalf("%s after purge: %d rows, %v", table, n, err)
+		}
+	}
+	var dups int
+	if err := db.sql.QueryRow(`SELECT COUNT(*) FROM media_duplicates WHERE original_chat_jid = ?`, noisy).Scan(&dups); err != nil || dups != 0 {
+		t.Fatalf("duplicates of purged messages: %d, %v", dups, err)
+	}
+	if _, err := db.GetMessage(noisy, "m-"+noisy); !IsNotFound(err) {
+		t.Fatalf("purged message still stored: %v", err)
+	}
+	if trash, _ := db.ListTrashedChats(); len(trash) != 0 {
+		t.Fatalf("trash after purge = %+v", trash)
+	}
+}
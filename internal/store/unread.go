@@ -0,0 +1,57 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// unreadCountSQL counts incoming messages of chat c after its read watermark.
+const unreadCountSQL = `(SELECT COUNT(*) FROM messages m WHERE m.chat_jid = c.jid AND m.from_me = 0 AND m.revoked_at IS NULL AND m.ts > COALESCE(c.read_ts, 0))`
+
+// UnreadMessage is an incoming message after a chat's read watermark.
+type UnreadMessage struct {
+	MsgID     string
+	SenderJID string
+	Timestamp time.Time
+}
+
+// ListUnreadMessages returns the incoming messages of chatJID after its read
+// watermark up to and including upTo, oldest first.
+func (d *DB) ListUnreadMessages(chatJID string, upTo time.Time) ([]UnreadMessage, error) {
+	rows, err := d.sql.Query(`
+		SELECT m.msg_id, COALESCE(m.sender_jid,''), m.ts
+		FROM messages m
+		JOIN chats c ON c.jid = m.chat_jid
+		WHERE m.chat_jid = ? AND m.from_me = 0 AND m.revoked_at IS NULL
+		  AND m.ts > COALESCE(c.read_ts, 0) AND m.ts <= ?
+		ORDER BY m.ts, m.rowid
+	`, chatJID, unix(upTo))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []UnreadMessage
+	for rows.Next() {
+		var m UnreadMessage
+		var ts int64
+		if err := rows.Scan(&m.MsgID, &m.SenderJID, &ts); err != nil {
+			return nil, err
+		}
+		m.Timestamp = fromUnix(ts)
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// MarkChatRead moves the read watermark of chatJID forward to upTo; it never
+// moves back. It returns sql.ErrNoRows if the chat is unknown.
+func (d *DB) MarkChatRead(chatJID string, upTo time.Time) error {
+	res, err := d.sql.Exec(`UPDATE chats SET read_ts = MAX(COALESCE(read_ts, 0), ?) WHERE jid = ?`, unix(upTo), chatJID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChatUnreadWatermark(t *testing.T) {
+	db := openTestDB(t)
+	chat := "123@s.whatsapp.net"
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := db.UpsertChat(chat, "dm", "Alice", base); err != nil {
+		t.Fatalf("UpsertChat: %v", err)
+	}
+	for i, p := range []UpsertMessageParams{
+		{MsgID: "in1", SenderJID: chat, Text: "one"},
+		{MsgID: "out", FromMe: true, Text: "reply"},
+		{MsgID: "in2", SenderJID: chat, Text: "two"},
+		{MsgID: "in3", SenderJID: chat, Text: "three"},
+	} {
+		p.ChatJID = chat
+		p.Timestamp = base.Add(time.Duration(i) * time.Minute)
+		if err := db.UpsertMessage(p); err != nil {
+			t.Fatalf("UpsertMessage: %v", err)
+		}
+	}
+
+	c, err := db.GetChat(chat)
+	if err != nil {
+		t.Fatalf("GetChat: %v", err)
+	}
+	if c.UnreadCount != 3 || !c.ReadUntil.IsZero() {
+		t.Fatalf("new chat: unread=%d read_until=%v", c.UnreadCount, c.ReadUntil)
+	}
+
+	unread, err := db.ListUnreadMessages(chat, base.Add(2*time.Minute))
+	if err != nil {
+		t.Fatalf("ListUnreadMessages: %v", err)
+	}
+	if len(unread) != 2 || unread[0].MsgID != "in1" || unread[1].MsgID != "in2" {
+		t.Fatalf("unread = %+v", unread)
+	}
+
+	if err := db.MarkChatRead(chat, base.Add(2*time.Minute)); err != nil {
+		t.Fatalf("MarkChatRead: %v", err)
+	}
+	// The watermark never moves back.
+	if err := db.MarkChatRead(chat, base); err != nil {
+		t.Fatalf("MarkChatRead: %v", err)
+	}
+	chats, err := db.ListChats("", 10)
+	if err != nil {
+		t.Fatalf("ListChats: %v", err)
+	}
+	if len(chats) != 1 || chats[0].UnreadCount != 1 || !chats[0].ReadUntil.Equal(base.Add(2*time.Minute)) {
+		t.Fatalf("chats = %+v", chats)
+	}
+
+	if err := db.MarkChatRead("unknown@s.whatsapp.net", base); !IsNotFound(err) {
+		t.Fatalf("expected not found for unknown chat, got %v", err)
+	}
+}
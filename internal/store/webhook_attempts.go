@@ -0,0 +1,158 @@
+package store
+
+import (
+	"strings"
+	"time"
+)
+
+// WebhookAttempt is one POST of a queued delivery to a subscription, kept
+// for the delivery log whether it succeeded or not.
+type WebhookAttempt struct {
+	ID         int64
+	WebhookID  int64
+	DeliveryID int64
+	EventType  string
+	// ChatJID and MsgID identify the message the event is about, if any.
+	ChatJID string
+	MsgID   string
+	Payload []byte
+	// Attempt counts from 1 for each delivery.
+	Attempt    int
+	Success    bool
+	StatusCode int // 0 if no response was received
+	Error      string
+	Latency    time.Duration
+	// ResponseSnippet is the start of the response body.
+	ResponseSnippet string
+	AttemptedAt     time.Time
+}
+
+type ListWebhookAttemptsParams struct {
+	WebhookID int64
+	ChatJID   string
+	MsgID     string
+	// Success filters by outcome when set.
+	Success *bool
+	Limit   int
+}
+
+func (d *DB) RecordWebhookAttempt(a WebhookAttempt) (int64, error) {
+	res, err := d.sql.Exec(`
+		INSERT INTO webhook_attempts(webhook_id, delivery_id, event_type, chat_jid, msg_id, payload, attempt, success, status_code, error, latency_ms, response_snippet, attempted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, a.WebhookID, a.DeliveryID, a.EventType, nullIfEmpty(a.ChatJID), nullIfEmpty(a.MsgID), string(a.Payload), a.Attempt, boolToInt(a.Success),
+		a.StatusCode, nullIfEmpty(a.Error), a.Latency.Milliseconds(), nullIfEmpty(a.ResponseSnippet), unix(a.AttemptedAt))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+const webhookAttemptColumns = `id, webhook_id, delivery_id, event_type, COALESCE(chat_jid,''), COALESCE(msg_id,''), payload, attempt, success, COALESCE(status_code,0), COALESCE(error,''), latency_ms, COALESCE(response_snippet,''), attempted_at`
+
+func scanWebhookAttempt(row rowScanner) (WebhookAttempt, error) {
+	var a WebhookAttempt
+	var payload string
+	var success int
+	var latency, at int64
+	if err := row.Scan(&a.ID, &a.WebhookID, &a.DeliveryID, &a.EventType, &a.ChatJID, &a.MsgID, &payload, &a.Attempt, &success, &a.StatusCode, &a.Error, &latency, &a.ResponseSnippet, &at); err != nil {
+		return WebhookAttempt{}, err
+	}
+	a.Payload = []byte(payload)
+	a.Success = success != 0
+	a.Latency = time.Duration(latency) * time.Millisecond
+	a.AttemptedAt = fromUnix(at)
+	return a, nil
+}
+
+// ListWebhookAttempts returns a subscription's delivery attempts, newest first.
+func (d *DB) ListWebhookAttempts(p ListWebhookAttemptsParams) ([]WebhookAttempt, error) {
+	if p.Limit <= 0 {
+		p.Limit = 50
+	}
+	q := `SELECT ` + webhookAttemptColumns + ` FROM webhook_attempts WHERE webhook_id = ?`
+	args := []interface{}{p.WebhookID}
+	if strings.TrimSpace(p.ChatJID) != "" {
+		q += ` AND chat_jid = ?`
+		args = append(args, p.ChatJID)
+	}
+	if strings.TrimSpace(p.MsgID) != "" {
+		q += ` AND msg_id = ?`
+		args = append(args, p.MsgID)
+	}
+	if p.Success != nil {
+		q += ` AND success = ?`
+		args = append(args, boolToInt(*p.Success))
+	}
+	q += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, p.Limit)
+
+	rows, err := d.sql.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []WebhookAttempt
+	for rows.Next() {
+		a, err := scanWebhookAttempt(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// RetryWebhookAttempt queues the payload of a logged attempt for delivery
+// again and returns the new queue id. It returns sql.ErrNoRows when the
+// attempt does not belong to the subscription.
+func (d *DB) RetryWebhookAttempt(webhookID, attemptID int64) (int64, error) {
+	a, err := scanWebhookAttempt(d.sql.QueryRow(`SELECT `+webhookAttemptColumns+` FROM webhook_attempts WHERE id = ? AND webhook_id = ?`, attemptID, webhookID))
+	if err != nil {
+		return 0, err
+	}
+	return d.EnqueueWebhookDelivery(webhookID, a.EventType, a.Payload)
+}
+
+// WebhookHealth summarizes recent attempts of a subscription.
+type WebhookHealth struct {
+	// ConsecutiveFailures counts failed attempts since the last success.
+	ConsecutiveFailures int
+	LastSuccessAt       time.Time
+	LastFailureAt       time.Time
+	LastError           string
+}
+
+func (d *DB) WebhookHealth(webhookID int64) (WebhookHealth, error) {
+	var h WebhookHealth
+	var lastOK, lastFail int64
+	if err := d.sql.QueryRow(`
+		SELECT COALESCE(MAX(CASE WHEN success = 1 THEN attempted_at END), 0),
+		       COALESCE(MAX(CASE WHEN success = 0 THEN attempted_at END), 0)
+		FROM webhook_attempts WHERE webhook_id = ?
+	`, webhookID).Scan(&lastOK, &lastFail); err != nil {
+		return WebhookHealth{}, err
+	}
+	h.LastSuccessAt = fromUnix(lastOK)
+	h.LastFailureAt = fromUnix(lastFail)
+	if err := d.sql.QueryRow(`
+		SELECT COUNT(*) FROM webhook_attempts
+		WHERE webhook_id = ? AND success = 0
+		  AND id > COALESCE((SELECT MAX(id) FROM webhook_attempts WHERE webhook_id = ? AND success = 1), 0)
+	`, webhookID, webhookID).Scan(&h.ConsecutiveFailures); err != nil {
+		return WebhookHealth{}, err
+	}
+	if h.ConsecutiveFailures > 0 {
+		_ = d.sql.QueryRow(`SELECT COALESCE(error,'') FROM webhook_attempts WHERE webhook_id = ? AND success = 0 ORDER BY id DESC LIMIT 1`, webhookID).Scan(&h.LastError)
+	}
+	return h, nil
+}
+
+// PruneWebhookAttempts deletes attempts logged before cutoff.
+func (d *DB) PruneWebhookAttempts(cutoff time.Time) (int64, error) {
+	res, err := d.sql.Exec(`DELETE FROM webhook_attempts WHERE attempted_at < ?`, unix(cutoff))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
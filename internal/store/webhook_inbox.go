@@ -0,0 +1,171 @@
+package store
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	InboxPending = "pending"
+	InboxDone    = "done"
+	InboxFailed  = "failed"
+)
+
+// InboxItem is an inbound webhook request accepted before it was processed.
+type InboxItem struct {
+	ID            int64
+	Method        string
+	Path          string
+	Header        map[string][]string
+	Body          []byte
+	KeyHash       string
+	Status        string
+	Attempts      int
+	LastStatus    int
+	LastError     string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DoneAt        time.Time
+}
+
+const inboxColumns = `id, method, path, header, body, key_hash, status, attempts, last_status, COALESCE(last_error,''), next_attempt_at, created_at, updated_at, COALESCE(done_at,0)`
+
+// EnqueueInbox persists a webhook request as due now.
+func (d *DB) EnqueueInbox(method, path string, header map[string][]string, body []byte, keyHash string) (InboxItem, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return InboxItem{}, err
+	}
+	now := unix(time.Now())
+	res, err := d.sql.Exec(`
+		INSERT INTO webhook_inbox(method, path, header, body, key_hash, status, next_attempt_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, method, path, string(h), body, keyHash, InboxPending, now, now, now)
+	if err != nil {
+		return InboxItem{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return InboxItem{}, err
+	}
+	return d.GetInboxItem(id)
+}
+
+func (d *DB) GetInboxItem(id int64) (InboxItem, error) {
+	return scanInboxItem(d.sql.QueryRow(`SELECT `+inboxColumns+` FROM webhook_inbox WHERE id = ?`, id))
+}
+
+// DueInbox returns pending requests whose next attempt is due, oldest first.
+func (d *DB) DueInbox(now time.Time, limit int) ([]InboxItem, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	return d.queryInbox(`SELECT `+inboxColumns+` FROM webhook_inbox
+		WHERE status = ? AND next_attempt_at <= ? ORDER BY id ASC LIMIT ?`, InboxPending, unix(now), limit)
+}
+
+// ListInbox returns requests newest first. An empty status lists everything.
+func (d *DB) ListInbox(status string, limit int) ([]InboxItem, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	q := `SELECT ` + inboxColumns + ` FROM webhook_inbox`
+	var args []interface{}
+	if status != "" {
+		q += ` WHERE status = ?`
+		args = append(args, status)
+	}
+	args = append(args, limit)
+	return d.queryInbox(q+` ORDER BY id DESC LIMIT ?`, args...)
+}
+
+func (d *DB) CountInbox(status string) (int64, error) {
+	var n int64
+	err := d.sql.QueryRow(`SELECT COUNT(1) FROM webhook_inbox WHERE status = ?`, status).Scan(&n)
+	return n, err
+}
+
+// MarkInboxDone records the attempt that processed the request.
+func (d *DB) MarkInboxDone(id int64, status int) error {
+	now := unix(time.Now())
+	_, err := d.sql.Exec(`
+		UPDATE webhook_inbox
+		SET status = ?, attempts = attempts + 1, last_status = ?, last_error = NULL, updated_at = ?, done_at = ?
+		WHERE id = ?
+	`, InboxDone, status, now, now, id)
+	return err
+}
+
+// MarkInboxError records a failed attempt and schedules the next one at
+// next. When giveUp is set the request is moved to the failed state instead.
+func (d *DB) MarkInboxError(id int64, status int, errMsg string, next time.Time, giveUp bool) error {
+	state := InboxPending
+	if giveUp {
+		state = InboxFailed
+	}
+	_, err := d.sql.Exec(`
+		UPDATE webhook_inbox
+		SET status = ?, attempts = attempts + 1, last_status = ?, last_error = ?, next_attempt_at = ?, updated_at = ?
+		WHERE id = ?
+	`, state, status, errMsg, unix(next), unix(time.Now()), id)
+	return err
+}
+
+// RetryInbox makes a failed request due again with a fresh attempt budget.
+// It reports false if no failed request has that id.
+func (d *DB) RetryInbox(id int64) (bool, error) {
+	now := unix(time.Now())
+	res, err := d.sql.Exec(`
+		UPDATE webhook_inbox SET status = ?, attempts = 0, next_attempt_at = ?, updated_at = ?
+		WHERE id = ? AND status = ?
+	`, InboxPending, now, now, id, InboxFailed)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// PruneInbox deletes processed requests older than before.
+func (d *DB) PruneInbox(before time.Time) (int64, error) {
+	res, err := d.sql.Exec(`DELETE FROM webhook_inbox WHERE status = ? AND done_at < ?`, InboxDone, unix(before))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (d *DB) queryInbox(q string, args ...interface{}) ([]InboxItem, error) {
+	rows, err := d.sql.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []InboxItem
+	for rows.Next() {
+		item, err := scanInboxItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+func scanInboxItem(row rowScanner) (InboxItem, error) {
+	var it InboxItem
+	var header string
+	var next, created, updated, done int64
+	if err := row.Scan(&it.ID, &it.Method, &it.Path, &header, &it.Body, &it.KeyHash, &it.Status, &it.Attempts, &it.LastStatus, &it.LastError, &next, &created, &updated, &done); err != nil {
+		return InboxItem{}, err
+	}
+	if err := json.Unmarshal([]byte(header), &it.Header); err != nil {
+		return InboxItem{}, err
+	}
+	it.NextAttemptAt = fromUnix(next)
+	it.CreatedAt = fromUnix(created)
+	it.UpdatedAt = fromUnix(updated)
+	it.DoneAt = fromUnix(done)
+	return it, nil
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWebhookInbox(t *testing.T) {
+	db := openTestDB(t)
+
+	item, err := db.EnqueueInbox("POST", "/api/v1/webhook/grafana?to=123", map[string][]string{"Content-Type": {"application/json"}}, []byte(`{"title":"x"}`), "abc")
+	if err != nil {
+		t.Fatalf("EnqueueInbox: %v", err)
+	}
+	if item.Status != InboxPending || item.Header["Content-Type"][0] != "application/json" || string(item.Body) != `{"title":"x"}` {
+		t.Fatalf("item = %+v", item)
+	}
+	other, _ := db.EnqueueInbox("POST", "/api/v1/webhook/generic", nil, nil, "")
+
+	due, err := db.DueInbox(time.Now(), 10)
+	if err != nil || len(due) != 2 || due[0].ID != item.ID {
+		t.Fatalf("DueInbox: %v %+v", err, due)
+	}
+
+	later := time.Now().Add(time.Hour)
+	if err := db.MarkInboxError(item.ID, 500, "send failed", later, false); err != nil {
+		t.Fatalf("MarkInboxError: %v", err)
+	}
+	if due, _ := db.DueInbox(time.Now(), 10); len(due) != 1 || due[0].ID != other.ID {
+		t.Fatalf("expected the retried item to wait, due = %+v", due)
+	}
+	if due, _ := db.DueInbox(later, 10); len(due) != 2 {
+		t.Fatalf("expected both due later, got %+v", due)
+	}
+
+	if err := db.MarkInboxDone(other.ID, 200); err != nil {
+		t.Fatalf("MarkInboxDone: %v", err)
+	}
+	if err := db.MarkInboxError(item.ID, 400, "invalid recipient", later, true); err != nil {
+		t.Fatalf("MarkInboxError give up: %v", err)
+	}
+	item, _ = db.GetInboxItem(item.ID)
+	if item.Status != InboxFailed || item.Attempts != 2 || item.LastStatus != 400 || item.LastError != "invalid recipient" {
+		t.Fatalf("failed item = %+v", item)
+	}
+	if n, _ := db.CountInbox(InboxFailed); n != 1 {
+		t.Fatalf("CountInbox failed = %d", n)
+	}
+
+	if ok, err := db.RetryInbox(item.ID); err != nil || !ok {
+		t.Fatalf("RetryInbox: %v %v", ok, err)
+	}
+	if ok, _ := db.RetryInbox(other.ID); ok {
+		t.Fatalf("done items must not be retried")
+	}
+	item, _ = db.GetInboxItem(item.ID)
+	if item.Status != InboxPending || item.Attempts != 0 {
+		t.Fatalf("retried item = %+v", item)
+	}
+
+	if n, err := db.PruneInbox(time.Now().Add(time.Minute)); err != nil || n != 1 {
+		t.Fatalf("PruneInbox: %d %v", n, err)
+	}
+	if list, _ := db.ListInbox("", 10); len(list) != 1 || list[0].ID != item.ID {
+		t.Fatalf("ListInbox = %+v", list)
+	}
+}
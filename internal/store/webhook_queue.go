@@ -0,0 +1,212 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// WebhookDelivery is a queued event waiting to be POSTed to a subscription.
+type WebhookDelivery struct {
+	ID            int64
+	WebhookID     int64
+	EventType     string
+	Payload       []byte
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+}
+
+// WebhookFailure is a delivery that exhausted its attempts (dead letter).
+type WebhookFailure struct {
+	ID        int64
+	WebhookID int64
+	EventType string
+	Payload   []byte
+	Attempts  int
+	LastError string
+	CreatedAt time.Time
+	FailedAt  time.Time
+}
+
+func (d *DB) EnqueueWebhookDelivery(webhookID int64, eventType string, payload []byte) (int64, error) {
+	if len(payload) == 0 {
+		return 0, fmt.Errorf("payload is required")
+	}
+	now := unix(time.Now().UTC())
+	res, err := d.sql.Exec(`
+		INSERT INTO webhook_queue(webhook_id, event_type, payload, attempts, next_attempt_at, created_at)
+		VALUES (?, ?, ?, 0, ?, ?)
+	`, webhookID, eventType, string(payload), now, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// DueWebhookDeliveries returns queued deliveries of enabled subscriptions
+// whose next attempt is due, oldest first. Deliveries of disabled
+// subscriptions stay queued without taking up the limit.
+func (d *DB) DueWebhookDeliveries(now time.Time, limit int) ([]WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := d.sql.Query(`
+		SELECT q.id, q.webhook_id, q.event_type, q.payload, q.attempts, COALESCE(q.last_error,''), q.next_attempt_at, q.created_at
+		FROM webhook_queue q
+		JOIN webhooks w ON w.id = q.webhook_id AND w.enabled = 1
+		WHERE q.next_attempt_at <= ?
+		ORDER BY q.next_attempt_at, q.id
+		LIMIT ?
+	`, unix(now), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []WebhookDelivery
+	for rows.Next() {
+		var w WebhookDelivery
+		var payload string
+		var next, created int64
+		if err := rows.Scan(&w.ID, &w.WebhookID, &w.EventType, &payload, &w.Attempts, &w.LastError, &next, &created); err != nil {
+			return nil, err
+		}
+		w.Payload = []byte(payload)
+		w.NextAttemptAt = fromUnix(next)
+		w.CreatedAt = fromUnix(created)
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
+
+// DeleteOrphanedWebhookDeliveries removes queued deliveries whose
+// subscription no longer exists and returns how many were removed.
+func (d *DB) DeleteOrphanedWebhookDeliveries() (int64, error) {
+	res, err := d.sql.Exec(`DELETE FROM webhook_queue WHERE webhook_id NOT IN (SELECT id FROM webhooks)`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// CountWebhookQueue returns the number of queued deliveries for a subscription.
+func (d *DB) CountWebhookQueue(webhookID int64) (int64, error) {
+	var n int64
+	err := d.sql.QueryRow(`SELECT COUNT(1) FROM webhook_queue WHERE webhook_id = ?`, webhookID).Scan(&n)
+	return n, err
+}
+
+// CountWebhookBacklog returns the number of queued deliveries across all
+// subscriptions.
+func (d *DB) CountWebhookBacklog() (int64, error) {
+	var n int64
+	err := d.sql.QueryRow(`SELECT COUNT(1) FROM webhook_queue`).Scan(&n)
+	return n, err
+}
+
+// CompleteWebhookDelivery removes a successfully delivered item from the queue.
+func (d *DB) CompleteWebhookDelivery(id int64) error {
+	_, err := d.sql.Exec(`DELETE FROM webhook_queue WHERE id = ?`, id)
+	return err
+}
+
+// RetryWebhookDelivery records a failed attempt and schedules the next one.
+func (d *DB) RetryWebhookDelivery(id int64, errMsg string, next time.Time) error {
+	_, err := d.sql.Exec(`
+		UPDATE webhook_queue SET attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?
+	`, errMsg, unix(next), id)
+	return err
+}
+
+// DeadLetterWebhookDelivery moves a delivery that gave up out of the queue
+// into the dead-letter table.
+func (d *DB) DeadLetterWebhookDelivery(id int64, errMsg string) error {
+	tx, err := d.sql.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.Exec(`
+		INSERT INTO webhook_dead_letters(webhook_id, event_type, payload, attempts, last_error, created_at, failed_at)
+		SELECT webhook_id, event_type, payload, attempts + 1, ?, created_at, ?
+		FROM webhook_queue WHERE id = ?
+	`, errMsg, unix(time.Now().UTC()), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("webhook delivery %d not found", id)
+	}
+	if _, err := tx.Exec(`DELETE FROM webhook_queue WHERE id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListWebhookFailures returns dead-lettered deliveries of a subscription,
+// newest first.
+func (d *DB) ListWebhookFailures(webhookID int64, limit int) ([]WebhookFailure, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := d.sql.Query(`
+		SELECT id, webhook_id, event_type, payload, attempts, COALESCE(last_error,''), created_at, failed_at
+		FROM webhook_dead_letters
+		WHERE webhook_id = ?
+		ORDER BY id DESC
+		LIMIT ?
+	`, webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []WebhookFailure
+	for rows.Next() {
+		var f WebhookFailure
+		var payload string
+		var created, failed int64
+		if err := rows.Scan(&f.ID, &f.WebhookID, &f.EventType, &payload, &f.Attempts, &f.LastError, &created, &failed); err != nil {
+			return nil, err
+		}
+		f.Payload = []byte(payload)
+		f.CreatedAt = fromUnix(created)
+		f.FailedAt = fromUnix(failed)
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+// RedeliverWebhookFailure moves a dead letter back into the queue with a fresh
+// attempt budget and returns the new queue id. It returns sql.ErrNoRows when
+// the failure does not belong to the subscription.
+func (d *DB) RedeliverWebhookFailure(webhookID, failureID int64) (int64, error) {
+	tx, err := d.sql.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var eventType, payload string
+	var created int64
+	if err := tx.QueryRow(`
+		SELECT event_type, payload, created_at FROM webhook_dead_letters WHERE id = ? AND webhook_id = ?
+	`, failureID, webhookID).Scan(&eventType, &payload, &created); err != nil {
+		return 0, err
+	}
+	res, err := tx.Exec(`
+		INSERT INTO webhook_queue(webhook_id, event_type, payload, attempts, next_attempt_at, created_at)
+		VALUES (?, ?, ?, 0, ?, ?)
+	`, webhookID, eventType, payload, unix(time.Now().UTC()), created)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`DELETE FROM webhook_dead_letters WHERE id = ?`, failureID); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
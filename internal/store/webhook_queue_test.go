@@ -0,0 +1,109 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWebhookQueueDeadLetterAndRedeliver(t *testing.T) {
+	db := openTestDB(t)
+
+	w, err := db.CreateWebhook(CreateWebhookParams{URL: "https://example.com/hook"})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	id, err := db.EnqueueWebhookDelivery(w.ID, "message", []byte(`{"type":"message"}`))
+	if err != nil {
+		t.Fatalf("EnqueueWebhookDelivery: %v", err)
+	}
+	if n, err := db.CountWebhookBacklog(); err != nil || n != 1 {
+		t.Fatalf("CountWebhookBacklog = %d, %v; want 1", n, err)
+	}
+
+	now := time.Now().UTC()
+	due, err := db.DueWebhookDeliveries(now, 10)
+	if err != nil || len(due) != 1 || string(due[0].Payload) != `{"type":"message"}` {
+		t.Fatalf("unexpected due list: %+v (%v)", due, err)
+	}
+
+	if err := db.RetryWebhookDelivery(id, "502", now.Add(time.Minute)); err != nil {
+		t.Fatalf("RetryWebhookDelivery: %v", err)
+	}
+	if due, _ := db.DueWebhookDeliveries(now, 10); len(due) != 0 {
+		t.Fatalf("expected retry to be scheduled later, got %+v", due)
+	}
+	due, _ = db.DueWebhookDeliveries(now.Add(2*time.Minute), 10)
+	if len(due) != 1 || due[0].Attempts != 1 || due[0].LastError != "502" {
+		t.Fatalf("unexpected retried delivery: %+v", due)
+	}
+
+	if err := db.DeadLetterWebhookDelivery(id, "timeout"); err != nil {
+		t.Fatalf("DeadLetterWebhookDelivery: %v", err)
+	}
+	if n, _ := db.CountWebhookQueue(w.ID); n != 0 {
+		t.Fatalf("expected empty queue, got %d", n)
+	}
+	failures, err := db.ListWebhookFailures(w.ID, 10)
+	if err != nil || len(failures) != 1 || failures[0].Attempts != 2 || failures[0].LastError != "timeout" {
+		t.Fatalf("unexpected failures: %+v (%v)", failures, err)
+	}
+
+	if _, err := db.RedeliverWebhookFailure(w.ID+1, failures[0].ID); !IsNotFound(err) {
+		t.Fatalf("expected not found for other webhook, got %v", err)
+	}
+	if _, err := db.RedeliverWebhookFailure(w.ID, failures[0].ID); err != nil {
+		t.Fatalf("RedeliverWebhookFailure: %v", err)
+	}
+	due, _ = db.DueWebhookDeliveries(time.Now().UTC(), 10)
+	if len(due) != 1 || due[0].Attempts != 0 {
+		t.Fatalf("expected fresh queued delivery, got %+v", due)
+	}
+	if failures, _ := db.ListWebhookFailures(w.ID, 10); len(failures) != 0 {
+		t.Fatalf("expected dead letter to be removed, got %+v", failures)
+	}
+
+	if _, err := db.DeleteWebhook(w.ID); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	if n, _ := db.CountWebhookQueue(w.ID); n != 0 {
+		t.Fatalf("expected queue to be cleared with the webhook, got %d", n)
+	}
+}
+
+func TestDueWebhookDeliveriesSkipsDisabledAndOrphaned(t *testing.T) {
+	db := openTestDB(t)
+
+	disabled, _ := db.CreateWebhook(CreateWebhookParams{URL: "https://example.com/off"})
+	off := false
+	if _, err := db.UpdateWebhook(disabled.ID, UpdateWebhookParams{Enabled: &off}); err != nil {
+		t.Fatalf("UpdateWebhook: %v", err)
+	}
+	live, _ := db.CreateWebhook(CreateWebhookParams{URL: "https://example.com/on"})
+	payload := []byte(`{"type":"message"}`)
+	for i := 0; i < 3; i++ {
+		if _, err := db.EnqueueWebhookDelivery(disabled.ID, "message", payload); err != nil {
+			t.Fatalf("EnqueueWebhookDelivery: %v", err)
+		}
+		if _, err := db.EnqueueWebhookDelivery(live.ID+100, "message", payload); err != nil {
+			t.Fatalf("EnqueueWebhookDelivery: %v", err)
+		}
+	}
+	if _, err := db.EnqueueWebhookDelivery(live.ID, "message", payload); err != nil {
+		t.Fatalf("EnqueueWebhookDelivery: %v", err)
+	}
+
+	due, err := db.DueWebhookDeliveries(time.Now().UTC(), 2)
+	if err != nil || len(due) != 1 || due[0].WebhookID != live.ID {
+		t.Fatalf("expected only the enabled subscription's delivery, got %+v (%v)", due, err)
+	}
+
+	if n, err := db.DeleteOrphanedWebhookDeliveries(); err != nil || n != 3 {
+		t.Fatalf("DeleteOrphanedWebhookDeliveries = %d, %v; want 3", n, err)
+	}
+	if n, _ := db.CountWebhookQueue(disabled.ID); n != 3 {
+		t.Fatalf("expected disabled subscription's deliveries to stay queued, got %d", n)
+	}
+	if n, _ := db.CountWebhookBacklog(); n != 4 {
+		t.Fatalf("CountWebhookBacklog = %d, want 4", n)
+	}
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WebhookRoute is a named generic webhook whose payloads are adapted by a
+// field mapping (see app.WebhookMapping).
+type WebhookRoute struct {
+	Name      string
+	Mapping   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// SetWebhookRoute stores the mapping of route name, replacing an existing
+// one. The caller is responsible for checking that mapping parses.
+func (d *DB) SetWebhookRoute(name, mapping string) (WebhookRoute, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return WebhookRoute{}, fmt.Errorf("name is required")
+	}
+	if strings.TrimSpace(mapping) == "" {
+		return WebhookRoute{}, fmt.Errorf("mapping is required")
+	}
+	now := unix(time.Now().UTC())
+	if _, err := d.sql.Exec(`
+		INSERT INTO webhook_routes(name, mapping, created_at, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET mapping = excluded.mapping, updated_at = excluded.updated_at
+	`, name, mapping, now, now); err != nil {
+		return WebhookRoute{}, err
+	}
+	return d.GetWebhookRoute(name)
+}
+
+// GetWebhookRoute returns route name, or an error matching IsNotFound.
+func (d *DB) GetWebhookRoute(name string) (WebhookRoute, error) {
+	return scanWebhookRoute(d.sql.QueryRow(`SELECT name, mapping, created_at, updated_at FROM webhook_routes WHERE name = ?`, name))
+}
+
+func (d *DB) ListWebhookRoutes() ([]WebhookRoute, error) {
+	rows, err := d.sql.Query(`SELECT name, mapping, created_at, updated_at FROM webhook_routes ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []WebhookRoute
+	for rows.Next() {
+		r, err := scanWebhookRoute(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func (d *DB) DeleteWebhookRoute(name string) (bool, error) {
+	res, err := d.sql.Exec(`DELETE FROM webhook_routes WHERE name = ?`, name)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func scanWebhookRoute(row rowScanner) (WebhookRoute, error) {
+	var r WebhookRoute
+	var created, updated int64
+	if err := row.Scan(&r.Name, &r.Mapping, &created, &updated); err != nil {
+		return WebhookRoute{}, err
+	}
+	r.CreatedAt = fromUnix(created)
+	r.UpdatedAt = fromUnix(updated)
+	return r, nil
+}
@@ -0,0 +1,36 @@
+package store
+
+import "testing"
+
+func TestWebhookRoutes(t *testing.T) {
+	db := openTestDB(t)
+
+	if _, err := db.GetWebhookRoute("pagerduty"); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if _, err := db.SetWebhookRoute("pagerduty", `{"message":"$.title"}`); err != nil {
+		t.Fatalf("SetWebhookRoute: %v", err)
+	}
+	r, err := db.SetWebhookRoute("pagerduty", `{"message":"$.incident.title"}`)
+	if err != nil {
+		t.Fatalf("SetWebhookRoute replace: %v", err)
+	}
+	if r.Mapping != `{"message":"$.incident.title"}` || r.CreatedAt.IsZero() {
+		t.Fatalf("unexpected route: %+v", r)
+	}
+	if _, err := db.SetWebhookRoute(" ", `{}`); err == nil {
+		t.Fatalf("expected error for empty name")
+	}
+
+	list, err := db.ListWebhookRoutes()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("ListWebhookRoutes: %v %+v", err, list)
+	}
+
+	if ok, err := db.DeleteWebhookRoute("pagerduty"); err != nil || !ok {
+		t.Fatalf("DeleteWebhookRoute: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := db.DeleteWebhookRoute("pagerduty"); ok {
+		t.Fatalf("second delete should report false")
+	}
+}
@@ -0,0 +1,229 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrWebhookInUse is returned when deleting a webhook that rules forward to.
+var ErrWebhookInUse = errors.New("webhook is used by rules")
+
+type Webhook struct {
+	ID      int64
+	URL     string
+	Events  []string
+	Chats   []string
+	Enabled bool
+	// Secret signs deliveries (HMAC-SHA256).
+	Secret string
+	// SkipMuted drops events of chats muted on the phone.
+	SkipMuted bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type CreateWebhookParams struct {
+	URL    string
+	Events []string
+	Chats  []string
+	// Secret defaults to 32 random bytes, hex-encoded.
+	Secret string
+	// Disabled creates the subscription switched off.
+	Disabled  bool
+	SkipMuted bool
+}
+
+// UpdateWebhookParams changes the non-nil fields of a subscription.
+type UpdateWebhookParams struct {
+	URL     *string
+	Events  *[]string
+	Chats   *[]string
+	Enabled *bool
+	// Secret replaces the signing secret; an empty string generates a new one.
+	Secret    *string
+	SkipMuted *bool
+}
+
+func (d *DB) CreateWebhook(p CreateWebhookParams) (Webhook, error) {
+	u, err := ParseHTTPURL(p.URL)
+	if err != nil {
+		return Webhook{}, err
+	}
+	secret := strings.TrimSpace(p.Secret)
+	if secret == "" {
+		if secret, err = newWebhookSecret(); err != nil {
+			return Webhook{}, err
+		}
+	}
+	now := time.Now().UTC()
+	res, err := d.sql.Exec(`
+		INSERT INTO webhooks(url, events, chats, enabled, secret, skip_muted, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, u, joinList(p.Events), joinList(p.Chats), boolToInt(!p.Disabled), secret, boolToInt(p.SkipMuted), unix(now), unix(now))
+	if err != nil {
+		return Webhook{}, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return Webhook{}, err
+	}
+	return d.GetWebhook(id)
+}
+
+func (d *DB) GetWebhook(id int64) (Webhook, error) {
+	row := d.sql.QueryRow(`SELECT id, url, events, chats, enabled, secret, skip_muted, created_at, updated_at FROM webhooks WHERE id = ?`, id)
+	return scanWebhook(row)
+}
+
+// ListWebhooks returns all subscriptions; enabledOnly skips disabled ones.
+func (d *DB) ListWebhooks(enabledOnly bool) ([]Webhook, error) {
+	q := `SELECT id, url, events, chats, enabled, secret, skip_muted, created_at, updated_at FROM webhooks`
+	if enabledOnly {
+		q += ` WHERE enabled = 1`
+	}
+	q += ` ORDER BY id`
+	rows, err := d.sql.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Webhook
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, w)
+	}
+	return out, rows.Err()
+}
+
+// UpdateWebhook applies a partial update to a subscription.
+func (d *DB) UpdateWebhook(id int64, p UpdateWebhookParams) (Webhook, error) {
+	w, err := d.GetWebhook(id)
+	if err != nil {
+		return Webhook{}, err
+	}
+	if p.URL != nil {
+		if w.URL, err = ParseHTTPURL(*p.URL); err != nil {
+			return Webhook{}, err
+		}
+	}
+	if p.Events != nil {
+		w.Events = *p.Events
+	}
+	if p.Chats != nil {
+		w.Chats = *p.Chats
+	}
+	if p.Enabled != nil {
+		w.Enabled = *p.Enabled
+	}
+	if p.SkipMuted != nil {
+		w.SkipMuted = *p.SkipMuted
+	}
+	if p.Secret != nil {
+		if w.Secret = strings.TrimSpace(*p.Secret); w.Secret == "" {
+			if w.Secret, err = newWebhookSecret(); err != nil {
+				return Webhook{}, err
+			}
+		}
+	}
+	if _, err := d.sql.Exec(`
+		UPDATE webhooks SET url=?, events=?, chats=?, enabled=?, secret=?, skip_muted=?, updated_at=? WHERE id = ?
+	`, w.URL, joinList(w.Events), joinList(w.Chats), boolToInt(w.Enabled), w.Secret, boolToInt(w.SkipMuted), unix(time.Now().UTC()), id); err != nil {
+		return Webhook{}, err
+	}
+	return d.GetWebhook(id)
+}
+
+// DeleteWebhook removes a subscription together with its queued and
+// dead-lettered deliveries. It fails with ErrWebhookInUse while webhook rules
+// forward to it.
+func (d *DB) DeleteWebhook(id int64) (bool, error) {
+	tx, err := d.sql.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var rules int
+	if err := tx.QueryRow(`SELECT COUNT(1) FROM rules WHERE action = ? AND arg = ?`, RuleWebhook, strconv.FormatInt(id, 10)).Scan(&rules); err != nil {
+		return false, err
+	}
+	if rules > 0 {
+		return false, fmt.Errorf("%w: %d rule(s) forward to webhook %d", ErrWebhookInUse, rules, id)
+	}
+	res, err := tx.Exec(`DELETE FROM webhooks WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	if _, err := tx.Exec(`DELETE FROM webhook_queue WHERE webhook_id = ?`, id); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`DELETE FROM webhook_dead_letters WHERE webhook_id = ?`, id); err != nil {
+		return false, err
+	}
+	return n > 0, tx.Commit()
+}
+
+func scanWebhook(row rowScanner) (Webhook, error) {
+	var w Webhook
+	var events, chats string
+	var enabled, skipMuted int
+	var created, updated int64
+	if err := row.Scan(&w.ID, &w.URL, &events, &chats, &enabled, &w.Secret, &skipMuted, &created, &updated); err != nil {
+		return Webhook{}, err
+	}
+	w.Events = splitList(events)
+	w.Chats = splitList(chats)
+	w.Enabled = enabled != 0
+	w.SkipMuted = skipMuted != 0
+	w.CreatedAt = fromUnix(created)
+	w.UpdatedAt = fromUnix(updated)
+	return w, nil
+}
+
+// ParseHTTPURL checks that raw is an absolute http(s) URL and returns it
+// normalized.
+func ParseHTTPURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("url must be an absolute http(s) URL")
+	}
+	return u.String(), nil
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func joinList(items []string) string {
+	var out []string
+	for _, it := range items {
+		if it = strings.TrimSpace(it); it != "" {
+			out = append(out, it)
+		}
+	}
+	return strings.Join(out, ",")
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, it := range strings.Split(s, ",") {
+		if it = strings.TrimSpace(it); it != "" {
+			out = append(out, it)
+		}
+	}
+	return out
+}
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestWebhookCRUD(t *testing.T) {
+	db := openTestDB(t)
+
+	if _, err := db.CreateWebhook(CreateWebhookParams{URL: "ftp://example.com"}); err == nil {
+		t.Fatalf("expected invalid URL error")
+	}
+
+	w, err := db.CreateWebhook(CreateWebhookParams{
+		URL:    "https://example.com/hook",
+		Events: []string{"message", " receipt "},
+		Chats:  []string{"123@s.whatsapp.net"},
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	if !w.Enabled || len(w.Events) != 2 || w.Events[1] != "receipt" || len(w.Chats) != 1 {
+		t.Fatalf("unexpected webhook: %+v", w)
+	}
+
+	all, err := db.ListWebhooks(true)
+	if err != nil {
+		t.Fatalf("ListWebhooks: %v", err)
+	}
+	if len(all) != 1 || all[0].URL != "https://example.com/hook" {
+		t.Fatalf("unexpected list: %+v", all)
+	}
+
+	ok, err := db.DeleteWebhook(w.ID)
+	if err != nil || !ok {
+		t.Fatalf("DeleteWebhook: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := db.DeleteWebhook(w.ID); ok {
+		t.Fatalf("expected second delete to report not found")
+	}
+	if _, err := db.GetWebhook(w.ID); !IsNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestDeleteWebhookUsedByRule(t *testing.T) {
+	db := openTestDB(t)
+
+	w, err := db.CreateWebhook(CreateWebhookParams{URL: "https://example.com/hook"})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	if _, err := db.CreateRule(CreateRuleParams{Action: RuleWebhook, Arg: strconv.FormatInt(w.ID+1, 10)}); err == nil {
+		t.Fatalf("expected rule forwarding to a missing webhook to be rejected")
+	}
+	r, err := db.CreateRule(CreateRuleParams{Action: RuleWebhook, Arg: strconv.FormatInt(w.ID, 10)})
+	if err != nil {
+		t.Fatalf("CreateRule: %v", err)
+	}
+
+	if _, err := db.DeleteWebhook(w.ID); !errors.Is(err, ErrWebhookInUse) {
+		t.Fatalf("expected ErrWebhookInUse, got %v", err)
+	}
+	if _, err := db.GetWebhook(w.ID); err != nil {
+		t.Fatalf("expected webhook to be kept: %v", err)
+	}
+	if _, err := db.DeleteRule(r.ID); err != nil {
+		t.Fatalf("DeleteRule: %v", err)
+	}
+	if ok, err := db.DeleteWebhook(w.ID); err != nil || !ok {
+		t.Fatalf("DeleteWebhook: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestWebhookSecret(t *testing.T) {
+	db := openTestDB(t)
+
+	a, err := db.CreateWebhook(CreateWebhookParams{URL: "https://example.com/a"})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	b, err := db.CreateWebhook(CreateWebhookParams{URL: "https://example.com/b"})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	if len(a.Secret) != 64 || a.Secret == b.Secret {
+		t.Fatalf("expected distinct generated secrets, got %q and %q", a.Secret, b.Secret)
+	}
+
+	c, err := db.CreateWebhook(CreateWebhookParams{URL: "https://example.com/c", Secret: " s3cret "})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	got, err := db.GetWebhook(c.ID)
+	if err != nil || got.Secret != "s3cret" {
+		t.Fatalf("expected custom secret, got %q (%v)", got.Secret, err)
+	}
+}
+
+func TestUpdateWebhook(t *testing.T) {
+	db := openTestDB(t)
+	w, err := db.CreateWebhook(CreateWebhookParams{URL: "https://example.com/a", Disabled: true})
+	if err != nil || w.Enabled {
+		t.Fatalf("CreateWebhook: %+v (%v)", w, err)
+	}
+
+	bad := "mailto:x@example.com"
+	if _, err := db.UpdateWebhook(w.ID, UpdateWebhookParams{URL: &bad}); err == nil {
+		t.Fatalf("expected invalid URL error")
+	}
+
+	on, events, rotate := true, []string{"call"}, ""
+	u, err := db.UpdateWebhook(w.ID, UpdateWebhookParams{Enabled: &on, Events: &events, Secret: &rotate})
+	if err != nil {
+		t.Fatalf("UpdateWebhook: %v", err)
+	}
+	if !u.Enabled || u.URL != w.URL || len(u.Events) != 1 || u.Events[0] != "call" || u.Secret == "" || u.Secret == w.Secret {
+		t.Fatalf("unexpected webhook: %+v", u)
+	}
+}
@@ -0,0 +1,61 @@
+package wa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// GetAbout returns our own profile "about" text.
+func (c *Client) GetAbout(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return "", fmt.Errorf("not connected")
+	}
+	if cli.Store.ID == nil {
+		return "", fmt.Errorf("not authenticated")
+	}
+	own := cli.Store.ID.ToNonAD()
+	info, err := cli.GetUserInfo(ctx, []types.JID{own})
+	if err != nil {
+		return "", err
+	}
+	return info[own].Status, nil
+}
+
+// SetAbout changes our own profile "about" text.
+func (c *Client) SetAbout(ctx context.Context, text string) error {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+	return cli.SetStatusMessage(ctx, text)
+}
+
+// ErrNoProfilePicture is returned when a contact or group has no profile
+// picture, or its privacy settings hide it from us.
+var ErrNoProfilePicture = errors.New("no profile picture")
+
+// GetProfilePicture returns where to download the full-size profile picture
+// of a contact or group. With existingID it returns nil when the picture
+// has not changed.
+func (c *Client) GetProfilePicture(ctx context.Context, jid types.JID, existingID string) (*types.ProfilePictureInfo, error) {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return nil, fmt.Errorf("not connected")
+	}
+	info, err := cli.GetProfilePictureInfo(ctx, jid, &whatsmeow.GetProfilePictureParams{ExistingID: existingID})
+	if errors.Is(err, whatsmeow.ErrProfilePictureNotSet) || errors.Is(err, whatsmeow.ErrProfilePictureUnauthorized) {
+		return nil, ErrNoProfilePicture
+	}
+	return info, err
+}
@@ -0,0 +1,36 @@
+package wa
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow/appstate"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// SetChatArchived archives or unarchives a chat on every linked device.
+// Archiving also unpins the chat.
+func (c *Client) SetChatArchived(ctx context.Context, chat types.JID, archived bool) error {
+	return c.sendAppState(ctx, appstate.BuildArchive(chat, archived, time.Time{}, nil))
+}
+
+// SetChatPinned pins or unpins a chat on every linked device.
+func (c *Client) SetChatPinned(ctx context.Context, chat types.JID, pinned bool) error {
+	return c.sendAppState(ctx, appstate.BuildPin(chat, pinned))
+}
+
+// SetChatMuted mutes a chat for d, or forever when d is zero, or unmutes it.
+func (c *Client) SetChatMuted(ctx context.Context, chat types.JID, muted bool, d time.Duration) error {
+	return c.sendAppState(ctx, appstate.BuildMute(chat, muted, d))
+}
+
+func (c *Client) sendAppState(ctx context.Context, patch appstate.PatchInfo) error {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+	return cli.SendAppState(ctx, patch)
+}
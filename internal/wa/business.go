@@ -0,0 +1,103 @@
+package wa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mau.fi/whatsmeow"
+	waBinary "go.mau.fi/whatsmeow/binary"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// ErrNotBusiness is returned when a JID is not a WhatsApp Business account.
+var ErrNotBusiness = errors.New("not a business account")
+
+// BusinessProfile is the public profile of a WhatsApp Business account.
+type BusinessProfile struct {
+	JID         types.JID
+	Description string
+	Categories  []string
+	Address     string
+	Email       string
+	Websites    []string
+	// TimeZone is the IANA zone the opening hours are in.
+	TimeZone string
+	Hours    []types.BusinessHoursConfig
+}
+
+// GetBusinessProfile fetches the business profile of jid. whatsmeow's own
+// GetBusinessProfile drops the description and websites, so the query is
+// sent and parsed here.
+func (c *Client) GetBusinessProfile(ctx context.Context, jid types.JID) (*BusinessProfile, error) {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return nil, fmt.Errorf("not connected")
+	}
+	resp, err := cli.DangerousInternals().SendIQ(ctx, whatsmeow.DangerousInfoQuery{
+		Namespace: "w:biz",
+		Type:      "get",
+		To:        types.ServerJID,
+		Content: []waBinary.Node{{
+			Tag:   "business_profile",
+			Attrs: waBinary.Attrs{"v": "244"},
+			Content: []waBinary.Node{{
+				Tag:   "profile",
+				Attrs: waBinary.Attrs{"jid": jid.ToNonAD()},
+			}},
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseBusinessProfile(resp)
+}
+
+func parseBusinessProfile(resp *waBinary.Node) (*BusinessProfile, error) {
+	node, ok := resp.GetOptionalChildByTag("business_profile", "profile")
+	if !ok {
+		return nil, ErrNotBusiness
+	}
+	jid, ok := node.AttrGetter().GetJID("jid", false)
+	if !ok || jid.IsEmpty() {
+		return nil, ErrNotBusiness
+	}
+	text := func(n waBinary.Node) string {
+		b, _ := n.Content.([]byte)
+		return string(b)
+	}
+	p := &BusinessProfile{
+		JID:         jid,
+		Description: text(node.GetChildByTag("description")),
+		Address:     text(node.GetChildByTag("address")),
+		Email:       text(node.GetChildByTag("email")),
+		Categories:  []string{},
+		Websites:    []string{},
+		Hours:       []types.BusinessHoursConfig{},
+	}
+	for _, w := range node.GetChildrenByTag("website") {
+		if s := text(w); s != "" {
+			p.Websites = append(p.Websites, s)
+		}
+	}
+	categories := node.GetChildByTag("categories")
+	for _, cat := range categories.GetChildrenByTag("category") {
+		if s := text(cat); s != "" {
+			p.Categories = append(p.Categories, s)
+		}
+	}
+	hours := node.GetChildByTag("business_hours")
+	p.TimeZone = hours.AttrGetter().OptionalString("timezone")
+	for _, h := range hours.GetChildrenByTag("business_hours_config") {
+		ag := h.AttrGetter()
+		p.Hours = append(p.Hours, types.BusinessHoursConfig{
+			DayOfWeek: ag.OptionalString("day_of_week"),
+			Mode:      ag.OptionalString("mode"),
+			OpenTime:  ag.OptionalString("open_time"),
+			CloseTime: ag.OptionalString("close_time"),
+		})
+	}
+	return p, nil
+}
@@ -0,0 +1,53 @@
+package wa
+
+import (
+	"errors"
+	"testing"
+
+	waBinary "go.mau.fi/whatsmeow/binary"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestParseBusinessProfile(t *testing.T) {
+	jid := types.NewJID("5511999990000", types.DefaultUserServer)
+	resp := &waBinary.Node{Tag: "iq", Content: []waBinary.Node{{
+		Tag: "business_profile",
+		Content: []waBinary.Node{{
+			Tag:   "profile",
+			Attrs: waBinary.Attrs{"jid": jid},
+			Content: []waBinary.Node{
+				{Tag: "description", Content: []byte("Fresh bread daily")},
+				{Tag: "address", Content: []byte("Rua A, 1")},
+				{Tag: "email", Content: []byte("hi@bakery.example")},
+				{Tag: "website", Content: []byte("https://bakery.example")},
+				{Tag: "website", Content: []byte("https://instagram.com/bakery")},
+				{Tag: "categories", Content: []waBinary.Node{{Tag: "category", Attrs: waBinary.Attrs{"id": "1"}, Content: []byte("Bakery")}}},
+				{Tag: "business_hours", Attrs: waBinary.Attrs{"timezone": "America/Sao_Paulo"}, Content: []waBinary.Node{
+					{Tag: "business_hours_config", Attrs: waBinary.Attrs{"day_of_week": "mon", "mode": "specific_hours", "open_time": "420", "close_time": "1140"}},
+				}},
+			},
+		}},
+	}}}
+
+	p, err := parseBusinessProfile(resp)
+	if err != nil {
+		t.Fatalf("parseBusinessProfile: %v", err)
+	}
+	if p.JID != jid || p.Description != "Fresh bread daily" || p.Address != "Rua A, 1" || p.Email != "hi@bakery.example" {
+		t.Fatalf("unexpected profile: %+v", p)
+	}
+	if len(p.Websites) != 2 || p.Websites[1] != "https://instagram.com/bakery" {
+		t.Fatalf("websites = %v", p.Websites)
+	}
+	if len(p.Categories) != 1 || p.Categories[0] != "Bakery" {
+		t.Fatalf("categories = %v", p.Categories)
+	}
+	if p.TimeZone != "America/Sao_Paulo" || len(p.Hours) != 1 || p.Hours[0].OpenTime != "420" {
+		t.Fatalf("hours = %q %+v", p.TimeZone, p.Hours)
+	}
+
+	empty := &waBinary.Node{Tag: "iq", Content: []waBinary.Node{{Tag: "business_profile"}}}
+	if _, err := parseBusinessProfile(empty); !errors.Is(err, ErrNotBusiness) {
+		t.Fatalf("expected ErrNotBusiness, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package wa
+
+import (
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// Call states reported by ParseCallEvent.
+const (
+	CallStateOffer     = "offer"
+	CallStateAccept    = "accept"
+	CallStateReject    = "reject"
+	CallStateTerminate = "terminate"
+)
+
+// CallEvent is the subset of a whatsmeow call event wacli cares about.
+type CallEvent struct {
+	ID        string
+	Chat      types.JID // the group for group calls, otherwise the other party
+	Caller    types.JID
+	State     string
+	Media     string // "audio" or "video"; only known for offers
+	Group     bool
+	Reason    string // terminate reason, e.g. "timeout"
+	Timestamp time.Time
+}
+
+// ParseCallEvent converts whatsmeow call events. Other events return false.
+func ParseCallEvent(evt interface{}) (CallEvent, bool) {
+	var meta types.BasicCallMeta
+	ce := CallEvent{}
+	switch v := evt.(type) {
+	case *events.CallOffer:
+		meta = v.BasicCallMeta
+		ce.State = CallStateOffer
+		ce.Media = "audio"
+		if v.Data != nil {
+			if _, ok := v.Data.GetOptionalChildByTag("video"); ok {
+				ce.Media = "video"
+			}
+		}
+	case *events.CallOfferNotice:
+		meta = v.BasicCallMeta
+		ce.State = CallStateOffer
+		ce.Media = v.Media
+		ce.Group = v.Type == "group"
+	case *events.CallAccept:
+		meta = v.BasicCallMeta
+		ce.State = CallStateAccept
+	case *events.CallReject:
+		meta = v.BasicCallMeta
+		ce.State = CallStateReject
+	case *events.CallTerminate:
+		meta = v.BasicCallMeta
+		ce.State = CallStateTerminate
+		ce.Reason = v.Reason
+	default:
+		return CallEvent{}, false
+	}
+	if meta.CallID == "" {
+		return CallEvent{}, false
+	}
+
+	ce.ID = meta.CallID
+	ce.Caller = meta.CallCreator
+	if ce.Caller.IsEmpty() {
+		ce.Caller = meta.From
+	}
+	ce.Chat = meta.From
+	if !meta.GroupJID.IsEmpty() {
+		ce.Chat = meta.GroupJID
+		ce.Group = true
+	}
+	ce.Timestamp = meta.Timestamp
+	if ce.Timestamp.IsZero() {
+		ce.Timestamp = time.Now()
+	}
+	ce.Timestamp = ce.Timestamp.UTC()
+	return ce, true
+}
@@ -0,0 +1,38 @@
+package wa
+
+import (
+	"testing"
+	"time"
+
+	waBinary "go.mau.fi/whatsmeow/binary"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestParseCallEvent(t *testing.T) {
+	caller := types.NewJID("5511999990000", types.DefaultUserServer)
+	ts := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	meta := types.BasicCallMeta{From: caller, CallCreator: caller, CallID: "CALL1", Timestamp: ts}
+
+	offer := &events.CallOffer{BasicCallMeta: meta, Data: &waBinary.Node{Tag: "offer", Content: []waBinary.Node{{Tag: "video"}}}}
+	ce, ok := ParseCallEvent(offer)
+	if !ok || ce.State != CallStateOffer || ce.Media != "video" || ce.Chat != caller || ce.Group || !ce.Timestamp.Equal(ts) {
+		t.Fatalf("unexpected offer: %+v", ce)
+	}
+
+	group := types.NewJID("123456", types.GroupServer)
+	notice := &events.CallOfferNotice{BasicCallMeta: types.BasicCallMeta{From: caller, CallCreator: caller, CallID: "CALL2", GroupJID: group}, Media: "audio", Type: "group"}
+	if ce, ok := ParseCallEvent(notice); !ok || ce.Chat != group || !ce.Group || ce.Caller != caller || ce.Media != "audio" {
+		t.Fatalf("unexpected group offer: %+v", ce)
+	}
+
+	if ce, ok := ParseCallEvent(&events.CallTerminate{BasicCallMeta: meta, Reason: "timeout"}); !ok || ce.State != CallStateTerminate || ce.Reason != "timeout" {
+		t.Fatalf("unexpected terminate: %+v", ce)
+	}
+	if _, ok := ParseCallEvent(&events.CallTerminate{}); ok {
+		t.Fatalf("expected events without call id to be ignored")
+	}
+	if _, ok := ParseCallEvent(&events.Connected{}); ok {
+		t.Fatalf("expected non-call events to be ignored")
+	}
+}
@@ -3,6 +3,7 @@ package wa
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -22,6 +23,10 @@ type Options struct {
 	StorePath string
 }
 
+// ErrQRTimeout is returned by Connect when every QR code of a pairing
+// session expired without being scanned.
+var ErrQRTimeout = errors.New("QR code timed out")
+
 type Client struct {
 	opts Options
 
@@ -79,6 +84,16 @@ func (c *Client) IsAuthed() bool {
 	return c.client != nil && c.client.Store != nil && c.client.Store.ID != nil
 }
 
+// OwnJID returns the paired device's JID, or an empty JID if unpaired.
+func (c *Client) OwnJID() types.JID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client == nil || c.client.Store == nil || c.client.Store.ID == nil {
+		return types.EmptyJID
+	}
+	return *c.client.Store.ID
+}
+
 func (c *Client) IsConnected() bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -147,7 +162,7 @@ func (c *Client) Connect(ctx context.Context, opts ConnectOptions) error {
 			case "success":
 				return nil
 			case "timeout":
-				return fmt.Errorf("QR code timed out")
+				return ErrQRTimeout
 			case "error":
 				return fmt.Errorf("QR error")
 			}
@@ -204,6 +219,18 @@ func (c *Client) SendProtoMessage(ctx context.Context, to types.JID, msg *waProt
 	return resp.ID, nil
 }
 
+// MarkRead sends read receipts for messages in chat. In groups all ids must
+// come from sender.
+func (c *Client) MarkRead(ctx context.Context, chat, sender types.JID, ids []types.MessageID) error {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+	return cli.MarkRead(ctx, ids, time.Now(), chat, sender)
+}
+
 func (c *Client) Upload(ctx context.Context, data []byte, mediaType whatsmeow.MediaType) (whatsmeow.UploadResponse, error) {
 	c.mu.Lock()
 	cli := c.client
@@ -254,13 +281,19 @@ func (c *Client) RequestHistorySyncOnDemand(ctx context.Context, lastKnown types
 	return resp.ID, nil
 }
 
+// ParseUserOrJID reads a phone number or a JID. A LID with a known phone
+// number is returned as the phone number JID, see SetLIDResolver.
 func ParseUserOrJID(s string) (types.JID, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
 		return types.JID{}, fmt.Errorf("recipient is required")
 	}
 	if strings.Contains(s, "@") {
-		return types.ParseJID(s)
+		jid, err := types.ParseJID(s)
+		if err != nil {
+			return jid, err
+		}
+		return PhoneJID(jid), nil
 	}
 	return types.JID{User: s, Server: types.DefaultUserServer}, nil
 }
@@ -279,6 +312,34 @@ func (c *Client) GetContact(ctx context.Context, jid types.JID) (types.ContactIn
 	return cli.Store.Contacts.GetContact(ctx, jid)
 }
 
+// IsOnWhatsApp asks WhatsApp which of the phone numbers (digits with country
+// code, optionally prefixed with +) have an account.
+func (c *Client) IsOnWhatsApp(ctx context.Context, phones []string) ([]types.IsOnWhatsAppResponse, error) {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return nil, fmt.Errorf("not connected")
+	}
+	query := make([]string, len(phones))
+	for i, p := range phones {
+		query[i] = "+" + strings.TrimPrefix(p, "+")
+	}
+	return cli.IsOnWhatsApp(ctx, query)
+}
+
+// GetChatSettings returns the mute, pin and archive state of a chat as
+// synced from the phone's app state.
+func (c *Client) GetChatSettings(ctx context.Context, chat types.JID) (types.LocalChatSettings, error) {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || cli.Store == nil || cli.Store.ChatSettings == nil {
+		return types.LocalChatSettings{}, fmt.Errorf("chat settings store not available")
+	}
+	return cli.Store.ChatSettings.GetChatSettings(ctx, chat)
+}
+
 func (c *Client) GetAllContacts(ctx context.Context) (map[types.JID]types.ContactInfo, error) {
 	c.mu.Lock()
 	cli := c.client
@@ -376,6 +437,16 @@ func (c *Client) Logout(ctx context.Context) error {
 	return cli.Logout(ctx)
 }
 
+// SetAutoReconnect turns whatsmeow's own reconnects after a dropped
+// connection on or off.
+func (c *Client) SetAutoReconnect(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil {
+		c.client.EnableAutoReconnect = enabled
+	}
+}
+
 // Reconnect loop helper.
 func (c *Client) ReconnectWithBackoff(ctx context.Context, minDelay, maxDelay time.Duration) error {
 	delay := minDelay
@@ -0,0 +1,56 @@
+package wa
+
+import (
+	"context"
+	"fmt"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// CreateCommunity creates a community; WhatsApp adds its announcement group
+// automatically.
+func (c *Client) CreateCommunity(ctx context.Context, name string) (*types.GroupInfo, error) {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return nil, fmt.Errorf("not connected")
+	}
+	return cli.CreateGroup(ctx, whatsmeow.ReqCreateGroup{
+		Name:        name,
+		GroupParent: types.GroupParent{IsParent: true},
+	})
+}
+
+// GetSubGroups lists every group linked to a community, including ones
+// this account has not joined.
+func (c *Client) GetSubGroups(ctx context.Context, community types.JID) ([]*types.GroupLinkTarget, error) {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return nil, fmt.Errorf("not connected")
+	}
+	return cli.GetSubGroups(ctx, community)
+}
+
+func (c *Client) LinkGroup(ctx context.Context, community, group types.JID) error {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+	return cli.LinkGroup(ctx, community, group)
+}
+
+func (c *Client) UnlinkGroup(ctx context.Context, community, group types.JID) error {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+	return cli.UnlinkGroup(ctx, community, group)
+}
@@ -0,0 +1,72 @@
+package wa
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Disappearing message durations accepted by the official WhatsApp clients.
+var ephemeralDurations = map[int]bool{
+	0:       true,
+	86400:   true, // 24 hours
+	604800:  true, // 7 days
+	7776000: true, // 90 days
+}
+
+// ValidateEphemeralSeconds checks that seconds is a disappearing-message
+// duration WhatsApp understands (0 disables the timer).
+func ValidateEphemeralSeconds(seconds int) error {
+	if !ephemeralDurations[seconds] {
+		return fmt.Errorf("ephemeral_seconds must be one of 0, 86400 (24h), 604800 (7d), 7776000 (90d)")
+	}
+	return nil
+}
+
+// WithEphemeral marks msg as a disappearing message that expires after the
+// given number of seconds. Plain conversation messages are upgraded to an
+// extended text message so they can carry the context info.
+func WithEphemeral(msg *waProto.Message, seconds uint32) *waProto.Message {
+	if msg == nil || seconds == 0 {
+		return msg
+	}
+	if msg.Conversation != nil {
+		msg.ExtendedTextMessage = &waProto.ExtendedTextMessage{Text: msg.Conversation}
+		msg.Conversation = nil
+	}
+	switch {
+	case msg.ExtendedTextMessage != nil:
+		msg.ExtendedTextMessage.ContextInfo = ephemeralContext(msg.ExtendedTextMessage.ContextInfo, seconds)
+	case msg.ImageMessage != nil:
+		msg.ImageMessage.ContextInfo = ephemeralContext(msg.ImageMessage.ContextInfo, seconds)
+	case msg.VideoMessage != nil:
+		msg.VideoMessage.ContextInfo = ephemeralContext(msg.VideoMessage.ContextInfo, seconds)
+	case msg.AudioMessage != nil:
+		msg.AudioMessage.ContextInfo = ephemeralContext(msg.AudioMessage.ContextInfo, seconds)
+	case msg.DocumentMessage != nil:
+		msg.DocumentMessage.ContextInfo = ephemeralContext(msg.DocumentMessage.ContextInfo, seconds)
+	}
+	return msg
+}
+
+func ephemeralContext(ci *waProto.ContextInfo, seconds uint32) *waProto.ContextInfo {
+	if ci == nil {
+		ci = &waProto.ContextInfo{}
+	}
+	ci.Expiration = &seconds
+	return ci
+}
+
+// SetDisappearingTimer changes the default disappearing-message timer of a chat.
+func (c *Client) SetDisappearingTimer(ctx context.Context, chat types.JID, timer time.Duration) error {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+	return cli.SetDisappearingTimer(ctx, chat, timer, time.Time{})
+}
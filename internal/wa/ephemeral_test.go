@@ -0,0 +1,51 @@
+package wa
+
+import (
+	"strconv"
+	"testing"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestWithEphemeralUpgradesConversation(t *testing.T) {
+	msg := WithEphemeral(&waProto.Message{Conversation: proto.String("hi")}, 86400)
+	if msg.Conversation != nil {
+		t.Fatalf("expected conversation to be moved")
+	}
+	ext := msg.GetExtendedTextMessage()
+	if ext.GetText() != "hi" || ext.GetContextInfo().GetExpiration() != 86400 {
+		t.Fatalf("unexpected extended text: %+v", ext)
+	}
+}
+
+func TestWithEphemeralMediaAndZero(t *testing.T) {
+	msg := WithEphemeral(&waProto.Message{ImageMessage: &waProto.ImageMessage{Caption: proto.String("c")}}, 604800)
+	if msg.GetImageMessage().GetContextInfo().GetExpiration() != 604800 {
+		t.Fatalf("expected expiration on image")
+	}
+
+	plain := WithEphemeral(&waProto.Message{Conversation: proto.String("x")}, 0)
+	if plain.GetConversation() != "x" || plain.ExtendedTextMessage != nil {
+		t.Fatalf("zero duration should leave message untouched")
+	}
+}
+
+func TestValidateEphemeralSeconds(t *testing.T) {
+	for _, ok := range []int{0, 86400, 604800, 7776000} {
+		if err := ValidateEphemeralSeconds(ok); err != nil {
+			t.Fatalf("expected %d valid: %v", ok, err)
+		}
+	}
+	invalid := []int{-1, 1, 3600}
+	if strconv.IntSize == 64 {
+		// Values that would wrap to a valid duration as uint32.
+		wide := int64(1) << 32
+		invalid = append(invalid, int(wide), int(wide+86400))
+	}
+	for _, bad := range invalid {
+		if err := ValidateEphemeralSeconds(bad); err == nil {
+			t.Fatalf("expected %d invalid", bad)
+		}
+	}
+}
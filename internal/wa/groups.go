@@ -28,6 +28,57 @@ func (c *Client) SetGroupName(ctx context.Context, jid types.JID, name string) e
 	return cli.SetGroupName(ctx, jid, name)
 }
 
+// SetGroupDescription replaces the group's description; an empty one
+// removes it.
+func (c *Client) SetGroupDescription(ctx context.Context, jid types.JID, description string) error {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+	// WhatsApp rejects the change unless it names the description it replaces.
+	info, err := cli.GetGroupInfo(ctx, jid)
+	if err != nil {
+		return err
+	}
+	return cli.SetGroupTopic(ctx, jid, info.TopicID, "", description)
+}
+
+// SetGroupPhoto replaces the group's photo with a square JPEG and returns
+// the new picture ID.
+func (c *Client) SetGroupPhoto(ctx context.Context, jid types.JID, jpeg []byte) (string, error) {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return "", fmt.Errorf("not connected")
+	}
+	return cli.SetGroupPhoto(ctx, jid, jpeg)
+}
+
+// SetGroupAnnounce toggles whether only admins can send messages.
+func (c *Client) SetGroupAnnounce(ctx context.Context, jid types.JID, announce bool) error {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+	return cli.SetGroupAnnounce(ctx, jid, announce)
+}
+
+// SetGroupLocked toggles whether only admins can edit the group's info.
+func (c *Client) SetGroupLocked(ctx context.Context, jid types.JID, locked bool) error {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+	return cli.SetGroupLocked(ctx, jid, locked)
+}
+
 type GroupParticipantAction string
 
 const (
@@ -72,6 +123,18 @@ func (c *Client) GetGroupInviteLink(ctx context.Context, group types.JID, reset
 	return cli.GetGroupInviteLink(ctx, group, reset)
 }
 
+// GetGroupInfoFromLink looks up the group behind an invite code or link
+// without joining it.
+func (c *Client) GetGroupInfoFromLink(ctx context.Context, code string) (*types.GroupInfo, error) {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return nil, fmt.Errorf("not connected")
+	}
+	return cli.GetGroupInfoFromLink(ctx, code)
+}
+
 func (c *Client) JoinGroupWithLink(ctx context.Context, code string) (types.JID, error) {
 	c.mu.Lock()
 	cli := c.client
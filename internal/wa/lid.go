@@ -0,0 +1,52 @@
+package wa
+
+import (
+	"sync/atomic"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// LIDResolver returns the phone number JID of a LID (@lid) JID, if known.
+type LIDResolver func(lid types.JID) (types.JID, bool)
+
+var lidResolver atomic.Pointer[LIDResolver]
+
+// SetLIDResolver makes ParseUserOrJID and PhoneJID map LIDs to phone number
+// JIDs with r; nil turns the mapping off.
+func SetLIDResolver(r LIDResolver) {
+	if r == nil {
+		lidResolver.Store(nil)
+		return
+	}
+	lidResolver.Store(&r)
+}
+
+// PhoneJID returns the phone number JID of the same account for a LID with
+// a known mapping, keeping the device, and jid itself otherwise.
+func PhoneJID(jid types.JID) types.JID {
+	if jid.Server != types.HiddenUserServer {
+		return jid
+	}
+	r := lidResolver.Load()
+	if r == nil {
+		return jid
+	}
+	pn, ok := (*r)(jid.ToNonAD())
+	if !ok {
+		return jid
+	}
+	pn.Device = jid.Device
+	return pn
+}
+
+// IsLIDPair reports whether a and b are the LID and the phone number JID
+// of an account, in either order, and returns them without device.
+func IsLIDPair(a, b types.JID) (lid, pn types.JID, ok bool) {
+	if a.Server == types.DefaultUserServer {
+		a, b = b, a
+	}
+	if a.Server != types.HiddenUserServer || b.Server != types.DefaultUserServer || a.User == "" || b.User == "" {
+		return types.JID{}, types.JID{}, false
+	}
+	return a.ToNonAD(), b.ToNonAD(), true
+}
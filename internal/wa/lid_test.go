@@ -0,0 +1,48 @@
+package wa
+
+import (
+	"testing"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestParseUserOrJIDResolvesLID(t *testing.T) {
+	pn := types.JID{User: "5511999990000", Server: types.DefaultUserServer}
+	SetLIDResolver(func(lid types.JID) (types.JID, bool) {
+		return pn, lid.User == "987654321"
+	})
+	t.Cleanup(func() { SetLIDResolver(nil) })
+
+	j, err := ParseUserOrJID("987654321@lid")
+	if err != nil || j != pn {
+		t.Fatalf("expected %s, got %s (%v)", pn, j, err)
+	}
+	if j, _ := ParseUserOrJID("111@lid"); j.Server != types.HiddenUserServer {
+		t.Fatalf("unknown LID should stay a LID, got %s", j)
+	}
+	if j := PhoneJID(types.JID{User: "987654321", Device: 3, Server: types.HiddenUserServer}); j.User != pn.User || j.Device != 3 {
+		t.Fatalf("expected device to be kept, got %s", j)
+	}
+
+	SetLIDResolver(nil)
+	if j, _ := ParseUserOrJID("987654321@lid"); j.Server != types.HiddenUserServer {
+		t.Fatalf("expected no mapping without a resolver, got %s", j)
+	}
+}
+
+func TestIsLIDPair(t *testing.T) {
+	lid := types.JID{User: "987654321", Device: 2, Server: types.HiddenUserServer}
+	pn := types.JID{User: "5511999990000", Server: types.DefaultUserServer}
+	for _, pair := range [][2]types.JID{{lid, pn}, {pn, lid}} {
+		l, p, ok := IsLIDPair(pair[0], pair[1])
+		if !ok || l != lid.ToNonAD() || p != pn {
+			t.Fatalf("IsLIDPair(%s, %s) = %s, %s, %v", pair[0], pair[1], l, p, ok)
+		}
+	}
+	if _, _, ok := IsLIDPair(pn, pn); ok {
+		t.Fatalf("two phone number JIDs are not a pair")
+	}
+	if _, _, ok := IsLIDPair(lid, types.EmptyJID); ok {
+		t.Fatalf("a LID alone is not a pair")
+	}
+}
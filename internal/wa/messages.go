@@ -19,10 +19,18 @@ type Media struct {
 	FileSHA256    []byte
 	FileEncSHA256 []byte
 	FileLength    uint64
+	// Thumbnail is the small JPEG preview embedded in image and video
+	// messages, if any.
+	Thumbnail []byte
 }
 
 type ParsedMessage struct {
-	Chat           types.JID
+	Chat types.JID
+	// ChatAlt and SenderAlt are the other address of a direct chat and of
+	// the sender: the phone number JID for a LID, or the LID for a phone
+	// number JID. WhatsApp sends them on live messages only.
+	ChatAlt        types.JID
+	SenderAlt      types.JID
 	ID             string
 	SenderJID      string
 	Timestamp      time.Time
@@ -34,6 +42,19 @@ type ParsedMessage struct {
 	ReplyToDisplay string
 	ReactionToID   string
 	ReactionEmoji  string
+	// RevokedID is the message the sender deleted for everyone; EditedID the
+	// one they edited, with the new content in Text.
+	RevokedID string
+	EditedID  string
+	// System is set for system notices such as SystemEphemeralTimer, with a
+	// description in Text.
+	System string
+	// EphemeralTimer is the new disappearing-message timer of a
+	// SystemEphemeralTimer notice; zero turns it off.
+	EphemeralTimer time.Duration
+	// ParticipantChanges lists the membership changes of a
+	// SystemGroupParticipants notice.
+	ParticipantChanges []GroupParticipantChange
 }
 
 func ParseLiveMessage(evt *events.Message) ParsedMessage {
@@ -43,6 +64,14 @@ func ParseLiveMessage(evt *events.Message) ParsedMessage {
 		Timestamp: evt.Info.Timestamp,
 		FromMe:    evt.Info.IsFromMe,
 		PushName:  evt.Info.PushName,
+		SenderAlt: evt.Info.SenderAlt,
+	}
+	if !evt.Info.IsGroup {
+		if evt.Info.IsFromMe {
+			msg.ChatAlt = evt.Info.RecipientAlt
+		} else {
+			msg.ChatAlt = evt.Info.SenderAlt
+		}
 	}
 	if s := evt.Info.Sender.String(); s != "" {
 		msg.SenderJID = s
@@ -73,6 +102,8 @@ func ParseHistoryMessage(chatJID string, hist *waProto.WebMessageInfo) ParsedMes
 
 	if hist.GetMessage() != nil {
 		extractWAProto(hist.GetMessage(), &pm)
+	} else {
+		parseHistoryStub(hist, &pm)
 	}
 	return pm
 }
@@ -82,6 +113,21 @@ func extractWAProto(m *waProto.Message, pm *ParsedMessage) {
 		return
 	}
 
+	if proto := m.GetProtocolMessage(); proto != nil {
+		switch proto.GetType() {
+		case waProto.ProtocolMessage_REVOKE:
+			pm.RevokedID = proto.GetKey().GetID()
+		case waProto.ProtocolMessage_EPHEMERAL_SETTING:
+			setEphemeralTimer(pm, time.Duration(proto.GetEphemeralExpiration())*time.Second)
+		case waProto.ProtocolMessage_MESSAGE_EDIT:
+			pm.EditedID = proto.GetKey().GetID()
+			var edited ParsedMessage
+			extractWAProto(proto.GetEditedMessage(), &edited)
+			pm.Text = edited.Text
+		}
+		return
+	}
+
 	if reaction := m.GetReactionMessage(); reaction != nil {
 		pm.ReactionEmoji = reaction.GetText()
 		if key := reaction.GetKey(); key != nil {
@@ -113,6 +159,7 @@ func extractWAProto(m *waProto.Message, pm *ParsedMessage) {
 			FileSHA256:    clone(img.GetFileSHA256()),
 			FileEncSHA256: clone(img.GetFileEncSHA256()),
 			FileLength:    img.GetFileLength(),
+			Thumbnail:     clone(img.GetJPEGThumbnail()),
 		}
 	}
 
@@ -133,6 +180,7 @@ func extractWAProto(m *waProto.Message, pm *ParsedMessage) {
 			FileSHA256:    clone(vid.GetFileSHA256()),
 			FileEncSHA256: clone(vid.GetFileEncSHA256()),
 			FileLength:    vid.GetFileLength(),
+			Thumbnail:     clone(vid.GetJPEGThumbnail()),
 		}
 	}
 
@@ -140,3 +140,74 @@ func TestParseLiveMessageReply(t *testing.T) {
 		t.Fatalf("expected ReplyToDisplay to be quoted, got %q", pm.ReplyToDisplay)
 	}
 }
+
+func TestParseLiveMessageRevokeAndEdit(t *testing.T) {
+	chat, _ := types.ParseJID("123@s.whatsapp.net")
+	info := types.MessageInfo{
+		MessageSource: types.MessageSource{Chat: chat, Sender: chat},
+		ID:            "proto",
+		Timestamp:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	revoke := ParseLiveMessage(&events.Message{
+		Info: info,
+		Message: &waProto.Message{
+			ProtocolMessage: &waProto.ProtocolMessage{
+				Type: waProto.ProtocolMessage_REVOKE.Enum(),
+				Key:  &waProto.MessageKey{ID: proto.String("orig")},
+			},
+		},
+	})
+	if revoke.RevokedID != "orig" || revoke.EditedID != "" || revoke.Text != "" {
+		t.Fatalf("unexpected revoke parse: %+v", revoke)
+	}
+
+	edit := ParseLiveMessage(&events.Message{
+		Info: info,
+		Message: &waProto.Message{
+			ProtocolMessage: &waProto.ProtocolMessage{
+				Type:          waProto.ProtocolMessage_MESSAGE_EDIT.Enum(),
+				Key:           &waProto.MessageKey{ID: proto.String("orig")},
+				EditedMessage: &waProto.Message{Conversation: proto.String("fixed typo")},
+			},
+		},
+	})
+	if edit.EditedID != "orig" || edit.RevokedID != "" || edit.Text != "fixed typo" {
+		t.Fatalf("unexpected edit parse: %+v", edit)
+	}
+}
+
+func TestParseSystemMessages(t *testing.T) {
+	chat, _ := types.ParseJID("123@s.whatsapp.net")
+	live := ParseLiveMessage(&events.Message{
+		Info: types.MessageInfo{MessageSource: types.MessageSource{Chat: chat, Sender: chat}, ID: "eph"},
+		Message: &waProto.Message{ProtocolMessage: &waProto.ProtocolMessage{
+			Type:                waProto.ProtocolMessage_EPHEMERAL_SETTING.Enum(),
+			EphemeralExpiration: proto.Uint32(604800),
+		}},
+	})
+	if live.System != SystemEphemeralTimer || live.EphemeralTimer != 7*24*time.Hour || live.Text != "Disappearing messages set to 7 days" {
+		t.Fatalf("unexpected ephemeral notice: %+v", live)
+	}
+
+	stub := func(typ waProto.WebMessageInfo_StubType, params ...string) ParsedMessage {
+		return ParseHistoryMessage("456@g.us", &waProto.WebMessageInfo{
+			Key:                   &waProto.MessageKey{ID: proto.String("stub")},
+			MessageTimestamp:      proto.Uint64(1704067200),
+			MessageStubType:       typ.Enum(),
+			MessageStubParameters: params,
+		})
+	}
+	if pm := stub(waProto.WebMessageInfo_GROUP_CREATE, "Team"); pm.System != SystemGroupCreated || pm.Text != `Group "Team" created` {
+		t.Fatalf("unexpected group notice: %+v", pm)
+	}
+	if pm := stub(waProto.WebMessageInfo_CHANGE_EPHEMERAL_SETTING, "0"); pm.System != SystemEphemeralTimer || pm.Text != "Disappearing messages turned off" {
+		t.Fatalf("unexpected ephemeral notice: %+v", pm)
+	}
+	if pm := stub(waProto.WebMessageInfo_E2E_IDENTITY_CHANGED); pm.System != SystemSecurityCodeChanged {
+		t.Fatalf("unexpected identity notice: %+v", pm)
+	}
+	if pm := stub(waProto.WebMessageInfo_GROUP_CHANGE_SUBJECT, "New"); pm.System != "" {
+		t.Fatalf("expected unhandled stub to stay untyped: %+v", pm)
+	}
+}
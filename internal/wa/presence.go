@@ -0,0 +1,63 @@
+package wa
+
+import (
+	"context"
+	"fmt"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// SubscribePresence asks the server for presence updates of jid. WhatsApp only
+// delivers them while our own presence is available, see SendPresence.
+func (c *Client) SubscribePresence(ctx context.Context, jid types.JID) error {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+	return cli.SubscribePresence(ctx, jid)
+}
+
+// SendPresence sets our own global presence (available/unavailable).
+func (c *Client) SendPresence(ctx context.Context, state types.Presence) error {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+	return cli.SendPresence(ctx, state)
+}
+
+// Chat states accepted by SendChatPresence.
+const (
+	ChatStateComposing = "composing"
+	ChatStateRecording = "recording"
+	ChatStatePaused    = "paused"
+)
+
+// ParseChatState maps composing/recording/paused to the chat presence
+// WhatsApp expects; recording is composing with audio media.
+func ParseChatState(state string) (types.ChatPresence, types.ChatPresenceMedia, error) {
+	switch state {
+	case ChatStateComposing:
+		return types.ChatPresenceComposing, types.ChatPresenceMediaText, nil
+	case ChatStateRecording:
+		return types.ChatPresenceComposing, types.ChatPresenceMediaAudio, nil
+	case ChatStatePaused:
+		return types.ChatPresencePaused, types.ChatPresenceMediaText, nil
+	}
+	return "", "", fmt.Errorf("state must be composing, recording or paused")
+}
+
+// SendChatPresence shows or clears the typing/recording indicator in chat.
+func (c *Client) SendChatPresence(ctx context.Context, chat types.JID, state types.ChatPresence, media types.ChatPresenceMedia) error {
+	c.mu.Lock()
+	cli := c.client
+	c.mu.Unlock()
+	if cli == nil || !cli.IsConnected() {
+		return fmt.Errorf("not connected")
+	}
+	return cli.SendChatPresence(ctx, chat, state, media)
+}
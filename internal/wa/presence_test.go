@@ -0,0 +1,21 @@
+package wa
+
+import (
+	"testing"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestParseChatState(t *testing.T) {
+	state, media, err := ParseChatState("recording")
+	if err != nil || state != types.ChatPresenceComposing || media != types.ChatPresenceMediaAudio {
+		t.Fatalf("recording: %v %v %v", state, media, err)
+	}
+	state, media, err = ParseChatState("paused")
+	if err != nil || state != types.ChatPresencePaused || media != types.ChatPresenceMediaText {
+		t.Fatalf("paused: %v %v %v", state, media, err)
+	}
+	if _, _, err := ParseChatState("typing"); err == nil {
+		t.Fatalf("expected error for unknown state")
+	}
+}
@@ -0,0 +1,174 @@
+package wa
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/binary/proto"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// System message types set on ParsedMessage.System.
+const (
+	SystemSecurityCodeChanged = "security_code_changed"
+	SystemEphemeralTimer      = "ephemeral_timer"
+	SystemGroupCreated        = "group_created"
+	SystemGroupParticipants   = "group_participants"
+)
+
+// Group membership change actions.
+const (
+	GroupEventAdd     = "add"
+	GroupEventJoin    = "join" // by invite link or approved request
+	GroupEventRemove  = "remove"
+	GroupEventLeave   = "leave"
+	GroupEventPromote = "promote"
+	GroupEventDemote  = "demote"
+)
+
+// GroupParticipantChange is one membership change in a group. Actor is who
+// made it; it is empty when WhatsApp does not say.
+type GroupParticipantChange struct {
+	Action      string
+	Participant types.JID
+	Actor       types.JID
+	Timestamp   time.Time
+}
+
+var groupStubActions = map[waProto.WebMessageInfo_StubType]string{
+	waProto.WebMessageInfo_GROUP_PARTICIPANT_ADD:               GroupEventAdd,
+	waProto.WebMessageInfo_GROUP_PARTICIPANT_REMOVE:            GroupEventRemove,
+	waProto.WebMessageInfo_GROUP_PARTICIPANT_LEAVE:             GroupEventLeave,
+	waProto.WebMessageInfo_GROUP_PARTICIPANT_PROMOTE:           GroupEventPromote,
+	waProto.WebMessageInfo_GROUP_PARTICIPANT_DEMOTE:            GroupEventDemote,
+	waProto.WebMessageInfo_GROUP_PARTICIPANT_INVITE:            GroupEventJoin,
+	waProto.WebMessageInfo_GROUP_PARTICIPANT_ADD_REQUEST_JOIN:  GroupEventJoin,
+	waProto.WebMessageInfo_GROUP_PARTICIPANT_ACCEPT:            GroupEventJoin,
+	waProto.WebMessageInfo_GROUP_PARTICIPANT_LINKED_GROUP_JOIN: GroupEventJoin,
+}
+
+var groupEventVerbs = map[string]string{
+	GroupEventAdd:     "added",
+	GroupEventJoin:    "joined",
+	GroupEventRemove:  "removed",
+	GroupEventLeave:   "left",
+	GroupEventPromote: "made admin",
+	GroupEventDemote:  "no longer admin",
+}
+
+// ParseGroupInfoEvent returns the membership changes in a live group
+// update. Joins and leaves by the participant themselves are reported as
+// GroupEventJoin and GroupEventLeave.
+func ParseGroupInfoEvent(evt *events.GroupInfo) []GroupParticipantChange {
+	var actor types.JID
+	if evt.Sender != nil {
+		actor = evt.Sender.ToNonAD()
+	}
+	if evt.SenderPN != nil && actor.Server == types.HiddenUserServer {
+		actor = evt.SenderPN.ToNonAD()
+	}
+	var out []GroupParticipantChange
+	add := func(action string, jids []types.JID) {
+		for _, j := range jids {
+			out = append(out, GroupParticipantChange{Action: action, Participant: j.ToNonAD(), Actor: actor, Timestamp: evt.Timestamp.UTC()})
+		}
+	}
+	for _, j := range evt.Join {
+		action := GroupEventAdd
+		if evt.JoinReason == "invite" || actor.IsEmpty() || actor == j.ToNonAD() {
+			action = GroupEventJoin
+		}
+		add(action, []types.JID{j})
+	}
+	for _, j := range evt.Leave {
+		action := GroupEventRemove
+		if actor.IsEmpty() || actor == j.ToNonAD() {
+			action = GroupEventLeave
+		}
+		add(action, []types.JID{j})
+	}
+	add(GroupEventPromote, evt.Promote)
+	add(GroupEventDemote, evt.Demote)
+	return out
+}
+
+func parseGroupParticipantStub(hist *waProto.WebMessageInfo, pm *ParsedMessage, action string) {
+	var actor types.JID
+	if a, err := types.ParseJID(strings.TrimSpace(pm.SenderJID)); err == nil && a.Server != types.GroupServer {
+		actor = a.ToNonAD()
+	}
+	var names []string
+	for _, p := range hist.GetMessageStubParameters() {
+		j, err := types.ParseJID(strings.TrimSpace(p))
+		if err != nil || j.User == "" {
+			continue
+		}
+		j = j.ToNonAD()
+		a := action
+		if action == GroupEventRemove && actor == j {
+			a = GroupEventLeave
+		}
+		pm.ParticipantChanges = append(pm.ParticipantChanges, GroupParticipantChange{Action: a, Participant: j, Actor: actor, Timestamp: pm.Timestamp})
+		names = append(names, "+"+j.User)
+	}
+	if len(pm.ParticipantChanges) == 0 {
+		return
+	}
+	pm.System = SystemGroupParticipants
+	pm.Text = strings.Join(names, ", ") + " " + groupEventVerbs[action]
+}
+
+// parseHistoryStub fills in system notices that history sync delivers as
+// message stubs rather than messages.
+func parseHistoryStub(hist *waProto.WebMessageInfo, pm *ParsedMessage) {
+	params := hist.GetMessageStubParameters()
+	switch hist.GetMessageStubType() {
+	case waProto.WebMessageInfo_E2E_IDENTITY_CHANGED:
+		pm.System = SystemSecurityCodeChanged
+		pm.Text = "Security code changed"
+	case waProto.WebMessageInfo_CHANGE_EPHEMERAL_SETTING:
+		var seconds int64
+		if len(params) > 0 {
+			seconds, _ = strconv.ParseInt(strings.TrimSpace(params[0]), 10, 64)
+		}
+		setEphemeralTimer(pm, time.Duration(seconds)*time.Second)
+	case waProto.WebMessageInfo_GROUP_CREATE:
+		pm.System = SystemGroupCreated
+		pm.Text = "Group created"
+		if len(params) > 0 && strings.TrimSpace(params[0]) != "" {
+			pm.Text = fmt.Sprintf("Group %q created", strings.TrimSpace(params[0]))
+		}
+	default:
+		if action, ok := groupStubActions[hist.GetMessageStubType()]; ok {
+			parseGroupParticipantStub(hist, pm, action)
+		}
+	}
+}
+
+func setEphemeralTimer(pm *ParsedMessage, timer time.Duration) {
+	pm.System = SystemEphemeralTimer
+	pm.EphemeralTimer = timer
+	if timer <= 0 {
+		pm.Text = "Disappearing messages turned off"
+		return
+	}
+	pm.Text = "Disappearing messages set to " + formatTimer(timer)
+}
+
+func formatTimer(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		days := int(d / (24 * time.Hour))
+		if days == 1 {
+			return "24 hours"
+		}
+		return fmt.Sprintf("%d days", days)
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%d hours", int(d/time.Hour))
+	default:
+		return d.String()
+	}
+}